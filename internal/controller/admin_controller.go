@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"log/slog"
+	"net/http"
+
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminController handles operator HTTP requests. Its routes are meant for the internal
+// network only; nginx does not proxy /admin.
+type AdminController struct {
+	cacheService service.CacheAdminService
+	// logLevel is the level variable of the application's slog handler
+	logLevel *slog.LevelVar
+	logger   *slog.Logger
+}
+
+// NewAdminController creates a new admin controller
+func NewAdminController(cacheService service.CacheAdminService, logLevel *slog.LevelVar, logger *slog.Logger) *AdminController {
+	return &AdminController{
+		cacheService: cacheService,
+		logLevel:     logLevel,
+		logger:       logger,
+	}
+}
+
+// logLevelRequest is the body of a log level change
+type logLevelRequest struct {
+	Level string `json:"level"`
+}
+
+// GetCache handles GET /admin/cache
+// Returns hit/miss statistics and every cached farm with its expiry
+func (c *AdminController) GetCache(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, c.cacheService.GetFarmCache())
+}
+
+// EvictFarm handles DELETE /admin/cache/farms/{farm_id}
+// Removes the farm's cached entry so its next request goes to the database
+func (c *AdminController) EvictFarm(ctx *gin.Context) {
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	evicted := c.cacheService.EvictFarm(farmID)
+	c.logger.Info("farm cache entry evicted",
+		"farm_id", farmID,
+		"evicted", evicted,
+	)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"farm_id": farmID,
+		"evicted": evicted,
+	})
+}
+
+// FlushCache handles DELETE /admin/cache
+// Removes every cached entry
+func (c *AdminController) FlushCache(ctx *gin.Context) {
+	c.cacheService.FlushFarmCache()
+	c.logger.Info("farm cache flushed")
+	ctx.Status(http.StatusNoContent)
+}
+
+// GetLogLevel handles GET /admin/log-level
+func (c *AdminController) GetLogLevel(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"level": c.logLevel.Level().String()})
+}
+
+// SetLogLevel handles PUT /admin/log-level
+// Body fields:
+//   - level (required): debug, info, warn, or error
+//
+// The change applies immediately and lasts until the next change or restart.
+func (c *AdminController) SetLogLevel(ctx *gin.Context) {
+	var req logLevelRequest
+	var level slog.Level
+	if err := ctx.ShouldBindJSON(&req); err != nil || level.UnmarshalText([]byte(req.Level)) != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": "level must be one of: debug, info, warn, error",
+		})
+		return
+	}
+
+	previous := c.logLevel.Level()
+	c.logLevel.Set(level)
+	// Logged at Warn so the change is recorded whatever the new level
+	c.logger.Warn("log level changed",
+		"previous", previous.String(),
+		"level", level.String(),
+	)
+
+	ctx.JSON(http.StatusOK, gin.H{"level": level.String()})
+}
@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"irrigation-analytics/internal/repository"
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSetLogLevel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	level := &slog.LevelVar{}
+	controller := NewAdminController(service.NewCacheAdminService(repository.NewFarmCache(0)), level, slog.Default())
+	r := gin.New()
+	r.PUT("/admin/log-level", controller.SetLogLevel)
+
+	tests := []struct {
+		body         string
+		expectedCode int
+		expected     slog.Level
+	}{
+		{`{"level": "debug"}`, http.StatusOK, slog.LevelDebug},
+		{`{"level": "WARN"}`, http.StatusOK, slog.LevelWarn},
+		{`{"level": "verbose"}`, http.StatusBadRequest, slog.LevelWarn},
+		{`{}`, http.StatusBadRequest, slog.LevelWarn},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("PUT", "/admin/log-level", bytes.NewBufferString(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.expectedCode {
+			t.Errorf("%s: expected status %d, got %d", tt.body, tt.expectedCode, w.Code)
+		}
+		if level.Level() != tt.expected {
+			t.Errorf("%s: expected level %s, got %s", tt.body, tt.expected, level.Level())
+		}
+	}
+}
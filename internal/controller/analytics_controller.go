@@ -1,21 +1,44 @@
 package controller
 
 import (
+	"context"
+	"crypto/subtle"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
+	"irrigation-analytics/internal/middleware"
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
 	"irrigation-analytics/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
+// maxHourlyRange bounds the date range of hourly requests to keep responses to ~744 buckets per sector
+const maxHourlyRange = 31 * 24 * time.Hour
+
+// versionHeader names the response schema version served
+const versionHeader = "X-API-Version"
+
+// vendorMediaType prefixes versioned media types, e.g. application/vnd.irrigation-analytics.v2+json
+const vendorMediaType = "application/vnd.irrigation-analytics."
+
+// adminTokenHeader carries the operator token that unlocks debug capture and include_deleted
+const adminTokenHeader = "X-Admin-Token"
+
 // AnalyticsController handles analytics-related HTTP requests
 type AnalyticsController struct {
 	analyticsService service.AnalyticsService
 	logger           *slog.Logger
+	// adminToken enables debug=true and include_deleted=true for requests presenting it; empty
+	// disables both
+	adminToken string
 }
 
 // NewAnalyticsController creates a new analytics controller
@@ -26,19 +49,457 @@ func NewAnalyticsController(analyticsService service.AnalyticsService, logger *s
 	}
 }
 
+// EnableDebugCapture allows debug=true, and include_deleted=true for audits, on requests whose
+// X-Admin-Token header matches adminToken
+func (c *AnalyticsController) EnableDebugCapture(adminToken string) {
+	c.adminToken = adminToken
+}
+
 // GetIrrigationAnalytics handles GET /v1/farms/{farm_id}/irrigation/analytics
 // Query parameters:
 //   - sector_id (optional): Filter by sector ID
 //   - start_date (required): Start date in ISO 8601 format (RFC3339 or YYYY-MM-DD)
 //   - end_date (required): End date in ISO 8601 format (RFC3339 or YYYY-MM-DD)
-//   - aggregation (optional): daily, weekly, or monthly (default: daily)
+//   - aggregation (optional): hourly, daily, weekly, monthly, or custom (default: daily); hourly is limited to 31 days
+//   - bucket_days (required with aggregation=custom): bucket length in days, 2-183; buckets start on 1 January
+//   - summary_only (optional): true to return only the summary section via a single totals query
+//   - efficiency_weighting (optional): mean or volume (default: mean in v1, volume in v2)
+//   - exclude_annotated (optional): true to leave events under exclude_from_efficiency annotations out of efficiency
+//   - exclude_seed (optional): true to leave seeded demo data out of the response
+//   - purpose (optional): comma-separated event purposes to include (irrigation, frost_protection, leaching, system_flush)
+//   - device_id (optional): only include events measured by this registered device
+//   - breakdown (optional): purpose to add per-purpose totals
+//   - strict (optional): true to return 502 when an optional section (annotations, pressure, weather,
+//     distribution, sector_breakdown) fails, instead of omitting it and listing it in warnings
+//   - normalize (optional): area to add water_per_hectare, events_per_hectare and applied_depth_mm
+//     from sector areas
+//   - weather (optional): true to add each bucket's stored rainfall_mm, et0_mm and frost or heat
+//     weather_events (daily and coarser buckets)
+//   - distribution (optional): true to add per-event median, p90, p95, min, max and stddev to the summary
+//   - include_trend (optional): true to add each point's 7-point moving averages and its series' trend slopes
+//   - split_events (optional): true to spread events running past midnight over the days they span
+//   - fill_gaps (optional): true to add a zero-valued data point for every bucket of the range without events
+//   - units (optional): metric or imperial to report volumes in US gallons, per-hectare figures per acre
+//     and depths in inches (default: metric)
+//   - level (optional): sector hierarchy depth to roll data points and sector_breakdown up to, 1 being
+//     top-level sectors (default: every sector and zone separately)
+//   - compare_to (optional): comma-separated periods to compare to, up to 5: previous_period, an offset
+//     such as -3y, -6m, -2w or -7d, or an explicit start/end range (default: -1y,-2y)
+//   - yoy_alignment (optional): calendar or season; season places the periods years back at the same
+//     position in the farm's crop year instead of on the same calendar dates (default: calendar)
+//   - omit (optional): comma-separated sections to leave out, skipping their queries: data,
+//     comparisons, sector_breakdown, annotations, pressure
+//   - debug (optional): true to add executed SQL and stage timings as _debug; requires the X-Admin-Token header
+//   - include_deleted (optional): true to count soft-deleted events, for audits; requires the X-Admin-Token header
+//   - api_version (optional): response schema version, v1 or v2 (default: v1); also negotiable through the
+//     Accept header as application/vnd.irrigation-analytics.v2+json or application/json; version=2
 func (c *AnalyticsController) GetIrrigationAnalytics(ctx *gin.Context) {
 	startTime := time.Now()
 	// Parse farm_id from path
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	// Parse optional sector_id from query
+	sectorID, ok := parseSectorID(ctx, c.logger, farmID)
+	if !ok {
+		return
+	}
+
+	c.serveAnalytics(ctx, startTime, farmID, sectorID, false)
+}
+
+// GetSectorAnalytics handles GET /v1/farms/{farm_id}/sectors/{sector_id}/irrigation/analytics
+// It serves the full analytics payload of one sector, including its zones, with the query
+// parameters of GetIrrigationAnalytics except sector_id. The sector_breakdown lists the sector and
+// its zones, and the response is 404 when the sector is not on the farm.
+func (c *AnalyticsController) GetSectorAnalytics(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	sectorIDStr := ctx.Param("sector_id")
+	sid, err := strconv.ParseUint(sectorIDStr, 10, 32)
+	if err != nil {
+		c.logger.Warn("invalid sector_id",
+			"sector_id", sectorIDStr,
+			"farm_id", farmID,
+			"error", err.Error(),
+		)
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid sector_id",
+			"message": "sector_id must be a valid unsigned integer",
+		})
+		return
+	}
+	sectorID := uint(sid)
+
+	c.serveAnalytics(ctx, startTime, farmID, &sectorID, true)
+}
+
+// serveAnalytics parses the analytics query parameters and writes the analytics of a farm, or of
+// one sector when sectorID is set. sectorScope marks the sector-level endpoint.
+func (c *AnalyticsController) serveAnalytics(ctx *gin.Context, startTime time.Time, farmID uint, sectorID *uint, sectorScope bool) {
+	// Parse and validate start_date and end_date
+	startDate, endDate, ok := parseDateRange(ctx, c.logger, farmID)
+	if !ok {
+		return
+	}
+
+	// Parse aggregation level (optional, default: daily)
+	aggregation, ok := parseAggregation(ctx)
+	if !ok {
+		return
+	}
+	if aggregation == "hourly" && endDate.Sub(startDate) > maxHourlyRange {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid date range",
+			"message": "hourly aggregation supports ranges of up to 31 days",
+		})
+		return
+	}
+
+	// Parse summary_only flag (optional, default: false)
+	summaryOnly, ok := parseBoolQuery(ctx, "summary_only")
+	if !ok {
+		return
+	}
+
+	// Negotiate the response schema version (optional, default: v1)
+	schema, ok := negotiateSchemaVersion(ctx)
+	if !ok {
+		return
+	}
+
+	// Parse efficiency weighting (optional, default set by the schema version)
+	weighting := ctx.DefaultQuery("efficiency_weighting", schema.EfficiencyWeighting)
+	if weighting != service.EfficiencyWeightingMean && weighting != service.EfficiencyWeightingVolume {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid efficiency_weighting",
+			"message": "efficiency_weighting must be one of: mean, volume",
+		})
+		return
+	}
+
+	// Parse exclude_annotated and exclude_seed flags (optional, default: false)
+	excludeAnnotated, ok := parseBoolQuery(ctx, "exclude_annotated")
+	if !ok {
+		return
+	}
+	excludeSeed, ok := parseBoolQuery(ctx, "exclude_seed")
+	if !ok {
+		return
+	}
+
+	// Parse purpose filter (optional, default: all purposes)
+	purposes, ok := parsePurposes(ctx)
+	if !ok {
+		return
+	}
+
+	// Parse device filter (optional, default: every event)
+	deviceID, ok := parseDeviceIDQuery(ctx)
+	if !ok {
+		return
+	}
+
+	// Parse breakdown (optional)
+	breakdown := ctx.Query("breakdown")
+	if breakdown != "" && breakdown != "purpose" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid breakdown",
+			"message": "breakdown must be: purpose",
+		})
+		return
+	}
+
+	// Parse strict flag (optional, default: false)
+	strict, ok := parseBoolQuery(ctx, "strict")
+	if !ok {
+		return
+	}
+
+	// Parse normalization (optional)
+	normalize := ctx.Query("normalize")
+	if normalize != "" && normalize != "area" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid normalize",
+			"message": "normalize must be: area",
+		})
+		return
+	}
+
+	// Parse weather flag (optional, default: false)
+	weather, ok := parseBoolQuery(ctx, "weather")
+	if !ok {
+		return
+	}
+
+	// Parse distribution flag (optional, default: false)
+	distribution, ok := parseBoolQuery(ctx, "distribution")
+	if !ok {
+		return
+	}
+
+	// Parse trend flag (optional, default: false)
+	includeTrend, ok := parseBoolQuery(ctx, "include_trend")
+	if !ok {
+		return
+	}
+
+	// Parse event splitting flag (optional, default: false)
+	splitEvents, ok := parseBoolQuery(ctx, "split_events")
+	if !ok {
+		return
+	}
+
+	// Parse gap filling flag (optional, default: false)
+	fillGaps, ok := parseBoolQuery(ctx, "fill_gaps")
+	if !ok {
+		return
+	}
+
+	// Parse unit system (optional, default: metric)
+	units := ctx.DefaultQuery("units", service.UnitsMetric)
+	if !service.IsValidUnitSystem(units) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid units",
+			"message": "units must be one of: metric, imperial",
+		})
+		return
+	}
+
+	// Parse hierarchy level (optional, default: no roll-up)
+	level := 0
+	if levelStr := ctx.Query("level"); levelStr != "" {
+		parsed, err := strconv.Atoi(levelStr)
+		if err != nil || parsed < 1 {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid level",
+				"message": "level must be a positive integer, 1 being top-level sectors",
+			})
+			return
+		}
+		level = parsed
+	}
+
+	// Parse debug flag (optional, admin only)
+	debug, ok := parseBoolQuery(ctx, "debug")
+	if !ok {
+		return
+	}
+	if debug && !c.isAdmin(ctx) {
+		c.logger.Warn("debug capture denied",
+			"farm_id", farmID,
+			"remote_addr", ctx.ClientIP(),
+		)
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "debug requires a valid X-Admin-Token header",
+		})
+		return
+	}
+
+	// Parse include_deleted flag (optional, admin only)
+	includeDeleted, ok := parseBoolQuery(ctx, "include_deleted")
+	if !ok {
+		return
+	}
+	if includeDeleted && !c.isAdmin(ctx) {
+		c.logger.Warn("include_deleted denied",
+			"farm_id", farmID,
+			"remote_addr", ctx.ClientIP(),
+		)
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "include_deleted requires a valid X-Admin-Token header",
+		})
+		return
+	}
+
+	// Parse comparison periods (optional, default: one and two years back)
+	compareTo, ok := parseCompareTo(ctx, startDate, endDate)
+	if !ok {
+		return
+	}
+
+	// Parse omitted sections (optional, default: every section)
+	omit, ok := parseOmit(ctx, compareTo != nil)
+	if !ok {
+		return
+	}
+
+	// Parse year alignment (optional, default: calendar)
+	yearAlignment := ctx.DefaultQuery("yoy_alignment", service.YearAlignmentCalendar)
+	if yearAlignment != service.YearAlignmentCalendar && yearAlignment != service.YearAlignmentSeason {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid yoy_alignment",
+			"message": "yoy_alignment must be one of: calendar, season",
+		})
+		return
+	}
+
+	opts := service.AnalyticsOptions{
+		EfficiencyWeighting: weighting,
+		ExcludeAnnotated:    excludeAnnotated,
+		ExcludeSeed:         excludeSeed,
+		Purposes:            purposes,
+		DeviceID:            deviceID,
+		IncludeDeleted:      includeDeleted,
+		BreakdownByPurpose:  breakdown == "purpose",
+		Debug:               debug,
+		NormalizeByArea:     normalize == "area",
+		IncludeWeather:      weather,
+		IncludeDistribution: distribution,
+		IncludeTrend:        includeTrend,
+		SplitEvents:         splitEvents,
+		FillGaps:            fillGaps,
+		Units:               units,
+		Level:               level,
+		SectorScope:         sectorScope,
+		Strict:              strict,
+		CompareTo:           compareTo,
+		YearAlignment:       yearAlignment,
+		Omit:                omit,
+	}
+
+	// Bind the service to the request, so the farm check and every query are cancelled with it
+	analyticsService := c.analyticsService.WithContext(ctx.Request.Context())
+
+	// Check if farm exists
+	if !ensureFarmExists(ctx, c.logger, analyticsService, farmID, startTime) {
+		return
+	}
+
+	// Log query parameters
+	c.logger.Info("processing analytics request",
+		"farm_id", farmID,
+		"sector_id", sectorID,
+		"start_date", startDate.Format(time.RFC3339),
+		"end_date", endDate.Format(time.RFC3339),
+		"aggregation", aggregation,
+		"schema_version", schema.Name,
+		"summary_only", summaryOnly,
+		"efficiency_weighting", weighting,
+		"exclude_annotated", excludeAnnotated,
+		"exclude_seed", excludeSeed,
+		"purposes", purposes,
+		"device_id", deviceID,
+		"breakdown", breakdown,
+		"normalize", normalize,
+		"weather", weather,
+		"distribution", distribution,
+		"include_trend", includeTrend,
+		"split_events", splitEvents,
+		"fill_gaps", fillGaps,
+		"units", units,
+		"level", level,
+		"strict", strict,
+		"omit", omit,
+		"debug", debug,
+		"include_deleted", includeDeleted,
+	)
+
+	// Call service, using the single-row fast path when only the summary is requested
+	var analytics *service.AnalyticsResponse
+	var err error
+	if summaryOnly {
+		analytics, err = analyticsService.GetIrrigationSummary(
+			farmID,
+			sectorID,
+			startDate,
+			endDate,
+			opts,
+		)
+	} else {
+		analytics, err = analyticsService.GetIrrigationAnalytics(
+			farmID,
+			sectorID,
+			startDate,
+			endDate,
+			aggregation,
+			opts,
+		)
+	}
+	if writeBudgetError(ctx, c.logger, farmID, err) || writeQueryCancelled(ctx, c.logger, farmID, err) {
+		return
+	}
+	if errors.Is(err, service.ErrSectorNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Sector not found",
+			"message": "No sector found with the specified ID on this farm",
+		})
+		return
+	}
+	var sectionErr *service.SectionError
+	if errors.As(err, &sectionErr) {
+		c.logger.Error("optional analytics section failed in strict mode",
+			"farm_id", farmID,
+			"section", sectionErr.Section,
+			"error", sectionErr.Err.Error(),
+			"latency_ms", time.Since(startTime).Milliseconds(),
+		)
+		ctx.JSON(http.StatusBadGateway, gin.H{
+			"error":   "Upstream query failed",
+			"message": fmt.Sprintf("the %s section could not be computed", sectionErr.Section),
+			"section": sectionErr.Section,
+		})
+		return
+	}
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to retrieve analytics",
+			"farm_id", farmID,
+			"sector_id", sectorID,
+			"start_date", startDate.Format(time.RFC3339),
+			"end_date", endDate.Format(time.RFC3339),
+			"aggregation", aggregation,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to retrieve analytics data",
+		})
+		return
+	}
+
+	for _, warning := range analytics.Warnings {
+		c.logger.Warn("analytics section omitted",
+			"farm_id", farmID,
+			"section", warning.Section,
+			"error", warning.Cause.Error(),
+		)
+	}
+
+	latency := time.Since(startTime)
+	c.logger.Info("analytics request completed",
+		"farm_id", farmID,
+		"sector_id", sectorID,
+		"aggregation", aggregation,
+		"data_points", len(analytics.Data),
+		"latency_ms", latency.Milliseconds(),
+	)
+
+	ctx.Header(versionHeader, schema.Name)
+	ctx.JSON(http.StatusOK, schema.SerializeAnalytics(analytics))
+}
+
+// isAdmin reports whether the request carries the configured admin token
+func (c *AnalyticsController) isAdmin(ctx *gin.Context) bool {
+	token := ctx.GetHeader(adminTokenHeader)
+	return c.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(c.adminToken)) == 1
+}
+
+// parseFarmID parses the farm_id path parameter, writing a 400 response when it is invalid
+func parseFarmID(ctx *gin.Context, logger *slog.Logger) (uint, bool) {
 	farmIDStr := ctx.Param("farm_id")
 	farmID, err := strconv.ParseUint(farmIDStr, 10, 32)
 	if err != nil {
-		c.logger.Warn("invalid farm_id",
+		logger.Warn("invalid farm_id",
 			"farm_id", farmIDStr,
 			"error", err.Error(),
 		)
@@ -46,29 +507,246 @@ func (c *AnalyticsController) GetIrrigationAnalytics(ctx *gin.Context) {
 			"error":   "Invalid farm_id",
 			"message": "farm_id must be a valid unsigned integer",
 		})
-		return
+		return 0, false
 	}
+	return uint(farmID), true
+}
 
-	// Parse optional sector_id from query
-	var sectorID *uint
-	if sectorIDStr := ctx.Query("sector_id"); sectorIDStr != "" {
-		sid, err := strconv.ParseUint(sectorIDStr, 10, 32)
-		if err != nil {
-			c.logger.Warn("invalid sector_id",
-				"sector_id", sectorIDStr,
-				"farm_id", farmID,
-				"error", err.Error(),
-			)
+// parseSectorID parses the optional sector_id query parameter, writing a 400 response when it is invalid
+func parseSectorID(ctx *gin.Context, logger *slog.Logger, farmID uint) (*uint, bool) {
+	sectorIDStr := ctx.Query("sector_id")
+	if sectorIDStr == "" {
+		return nil, true
+	}
+
+	sid, err := strconv.ParseUint(sectorIDStr, 10, 32)
+	if err != nil {
+		logger.Warn("invalid sector_id",
+			"sector_id", sectorIDStr,
+			"farm_id", farmID,
+			"error", err.Error(),
+		)
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid sector_id",
+			"message": "sector_id must be a valid unsigned integer",
+		})
+		return nil, false
+	}
+	sidUint := uint(sid)
+	return &sidUint, true
+}
+
+// negotiateSchemaVersion selects the response schema version from the api_version query
+// parameter or, failing that, the Accept header, and writes 406 for an unknown version
+func negotiateSchemaVersion(ctx *gin.Context) (service.SchemaVersion, bool) {
+	// Responses differ by Accept, so caches must key on it
+	ctx.Header("Vary", "Accept")
+
+	name := ctx.Query("api_version")
+	if name == "" {
+		name = acceptedSchemaVersion(ctx.GetHeader("Accept"))
+	}
+	if name == "" {
+		name = service.DefaultSchemaVersion
+	}
+
+	schema, ok := service.LookupSchemaVersion(name)
+	if !ok {
+		ctx.JSON(http.StatusNotAcceptable, gin.H{
+			"error":    "Unsupported API version",
+			"message":  fmt.Sprintf("api_version %q is not supported", name),
+			"versions": service.SchemaVersions(),
+		})
+		return service.SchemaVersion{}, false
+	}
+	return schema, true
+}
+
+// acceptedSchemaVersion returns the version named by the first versioned media type in an
+// Accept header, or "" when none names one
+func acceptedSchemaVersion(accept string) string {
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(strings.TrimSpace(mediaRange), ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		if rest, ok := strings.CutPrefix(mediaType, vendorMediaType); ok {
+			if version, ok := strings.CutSuffix(rest, "+json"); ok {
+				return version
+			}
+		}
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "version") && value != "" {
+				return "v" + strings.TrimPrefix(strings.Trim(value, `"`), "v")
+			}
+		}
+	}
+	return ""
+}
+
+// parseAggregation parses the optional aggregation query parameter (default: daily). custom
+// takes its bucket length from bucket_days and returns the repository's custom aggregation key.
+func parseAggregation(ctx *gin.Context) (string, bool) {
+	aggregation := ctx.DefaultQuery("aggregation", "daily")
+	switch aggregation {
+	case "hourly", "daily", "weekly", "monthly", "quarterly", "yearly":
+		return aggregation, true
+	case "custom":
+		days, err := strconv.Atoi(ctx.Query("bucket_days"))
+		if err != nil || days < 2 || days > repository.MaxCustomBucketDays {
 			ctx.JSON(http.StatusBadRequest, gin.H{
-				"error":   "Invalid sector_id",
-				"message": "sector_id must be a valid unsigned integer",
+				"error":   "Invalid bucket_days",
+				"message": fmt.Sprintf("aggregation=custom requires bucket_days between 2 and %d", repository.MaxCustomBucketDays),
 			})
-			return
+			return "", false
+		}
+		return repository.CustomAggregation(days), true
+	}
+	ctx.JSON(http.StatusBadRequest, gin.H{
+		"error":   "Invalid aggregation",
+		"message": "aggregation must be one of: hourly, daily, weekly, monthly, quarterly, yearly, custom",
+	})
+	return "", false
+}
+
+// parseBoolQuery parses an optional boolean query parameter (default: false),
+// writing a 400 response when it is set to something other than a boolean
+func parseBoolQuery(ctx *gin.Context, name string) (bool, bool) {
+	value := ctx.Query(name)
+	if value == "" {
+		return false, true
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid " + name,
+			"message": name + " must be true or false",
+		})
+		return false, false
+	}
+	return parsed, true
+}
+
+// parsePurposes parses the optional comma-separated purpose query parameter (default: all
+// purposes), writing a 400 response when it names an unknown purpose
+func parsePurposes(ctx *gin.Context) ([]string, bool) {
+	var purposes []string
+	if purposeStr := ctx.Query("purpose"); purposeStr != "" {
+		for _, purpose := range strings.Split(purposeStr, ",") {
+			purpose = strings.TrimSpace(purpose)
+			if !model.IsValidPurpose(purpose) {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"error":   "Invalid purpose",
+					"message": "purpose must be a comma-separated list of: irrigation, frost_protection, leaching, system_flush",
+				})
+				return nil, false
+			}
+			purposes = append(purposes, purpose)
 		}
-		sidUint := uint(sid)
-		sectorID = &sidUint
 	}
+	return purposes, true
+}
 
+// parseDeviceIDQuery parses the optional device_id query parameter (default: every event),
+// writing a 400 response when it is invalid
+func parseDeviceIDQuery(ctx *gin.Context) (*uint, bool) {
+	deviceStr := ctx.Query("device_id")
+	if deviceStr == "" {
+		return nil, true
+	}
+	parsed, err := strconv.ParseUint(deviceStr, 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid device_id",
+			"message": "device_id must be a valid unsigned integer",
+		})
+		return nil, false
+	}
+	id := uint(parsed)
+	return &id, true
+}
+
+// parseCompareTo parses the optional compare_to query parameter into comparison periods, writing
+// a 400 response when an entry is neither an offset nor a start/end range in ISO 8601 format. The
+// periods are nil when compare_to is not set.
+func parseCompareTo(ctx *gin.Context, startDate, endDate time.Time) ([]service.ComparisonPeriod, bool) {
+	value := ctx.Query("compare_to")
+	if value == "" {
+		return nil, true
+	}
+
+	entries := strings.Split(value, ",")
+	if len(entries) > service.MaxComparisons {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid compare_to",
+			"message": fmt.Sprintf("compare_to accepts up to %d comparisons", service.MaxComparisons),
+		})
+		return nil, false
+	}
+	comparisons := make([]service.ComparisonPeriod, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		from, to, isRange := strings.Cut(entry, "/")
+		if !isRange {
+			comparison, err := service.ComparisonOffset(entry, startDate, endDate)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"error":   "Invalid compare_to",
+					"message": err.Error() + "; use previous_period, an offset such as -1y, -6m, -2w or -7d, or a start/end range",
+				})
+				return nil, false
+			}
+			comparisons = append(comparisons, comparison)
+			continue
+		}
+
+		rangeStart, startErr := parseISO8601Date(from)
+		rangeEnd, endErr := parseISO8601Date(to)
+		if startErr != nil || endErr != nil || !rangeEnd.After(rangeStart) {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid compare_to",
+				"message": fmt.Sprintf("comparison range %q must be a start/end range of ISO 8601 dates with the end after the start", entry),
+			})
+			return nil, false
+		}
+		comparisons = append(comparisons, service.ComparisonPeriod{Label: entry, StartDate: rangeStart, EndDate: rangeEnd})
+	}
+	return comparisons, true
+}
+
+// parseOmit parses the optional omit query parameter into the sections to leave out, writing a
+// 400 response for an unknown section, or for omitting comparisons that compare_to asks for
+func parseOmit(ctx *gin.Context, comparing bool) ([]string, bool) {
+	value := ctx.Query("omit")
+	if value == "" {
+		return nil, true
+	}
+
+	var omit []string
+	for _, section := range strings.Split(value, ",") {
+		section = strings.TrimSpace(section)
+		if !slices.Contains(service.OmittableSections, section) {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid omit",
+				"message": fmt.Sprintf("omit must list sections among: %s", strings.Join(service.OmittableSections, ", ")),
+			})
+			return nil, false
+		}
+		omit = append(omit, section)
+	}
+	if comparing && slices.Contains(omit, service.SectionComparisons) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid omit",
+			"message": "compare_to can't be combined with omit=comparisons",
+		})
+		return nil, false
+	}
+	return omit, true
+}
+
+// parseDateRange parses the required start_date and end_date query parameters,
+// writing a 400 response when either is missing, malformed, or the range is inverted
+func parseDateRange(ctx *gin.Context, logger *slog.Logger, farmID uint) (time.Time, time.Time, bool) {
 	// Parse start_date from query
 	startDateStr := ctx.Query("start_date")
 	if startDateStr == "" {
@@ -76,12 +754,12 @@ func (c *AnalyticsController) GetIrrigationAnalytics(ctx *gin.Context) {
 			"error":   "Missing required parameter",
 			"message": "start_date is required",
 		})
-		return
+		return time.Time{}, time.Time{}, false
 	}
 
 	startDate, err := parseISO8601Date(startDateStr)
 	if err != nil {
-		c.logger.Warn("invalid start_date",
+		logger.Warn("invalid start_date",
 			"start_date", startDateStr,
 			"farm_id", farmID,
 			"error", err.Error(),
@@ -90,7 +768,7 @@ func (c *AnalyticsController) GetIrrigationAnalytics(ctx *gin.Context) {
 			"error":   "Invalid start_date",
 			"message": "start_date must be in ISO 8601 format (RFC3339 or YYYY-MM-DD)",
 		})
-		return
+		return time.Time{}, time.Time{}, false
 	}
 
 	// Parse end_date from query
@@ -100,12 +778,12 @@ func (c *AnalyticsController) GetIrrigationAnalytics(ctx *gin.Context) {
 			"error":   "Missing required parameter",
 			"message": "end_date is required",
 		})
-		return
+		return time.Time{}, time.Time{}, false
 	}
 
 	endDate, err := parseISO8601Date(endDateStr)
 	if err != nil {
-		c.logger.Warn("invalid end_date",
+		logger.Warn("invalid end_date",
 			"end_date", endDateStr,
 			"farm_id", farmID,
 			"error", err.Error(),
@@ -114,7 +792,7 @@ func (c *AnalyticsController) GetIrrigationAnalytics(ctx *gin.Context) {
 			"error":   "Invalid end_date",
 			"message": "end_date must be in ISO 8601 format (RFC3339 or YYYY-MM-DD)",
 		})
-		return
+		return time.Time{}, time.Time{}, false
 	}
 
 	// Validate date range
@@ -123,24 +801,89 @@ func (c *AnalyticsController) GetIrrigationAnalytics(ctx *gin.Context) {
 			"error":   "Invalid date range",
 			"message": "end_date must be after start_date",
 		})
-		return
+		return time.Time{}, time.Time{}, false
 	}
 
-	// Parse aggregation level (optional, default: daily)
-	aggregation := ctx.DefaultQuery("aggregation", "daily")
-	if aggregation != "daily" && aggregation != "weekly" && aggregation != "monthly" {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid aggregation",
-			"message": "aggregation must be one of: daily, weekly, monthly",
+	return startDate, endDate, true
+}
+
+// writeBudgetError writes a 422 response when err is a query budget rejection, reporting whether it did
+func writeBudgetError(ctx *gin.Context, logger *slog.Logger, farmID uint, err error) bool {
+	var budgetErr *service.BudgetError
+	if !errors.As(err, &budgetErr) {
+		return false
+	}
+	logger.Warn("query budget exceeded",
+		"farm_id", farmID,
+		"path", ctx.FullPath(),
+		"limit", budgetErr.Limit,
+		"estimated", budgetErr.Estimated,
+		"max", budgetErr.Max,
+	)
+	ctx.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":   "Query budget exceeded",
+		"message": budgetErr.Error(),
+		"budget":  budgetErr,
+	})
+	return true
+}
+
+// statusClientClosedRequest is the non-standard status logged for requests the client abandoned
+const statusClientClosedRequest = 499
+
+// writeQueryCancelled writes a 504 response when err comes from a query that hit its timeout, and
+// a 499 status when the client went away mid-query, reporting whether it did
+func writeQueryCancelled(ctx *gin.Context, logger *slog.Logger, farmID uint, err error) bool {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		logger.Warn("query timed out",
+			"farm_id", farmID,
+			"path", ctx.FullPath(),
+			"error", err.Error(),
+		)
+		ctx.JSON(http.StatusGatewayTimeout, gin.H{
+			"error":   "Query timeout",
+			"message": "The query took longer than the configured timeout; narrow the date range or use a coarser aggregation",
 		})
-		return
+		return true
+	case errors.Is(err, context.Canceled):
+		logger.Info("request cancelled by the client",
+			"farm_id", farmID,
+			"path", ctx.FullPath(),
+		)
+		ctx.AbortWithStatus(statusClientClosedRequest)
+		return true
 	}
+	return false
+}
 
-	// Check if farm exists
-	farmExists, err := c.analyticsService.FarmExists(uint(farmID))
+// writeBodyTooLarge writes a 413 response when err comes from a body cut off by
+// middleware.BodyLimitMiddleware, reporting whether it did
+func writeBodyTooLarge(ctx *gin.Context, logger *slog.Logger, farmID uint, err error) bool {
+	var maxErr *http.MaxBytesError
+	if !errors.As(err, &maxErr) {
+		return false
+	}
+	logger.Warn("request body too large",
+		"farm_id", farmID,
+		"path", ctx.FullPath(),
+		"max_bytes", maxErr.Limit,
+	)
+	middleware.AbortBodyTooLarge(ctx, maxErr.Limit)
+	return true
+}
+
+// farmChecker is implemented by services that can confirm a farm exists
+type farmChecker interface {
+	FarmExists(farmID uint) (bool, error)
+}
+
+// ensureFarmExists checks that the farm exists, writing a 404 or 500 response when it can't be confirmed
+func ensureFarmExists(ctx *gin.Context, logger *slog.Logger, checker farmChecker, farmID uint, startTime time.Time) bool {
+	farmExists, err := checker.FarmExists(farmID)
 	if err != nil {
 		latency := time.Since(startTime)
-		c.logger.Error("failed to check farm existence",
+		logger.Error("failed to check farm existence",
 			"farm_id", farmID,
 			"error", err.Error(),
 			"latency_ms", latency.Milliseconds(),
@@ -149,11 +892,11 @@ func (c *AnalyticsController) GetIrrigationAnalytics(ctx *gin.Context) {
 			"error":   "Internal server error",
 			"message": "Failed to verify farm existence",
 		})
-		return
+		return false
 	}
 	if !farmExists {
 		latency := time.Since(startTime)
-		c.logger.Warn("farm not found",
+		logger.Warn("farm not found",
 			"farm_id", farmID,
 			"latency_ms", latency.Milliseconds(),
 		)
@@ -161,54 +904,9 @@ func (c *AnalyticsController) GetIrrigationAnalytics(ctx *gin.Context) {
 			"error":   "Farm not found",
 			"message": fmt.Sprintf("Farm with ID %d does not exist", farmID),
 		})
-		return
-	}
-
-	// Log query parameters
-	c.logger.Info("processing analytics request",
-		"farm_id", farmID,
-		"sector_id", sectorID,
-		"start_date", startDate.Format(time.RFC3339),
-		"end_date", endDate.Format(time.RFC3339),
-		"aggregation", aggregation,
-	)
-
-	// Call service
-	analytics, err := c.analyticsService.GetIrrigationAnalytics(
-		uint(farmID),
-		sectorID,
-		startDate,
-		endDate,
-		aggregation,
-	)
-	if err != nil {
-		latency := time.Since(startTime)
-		c.logger.Error("failed to retrieve analytics",
-			"farm_id", farmID,
-			"sector_id", sectorID,
-			"start_date", startDate.Format(time.RFC3339),
-			"end_date", endDate.Format(time.RFC3339),
-			"aggregation", aggregation,
-			"error", err.Error(),
-			"latency_ms", latency.Milliseconds(),
-		)
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Internal server error",
-			"message": "Failed to retrieve analytics data",
-		})
-		return
+		return false
 	}
-
-	latency := time.Since(startTime)
-	c.logger.Info("analytics request completed",
-		"farm_id", farmID,
-		"sector_id", sectorID,
-		"aggregation", aggregation,
-		"data_points", len(analytics.Data),
-		"latency_ms", latency.Milliseconds(),
-	)
-
-	ctx.JSON(http.StatusOK, analytics)
+	return true
 }
 
 // parseISO8601Date parses a date string in ISO 8601 format (RFC3339 is ISO 8601 compliant)
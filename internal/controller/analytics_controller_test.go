@@ -1,9 +1,13 @@
 package controller
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,17 +19,58 @@ import (
 
 // mockAnalyticsService is a mock implementation of AnalyticsService for testing
 type mockAnalyticsService struct {
-	analytics *service.AnalyticsResponse
-	err       error
+	analytics     *service.AnalyticsResponse
+	err           error
+	summaryCalled bool
+	opts          service.AnalyticsOptions
+	sectorID      *uint
+	aggregation   string
+	ctx           context.Context
+	farmCheckCtx  context.Context
 }
 
-func (m *mockAnalyticsService) GetIrrigationAnalytics(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string) (*service.AnalyticsResponse, error) {
+func (m *mockAnalyticsService) WithContext(ctx context.Context) service.AnalyticsService {
+	m.ctx = ctx
+	return m
+}
+
+func (m *mockAnalyticsService) FarmExists(farmID uint) (bool, error) {
+	m.farmCheckCtx = m.ctx
+	return true, nil
+}
+
+func (m *mockAnalyticsService) GetIrrigationAnalytics(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string, opts service.AnalyticsOptions) (*service.AnalyticsResponse, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.opts = opts
+	m.sectorID = sectorID
+	m.aggregation = aggregation
+	return m.analytics, nil
+}
+
+func (m *mockAnalyticsService) GetIrrigationSummary(farmID uint, sectorID *uint, startDate, endDate time.Time, opts service.AnalyticsOptions) (*service.AnalyticsResponse, error) {
 	if m.err != nil {
 		return nil, m.err
 	}
+	m.summaryCalled = true
 	return m.analytics, nil
 }
 
+func (m *mockAnalyticsService) GetTopContributors(farmID uint, startDate, endDate time.Time, dimension, baseline string, limit int) (*service.ContributorsResponse, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &service.ContributorsResponse{FarmID: farmID, Dimension: dimension, Baseline: baseline}, nil
+}
+
+func (m *mockAnalyticsService) GetTimeSeries(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string, metrics []string) (*service.TimeSeriesResponse, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &service.TimeSeriesResponse{FarmID: farmID, Aggregation: aggregation, Series: map[string][]*float64{}}, nil
+}
+
 func setupRouter(controller *AnalyticsController) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -34,6 +79,10 @@ func setupRouter(controller *AnalyticsController) *gin.Engine {
 		farms := v1.Group("/farms")
 		{
 			farms.GET("/:farm_id/irrigation/analytics", controller.GetIrrigationAnalytics)
+			farms.GET("/:farm_id/sectors/:sector_id/irrigation/analytics", controller.GetSectorAnalytics)
+			farms.GET("/:farm_id/irrigation/contributors", controller.GetTopContributors)
+			farms.GET("/:farm_id/timeseries", controller.GetTimeSeries)
+			farms.GET("/:farm_id/irrigation/report", controller.GetIrrigationReport)
 		}
 	}
 	return r
@@ -226,6 +275,40 @@ func TestGetIrrigationAnalytics_WithSectorID(t *testing.T) {
 	}
 }
 
+func TestGetSectorAnalytics(t *testing.T) {
+	mockService := &mockAnalyticsService{
+		analytics: &service.AnalyticsResponse{FarmID: 1, SectorID: uintPtr(2), Aggregation: "daily"},
+	}
+
+	logger := slog.Default()
+	controller := NewAnalyticsController(mockService, logger)
+	router := setupRouter(controller)
+
+	req, _ := http.NewRequest("GET", "/v1/farms/1/sectors/2/irrigation/analytics?start_date=2024-01-01&end_date=2024-01-31", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if mockService.sectorID == nil || *mockService.sectorID != 2 || !mockService.opts.SectorScope {
+		t.Errorf("Expected a sector-scoped request for sector 2, got %v with %+v", mockService.sectorID, mockService.opts)
+	}
+
+	for path, expected := range map[string]int{
+		"/v1/farms/1/sectors/abc/irrigation/analytics?start_date=2024-01-01&end_date=2024-01-31": http.StatusBadRequest,
+		"/v1/farms/1/sectors/9/irrigation/analytics?start_date=2024-01-01&end_date=2024-01-31":   http.StatusNotFound,
+	} {
+		mockService.err = service.ErrSectorNotFound
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != expected {
+			t.Errorf("%s: expected status code %d, got %d", path, expected, w.Code)
+		}
+	}
+}
+
 func TestGetIrrigationAnalytics_InvalidSectorID(t *testing.T) {
 	mockService := &mockAnalyticsService{}
 	logger := slog.Default()
@@ -306,3 +389,589 @@ func uintPtr(u uint) *uint {
 	return &u
 }
 
+func TestGetIrrigationAnalytics_SummaryOnly(t *testing.T) {
+	mockService := &mockAnalyticsService{
+		analytics: &service.AnalyticsResponse{
+			FarmID:      1,
+			Aggregation: "summary",
+			Data:        []service.AggregatedDataPoint{},
+			Summary:     service.AnalyticsSummary{TotalWaterVolume: 250.0, TotalEvents: 3},
+		},
+	}
+
+	logger := slog.Default()
+	controller := NewAnalyticsController(mockService, logger)
+	router := setupRouter(controller)
+
+	req, _ := http.NewRequest("GET", "/v1/farms/1/irrigation/analytics?start_date=2024-01-01&end_date=2024-01-31&summary_only=true", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if !mockService.summaryCalled {
+		t.Error("Expected summary fast path to be used")
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/farms/1/irrigation/analytics?start_date=2024-01-01&end_date=2024-01-31&summary_only=maybe", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for invalid summary_only, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestGetIrrigationAnalytics_EfficiencyWeighting(t *testing.T) {
+	mockService := &mockAnalyticsService{
+		analytics: &service.AnalyticsResponse{FarmID: 1, Data: []service.AggregatedDataPoint{}},
+	}
+
+	logger := slog.Default()
+	controller := NewAnalyticsController(mockService, logger)
+	router := setupRouter(controller)
+
+	req, _ := http.NewRequest("GET", "/v1/farms/1/irrigation/analytics?start_date=2024-01-01&end_date=2024-01-31&efficiency_weighting=volume", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if mockService.opts.EfficiencyWeighting != service.EfficiencyWeightingVolume {
+		t.Errorf("Expected volume weighting to be passed to service, got %q", mockService.opts.EfficiencyWeighting)
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/farms/1/irrigation/analytics?start_date=2024-01-01&end_date=2024-01-31&efficiency_weighting=median", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for invalid efficiency_weighting, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestGetIrrigationAnalytics_SchemaVersion(t *testing.T) {
+	tests := []struct {
+		name              string
+		query             string
+		accept            string
+		expectedCode      int
+		expectedVersion   string
+		expectedWeighting string
+	}{
+		{"default", "", "", http.StatusOK, service.SchemaV1, service.EfficiencyWeightingMean},
+		{"query parameter", "&api_version=v2", "", http.StatusOK, service.SchemaV2, service.EfficiencyWeightingVolume},
+		{"vendor media type", "", "application/vnd.irrigation-analytics.v2+json", http.StatusOK, service.SchemaV2, service.EfficiencyWeightingVolume},
+		{"version parameter", "", "application/json; version=2", http.StatusOK, service.SchemaV2, service.EfficiencyWeightingVolume},
+		{"query wins over header", "&api_version=v1", "application/vnd.irrigation-analytics.v2+json", http.StatusOK, service.SchemaV1, service.EfficiencyWeightingMean},
+		{"explicit weighting wins", "&api_version=v2&efficiency_weighting=mean", "", http.StatusOK, service.SchemaV2, service.EfficiencyWeightingMean},
+		{"unknown version", "&api_version=v9", "", http.StatusNotAcceptable, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockAnalyticsService{
+				analytics: &service.AnalyticsResponse{FarmID: 1, Data: []service.AggregatedDataPoint{}},
+			}
+			router := setupRouter(NewAnalyticsController(mockService, slog.Default()))
+
+			req, _ := http.NewRequest("GET", "/v1/farms/1/irrigation/analytics?start_date=2024-01-01&end_date=2024-01-31"+tt.query, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("Expected status code %d, got %d", tt.expectedCode, w.Code)
+			}
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+			if got := w.Header().Get("X-API-Version"); got != tt.expectedVersion {
+				t.Errorf("Expected X-API-Version %q, got %q", tt.expectedVersion, got)
+			}
+			if mockService.opts.EfficiencyWeighting != tt.expectedWeighting {
+				t.Errorf("Expected %q weighting, got %q", tt.expectedWeighting, mockService.opts.EfficiencyWeighting)
+			}
+
+			var body map[string]any
+			json.Unmarshal(w.Body.Bytes(), &body)
+			if _, ok := body["schema_version"]; ok != (tt.expectedVersion == service.SchemaV2) {
+				t.Errorf("schema_version present = %v for %s", ok, tt.expectedVersion)
+			}
+		})
+	}
+}
+
+func TestGetIrrigationAnalytics_ExclusionFlags(t *testing.T) {
+	mockService := &mockAnalyticsService{
+		analytics: &service.AnalyticsResponse{FarmID: 1, Data: []service.AggregatedDataPoint{}},
+	}
+
+	logger := slog.Default()
+	controller := NewAnalyticsController(mockService, logger)
+	router := setupRouter(controller)
+
+	req, _ := http.NewRequest("GET", "/v1/farms/1/irrigation/analytics?start_date=2024-01-01&end_date=2024-01-31&exclude_seed=true&exclude_annotated=1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if !mockService.opts.ExcludeSeed || !mockService.opts.ExcludeAnnotated {
+		t.Errorf("Expected exclusion flags to be passed to service, got %+v", mockService.opts)
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/farms/1/irrigation/analytics?start_date=2024-01-01&end_date=2024-01-31&exclude_seed=demo", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for invalid exclude_seed, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestGetIrrigationAnalytics_Units(t *testing.T) {
+	mockService := &mockAnalyticsService{
+		analytics: &service.AnalyticsResponse{FarmID: 1, Data: []service.AggregatedDataPoint{}},
+	}
+
+	logger := slog.Default()
+	controller := NewAnalyticsController(mockService, logger)
+	router := setupRouter(controller)
+
+	req, _ := http.NewRequest("GET", "/v1/farms/1/irrigation/analytics?start_date=2024-01-01&end_date=2024-01-31&units=imperial", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if mockService.opts.Units != service.UnitsImperial {
+		t.Errorf("Expected imperial units to be passed to service, got %q", mockService.opts.Units)
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/farms/1/irrigation/analytics?start_date=2024-01-01&end_date=2024-01-31&units=furlongs", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for invalid units, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestGetIrrigationAnalytics_DeviceFilter(t *testing.T) {
+	mockService := &mockAnalyticsService{
+		analytics: &service.AnalyticsResponse{FarmID: 1, Data: []service.AggregatedDataPoint{}},
+	}
+
+	logger := slog.Default()
+	controller := NewAnalyticsController(mockService, logger)
+	router := setupRouter(controller)
+
+	req, _ := http.NewRequest("GET", "/v1/farms/1/irrigation/analytics?start_date=2024-01-01&end_date=2024-01-31&device_id=7", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if mockService.opts.DeviceID == nil || *mockService.opts.DeviceID != 7 {
+		t.Errorf("Expected device 7 to be passed to service, got %v", mockService.opts.DeviceID)
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/farms/1/irrigation/analytics?start_date=2024-01-01&end_date=2024-01-31&device_id=meter", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for invalid device_id, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestGetIrrigationAnalytics_CompareTo(t *testing.T) {
+	mockService := &mockAnalyticsService{
+		analytics: &service.AnalyticsResponse{FarmID: 1, Data: []service.AggregatedDataPoint{}},
+	}
+
+	logger := slog.Default()
+	controller := NewAnalyticsController(mockService, logger)
+	router := setupRouter(controller)
+
+	req, _ := http.NewRequest("GET", "/v1/farms/1/irrigation/analytics?start_date=2024-04-01&end_date=2024-07-01&compare_to=previous_period,-3y,2021-04-01/2021-07-01", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	compareTo := mockService.opts.CompareTo
+	if len(compareTo) != 3 {
+		t.Fatalf("Expected 3 comparison periods, got %+v", compareTo)
+	}
+	if !compareTo[0].StartDate.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || !compareTo[0].EndDate.Equal(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the 91 days before the period, got %+v", compareTo[0])
+	}
+	if compareTo[1].YearsBack != 3 || compareTo[2].Label != "2021-04-01/2021-07-01" || compareTo[2].YearsBack != 0 {
+		t.Errorf("Unexpected comparison periods %+v", compareTo[1:])
+	}
+
+	for _, compareTo := range []string{"last_year", "-0y", "-11y", "2021-07-01/2021-04-01", "-1y,-2y,-3y,-4y,-5y,-6y"} {
+		req, _ = http.NewRequest("GET", "/v1/farms/1/irrigation/analytics?start_date=2024-04-01&end_date=2024-07-01&compare_to="+compareTo, nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d for compare_to=%s, got %d", http.StatusBadRequest, compareTo, w.Code)
+		}
+	}
+}
+
+func TestGetIrrigationAnalytics_Omit(t *testing.T) {
+	mockService := &mockAnalyticsService{
+		analytics: &service.AnalyticsResponse{FarmID: 1, Data: []service.AggregatedDataPoint{}},
+	}
+
+	logger := slog.Default()
+	controller := NewAnalyticsController(mockService, logger)
+	router := setupRouter(controller)
+
+	req, _ := http.NewRequest("GET", "/v1/farms/1/irrigation/analytics?start_date=2024-04-01&end_date=2024-07-01&omit=comparisons,%20sector_breakdown", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if omit := mockService.opts.Omit; len(omit) != 2 || omit[0] != service.SectionComparisons || omit[1] != service.SectionSectorBreakdown {
+		t.Errorf("Expected comparisons and sector_breakdown to be omitted, got %v", omit)
+	}
+
+	for _, query := range []string{"omit=year_over_year", "omit=data,", "omit=comparisons&compare_to=-3y"} {
+		req, _ = http.NewRequest("GET", "/v1/farms/1/irrigation/analytics?start_date=2024-04-01&end_date=2024-07-01&"+query, nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d for %s, got %d", http.StatusBadRequest, query, w.Code)
+		}
+	}
+}
+
+func TestGetIrrigationAnalytics_HourlyAggregation(t *testing.T) {
+	mockService := &mockAnalyticsService{
+		analytics: &service.AnalyticsResponse{FarmID: 1, Aggregation: "hourly", Data: []service.AggregatedDataPoint{}},
+	}
+
+	logger := slog.Default()
+	controller := NewAnalyticsController(mockService, logger)
+	router := setupRouter(controller)
+
+	req, _ := http.NewRequest("GET", "/v1/farms/1/irrigation/analytics?start_date=2024-01-01&end_date=2024-01-08&aggregation=hourly", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/farms/1/irrigation/analytics?start_date=2024-01-01&end_date=2024-03-01&aggregation=hourly", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for hourly range over 31 days, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestGetIrrigationAnalytics_Debug(t *testing.T) {
+	mockService := &mockAnalyticsService{
+		analytics: &service.AnalyticsResponse{FarmID: 1, Data: []service.AggregatedDataPoint{}},
+	}
+
+	logger := slog.Default()
+	controller := NewAnalyticsController(mockService, logger)
+	router := setupRouter(controller)
+	url := "/v1/farms/1/irrigation/analytics?start_date=2024-01-01&end_date=2024-01-31&debug=true"
+
+	// Debug capture is disabled until an admin token is configured
+	req, _ := http.NewRequest("GET", url, nil)
+	req.Header.Set("X-Admin-Token", "")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status code %d without a configured token, got %d", http.StatusForbidden, w.Code)
+	}
+
+	controller.EnableDebugCapture("s3cret")
+
+	req, _ = http.NewRequest("GET", url, nil)
+	req.Header.Set("X-Admin-Token", "wrong")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status code %d for a wrong token, got %d", http.StatusForbidden, w.Code)
+	}
+
+	req, _ = http.NewRequest("GET", url, nil)
+	req.Header.Set("X-Admin-Token", "s3cret")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if !mockService.opts.Debug {
+		t.Error("Expected debug to be passed to service")
+	}
+}
+
+func TestGetIrrigationAnalytics_IncludeDeleted(t *testing.T) {
+	mockService := &mockAnalyticsService{
+		analytics: &service.AnalyticsResponse{FarmID: 1, Data: []service.AggregatedDataPoint{}},
+	}
+
+	logger := slog.Default()
+	controller := NewAnalyticsController(mockService, logger)
+	controller.EnableDebugCapture("s3cret")
+	router := setupRouter(controller)
+	url := "/v1/farms/1/irrigation/analytics?start_date=2024-01-01&end_date=2024-01-31&include_deleted=true"
+
+	req, _ := http.NewRequest("GET", url, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status code %d without the admin token, got %d", http.StatusForbidden, w.Code)
+	}
+
+	req, _ = http.NewRequest("GET", url, nil)
+	req.Header.Set("X-Admin-Token", "s3cret")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if !mockService.opts.IncludeDeleted {
+		t.Error("Expected include_deleted to be passed to service")
+	}
+}
+
+func TestGetIrrigationAnalytics_BudgetExceeded(t *testing.T) {
+	mockService := &mockAnalyticsService{
+		err: &service.BudgetError{Limit: "bucket_cost", Max: 12000, Estimated: 32880, Hint: "use a coarser aggregation or a shorter date range"},
+	}
+
+	logger := slog.Default()
+	controller := NewAnalyticsController(mockService, logger)
+	router := setupRouter(controller)
+
+	req, _ := http.NewRequest("GET", "/v1/farms/1/irrigation/analytics?start_date=1995-01-01&end_date=2024-12-31", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status code %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+	var body struct {
+		Budget service.BudgetError `json:"budget"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if body.Budget.Limit != "bucket_cost" || body.Budget.Estimated != 32880 {
+		t.Errorf("Expected structured budget details, got %+v", body.Budget)
+	}
+}
+
+func TestGetIrrigationAnalytics_StrictSectionFailure(t *testing.T) {
+	mockService := &mockAnalyticsService{
+		err: &service.SectionError{Section: service.SectionSectorBreakdown, Err: errors.New("connection reset")},
+	}
+
+	logger := slog.Default()
+	controller := NewAnalyticsController(mockService, logger)
+	router := setupRouter(controller)
+
+	req, _ := http.NewRequest("GET", "/v1/farms/1/irrigation/analytics?start_date=2024-01-01&end_date=2024-01-31&strict=true", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("Expected status code %d, got %d", http.StatusBadGateway, w.Code)
+	}
+	if strings.Contains(w.Body.String(), "connection reset") {
+		t.Errorf("Expected the underlying error to stay out of the response, got %s", w.Body.String())
+	}
+}
+
+func TestGetIrrigationAnalytics_Purpose(t *testing.T) {
+	mockService := &mockAnalyticsService{
+		analytics: &service.AnalyticsResponse{FarmID: 1, Data: []service.AggregatedDataPoint{}},
+	}
+
+	logger := slog.Default()
+	controller := NewAnalyticsController(mockService, logger)
+	router := setupRouter(controller)
+
+	req, _ := http.NewRequest("GET", "/v1/farms/1/irrigation/analytics?start_date=2024-01-01&end_date=2024-01-31&purpose=irrigation,%20leaching&breakdown=purpose", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if len(mockService.opts.Purposes) != 2 || mockService.opts.Purposes[1] != "leaching" || !mockService.opts.BreakdownByPurpose {
+		t.Errorf("Expected purposes and breakdown to be passed to service, got %+v", mockService.opts)
+	}
+
+	for _, query := range []string{"purpose=watering", "breakdown=device"} {
+		req, _ = http.NewRequest("GET", "/v1/farms/1/irrigation/analytics?start_date=2024-01-01&end_date=2024-01-31&"+query, nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d for %s, got %d", http.StatusBadRequest, query, w.Code)
+		}
+	}
+}
+
+func TestGetTopContributors_Validation(t *testing.T) {
+	logger := slog.Default()
+	controller := NewAnalyticsController(&mockAnalyticsService{}, logger)
+	router := setupRouter(controller)
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedCode int
+	}{
+		{"defaults", "start_date=2025-01-01&end_date=2025-01-31", http.StatusOK},
+		{"day dimension with previous period", "start_date=2025-01-01&end_date=2025-01-31&dimension=day&baseline=previous_period", http.StatusOK},
+		{"invalid dimension", "start_date=2025-01-01&end_date=2025-01-31&dimension=hour", http.StatusBadRequest},
+		{"invalid baseline", "start_date=2025-01-01&end_date=2025-01-31&baseline=last_week", http.StatusBadRequest},
+		{"limit too large", "start_date=2025-01-01&end_date=2025-01-31&limit=500", http.StatusBadRequest},
+		{"missing start_date", "end_date=2025-01-31", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/v1/farms/1/irrigation/contributors?"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status code %d, got %d", tt.expectedCode, w.Code)
+			}
+		})
+	}
+}
+
+func TestGetTimeSeries_Validation(t *testing.T) {
+	logger := slog.Default()
+	controller := NewAnalyticsController(&mockAnalyticsService{}, logger)
+	router := setupRouter(controller)
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedCode int
+	}{
+		{"irrigation metrics", "start_date=2025-01-01&end_date=2025-01-31&metrics=volume,efficiency", http.StatusOK},
+		{"mixed sources", "start_date=2025-01-01&end_date=2025-01-31&metrics=volume,avg_pressure&aggregation=weekly", http.StatusOK},
+		{"missing metrics", "start_date=2025-01-01&end_date=2025-01-31", http.StatusBadRequest},
+		{"unknown metric", "start_date=2025-01-01&end_date=2025-01-31&metrics=volume,soil_moisture", http.StatusBadRequest},
+		{"invalid aggregation", "start_date=2025-01-01&end_date=2025-01-31&metrics=volume&aggregation=fortnightly", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/v1/farms/1/timeseries?"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status code %d, got %d", tt.expectedCode, w.Code)
+			}
+		})
+	}
+}
+
+func TestGetIrrigationReport(t *testing.T) {
+	mockService := &mockAnalyticsService{analytics: &service.AnalyticsResponse{FarmID: 1}}
+	controller := NewAnalyticsController(mockService, slog.Default())
+	router := setupRouter(controller)
+
+	tests := []struct {
+		name                string
+		query               string
+		expectedCode        int
+		expectedAggregation string
+	}{
+		{"month", "month=2025-06", http.StatusOK, "daily"},
+		{"explicit pdf format", "format=pdf&month=2025-06", http.StatusOK, "daily"},
+		{"season", "start_date=2025-03-01&end_date=2025-07-01", http.StatusOK, "weekly"},
+		{"year", "start_date=2025-01-01&end_date=2026-01-01", http.StatusOK, "monthly"},
+		{"unsupported format", "format=docx&month=2025-06", http.StatusBadRequest, ""},
+		{"invalid month", "month=2025-13", http.StatusBadRequest, ""},
+		{"month with dates", "month=2025-06&start_date=2025-06-01", http.StatusBadRequest, ""},
+		{"missing range", "", http.StatusBadRequest, ""},
+		{"range too long", "start_date=2024-01-01&end_date=2025-06-01", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService.aggregation = ""
+			req, _ := http.NewRequest("GET", "/v1/farms/1/irrigation/report?"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedCode, w.Code, w.Body.String())
+			}
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != "application/pdf" {
+				t.Errorf("Expected application/pdf, got %q", contentType)
+			}
+			if !strings.HasPrefix(w.Body.String(), "%PDF-") {
+				t.Error("Expected the body to be a PDF document")
+			}
+			if mockService.aggregation != tt.expectedAggregation || !mockService.opts.FillGaps {
+				t.Errorf("Expected %s buckets with gaps filled, got %q (fill_gaps %v)", tt.expectedAggregation, mockService.aggregation, mockService.opts.FillGaps)
+			}
+		})
+	}
+}
+
+func TestGetIrrigationAnalytics_QueryCancelled(t *testing.T) {
+	mockService := &mockAnalyticsService{err: fmt.Errorf("comparison query: %w", context.DeadlineExceeded)}
+
+	logger := slog.Default()
+	controller := NewAnalyticsController(mockService, logger)
+	router := setupRouter(controller)
+
+	req, _ := http.NewRequest("GET", "/v1/farms/1/irrigation/analytics?start_date=2024-01-01&end_date=2024-01-31", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected status code %d for a timed out query, got %d", http.StatusGatewayTimeout, w.Code)
+	}
+	if mockService.ctx == nil {
+		t.Error("Expected the service bound to the request context")
+	}
+	if mockService.farmCheckCtx == nil {
+		t.Error("Expected the farm check to run under the request context")
+	}
+
+	mockService.err = context.Canceled
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != statusClientClosedRequest {
+		t.Errorf("Expected status code %d for a cancelled request, got %d", statusClientClosedRequest, w.Code)
+	}
+}
@@ -0,0 +1,231 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAnnotationLabelLength matches the size of the label column
+const maxAnnotationLabelLength = 255
+
+// AnnotationController handles event and day annotation HTTP requests
+type AnnotationController struct {
+	annotationService service.AnnotationService
+	logger            *slog.Logger
+}
+
+// NewAnnotationController creates a new annotation controller
+func NewAnnotationController(annotationService service.AnnotationService, logger *slog.Logger) *AnnotationController {
+	return &AnnotationController{
+		annotationService: annotationService,
+		logger:            logger,
+	}
+}
+
+// createAnnotationRequest is the body of an annotation create request
+type createAnnotationRequest struct {
+	EventID               *uint  `json:"event_id"`
+	Date                  string `json:"date"`
+	SectorID              *uint  `json:"sector_id"`
+	Label                 string `json:"label"`
+	Note                  string `json:"note"`
+	ExcludeFromEfficiency bool   `json:"exclude_from_efficiency"`
+}
+
+// CreateAnnotation handles POST /v1/farms/{farm_id}/irrigation/annotations
+// Body fields:
+//   - event_id or date (exactly one required): the event, or the day (ISO 8601), being annotated
+//   - sector_id (optional, day annotations only): limit the annotation to one sector
+//   - label (required): short description such as "pipe burst" or "flush cycle"
+//   - note (optional): free-form details
+//   - exclude_from_efficiency (optional): leave covered events out of efficiency when requested
+func (c *AnnotationController) CreateAnnotation(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	var req createAnnotationRequest
+	err := ctx.ShouldBindJSON(&req)
+	if writeBodyTooLarge(ctx, c.logger, farmID, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": "body must be a JSON annotation object",
+		})
+		return
+	}
+
+	input, errMessage := req.toInput()
+	if errMessage != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid annotation",
+			"message": errMessage,
+		})
+		return
+	}
+
+	if !ensureFarmExists(ctx, c.logger, c.annotationService, farmID, startTime) {
+		return
+	}
+
+	annotation, err := c.annotationService.CreateAnnotation(farmID, input)
+	if errors.Is(err, service.ErrEventNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Event not found",
+			"message": fmt.Sprintf("Event with ID %d does not exist for farm %d", *input.EventID, farmID),
+		})
+		return
+	}
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to create annotation",
+			"farm_id", farmID,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to create annotation",
+		})
+		return
+	}
+
+	c.logger.Info("annotation created",
+		"farm_id", farmID,
+		"annotation_id", annotation.ID,
+		"label", annotation.Label,
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusCreated, annotation)
+}
+
+// toInput validates the request, returning a message describing the first problem found
+func (r createAnnotationRequest) toInput() (service.AnnotationInput, string) {
+	label := strings.TrimSpace(r.Label)
+	if label == "" {
+		return service.AnnotationInput{}, "label is required"
+	}
+	if len(label) > maxAnnotationLabelLength {
+		return service.AnnotationInput{}, "label must be at most 255 characters"
+	}
+	if (r.EventID == nil) == (r.Date == "") {
+		return service.AnnotationInput{}, "exactly one of event_id or date is required"
+	}
+	if r.EventID != nil && r.SectorID != nil {
+		return service.AnnotationInput{}, "sector_id can only be set on day annotations"
+	}
+
+	input := service.AnnotationInput{
+		EventID:               r.EventID,
+		SectorID:              r.SectorID,
+		Label:                 label,
+		Note:                  r.Note,
+		ExcludeFromEfficiency: r.ExcludeFromEfficiency,
+	}
+	if r.Date != "" {
+		date, err := parseISO8601Date(r.Date)
+		if err != nil {
+			return service.AnnotationInput{}, "date must be in ISO 8601 format (RFC3339 or YYYY-MM-DD)"
+		}
+		input.Date = &date
+	}
+	return input, ""
+}
+
+// ListAnnotations handles GET /v1/farms/{farm_id}/irrigation/annotations
+// Query parameters:
+//   - start_date (required): Start date in ISO 8601 format (RFC3339 or YYYY-MM-DD)
+//   - end_date (required): End date in ISO 8601 format (RFC3339 or YYYY-MM-DD)
+func (c *AnnotationController) ListAnnotations(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	startDate, endDate, ok := parseDateRange(ctx, c.logger, farmID)
+	if !ok {
+		return
+	}
+
+	if !ensureFarmExists(ctx, c.logger, c.annotationService, farmID, startTime) {
+		return
+	}
+
+	annotations, err := c.annotationService.ListAnnotations(farmID, startDate, endDate)
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to list annotations",
+			"farm_id", farmID,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to list annotations",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"farm_id":     farmID,
+		"annotations": annotations,
+	})
+}
+
+// DeleteAnnotation handles DELETE /v1/farms/{farm_id}/irrigation/annotations/{annotation_id}
+func (c *AnnotationController) DeleteAnnotation(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	annotationID, err := strconv.ParseUint(ctx.Param("annotation_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid annotation_id",
+			"message": "annotation_id must be a valid unsigned integer",
+		})
+		return
+	}
+
+	deleted, err := c.annotationService.DeleteAnnotation(farmID, uint(annotationID))
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to delete annotation",
+			"farm_id", farmID,
+			"annotation_id", annotationID,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to delete annotation",
+		})
+		return
+	}
+	if !deleted {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Annotation not found",
+			"message": fmt.Sprintf("Annotation with ID %d does not exist for farm %d", annotationID, farmID),
+		})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"log/slog"
+)
+
+// mockAnnotationService is a mock implementation of AnnotationService for testing
+type mockAnnotationService struct {
+	created *service.AnnotationInput
+}
+
+func (m *mockAnnotationService) FarmExists(farmID uint) (bool, error) {
+	return true, nil
+}
+
+func (m *mockAnnotationService) CreateAnnotation(farmID uint, input service.AnnotationInput) (*model.Annotation, error) {
+	if input.EventID != nil && *input.EventID == 404 {
+		return nil, service.ErrEventNotFound
+	}
+	m.created = &input
+	return &model.Annotation{ID: 1, FarmID: farmID, Label: input.Label}, nil
+}
+
+func (m *mockAnnotationService) ListAnnotations(farmID uint, startDate, endDate time.Time) ([]model.Annotation, error) {
+	return []model.Annotation{}, nil
+}
+
+func (m *mockAnnotationService) DeleteAnnotation(farmID, annotationID uint) (bool, error) {
+	return annotationID == 1, nil
+}
+
+func setupAnnotationRouter(controller *AnnotationController) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	farms := r.Group("/v1/farms")
+	farms.POST("/:farm_id/irrigation/annotations", controller.CreateAnnotation)
+	farms.GET("/:farm_id/irrigation/annotations", controller.ListAnnotations)
+	farms.DELETE("/:farm_id/irrigation/annotations/:annotation_id", controller.DeleteAnnotation)
+	return r
+}
+
+func TestCreateAnnotation_Validation(t *testing.T) {
+	router := setupAnnotationRouter(NewAnnotationController(&mockAnnotationService{}, slog.Default()))
+
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+	}{
+		{"day annotation", `{"date":"2025-03-12","label":"flush cycle","exclude_from_efficiency":true}`, http.StatusCreated},
+		{"event annotation", `{"event_id":7,"label":"pipe burst"}`, http.StatusCreated},
+		{"unknown event", `{"event_id":404,"label":"pipe burst"}`, http.StatusNotFound},
+		{"missing label", `{"date":"2025-03-12"}`, http.StatusBadRequest},
+		{"event and date", `{"event_id":7,"date":"2025-03-12","label":"pipe burst"}`, http.StatusBadRequest},
+		{"neither event nor date", `{"label":"pipe burst"}`, http.StatusBadRequest},
+		{"sector on event annotation", `{"event_id":7,"sector_id":2,"label":"pipe burst"}`, http.StatusBadRequest},
+		{"invalid date", `{"date":"12/03/2025","label":"pipe burst"}`, http.StatusBadRequest},
+		{"malformed body", `{"label":`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/v1/farms/1/irrigation/annotations", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status code %d, got %d (%s)", tt.expectedCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestDeleteAnnotation(t *testing.T) {
+	router := setupAnnotationRouter(NewAnnotationController(&mockAnnotationService{}, slog.Default()))
+
+	req, _ := http.NewRequest("DELETE", "/v1/farms/1/irrigation/annotations/1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status code %d, got %d", http.StatusNoContent, w.Code)
+	}
+
+	req, _ = http.NewRequest("DELETE", "/v1/farms/1/irrigation/annotations/2", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d for unknown annotation, got %d", http.StatusNotFound, w.Code)
+	}
+}
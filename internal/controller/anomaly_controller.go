@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBaselineDays bounds the baseline window of an anomaly request
+const maxBaselineDays = 730
+
+// AnomalyController handles anomaly detection HTTP requests
+type AnomalyController struct {
+	anomalyService service.AnomalyService
+	logger         *slog.Logger
+}
+
+// NewAnomalyController creates a new anomaly controller
+func NewAnomalyController(anomalyService service.AnomalyService, logger *slog.Logger) *AnomalyController {
+	return &AnomalyController{
+		anomalyService: anomalyService,
+		logger:         logger,
+	}
+}
+
+// GetAnomalies handles GET /v1/farms/{farm_id}/irrigation/anomalies
+// Query parameters:
+//   - start_date (required): Start date in ISO 8601 format (RFC3339 or YYYY-MM-DD)
+//   - end_date (required): End date in ISO 8601 format (RFC3339 or YYYY-MM-DD)
+//   - sector_id (optional): Filter by sector ID
+//   - aggregation (optional): hourly, daily, weekly, monthly, or custom with bucket_days (default: daily)
+//   - method (optional): zscore or iqr (default: zscore)
+//   - threshold (optional): z-score or IQR multiplier (default: 3 for zscore, 1.5 for iqr)
+//   - baseline_days (optional): days before start_date used as each sector's baseline, 7-730 (default: 90)
+func (c *AnomalyController) GetAnomalies(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	sectorID, ok := parseSectorID(ctx, c.logger, farmID)
+	if !ok {
+		return
+	}
+
+	startDate, endDate, ok := parseDateRange(ctx, c.logger, farmID)
+	if !ok {
+		return
+	}
+
+	aggregation, ok := parseAggregation(ctx)
+	if !ok {
+		return
+	}
+
+	method := ctx.DefaultQuery("method", service.AnomalyMethodZScore)
+	threshold := 3.0
+	switch method {
+	case service.AnomalyMethodZScore:
+	case service.AnomalyMethodIQR:
+		threshold = 1.5
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid method",
+			"message": "method must be one of: zscore, iqr",
+		})
+		return
+	}
+
+	if thresholdStr := ctx.Query("threshold"); thresholdStr != "" {
+		parsed, err := strconv.ParseFloat(thresholdStr, 64)
+		if err != nil || parsed <= 0 || parsed > 100 {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid threshold",
+				"message": "threshold must be a positive number up to 100",
+			})
+			return
+		}
+		threshold = parsed
+	}
+
+	baselineDays, err := strconv.Atoi(ctx.DefaultQuery("baseline_days", "90"))
+	if err != nil || baselineDays < 7 || baselineDays > maxBaselineDays {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid baseline_days",
+			"message": "baseline_days must be an integer between 7 and 730",
+		})
+		return
+	}
+
+	if !ensureFarmExists(ctx, c.logger, c.anomalyService, farmID, startTime) {
+		return
+	}
+
+	opts := service.AnomalyOptions{
+		Aggregation:  aggregation,
+		Method:       method,
+		Threshold:    threshold,
+		BaselineDays: baselineDays,
+	}
+	anomalies, err := c.anomalyService.DetectAnomalies(farmID, sectorID, startDate, endDate, opts)
+	if writeBudgetError(ctx, c.logger, farmID, err) {
+		return
+	}
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to detect anomalies",
+			"farm_id", farmID,
+			"sector_id", sectorID,
+			"method", method,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to detect anomalies",
+		})
+		return
+	}
+
+	latency := time.Since(startTime)
+	c.logger.Info("anomaly request completed",
+		"farm_id", farmID,
+		"sector_id", sectorID,
+		"method", method,
+		"threshold", threshold,
+		"anomalies", len(anomalies.Anomalies),
+		"latency_ms", latency.Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusOK, anomalies)
+}
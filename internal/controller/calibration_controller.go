@@ -0,0 +1,246 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Calibration bounds matching the factor and offset column precision
+const (
+	maxCalibrationFactor = 10000
+	maxCalibrationOffset = 1e8
+)
+
+// CalibrationController handles ingestion calibration HTTP requests
+type CalibrationController struct {
+	calibrationService service.CalibrationService
+	logger             *slog.Logger
+}
+
+// NewCalibrationController creates a new calibration controller
+func NewCalibrationController(calibrationService service.CalibrationService, logger *slog.Logger) *CalibrationController {
+	return &CalibrationController{
+		calibrationService: calibrationService,
+		logger:             logger,
+	}
+}
+
+// calibrationRequest is the body of a calibration request
+type calibrationRequest struct {
+	Target     string   `json:"target"`
+	DataSource string   `json:"data_source"`
+	SectorID   *uint    `json:"sector_id"`
+	DeviceID   string   `json:"device_id"`
+	Factor     *float64 `json:"factor"`
+	Offset     float64  `json:"offset"`
+	Note       string   `json:"note"`
+}
+
+// SetCalibration handles POST /v1/farms/{farm_id}/calibrations
+// Body fields:
+//   - target (required): volume or pressure
+//   - data_source (required for volume): the data source whose events are corrected
+//   - sector_id (optional, volume only): limit the calibration to one sector's meter
+//   - device_id (required for pressure): the pressure sensor whose readings are corrected
+//   - factor (optional): multiplier applied to reported values, default 1
+//   - offset (optional): added after the factor, in liters per event or in bar, default 0
+//   - note (optional): free-form details such as the meter test it comes from
+//
+// A calibration with the same target and match replaces the existing one.
+func (c *CalibrationController) SetCalibration(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	var req calibrationRequest
+	err := ctx.ShouldBindJSON(&req)
+	if writeBodyTooLarge(ctx, c.logger, farmID, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": "body must be a JSON calibration object",
+		})
+		return
+	}
+
+	input, errMessage := req.toInput()
+	if errMessage != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid calibration",
+			"message": errMessage,
+		})
+		return
+	}
+
+	if !ensureFarmExists(ctx, c.logger, c.calibrationService, farmID, startTime) {
+		return
+	}
+
+	calibration, err := c.calibrationService.SetCalibration(farmID, input)
+	if errors.Is(err, service.ErrSectorNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Sector not found",
+			"message": fmt.Sprintf("Sector with ID %d does not exist for farm %d", *input.SectorID, farmID),
+		})
+		return
+	}
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to set calibration",
+			"farm_id", farmID,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to set calibration",
+		})
+		return
+	}
+
+	c.logger.Info("calibration set",
+		"farm_id", farmID,
+		"calibration_id", calibration.ID,
+		"target", calibration.Target,
+		"factor", calibration.Factor,
+		"offset", calibration.Offset,
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusCreated, calibration)
+}
+
+// toInput validates the request, returning a message describing the first problem found
+func (r calibrationRequest) toInput() (service.CalibrationInput, string) {
+	input := service.CalibrationInput{
+		Target:     r.Target,
+		DataSource: r.DataSource,
+		SectorID:   r.SectorID,
+		DeviceID:   strings.TrimSpace(r.DeviceID),
+		Factor:     1,
+		Offset:     r.Offset,
+		Note:       r.Note,
+	}
+	if r.Factor != nil {
+		input.Factor = *r.Factor
+	}
+
+	switch r.Target {
+	case model.CalibrationTargetVolume:
+		if !model.IsValidDataSource(r.DataSource) {
+			return input, "data_source must be one of seed, api, mqtt or import"
+		}
+		if input.DeviceID != "" {
+			return input, "device_id can only be set on pressure calibrations"
+		}
+	case model.CalibrationTargetPressure:
+		if input.DeviceID == "" || len(input.DeviceID) > 64 {
+			return input, "device_id is required and must be at most 64 characters"
+		}
+		if r.DataSource != "" || r.SectorID != nil {
+			return input, "data_source and sector_id can only be set on volume calibrations"
+		}
+	default:
+		return input, "target must be volume or pressure"
+	}
+
+	if !(input.Factor > 0 && input.Factor < maxCalibrationFactor) {
+		return input, fmt.Sprintf("factor must be greater than 0 and less than %d", maxCalibrationFactor)
+	}
+	if math.Abs(input.Offset) >= maxCalibrationOffset {
+		return input, "offset must be less than 100000000 in absolute value"
+	}
+	return input, ""
+}
+
+// ListCalibrations handles GET /v1/farms/{farm_id}/calibrations
+func (c *CalibrationController) ListCalibrations(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	if !ensureFarmExists(ctx, c.logger, c.calibrationService, farmID, startTime) {
+		return
+	}
+
+	calibrations, err := c.calibrationService.ListCalibrations(farmID)
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to list calibrations",
+			"farm_id", farmID,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to list calibrations",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"farm_id":      farmID,
+		"calibrations": calibrations,
+	})
+}
+
+// DeleteCalibration handles DELETE /v1/farms/{farm_id}/calibrations/{calibration_id}
+// Values already ingested keep their correction.
+func (c *CalibrationController) DeleteCalibration(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	calibrationID, err := strconv.ParseUint(ctx.Param("calibration_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid calibration_id",
+			"message": "calibration_id must be a valid unsigned integer",
+		})
+		return
+	}
+
+	deleted, err := c.calibrationService.DeleteCalibration(farmID, uint(calibrationID))
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to delete calibration",
+			"farm_id", farmID,
+			"calibration_id", calibrationID,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to delete calibration",
+		})
+		return
+	}
+	if !deleted {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Calibration not found",
+			"message": fmt.Sprintf("Calibration with ID %d does not exist for farm %d", calibrationID, farmID),
+		})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
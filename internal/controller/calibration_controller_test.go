@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mockCalibrationService is a mock implementation of CalibrationService for testing
+type mockCalibrationService struct {
+	set *service.CalibrationInput
+}
+
+func (m *mockCalibrationService) FarmExists(farmID uint) (bool, error) {
+	return true, nil
+}
+
+func (m *mockCalibrationService) SetCalibration(farmID uint, input service.CalibrationInput) (*model.Calibration, error) {
+	if input.SectorID != nil && *input.SectorID == 404 {
+		return nil, service.ErrSectorNotFound
+	}
+	m.set = &input
+	return &model.Calibration{ID: 1, FarmID: farmID, Target: input.Target, Factor: input.Factor}, nil
+}
+
+func (m *mockCalibrationService) ListCalibrations(farmID uint) ([]model.Calibration, error) {
+	return []model.Calibration{}, nil
+}
+
+func (m *mockCalibrationService) DeleteCalibration(farmID, calibrationID uint) (bool, error) {
+	return calibrationID == 1, nil
+}
+
+func TestSetCalibration_Validation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mock := &mockCalibrationService{}
+	controller := NewCalibrationController(mock, slog.Default())
+	router := gin.New()
+	router.POST("/v1/farms/:farm_id/calibrations", controller.SetCalibration)
+	router.DELETE("/v1/farms/:farm_id/calibrations/:calibration_id", controller.DeleteCalibration)
+
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+	}{
+		{"volume", `{"target":"volume","data_source":"import","factor":1.04}`, http.StatusCreated},
+		{"sector volume", `{"target":"volume","data_source":"mqtt","sector_id":3,"offset":-2.5}`, http.StatusCreated},
+		{"pressure", `{"target":"pressure","device_id":"pt-7","offset":0.2}`, http.StatusCreated},
+		{"unknown sector", `{"target":"volume","data_source":"import","sector_id":404}`, http.StatusNotFound},
+		{"unknown target", `{"target":"flow"}`, http.StatusBadRequest},
+		{"unknown data source", `{"target":"volume","data_source":"scada"}`, http.StatusBadRequest},
+		{"pressure without device", `{"target":"pressure"}`, http.StatusBadRequest},
+		{"pressure with sector", `{"target":"pressure","device_id":"pt-7","sector_id":3}`, http.StatusBadRequest},
+		{"zero factor", `{"target":"volume","data_source":"import","factor":0}`, http.StatusBadRequest},
+		{"malformed body", `{"target":`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/v1/farms/1/calibrations", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status code %d, got %d (%s)", tt.expectedCode, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	if mock.set == nil || mock.set.Factor != 1 || mock.set.DeviceID != "pt-7" {
+		t.Errorf("expected the factor to default to 1, got %+v", mock.set)
+	}
+
+	for id, expectedCode := range map[string]int{"1": http.StatusNoContent, "2": http.StatusNotFound, "x": http.StatusBadRequest} {
+		req, _ := http.NewRequest("DELETE", "/v1/farms/1/calibrations/"+id, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != expectedCode {
+			t.Errorf("delete %s: expected status code %d, got %d", id, expectedCode, w.Code)
+		}
+	}
+}
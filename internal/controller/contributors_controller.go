@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxContributorsLimit caps how many contributors a single request can return
+const maxContributorsLimit = 50
+
+// GetTopContributors handles GET /v1/farms/{farm_id}/irrigation/contributors
+// Query parameters:
+//   - start_date (required): Start date in ISO 8601 format (RFC3339 or YYYY-MM-DD)
+//   - end_date (required): End date in ISO 8601 format (RFC3339 or YYYY-MM-DD)
+//   - dimension (optional): sector or day (default: sector)
+//   - baseline (optional): previous_year or previous_period (default: previous_year)
+//   - limit (optional): number of contributors to return, 1-50 (default: 5)
+func (c *AnalyticsController) GetTopContributors(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	startDate, endDate, ok := parseDateRange(ctx, c.logger, farmID)
+	if !ok {
+		return
+	}
+
+	dimension := ctx.DefaultQuery("dimension", service.ContributorDimensionSector)
+	if dimension != service.ContributorDimensionSector && dimension != service.ContributorDimensionDay {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid dimension",
+			"message": "dimension must be one of: sector, day",
+		})
+		return
+	}
+
+	baseline := ctx.DefaultQuery("baseline", service.BaselinePreviousYear)
+	if baseline != service.BaselinePreviousYear && baseline != service.BaselinePreviousPeriod {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid baseline",
+			"message": "baseline must be one of: previous_year, previous_period",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "5"))
+	if err != nil || limit < 1 || limit > maxContributorsLimit {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid limit",
+			"message": "limit must be an integer between 1 and 50",
+		})
+		return
+	}
+
+	analyticsService := c.analyticsService.WithContext(ctx.Request.Context())
+
+	if !ensureFarmExists(ctx, c.logger, analyticsService, farmID, startTime) {
+		return
+	}
+
+	contributors, err := analyticsService.GetTopContributors(farmID, startDate, endDate, dimension, baseline, limit)
+	if writeBudgetError(ctx, c.logger, farmID, err) || writeQueryCancelled(ctx, c.logger, farmID, err) {
+		return
+	}
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to compute top contributors",
+			"farm_id", farmID,
+			"dimension", dimension,
+			"baseline", baseline,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to compute top contributors",
+		})
+		return
+	}
+
+	latency := time.Since(startTime)
+	c.logger.Info("top contributors request completed",
+		"farm_id", farmID,
+		"dimension", dimension,
+		"baseline", baseline,
+		"contributors", len(contributors.Contributors),
+		"latency_ms", latency.Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusOK, contributors)
+}
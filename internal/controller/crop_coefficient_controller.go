@@ -0,0 +1,326 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxCropKc bounds overridden crop coefficients; FAO-56 values stay well below it
+const maxCropKc = 2
+
+// CropCoefficientController handles crop coefficient library HTTP requests
+type CropCoefficientController struct {
+	cropService service.CropCoefficientService
+	logger      *slog.Logger
+}
+
+// NewCropCoefficientController creates a new crop coefficient controller
+func NewCropCoefficientController(cropService service.CropCoefficientService, logger *slog.Logger) *CropCoefficientController {
+	return &CropCoefficientController{
+		cropService: cropService,
+		logger:      logger,
+	}
+}
+
+// cropOverrideRequest is the body of SetCropOverride
+type cropOverrideRequest struct {
+	KcIni *float64 `json:"kc_ini"`
+	KcMid *float64 `json:"kc_mid"`
+	KcEnd *float64 `json:"kc_end"`
+}
+
+// sectorCropRequest is the body of SetSectorCrop
+type sectorCropRequest struct {
+	Crop      string `json:"crop"`
+	CropStage string `json:"crop_stage"`
+}
+
+// parseCropLanguage reads the lang query parameter, writing a 400 response when it is unknown
+func parseCropLanguage(ctx *gin.Context) (string, bool) {
+	lang := ctx.DefaultQuery("lang", service.CropLanguageEnglish)
+	if !service.IsValidCropLanguage(lang) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid lang",
+			"message": "lang must be one of: en, es, pt, fr",
+		})
+		return "", false
+	}
+	return lang, true
+}
+
+// ListCrops handles GET /v1/crops
+// Query parameters:
+//   - lang (optional): en, es, pt or fr for crop names (default: en)
+//
+// Lists the built-in FAO-56 crop coefficient library.
+func (c *CropCoefficientController) ListCrops(ctx *gin.Context) {
+	lang, ok := parseCropLanguage(ctx)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"lang":  lang,
+		"crops": c.cropService.ListCrops(lang),
+	})
+}
+
+// ListFarmCrops handles GET /v1/farms/{farm_id}/crop-coefficients
+// Query parameters:
+//   - lang (optional): en, es, pt or fr for crop names (default: en)
+//
+// Lists the library with the farm's overrides applied; overridden crops are marked.
+func (c *CropCoefficientController) ListFarmCrops(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+	lang, ok := parseCropLanguage(ctx)
+	if !ok {
+		return
+	}
+
+	if !ensureFarmExists(ctx, c.logger, c.cropService, farmID, startTime) {
+		return
+	}
+
+	crops, err := c.cropService.ListFarmCrops(farmID, lang)
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to list crop coefficients",
+			"farm_id", farmID,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to list crop coefficients",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"farm_id": farmID,
+		"lang":    lang,
+		"crops":   crops,
+	})
+}
+
+// SetCropOverride handles PUT /v1/farms/{farm_id}/crop-coefficients/{crop}
+// Body fields:
+//   - kc_ini, kc_mid, kc_end (at least one): the farm's Kc values, greater than 0 and at most 2;
+//     values left out keep the library's
+//
+// The body replaces any earlier override of the crop.
+func (c *CropCoefficientController) SetCropOverride(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+	crop := ctx.Param("crop")
+
+	var req cropOverrideRequest
+	err := ctx.ShouldBindJSON(&req)
+	if writeBodyTooLarge(ctx, c.logger, farmID, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": "body must be a JSON object with kc_ini, kc_mid or kc_end",
+		})
+		return
+	}
+	if errMessage := req.validate(); errMessage != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid crop coefficients",
+			"message": errMessage,
+		})
+		return
+	}
+
+	if !ensureFarmExists(ctx, c.logger, c.cropService, farmID, startTime) {
+		return
+	}
+
+	coefficients, err := c.cropService.SetOverride(farmID, crop, service.CropOverrideInput{
+		KcIni: req.KcIni,
+		KcMid: req.KcMid,
+		KcEnd: req.KcEnd,
+	})
+	if errors.Is(err, service.ErrUnknownCrop) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Crop not found",
+			"message": fmt.Sprintf("Crop %q is not in the crop coefficient library", crop),
+		})
+		return
+	}
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to save crop coefficient override",
+			"farm_id", farmID,
+			"crop", crop,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to save crop coefficient override",
+		})
+		return
+	}
+
+	c.logger.Info("crop coefficient override saved",
+		"farm_id", farmID,
+		"crop", crop,
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusOK, coefficients)
+}
+
+// validate checks the request, returning a message describing the first problem found
+func (r cropOverrideRequest) validate() string {
+	if r.KcIni == nil && r.KcMid == nil && r.KcEnd == nil {
+		return "at least one of kc_ini, kc_mid and kc_end is required"
+	}
+	for _, kc := range []*float64{r.KcIni, r.KcMid, r.KcEnd} {
+		if kc != nil && !(*kc > 0 && *kc <= maxCropKc) {
+			return fmt.Sprintf("kc_ini, kc_mid and kc_end must be greater than 0 and at most %d", maxCropKc)
+		}
+	}
+	return ""
+}
+
+// DeleteCropOverride handles DELETE /v1/farms/{farm_id}/crop-coefficients/{crop}
+// The crop goes back to the library's values.
+func (c *CropCoefficientController) DeleteCropOverride(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+	crop := ctx.Param("crop")
+
+	deleted, err := c.cropService.DeleteOverride(farmID, crop)
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to delete crop coefficient override",
+			"farm_id", farmID,
+			"crop", crop,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to delete crop coefficient override",
+		})
+		return
+	}
+	if !deleted {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Override not found",
+			"message": fmt.Sprintf("Farm %d has no override for crop %q", farmID, crop),
+		})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// SetSectorCrop handles PUT /v1/farms/{farm_id}/sectors/{sector_id}/crop
+// Body fields:
+//   - crop: a library crop key, or empty to clear the sector's crop
+//   - crop_stage (required with crop): initial, development, mid or late
+//
+// Weather-based recommendations scale ET0 by the Kc of the sector's crop and stage.
+func (c *CropCoefficientController) SetSectorCrop(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+	sectorID, err := strconv.ParseUint(ctx.Param("sector_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid sector_id",
+			"message": "sector_id must be a valid unsigned integer",
+		})
+		return
+	}
+
+	var req sectorCropRequest
+	err = ctx.ShouldBindJSON(&req)
+	if writeBodyTooLarge(ctx, c.logger, farmID, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": "body must be a JSON object with crop and crop_stage",
+		})
+		return
+	}
+	if (req.Crop == "") != (req.CropStage == "") || (req.CropStage != "" && !model.IsValidCropStage(req.CropStage)) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid sector crop",
+			"message": "crop and crop_stage must be given together, with crop_stage one of: initial, development, mid, late",
+		})
+		return
+	}
+
+	err = c.cropService.SetSectorCrop(farmID, uint(sectorID), req.Crop, req.CropStage)
+	if errors.Is(err, service.ErrUnknownCrop) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid sector crop",
+			"message": fmt.Sprintf("crop %q is not in the crop coefficient library", req.Crop),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrSectorNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Sector not found",
+			"message": fmt.Sprintf("Sector with ID %d does not exist for farm %d", sectorID, farmID),
+		})
+		return
+	}
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to set sector crop",
+			"farm_id", farmID,
+			"sector_id", sectorID,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to set sector crop",
+		})
+		return
+	}
+
+	c.logger.Info("sector crop set",
+		"farm_id", farmID,
+		"sector_id", sectorID,
+		"crop", req.Crop,
+		"crop_stage", req.CropStage,
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"farm_id":    farmID,
+		"sector_id":  sectorID,
+		"crop":       req.Crop,
+		"crop_stage": req.CropStage,
+	})
+}
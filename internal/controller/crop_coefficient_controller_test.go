@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mockCropCoefficientService is a mock implementation of CropCoefficientService for testing
+type mockCropCoefficientService struct{}
+
+func (m *mockCropCoefficientService) FarmExists(farmID uint) (bool, error) {
+	return farmID == 1, nil
+}
+
+func (m *mockCropCoefficientService) ListCrops(lang string) []service.CropCoefficients {
+	return []service.CropCoefficients{{Crop: "tomato", KcIni: 0.6, KcMid: 1.15, KcEnd: 0.8}}
+}
+
+func (m *mockCropCoefficientService) ListFarmCrops(farmID uint, lang string) ([]service.CropCoefficients, error) {
+	return m.ListCrops(lang), nil
+}
+
+func (m *mockCropCoefficientService) SetOverride(farmID uint, crop string, input service.CropOverrideInput) (*service.CropCoefficients, error) {
+	if crop != "tomato" {
+		return nil, service.ErrUnknownCrop
+	}
+	return &service.CropCoefficients{Crop: crop, Overridden: true}, nil
+}
+
+func (m *mockCropCoefficientService) DeleteOverride(farmID uint, crop string) (bool, error) {
+	return crop == "tomato", nil
+}
+
+func (m *mockCropCoefficientService) SetSectorCrop(farmID, sectorID uint, crop, stage string) error {
+	if crop != "" && crop != "tomato" {
+		return service.ErrUnknownCrop
+	}
+	if sectorID != 1 {
+		return service.ErrSectorNotFound
+	}
+	return nil
+}
+
+func TestCropCoefficientController(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	controller := NewCropCoefficientController(&mockCropCoefficientService{}, slog.Default())
+	router := gin.New()
+	router.GET("/v1/crops", controller.ListCrops)
+	router.GET("/v1/farms/:farm_id/crop-coefficients", controller.ListFarmCrops)
+	router.PUT("/v1/farms/:farm_id/crop-coefficients/:crop", controller.SetCropOverride)
+	router.DELETE("/v1/farms/:farm_id/crop-coefficients/:crop", controller.DeleteCropOverride)
+	router.PUT("/v1/farms/:farm_id/sectors/:sector_id/crop", controller.SetSectorCrop)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		expectedCode int
+	}{
+		{"library", "GET", "/v1/crops", "", http.StatusOK},
+		{"library in Spanish", "GET", "/v1/crops?lang=es", "", http.StatusOK},
+		{"unknown language", "GET", "/v1/crops?lang=de", "", http.StatusBadRequest},
+		{"farm crops", "GET", "/v1/farms/1/crop-coefficients?lang=pt", "", http.StatusOK},
+		{"farm crops unknown farm", "GET", "/v1/farms/2/crop-coefficients", "", http.StatusNotFound},
+		{"override", "PUT", "/v1/farms/1/crop-coefficients/tomato", `{"kc_mid":1.05}`, http.StatusOK},
+		{"override unknown crop", "PUT", "/v1/farms/1/crop-coefficients/kale", `{"kc_mid":1.05}`, http.StatusNotFound},
+		{"override without values", "PUT", "/v1/farms/1/crop-coefficients/tomato", `{}`, http.StatusBadRequest},
+		{"override out of range", "PUT", "/v1/farms/1/crop-coefficients/tomato", `{"kc_ini":0}`, http.StatusBadRequest},
+		{"override malformed body", "PUT", "/v1/farms/1/crop-coefficients/tomato", `{"kc_mid":`, http.StatusBadRequest},
+		{"delete override", "DELETE", "/v1/farms/1/crop-coefficients/tomato", "", http.StatusNoContent},
+		{"delete missing override", "DELETE", "/v1/farms/1/crop-coefficients/olive", "", http.StatusNotFound},
+		{"sector crop", "PUT", "/v1/farms/1/sectors/1/crop", `{"crop":"tomato","crop_stage":"mid"}`, http.StatusOK},
+		{"clear sector crop", "PUT", "/v1/farms/1/sectors/1/crop", `{"crop":"","crop_stage":""}`, http.StatusOK},
+		{"sector crop without stage", "PUT", "/v1/farms/1/sectors/1/crop", `{"crop":"tomato"}`, http.StatusBadRequest},
+		{"sector crop invalid stage", "PUT", "/v1/farms/1/sectors/1/crop", `{"crop":"tomato","crop_stage":"harvest"}`, http.StatusBadRequest},
+		{"sector crop unknown crop", "PUT", "/v1/farms/1/sectors/1/crop", `{"crop":"kale","crop_stage":"mid"}`, http.StatusBadRequest},
+		{"sector crop unknown sector", "PUT", "/v1/farms/1/sectors/9/crop", `{"crop":"tomato","crop_stage":"mid"}`, http.StatusNotFound},
+		{"sector crop invalid sector id", "PUT", "/v1/farms/1/sectors/abc/crop", `{"crop":"tomato","crop_stage":"mid"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status code %d, got %d (%s)", tt.expectedCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"embed"
+	"io/fs"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// dashboardAssets holds the mini dashboard's page, script and stylesheet
+//
+//go:embed ui
+var dashboardAssets embed.FS
+
+// DashboardController serves a small dashboard charting a farm's analytics from the public
+// analytics endpoint, for checking a farm from a browser without the main frontend
+type DashboardController struct {
+	assets fs.FS
+	logger *slog.Logger
+}
+
+// NewDashboardController creates a new dashboard controller serving the embedded assets
+func NewDashboardController(logger *slog.Logger) *DashboardController {
+	assets, _ := fs.Sub(dashboardAssets, "ui")
+	return &DashboardController{assets: assets, logger: logger}
+}
+
+// Dashboard handles GET /ui/*filepath
+// Serves the dashboard page at /ui/ and its assets by name
+func (c *DashboardController) Dashboard(ctx *gin.Context) {
+	name := strings.TrimPrefix(ctx.Param("filepath"), "/")
+	if name == "" {
+		name = "index.html"
+	}
+
+	serveAsset(ctx, c.assets, name, "unknown dashboard asset")
+}
+
+// serveAsset writes an embedded asset with the content type of its extension, or 404 with
+// notFound as the message
+func serveAsset(ctx *gin.Context, assets fs.FS, name, notFound string) {
+	body, err := fs.ReadFile(assets, name)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Not found",
+			"message": notFound,
+		})
+		return
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	// Assets change with each release, so browsers revalidate them rather than keeping a stale copy
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Data(http.StatusOK, contentType, body)
+}
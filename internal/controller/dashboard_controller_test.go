@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDashboard(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	controller := NewDashboardController(slog.Default())
+	router := gin.New()
+	router.GET("/ui/*filepath", controller.Dashboard)
+
+	tests := []struct {
+		name         string
+		url          string
+		expectedCode int
+		contentType  string
+		contains     string
+	}{
+		{"page", "/ui/", http.StatusOK, "text/html", `<script src="dashboard.js">`},
+		{"script", "/ui/dashboard.js", http.StatusOK, "javascript", "/irrigation/analytics?"},
+		{"stylesheet", "/ui/dashboard.css", http.StatusOK, "text/css", ".chart"},
+		{"unknown asset", "/ui/missing.js", http.StatusNotFound, "application/json", "unknown dashboard asset"},
+		{"outside the assets", "/ui/../dashboard_controller.go", http.StatusNotFound, "application/json", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", tt.url, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status code %d, got %d", tt.expectedCode, w.Code)
+			}
+			if contentType := w.Header().Get("Content-Type"); !strings.Contains(contentType, tt.contentType) {
+				t.Errorf("expected a %s content type, got %q", tt.contentType, contentType)
+			}
+			if !strings.Contains(w.Body.String(), tt.contains) {
+				t.Errorf("expected the body to contain %q", tt.contains)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeadLetterController handles rejected ingestion message HTTP requests
+type DeadLetterController struct {
+	deadLetterService service.DeadLetterService
+	logger            *slog.Logger
+}
+
+// NewDeadLetterController creates a new dead letter controller
+func NewDeadLetterController(deadLetterService service.DeadLetterService, logger *slog.Logger) *DeadLetterController {
+	return &DeadLetterController{
+		deadLetterService: deadLetterService,
+		logger:            logger,
+	}
+}
+
+// replayRequest is the optional body of a replay request
+type replayRequest struct {
+	// Payload replaces the stored payload, in the letter's payload_format
+	Payload json.RawMessage `json:"payload"`
+}
+
+// ListDeadLetters handles GET /v1/farms/{farm_id}/dead-letters
+// Query parameters:
+//   - status (optional): pending, replayed or discarded (default: pending)
+//   - limit (optional): number of letters to return, oldest first, 1-1000 (default: 100)
+func (c *DeadLetterController) ListDeadLetters(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	status := ctx.DefaultQuery("status", model.DeadLetterPending)
+	if status != model.DeadLetterPending && status != model.DeadLetterReplayed && status != model.DeadLetterDiscarded {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid status",
+			"message": "status must be one of: pending, replayed, discarded",
+		})
+		return
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(service.DefaultDeadLetterLimit)))
+	if err != nil || limit < 1 || limit > service.MaxDeadLetterLimit {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid limit",
+			"message": fmt.Sprintf("limit must be an integer between 1 and %d", service.MaxDeadLetterLimit),
+		})
+		return
+	}
+
+	if !ensureFarmExists(ctx, c.logger, c.deadLetterService, farmID, startTime) {
+		return
+	}
+
+	letters, err := c.deadLetterService.ListDeadLetters(farmID, status, limit)
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to list dead letters",
+			"farm_id", farmID,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to list dead letters",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"farm_id":      farmID,
+		"status":       status,
+		"dead_letters": letters,
+	})
+}
+
+// ReplayDeadLetter handles POST /v1/farms/{farm_id}/dead-letters/{dead_letter_id}/replay
+// Body fields (the body is optional):
+//   - payload (optional): a fixed payload replacing the stored one, in the letter's payload_format
+//
+// Returns the replayed letter with its event_id, or 422 with the letter and the new error when
+// the payload is still rejected.
+func (c *DeadLetterController) ReplayDeadLetter(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+	letterID, ok := parseDeadLetterID(ctx)
+	if !ok {
+		return
+	}
+
+	var req replayRequest
+	err := ctx.ShouldBindJSON(&req)
+	if writeBodyTooLarge(ctx, c.logger, farmID, err) {
+		return
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": "body must be empty or a JSON object with a payload",
+		})
+		return
+	}
+
+	letter, err := c.deadLetterService.ReplayDeadLetter(farmID, letterID, req.Payload)
+	if errors.Is(err, service.ErrReplayRejected) {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":       "Replay rejected",
+			"message":     letter.Error,
+			"dead_letter": letter,
+		})
+		return
+	}
+	if c.writeLetterError(ctx, farmID, letterID, "replay", err, startTime) {
+		return
+	}
+
+	c.logger.Info("dead letter replayed",
+		"farm_id", farmID,
+		"dead_letter_id", letterID,
+		"event_id", *letter.EventID,
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+	ctx.JSON(http.StatusOK, letter)
+}
+
+// DiscardDeadLetter handles POST /v1/farms/{farm_id}/dead-letters/{dead_letter_id}/discard
+// The letter stays listed with status discarded.
+func (c *DeadLetterController) DiscardDeadLetter(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+	letterID, ok := parseDeadLetterID(ctx)
+	if !ok {
+		return
+	}
+
+	letter, err := c.deadLetterService.DiscardDeadLetter(farmID, letterID)
+	if c.writeLetterError(ctx, farmID, letterID, "discard", err, startTime) {
+		return
+	}
+
+	c.logger.Info("dead letter discarded",
+		"farm_id", farmID,
+		"dead_letter_id", letterID,
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+	ctx.JSON(http.StatusOK, letter)
+}
+
+// writeLetterError writes the response for a failed replay or discard, reporting whether err was set
+func (c *DeadLetterController) writeLetterError(ctx *gin.Context, farmID, letterID uint, action string, err error, startTime time.Time) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, service.ErrDeadLetterNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Dead letter not found",
+			"message": fmt.Sprintf("Dead letter with ID %d does not exist for farm %d", letterID, farmID),
+		})
+	case errors.Is(err, service.ErrDeadLetterResolved):
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error":   "Dead letter already resolved",
+			"message": fmt.Sprintf("Dead letter %d was already replayed or discarded", letterID),
+		})
+	default:
+		c.logger.Error("failed to "+action+" dead letter",
+			"farm_id", farmID,
+			"dead_letter_id", letterID,
+			"error", err.Error(),
+			"latency_ms", time.Since(startTime).Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to " + action + " dead letter",
+		})
+	}
+	return true
+}
+
+// parseDeadLetterID parses the dead_letter_id path parameter, writing a 400 response when it is invalid
+func parseDeadLetterID(ctx *gin.Context) (uint, bool) {
+	letterID, err := strconv.ParseUint(ctx.Param("dead_letter_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid dead_letter_id",
+			"message": "dead_letter_id must be a valid unsigned integer",
+		})
+		return 0, false
+	}
+	return uint(letterID), true
+}
@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mockDeadLetterService is a mock implementation of DeadLetterService for testing
+type mockDeadLetterService struct{}
+
+func (m *mockDeadLetterService) FarmExists(farmID uint) (bool, error) {
+	return true, nil
+}
+
+func (m *mockDeadLetterService) ListDeadLetters(farmID uint, status string, limit int) ([]model.DeadLetter, error) {
+	return []model.DeadLetter{}, nil
+}
+
+func (m *mockDeadLetterService) ReplayDeadLetter(farmID, letterID uint, payload json.RawMessage) (*model.DeadLetter, error) {
+	switch letterID {
+	case 404:
+		return nil, service.ErrDeadLetterNotFound
+	case 409:
+		return nil, service.ErrDeadLetterResolved
+	case 422:
+		letter := &model.DeadLetter{ID: letterID, Status: model.DeadLetterPending, Error: "invalid water_volume"}
+		return letter, fmt.Errorf("%w: %s", service.ErrReplayRejected, letter.Error)
+	}
+	eventID := uint(7)
+	return &model.DeadLetter{ID: letterID, Status: model.DeadLetterReplayed, EventID: &eventID}, nil
+}
+
+func (m *mockDeadLetterService) DiscardDeadLetter(farmID, letterID uint) (*model.DeadLetter, error) {
+	if letterID == 404 {
+		return nil, service.ErrDeadLetterNotFound
+	}
+	return &model.DeadLetter{ID: letterID, Status: model.DeadLetterDiscarded}, nil
+}
+
+func TestDeadLetterController(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mock := &mockDeadLetterService{}
+	controller := NewDeadLetterController(mock, slog.Default())
+	router := gin.New()
+	router.GET("/v1/farms/:farm_id/dead-letters", controller.ListDeadLetters)
+	router.POST("/v1/farms/:farm_id/dead-letters/:dead_letter_id/replay", controller.ReplayDeadLetter)
+	router.POST("/v1/farms/:farm_id/dead-letters/:dead_letter_id/discard", controller.DiscardDeadLetter)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		expectedCode int
+	}{
+		{"list", "GET", "/v1/farms/1/dead-letters?status=discarded&limit=10", "", http.StatusOK},
+		{"list unknown status", "GET", "/v1/farms/1/dead-letters?status=failed", "", http.StatusBadRequest},
+		{"list limit too high", "GET", "/v1/farms/1/dead-letters?limit=1001", "", http.StatusBadRequest},
+		{"replay without body", "POST", "/v1/farms/1/dead-letters/5/replay", "", http.StatusOK},
+		{"replay with payload", "POST", "/v1/farms/1/dead-letters/5/replay", `{"payload":{"water_volume":12}}`, http.StatusOK},
+		{"replay rejected", "POST", "/v1/farms/1/dead-letters/422/replay", "", http.StatusUnprocessableEntity},
+		{"replay unknown", "POST", "/v1/farms/1/dead-letters/404/replay", "", http.StatusNotFound},
+		{"replay resolved", "POST", "/v1/farms/1/dead-letters/409/replay", "", http.StatusConflict},
+		{"replay malformed body", "POST", "/v1/farms/1/dead-letters/5/replay", `{"payload":`, http.StatusBadRequest},
+		{"replay invalid id", "POST", "/v1/farms/1/dead-letters/abc/replay", "", http.StatusBadRequest},
+		{"discard", "POST", "/v1/farms/1/dead-letters/5/discard", "", http.StatusOK},
+		{"discard unknown", "POST", "/v1/farms/1/dead-letters/404/discard", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status code %d, got %d (%s)", tt.expectedCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeviceController handles device registry HTTP requests
+type DeviceController struct {
+	deviceService service.DeviceService
+	logger        *slog.Logger
+}
+
+// NewDeviceController creates a new device controller
+func NewDeviceController(deviceService service.DeviceService, logger *slog.Logger) *DeviceController {
+	return &DeviceController{
+		deviceService: deviceService,
+		logger:        logger,
+	}
+}
+
+// deviceRequest is the body of CreateDevice and UpdateDevice
+type deviceRequest struct {
+	SectorID     uint   `json:"sector_id"`
+	Kind         string `json:"kind"`
+	Name         string `json:"name"`
+	SerialNumber string `json:"serial_number"`
+	Note         string `json:"note"`
+}
+
+// toInput validates the request, returning a message describing the first problem found
+func (r deviceRequest) toInput() (service.DeviceInput, string) {
+	input := service.DeviceInput{
+		SectorID:     r.SectorID,
+		Kind:         r.Kind,
+		Name:         strings.TrimSpace(r.Name),
+		SerialNumber: strings.TrimSpace(r.SerialNumber),
+		Note:         r.Note,
+	}
+	switch {
+	case r.SectorID == 0:
+		return input, "sector_id is required"
+	case !model.IsValidDeviceKind(r.Kind):
+		return input, "kind must be one of: flow_meter, valve"
+	case input.Name == "" || len(input.Name) > 128:
+		return input, "name is required and must be at most 128 characters"
+	case len(input.SerialNumber) > 64:
+		return input, "serial_number must be at most 64 characters"
+	}
+	return input, ""
+}
+
+// parseDeviceID reads the device_id path parameter, writing a 400 response when it is invalid
+func parseDeviceID(ctx *gin.Context) (uint, bool) {
+	deviceID, err := strconv.ParseUint(ctx.Param("device_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid device_id",
+			"message": "device_id must be a valid unsigned integer",
+		})
+		return 0, false
+	}
+	return uint(deviceID), true
+}
+
+// bindDeviceRequest decodes and validates a device body, writing a 400 or 413 response on failure
+func (c *DeviceController) bindDeviceRequest(ctx *gin.Context, farmID uint) (service.DeviceInput, bool) {
+	var req deviceRequest
+	err := ctx.ShouldBindJSON(&req)
+	if writeBodyTooLarge(ctx, c.logger, farmID, err) {
+		return service.DeviceInput{}, false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": "body must be a JSON device object",
+		})
+		return service.DeviceInput{}, false
+	}
+	input, errMessage := req.toInput()
+	if errMessage != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid device",
+			"message": errMessage,
+		})
+		return service.DeviceInput{}, false
+	}
+	return input, true
+}
+
+// CreateDevice handles POST /v1/farms/{farm_id}/devices
+// Body fields:
+//   - sector_id (required): the sector the device is installed on
+//   - kind (required): flow_meter or valve
+//   - name (required): a label for the device, at most 128 characters
+//   - serial_number (optional): the manufacturer's serial, at most 64 characters
+//   - note (optional): free-form details such as the install date
+func (c *DeviceController) CreateDevice(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+	input, ok := c.bindDeviceRequest(ctx, farmID)
+	if !ok {
+		return
+	}
+
+	if !ensureFarmExists(ctx, c.logger, c.deviceService, farmID, startTime) {
+		return
+	}
+
+	device, err := c.deviceService.CreateDevice(farmID, input)
+	if c.writeDeviceError(ctx, farmID, 0, input.SectorID, err, startTime, "create") {
+		return
+	}
+
+	c.logger.Info("device created",
+		"farm_id", farmID,
+		"device_id", device.ID,
+		"sector_id", device.IrrigationSectorID,
+		"kind", device.Kind,
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusCreated, device)
+}
+
+// ListDevices handles GET /v1/farms/{farm_id}/devices
+// Query parameters:
+//   - sector_id (optional): only list the devices installed on this sector
+func (c *DeviceController) ListDevices(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+	sectorID, ok := parseSectorID(ctx, c.logger, farmID)
+	if !ok {
+		return
+	}
+
+	if !ensureFarmExists(ctx, c.logger, c.deviceService, farmID, startTime) {
+		return
+	}
+
+	devices, err := c.deviceService.ListDevices(farmID, sectorID)
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to list devices",
+			"farm_id", farmID,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to list devices",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"farm_id": farmID,
+		"devices": devices,
+	})
+}
+
+// GetDevice handles GET /v1/farms/{farm_id}/devices/{device_id}
+func (c *DeviceController) GetDevice(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+	deviceID, ok := parseDeviceID(ctx)
+	if !ok {
+		return
+	}
+
+	device, err := c.deviceService.GetDevice(farmID, deviceID)
+	if c.writeDeviceError(ctx, farmID, deviceID, 0, err, startTime, "get") {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, device)
+}
+
+// UpdateDevice handles PUT /v1/farms/{farm_id}/devices/{device_id}
+// The body has the fields of CreateDevice and replaces the device's details. Events the device
+// already measured keep their sector when it moves.
+func (c *DeviceController) UpdateDevice(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+	deviceID, ok := parseDeviceID(ctx)
+	if !ok {
+		return
+	}
+	input, ok := c.bindDeviceRequest(ctx, farmID)
+	if !ok {
+		return
+	}
+
+	device, err := c.deviceService.UpdateDevice(farmID, deviceID, input)
+	if c.writeDeviceError(ctx, farmID, deviceID, input.SectorID, err, startTime, "update") {
+		return
+	}
+
+	c.logger.Info("device updated",
+		"farm_id", farmID,
+		"device_id", deviceID,
+		"sector_id", device.IrrigationSectorID,
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusOK, device)
+}
+
+// DeleteDevice handles DELETE /v1/farms/{farm_id}/devices/{device_id}
+// Events keep their device_id, so they can still be filtered by the removed device.
+func (c *DeviceController) DeleteDevice(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+	deviceID, ok := parseDeviceID(ctx)
+	if !ok {
+		return
+	}
+
+	deleted, err := c.deviceService.DeleteDevice(farmID, deviceID)
+	if err == nil && !deleted {
+		err = service.ErrDeviceNotFound
+	}
+	if c.writeDeviceError(ctx, farmID, deviceID, 0, err, startTime, "delete") {
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// writeDeviceError writes the response for a failed device operation, returning false when err
+// is nil
+func (c *DeviceController) writeDeviceError(ctx *gin.Context, farmID, deviceID, sectorID uint, err error, startTime time.Time, operation string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, service.ErrDeviceNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Device not found",
+			"message": fmt.Sprintf("Device with ID %d does not exist for farm %d", deviceID, farmID),
+		})
+	case errors.Is(err, service.ErrSectorNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Sector not found",
+			"message": fmt.Sprintf("Sector with ID %d does not exist for farm %d", sectorID, farmID),
+		})
+	default:
+		latency := time.Since(startTime)
+		c.logger.Error("failed to "+operation+" device",
+			"farm_id", farmID,
+			"device_id", deviceID,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": fmt.Sprintf("Failed to %s device", operation),
+		})
+	}
+	return true
+}
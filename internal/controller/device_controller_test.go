@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mockDeviceService is a mock implementation of DeviceService for testing
+type mockDeviceService struct {
+	created *service.DeviceInput
+	listed  *uint
+}
+
+func (m *mockDeviceService) FarmExists(farmID uint) (bool, error) {
+	return true, nil
+}
+
+func (m *mockDeviceService) CreateDevice(farmID uint, input service.DeviceInput) (*model.Device, error) {
+	if input.SectorID == 404 {
+		return nil, service.ErrSectorNotFound
+	}
+	m.created = &input
+	return &model.Device{ID: 1, FarmID: farmID, IrrigationSectorID: input.SectorID, Kind: input.Kind, Name: input.Name}, nil
+}
+
+func (m *mockDeviceService) ListDevices(farmID uint, sectorID *uint) ([]model.Device, error) {
+	m.listed = sectorID
+	return []model.Device{}, nil
+}
+
+func (m *mockDeviceService) GetDevice(farmID, deviceID uint) (*model.Device, error) {
+	if deviceID != 1 {
+		return nil, service.ErrDeviceNotFound
+	}
+	return &model.Device{ID: 1, FarmID: farmID}, nil
+}
+
+func (m *mockDeviceService) UpdateDevice(farmID, deviceID uint, input service.DeviceInput) (*model.Device, error) {
+	if deviceID != 1 {
+		return nil, service.ErrDeviceNotFound
+	}
+	return &model.Device{ID: deviceID, FarmID: farmID, IrrigationSectorID: input.SectorID}, nil
+}
+
+func (m *mockDeviceService) DeleteDevice(farmID, deviceID uint) (bool, error) {
+	return deviceID == 1, nil
+}
+
+func TestDeviceController(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mock := &mockDeviceService{}
+	controller := NewDeviceController(mock, slog.Default())
+	router := gin.New()
+	router.POST("/v1/farms/:farm_id/devices", controller.CreateDevice)
+	router.GET("/v1/farms/:farm_id/devices", controller.ListDevices)
+	router.GET("/v1/farms/:farm_id/devices/:device_id", controller.GetDevice)
+	router.PUT("/v1/farms/:farm_id/devices/:device_id", controller.UpdateDevice)
+	router.DELETE("/v1/farms/:farm_id/devices/:device_id", controller.DeleteDevice)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		expectedCode int
+	}{
+		{"create flow meter", "POST", "/v1/farms/1/devices", `{"sector_id":3,"kind":"flow_meter","name":" Meter A ","serial_number":"FM-001"}`, http.StatusCreated},
+		{"create valve", "POST", "/v1/farms/1/devices", `{"sector_id":3,"kind":"valve","name":"Valve 2"}`, http.StatusCreated},
+		{"unknown sector", "POST", "/v1/farms/1/devices", `{"sector_id":404,"kind":"valve","name":"Valve 2"}`, http.StatusNotFound},
+		{"missing sector", "POST", "/v1/farms/1/devices", `{"kind":"valve","name":"Valve 2"}`, http.StatusBadRequest},
+		{"unknown kind", "POST", "/v1/farms/1/devices", `{"sector_id":3,"kind":"pump","name":"Pump"}`, http.StatusBadRequest},
+		{"blank name", "POST", "/v1/farms/1/devices", `{"sector_id":3,"kind":"valve","name":"  "}`, http.StatusBadRequest},
+		{"malformed body", "POST", "/v1/farms/1/devices", `{"sector_id":`, http.StatusBadRequest},
+		{"list", "GET", "/v1/farms/1/devices?sector_id=3", "", http.StatusOK},
+		{"list invalid sector", "GET", "/v1/farms/1/devices?sector_id=x", "", http.StatusBadRequest},
+		{"get", "GET", "/v1/farms/1/devices/1", "", http.StatusOK},
+		{"get unknown", "GET", "/v1/farms/1/devices/2", "", http.StatusNotFound},
+		{"get invalid id", "GET", "/v1/farms/1/devices/x", "", http.StatusBadRequest},
+		{"update", "PUT", "/v1/farms/1/devices/1", `{"sector_id":4,"kind":"flow_meter","name":"Meter A"}`, http.StatusOK},
+		{"update unknown", "PUT", "/v1/farms/1/devices/2", `{"sector_id":4,"kind":"flow_meter","name":"Meter A"}`, http.StatusNotFound},
+		{"update invalid", "PUT", "/v1/farms/1/devices/1", `{"sector_id":4,"kind":"flow_meter"}`, http.StatusBadRequest},
+		{"delete", "DELETE", "/v1/farms/1/devices/1", "", http.StatusNoContent},
+		{"delete unknown", "DELETE", "/v1/farms/1/devices/2", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status code %d, got %d (%s)", tt.expectedCode, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	if mock.created == nil || mock.created.Name != "Valve 2" {
+		t.Errorf("expected the last created device to be passed to the service, got %+v", mock.created)
+	}
+	if mock.listed == nil || *mock.listed != 3 {
+		t.Errorf("expected the sector filter to be passed to the service, got %v", mock.listed)
+	}
+}
@@ -0,0 +1,230 @@
+// API reference rendered from /openapi.json. Like the dashboard it is plain script served by
+// the application itself, so the page loads nothing from outside the server and works without
+// internet access.
+(function () {
+  "use strict";
+
+  var status = document.getElementById("status");
+  var operations = document.getElementById("operations");
+  var filter = document.getElementById("filter");
+  var METHODS = ["get", "post", "put", "patch", "delete"];
+  // Nested schemas are expanded this deep; recursive types such as Farm stop at a $ref name
+  var MAX_DEPTH = 6;
+
+  var spec;
+
+  function element(tag, className, text) {
+    var node = document.createElement(tag);
+    if (className) {
+      node.className = className;
+    }
+    if (text !== undefined) {
+      node.textContent = text;
+    }
+    return node;
+  }
+
+  function refName(ref) {
+    return ref.replace("#/components/schemas/", "");
+  }
+
+  function resolve(schema) {
+    if (schema && schema.$ref) {
+      return spec.components.schemas[refName(schema.$ref)] || {};
+    }
+    return schema || {};
+  }
+
+  // describeType summarises a schema in one line, e.g. "array of DataPoint, nullable"
+  function describeType(schema) {
+    if (schema.$ref) {
+      return refName(schema.$ref);
+    }
+    if (schema.allOf) {
+      return describeType(schema.allOf[0]) + (schema.nullable ? ", nullable" : "");
+    }
+    var text = schema.type || "any";
+    if (schema.type === "array" && schema.items) {
+      text = "array of " + describeType(schema.items);
+    } else if (schema.type === "object" && schema.additionalProperties) {
+      text = "map of " + describeType(schema.additionalProperties);
+    }
+    if (schema.format) {
+      text += " (" + schema.format + ")";
+    }
+    if (schema.enum) {
+      text += ": " + schema.enum.join(" | ");
+    }
+    if (schema.nullable) {
+      text += ", nullable";
+    }
+    return text;
+  }
+
+  // fields returns the object schema whose properties a schema lists, if any
+  function fields(schema) {
+    if (schema.allOf) {
+      return fields(schema.allOf[0]);
+    }
+    if (schema.type === "array" && schema.items) {
+      return fields(schema.items);
+    }
+    var resolved = resolve(schema);
+    return resolved.properties ? resolved : null;
+  }
+
+  function renderSchema(schema, depth, seen) {
+    var object = fields(schema);
+    if (!object || depth > MAX_DEPTH) {
+      return null;
+    }
+    var required = object.required || [];
+    var list = element("ul", "schema");
+    Object.keys(object.properties).sort().forEach(function (name) {
+      var property = object.properties[name];
+      var item = element("li");
+      item.appendChild(element("span", "", name));
+      if (required.indexOf(name) >= 0) {
+        item.appendChild(element("span", "required", "*"));
+      }
+      item.appendChild(element("span", "type", " " + describeType(property)));
+
+      // A type already open further up is not expanded again
+      var ref = property.$ref || (property.items && property.items.$ref) || (property.allOf && property.allOf[0].$ref);
+      if (!ref || seen.indexOf(ref) < 0) {
+        var nested = renderSchema(property, depth + 1, ref ? seen.concat(ref) : seen);
+        if (nested) {
+          item.appendChild(nested);
+        }
+      }
+      list.appendChild(item);
+    });
+    return list;
+  }
+
+  function renderParameters(parameters) {
+    var table = element("table");
+    var head = element("tr");
+    ["Name", "In", "Type", "Description"].forEach(function (title) {
+      head.appendChild(element("th", "", title));
+    });
+    table.appendChild(head);
+    parameters.forEach(function (param) {
+      var row = element("tr");
+      row.appendChild(element("td", "path", param.name + (param.required ? " *" : "")));
+      row.appendChild(element("td", "", param.in));
+      row.appendChild(element("td", "", describeType(param.schema || {})));
+      row.appendChild(element("td", "", param.description || ""));
+      table.appendChild(row);
+    });
+    return table;
+  }
+
+  function renderContent(title, content) {
+    var section = document.createDocumentFragment();
+    Object.keys(content).forEach(function (mediaType) {
+      var schema = content[mediaType].schema || {};
+      section.appendChild(element("h3", "", title + " (" + mediaType + "): " + describeType(schema)));
+      var tree = renderSchema(schema, 0, schema.$ref ? [schema.$ref] : []);
+      if (tree) {
+        section.appendChild(tree);
+      }
+    });
+    return section;
+  }
+
+  function renderOperation(path, method, op) {
+    var details = element("details");
+    details.dataset.search = [method, path, op.summary || "", (op.tags || []).join(" ")].join(" ").toLowerCase();
+
+    var summary = element("summary");
+    summary.appendChild(element("span", "method " + method, method));
+    summary.appendChild(element("span", "path", path));
+    summary.appendChild(element("span", "summary-text", op.summary || ""));
+    details.appendChild(summary);
+
+    var body = element("div", "operation-body");
+    if (op.description) {
+      body.appendChild(element("p", "", op.description));
+    }
+    if (op.parameters && op.parameters.length) {
+      body.appendChild(element("h3", "", "Parameters (* required)"));
+      body.appendChild(renderParameters(op.parameters));
+    }
+    if (op.requestBody) {
+      body.appendChild(renderContent("Request body", op.requestBody.content));
+    }
+    Object.keys(op.responses || {}).forEach(function (code) {
+      var response = op.responses[code];
+      if (code === "default") {
+        return;
+      }
+      body.appendChild(element("h3", "", "Response " + code + " " + response.description));
+      if (response.content) {
+        body.appendChild(renderContent("Body", response.content));
+      }
+    });
+    details.appendChild(body);
+    return details;
+  }
+
+  function render() {
+    document.getElementById("version").textContent = "v" + spec.info.version;
+
+    // Group operations by their first tag, in path order within a tag
+    var byTag = {};
+    Object.keys(spec.paths).sort().forEach(function (path) {
+      METHODS.forEach(function (method) {
+        var op = spec.paths[path][method];
+        if (!op) {
+          return;
+        }
+        var tag = (op.tags && op.tags[0]) || "other";
+        (byTag[tag] = byTag[tag] || []).push(renderOperation(path, method, op));
+      });
+    });
+
+    Object.keys(byTag).sort().forEach(function (tag) {
+      var section = element("section");
+      section.appendChild(element("h2", "", tag));
+      byTag[tag].forEach(function (node) {
+        section.appendChild(node);
+      });
+      operations.appendChild(section);
+    });
+  }
+
+  function applyFilter() {
+    var term = filter.value.trim().toLowerCase();
+    Array.prototype.forEach.call(operations.querySelectorAll("section"), function (section) {
+      var visible = 0;
+      Array.prototype.forEach.call(section.querySelectorAll("details"), function (details) {
+        var match = !term || details.dataset.search.indexOf(term) >= 0;
+        details.hidden = !match;
+        if (match) {
+          visible++;
+        }
+      });
+      section.hidden = visible === 0;
+    });
+  }
+
+  filter.addEventListener("input", applyFilter);
+
+  fetch("/openapi.json")
+    .then(function (response) {
+      if (!response.ok) {
+        throw new Error(response.statusText);
+      }
+      return response.json();
+    })
+    .then(function (body) {
+      spec = body;
+      render();
+      status.textContent = "";
+    })
+    .catch(function (err) {
+      status.textContent = "Could not load the API document: " + err.message;
+      status.className = "error";
+    });
+})();
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// docsAssets holds the API reference page, script and stylesheet
+//
+//go:embed docs
+var docsAssets embed.FS
+
+// DocsController serves the OpenAPI document and an API reference page rendering it. The page
+// is embedded like the dashboard, so /docs runs no third-party code and works offline.
+type DocsController struct {
+	spec   []byte
+	assets fs.FS
+	logger *slog.Logger
+}
+
+// NewDocsController creates a new docs controller. The OpenAPI document is generated once,
+// from the documented routes and their request and response types.
+func NewDocsController(apiVersion string, logger *slog.Logger) (*DocsController, error) {
+	spec, err := json.Marshal(BuildOpenAPI(apiRoutes, apiVersion))
+	if err != nil {
+		return nil, err
+	}
+	assets, _ := fs.Sub(docsAssets, "docs")
+	return &DocsController{spec: spec, assets: assets, logger: logger}, nil
+}
+
+// OpenAPISpec handles GET /openapi.json
+func (c *DocsController) OpenAPISpec(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "application/json; charset=utf-8", c.spec)
+}
+
+// Reference handles GET /docs and GET /docs/*filepath
+// Serves the reference page at /docs and its assets by name
+func (c *DocsController) Reference(ctx *gin.Context) {
+	name := strings.TrimPrefix(ctx.Param("filepath"), "/")
+	if name == "" {
+		name = "index.html"
+	}
+	serveAsset(ctx, c.assets, name, "unknown docs asset")
+}
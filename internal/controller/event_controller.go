@@ -0,0 +1,311 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EventController handles HTTP requests on individual irrigation events
+type EventController struct {
+	eventService service.EventService
+	logger       *slog.Logger
+}
+
+// NewEventController creates a new event controller
+func NewEventController(eventService service.EventService, logger *slog.Logger) *EventController {
+	return &EventController{
+		eventService: eventService,
+		logger:       logger,
+	}
+}
+
+// classifyEventRequest is the body of an event classification request
+type classifyEventRequest struct {
+	Purpose string `json:"purpose"`
+}
+
+// ClassifyEvent handles PATCH /v1/farms/{farm_id}/irrigation/events/{event_id}
+// Body fields:
+//   - purpose (required): irrigation, frost_protection, leaching, or system_flush
+func (c *EventController) ClassifyEvent(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	eventID, ok := parseEventID(ctx)
+	if !ok {
+		return
+	}
+
+	var req classifyEventRequest
+	err := ctx.ShouldBindJSON(&req)
+	if writeBodyTooLarge(ctx, c.logger, farmID, err) {
+		return
+	}
+	if err != nil || !model.IsValidPurpose(req.Purpose) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid purpose",
+			"message": "purpose must be one of: irrigation, frost_protection, leaching, system_flush",
+		})
+		return
+	}
+
+	if !ensureFarmExists(ctx, c.logger, c.eventService, farmID, startTime) {
+		return
+	}
+
+	event, err := c.eventService.ClassifyEvent(farmID, eventID, req.Purpose)
+	if errors.Is(err, service.ErrEventNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Event not found",
+			"message": fmt.Sprintf("Event with ID %d does not exist for farm %d", eventID, farmID),
+		})
+		return
+	}
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to classify event",
+			"farm_id", farmID,
+			"event_id", eventID,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to classify event",
+		})
+		return
+	}
+
+	c.logger.Info("event classified",
+		"farm_id", farmID,
+		"event_id", eventID,
+		"purpose", req.Purpose,
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusOK, event)
+}
+
+// RecomputeDurations handles POST /v1/farms/{farm_id}/irrigation/durations/recompute
+// Query parameters:
+//   - start_date (required): Start date in ISO 8601 format (RFC3339 or YYYY-MM-DD)
+//   - end_date (required): End date in ISO 8601 format (RFC3339 or YYYY-MM-DD)
+//   - dry_run (optional): true to report the corrections without writing them
+//
+// Rewrites the duration of every event in the range whose stored duration differs from its start
+// and end times, and reports each correction.
+func (c *EventController) RecomputeDurations(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	startDate, endDate, ok := parseDateRange(ctx, c.logger, farmID)
+	if !ok {
+		return
+	}
+	dryRun, ok := parseBoolQuery(ctx, "dry_run")
+	if !ok {
+		return
+	}
+
+	if !ensureFarmExists(ctx, c.logger, c.eventService, farmID, startTime) {
+		return
+	}
+
+	result, err := c.eventService.RecomputeDurations(farmID, startDate, endDate, dryRun)
+	if err != nil {
+		c.logger.Error("failed to recompute durations",
+			"farm_id", farmID,
+			"start_date", startDate.Format(time.RFC3339),
+			"end_date", endDate.Format(time.RFC3339),
+			"error", err.Error(),
+			"latency_ms", time.Since(startTime).Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to recompute durations; nothing was changed",
+		})
+		return
+	}
+
+	c.logger.Info("durations recomputed",
+		"farm_id", farmID,
+		"dry_run", dryRun,
+		"corrected", result.Corrected,
+		"invalid_events", result.InvalidEvents,
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// ListEvents handles GET /v1/farms/{farm_id}/irrigation/events
+// Query parameters:
+//   - start_date (required): Start date in ISO 8601 format (RFC3339 or YYYY-MM-DD)
+//   - end_date (required): End date in ISO 8601 format (RFC3339 or YYYY-MM-DD)
+//   - sector_id (optional): Only events of this sector and its zones
+//   - min_volume, max_volume (optional): Inclusive water volume bounds in liters
+//   - purpose (optional): comma-separated event purposes to include
+//   - device_id (optional): Only events measured by this registered device
+//   - sort (optional): start_time (default), end_time, water_volume, duration or real_amount
+//   - order (optional): asc (default) or desc
+//   - limit (optional): number of events to return, 1-1000 (default: 100)
+//   - offset (optional): number of matching events to skip (default: 0)
+//
+// Lists the raw events behind the analytics data points, selected as the analytics endpoint
+// selects them, so a bucket can be drilled into with its own start and end.
+func (c *EventController) ListEvents(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	startDate, endDate, ok := parseDateRange(ctx, c.logger, farmID)
+	if !ok {
+		return
+	}
+	sectorID, ok := parseSectorID(ctx, c.logger, farmID)
+	if !ok {
+		return
+	}
+	purposes, ok := parsePurposes(ctx)
+	if !ok {
+		return
+	}
+	deviceID, ok := parseDeviceIDQuery(ctx)
+	if !ok {
+		return
+	}
+
+	opts := service.EventListOptions{SectorID: sectorID, Purposes: purposes, DeviceID: deviceID}
+	if opts.MinVolume, ok = parseVolumeQuery(ctx, "min_volume"); !ok {
+		return
+	}
+	if opts.MaxVolume, ok = parseVolumeQuery(ctx, "max_volume"); !ok {
+		return
+	}
+	if opts.MinVolume != nil && opts.MaxVolume != nil && *opts.MinVolume > *opts.MaxVolume {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid volume range",
+			"message": "min_volume must not exceed max_volume",
+		})
+		return
+	}
+
+	opts.Sort = ctx.DefaultQuery("sort", service.DefaultEventSort)
+	if !service.IsValidEventSort(opts.Sort) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid sort",
+			"message": "sort must be one of: start_time, end_time, water_volume, duration, real_amount",
+		})
+		return
+	}
+	switch order := ctx.DefaultQuery("order", "asc"); order {
+	case "asc":
+	case "desc":
+		opts.Descending = true
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid order",
+			"message": "order must be asc or desc",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(service.DefaultEventPageLimit)))
+	if err != nil || limit < 1 || limit > service.MaxEventPageLimit {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid limit",
+			"message": fmt.Sprintf("limit must be an integer between 1 and %d", service.MaxEventPageLimit),
+		})
+		return
+	}
+	offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid offset",
+			"message": "offset must be a non-negative integer",
+		})
+		return
+	}
+	opts.Limit, opts.Offset = limit, offset
+
+	if !ensureFarmExists(ctx, c.logger, c.eventService, farmID, startTime) {
+		return
+	}
+
+	page, err := c.eventService.ListEvents(farmID, startDate, endDate, opts)
+	if err != nil {
+		c.logger.Error("failed to list events",
+			"farm_id", farmID,
+			"start_date", startDate.Format(time.RFC3339),
+			"end_date", endDate.Format(time.RFC3339),
+			"error", err.Error(),
+			"latency_ms", time.Since(startTime).Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to list events",
+		})
+		return
+	}
+
+	c.logger.Info("events listed",
+		"farm_id", farmID,
+		"sector_id", sectorID,
+		"sort", opts.Sort,
+		"offset", offset,
+		"returned", len(page.Events),
+		"total", page.Total,
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusOK, page)
+}
+
+// parseVolumeQuery parses an optional non-negative volume query parameter, writing a 400
+// response when it is invalid
+func parseVolumeQuery(ctx *gin.Context, name string) (*float64, bool) {
+	value := ctx.Query(name)
+	if value == "" {
+		return nil, true
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || parsed < 0 || math.IsInf(parsed, 0) || math.IsNaN(parsed) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid " + name,
+			"message": name + " must be a non-negative number of liters",
+		})
+		return nil, false
+	}
+	return &parsed, true
+}
+
+// parseEventID parses the event_id path parameter, writing a 400 response when it is invalid
+func parseEventID(ctx *gin.Context) (uint, bool) {
+	eventID, err := strconv.ParseUint(ctx.Param("event_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid event_id",
+			"message": "event_id must be a valid unsigned integer",
+		})
+		return 0, false
+	}
+	return uint(eventID), true
+}
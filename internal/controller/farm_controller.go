@@ -0,0 +1,267 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FarmController handles farm lifecycle HTTP requests
+type FarmController struct {
+	farmService service.FarmService
+	logger      *slog.Logger
+}
+
+// NewFarmController creates a new farm controller
+func NewFarmController(farmService service.FarmService, logger *slog.Logger) *FarmController {
+	return &FarmController{
+		farmService: farmService,
+		logger:      logger,
+	}
+}
+
+// ArchiveFarm handles POST /v1/farms/{farm_id}/archive
+// Archived farms are left out of cross-farm aggregates unless include_archived=true is passed;
+// their own analytics stay available. Archiving an archived farm keeps its archived_at.
+func (c *FarmController) ArchiveFarm(ctx *gin.Context) {
+	c.setArchived(ctx, true)
+}
+
+// UnarchiveFarm handles POST /v1/farms/{farm_id}/unarchive
+func (c *FarmController) UnarchiveFarm(ctx *gin.Context) {
+	c.setArchived(ctx, false)
+}
+
+func (c *FarmController) setArchived(ctx *gin.Context, archive bool) {
+	startTime := time.Now()
+
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	action := c.farmService.UnarchiveFarm
+	if archive {
+		action = c.farmService.ArchiveFarm
+	}
+	farm, err := action(farmID)
+	if errors.Is(err, service.ErrFarmNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Farm not found",
+			"message": "No farm found with the specified ID",
+		})
+		return
+	}
+	if err != nil {
+		c.logger.Error("failed to update farm archive state",
+			"farm_id", farmID,
+			"archive", archive,
+			"error", err.Error(),
+			"latency_ms", time.Since(startTime).Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to update the farm",
+		})
+		return
+	}
+
+	c.logger.Info("farm archive state updated",
+		"farm_id", farmID,
+		"archived", farm.ArchivedAt != nil,
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+	ctx.JSON(http.StatusOK, farm)
+}
+
+// attributionPolicyRequest is the body of SetAttributionPolicy
+type attributionPolicyRequest struct {
+	Policy string `json:"policy"`
+}
+
+// SetAttributionPolicy handles PUT /v1/farms/{farm_id}/attribution-policy
+// Body fields:
+//   - policy (required): start, end or proportional; how events spanning several buckets are
+//     attributed in every aggregation of the farm
+func (c *FarmController) SetAttributionPolicy(ctx *gin.Context) {
+	startTime := time.Now()
+
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	var req attributionPolicyRequest
+	err := ctx.ShouldBindJSON(&req)
+	if writeBodyTooLarge(ctx, c.logger, farmID, err) {
+		return
+	}
+	if err != nil || !model.IsValidAttributionPolicy(req.Policy) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid policy",
+			"message": "policy must be one of: start, end, proportional",
+		})
+		return
+	}
+
+	farm, err := c.farmService.SetAttributionPolicy(farmID, req.Policy)
+	if errors.Is(err, service.ErrFarmNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Farm not found",
+			"message": "No farm found with the specified ID",
+		})
+		return
+	}
+	if err != nil {
+		c.logger.Error("failed to update farm attribution policy",
+			"farm_id", farmID,
+			"policy", req.Policy,
+			"error", err.Error(),
+			"latency_ms", time.Since(startTime).Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to update the farm",
+		})
+		return
+	}
+
+	c.logger.Info("farm attribution policy updated",
+		"farm_id", farmID,
+		"policy", farm.AttributionPolicy,
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+	ctx.JSON(http.StatusOK, farm)
+}
+
+// waterAccountRequest is the body of SetWaterAccount
+type waterAccountRequest struct {
+	WaterAccount string `json:"water_account"`
+}
+
+// SetWaterAccount handles PUT /v1/farms/{farm_id}/water-account
+// Body fields:
+//   - water_account (required): the farm's account with its irrigation district, at most 64
+//     characters; an empty account clears it
+func (c *FarmController) SetWaterAccount(ctx *gin.Context) {
+	startTime := time.Now()
+
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	var req waterAccountRequest
+	err := ctx.ShouldBindJSON(&req)
+	if writeBodyTooLarge(ctx, c.logger, farmID, err) {
+		return
+	}
+	account := strings.TrimSpace(req.WaterAccount)
+	if err != nil || len(account) > service.MaxWaterAccountLength {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid water account",
+			"message": "water_account must be a string of at most 64 characters",
+		})
+		return
+	}
+
+	farm, err := c.farmService.SetWaterAccount(farmID, account)
+	if errors.Is(err, service.ErrFarmNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Farm not found",
+			"message": "No farm found with the specified ID",
+		})
+		return
+	}
+	if err != nil {
+		c.logger.Error("failed to update farm water account",
+			"farm_id", farmID,
+			"error", err.Error(),
+			"latency_ms", time.Since(startTime).Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to update the farm",
+		})
+		return
+	}
+
+	c.logger.Info("farm water account updated",
+		"farm_id", farmID,
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+	ctx.JSON(http.StatusOK, farm)
+}
+
+// cropYearRequest is the body of SetCropYear
+type cropYearRequest struct {
+	CropYearStart string `json:"crop_year_start"`
+}
+
+// SetCropYear handles PUT /v1/farms/{farm_id}/crop-year
+// Body fields:
+//   - crop_year_start (required): MM-DD day the farm's crop year starts, e.g. 10-01; 02-29 is not
+//     allowed, and an empty value resets it to 01-01
+//
+// Season-aligned year-over-year comparisons (yoy_alignment=season) align on this day.
+func (c *FarmController) SetCropYear(ctx *gin.Context) {
+	startTime := time.Now()
+
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	var req cropYearRequest
+	err := ctx.ShouldBindJSON(&req)
+	if writeBodyTooLarge(ctx, c.logger, farmID, err) {
+		return
+	}
+	var cropYear model.CropYear
+	if err == nil {
+		cropYear, err = model.ParseCropYear(strings.TrimSpace(req.CropYearStart))
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid crop year start",
+			"message": "crop_year_start must be a day in MM-DD format, other than 02-29",
+		})
+		return
+	}
+
+	farm, err := c.farmService.SetCropYearStart(farmID, cropYear)
+	if errors.Is(err, service.ErrFarmNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Farm not found",
+			"message": "No farm found with the specified ID",
+		})
+		return
+	}
+	if err != nil {
+		c.logger.Error("failed to update farm crop year",
+			"farm_id", farmID,
+			"crop_year_start", cropYear.String(),
+			"error", err.Error(),
+			"latency_ms", time.Since(startTime).Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to update the farm",
+		})
+		return
+	}
+
+	c.logger.Info("farm crop year updated",
+		"farm_id", farmID,
+		"crop_year_start", farm.CropYearStart,
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+	ctx.JSON(http.StatusOK, farm)
+}
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FaultController handles the fault injection admin routes. They are registered in the /admin
+// group only when FAULT_INJECTION_ENABLED is true, which staging sets and production never does.
+type FaultController struct {
+	faultService service.FaultAdminService
+	logger       *slog.Logger
+}
+
+// NewFaultController creates a new fault controller
+func NewFaultController(faultService service.FaultAdminService, logger *slog.Logger) *FaultController {
+	return &FaultController{
+		faultService: faultService,
+		logger:       logger,
+	}
+}
+
+// GetFaults handles GET /admin/faults
+// Returns the live rules with their counters and the targets rules can be set on
+func (c *FaultController) GetFaults(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, c.faultService.ListFaults())
+}
+
+// SetFault handles PUT /admin/faults
+// Body fields:
+//   - target (required): an irrigation repository method, or db for every statement
+//   - latency_ms (optional): delay added before each call, up to 60000
+//   - error_rate (optional): share of calls failed, 0 to 1
+//   - duration_seconds (optional): how long the rule lasts, default 600, at most 3600
+//
+// Replaces the target's rule. At least one of latency_ms and error_rate must be set.
+func (c *FaultController) SetFault(ctx *gin.Context) {
+	var req service.FaultInput
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": "body must be a JSON object with a target and latency_ms or error_rate",
+		})
+		return
+	}
+
+	rule, err := c.faultService.SetFault(req)
+	if errors.Is(err, service.ErrInvalidFault) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid fault rule",
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to set the fault rule",
+		})
+		return
+	}
+
+	// Logged at Warn so injected failures can be told apart from real ones
+	c.logger.Warn("fault rule set",
+		"target", rule.Target,
+		"latency_ms", rule.LatencyMs,
+		"error_rate", rule.ErrorRate,
+		"expires_at", rule.ExpiresAt,
+	)
+	ctx.JSON(http.StatusOK, rule)
+}
+
+// DeleteFault handles DELETE /admin/faults/{target}
+// Removes one target's rule, and the response reports whether it was set
+func (c *FaultController) DeleteFault(ctx *gin.Context) {
+	target := ctx.Param("target")
+	deleted := c.faultService.DeleteFault(target)
+	c.logger.Warn("fault rule deleted",
+		"target", target,
+		"deleted", deleted,
+	)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"target":  target,
+		"deleted": deleted,
+	})
+}
+
+// ClearFaults handles DELETE /admin/faults
+// Removes every rule
+func (c *FaultController) ClearFaults(ctx *gin.Context) {
+	c.faultService.ClearFaults()
+	c.logger.Warn("fault rules cleared")
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/repository"
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSetFault(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	faults := repository.NewFaultInjector()
+	controller := NewFaultController(service.NewFaultAdminService(faults), slog.Default())
+	r := gin.New()
+	r.PUT("/admin/faults", controller.SetFault)
+
+	tests := []struct {
+		body         string
+		expectedCode int
+	}{
+		{`{"target": "GetDistributionData", "error_rate": 1}`, http.StatusOK},
+		{`{"target": "db", "latency_ms": 250, "duration_seconds": 60}`, http.StatusOK},
+		{`{"target": "DropDatabase", "error_rate": 1}`, http.StatusBadRequest},
+		{`{"target": "db"}`, http.StatusBadRequest},
+		{`{"target": "db", "error_rate": 1.5}`, http.StatusBadRequest},
+		{`{"target": "db", "latency_ms": 120000}`, http.StatusBadRequest},
+		{`{"target": "db", "latency_ms": 10, "duration_seconds": 86400}`, http.StatusBadRequest},
+		{`[]`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("PUT", "/admin/faults", bytes.NewBufferString(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.expectedCode {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.body, tt.expectedCode, w.Code, w.Body.String())
+		}
+	}
+
+	rules := faults.Rules()
+	if len(rules) != 2 || rules[0].Target != "GetDistributionData" || rules[1].Target != "db" || rules[1].LatencyMs != 250 {
+		t.Errorf("expected the two valid rules set, got %+v", rules)
+	}
+
+	var rule repository.FaultRule
+	req, _ := http.NewRequest("PUT", "/admin/faults", bytes.NewBufferString(`{"target": "FarmExists", "error_rate": 0.5}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	// Without duration_seconds the rule lasts the default 10 minutes, past the 60s db rule
+	if err := json.Unmarshal(w.Body.Bytes(), &rule); err != nil || rule.Target != "FarmExists" || !rule.ExpiresAt.After(rules[1].ExpiresAt.Add(500*time.Second)) {
+		t.Errorf("expected the rule with the default duration, got %s", w.Body.String())
+	}
+}
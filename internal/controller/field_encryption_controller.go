@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FieldEncryptionController handles the admin routes of encrypted columns
+type FieldEncryptionController struct {
+	encryptionService service.FieldEncryptionService
+	logger            *slog.Logger
+}
+
+// NewFieldEncryptionController creates a new field encryption controller
+func NewFieldEncryptionController(encryptionService service.FieldEncryptionService, logger *slog.Logger) *FieldEncryptionController {
+	return &FieldEncryptionController{
+		encryptionService: encryptionService,
+		logger:            logger,
+	}
+}
+
+// GetStatus handles GET /admin/encryption
+// Returns the configured key IDs and, for every encrypted column, how many values are stored in
+// plaintext and under each key
+func (c *FieldEncryptionController) GetStatus(ctx *gin.Context) {
+	status, err := c.encryptionService.GetStatus()
+	if err != nil {
+		c.logger.Error("failed to read field encryption status",
+			"error", err.Error(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to read the encrypted columns",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, status)
+}
+
+// Reencrypt handles POST /admin/encryption/reencrypt
+// Rewrites every farm's and sector's encrypted columns with the primary key. Returns 409 when no
+// field keys are configured.
+func (c *FieldEncryptionController) Reencrypt(ctx *gin.Context) {
+	startTime := time.Now()
+
+	result, err := c.encryptionService.Reencrypt()
+	if errors.Is(err, service.ErrFieldEncryptionDisabled) {
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error":   "Encryption disabled",
+			"message": "set FIELD_ENCRYPTION_KEYS before re-encrypting",
+		})
+		return
+	}
+	if err != nil {
+		c.logger.Error("field re-encryption failed",
+			"error", err.Error(),
+			"latency_ms", time.Since(startTime).Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Re-encryption stopped; batches already rewritten are kept, and running it again resumes safely",
+		})
+		return
+	}
+
+	// Logged at Warn so key changes are recorded whatever the log level
+	c.logger.Warn("fields re-encrypted",
+		"key_id", result.KeyID,
+		"farms", result.Farms,
+		"sectors", result.Sectors,
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+	ctx.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FixturesController serves canonical example responses for client contract tests.
+// Register its routes only outside production (GIN_MODE != release).
+type FixturesController struct {
+	logger *slog.Logger
+}
+
+// NewFixturesController creates a new fixtures controller
+func NewFixturesController(logger *slog.Logger) *FixturesController {
+	return &FixturesController{logger: logger}
+}
+
+// ListFixtures handles GET /v1/fixtures
+// Returns every schema version's fixtures, keyed by version and then by fixture name
+func (c *FixturesController) ListFixtures(ctx *gin.Context) {
+	versions := make(map[string]map[string]any)
+	for _, version := range service.FixtureVersions() {
+		versions[version], _ = service.Fixtures(version)
+	}
+	ctx.JSON(http.StatusOK, gin.H{"versions": versions})
+}
+
+// GetFixture handles GET /v1/fixtures/{version}/{name}
+// Returns one fixture exactly as the corresponding endpoint would serialize it
+func (c *FixturesController) GetFixture(ctx *gin.Context) {
+	version := ctx.Param("version")
+	fixtures, ok := service.Fixtures(version)
+	if !ok {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":    "Not found",
+			"message":  "unknown schema version",
+			"versions": service.FixtureVersions(),
+		})
+		return
+	}
+
+	fixture, ok := fixtures[ctx.Param("name")]
+	if !ok {
+		names := make([]string, 0, len(fixtures))
+		for name := range fixtures {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":    "Not found",
+			"message":  "unknown fixture",
+			"fixtures": names,
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, fixture)
+}
@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"log/slog"
+	"net/http"
+
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthController handles liveness and readiness probe HTTP requests
+type HealthController struct {
+	healthService service.HealthService
+	logger        *slog.Logger
+}
+
+// NewHealthController creates a new health controller
+func NewHealthController(healthService service.HealthService, logger *slog.Logger) *HealthController {
+	return &HealthController{
+		healthService: healthService,
+		logger:        logger,
+	}
+}
+
+// Liveness handles GET /healthz
+// Returns 200 while the process can serve requests; dependencies are not checked
+func (c *HealthController) Liveness(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, c.healthService.Liveness())
+}
+
+// Readiness handles GET /readyz
+// Returns 200 when the database answers and the migrations are applied, and 503 with the
+// failing checks otherwise
+func (c *HealthController) Readiness(ctx *gin.Context) {
+	report := c.healthService.Readiness(ctx.Request.Context())
+	if report.Status != service.HealthStatusOK {
+		c.logger.Warn("readiness check failed", "checks", report.Checks)
+		ctx.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	ctx.JSON(http.StatusOK, report)
+}
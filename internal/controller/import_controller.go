@@ -0,0 +1,513 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportRows caps the number of rows accepted in a single import request
+const maxImportRows = 200000
+
+// maxIdempotencyKeyLength matches the idempotency key column size
+const maxIdempotencyKeyLength = 255
+
+// ImportController handles bulk import HTTP requests
+type ImportController struct {
+	importService service.ImportService
+	logger        *slog.Logger
+	// idempotency replays imports sent again with the same Idempotency-Key; nil ignores the header
+	idempotency service.IdempotencyService
+}
+
+// NewImportController creates a new import controller
+func NewImportController(importService service.ImportService, logger *slog.Logger) *ImportController {
+	return &ImportController{
+		importService: importService,
+		logger:        logger,
+	}
+}
+
+// EnableIdempotency honours the Idempotency-Key header on ImportEvents
+func (c *ImportController) EnableIdempotency(idempotency service.IdempotencyService) {
+	c.idempotency = idempotency
+}
+
+// importRequest is the body of a bulk import request
+type importRequest struct {
+	ImportID *uint               `json:"import_id"`
+	Events   []service.ImportRow `json:"events"`
+}
+
+// ImportEvents handles POST /v1/farms/{farm_id}/irrigation/imports
+// Body fields:
+//   - events (required): irrigation events to import, committed in chunks of 1,000
+//   - import_id (optional): resume a failed import by resubmitting the same events
+//
+// Query parameters:
+//   - dry_run (optional): true to validate and report accepted/rejected rows and their impact without writing
+//
+// Headers:
+//   - Idempotency-Key (optional): a retry with the same key and body gets the original response
+//     instead of importing again, while the key is kept; the same key with another body is a 422
+func (c *ImportController) ImportEvents(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	var req importRequest
+	err := ctx.ShouldBindJSON(&req)
+	if writeBodyTooLarge(ctx, c.logger, farmID, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": "body must be a JSON object with an events array",
+		})
+		return
+	}
+	if len(req.Events) == 0 || len(req.Events) > maxImportRows {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid events",
+			"message": fmt.Sprintf("events must contain between 1 and %d rows", maxImportRows),
+		})
+		return
+	}
+
+	dryRun, ok := parseBoolQuery(ctx, "dry_run")
+	if !ok {
+		return
+	}
+
+	if !ensureFarmExists(ctx, c.logger, c.importService, farmID, startTime) {
+		return
+	}
+
+	if dryRun {
+		c.previewImport(ctx, farmID, req.Events, startTime)
+		return
+	}
+
+	key := ctx.GetHeader("Idempotency-Key")
+	if key == "" || c.idempotency == nil {
+		status, body := c.importEvents(farmID, req, startTime)
+		ctx.JSON(status, body)
+		return
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid Idempotency-Key",
+			"message": fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength),
+		})
+		return
+	}
+
+	record, replay, err := c.idempotency.Begin(farmID, service.IdempotencyScopeImports, key, req)
+	switch {
+	case errors.Is(err, service.ErrIdempotencyKeyReused):
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "Idempotency key reused",
+			"message": "Idempotency-Key was already used with a different request body",
+		})
+		return
+	case errors.Is(err, service.ErrIdempotencyInProgress):
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error":   "Request in progress",
+			"message": "the first request with this Idempotency-Key has not finished; retry later",
+		})
+		return
+	case err != nil:
+		latency := time.Since(startTime)
+		c.logger.Error("failed to reserve idempotency key",
+			"farm_id", farmID,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to check the Idempotency-Key",
+		})
+		return
+	case replay:
+		c.logger.Info("import replayed",
+			"farm_id", farmID,
+			"idempotency_key", key,
+			"latency_ms", time.Since(startTime).Milliseconds(),
+		)
+		ctx.Header("Idempotent-Replayed", "true")
+		ctx.Data(record.StatusCode, "application/json; charset=utf-8", record.Response)
+		return
+	}
+
+	status, body := c.importEvents(farmID, req, startTime)
+	// Record the outcome first, so a retry sent as soon as this response arrives is replayed
+	c.finishIdempotent(farmID, record, status, body)
+	ctx.JSON(status, body)
+}
+
+// finishIdempotent stores a successful response for replay. Other responses, or a response that
+// couldn't be stored, free the key so the request can be retried with it.
+func (c *ImportController) finishIdempotent(farmID uint, record *model.IdempotencyKey, status int, body any) {
+	if status == http.StatusOK {
+		err := c.idempotency.Complete(record, status, body)
+		if err == nil {
+			return
+		}
+		c.logger.Error("failed to store idempotent response",
+			"farm_id", farmID,
+			"idempotency_key", record.Key,
+			"error", err.Error(),
+		)
+	}
+	if err := c.idempotency.Release(record); err != nil {
+		c.logger.Error("failed to release idempotency key",
+			"farm_id", farmID,
+			"idempotency_key", record.Key,
+			"error", err.Error(),
+		)
+	}
+}
+
+// importEvents runs an import request, returning the response status and body
+func (c *ImportController) importEvents(farmID uint, req importRequest, startTime time.Time) (int, any) {
+	result, err := c.importService.ImportEvents(farmID, req.ImportID, req.Events)
+	switch {
+	case errors.Is(err, service.ErrImportNotFound):
+		return http.StatusNotFound, gin.H{
+			"error":   "Import not found",
+			"message": fmt.Sprintf("Import with ID %d does not exist for farm %d", *req.ImportID, farmID),
+		}
+	case errors.Is(err, service.ErrImportMismatch):
+		return http.StatusConflict, gin.H{
+			"error":   "Import mismatch",
+			"message": "a resumed import must resubmit the same events as the original request",
+		}
+	case err != nil:
+		latency := time.Since(startTime)
+		c.logger.Error("import failed",
+			"farm_id", farmID,
+			"rows", len(req.Events),
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		body := gin.H{
+			"error":   "Internal server error",
+			"message": "Import failed; resubmit the same events with import_id to resume",
+		}
+		if result != nil {
+			body["import"] = result.Import
+		}
+		return http.StatusInternalServerError, body
+	}
+
+	c.logger.Info("import completed",
+		"farm_id", farmID,
+		"import_id", result.Import.ID,
+		"imported_rows", result.Import.ImportedRows,
+		"rejected_rows", result.Import.RejectedRows,
+		"duplicate_rows", result.Import.DuplicateRows,
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	return http.StatusOK, result
+}
+
+// previewImport writes the dry-run result of an import request
+func (c *ImportController) previewImport(ctx *gin.Context, farmID uint, rows []service.ImportRow, startTime time.Time) {
+	preview, err := c.importService.PreviewImport(farmID, rows)
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("import dry run failed",
+			"farm_id", farmID,
+			"rows", len(rows),
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to preview import",
+		})
+		return
+	}
+
+	c.logger.Info("import dry run completed",
+		"farm_id", farmID,
+		"accepted_rows", preview.AcceptedRows,
+		"rejected_rows", preview.RejectedRows,
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusOK, preview)
+}
+
+// PreviewBackfill handles POST /v1/farms/{farm_id}/irrigation/backfill/preview
+// Takes the same body as an import and reports, for each calendar month the events fall in,
+// the summary and year-over-year changes with and without them. Nothing is written.
+func (c *ImportController) PreviewBackfill(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	var req importRequest
+	err := ctx.ShouldBindJSON(&req)
+	if writeBodyTooLarge(ctx, c.logger, farmID, err) {
+		return
+	}
+	if err != nil || len(req.Events) == 0 || len(req.Events) > maxImportRows {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": fmt.Sprintf("body must be a JSON object with an events array of 1 to %d rows", maxImportRows),
+		})
+		return
+	}
+
+	if !ensureFarmExists(ctx, c.logger, c.importService, farmID, startTime) {
+		return
+	}
+
+	preview, err := c.importService.PreviewBackfill(farmID, req.Events)
+	if errors.Is(err, service.ErrBackfillTooWide) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Backfill too wide",
+			"message": "a backfill preview can cover at most 36 calendar months",
+		})
+		return
+	}
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("backfill preview failed",
+			"farm_id", farmID,
+			"rows", len(req.Events),
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to preview backfill",
+		})
+		return
+	}
+
+	c.logger.Info("backfill preview completed",
+		"farm_id", farmID,
+		"accepted_rows", preview.Import.AcceptedRows,
+		"periods", len(preview.Periods),
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusOK, preview)
+}
+
+// ImportFile handles POST /v1/farms/{farm_id}/irrigation/import
+// Multipart form fields:
+//   - file (required): a .csv or .xlsx spreadsheet whose first row names the columns sector_id,
+//     start_time, end_time, water_volume, real_amount and optionally nominal_amount, purpose and
+//     volume_unit
+//   - import_id (optional): resume a failed import by uploading the same file again
+//
+// Query parameters:
+//   - dry_run (optional): true to validate and report accepted/rejected rows and their impact without writing
+//
+// Rejected rows are identified by their line in the file, counting the header as line 1.
+func (c *ImportController) ImportFile(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	header, err := ctx.FormFile("file")
+	if writeBodyTooLarge(ctx, c.logger, farmID, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Missing file",
+			"message": "upload the spreadsheet as the file field of a multipart form",
+		})
+		return
+	}
+
+	var importID *uint
+	if value := ctx.PostForm("import_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid import_id",
+				"message": "import_id must be a valid unsigned integer",
+			})
+			return
+		}
+		resumeID := uint(id)
+		importID = &resumeID
+	}
+
+	dryRun, ok := parseBoolQuery(ctx, "dry_run")
+	if !ok {
+		return
+	}
+
+	upload, err := header.Open()
+	if err != nil {
+		c.logger.Error("failed to open uploaded file",
+			"farm_id", farmID,
+			"filename", header.Filename,
+			"error", err.Error(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to read the uploaded file",
+		})
+		return
+	}
+	defer upload.Close()
+
+	var file *service.ImportFile
+	switch strings.ToLower(filepath.Ext(header.Filename)) {
+	case ".csv":
+		file, err = service.ParseImportCSV(upload)
+	case ".xlsx":
+		file, err = service.ParseImportXLSX(upload, header.Size)
+	default:
+		ctx.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error":   "Unsupported file type",
+			"message": "file must be a .csv or .xlsx spreadsheet",
+		})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidImportFile) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid file",
+			"message": err.Error(),
+		})
+		return
+	}
+	if writeBodyTooLarge(ctx, c.logger, farmID, err) {
+		return
+	}
+	if err != nil {
+		c.logger.Error("failed to parse uploaded file",
+			"farm_id", farmID,
+			"filename", header.Filename,
+			"error", err.Error(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to read the uploaded file",
+		})
+		return
+	}
+	if file.TotalRows == 0 || file.TotalRows > maxImportRows {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid file",
+			"message": fmt.Sprintf("file must contain between 1 and %d data rows", maxImportRows),
+		})
+		return
+	}
+
+	if !ensureFarmExists(ctx, c.logger, c.importService, farmID, startTime) {
+		return
+	}
+
+	report, err := c.importService.ImportFile(farmID, importID, file, dryRun)
+	switch {
+	case errors.Is(err, service.ErrImportNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Import not found",
+			"message": fmt.Sprintf("Import with ID %d does not exist for farm %d", *importID, farmID),
+		})
+		return
+	case errors.Is(err, service.ErrImportMismatch):
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error":   "Import mismatch",
+			"message": "a resumed import must upload the same file as the original request",
+		})
+		return
+	case err != nil:
+		latency := time.Since(startTime)
+		c.logger.Error("file import failed",
+			"farm_id", farmID,
+			"filename", header.Filename,
+			"rows", file.TotalRows,
+			"dry_run", dryRun,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		body := gin.H{
+			"error":   "Internal server error",
+			"message": "Import failed; upload the same file with import_id to resume",
+		}
+		if report != nil && report.Import != nil {
+			body["import"] = report.Import
+		}
+		ctx.JSON(http.StatusInternalServerError, body)
+		return
+	}
+
+	c.logger.Info("file import completed",
+		"farm_id", farmID,
+		"filename", header.Filename,
+		"format", file.Format,
+		"dry_run", dryRun,
+		"accepted_rows", report.AcceptedRows,
+		"rejected_rows", report.RejectedRows,
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusOK, report)
+}
+
+// GetImport handles GET /v1/farms/{farm_id}/irrigation/imports/{import_id}
+func (c *ImportController) GetImport(ctx *gin.Context) {
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	importID, err := strconv.ParseUint(ctx.Param("import_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid import_id",
+			"message": "import_id must be a valid unsigned integer",
+		})
+		return
+	}
+
+	job, err := c.importService.GetImport(farmID, uint(importID))
+	if errors.Is(err, service.ErrImportNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Import not found",
+			"message": fmt.Sprintf("Import with ID %d does not exist for farm %d", importID, farmID),
+		})
+		return
+	}
+	if err != nil {
+		c.logger.Error("failed to retrieve import",
+			"farm_id", farmID,
+			"import_id", importID,
+			"error", err.Error(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to retrieve import",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, job)
+}
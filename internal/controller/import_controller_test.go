@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mockImportService is a mock implementation of ImportService for testing
+type mockImportService struct {
+	service.ImportService
+	imports int
+}
+
+func (m *mockImportService) FarmExists(farmID uint) (bool, error) {
+	return true, nil
+}
+
+func (m *mockImportService) ImportEvents(farmID uint, importID *uint, rows []service.ImportRow) (*service.ImportResult, error) {
+	m.imports++
+	job := &model.ImportJob{ID: uint(m.imports), FarmID: farmID, Status: model.ImportStatusCompleted, ImportedRows: len(rows)}
+	return &service.ImportResult{Import: job, Rejected: []service.RowRejection{}, Duplicates: []int{}}, nil
+}
+
+// mockIdempotencyService keeps idempotency records in memory by key
+type mockIdempotencyService struct {
+	records map[string]*model.IdempotencyKey
+	bodies  map[string]string
+}
+
+func (m *mockIdempotencyService) Begin(farmID uint, scope, key string, request any) (*model.IdempotencyKey, bool, error) {
+	body, _ := json.Marshal(request)
+	if record, ok := m.records[key]; ok {
+		if m.bodies[key] != string(body) {
+			return nil, false, service.ErrIdempotencyKeyReused
+		}
+		return record, true, nil
+	}
+	m.bodies[key] = string(body)
+	return &model.IdempotencyKey{FarmID: farmID, Scope: scope, Key: key}, false, nil
+}
+
+func (m *mockIdempotencyService) Complete(record *model.IdempotencyKey, statusCode int, response any) error {
+	body, _ := json.Marshal(response)
+	record.StatusCode, record.Response = statusCode, model.RawPayload(body)
+	m.records[record.Key] = record
+	return nil
+}
+
+func (m *mockIdempotencyService) Release(record *model.IdempotencyKey) error {
+	delete(m.bodies, record.Key)
+	return nil
+}
+
+func (m *mockIdempotencyService) PurgeExpired() (int64, error) {
+	return 0, nil
+}
+
+func TestImportEvents_IdempotencyKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mock := &mockImportService{}
+	controller := NewImportController(mock, slog.Default())
+	controller.EnableIdempotency(&mockIdempotencyService{records: map[string]*model.IdempotencyKey{}, bodies: map[string]string{}})
+	router := gin.New()
+	router.POST("/v1/farms/:farm_id/irrigation/imports", controller.ImportEvents)
+
+	batch := `{"events":[{"sector_id":1,"external_id":"gw-1","start_time":"2025-01-01T06:00:00Z","end_time":"2025-01-01T07:00:00Z","water_volume":100,"real_amount":90}]}`
+	other := `{"events":[{"sector_id":1,"external_id":"gw-2","start_time":"2025-01-01T06:00:00Z","end_time":"2025-01-01T07:00:00Z","water_volume":100,"real_amount":90}]}`
+
+	tests := []struct {
+		name         string
+		key          string
+		body         string
+		expectedCode int
+		replayed     bool
+		imports      int
+	}{
+		{"first request", "batch-1", batch, http.StatusOK, false, 1},
+		{"retry", "batch-1", batch, http.StatusOK, true, 1},
+		{"reused with another body", "batch-1", other, http.StatusUnprocessableEntity, false, 1},
+		{"without key", "", batch, http.StatusOK, false, 2},
+		{"key too long", strings.Repeat("k", 256), batch, http.StatusBadRequest, false, 2},
+	}
+
+	var first string
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/v1/farms/1/irrigation/imports", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.key != "" {
+				req.Header.Set("Idempotency-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status code %d, got %d (%s)", tt.expectedCode, w.Code, w.Body.String())
+			}
+			if replayed := w.Header().Get("Idempotent-Replayed") == "true"; replayed != tt.replayed {
+				t.Errorf("expected replayed=%v, got %v", tt.replayed, replayed)
+			}
+			if mock.imports != tt.imports {
+				t.Errorf("expected %d imports to have run, got %d", tt.imports, mock.imports)
+			}
+			if first == "" {
+				first = w.Body.String()
+			} else if tt.replayed && w.Body.String() != first {
+				t.Errorf("expected the original response %s, got %s", first, w.Body.String())
+			}
+		})
+	}
+}
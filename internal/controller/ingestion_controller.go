@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"irrigation-analytics/internal/middleware"
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IngestionController handles ingestion monitoring HTTP requests
+type IngestionController struct {
+	ingestionService service.IngestionService
+	logger           *slog.Logger
+}
+
+// NewIngestionController creates a new ingestion controller
+func NewIngestionController(ingestionService service.IngestionService, logger *slog.Logger) *IngestionController {
+	return &IngestionController{
+		ingestionService: ingestionService,
+		logger:           logger,
+	}
+}
+
+// GetIngestionStatus handles GET /v1/ingestion/status
+// Returns event counts and water volumes per data_source (seed, api, mqtt, import)
+// Query parameters:
+//   - include_archived (optional): true to count archived farms
+func (c *IngestionController) GetIngestionStatus(ctx *gin.Context) {
+	startTime := time.Now()
+
+	includeArchived, ok := parseBoolQuery(ctx, "include_archived")
+	if !ok {
+		return
+	}
+
+	status, err := c.ingestionService.GetIngestionStatus(includeArchived)
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to retrieve ingestion status",
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to retrieve ingestion status",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, status)
+}
+
+// SourceGauges exposes the per-source ingestion figures of active farms as Prometheus gauges.
+// Pass it to middleware.PrometheusHandler.
+func (c *IngestionController) SourceGauges() ([]middleware.Gauge, error) {
+	status, err := c.ingestionService.GetIngestionStatus(false)
+	if err != nil {
+		c.logger.Error("failed to collect ingestion gauges", "error", err.Error())
+		return nil, err
+	}
+
+	gauges := make([]middleware.Gauge, 0, 3*len(status.Sources))
+	for _, source := range status.Sources {
+		labels := map[string]string{"data_source": source.DataSource}
+		gauges = append(gauges,
+			middleware.Gauge{
+				Name:   "irrigation_ingested_events",
+				Help:   "Stored irrigation events by data_source.",
+				Labels: labels,
+				Value:  float64(source.EventCount),
+			},
+			middleware.Gauge{
+				Name:   "irrigation_ingested_water_volume",
+				Help:   "Stored water volume by data_source.",
+				Labels: labels,
+				Value:  source.WaterVolume,
+			},
+			middleware.Gauge{
+				Name:   "irrigation_last_ingested_timestamp_seconds",
+				Help:   "Unix time of the most recently ingested event by data_source.",
+				Labels: labels,
+				Value:  float64(source.LastIngestedAt.Unix()),
+			},
+		)
+	}
+	return gauges, nil
+}
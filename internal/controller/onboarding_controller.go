@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OnboardingController handles farm onboarding HTTP requests
+type OnboardingController struct {
+	onboardingService service.OnboardingService
+	logger            *slog.Logger
+}
+
+// NewOnboardingController creates a new onboarding controller
+func NewOnboardingController(onboardingService service.OnboardingService, logger *slog.Logger) *OnboardingController {
+	return &OnboardingController{
+		onboardingService: onboardingService,
+		logger:            logger,
+	}
+}
+
+// OnboardFarm handles POST /v1/farms/onboard
+// Body fields:
+//   - name (required), location, total_area (hectares) and description of the farm
+//   - latitude and longitude (optional, together): the farm's position, needed for weather
+//   - sectors (required): name (required, unique within the farm), area and description of each sector
+//
+// The farm and its sectors are created in one transaction; on any failure nothing is stored.
+func (c *OnboardingController) OnboardFarm(ctx *gin.Context) {
+	startTime := time.Now()
+
+	var req service.OnboardFarmInput
+	err := ctx.ShouldBindJSON(&req)
+	if writeBodyTooLarge(ctx, c.logger, 0, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": "body must be a JSON object with a farm name and a sectors array",
+		})
+		return
+	}
+
+	farm, err := c.onboardingService.OnboardFarm(req)
+	if errors.Is(err, service.ErrInvalidOnboarding) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid farm",
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("farm onboarding failed",
+			"name", req.Name,
+			"sectors", len(req.Sectors),
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to create the farm; nothing was stored",
+		})
+		return
+	}
+
+	c.logger.Info("farm onboarded",
+		"farm_id", farm.ID,
+		"sectors", len(farm.IrrigationSectors),
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusCreated, farm)
+}
+
+// CloneFarm handles POST /admin/farms/{farm_id}/clone
+// Body fields:
+//   - name (required): name of the new farm
+//   - location, description (optional): override the source farm's values
+//
+// Creates a new farm with copies of the source farm's sectors, including their areas and
+// fallback flow rates. Event data is not copied.
+func (c *OnboardingController) CloneFarm(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	var req service.CloneFarmInput
+	err := ctx.ShouldBindJSON(&req)
+	if writeBodyTooLarge(ctx, c.logger, farmID, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": "body must be a JSON object with the new farm's name",
+		})
+		return
+	}
+
+	farm, err := c.onboardingService.CloneFarm(farmID, req)
+	switch {
+	case errors.Is(err, service.ErrInvalidOnboarding):
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid farm",
+			"message": err.Error(),
+		})
+		return
+	case errors.Is(err, service.ErrFarmNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Farm not found",
+			"message": fmt.Sprintf("Farm with ID %d does not exist", farmID),
+		})
+		return
+	case err != nil:
+		latency := time.Since(startTime)
+		c.logger.Error("farm clone failed",
+			"source_farm_id", farmID,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to clone the farm; nothing was stored",
+		})
+		return
+	}
+
+	c.logger.Info("farm cloned",
+		"source_farm_id", farmID,
+		"farm_id", farm.ID,
+		"sectors", len(farm.IrrigationSectors),
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusCreated, farm)
+}
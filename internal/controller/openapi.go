@@ -0,0 +1,897 @@
+package controller
+
+import (
+	"go/ast"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"irrigation-analytics/internal/middleware"
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+	"irrigation-analytics/internal/service"
+
+	"gorm.io/gorm"
+)
+
+// apiParam documents one path or query parameter
+type apiParam struct {
+	Name        string
+	In          string // path, query or header
+	Type        string // integer, number, string or boolean
+	Format      string
+	Required    bool
+	Enum        []string
+	Description string
+}
+
+// apiRoute documents one endpoint. Body and Response are zero values of the types bound and
+// written by the handler; their schemas are derived from the json tags.
+type apiRoute struct {
+	Method      string
+	Path        string // gin syntax, e.g. /v1/farms/:farm_id
+	Tag         string
+	Summary     string
+	Description string
+	Params      []apiParam
+	Body        any
+	// Multipart lists the form fields of a multipart/form-data body instead of a JSON Body
+	Multipart []apiParam
+	Status    int
+	Response  any
+	// ContentType is the media type of a non-JSON success response, which Response then omits
+	ContentType string
+}
+
+// errorResponse is the body every handler writes on failure
+type errorResponse struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+func pathParam(name, description string) apiParam {
+	return apiParam{Name: name, In: "path", Type: "integer", Required: true, Description: description}
+}
+
+func queryParam(name, typ string, required bool, description string, enum ...string) apiParam {
+	return apiParam{Name: name, In: "query", Type: typ, Required: required, Description: description, Enum: enum}
+}
+
+func headerParam(name, description string) apiParam {
+	return apiParam{Name: name, In: "header", Type: "string", Description: description}
+}
+
+var (
+	farmIDParam      = pathParam("farm_id", "Farm ID")
+	startDateParam   = queryParam("start_date", "string", true, "Start date in ISO 8601 format (RFC3339 or YYYY-MM-DD)")
+	endDateParam     = queryParam("end_date", "string", true, "End date in ISO 8601 format (RFC3339 or YYYY-MM-DD)")
+	sectorIDParam    = queryParam("sector_id", "integer", false, "Filter by sector ID")
+	aggregationParam = queryParam("aggregation", "string", false, "Bucket size (default: daily); hourly is limited to 31 days",
+		"hourly", "daily", "weekly", "monthly", "quarterly", "yearly", "custom")
+	bucketDaysParam = queryParam("bucket_days", "integer", false, "Bucket length in days with aggregation=custom, 2-183; buckets start on 1 January")
+	dryRunParam     = queryParam("dry_run", "boolean", false, "true to validate and report the impact without writing")
+	regionParam     = queryParam("region", "string", false, "Region whose database serves the request when farms are sharded by region (default: the default region)")
+	cropLangParam   = queryParam("lang", "string", false, "Language of crop names (default: en)",
+		service.CropLanguageEnglish, service.CropLanguageSpanish, service.CropLanguagePortuguese, service.CropLanguageFrench)
+)
+
+// analyticsOptionParams are the optional query parameters shared by the farm and sector analytics endpoints
+var analyticsOptionParams = []apiParam{
+	queryParam("summary_only", "boolean", false, "true to return only the summary section via a single totals query"),
+	queryParam("efficiency_weighting", "string", false, "How bucket efficiencies are combined (default: mean in v1, volume in v2)",
+		service.EfficiencyWeightingMean, service.EfficiencyWeightingVolume),
+	queryParam("exclude_annotated", "boolean", false, "true to leave events under exclude_from_efficiency annotations out of efficiency"),
+	queryParam("exclude_seed", "boolean", false, "true to leave seeded demo data out of the response"),
+	queryParam("purpose", "string", false, "Comma-separated event purposes to include"),
+	queryParam("device_id", "integer", false, "Only include events measured by this registered device"),
+	queryParam("breakdown", "string", false, "Add per-purpose totals", "purpose"),
+	queryParam("strict", "boolean", false, "true to return 502 when an optional section fails instead of listing it in warnings"),
+	queryParam("normalize", "string", false, "Add per-hectare figures and applied depth in mm from sector areas", "area"),
+	queryParam("weather", "boolean", false, "true to add each bucket's stored rainfall_mm, et0_mm and frost or heat weather_events (daily and coarser buckets)"),
+	queryParam("distribution", "boolean", false, "true to add per-event median, p90, p95, min, max and stddev to the summary"),
+	queryParam("include_trend", "boolean", false, "true to add each point's 7-point moving averages and its series' trend slopes"),
+	queryParam("split_events", "boolean", false, "true to spread events running past midnight over the days they span"),
+	queryParam("fill_gaps", "boolean", false, "true to add a zero-valued data point for every bucket of the range without events"),
+	queryParam("units", "string", false, "Unit system: imperial reports US gallons, per-acre figures and inches (default: metric)",
+		service.UnitsMetric, service.UnitsImperial),
+	queryParam("level", "integer", false, "Sector hierarchy depth to roll data points and sector_breakdown up to; 1 is top-level sectors"),
+	queryParam("compare_to", "string", false, "Comma-separated periods to compare to, up to 5: previous_period, an offset such as -3y, -6m, -2w or -7d, or a start/end range (default: -1y,-2y)"),
+	queryParam("yoy_alignment", "string", false, "How periods years back are placed: the same calendar dates, or the same position in the farm's crop year (default: calendar)",
+		service.YearAlignmentCalendar, service.YearAlignmentSeason),
+	queryParam("omit", "string", false, "Comma-separated sections to leave out, skipping their queries: "+strings.Join(service.OmittableSections, ", ")),
+	queryParam("debug", "boolean", false, "true to add executed SQL and stage timings as _debug; requires the X-Admin-Token header"),
+	queryParam("include_deleted", "boolean", false, "true to count soft-deleted events, for audits; requires the X-Admin-Token header"),
+	queryParam("api_version", "string", false, "Response schema version (default: v1); also negotiable through the Accept header",
+		service.SchemaVersions()...),
+}
+
+// apiRoutes documents every endpoint served by the controllers. Keep it in step with the
+// handler doc comments when adding or changing a route.
+var apiRoutes = []apiRoute{
+	{
+		Method: http.MethodGet, Path: "/v1/farms/:farm_id/irrigation/analytics", Tag: "analytics",
+		Summary: "Irrigation analytics for a date range",
+		Params: append([]apiParam{
+			farmIDParam, startDateParam, endDateParam, sectorIDParam, aggregationParam, bucketDaysParam,
+		}, analyticsOptionParams...),
+		Response: service.AnalyticsResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/farms/:farm_id/sectors/:sector_id/irrigation/analytics", Tag: "analytics",
+		Summary:     "Irrigation analytics for one sector and its zones",
+		Description: "The full analytics payload scoped to one sector, with sector_breakdown listing the sector and its zones; 404 when the sector is not on the farm",
+		Params: append([]apiParam{
+			farmIDParam, pathParam("sector_id", "Sector ID"), startDateParam, endDateParam, aggregationParam, bucketDaysParam,
+		}, analyticsOptionParams...),
+		Response: service.AnalyticsResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/farms/:farm_id/irrigation/contributors", Tag: "analytics",
+		Summary: "Sectors or days that drove the change in water volume",
+		Params: []apiParam{
+			farmIDParam, startDateParam, endDateParam,
+			queryParam("dimension", "string", false, "Grouping (default: sector)", "sector", "day"),
+			queryParam("baseline", "string", false, "Comparison period (default: previous_year)", "previous_year", "previous_period"),
+			queryParam("limit", "integer", false, "Number of contributors to return, 1-50 (default: 5)"),
+		},
+		Response: service.ContributorsResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/farms/:farm_id/irrigation/anomalies", Tag: "analytics",
+		Summary: "Buckets deviating from their sector's baseline",
+		Params: []apiParam{
+			farmIDParam, startDateParam, endDateParam, sectorIDParam, aggregationParam, bucketDaysParam,
+			queryParam("method", "string", false, "Detection method (default: zscore)", service.AnomalyMethodZScore, service.AnomalyMethodIQR),
+			queryParam("threshold", "number", false, "z-score or IQR multiplier (default: 3 for zscore, 1.5 for iqr)"),
+			queryParam("baseline_days", "integer", false, "Days before start_date used as each sector's baseline, 7-730 (default: 90)"),
+		},
+		Response: service.AnomalyResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/farms/:farm_id/irrigation/recommendations", Tag: "analytics",
+		Summary: "Suggested next irrigation window and volume per sector",
+		Params: []apiParam{
+			farmIDParam,
+			queryParam("lookback_days", "integer", false, "Days of history before today used per sector, 3-90 (default: 14)"),
+			queryParam("weather", "boolean", false, "true to size volumes from stored ET0 and rainfall"),
+			queryParam("units", "string", false, "Unit system: imperial reports US gallons, acres and inches (default: metric)",
+				service.UnitsMetric, service.UnitsImperial),
+		},
+		Response: service.RecommendationResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/farms/:farm_id/irrigation/report", Tag: "analytics",
+		Summary:     "Monthly or seasonal irrigation report as a PDF",
+		Description: "Summary, comparison with the two previous years, sector breakdown and charts of water volume and efficiency, for sharing with farm owners",
+		Params: []apiParam{
+			farmIDParam,
+			queryParam("format", "string", false, "Report format (default: pdf)", service.ReportFormatPDF),
+			queryParam("month", "string", false, "Month to report on as YYYY-MM, instead of start_date and end_date"),
+			queryParam("start_date", "string", false, "Season start in ISO 8601 format, required without month"),
+			queryParam("end_date", "string", false, "Season end in ISO 8601 format, at most 366 days after start_date, required without month"),
+		},
+		ContentType: "application/pdf",
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/farms/:farm_id/irrigation/scenarios", Tag: "analytics",
+		Summary:     "What-if volume and cost under hypothetical changes",
+		Description: "Replays a recorded period under a tariff with changes such as shifting volume to off-peak hours or raising a sector's efficiency; nothing is written",
+		Params:      []apiParam{farmIDParam},
+		Body:        scenarioRequest{},
+		Response:    service.ScenarioResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/farms/:farm_id/irrigation/schedule/optimize", Tag: "analytics",
+		Summary:     "Propose a least-cost weekly irrigation plan",
+		Description: "Fills the cheapest tariff hours first within the pump capacity until each sector's weekly volume is met; nothing is written",
+		Params:      []apiParam{farmIDParam},
+		Body:        optimizeRequest{},
+		Response:    service.IrrigationPlan{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/farms/:farm_id/irrigation/schedule/variance", Tag: "analytics",
+		Summary:     "Compare a plan with the recorded irrigation",
+		Description: "Planned against actual volume, runtime and cost per sector and week, with the unplanned events and missed runs; nothing is written",
+		Params:      []apiParam{farmIDParam},
+		Body:        varianceRequest{},
+		Response:    service.PlanVariance{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/farms/:farm_id/timeseries", Tag: "analytics",
+		Summary: "Several metrics aligned on the same bucket timestamps",
+		Params: []apiParam{
+			farmIDParam,
+			queryParam("metrics", "string", true, "Comma-separated metric names: "+strings.Join(service.TimeSeriesMetrics(), ", ")),
+			startDateParam, endDateParam, sectorIDParam, aggregationParam, bucketDaysParam,
+		},
+		Response: service.TimeSeriesResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/farms/:farm_id/irrigation/annotations", Tag: "annotations",
+		Summary: "Annotate an event or a day",
+		Params:  []apiParam{farmIDParam},
+		Body:    createAnnotationRequest{},
+		Status:  http.StatusCreated, Response: model.Annotation{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/farms/:farm_id/irrigation/annotations", Tag: "annotations",
+		Summary: "List annotations in a date range",
+		Params:  []apiParam{farmIDParam, startDateParam, endDateParam},
+		Response: struct {
+			FarmID      uint               `json:"farm_id"`
+			Annotations []model.Annotation `json:"annotations"`
+		}{},
+	},
+	{
+		Method: http.MethodDelete, Path: "/v1/farms/:farm_id/irrigation/annotations/:annotation_id", Tag: "annotations",
+		Summary: "Delete an annotation",
+		Params:  []apiParam{farmIDParam, pathParam("annotation_id", "Annotation ID")},
+		Status:  http.StatusNoContent,
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/farms/:farm_id/irrigation/events", Tag: "events",
+		Summary:     "List the raw events behind the analytics",
+		Description: "Events are selected as the analytics endpoint selects them, by the farm's attribution policy, so a data point can be drilled into with its bucket's start and end. Ties in the sort order are broken by id; follow next_offset for the next page.",
+		Params: []apiParam{farmIDParam, startDateParam, endDateParam,
+			queryParam("sector_id", "integer", false, "Only events of this sector and its zones"),
+			queryParam("min_volume", "number", false, "Smallest water volume in liters, inclusive"),
+			queryParam("max_volume", "number", false, "Largest water volume in liters, inclusive"),
+			queryParam("purpose", "string", false, "Comma-separated event purposes to include"),
+			queryParam("device_id", "integer", false, "Only events measured by this registered device"),
+			queryParam("sort", "string", false, "Sort field (default: start_time)",
+				"start_time", "end_time", "water_volume", "duration", "real_amount"),
+			queryParam("order", "string", false, "Sort direction (default: asc)", "asc", "desc"),
+			queryParam("limit", "integer", false, "Number of events to return, 1-1000 (default: 100)"),
+			queryParam("offset", "integer", false, "Number of matching events to skip (default: 0)"),
+		},
+		Response: service.EventPage{},
+	},
+	{
+		Method: http.MethodPatch, Path: "/v1/farms/:farm_id/irrigation/events/:event_id", Tag: "events",
+		Summary:  "Set an event's purpose",
+		Params:   []apiParam{farmIDParam, pathParam("event_id", "Irrigation event ID")},
+		Body:     classifyEventRequest{},
+		Response: model.IrrigationData{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/farms/:farm_id/irrigation/durations/recompute", Tag: "events",
+		Summary:     "Rederive event durations from start and end times",
+		Description: "Rewrites durations that disagree with end_time - start_time, in whole minutes, and lists the first 1,000 corrections",
+		Params:      []apiParam{farmIDParam, startDateParam, endDateParam, dryRunParam},
+		Response:    service.DurationRecompute{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/farms/:farm_id/irrigation/imports", Tag: "imports",
+		Summary: "Bulk import irrigation events",
+		Description: "Events are committed in chunks of 1,000. With dry_run the response is an ImportPreview instead. " +
+			"Rows whose external_id is already stored are skipped as duplicates.",
+		Params: []apiParam{farmIDParam, dryRunParam,
+			headerParam("Idempotency-Key", "A retry with the same key and body gets the original response instead of importing again"),
+		},
+		Body:     importRequest{},
+		Response: service.ImportResult{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/farms/:farm_id/irrigation/imports/:import_id", Tag: "imports",
+		Summary:  "Import job progress",
+		Params:   []apiParam{farmIDParam, pathParam("import_id", "Import job ID")},
+		Response: model.ImportJob{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/farms/:farm_id/irrigation/import", Tag: "imports",
+		Summary:     "Import irrigation events from a CSV or XLSX spreadsheet",
+		Description: "Rejected rows are identified by their line in the file, counting the header as line 1.",
+		Params:      []apiParam{farmIDParam, dryRunParam},
+		Multipart: []apiParam{
+			{Name: "file", Type: "string", Format: "binary", Required: true, Description: "A .csv or .xlsx spreadsheet"},
+			{Name: "import_id", Type: "integer", Description: "Resume a failed import by uploading the same file again"},
+		},
+		Response: service.FileImportReport{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/farms/:farm_id/irrigation/backfill/preview", Tag: "imports",
+		Summary:  "Preview how a backfill changes monthly summaries and year-over-year figures",
+		Params:   []apiParam{farmIDParam},
+		Body:     importRequest{},
+		Response: service.BackfillPreview{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/farms/:farm_id/pressure-readings", Tag: "telemetry",
+		Summary: "Record line pressure readings",
+		Params:  []apiParam{farmIDParam},
+		Body:    pressureRequest{},
+		Status:  http.StatusCreated,
+		Response: struct {
+			FarmID   uint `json:"farm_id"`
+			Recorded int  `json:"recorded"`
+		}{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/farms/:farm_id/weather/sync", Tag: "telemetry",
+		Summary:  "Fetch and store daily precipitation and ET0 for the farm's location",
+		Params:   []apiParam{farmIDParam, startDateParam, endDateParam},
+		Response: service.WeatherSyncResult{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/farms/:farm_id/water-orders/reconciliation", Tag: "telemetry",
+		Summary:     "Reconcile district water orders with delivered and applied volumes",
+		Description: "Orders are read from the irrigation district on every call; needs the farm's water_account",
+		Params: []apiParam{farmIDParam, startDateParam, endDateParam,
+			queryParam("aggregation", "string", false, "Period of each balance (default: monthly)",
+				service.WaterOrderPeriodDaily, service.WaterOrderPeriodWeekly, service.WaterOrderPeriodMonthly),
+		},
+		Response: service.WaterOrderReconciliation{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/ingestion/status", Tag: "telemetry",
+		Summary:  "Event counts and water volumes per data_source",
+		Params:   []apiParam{queryParam("include_archived", "boolean", false, "true to count archived farms"), regionParam},
+		Response: service.IngestionStatus{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/farms/onboard", Tag: "farms",
+		Summary: "Create a farm and its sectors in one transaction",
+		Params:  []apiParam{regionParam},
+		Body:    service.OnboardFarmInput{},
+		Status:  http.StatusCreated, Response: model.Farm{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/farms/:farm_id/archive", Tag: "farms",
+		Summary:     "Archive a farm",
+		Description: "Archived farms are left out of cross-farm aggregates unless include_archived=true; their own analytics stay available",
+		Params:      []apiParam{farmIDParam},
+		Response:    model.Farm{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/farms/:farm_id/unarchive", Tag: "farms",
+		Summary:  "Make an archived farm active again",
+		Params:   []apiParam{farmIDParam},
+		Response: model.Farm{},
+	},
+	{
+		Method: http.MethodPut, Path: "/v1/farms/:farm_id/attribution-policy", Tag: "farms",
+		Summary:     "Choose how events spanning several buckets are attributed",
+		Description: "start, end or proportional; honored by every aggregation of the farm",
+		Params:      []apiParam{farmIDParam},
+		Body:        attributionPolicyRequest{},
+		Response:    model.Farm{},
+	},
+	{
+		Method: http.MethodPut, Path: "/v1/farms/:farm_id/water-account", Tag: "farms",
+		Summary:     "Set the farm's irrigation district account",
+		Description: "Needed to reconcile water orders; an empty account clears it",
+		Params:      []apiParam{farmIDParam},
+		Body:        waterAccountRequest{},
+		Response:    model.Farm{},
+	},
+	{
+		Method: http.MethodPut, Path: "/v1/farms/:farm_id/crop-year", Tag: "farms",
+		Summary:     "Set the day the farm's crop year starts",
+		Description: "MM-DD; season-aligned year-over-year comparisons align on it. An empty value resets it to 01-01",
+		Params:      []apiParam{farmIDParam},
+		Body:        cropYearRequest{},
+		Response:    model.Farm{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/farms/:farm_id/calibrations", Tag: "calibrations",
+		Summary:     "Correct a meter or sensor bias at ingestion",
+		Description: "Replaces the calibration with the same target and match; applies to values ingested from now on",
+		Params:      []apiParam{farmIDParam},
+		Body:        calibrationRequest{},
+		Status:      http.StatusCreated, Response: model.Calibration{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/farms/:farm_id/calibrations", Tag: "calibrations",
+		Summary: "List the farm's calibrations",
+		Params:  []apiParam{farmIDParam},
+		Response: struct {
+			FarmID       uint                `json:"farm_id"`
+			Calibrations []model.Calibration `json:"calibrations"`
+		}{},
+	},
+	{
+		Method: http.MethodDelete, Path: "/v1/farms/:farm_id/calibrations/:calibration_id", Tag: "calibrations",
+		Summary: "Delete a calibration",
+		Params:  []apiParam{farmIDParam, pathParam("calibration_id", "Calibration ID")},
+		Status:  http.StatusNoContent,
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/farms/:farm_id/devices", Tag: "devices",
+		Summary: "Register a flow meter or valve on a sector",
+		Params:  []apiParam{farmIDParam},
+		Body:    deviceRequest{},
+		Status:  http.StatusCreated, Response: model.Device{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/farms/:farm_id/devices", Tag: "devices",
+		Summary: "List the farm's devices",
+		Params:  []apiParam{farmIDParam, queryParam("sector_id", "integer", false, "Only list the devices installed on this sector")},
+		Response: struct {
+			FarmID  uint           `json:"farm_id"`
+			Devices []model.Device `json:"devices"`
+		}{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/farms/:farm_id/devices/:device_id", Tag: "devices",
+		Summary:  "Get a device",
+		Params:   []apiParam{farmIDParam, pathParam("device_id", "Device ID")},
+		Response: model.Device{},
+	},
+	{
+		Method: http.MethodPut, Path: "/v1/farms/:farm_id/devices/:device_id", Tag: "devices",
+		Summary:     "Replace a device's details",
+		Description: "Events the device already measured keep their sector when it moves",
+		Params:      []apiParam{farmIDParam, pathParam("device_id", "Device ID")},
+		Body:        deviceRequest{},
+		Response:    model.Device{},
+	},
+	{
+		Method: http.MethodDelete, Path: "/v1/farms/:farm_id/devices/:device_id", Tag: "devices",
+		Summary:     "Delete a device",
+		Description: "Events keep their device_id and can still be filtered by it",
+		Params:      []apiParam{farmIDParam, pathParam("device_id", "Device ID")},
+		Status:      http.StatusNoContent,
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/farms/:farm_id/webhooks", Tag: "webhooks",
+		Summary:     "Register a webhook for threshold alerts",
+		Description: "The URL's host must resolve to public addresses only. The response includes the signing secret, which is not shown again",
+		Params:      []apiParam{farmIDParam},
+		Body:        webhookRequest{},
+		Status:      http.StatusCreated,
+		Response:    service.CreatedWebhook{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/farms/:farm_id/webhooks", Tag: "webhooks",
+		Summary: "List a farm's webhooks",
+		Params:  []apiParam{farmIDParam},
+		Response: struct {
+			FarmID   uint            `json:"farm_id"`
+			Webhooks []model.Webhook `json:"webhooks"`
+		}{},
+	},
+	{
+		Method: http.MethodDelete, Path: "/v1/farms/:farm_id/webhooks/:webhook_id", Tag: "webhooks",
+		Summary: "Delete a webhook",
+		Params:  []apiParam{farmIDParam, pathParam("webhook_id", "Webhook ID")},
+		Status:  http.StatusNoContent,
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/farms/:farm_id/webhooks/:webhook_id/deliveries", Tag: "webhooks",
+		Summary: "List a webhook's alert deliveries",
+		Params: []apiParam{
+			farmIDParam,
+			pathParam("webhook_id", "Webhook ID"),
+			queryParam("limit", "integer", false, "Number of deliveries to return, newest first, 1-500 (default: 50)"),
+		},
+		Response: struct {
+			WebhookID  uint                    `json:"webhook_id"`
+			Deliveries []model.WebhookDelivery `json:"deliveries"`
+		}{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/farms/:farm_id/reports/schedules", Tag: "reports",
+		Summary:     "Email a weekly or monthly analytics summary",
+		Description: "Reports go out at 06:00 UTC on Mondays for the previous week or on the 1st for the previous month",
+		Params:      []apiParam{farmIDParam},
+		Body:        reportScheduleRequest{},
+		Status:      http.StatusCreated,
+		Response:    model.ReportSchedule{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/farms/:farm_id/reports/schedules", Tag: "reports",
+		Summary: "List a farm's report schedules",
+		Params:  []apiParam{farmIDParam},
+		Response: struct {
+			FarmID    uint                   `json:"farm_id"`
+			Schedules []model.ReportSchedule `json:"schedules"`
+		}{},
+	},
+	{
+		Method: http.MethodDelete, Path: "/v1/farms/:farm_id/reports/schedules/:schedule_id", Tag: "reports",
+		Summary: "Delete a report schedule",
+		Params:  []apiParam{farmIDParam, pathParam("schedule_id", "Report schedule ID")},
+		Status:  http.StatusNoContent,
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/crops", Tag: "crops",
+		Summary: "List the built-in FAO-56 crop coefficient library",
+		Params:  []apiParam{cropLangParam},
+		Response: struct {
+			Lang  string                     `json:"lang"`
+			Crops []service.CropCoefficients `json:"crops"`
+		}{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/farms/:farm_id/crop-coefficients", Tag: "crops",
+		Summary: "List crop coefficients with the farm's overrides applied",
+		Params:  []apiParam{farmIDParam, cropLangParam},
+		Response: struct {
+			FarmID uint                       `json:"farm_id"`
+			Lang   string                     `json:"lang"`
+			Crops  []service.CropCoefficients `json:"crops"`
+		}{},
+	},
+	{
+		Method: http.MethodPut, Path: "/v1/farms/:farm_id/crop-coefficients/:crop", Tag: "crops",
+		Summary:     "Override a crop's Kc values for the farm",
+		Description: "Values left out keep the library's; the body replaces any earlier override",
+		Params:      []apiParam{farmIDParam, pathParam("crop", "Crop library key")},
+		Body:        cropOverrideRequest{},
+		Response:    service.CropCoefficients{},
+	},
+	{
+		Method: http.MethodDelete, Path: "/v1/farms/:farm_id/crop-coefficients/:crop", Tag: "crops",
+		Summary: "Return a crop to the library's Kc values",
+		Params:  []apiParam{farmIDParam, pathParam("crop", "Crop library key")},
+		Status:  http.StatusNoContent,
+	},
+	{
+		Method: http.MethodPut, Path: "/v1/farms/:farm_id/sectors/:sector_id/crop", Tag: "crops",
+		Summary:     "Set the crop and growth stage a sector is growing",
+		Description: "An empty crop clears it; weather-based recommendations scale ET0 by the crop's Kc",
+		Params:      []apiParam{farmIDParam, pathParam("sector_id", "Sector ID")},
+		Body:        sectorCropRequest{},
+		Response: struct {
+			FarmID    uint   `json:"farm_id"`
+			SectorID  uint   `json:"sector_id"`
+			Crop      string `json:"crop"`
+			CropStage string `json:"crop_stage"`
+		}{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/farms/:farm_id/dead-letters", Tag: "dead-letters",
+		Summary: "List rejected ingestion messages",
+		Params: []apiParam{
+			farmIDParam,
+			queryParam("status", "string", false, "pending, replayed or discarded (default: pending)"),
+			queryParam("limit", "integer", false, "Number of letters to return, oldest first, 1-1000 (default: 100)"),
+		},
+		Response: struct {
+			FarmID      uint               `json:"farm_id"`
+			Status      string             `json:"status"`
+			DeadLetters []model.DeadLetter `json:"dead_letters"`
+		}{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/farms/:farm_id/dead-letters/:dead_letter_id/replay", Tag: "dead-letters",
+		Summary:     "Replay a rejected message, optionally with a fixed payload",
+		Description: "422 with the letter and its new error when the payload is still rejected",
+		Params:      []apiParam{farmIDParam, pathParam("dead_letter_id", "Dead letter ID")},
+		Body:        replayRequest{},
+		Response:    model.DeadLetter{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/farms/:farm_id/dead-letters/:dead_letter_id/discard", Tag: "dead-letters",
+		Summary:  "Discard a rejected message",
+		Params:   []apiParam{farmIDParam, pathParam("dead_letter_id", "Dead letter ID")},
+		Response: model.DeadLetter{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/version", Tag: "meta",
+		Summary:     "Build and runtime version of the deployment",
+		Description: "Git commit and build time embedded at build time, the schema migration version and the feature flags",
+		Response:    service.VersionInfo{},
+	},
+	{
+		Method: http.MethodPost, Path: "/admin/farms/:farm_id/clone", Tag: "admin",
+		Summary: "Create a farm with a copy of another farm's sectors",
+		Params:  []apiParam{farmIDParam},
+		Body:    service.CloneFarmInput{},
+		Status:  http.StatusCreated, Response: model.Farm{},
+	},
+	{
+		Method: http.MethodPost, Path: "/admin/farms/:farm_id/irrigation/overlaps", Tag: "admin",
+		Summary:     "Merge or flag a sector's overlapping events",
+		Description: "Duplicate uploads leave overlapping events that double-count volume; merge folds each group into its earliest event, flag annotates the others",
+		Params: []apiParam{farmIDParam, startDateParam, endDateParam,
+			queryParam("action", "string", true, "What to do with each group of overlapping events", service.OverlapActionMerge, service.OverlapActionFlag),
+			sectorIDParam, dryRunParam,
+		},
+		Response: service.OverlapResolution{},
+	},
+	{
+		Method: http.MethodGet, Path: "/admin/cache", Tag: "admin",
+		Summary:  "Farm cache statistics and entries",
+		Response: service.FarmCacheReport{},
+	},
+	{
+		Method: http.MethodDelete, Path: "/admin/cache", Tag: "admin",
+		Summary: "Flush the farm cache",
+		Status:  http.StatusNoContent,
+	},
+	{
+		Method: http.MethodDelete, Path: "/admin/cache/farms/:farm_id", Tag: "admin",
+		Summary: "Evict one farm from the cache",
+		Params:  []apiParam{farmIDParam},
+		Response: struct {
+			FarmID  uint `json:"farm_id"`
+			Evicted bool `json:"evicted"`
+		}{},
+	},
+	{
+		Method: http.MethodGet, Path: "/admin/slo", Tag: "admin",
+		Summary:     "Latency and availability SLOs with their burn rates",
+		Description: "Requests, errors and slow requests of every endpoint with an SLO over 5m, 30m, 1h and 6h, the budget left over 6h, and the burn-rate alerts raised",
+		Response:    middleware.SLOReport{},
+	},
+	{
+		Method: http.MethodGet, Path: "/admin/encryption", Tag: "admin",
+		Summary:     "Field encryption keys and column status",
+		Description: "The configured key IDs and, for every encrypted column, the values stored in plaintext and under each key",
+		Response:    service.FieldEncryptionStatus{},
+	},
+	{
+		Method: http.MethodPost, Path: "/admin/encryption/reencrypt", Tag: "admin",
+		Summary:     "Rewrite every encrypted column with the primary key",
+		Description: "Encrypts rows stored before encryption was enabled and moves values off older keys; 409 when no keys are configured",
+		Response:    repository.ReencryptionResult{},
+	},
+	{
+		Method: http.MethodGet, Path: "/admin/faults", Tag: "admin",
+		Summary:     "Fault injection rules",
+		Description: "Live rules with the calls they delayed and failed, and the targets rules can be set on; only registered when FAULT_INJECTION_ENABLED is true",
+		Response:    service.FaultReport{},
+	},
+	{
+		Method: http.MethodPut, Path: "/admin/faults", Tag: "admin",
+		Summary:     "Add latency or failures to a repository method or every statement",
+		Description: "Replaces the target's rule; rules expire after duration_seconds",
+		Body:        service.FaultInput{},
+		Response:    repository.FaultRule{},
+	},
+	{
+		Method: http.MethodDelete, Path: "/admin/faults", Tag: "admin",
+		Summary: "Remove every fault rule",
+		Status:  http.StatusNoContent,
+	},
+	{
+		Method: http.MethodDelete, Path: "/admin/faults/:target", Tag: "admin",
+		Summary: "Remove one target's fault rule",
+		Params:  []apiParam{{Name: "target", In: "path", Type: "string", Required: true, Description: "Repository method name or db"}},
+		Response: struct {
+			Target  string `json:"target"`
+			Deleted bool   `json:"deleted"`
+		}{},
+	},
+	{
+		Method: http.MethodGet, Path: "/admin/log-level", Tag: "admin",
+		Summary:  "Current log level",
+		Response: logLevelRequest{},
+	},
+	{
+		Method: http.MethodPut, Path: "/admin/log-level", Tag: "admin",
+		Summary:  "Change the log level at runtime",
+		Body:     logLevelRequest{},
+		Response: logLevelRequest{},
+	},
+}
+
+// ginParamPattern matches gin's :name path parameters
+var ginParamPattern = regexp.MustCompile(`:([A-Za-z_]+)`)
+
+// BuildOpenAPI returns an OpenAPI 3.0 document for routes, with component schemas generated
+// from the request and response types
+func BuildOpenAPI(routes []apiRoute, version string) map[string]any {
+	schemas := &schemaRegistry{components: map[string]any{}}
+	schemas.components["Error"] = schemas.schemaFor(reflect.TypeOf(errorResponse{}))
+
+	paths := map[string]map[string]any{}
+	for _, route := range routes {
+		path := ginParamPattern.ReplaceAllString(route.Path, "{$1}")
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		paths[path][strings.ToLower(route.Method)] = schemas.operation(route)
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Irrigation Analytics API",
+			"version": version,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]any{
+				"apiKey": map[string]any{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+				"bearer": map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+		"security": []map[string][]string{{"apiKey": {}}, {"bearer": {}}},
+	}
+}
+
+// operation builds the OpenAPI operation object for a route
+func (r *schemaRegistry) operation(route apiRoute) map[string]any {
+	op := map[string]any{
+		"summary":     route.Summary,
+		"tags":        []string{route.Tag},
+		"operationId": strings.ToLower(route.Method) + ginParamPattern.ReplaceAllString(strings.ReplaceAll(route.Path, "/", "_"), "by_$1"),
+	}
+	if route.Description != "" {
+		op["description"] = route.Description
+	}
+
+	if len(route.Params) > 0 {
+		params := make([]map[string]any, 0, len(route.Params))
+		for _, p := range route.Params {
+			params = append(params, map[string]any{
+				"name":        p.Name,
+				"in":          p.In,
+				"required":    p.Required,
+				"description": p.Description,
+				"schema":      paramSchema(p),
+			})
+		}
+		op["parameters"] = params
+	}
+
+	switch {
+	case route.Body != nil:
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": r.schemaFor(reflect.TypeOf(route.Body))},
+			},
+		}
+	case len(route.Multipart) > 0:
+		properties := map[string]any{}
+		var required []string
+		for _, field := range route.Multipart {
+			properties[field.Name] = paramSchema(field)
+			if field.Required {
+				required = append(required, field.Name)
+			}
+		}
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{
+				"multipart/form-data": map[string]any{
+					"schema": map[string]any{"type": "object", "properties": properties, "required": required},
+				},
+			},
+		}
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]any{"description": http.StatusText(status)}
+	if route.ContentType != "" {
+		success["content"] = map[string]any{
+			route.ContentType: map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}},
+		}
+	} else if route.Response != nil {
+		success["content"] = map[string]any{
+			"application/json": map[string]any{"schema": r.schemaFor(reflect.TypeOf(route.Response))},
+		}
+	}
+	op["responses"] = map[string]any{
+		strconv.Itoa(status): success,
+		"default": map[string]any{
+			"description": "Error",
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/Error"}},
+			},
+		},
+	}
+	return op
+}
+
+// paramSchema returns the schema of a parameter or multipart field
+func paramSchema(p apiParam) map[string]any {
+	schema := map[string]any{"type": p.Type}
+	if p.Format != "" {
+		schema["format"] = p.Format
+	}
+	if len(p.Enum) > 0 {
+		schema["enum"] = p.Enum
+	}
+	return schema
+}
+
+// schemaRegistry generates JSON schemas from Go types. Exported named structs become
+// components referenced with $ref, which also terminates recursive types such as Farm.
+type schemaRegistry struct {
+	components map[string]any
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+)
+
+// schemaFor returns the schema of t as encoding/json serializes it
+func (r *schemaRegistry) schemaFor(t reflect.Type) map[string]any {
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case deletedAtType:
+		return map[string]any{"type": "string", "format": "date-time", "nullable": true}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := r.schemaFor(t.Elem())
+		if _, ok := schema["$ref"]; ok {
+			// Siblings of $ref are ignored in OpenAPI 3.0, so wrap it to mark it nullable
+			return map[string]any{"allOf": []any{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": r.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": r.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" || !ast.IsExported(t.Name()) {
+			return r.structSchema(t)
+		}
+		if _, ok := r.components[t.Name()]; !ok {
+			// Reserve the name before descending so recursive references resolve to it
+			r.components[t.Name()] = nil
+			r.components[t.Name()] = r.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	}
+	return map[string]any{}
+}
+
+// structSchema returns the object schema of a struct's JSON fields. Fields without
+// omitempty are listed as required.
+func (r *schemaRegistry) structSchema(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			embedded := r.structSchema(field.Type)
+			for k, v := range embedded["properties"].(map[string]any) {
+				properties[k] = v
+			}
+			if req, ok := embedded["required"].([]string); ok {
+				required = append(required, req...)
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = r.schemaFor(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
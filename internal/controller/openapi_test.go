@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	controller, err := NewDocsController("1.0.0", slog.Default())
+	if err != nil {
+		t.Fatalf("NewDocsController: %v", err)
+	}
+	r := gin.New()
+	r.GET("/openapi.json", controller.OpenAPISpec)
+
+	req, _ := http.NewRequest("GET", "/openapi.json", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var spec struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	for _, route := range apiRoutes {
+		path := ginParamPattern.ReplaceAllString(route.Path, "{$1}")
+		if _, ok := spec.Paths[path][strings.ToLower(route.Method)]; !ok {
+			t.Errorf("missing operation %s %s", route.Method, path)
+		}
+	}
+
+	// Every $ref must name a generated component
+	for _, match := range regexp.MustCompile(`"#/components/schemas/([A-Za-z]+)"`).FindAllStringSubmatch(w.Body.String(), -1) {
+		if _, ok := spec.Components.Schemas[match[1]]; !ok {
+			t.Errorf("unresolved reference to %s", match[1])
+		}
+	}
+
+	var analytics struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+		Required   []string                   `json:"required"`
+	}
+	if err := json.Unmarshal(spec.Components.Schemas["AnalyticsResponse"], &analytics); err != nil {
+		t.Fatalf("AnalyticsResponse schema: %v", err)
+	}
+	if _, ok := analytics.Properties["_debug"]; !ok {
+		t.Error("expected _debug property from the json tag")
+	}
+	for _, name := range analytics.Required {
+		if name == "warnings" {
+			t.Error("omitempty field warnings should not be required")
+		}
+	}
+}
+
+func TestDocsReference(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	controller, _ := NewDocsController("1.0.0", slog.Default())
+	r := gin.New()
+	r.GET("/docs", controller.Reference)
+	r.GET("/docs/*filepath", controller.Reference)
+
+	tests := []struct {
+		name         string
+		url          string
+		expectedCode int
+		contentType  string
+		contains     string
+	}{
+		{"page", "/docs", http.StatusOK, "text/html", `<script src="/docs/docs.js">`},
+		{"page with trailing slash", "/docs/", http.StatusOK, "text/html", `<script src="/docs/docs.js">`},
+		{"script", "/docs/docs.js", http.StatusOK, "javascript", `fetch("/openapi.json")`},
+		{"stylesheet", "/docs/docs.css", http.StatusOK, "text/css", ".method"},
+		{"unknown asset", "/docs/swagger-ui.js", http.StatusNotFound, "application/json", "unknown docs asset"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", tt.url, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status code %d, got %d", tt.expectedCode, w.Code)
+			}
+			if contentType := w.Header().Get("Content-Type"); !strings.Contains(contentType, tt.contentType) {
+				t.Errorf("expected a %s content type, got %q", tt.contentType, contentType)
+			}
+			if !strings.Contains(w.Body.String(), tt.contains) {
+				t.Errorf("expected the body to contain %q", tt.contains)
+			}
+			// Nothing on the page may come from another origin
+			if strings.Contains(w.Body.String(), "https://") {
+				t.Error("expected no external URLs in the docs assets")
+			}
+		})
+	}
+}
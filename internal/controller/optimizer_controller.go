@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Optimizer request bounds
+const (
+	maxPlanSectors      = 100
+	maxPlanWeeklyVolume = 1e9
+	maxPlanFlowRate     = 1e6
+)
+
+// OptimizerController handles irrigation schedule optimization HTTP requests
+type OptimizerController struct {
+	optimizerService service.OptimizerService
+	logger           *slog.Logger
+}
+
+// NewOptimizerController creates a new optimizer controller
+func NewOptimizerController(optimizerService service.OptimizerService, logger *slog.Logger) *OptimizerController {
+	return &OptimizerController{
+		optimizerService: optimizerService,
+		logger:           logger,
+	}
+}
+
+// optimizeRequest is the body of a schedule optimization request
+type optimizeRequest struct {
+	WeekStart    string                      `json:"week_start"`
+	Tariff       service.Tariff              `json:"tariff"`
+	PumpCapacity float64                     `json:"pump_capacity"`
+	Sectors      []service.SectorRequirement `json:"sectors"`
+}
+
+// OptimizeSchedule handles POST /v1/farms/{farm_id}/irrigation/schedule/optimize
+// Body fields:
+//   - week_start (required): the first day of the week to plan, YYYY-MM-DD; the week runs 7 UTC days
+//   - tariff (required): as for scenarios, prices per m³ and the UTC off-peak window
+//   - pump_capacity (required): the liters per minute the sectors running at once may draw together
+//   - sectors (required): 1-100 distinct sectors, each {"sector_id", "weekly_volume" in liters,
+//     "flow_rate" in liters per minute, optional when the sector has a fallback_flow_rate}
+//
+// Nothing is written; the response is the proposed plan, with feasible false when some
+// requirement does not fit in the week.
+func (c *OptimizerController) OptimizeSchedule(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	var req optimizeRequest
+	err := ctx.ShouldBindJSON(&req)
+	if writeBodyTooLarge(ctx, c.logger, farmID, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": "body must be a JSON optimization object",
+		})
+		return
+	}
+	weekStart, errMessage := req.validate()
+	if errMessage != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid optimization request",
+			"message": errMessage,
+		})
+		return
+	}
+
+	if !ensureFarmExists(ctx, c.logger, c.optimizerService, farmID, startTime) {
+		return
+	}
+
+	plan, err := c.optimizerService.Optimize(farmID, service.PlanInput{
+		WeekStart:    weekStart,
+		Tariff:       req.Tariff,
+		PumpCapacity: req.PumpCapacity,
+		Requirements: req.Sectors,
+	})
+	if errors.Is(err, service.ErrSectorNotFound) || errors.Is(err, service.ErrFlowRateUnknown) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid optimization request",
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to optimize schedule",
+			"farm_id", farmID,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to optimize schedule",
+		})
+		return
+	}
+
+	c.logger.Info("schedule optimized",
+		"farm_id", farmID,
+		"sectors", len(req.Sectors),
+		"runs", len(plan.Runs),
+		"feasible", plan.Feasible,
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusOK, plan)
+}
+
+// validate parses the week start and checks the request, returning a message describing the
+// first problem found
+func (r optimizeRequest) validate() (time.Time, string) {
+	weekStart, err := time.Parse("2006-01-02", r.WeekStart)
+	if err != nil {
+		return time.Time{}, "week_start must be a date in YYYY-MM-DD format"
+	}
+	if errMessage := validateTariff(r.Tariff); errMessage != "" {
+		return time.Time{}, errMessage
+	}
+	if !(r.PumpCapacity > 0 && r.PumpCapacity < maxPlanFlowRate) {
+		return time.Time{}, "pump_capacity must be greater than 0 and less than 1000000"
+	}
+
+	if len(r.Sectors) == 0 || len(r.Sectors) > maxPlanSectors {
+		return time.Time{}, fmt.Sprintf("sectors must list between 1 and %d sectors", maxPlanSectors)
+	}
+	seen := make(map[uint]bool, len(r.Sectors))
+	for i, sector := range r.Sectors {
+		if seen[sector.SectorID] {
+			return time.Time{}, fmt.Sprintf("sectors[%d]: sector_id %d is listed twice", i, sector.SectorID)
+		}
+		seen[sector.SectorID] = true
+		if !(sector.WeeklyVolume > 0 && sector.WeeklyVolume < maxPlanWeeklyVolume) {
+			return time.Time{}, fmt.Sprintf("sectors[%d]: weekly_volume must be greater than 0 and less than 1000000000", i)
+		}
+		if v := sector.FlowRate; v != nil && !(*v > 0 && *v < maxPlanFlowRate) {
+			return time.Time{}, fmt.Sprintf("sectors[%d]: flow_rate must be greater than 0 and less than 1000000", i)
+		}
+	}
+	return weekStart, ""
+}
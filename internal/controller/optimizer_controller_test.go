@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mockOptimizerService knows sectors 1 and 2; sector 2 has no fallback flow rate
+type mockOptimizerService struct{}
+
+func (m *mockOptimizerService) FarmExists(farmID uint) (bool, error) {
+	return true, nil
+}
+
+func (m *mockOptimizerService) Optimize(farmID uint, input service.PlanInput) (*service.IrrigationPlan, error) {
+	for i, requirement := range input.Requirements {
+		if requirement.SectorID > 2 {
+			return nil, fmt.Errorf("sectors[%d]: %w: %d", i, service.ErrSectorNotFound, requirement.SectorID)
+		}
+		if requirement.SectorID == 2 && requirement.FlowRate == nil {
+			return nil, fmt.Errorf("sectors[%d]: %w", i, service.ErrFlowRateUnknown)
+		}
+	}
+	return &service.IrrigationPlan{FarmID: farmID, Feasible: true}, nil
+}
+
+func (m *mockOptimizerService) Variance(farmID uint, plan service.IrrigationPlan, tolerance service.VarianceTolerance) (*service.PlanVariance, error) {
+	for i, run := range plan.Runs {
+		if run.SectorID > 2 {
+			return nil, fmt.Errorf("runs[%d]: %w: %d", i, service.ErrSectorNotFound, run.SectorID)
+		}
+	}
+	return &service.PlanVariance{FarmID: farmID, TimingToleranceMinutes: tolerance.TimingMinutes, VolumeTolerance: tolerance.Volume}, nil
+}
+
+func TestOptimizerController(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	controller := NewOptimizerController(&mockOptimizerService{}, slog.Default())
+	router := gin.New()
+	router.POST("/v1/farms/:farm_id/irrigation/schedule/optimize", controller.OptimizeSchedule)
+
+	const base = `"week_start":"2025-06-16","pump_capacity":250,"tariff":{"peak_price_per_m3":0.4,"off_peak_price_per_m3":0.1,"off_peak_start_hour":22,"off_peak_end_hour":6}`
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+	}{
+		{"optimize", `{` + base + `,"sectors":[{"sector_id":1,"weekly_volume":30000},{"sector_id":2,"weekly_volume":45000,"flow_rate":150}]}`, http.StatusOK},
+		{"unknown sector", `{` + base + `,"sectors":[{"sector_id":9,"weekly_volume":30000}]}`, http.StatusBadRequest},
+		{"no flow rate", `{` + base + `,"sectors":[{"sector_id":2,"weekly_volume":30000}]}`, http.StatusBadRequest},
+		{"duplicate sector", `{` + base + `,"sectors":[{"sector_id":1,"weekly_volume":30000},{"sector_id":1,"weekly_volume":100}]}`, http.StatusBadRequest},
+		{"no sectors", `{` + base + `,"sectors":[]}`, http.StatusBadRequest},
+		{"zero volume", `{` + base + `,"sectors":[{"sector_id":1,"weekly_volume":0}]}`, http.StatusBadRequest},
+		{"zero flow rate", `{` + base + `,"sectors":[{"sector_id":1,"weekly_volume":100,"flow_rate":0}]}`, http.StatusBadRequest},
+		{"no pump capacity", `{"week_start":"2025-06-16","tariff":{"off_peak_start_hour":22,"off_peak_end_hour":6},"sectors":[{"sector_id":1,"weekly_volume":100}]}`, http.StatusBadRequest},
+		{"invalid week", `{"week_start":"16/06/2025","pump_capacity":250,"tariff":{"off_peak_start_hour":22,"off_peak_end_hour":6},"sectors":[{"sector_id":1,"weekly_volume":100}]}`, http.StatusBadRequest},
+		{"same window hours", `{"week_start":"2025-06-16","pump_capacity":250,"tariff":{"off_peak_start_hour":6,"off_peak_end_hour":6},"sectors":[{"sector_id":1,"weekly_volume":100}]}`, http.StatusBadRequest},
+		{"malformed body", `{"sectors":`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/v1/farms/1/irrigation/schedule/optimize", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status code %d, got %d (%s)", tt.expectedCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestPlanVarianceController(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	controller := NewOptimizerController(&mockOptimizerService{}, slog.Default())
+	router := gin.New()
+	router.POST("/v1/farms/:farm_id/irrigation/schedule/variance", controller.GetPlanVariance)
+
+	const header = `"period":{"start_date":"2025-06-16T00:00:00Z","end_date":"2025-06-23T00:00:00Z"},"tariff":{"peak_price_per_m3":0.4,"off_peak_price_per_m3":0.1,"off_peak_start_hour":22,"off_peak_end_hour":6}`
+	const run = `{"sector_id":1,"start":"2025-06-16T22:00:00Z","end":"2025-06-17T02:00:00Z","volume":24000}`
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{"variance", `{"plan":{` + header + `,"runs":[` + run + `]}}`, http.StatusOK, `"timing_tolerance_minutes":60`},
+		{"tolerances", `{"plan":{` + header + `,"runs":[]},"timing_tolerance_minutes":0,"volume_tolerance":0.25}`, http.StatusOK, `"volume_tolerance":0.25`},
+		{"unknown sector", `{"plan":{` + header + `,"runs":[{"sector_id":9,"start":"2025-06-16T22:00:00Z","end":"2025-06-16T23:00:00Z","volume":10}]}}`, http.StatusBadRequest, ""},
+		{"run outside period", `{"plan":{` + header + `,"runs":[{"sector_id":1,"start":"2025-06-23T22:00:00Z","end":"2025-06-23T23:00:00Z","volume":10}]}}`, http.StatusBadRequest, ""},
+		{"reversed run", `{"plan":{` + header + `,"runs":[{"sector_id":1,"start":"2025-06-16T23:00:00Z","end":"2025-06-16T22:00:00Z","volume":10}]}}`, http.StatusBadRequest, ""},
+		{"no period", `{"plan":{"tariff":{"off_peak_start_hour":22,"off_peak_end_hour":6},"runs":[]}}`, http.StatusBadRequest, ""},
+		{"long period", `{"plan":{"period":{"start_date":"2025-01-01T00:00:00Z","end_date":"2025-06-01T00:00:00Z"},"tariff":{"off_peak_start_hour":22,"off_peak_end_hour":6},"runs":[]}}`, http.StatusBadRequest, ""},
+		{"timing tolerance", `{"plan":{` + header + `,"runs":[]},"timing_tolerance_minutes":721}`, http.StatusBadRequest, ""},
+		{"malformed body", `{"plan":`, http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/v1/farms/1/irrigation/schedule/variance", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status code %d, got %d (%s)", tt.expectedCode, w.Code, w.Body.String())
+			}
+			if tt.expectedBody != "" && !strings.Contains(w.Body.String(), tt.expectedBody) {
+				t.Errorf("expected %s in %s", tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OverlapController handles overlapping event HTTP requests. Its route is an operator tool in the
+// /admin group.
+type OverlapController struct {
+	overlapService service.OverlapService
+	logger         *slog.Logger
+}
+
+// NewOverlapController creates a new overlap controller
+func NewOverlapController(overlapService service.OverlapService, logger *slog.Logger) *OverlapController {
+	return &OverlapController{
+		overlapService: overlapService,
+		logger:         logger,
+	}
+}
+
+// ResolveOverlaps handles POST /admin/farms/{farm_id}/irrigation/overlaps
+// Query parameters:
+//   - start_date (required): Start date in ISO 8601 format (RFC3339 or YYYY-MM-DD)
+//   - end_date (required): End date in ISO 8601 format (RFC3339 or YYYY-MM-DD)
+//   - action (required): merge to fold each group of overlapping events into its earliest event,
+//     or flag to annotate the others without changing them
+//   - sector_id (optional): Only events of this sector and its zones
+//   - dry_run (optional): true to report the groups and the impact without writing
+//
+// Finds the events of each sector whose intervals overlap, as duplicate uploads leave them, and
+// merges or flags them so their volume stops being double-counted.
+func (c *OverlapController) ResolveOverlaps(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	startDate, endDate, ok := parseDateRange(ctx, c.logger, farmID)
+	if !ok {
+		return
+	}
+	action := ctx.Query("action")
+	if action != service.OverlapActionMerge && action != service.OverlapActionFlag {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid action",
+			"message": "action must be one of: merge, flag",
+		})
+		return
+	}
+	sectorID, ok := parseSectorID(ctx, c.logger, farmID)
+	if !ok {
+		return
+	}
+	dryRun, ok := parseBoolQuery(ctx, "dry_run")
+	if !ok {
+		return
+	}
+
+	if !ensureFarmExists(ctx, c.logger, c.overlapService, farmID, startTime) {
+		return
+	}
+
+	result, err := c.overlapService.ResolveOverlaps(farmID, sectorID, startDate, endDate, action, dryRun)
+	if errors.Is(err, service.ErrTooManyOverlaps) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Range too large",
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.logger.Error("failed to resolve overlapping events",
+			"farm_id", farmID,
+			"action", action,
+			"start_date", startDate.Format(time.RFC3339),
+			"end_date", endDate.Format(time.RFC3339),
+			"error", err.Error(),
+			"latency_ms", time.Since(startTime).Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to resolve overlapping events",
+		})
+		return
+	}
+
+	c.logger.Info("overlapping events resolved",
+		"farm_id", farmID,
+		"action", action,
+		"dry_run", dryRun,
+		"groups", result.Groups,
+		"merged", result.Merged,
+		"flagged", result.Flagged,
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusOK, result)
+}
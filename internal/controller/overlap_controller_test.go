@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mockOverlapService records the last resolution request
+type mockOverlapService struct {
+	action string
+	dryRun bool
+}
+
+func (m *mockOverlapService) FarmExists(farmID uint) (bool, error) {
+	return farmID == 1, nil
+}
+
+func (m *mockOverlapService) ResolveOverlaps(farmID uint, sectorID *uint, startDate, endDate time.Time, action string, dryRun bool) (*service.OverlapResolution, error) {
+	m.action, m.dryRun = action, dryRun
+	return &service.OverlapResolution{FarmID: farmID, Action: action, DryRun: dryRun, OverlapGroups: []service.OverlapGroup{}}, nil
+}
+
+func TestResolveOverlaps(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mock := &mockOverlapService{}
+	controller := NewOverlapController(mock, slog.Default())
+	router := gin.New()
+	router.POST("/admin/farms/:farm_id/irrigation/overlaps", controller.ResolveOverlaps)
+
+	tests := []struct {
+		name         string
+		query        string
+		farmID       string
+		expectedCode int
+	}{
+		{"merge preview", "start_date=2025-01-01&end_date=2025-02-01&action=merge&dry_run=true", "1", http.StatusOK},
+		{"flag one sector", "start_date=2025-01-01&end_date=2025-02-01&action=flag&sector_id=2", "1", http.StatusOK},
+		{"missing action", "start_date=2025-01-01&end_date=2025-02-01", "1", http.StatusBadRequest},
+		{"unknown action", "start_date=2025-01-01&end_date=2025-02-01&action=delete", "1", http.StatusBadRequest},
+		{"invalid dry_run", "start_date=2025-01-01&end_date=2025-02-01&action=merge&dry_run=maybe", "1", http.StatusBadRequest},
+		{"missing dates", "action=merge", "1", http.StatusBadRequest},
+		{"unknown farm", "start_date=2025-01-01&end_date=2025-02-01&action=merge", "9", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/admin/farms/"+tt.farmID+"/irrigation/overlaps?"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status code %d, got %d (%s)", tt.expectedCode, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	if mock.action != service.OverlapActionFlag || mock.dryRun {
+		t.Errorf("expected the last resolution to flag for real, got %q (dry run %v)", mock.action, mock.dryRun)
+	}
+}
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Plan variance request bounds and defaults
+const (
+	maxVarianceRange              = 92 * 24 * time.Hour
+	maxVarianceRuns               = 20000
+	maxTimingToleranceMinutes     = 720
+	maxVolumeTolerance            = 10
+	defaultTimingToleranceMinutes = 60
+	defaultVolumeTolerance        = 0.1
+)
+
+// varianceRequest is the body of a plan variance request
+type varianceRequest struct {
+	Plan                   service.IrrigationPlan `json:"plan"`
+	TimingToleranceMinutes *int                   `json:"timing_tolerance_minutes"`
+	VolumeTolerance        *float64               `json:"volume_tolerance"`
+}
+
+// GetPlanVariance handles POST /v1/farms/{farm_id}/irrigation/schedule/variance
+// Body fields:
+//   - plan (required): a plan as returned by the optimizer; its period (at most 92 days), tariff
+//     and runs are read, and each run needs sector_id, start, end and volume
+//   - timing_tolerance_minutes (optional): how far outside a planned run an event may fall and
+//     still be on plan, 0-720 (default: 60)
+//   - volume_tolerance (optional): the fraction a week's volume may differ from plan (default: 0.1)
+//
+// Nothing is written; the response compares the plan with the recorded events per sector and
+// week and lists the unplanned events and missed runs.
+func (c *OptimizerController) GetPlanVariance(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	var req varianceRequest
+	err := ctx.ShouldBindJSON(&req)
+	if writeBodyTooLarge(ctx, c.logger, farmID, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": "body must be a JSON object with a plan",
+		})
+		return
+	}
+	tolerance, errMessage := req.validate()
+	if errMessage != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid variance request",
+			"message": errMessage,
+		})
+		return
+	}
+
+	if !ensureFarmExists(ctx, c.logger, c.optimizerService, farmID, startTime) {
+		return
+	}
+
+	variance, err := c.optimizerService.Variance(farmID, req.Plan, tolerance)
+	if errors.Is(err, service.ErrSectorNotFound) || errors.Is(err, service.ErrTooManyEvents) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid variance request",
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to compute plan variance",
+			"farm_id", farmID,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to compute plan variance",
+		})
+		return
+	}
+
+	c.logger.Info("plan variance computed",
+		"farm_id", farmID,
+		"runs", len(req.Plan.Runs),
+		"deviations", len(variance.Deviations),
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusOK, variance)
+}
+
+// validate checks the plan and tolerances, returning the tolerances with their defaults applied
+// or a message describing the first problem found
+func (r varianceRequest) validate() (service.VarianceTolerance, string) {
+	tolerance := service.VarianceTolerance{TimingMinutes: defaultTimingToleranceMinutes, Volume: defaultVolumeTolerance}
+	if v := r.TimingToleranceMinutes; v != nil {
+		if *v < 0 || *v > maxTimingToleranceMinutes {
+			return tolerance, fmt.Sprintf("timing_tolerance_minutes must be between 0 and %d", maxTimingToleranceMinutes)
+		}
+		tolerance.TimingMinutes = *v
+	}
+	if v := r.VolumeTolerance; v != nil {
+		if !(*v >= 0 && *v <= maxVolumeTolerance) {
+			return tolerance, fmt.Sprintf("volume_tolerance must be between 0 and %d", maxVolumeTolerance)
+		}
+		tolerance.Volume = *v
+	}
+
+	plan := r.Plan
+	period := plan.Period
+	if !period.EndDate.After(period.StartDate) || period.EndDate.Sub(period.StartDate) > maxVarianceRange {
+		return tolerance, "plan.period must have an end_date after its start_date and at most 92 days later"
+	}
+	if errMessage := validateTariff(plan.Tariff); errMessage != "" {
+		return tolerance, "plan." + errMessage
+	}
+	if len(plan.Runs) > maxVarianceRuns {
+		return tolerance, fmt.Sprintf("plan.runs must list at most %d runs", maxVarianceRuns)
+	}
+	for i, run := range plan.Runs {
+		if !run.End.After(run.Start) || run.Start.Before(period.StartDate) || run.End.After(period.EndDate) {
+			return tolerance, fmt.Sprintf("plan.runs[%d]: must end after it starts, within the plan's period", i)
+		}
+		if !(run.Volume >= 0 && run.Volume < maxPlanWeeklyVolume) {
+			return tolerance, fmt.Sprintf("plan.runs[%d]: volume must be at least 0 and less than 1000000000", i)
+		}
+	}
+	return tolerance, ""
+}
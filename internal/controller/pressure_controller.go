@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxPressureReadings caps the number of readings accepted in a single request
+const maxPressureReadings = 10000
+
+// PressureController handles pressure telemetry HTTP requests
+type PressureController struct {
+	pressureService service.PressureService
+	logger          *slog.Logger
+}
+
+// NewPressureController creates a new pressure controller
+func NewPressureController(pressureService service.PressureService, logger *slog.Logger) *PressureController {
+	return &PressureController{
+		pressureService: pressureService,
+		logger:          logger,
+	}
+}
+
+// pressureRequest is the body of a pressure ingestion request
+type pressureRequest struct {
+	Readings []service.PressureInput `json:"readings"`
+}
+
+// RecordReadings handles POST /v1/farms/{farm_id}/pressure-readings
+// Body fields:
+//   - readings (required): sector_id, recorded_at, pressure_bar and optional device_id per reading
+//
+// The batch is stored only when every reading is valid; otherwise 422 lists the rejected readings.
+func (c *PressureController) RecordReadings(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	var req pressureRequest
+	err := ctx.ShouldBindJSON(&req)
+	if writeBodyTooLarge(ctx, c.logger, farmID, err) {
+		return
+	}
+	if err != nil || len(req.Readings) == 0 || len(req.Readings) > maxPressureReadings {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": fmt.Sprintf("body must be a JSON object with a readings array of 1 to %d readings", maxPressureReadings),
+		})
+		return
+	}
+
+	if !ensureFarmExists(ctx, c.logger, c.pressureService, farmID, startTime) {
+		return
+	}
+
+	rejected, err := c.pressureService.RecordReadings(farmID, req.Readings)
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to record pressure readings",
+			"farm_id", farmID,
+			"readings", len(req.Readings),
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to record pressure readings",
+		})
+		return
+	}
+	if len(rejected) > 0 {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":    "Invalid readings",
+			"message":  "no readings were stored; fix the rejected readings and resubmit the batch",
+			"rejected": rejected,
+		})
+		return
+	}
+
+	c.logger.Info("pressure readings recorded",
+		"farm_id", farmID,
+		"readings", len(req.Readings),
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"farm_id":  farmID,
+		"recorded": len(req.Readings),
+	})
+}
@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxLookbackDays bounds the history window of a recommendation request
+const maxLookbackDays = 90
+
+// RecommendationController handles irrigation scheduling recommendation HTTP requests
+type RecommendationController struct {
+	recommendationService service.RecommendationService
+	logger                *slog.Logger
+}
+
+// NewRecommendationController creates a new recommendation controller
+func NewRecommendationController(recommendationService service.RecommendationService, logger *slog.Logger) *RecommendationController {
+	return &RecommendationController{
+		recommendationService: recommendationService,
+		logger:                logger,
+	}
+}
+
+// GetRecommendations handles GET /v1/farms/{farm_id}/irrigation/recommendations
+// Query parameters:
+//   - lookback_days (optional): days of history before today used per sector, 3-90 (default: 14)
+//   - weather (optional): true to size volumes from stored ET0 and rainfall instead of recent volumes
+//   - units (optional): metric or imperial to report US gallons, acres and inches (default: metric)
+func (c *RecommendationController) GetRecommendations(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	lookbackDays, err := strconv.Atoi(ctx.DefaultQuery("lookback_days", "14"))
+	if err != nil || lookbackDays < 3 || lookbackDays > maxLookbackDays {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid lookback_days",
+			"message": "lookback_days must be an integer between 3 and 90",
+		})
+		return
+	}
+
+	weather, ok := parseBoolQuery(ctx, "weather")
+	if !ok {
+		return
+	}
+
+	units := ctx.DefaultQuery("units", service.UnitsMetric)
+	if !service.IsValidUnitSystem(units) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid units",
+			"message": "units must be one of: metric, imperial",
+		})
+		return
+	}
+
+	if !ensureFarmExists(ctx, c.logger, c.recommendationService, farmID, startTime) {
+		return
+	}
+
+	opts := service.RecommendationOptions{LookbackDays: lookbackDays, UseWeather: weather, Units: units}
+	recommendations, err := c.recommendationService.Recommend(farmID, time.Now().UTC(), opts)
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to compute recommendations",
+			"farm_id", farmID,
+			"lookback_days", lookbackDays,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to compute recommendations",
+		})
+		return
+	}
+
+	latency := time.Since(startTime)
+	c.logger.Info("recommendation request completed",
+		"farm_id", farmID,
+		"lookback_days", lookbackDays,
+		"used_weather", recommendations.UsedWeather,
+		"sectors", len(recommendations.Recommendations),
+		"latency_ms", latency.Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusOK, recommendations)
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegionDispatcher sends each request to the handler of the region holding its data. Every region
+// runs its own controllers, services and repositories over its own database, behind one API.
+type RegionDispatcher struct {
+	regions service.RegionService
+	logger  *slog.Logger
+}
+
+// NewRegionDispatcher creates a new region dispatcher
+func NewRegionDispatcher(regions service.RegionService, logger *slog.Logger) *RegionDispatcher {
+	return &RegionDispatcher{regions: regions, logger: logger}
+}
+
+// ByFarm returns a handler running the handler, from handlers keyed by region, of the region
+// holding the farm named by the farm_id path parameter
+func (d *RegionDispatcher) ByFarm(handlers map[string]gin.HandlerFunc) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		farmID, ok := parseFarmID(ctx, d.logger)
+		if !ok {
+			return
+		}
+
+		region, err := d.regions.RegionOf(farmID)
+		if err != nil {
+			d.logger.Error("failed to route farm",
+				"farm_id", farmID,
+				"error", err.Error(),
+			)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Internal server error",
+				"message": "Failed to find the farm's region",
+			})
+			return
+		}
+		d.dispatch(ctx, handlers, region)
+	}
+}
+
+// ByRegion returns a handler running the handler of the region named by the region query
+// parameter, or of the default region without one. It serves the routes not scoped to an
+// existing farm, such as onboarding, which creates the farm in that region.
+func (d *RegionDispatcher) ByRegion(handlers map[string]gin.HandlerFunc) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		region := ctx.DefaultQuery("region", d.regions.DefaultRegion())
+		if regions := d.regions.Regions(); !slices.Contains(regions, region) {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid region",
+				"message": "region must be one of: " + strings.Join(regions, ", "),
+			})
+			return
+		}
+		d.dispatch(ctx, handlers, region)
+	}
+}
+
+// dispatch runs the region's handler, writing a 500 response when the region has none
+func (d *RegionDispatcher) dispatch(ctx *gin.Context, handlers map[string]gin.HandlerFunc, region string) {
+	handler, ok := handlers[region]
+	if !ok {
+		d.logger.Error("no handler registered for region",
+			"region", region,
+			"path", ctx.FullPath(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Region is not served by this route",
+		})
+		return
+	}
+	handler(ctx)
+}
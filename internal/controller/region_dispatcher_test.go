@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mockRegionService routes farms by a fixed table
+type mockRegionService struct {
+	farms map[uint]string
+}
+
+func (m *mockRegionService) RegionOf(farmID uint) (string, error) {
+	if farmID == 13 {
+		return "", errors.New("routing database unavailable")
+	}
+	if region, ok := m.farms[farmID]; ok {
+		return region, nil
+	}
+	return "us", nil
+}
+
+func (m *mockRegionService) Regions() []string {
+	return []string{"eu", "us"}
+}
+
+func (m *mockRegionService) DefaultRegion() string {
+	return "us"
+}
+
+func TestRegionDispatcher(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dispatcher := NewRegionDispatcher(&mockRegionService{farms: map[uint]string{2: "eu", 3: "apac"}}, slog.Default())
+
+	regionHandler := func(region string) gin.HandlerFunc {
+		return func(ctx *gin.Context) {
+			ctx.JSON(http.StatusOK, gin.H{"region": region})
+		}
+	}
+	handlers := map[string]gin.HandlerFunc{"eu": regionHandler("eu"), "us": regionHandler("us")}
+
+	router := gin.New()
+	router.GET("/v1/farms/:farm_id/irrigation/analytics", dispatcher.ByFarm(handlers))
+	router.POST("/v1/farms/onboard", dispatcher.ByRegion(handlers))
+
+	tests := []struct {
+		name         string
+		method       string
+		url          string
+		expectedCode int
+		region       string
+	}{
+		{"routed farm", "GET", "/v1/farms/2/irrigation/analytics", http.StatusOK, "eu"},
+		{"unrouted farm", "GET", "/v1/farms/1/irrigation/analytics", http.StatusOK, "us"},
+		{"invalid farm id", "GET", "/v1/farms/abc/irrigation/analytics", http.StatusBadRequest, ""},
+		{"routing failure", "GET", "/v1/farms/13/irrigation/analytics", http.StatusInternalServerError, ""},
+		{"region without handler", "GET", "/v1/farms/3/irrigation/analytics", http.StatusInternalServerError, ""},
+		{"region by query", "POST", "/v1/farms/onboard?region=eu", http.StatusOK, "eu"},
+		{"default region", "POST", "/v1/farms/onboard", http.StatusOK, "us"},
+		{"unknown region", "POST", "/v1/farms/onboard?region=mars", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.url, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status code %d, got %d (%s)", tt.expectedCode, w.Code, w.Body.String())
+			}
+			if tt.region != "" && w.Body.String() != `{"region":"`+tt.region+`"}` {
+				t.Errorf("expected the %s handler to run, got %s", tt.region, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxReportRange bounds a report to one season or year so its charts stay readable
+const maxReportRange = 366 * 24 * time.Hour
+
+// GetIrrigationReport handles GET /v1/farms/{farm_id}/irrigation/report
+// Query parameters:
+//   - format (optional): pdf (default: pdf)
+//   - month (optional): the month to report on as YYYY-MM, instead of start_date and end_date
+//   - start_date, end_date (required without month): the season to report on, at most 366 days
+//
+// Buckets are daily for ranges of up to 62 days, weekly up to 183 days and monthly beyond.
+func (c *AnalyticsController) GetIrrigationReport(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	format := ctx.DefaultQuery("format", service.ReportFormatPDF)
+	if format != service.ReportFormatPDF {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid format",
+			"message": "format must be one of: pdf",
+		})
+		return
+	}
+
+	var startDate, endDate time.Time
+	if month := ctx.Query("month"); month != "" {
+		if ctx.Query("start_date") != "" || ctx.Query("end_date") != "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid date range",
+				"message": "month cannot be combined with start_date or end_date",
+			})
+			return
+		}
+		first, err := time.Parse("2006-01", month)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid month",
+				"message": "month must be in YYYY-MM format",
+			})
+			return
+		}
+		startDate, endDate = first, first.AddDate(0, 1, 0)
+	} else {
+		startDate, endDate, ok = parseDateRange(ctx, c.logger, farmID)
+		if !ok {
+			return
+		}
+		if endDate.Sub(startDate) > maxReportRange {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid date range",
+				"message": "a report covers up to 366 days",
+			})
+			return
+		}
+	}
+
+	aggregation := "monthly"
+	switch span := endDate.Sub(startDate); {
+	case span <= 62*24*time.Hour:
+		aggregation = "daily"
+	case span <= 183*24*time.Hour:
+		aggregation = "weekly"
+	}
+
+	analyticsService := c.analyticsService.WithContext(ctx.Request.Context())
+
+	if !ensureFarmExists(ctx, c.logger, analyticsService, farmID, startTime) {
+		return
+	}
+
+	analytics, err := analyticsService.GetIrrigationAnalytics(
+		farmID,
+		nil,
+		startDate,
+		endDate,
+		aggregation,
+		service.AnalyticsOptions{FillGaps: true},
+	)
+	if writeBudgetError(ctx, c.logger, farmID, err) || writeQueryCancelled(ctx, c.logger, farmID, err) {
+		return
+	}
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to build irrigation report",
+			"farm_id", farmID,
+			"start_date", startDate.Format(time.RFC3339),
+			"end_date", endDate.Format(time.RFC3339),
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to build irrigation report",
+		})
+		return
+	}
+
+	report := service.RenderReportPDF(analytics, time.Now())
+
+	c.logger.Info("irrigation report rendered",
+		"farm_id", farmID,
+		"format", format,
+		"aggregation", aggregation,
+		"bytes", len(report),
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	filename := fmt.Sprintf("farm-%d-irrigation-report-%s.pdf", farmID, startDate.Format("2006-01-02"))
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	ctx.Data(http.StatusOK, "application/pdf", report)
+}
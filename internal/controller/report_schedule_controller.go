@@ -0,0 +1,200 @@
+package controller
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Report schedule bounds
+const (
+	maxReportRecipients      = 20
+	maxReportRecipientLength = 254
+)
+
+// ReportScheduleController handles emailed report schedule HTTP requests
+type ReportScheduleController struct {
+	scheduleService service.ReportScheduleService
+	logger          *slog.Logger
+}
+
+// NewReportScheduleController creates a new report schedule controller
+func NewReportScheduleController(scheduleService service.ReportScheduleService, logger *slog.Logger) *ReportScheduleController {
+	return &ReportScheduleController{
+		scheduleService: scheduleService,
+		logger:          logger,
+	}
+}
+
+// reportScheduleRequest is the body of a report schedule create request
+type reportScheduleRequest struct {
+	Frequency  string   `json:"frequency"`
+	Recipients []string `json:"recipients"`
+	AttachPDF  bool     `json:"attach_pdf"`
+}
+
+// CreateReportSchedule handles POST /v1/farms/{farm_id}/reports/schedules
+// Body fields:
+//   - frequency (required): weekly, sent Mondays for the previous Monday to Sunday, or monthly,
+//     sent on the 1st for the previous month; reports go out at 06:00 UTC
+//   - recipients (required): 1-20 email addresses
+//   - attach_pdf (optional): attach the PDF irrigation report (default: false)
+func (c *ReportScheduleController) CreateReportSchedule(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	var req reportScheduleRequest
+	err := ctx.ShouldBindJSON(&req)
+	if writeBodyTooLarge(ctx, c.logger, farmID, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": "body must be a JSON report schedule object",
+		})
+		return
+	}
+	if errMessage := req.validate(); errMessage != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid report schedule",
+			"message": errMessage,
+		})
+		return
+	}
+
+	if !ensureFarmExists(ctx, c.logger, c.scheduleService, farmID, startTime) {
+		return
+	}
+
+	schedule, err := c.scheduleService.CreateSchedule(farmID, service.ReportScheduleInput{
+		Frequency:  req.Frequency,
+		Recipients: req.Recipients,
+		AttachPDF:  req.AttachPDF,
+	})
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to create report schedule",
+			"farm_id", farmID,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to create report schedule",
+		})
+		return
+	}
+
+	c.logger.Info("report schedule created",
+		"farm_id", farmID,
+		"schedule_id", schedule.ID,
+		"frequency", schedule.Frequency,
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusCreated, schedule)
+}
+
+// validate checks the request, returning a message describing the first problem found
+func (r reportScheduleRequest) validate() string {
+	if r.Frequency != model.ReportFrequencyWeekly && r.Frequency != model.ReportFrequencyMonthly {
+		return fmt.Sprintf("frequency must be one of: %s, %s", model.ReportFrequencyWeekly, model.ReportFrequencyMonthly)
+	}
+	if len(r.Recipients) == 0 || len(r.Recipients) > maxReportRecipients {
+		return fmt.Sprintf("recipients must list between 1 and %d email addresses", maxReportRecipients)
+	}
+	for i, recipient := range r.Recipients {
+		address, err := mail.ParseAddress(recipient)
+		if err != nil || address.Address != recipient || len(recipient) > maxReportRecipientLength {
+			return fmt.Sprintf("recipients[%d] must be a plain email address such as name@example.com", i)
+		}
+	}
+	return ""
+}
+
+// ListReportSchedules handles GET /v1/farms/{farm_id}/reports/schedules
+func (c *ReportScheduleController) ListReportSchedules(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	if !ensureFarmExists(ctx, c.logger, c.scheduleService, farmID, startTime) {
+		return
+	}
+
+	schedules, err := c.scheduleService.ListSchedules(farmID)
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to list report schedules",
+			"farm_id", farmID,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to list report schedules",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"farm_id":   farmID,
+		"schedules": schedules,
+	})
+}
+
+// DeleteReportSchedule handles DELETE /v1/farms/{farm_id}/reports/schedules/{schedule_id}
+func (c *ReportScheduleController) DeleteReportSchedule(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+	scheduleID, err := strconv.ParseUint(ctx.Param("schedule_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid schedule_id",
+			"message": "schedule_id must be a valid unsigned integer",
+		})
+		return
+	}
+
+	deleted, err := c.scheduleService.DeleteSchedule(farmID, uint(scheduleID))
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to delete report schedule",
+			"farm_id", farmID,
+			"schedule_id", scheduleID,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to delete report schedule",
+		})
+		return
+	}
+	if !deleted {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Report schedule not found",
+			"message": fmt.Sprintf("Report schedule with ID %d does not exist for farm %d", scheduleID, farmID),
+		})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mockReportScheduleService is a mock implementation of ReportScheduleService for testing
+type mockReportScheduleService struct{}
+
+func (m *mockReportScheduleService) FarmExists(farmID uint) (bool, error) {
+	return farmID == 1, nil
+}
+
+func (m *mockReportScheduleService) CreateSchedule(farmID uint, input service.ReportScheduleInput) (*model.ReportSchedule, error) {
+	return &model.ReportSchedule{ID: 1, FarmID: farmID, Frequency: input.Frequency, Recipients: input.Recipients}, nil
+}
+
+func (m *mockReportScheduleService) ListSchedules(farmID uint) ([]model.ReportSchedule, error) {
+	return []model.ReportSchedule{}, nil
+}
+
+func (m *mockReportScheduleService) DeleteSchedule(farmID, scheduleID uint) (bool, error) {
+	return scheduleID == 1, nil
+}
+
+func TestReportScheduleController(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	controller := NewReportScheduleController(&mockReportScheduleService{}, slog.Default())
+	router := gin.New()
+	router.POST("/v1/farms/:farm_id/reports/schedules", controller.CreateReportSchedule)
+	router.GET("/v1/farms/:farm_id/reports/schedules", controller.ListReportSchedules)
+	router.DELETE("/v1/farms/:farm_id/reports/schedules/:schedule_id", controller.DeleteReportSchedule)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		expectedCode int
+	}{
+		{"create weekly", "POST", "/v1/farms/1/reports/schedules", `{"frequency":"weekly","recipients":["owner@example.com"]}`, http.StatusCreated},
+		{"create monthly with pdf", "POST", "/v1/farms/1/reports/schedules", `{"frequency":"monthly","recipients":["a@example.com","b@example.com"],"attach_pdf":true}`, http.StatusCreated},
+		{"unknown farm", "POST", "/v1/farms/2/reports/schedules", `{"frequency":"weekly","recipients":["owner@example.com"]}`, http.StatusNotFound},
+		{"daily", "POST", "/v1/farms/1/reports/schedules", `{"frequency":"daily","recipients":["owner@example.com"]}`, http.StatusBadRequest},
+		{"no recipients", "POST", "/v1/farms/1/reports/schedules", `{"frequency":"weekly","recipients":[]}`, http.StatusBadRequest},
+		{"invalid recipient", "POST", "/v1/farms/1/reports/schedules", `{"frequency":"weekly","recipients":["owner"]}`, http.StatusBadRequest},
+		{"display name", "POST", "/v1/farms/1/reports/schedules", `{"frequency":"weekly","recipients":["Owner <owner@example.com>"]}`, http.StatusBadRequest},
+		{"malformed body", "POST", "/v1/farms/1/reports/schedules", `{"frequency":`, http.StatusBadRequest},
+		{"list", "GET", "/v1/farms/1/reports/schedules", "", http.StatusOK},
+		{"delete", "DELETE", "/v1/farms/1/reports/schedules/1", "", http.StatusNoContent},
+		{"delete unknown", "DELETE", "/v1/farms/1/reports/schedules/2", "", http.StatusNotFound},
+		{"delete invalid id", "DELETE", "/v1/farms/1/reports/schedules/abc", "", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status code %d, got %d (%s)", tt.expectedCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
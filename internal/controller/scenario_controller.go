@@ -0,0 +1,169 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Scenario request bounds
+const (
+	maxScenarioChanges    = 20
+	maxScenarioRange      = 366 * 24 * time.Hour
+	maxScenarioEfficiency = 10
+)
+
+// ScenarioController handles what-if scenario HTTP requests
+type ScenarioController struct {
+	scenarioService service.ScenarioService
+	logger          *slog.Logger
+}
+
+// NewScenarioController creates a new scenario controller
+func NewScenarioController(scenarioService service.ScenarioService, logger *slog.Logger) *ScenarioController {
+	return &ScenarioController{
+		scenarioService: scenarioService,
+		logger:          logger,
+	}
+}
+
+// scenarioRequest is the body of a what-if request
+type scenarioRequest struct {
+	StartDate string                   `json:"start_date"`
+	EndDate   string                   `json:"end_date"`
+	Tariff    service.Tariff           `json:"tariff"`
+	Changes   []service.ScenarioChange `json:"changes"`
+}
+
+// EvaluateScenario handles POST /v1/farms/{farm_id}/irrigation/scenarios
+// Body fields:
+//   - start_date, end_date (required): the recorded period to replay, at most 366 days
+//   - tariff (required): peak_price_per_m3, off_peak_price_per_m3, and the UTC off_peak_start_hour
+//     and off_peak_end_hour, 0-23; the window wraps past midnight when it starts later than it ends
+//   - changes (required): 1-20 changes applied in order, each one of
+//     {"type": "shift_off_peak", "fraction": 0-1, "sector_id": optional} or
+//     {"type": "set_efficiency", "sector_id": required, "efficiency": target}
+//
+// Nothing is written; the response compares the period's recorded volume and cost with the scenario.
+func (c *ScenarioController) EvaluateScenario(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	var req scenarioRequest
+	err := ctx.ShouldBindJSON(&req)
+	if writeBodyTooLarge(ctx, c.logger, farmID, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": "body must be a JSON scenario object",
+		})
+		return
+	}
+	startDate, endDate, errMessage := req.validate()
+	if errMessage != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid scenario",
+			"message": errMessage,
+		})
+		return
+	}
+
+	if !ensureFarmExists(ctx, c.logger, c.scenarioService, farmID, startTime) {
+		return
+	}
+
+	response, err := c.scenarioService.Evaluate(farmID, startDate, endDate, req.Tariff, req.Changes)
+	if errors.Is(err, service.ErrSectorNotFound) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid scenario",
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to evaluate scenario",
+			"farm_id", farmID,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to evaluate scenario",
+		})
+		return
+	}
+
+	c.logger.Info("scenario evaluated",
+		"farm_id", farmID,
+		"changes", len(req.Changes),
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// validate parses the period and checks the request, returning a message describing the first
+// problem found
+func (r scenarioRequest) validate() (time.Time, time.Time, string) {
+	startDate, err := parseISO8601Date(r.StartDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, "start_date must be in ISO 8601 format (RFC3339 or YYYY-MM-DD)"
+	}
+	endDate, err := parseISO8601Date(r.EndDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, "end_date must be in ISO 8601 format (RFC3339 or YYYY-MM-DD)"
+	}
+	if !endDate.After(startDate) || endDate.Sub(startDate) > maxScenarioRange {
+		return time.Time{}, time.Time{}, "end_date must be after start_date and at most 366 days later"
+	}
+
+	if errMessage := validateTariff(r.Tariff); errMessage != "" {
+		return time.Time{}, time.Time{}, errMessage
+	}
+
+	if len(r.Changes) == 0 || len(r.Changes) > maxScenarioChanges {
+		return time.Time{}, time.Time{}, fmt.Sprintf("changes must list between 1 and %d changes", maxScenarioChanges)
+	}
+	for i, change := range r.Changes {
+		switch change.Type {
+		case service.ScenarioShiftOffPeak:
+			if !(change.Fraction > 0 && change.Fraction <= 1) {
+				return time.Time{}, time.Time{}, fmt.Sprintf("changes[%d]: fraction must be greater than 0 and at most 1", i)
+			}
+		case service.ScenarioSetEfficiency:
+			if change.SectorID == nil {
+				return time.Time{}, time.Time{}, fmt.Sprintf("changes[%d]: sector_id is required", i)
+			}
+			if !(change.Efficiency > 0 && change.Efficiency < maxScenarioEfficiency) {
+				return time.Time{}, time.Time{}, fmt.Sprintf("changes[%d]: efficiency must be greater than 0 and less than %d", i, maxScenarioEfficiency)
+			}
+		default:
+			return time.Time{}, time.Time{}, fmt.Sprintf("changes[%d]: type must be one of: %s, %s", i, service.ScenarioShiftOffPeak, service.ScenarioSetEfficiency)
+		}
+	}
+	return startDate, endDate, ""
+}
+
+// validateTariff checks a tariff's prices and off-peak window, returning a message describing the
+// first problem found
+func validateTariff(t service.Tariff) string {
+	if t.PeakPricePerM3 < 0 || t.OffPeakPricePerM3 < 0 {
+		return "tariff prices must not be negative"
+	}
+	if t.OffPeakStartHour < 0 || t.OffPeakStartHour > 23 || t.OffPeakEndHour < 0 || t.OffPeakEndHour > 23 || t.OffPeakStartHour == t.OffPeakEndHour {
+		return "tariff off_peak_start_hour and off_peak_end_hour must be different hours between 0 and 23"
+	}
+	return ""
+}
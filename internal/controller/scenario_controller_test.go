@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mockScenarioService knows sectors 1 and 2
+type mockScenarioService struct{}
+
+func (m *mockScenarioService) FarmExists(farmID uint) (bool, error) {
+	return true, nil
+}
+
+func (m *mockScenarioService) Evaluate(farmID uint, startDate, endDate time.Time, tariff service.Tariff, changes []service.ScenarioChange) (*service.ScenarioResponse, error) {
+	for i, change := range changes {
+		if change.SectorID != nil && *change.SectorID > 2 {
+			return nil, fmt.Errorf("changes[%d]: %w: %d", i, service.ErrSectorNotFound, *change.SectorID)
+		}
+	}
+	return &service.ScenarioResponse{FarmID: farmID, Tariff: tariff}, nil
+}
+
+func TestScenarioController(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	controller := NewScenarioController(&mockScenarioService{}, slog.Default())
+	router := gin.New()
+	router.POST("/v1/farms/:farm_id/irrigation/scenarios", controller.EvaluateScenario)
+
+	const period = `"start_date":"2025-06-01","end_date":"2025-07-01"`
+	const tariff = `"tariff":{"peak_price_per_m3":0.4,"off_peak_price_per_m3":0.15,"off_peak_start_hour":22,"off_peak_end_hour":6}`
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+	}{
+		{"shift and efficiency", `{` + period + `,` + tariff + `,"changes":[{"type":"shift_off_peak","fraction":0.3},{"type":"set_efficiency","sector_id":2,"efficiency":0.95}]}`, http.StatusOK},
+		{"shift one sector", `{` + period + `,` + tariff + `,"changes":[{"type":"shift_off_peak","fraction":1,"sector_id":1}]}`, http.StatusOK},
+		{"unknown sector", `{` + period + `,` + tariff + `,"changes":[{"type":"set_efficiency","sector_id":9,"efficiency":0.95}]}`, http.StatusBadRequest},
+		{"no changes", `{` + period + `,` + tariff + `,"changes":[]}`, http.StatusBadRequest},
+		{"unknown type", `{` + period + `,` + tariff + `,"changes":[{"type":"add_sector"}]}`, http.StatusBadRequest},
+		{"fraction too large", `{` + period + `,` + tariff + `,"changes":[{"type":"shift_off_peak","fraction":1.5}]}`, http.StatusBadRequest},
+		{"efficiency without sector", `{` + period + `,` + tariff + `,"changes":[{"type":"set_efficiency","efficiency":0.95}]}`, http.StatusBadRequest},
+		{"missing tariff", `{` + period + `,"changes":[{"type":"shift_off_peak","fraction":0.3}]}`, http.StatusBadRequest},
+		{"negative price", `{` + period + `,"tariff":{"peak_price_per_m3":-1,"off_peak_start_hour":22,"off_peak_end_hour":6},"changes":[{"type":"shift_off_peak","fraction":0.3}]}`, http.StatusBadRequest},
+		{"reversed period", `{"start_date":"2025-07-01","end_date":"2025-06-01",` + tariff + `,"changes":[{"type":"shift_off_peak","fraction":0.3}]}`, http.StatusBadRequest},
+		{"malformed body", `{"changes":`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/v1/farms/1/irrigation/scenarios", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status code %d, got %d (%s)", tt.expectedCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTimeSeries handles GET /v1/farms/{farm_id}/timeseries
+// Query parameters:
+//   - metrics (required): comma-separated metric names (see service.TimeSeriesMetrics)
+//   - start_date (required): Start date in ISO 8601 format (RFC3339 or YYYY-MM-DD)
+//   - end_date (required): End date in ISO 8601 format (RFC3339 or YYYY-MM-DD)
+//   - sector_id (optional): Filter by specific sector
+//   - aggregation (optional): hourly, daily, weekly, monthly, or custom with bucket_days (default: daily)
+func (c *AnalyticsController) GetTimeSeries(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	sectorID, ok := parseSectorID(ctx, c.logger, farmID)
+	if !ok {
+		return
+	}
+
+	startDate, endDate, ok := parseDateRange(ctx, c.logger, farmID)
+	if !ok {
+		return
+	}
+
+	aggregation, ok := parseAggregation(ctx)
+	if !ok {
+		return
+	}
+	if aggregation == "hourly" && endDate.Sub(startDate) > maxHourlyRange {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid date range",
+			"message": "hourly aggregation supports ranges of up to 31 days",
+		})
+		return
+	}
+
+	var metrics []string
+	seen := make(map[string]bool)
+	for _, metric := range strings.Split(ctx.Query("metrics"), ",") {
+		metric = strings.TrimSpace(metric)
+		if metric != "" && !seen[metric] {
+			seen[metric] = true
+			metrics = append(metrics, metric)
+		}
+	}
+	if len(metrics) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Missing required parameter",
+			"message": "metrics is required: " + strings.Join(service.TimeSeriesMetrics(), ", "),
+		})
+		return
+	}
+	if err := service.ValidateMetrics(metrics); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid metrics",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	analyticsService := c.analyticsService.WithContext(ctx.Request.Context())
+
+	if !ensureFarmExists(ctx, c.logger, analyticsService, farmID, startTime) {
+		return
+	}
+
+	series, err := analyticsService.GetTimeSeries(farmID, sectorID, startDate, endDate, aggregation, metrics)
+	if writeBudgetError(ctx, c.logger, farmID, err) || writeQueryCancelled(ctx, c.logger, farmID, err) {
+		return
+	}
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to retrieve time series",
+			"farm_id", farmID,
+			"sector_id", sectorID,
+			"metrics", metrics,
+			"aggregation", aggregation,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to retrieve time series",
+		})
+		return
+	}
+
+	c.logger.Info("time series request completed",
+		"farm_id", farmID,
+		"metrics", metrics,
+		"aggregation", aggregation,
+		"buckets", len(series.Timestamps),
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusOK, series)
+}
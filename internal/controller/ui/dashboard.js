@@ -0,0 +1,241 @@
+// Mini dashboard for a farm's irrigation analytics. It calls the public analytics endpoint
+// with the same credentials as any other client and draws the charts as plain SVG, so
+// nothing is loaded from outside the server.
+(function () {
+  "use strict";
+
+  var SVG_NS = "http://www.w3.org/2000/svg";
+  var CREDENTIAL_KEY = "irrigation-analytics.credential";
+
+  var form = document.getElementById("query");
+  var status = document.getElementById("status");
+  var results = document.getElementById("results");
+
+  // Starting values come from the query string (e.g. /ui/?farm_id=1) so a view can be shared,
+  // defaulting to the last 30 days
+  function restoreForm() {
+    var params = new URLSearchParams(window.location.search);
+    var end = new Date();
+    var start = new Date(end.getTime() - 29 * 24 * 3600 * 1000);
+    form.farm_id.value = params.get("farm_id") || "";
+    form.start_date.value = params.get("start_date") || isoDate(start);
+    form.end_date.value = params.get("end_date") || isoDate(end);
+    form.aggregation.value = params.get("aggregation") || "daily";
+    form.credential.value = window.localStorage.getItem(CREDENTIAL_KEY) || "";
+  }
+
+  function isoDate(date) {
+    return date.toISOString().slice(0, 10);
+  }
+
+  // A credential with three dot-separated parts is a JWT; anything else is a static API key
+  function authHeaders(credential) {
+    if (!credential) {
+      return {};
+    }
+    if (credential.split(".").length === 3) {
+      return { Authorization: "Bearer " + credential };
+    }
+    return { "X-API-Key": credential };
+  }
+
+  function setStatus(message, isError) {
+    status.textContent = message;
+    status.className = isError ? "error" : "";
+  }
+
+  function load(event) {
+    event.preventDefault();
+
+    var query = new URLSearchParams({
+      start_date: form.start_date.value,
+      end_date: form.end_date.value,
+      aggregation: form.aggregation.value,
+      fill_gaps: "true"
+    });
+    var credential = form.credential.value.trim();
+    window.localStorage.setItem(CREDENTIAL_KEY, credential);
+    window.history.replaceState(null, "", "?" + new URLSearchParams({
+      farm_id: form.farm_id.value,
+      start_date: form.start_date.value,
+      end_date: form.end_date.value,
+      aggregation: form.aggregation.value
+    }));
+
+    setStatus("Loading…", false);
+    var url = "/v1/farms/" + encodeURIComponent(form.farm_id.value) + "/irrigation/analytics?" + query;
+    fetch(url, { headers: authHeaders(credential) })
+      .then(function (response) {
+        return response.json().then(function (body) {
+          if (!response.ok) {
+            throw new Error(body.message || body.error || response.statusText);
+          }
+          return body;
+        });
+      })
+      .then(function (analytics) {
+        render(analytics);
+        setStatus("", false);
+      })
+      .catch(function (err) {
+        results.hidden = true;
+        setStatus("Could not load analytics: " + err.message, true);
+      });
+  }
+
+  function render(analytics) {
+    var summary = analytics.summary;
+    var lastYear = (analytics.period_comparison || {}).one_year_ago;
+
+    text("total-volume", formatNumber(summary.total_water_volume) + (analytics.units === "imperial" ? " gal" : " L"));
+    text("total-events", formatNumber(summary.total_events));
+    text("avg-efficiency", formatPercent(summary.average_efficiency));
+    text("total-duration", formatNumber(summary.total_duration / 60) + " h");
+    text("volume-change", lastYear ? formatChange(lastYear.volume_change_percent) : "");
+    text("events-change", lastYear ? formatChange(lastYear.events_change_percent) : "");
+    text("efficiency-change", lastYear ? formatChange(lastYear.efficiency_change_percent) : "");
+
+    var points = analytics.data || [];
+    barChart("volume-chart", points.map(function (point) {
+      return { label: point.period.slice(0, 10), value: point.water_volume, provisional: point.is_final === false };
+    }));
+    lineChart("efficiency-chart", points.map(function (point) {
+      return { label: point.period.slice(0, 10), value: point.event_count > 0 ? point.efficiency : null };
+    }));
+    barChart("sector-chart", (analytics.sector_breakdown || []).map(function (sector) {
+      return { label: "Sector " + sector.sector_id, value: sector.total_water_volume };
+    }));
+
+    var warnings = document.getElementById("warnings");
+    warnings.textContent = "";
+    (analytics.warnings || []).forEach(function (warning) {
+      var item = document.createElement("li");
+      item.textContent = warning.section + ": " + warning.message;
+      warnings.appendChild(item);
+    });
+
+    results.hidden = false;
+  }
+
+  function text(id, value) {
+    document.getElementById(id).textContent = value;
+  }
+
+  function formatNumber(value) {
+    return Number(value || 0).toLocaleString(undefined, { maximumFractionDigits: 1 });
+  }
+
+  function formatPercent(ratio) {
+    return formatNumber((ratio || 0) * 100) + " %";
+  }
+
+  function formatChange(percent) {
+    var sign = percent > 0 ? "+" : "";
+    return sign + formatNumber(percent) + " % vs last year";
+  }
+
+  function svg(name, attrs, parent) {
+    var node = document.createElementNS(SVG_NS, name);
+    Object.keys(attrs).forEach(function (key) {
+      node.setAttribute(key, attrs[key]);
+    });
+    if (parent) {
+      parent.appendChild(node);
+    }
+    return node;
+  }
+
+  function chartFrame(id, values, format) {
+    var container = document.getElementById(id);
+    container.textContent = "";
+    if (values.length === 0) {
+      var empty = document.createElement("div");
+      empty.className = "empty";
+      empty.textContent = "No data for this period";
+      container.appendChild(empty);
+      return null;
+    }
+    var frame = { width: 900, height: 220, left: 56, bottom: 24, top: 8 };
+    frame.root = svg("svg", { viewBox: "0 0 " + frame.width + " " + frame.height }, container);
+    frame.max = Math.max.apply(null, values) || 1;
+    frame.plotWidth = frame.width - frame.left;
+    frame.plotHeight = frame.height - frame.bottom - frame.top;
+    frame.y = function (value) {
+      return frame.top + frame.plotHeight * (1 - value / frame.max);
+    };
+    svg("text", { x: frame.left - 6, y: frame.top + 10, "text-anchor": "end" }, frame.root).textContent = format(frame.max);
+    svg("text", { x: frame.left - 6, y: frame.top + frame.plotHeight, "text-anchor": "end" }, frame.root).textContent = format(0);
+    return frame;
+  }
+
+  // xLabels labels the first, middle and last items, which stays readable at any bucket count
+  function xLabels(frame, items, step) {
+    var picks = [0, Math.floor((items.length - 1) / 2), items.length - 1];
+    picks.forEach(function (index, i) {
+      if (i > 0 && index === picks[i - 1]) {
+        return;
+      }
+      svg("text", {
+        x: frame.left + step * (index + 0.5),
+        y: frame.height - 6,
+        "text-anchor": "middle"
+      }, frame.root).textContent = items[index].label;
+    });
+  }
+
+  function barChart(id, items) {
+    var frame = chartFrame(id, items.map(function (item) { return item.value; }), formatNumber);
+    if (!frame) {
+      return;
+    }
+    var step = frame.plotWidth / items.length;
+    items.forEach(function (item, index) {
+      var y = frame.y(item.value);
+      var bar = svg("rect", {
+        class: item.provisional ? "bar provisional" : "bar",
+        x: frame.left + step * index + step * 0.1,
+        y: y,
+        width: Math.max(step * 0.8, 1),
+        height: frame.top + frame.plotHeight - y
+      }, frame.root);
+      svg("title", {}, bar).textContent = item.label + ": " + formatNumber(item.value);
+    });
+    xLabels(frame, items, step);
+  }
+
+  // lineChart skips buckets without a value, breaking the line there, and marks 100 %
+  // efficiency with a dashed target line
+  function lineChart(id, items) {
+    var values = items.filter(function (item) { return item.value !== null; });
+    var scale = values.length > 0 ? values.map(function (item) { return item.value; }).concat([1]) : [];
+    var frame = chartFrame(id, scale, formatPercent);
+    if (!frame) {
+      return;
+    }
+    var step = frame.plotWidth / items.length;
+    svg("line", {
+      class: "target",
+      x1: frame.left, x2: frame.width,
+      y1: frame.y(1), y2: frame.y(1)
+    }, frame.root);
+
+    var path = "";
+    var drawing = false;
+    items.forEach(function (item, index) {
+      if (item.value === null) {
+        drawing = false;
+        return;
+      }
+      path += (drawing ? " L " : " M ") + (frame.left + step * (index + 0.5)) + " " + frame.y(item.value);
+      drawing = true;
+    });
+    svg("path", { class: "line", d: path.trim() }, frame.root);
+    xLabels(frame, items, step);
+  }
+
+  form.addEventListener("submit", load);
+  restoreForm();
+  if (form.farm_id.value) {
+    form.requestSubmit();
+  }
+})();
@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"log/slog"
+	"net/http"
+
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VersionController serves the build and runtime details of the deployment
+type VersionController struct {
+	info   *service.VersionInfo
+	logger *slog.Logger
+}
+
+// NewVersionController creates a new version controller. features names the optional features
+// the server was started with and whether each is enabled; the details are collected once.
+func NewVersionController(features map[string]bool, logger *slog.Logger) (*VersionController, error) {
+	info, err := service.BuildVersionInfo(features)
+	if err != nil {
+		return nil, err
+	}
+	return &VersionController{info: info, logger: logger}, nil
+}
+
+// GetVersion handles GET /v1/version
+// Returns the version, git commit and build time embedded at build time, the schema migration
+// version and the feature flags, so support can tell what a deployment is running.
+func (c *VersionController) GetVersion(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, c.info)
+}
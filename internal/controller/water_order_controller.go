@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxWaterOrderRange bounds one reconciliation to a year of district orders
+const maxWaterOrderRange = 366 * 24 * time.Hour
+
+// WaterOrderController handles water order reconciliation HTTP requests
+type WaterOrderController struct {
+	waterOrderService service.WaterOrderService
+	logger            *slog.Logger
+}
+
+// NewWaterOrderController creates a new water order controller
+func NewWaterOrderController(waterOrderService service.WaterOrderService, logger *slog.Logger) *WaterOrderController {
+	return &WaterOrderController{
+		waterOrderService: waterOrderService,
+		logger:            logger,
+	}
+}
+
+// GetWaterOrderReconciliation handles GET /v1/farms/{farm_id}/water-orders/reconciliation
+// Query parameters:
+//   - start_date (required): Start date in ISO 8601 format (RFC3339 or YYYY-MM-DD)
+//   - end_date (required): End date in ISO 8601 format (RFC3339 or YYYY-MM-DD); at most 366 days after start_date
+//   - aggregation (optional): daily, weekly or monthly (default: monthly)
+//
+// Reads the farm's water orders from the irrigation district and compares the volumes ordered,
+// delivered and applied in each period.
+func (c *WaterOrderController) GetWaterOrderReconciliation(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	startDate, endDate, ok := parseDateRange(ctx, c.logger, farmID)
+	if !ok {
+		return
+	}
+	if endDate.Sub(startDate) > maxWaterOrderRange {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid date range",
+			"message": "water orders can be reconciled for up to 366 days at once",
+		})
+		return
+	}
+
+	aggregation := ctx.DefaultQuery("aggregation", service.WaterOrderPeriodMonthly)
+	switch aggregation {
+	case service.WaterOrderPeriodDaily, service.WaterOrderPeriodWeekly, service.WaterOrderPeriodMonthly:
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid aggregation",
+			"message": "aggregation must be one of: daily, weekly, monthly",
+		})
+		return
+	}
+
+	reconciliation, err := c.waterOrderService.Reconcile(ctx.Request.Context(), farmID, startDate, endDate, aggregation)
+	switch {
+	case errors.Is(err, service.ErrFarmNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Farm not found",
+			"message": fmt.Sprintf("Farm with ID %d does not exist", farmID),
+		})
+		return
+	case errors.Is(err, service.ErrFarmWithoutWaterAccount):
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "Water account unknown",
+			"message": "set the farm's water_account before reconciling water orders",
+		})
+		return
+	case errors.Is(err, service.ErrWaterOrderProvider):
+		c.logger.Error("water order provider request failed",
+			"farm_id", farmID,
+			"error", err.Error(),
+			"latency_ms", time.Since(startTime).Milliseconds(),
+		)
+		ctx.JSON(http.StatusBadGateway, gin.H{
+			"error":   "Upstream query failed",
+			"message": "the irrigation district's order API could not be reached",
+		})
+		return
+	case err != nil:
+		c.logger.Error("water order reconciliation failed",
+			"farm_id", farmID,
+			"error", err.Error(),
+			"latency_ms", time.Since(startTime).Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to reconcile water orders",
+		})
+		return
+	}
+
+	c.logger.Info("water orders reconciled",
+		"farm_id", farmID,
+		"provider", reconciliation.Provider,
+		"orders", len(reconciliation.Orders),
+		"aggregation", aggregation,
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusOK, reconciliation)
+}
@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxWeatherSyncRange bounds one sync to a year of daily provider data
+const maxWeatherSyncRange = 366 * 24 * time.Hour
+
+// WeatherController handles weather sync HTTP requests
+type WeatherController struct {
+	weatherService service.WeatherService
+	logger         *slog.Logger
+}
+
+// NewWeatherController creates a new weather controller
+func NewWeatherController(weatherService service.WeatherService, logger *slog.Logger) *WeatherController {
+	return &WeatherController{
+		weatherService: weatherService,
+		logger:         logger,
+	}
+}
+
+// SyncWeather handles POST /v1/farms/{farm_id}/weather/sync
+// Query parameters:
+//   - start_date (required): Start date in ISO 8601 format (RFC3339 or YYYY-MM-DD)
+//   - end_date (required): End date in ISO 8601 format (RFC3339 or YYYY-MM-DD); at most 366 days after start_date
+//
+// Fetches daily precipitation and ET0 for the farm's latitude and longitude from the weather
+// provider and stores them, replacing days already stored.
+func (c *WeatherController) SyncWeather(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	startDate, endDate, ok := parseDateRange(ctx, c.logger, farmID)
+	if !ok {
+		return
+	}
+	if endDate.Sub(startDate) > maxWeatherSyncRange {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid date range",
+			"message": "weather can be synced for up to 366 days at once",
+		})
+		return
+	}
+
+	result, err := c.weatherService.SyncWeather(farmID, startDate, endDate)
+	switch {
+	case errors.Is(err, service.ErrFarmNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Farm not found",
+			"message": fmt.Sprintf("Farm with ID %d does not exist", farmID),
+		})
+		return
+	case errors.Is(err, service.ErrFarmWithoutCoordinates):
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "Farm location unknown",
+			"message": "set the farm's latitude and longitude before syncing weather",
+		})
+		return
+	case errors.Is(err, service.ErrWeatherProvider):
+		c.logger.Error("weather provider request failed",
+			"farm_id", farmID,
+			"error", err.Error(),
+			"latency_ms", time.Since(startTime).Milliseconds(),
+		)
+		ctx.JSON(http.StatusBadGateway, gin.H{
+			"error":   "Upstream query failed",
+			"message": "the weather provider could not be reached; nothing was stored",
+		})
+		return
+	case err != nil:
+		c.logger.Error("weather sync failed",
+			"farm_id", farmID,
+			"error", err.Error(),
+			"latency_ms", time.Since(startTime).Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to store weather",
+		})
+		return
+	}
+
+	c.logger.Info("weather synced",
+		"farm_id", farmID,
+		"provider", result.Provider,
+		"days", result.Days,
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,280 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Webhook bounds matching the column sizes
+const (
+	maxWebhookURLLength   = 2048
+	maxWebhookVolume      = 1e12
+	maxWebhookEfficiency  = 10
+	maxWebhookNoDataHours = 720
+)
+
+// WebhookController handles farm alert webhook HTTP requests
+type WebhookController struct {
+	webhookService service.WebhookService
+	logger         *slog.Logger
+}
+
+// NewWebhookController creates a new webhook controller
+func NewWebhookController(webhookService service.WebhookService, logger *slog.Logger) *WebhookController {
+	return &WebhookController{
+		webhookService: webhookService,
+		logger:         logger,
+	}
+}
+
+// webhookRequest is the body of a webhook create request
+type webhookRequest struct {
+	URL                 string   `json:"url"`
+	MaxDailyWaterVolume *float64 `json:"max_daily_water_volume"`
+	MinDailyEfficiency  *float64 `json:"min_daily_efficiency"`
+	NoDataHours         *int     `json:"no_data_hours"`
+}
+
+// CreateWebhook handles POST /v1/farms/{farm_id}/webhooks
+// Body fields (at least one threshold is required):
+//   - url (required): the http or https URL alerts are POSTed to; its host must resolve to
+//     public addresses only
+//   - max_daily_water_volume (optional): alert when a day's water_volume exceeds it, in liters
+//   - min_daily_efficiency (optional): alert when yesterday's efficiency falls below it
+//   - no_data_hours (optional): alert when no event has arrived for that many hours, 1-720
+//
+// The response includes the signing secret, which is not shown again.
+func (c *WebhookController) CreateWebhook(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	var req webhookRequest
+	err := ctx.ShouldBindJSON(&req)
+	if writeBodyTooLarge(ctx, c.logger, farmID, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": "body must be a JSON webhook object",
+		})
+		return
+	}
+	if errMessage := req.validate(); errMessage != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid webhook",
+			"message": errMessage,
+		})
+		return
+	}
+
+	if !ensureFarmExists(ctx, c.logger, c.webhookService, farmID, startTime) {
+		return
+	}
+
+	webhook, err := c.webhookService.CreateWebhook(ctx.Request.Context(), farmID, service.WebhookInput{
+		URL:                 req.URL,
+		MaxDailyWaterVolume: req.MaxDailyWaterVolume,
+		MinDailyEfficiency:  req.MinDailyEfficiency,
+		NoDataHours:         req.NoDataHours,
+	})
+	if errors.Is(err, service.ErrInvalidWebhookTarget) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid webhook",
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to create webhook",
+			"farm_id", farmID,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to create webhook",
+		})
+		return
+	}
+
+	c.logger.Info("webhook created",
+		"farm_id", farmID,
+		"webhook_id", webhook.ID,
+		"latency_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	ctx.JSON(http.StatusCreated, webhook)
+}
+
+// validate checks the request, returning a message describing the first problem found
+func (r webhookRequest) validate() string {
+	target, err := url.Parse(r.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" || len(r.URL) > maxWebhookURLLength {
+		return fmt.Sprintf("url must be an http or https URL of at most %d characters", maxWebhookURLLength)
+	}
+	if r.MaxDailyWaterVolume == nil && r.MinDailyEfficiency == nil && r.NoDataHours == nil {
+		return "at least one of max_daily_water_volume, min_daily_efficiency or no_data_hours is required"
+	}
+	if v := r.MaxDailyWaterVolume; v != nil && !(*v > 0 && *v < maxWebhookVolume) {
+		return "max_daily_water_volume must be greater than 0 and less than 1000000000000"
+	}
+	if v := r.MinDailyEfficiency; v != nil && !(*v > 0 && *v < maxWebhookEfficiency) {
+		return fmt.Sprintf("min_daily_efficiency must be greater than 0 and less than %d", maxWebhookEfficiency)
+	}
+	if v := r.NoDataHours; v != nil && (*v < 1 || *v > maxWebhookNoDataHours) {
+		return fmt.Sprintf("no_data_hours must be between 1 and %d", maxWebhookNoDataHours)
+	}
+	return ""
+}
+
+// ListWebhooks handles GET /v1/farms/{farm_id}/webhooks
+func (c *WebhookController) ListWebhooks(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+
+	if !ensureFarmExists(ctx, c.logger, c.webhookService, farmID, startTime) {
+		return
+	}
+
+	webhooks, err := c.webhookService.ListWebhooks(farmID)
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to list webhooks",
+			"farm_id", farmID,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to list webhooks",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"farm_id":  farmID,
+		"webhooks": webhooks,
+	})
+}
+
+// DeleteWebhook handles DELETE /v1/farms/{farm_id}/webhooks/{webhook_id}
+// Queued alerts of the webhook are not sent.
+func (c *WebhookController) DeleteWebhook(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+	webhookID, ok := parseWebhookID(ctx)
+	if !ok {
+		return
+	}
+
+	deleted, err := c.webhookService.DeleteWebhook(farmID, webhookID)
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to delete webhook",
+			"farm_id", farmID,
+			"webhook_id", webhookID,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to delete webhook",
+		})
+		return
+	}
+	if !deleted {
+		writeWebhookNotFound(ctx, farmID, webhookID)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries handles GET /v1/farms/{farm_id}/webhooks/{webhook_id}/deliveries
+// Query parameters:
+//   - limit (optional): number of deliveries to return, newest first, 1-500 (default: 50)
+func (c *WebhookController) ListWebhookDeliveries(ctx *gin.Context) {
+	startTime := time.Now()
+	farmID, ok := parseFarmID(ctx, c.logger)
+	if !ok {
+		return
+	}
+	webhookID, ok := parseWebhookID(ctx)
+	if !ok {
+		return
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(service.DefaultWebhookDeliveryLimit)))
+	if err != nil || limit < 1 || limit > service.MaxWebhookDeliveryLimit {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid limit",
+			"message": fmt.Sprintf("limit must be an integer between 1 and %d", service.MaxWebhookDeliveryLimit),
+		})
+		return
+	}
+
+	deliveries, err := c.webhookService.ListDeliveries(farmID, webhookID, limit)
+	if errors.Is(err, service.ErrWebhookNotFound) {
+		writeWebhookNotFound(ctx, farmID, webhookID)
+		return
+	}
+	if err != nil {
+		latency := time.Since(startTime)
+		c.logger.Error("failed to list webhook deliveries",
+			"farm_id", farmID,
+			"webhook_id", webhookID,
+			"error", err.Error(),
+			"latency_ms", latency.Milliseconds(),
+		)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to list webhook deliveries",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"webhook_id": webhookID,
+		"deliveries": deliveries,
+	})
+}
+
+// parseWebhookID parses the webhook_id path parameter, writing a 400 response when it is invalid
+func parseWebhookID(ctx *gin.Context) (uint, bool) {
+	webhookID, err := strconv.ParseUint(ctx.Param("webhook_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid webhook_id",
+			"message": "webhook_id must be a valid unsigned integer",
+		})
+		return 0, false
+	}
+	return uint(webhookID), true
+}
+
+// writeWebhookNotFound writes the 404 response for a webhook the farm doesn't have
+func writeWebhookNotFound(ctx *gin.Context, farmID, webhookID uint) {
+	ctx.JSON(http.StatusNotFound, gin.H{
+		"error":   "Webhook not found",
+		"message": fmt.Sprintf("Webhook with ID %d does not exist for farm %d", webhookID, farmID),
+	})
+}
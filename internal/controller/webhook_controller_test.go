@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mockWebhookService is a mock implementation of WebhookService for testing
+type mockWebhookService struct{}
+
+func (m *mockWebhookService) FarmExists(farmID uint) (bool, error) {
+	return true, nil
+}
+
+func (m *mockWebhookService) CreateWebhook(ctx context.Context, farmID uint, input service.WebhookInput) (*service.CreatedWebhook, error) {
+	if strings.Contains(input.URL, "169.254.169.254") {
+		return nil, fmt.Errorf("%w: 169.254.169.254 is not a public address", service.ErrInvalidWebhookTarget)
+	}
+	return &service.CreatedWebhook{Webhook: model.Webhook{ID: 1, FarmID: farmID, URL: input.URL, Secret: "s3cret"}, Secret: "s3cret"}, nil
+}
+
+func (m *mockWebhookService) ListWebhooks(farmID uint) ([]model.Webhook, error) {
+	return []model.Webhook{}, nil
+}
+
+func (m *mockWebhookService) DeleteWebhook(farmID, webhookID uint) (bool, error) {
+	return webhookID == 1, nil
+}
+
+func (m *mockWebhookService) ListDeliveries(farmID, webhookID uint, limit int) ([]model.WebhookDelivery, error) {
+	if webhookID != 1 {
+		return nil, service.ErrWebhookNotFound
+	}
+	return []model.WebhookDelivery{}, nil
+}
+
+func TestWebhookController(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	controller := NewWebhookController(&mockWebhookService{}, slog.Default())
+	router := gin.New()
+	router.POST("/v1/farms/:farm_id/webhooks", controller.CreateWebhook)
+	router.DELETE("/v1/farms/:farm_id/webhooks/:webhook_id", controller.DeleteWebhook)
+	router.GET("/v1/farms/:farm_id/webhooks/:webhook_id/deliveries", controller.ListWebhookDeliveries)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		expectedCode int
+	}{
+		{"create", "POST", "/v1/farms/1/webhooks", `{"url":"https://example.com/hook","max_daily_water_volume":5000,"no_data_hours":6}`, http.StatusCreated},
+		{"internal target", "POST", "/v1/farms/1/webhooks", `{"url":"http://169.254.169.254/latest/meta-data","no_data_hours":6}`, http.StatusBadRequest},
+		{"no threshold", "POST", "/v1/farms/1/webhooks", `{"url":"https://example.com/hook"}`, http.StatusBadRequest},
+		{"not http", "POST", "/v1/farms/1/webhooks", `{"url":"ftp://example.com","no_data_hours":6}`, http.StatusBadRequest},
+		{"relative url", "POST", "/v1/farms/1/webhooks", `{"url":"/hook","no_data_hours":6}`, http.StatusBadRequest},
+		{"zero efficiency", "POST", "/v1/farms/1/webhooks", `{"url":"http://example.com","min_daily_efficiency":0}`, http.StatusBadRequest},
+		{"too many hours", "POST", "/v1/farms/1/webhooks", `{"url":"http://example.com","no_data_hours":721}`, http.StatusBadRequest},
+		{"malformed body", "POST", "/v1/farms/1/webhooks", `{"url":`, http.StatusBadRequest},
+		{"delete", "DELETE", "/v1/farms/1/webhooks/1", "", http.StatusNoContent},
+		{"delete unknown", "DELETE", "/v1/farms/1/webhooks/2", "", http.StatusNotFound},
+		{"deliveries", "GET", "/v1/farms/1/webhooks/1/deliveries?limit=10", "", http.StatusOK},
+		{"deliveries unknown", "GET", "/v1/farms/1/webhooks/2/deliveries", "", http.StatusNotFound},
+		{"deliveries limit", "GET", "/v1/farms/1/webhooks/1/deliveries?limit=0", "", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status code %d, got %d (%s)", tt.expectedCode, w.Code, w.Body.String())
+			}
+			if tt.name == "create" && strings.Count(w.Body.String(), "s3cret") != 1 {
+				t.Errorf("expected the secret once in the response, got %s", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Access log sinks
+const (
+	AccessLogSinkStdout = "stdout"
+	AccessLogSinkFile   = "file"
+	AccessLogSinkSyslog = "syslog"
+)
+
+// AccessLogConfig selects where and how StructuredLoggingMiddleware writes access logs.
+// An empty Sink keeps access logs in the application log.
+type AccessLogConfig struct {
+	// Sink is stdout, file or syslog
+	Sink string
+	// Format is json (default) or text
+	Format string
+	// Path is the file appended to by the file sink
+	Path string
+	// SyslogNetwork and SyslogAddress select a remote syslog server; both empty means the local daemon
+	SyslogNetwork string
+	SyslogAddress string
+	// SyslogTag defaults to irrigation-access
+	SyslogTag string
+}
+
+// nopCloser is returned for sinks the access logger doesn't own
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// NewAccessLogger builds the logger for StructuredLoggingMiddleware from cfg. With no sink
+// configured it returns appLogger. The returned Closer releases the file or syslog connection.
+func NewAccessLogger(cfg AccessLogConfig, appLogger *slog.Logger) (*slog.Logger, io.Closer, error) {
+	var w io.Writer
+	var closer io.Closer = nopCloser{}
+
+	switch cfg.Sink {
+	case "":
+		return appLogger, closer, nil
+	case AccessLogSinkStdout:
+		w = os.Stdout
+	case AccessLogSinkFile:
+		if cfg.Path == "" {
+			return nil, nil, fmt.Errorf("access log file sink requires a path")
+		}
+		file, err := os.OpenFile(cfg.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open access log: %w", err)
+		}
+		w, closer = file, file
+	case AccessLogSinkSyslog:
+		tag := cfg.SyslogTag
+		if tag == "" {
+			tag = "irrigation-access"
+		}
+		writer, err := dialSyslog(cfg.SyslogNetwork, cfg.SyslogAddress, tag)
+		if err != nil {
+			return nil, nil, fmt.Errorf("connect access log syslog: %w", err)
+		}
+		w, closer = writer, writer
+	default:
+		return nil, nil, fmt.Errorf("unknown access log sink %q", cfg.Sink)
+	}
+
+	var handler slog.Handler
+	switch cfg.Format {
+	case "", "json":
+		handler = slog.NewJSONHandler(w, nil)
+	case "text":
+		handler = slog.NewTextHandler(w, nil)
+	default:
+		closer.Close()
+		return nil, nil, fmt.Errorf("unknown access log format %q", cfg.Format)
+	}
+
+	return slog.New(handler).With("log_type", "access"), closer, nil
+}
@@ -0,0 +1,13 @@
+//go:build windows || plan9
+
+package middleware
+
+import (
+	"errors"
+	"io"
+)
+
+// dialSyslog reports that syslog is unavailable on this platform
+func dialSyslog(network, address, tag string) (io.WriteCloser, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
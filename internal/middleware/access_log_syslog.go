@@ -0,0 +1,13 @@
+//go:build !windows && !plan9
+
+package middleware
+
+import (
+	"io"
+	"log/syslog"
+)
+
+// dialSyslog connects to syslog with the info priority on the daemon facility
+func dialSyslog(network, address, tag string) (io.WriteCloser, error) {
+	return syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewAccessLogger_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	logger, closer, err := NewAccessLogger(AccessLogConfig{Sink: AccessLogSinkFile, Path: path}, slog.Default())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	logger.Info("request completed", "status_code", 200)
+	if err := closer.Close(); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read access log: %v", err)
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(string(contents))), &record); err != nil {
+		t.Fatalf("expected a JSON record, got %q", contents)
+	}
+	if record["log_type"] != "access" || record["msg"] != "request completed" {
+		t.Errorf("unexpected record: %v", record)
+	}
+}
+
+func TestNewAccessLogger_Config(t *testing.T) {
+	app := slog.Default()
+	if logger, _, err := NewAccessLogger(AccessLogConfig{}, app); err != nil || logger != app {
+		t.Errorf("expected the application logger without a sink, got %v, %v", logger, err)
+	}
+
+	invalid := []AccessLogConfig{
+		{Sink: "kafka"},
+		{Sink: AccessLogSinkFile},
+		{Sink: AccessLogSinkStdout, Format: "xml"},
+	}
+	for _, cfg := range invalid {
+		if _, _, err := NewAccessLogger(cfg, app); err == nil {
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+}
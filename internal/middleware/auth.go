@@ -0,0 +1,311 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeyHeader carries a static API key
+const apiKeyHeader = "X-API-Key"
+
+// principalKey is the gin context key holding the authenticated Principal
+const principalKey = "auth.principal"
+
+// jwtLeeway absorbs clock skew when checking exp and nbf
+const jwtLeeway = 30 * time.Second
+
+// DefaultFarmAgnosticRoutes are the routes without a :farm_id that principals limited to
+// specific farms may call, as "METHOD /route/pattern". They serve no farm data.
+var DefaultFarmAgnosticRoutes = []string{
+	"GET /v1/crops",
+	"GET /v1/version",
+}
+
+// Principal is an authenticated caller and the farms it may query
+type Principal struct {
+	Subject string
+	// AllFarms grants access to every farm; otherwise only FarmIDs are allowed
+	AllFarms bool
+	FarmIDs  []uint
+}
+
+// CanAccessFarm reports whether the principal may query the farm
+func (p Principal) CanAccessFarm(farmID uint) bool {
+	return p.AllFarms || slices.Contains(p.FarmIDs, farmID)
+}
+
+// AuthConfig configures AuthMiddleware. Either method may be left unset.
+type AuthConfig struct {
+	// APIKeys maps each static key to its principal
+	APIKeys map[string]Principal
+	// JWTSecret verifies HS256 bearer tokens; JWTs are rejected when empty
+	JWTSecret []byte
+	// JWTIssuer and JWTAudience, when set, must match the token's iss and aud claims
+	JWTIssuer   string
+	JWTAudience string
+	// FarmAgnosticRoutes lists the routes without a :farm_id open to principals limited to
+	// specific farms; nil uses DefaultFarmAgnosticRoutes
+	FarmAgnosticRoutes []string
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// ParseAPIKeys parses "key=farms" entries separated by semicolons, where farms is a
+// comma-separated list of farm IDs or * for every farm (e.g. "k1=1,2;ops-key=*").
+// The principal's subject in logs is APIKeySubject(key), which doesn't reveal the key.
+func ParseAPIKeys(spec string) (map[string]Principal, error) {
+	keys := make(map[string]Principal)
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, farms, ok := strings.Cut(entry, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid API key entry %q: expected key=farms", entry)
+		}
+
+		principal := Principal{Subject: APIKeySubject(key)}
+		for _, farm := range strings.Split(farms, ",") {
+			farm = strings.TrimSpace(farm)
+			if farm == "*" {
+				principal.AllFarms = true
+				continue
+			}
+			id, err := strconv.ParseUint(farm, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid farm ID %q for API key %s", farm, principal.Subject)
+			}
+			principal.FarmIDs = append(principal.FarmIDs, uint(id))
+		}
+		if !principal.AllFarms && len(principal.FarmIDs) == 0 {
+			return nil, fmt.Errorf("API key %s grants no farms", principal.Subject)
+		}
+		keys[key] = principal
+	}
+	return keys, nil
+}
+
+// APIKeySubject identifies an API key in logs by the first 12 hex characters of its SHA-256,
+// so operators can tell keys apart without the logs holding any part of a key
+func APIKeySubject(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "api-key:" + hex.EncodeToString(sum[:])[:12]
+}
+
+// AuthMiddleware authenticates requests with an X-API-Key header or an HS256 JWT in
+// "Authorization: Bearer <token>", responding 401 when neither is valid. On routes with a
+// :farm_id parameter it also responds 403 unless the principal may query that farm. Routes
+// without one may cover every farm, so principals limited to specific farms get 403 there too,
+// except on FarmAgnosticRoutes.
+// JWTs name their farms in a "farms" claim: an array of IDs, or "*" for every farm.
+func AuthMiddleware(cfg AuthConfig, logger *slog.Logger) gin.HandlerFunc {
+	if cfg.now == nil {
+		cfg.now = time.Now
+	}
+	if cfg.FarmAgnosticRoutes == nil {
+		cfg.FarmAgnosticRoutes = DefaultFarmAgnosticRoutes
+	}
+	return func(c *gin.Context) {
+		principal, err := cfg.authenticate(c.Request)
+		if err != nil {
+			logger.Warn("request not authenticated",
+				"method", c.Request.Method,
+				"path", c.Request.URL.Path,
+				"remote_addr", c.ClientIP(),
+				"reason", err.Error(),
+			)
+			c.Header("WWW-Authenticate", `Bearer realm="irrigation-analytics"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "Unauthorized",
+				"message": "a valid X-API-Key header or bearer token is required",
+			})
+			return
+		}
+
+		rawFarmID, scoped := c.Params.Get("farm_id")
+		if !scoped && !principal.AllFarms && !slices.Contains(cfg.FarmAgnosticRoutes, c.Request.Method+" "+c.FullPath()) {
+			logger.Warn("cross-farm access denied",
+				"subject", principal.Subject,
+				"path", c.Request.URL.Path,
+			)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "Forbidden",
+				"message": "this endpoint requires access to all farms",
+			})
+			return
+		}
+
+		// A malformed farm_id is left for the handler to reject with 400
+		if farmID, err := strconv.ParseUint(rawFarmID, 10, 32); scoped && err == nil && !principal.CanAccessFarm(uint(farmID)) {
+			logger.Warn("farm access denied",
+				"subject", principal.Subject,
+				"farm_id", farmID,
+				"path", c.Request.URL.Path,
+			)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "Forbidden",
+				"message": fmt.Sprintf("not authorized for farm %d", farmID),
+			})
+			return
+		}
+
+		c.Set(principalKey, principal)
+		c.Next()
+	}
+}
+
+// RequireAllFarms rejects principals limited to specific farms with 403. AuthMiddleware already
+// does so on routes without a :farm_id; use it to also guard a farm-scoped route that acts
+// beyond its farm.
+func RequireAllFarms() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := PrincipalFrom(c)
+		if !ok || !principal.AllFarms {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "Forbidden",
+				"message": "this endpoint requires access to all farms",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// PrincipalFrom returns the principal set by AuthMiddleware
+func PrincipalFrom(c *gin.Context) (Principal, bool) {
+	value, ok := c.Get(principalKey)
+	if !ok {
+		return Principal{}, false
+	}
+	principal, ok := value.(Principal)
+	return principal, ok
+}
+
+// authenticate resolves the request's credentials to a principal
+func (cfg AuthConfig) authenticate(r *http.Request) (Principal, error) {
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		// Compare against every key, without stopping at a match, so timing reveals nothing
+		var found Principal
+		matched := false
+		for candidate, principal := range cfg.APIKeys {
+			if subtle.ConstantTimeCompare([]byte(candidate), []byte(key)) == 1 {
+				found, matched = principal, true
+			}
+		}
+		if !matched {
+			return Principal{}, errors.New("unknown API key")
+		}
+		return found, nil
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return Principal{}, errors.New("no credentials")
+	}
+	return cfg.verifyJWT(strings.TrimSpace(token))
+}
+
+// jwtClaims are the registered and custom claims read from a token
+type jwtClaims struct {
+	Subject   string          `json:"sub"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *float64        `json:"exp"`
+	NotBefore *float64        `json:"nbf"`
+	Farms     json.RawMessage `json:"farms"`
+}
+
+// verifyJWT checks an HS256 token's signature and claims and returns its principal
+func (cfg AuthConfig) verifyJWT(token string) (Principal, error) {
+	if len(cfg.JWTSecret) == 0 {
+		return Principal{}, errors.New("bearer tokens are not enabled")
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, errors.New("malformed token")
+	}
+
+	var header struct {
+		Algorithm string `json:"alg"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil || header.Algorithm != "HS256" {
+		return Principal{}, errors.New("unsupported token algorithm")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, errors.New("malformed token signature")
+	}
+	mac := hmac.New(sha256.New, cfg.JWTSecret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return Principal{}, errors.New("invalid token signature")
+	}
+
+	var claims jwtClaims
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return Principal{}, errors.New("malformed token claims")
+	}
+
+	now := cfg.now()
+	if claims.ExpiresAt == nil || now.After(time.Unix(int64(*claims.ExpiresAt), 0).Add(jwtLeeway)) {
+		return Principal{}, errors.New("token expired or without exp")
+	}
+	if claims.NotBefore != nil && now.Add(jwtLeeway).Before(time.Unix(int64(*claims.NotBefore), 0)) {
+		return Principal{}, errors.New("token not yet valid")
+	}
+	if cfg.JWTIssuer != "" && claims.Issuer != cfg.JWTIssuer {
+		return Principal{}, errors.New("unexpected token issuer")
+	}
+	if cfg.JWTAudience != "" && !audienceContains(claims.Audience, cfg.JWTAudience) {
+		return Principal{}, errors.New("unexpected token audience")
+	}
+
+	principal := Principal{Subject: claims.Subject}
+	var all string
+	if err := json.Unmarshal(claims.Farms, &all); err == nil && all == "*" {
+		principal.AllFarms = true
+	} else if err := json.Unmarshal(claims.Farms, &principal.FarmIDs); err != nil || len(principal.FarmIDs) == 0 {
+		return Principal{}, errors.New("token grants no farms")
+	}
+	return principal, nil
+}
+
+// decodeJWTPart decodes one base64url segment of a token as JSON
+func decodeJWTPart(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// audienceContains reports whether an aud claim, a string or an array of strings, includes audience
+func audienceContains(raw json.RawMessage, audience string) bool {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return single == audience
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return slices.Contains(list, audience)
+	}
+	return false
+}
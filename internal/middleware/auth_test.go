@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// signJWT builds an HS256 token with the given claims JSON
+func signJWT(secret, claims string) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(claims))
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(header + "." + payload))
+	return header + "." + payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestParseAPIKeys(t *testing.T) {
+	keys, err := ParseAPIKeys("farm-key=1, 2; ops-key=*")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !keys["ops-key"].AllFarms || !keys["farm-key"].CanAccessFarm(2) || keys["farm-key"].CanAccessFarm(3) {
+		t.Errorf("unexpected keys %+v", keys)
+	}
+	// Subjects identify keys in logs without revealing them
+	if subject := keys["farm-key"].Subject; subject != APIKeySubject("farm-key") || len(subject) != len("api-key:")+12 || strings.Contains(subject, "farm") {
+		t.Errorf("unexpected subject %q", subject)
+	}
+
+	for _, invalid := range []string{"no-farms=", "key=abc", "=1"} {
+		if _, err := ParseAPIKeys(invalid); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Unix(1750000000, 0)
+	keys, _ := ParseAPIKeys("farm-key=1;ops-key=*")
+	cfg := AuthConfig{APIKeys: keys, JWTSecret: []byte("secret"), JWTAudience: "irrigation", now: func() time.Time { return now }}
+
+	r := gin.New()
+	v1 := r.Group("/v1", AuthMiddleware(cfg, slog.Default()))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	v1.GET("/farms/:farm_id/irrigation/analytics", ok)
+	v1.GET("/ingestion/status", ok)
+	v1.GET("/crops", ok)
+	v1.GET("/version", ok)
+
+	valid := signJWT("secret", `{"sub":"grower-7","aud":["irrigation"],"exp":1750003600,"farms":[2,3]}`)
+	expired := signJWT("secret", `{"sub":"grower-7","aud":"irrigation","exp":1749990000,"farms":[2]}`)
+	forged := signJWT("other", `{"sub":"grower-7","aud":"irrigation","exp":1750003600,"farms":"*"}`)
+	wrongAudience := signJWT("secret", `{"sub":"grower-7","aud":"billing","exp":1750003600,"farms":"*"}`)
+
+	tests := []struct {
+		name         string
+		path         string
+		header       string
+		value        string
+		expectedCode int
+	}{
+		{"no credentials", "/v1/farms/1/irrigation/analytics", "", "", http.StatusUnauthorized},
+		{"API key for its farm", "/v1/farms/1/irrigation/analytics", "X-API-Key", "farm-key", http.StatusOK},
+		{"API key for another farm", "/v1/farms/2/irrigation/analytics", "X-API-Key", "farm-key", http.StatusForbidden},
+		{"unknown API key", "/v1/farms/1/irrigation/analytics", "X-API-Key", "guess", http.StatusUnauthorized},
+		{"JWT for its farm", "/v1/farms/3/irrigation/analytics", "Authorization", "Bearer " + valid, http.StatusOK},
+		{"JWT for another farm", "/v1/farms/1/irrigation/analytics", "Authorization", "Bearer " + valid, http.StatusForbidden},
+		{"expired JWT", "/v1/farms/2/irrigation/analytics", "Authorization", "Bearer " + expired, http.StatusUnauthorized},
+		{"forged JWT", "/v1/farms/2/irrigation/analytics", "Authorization", "Bearer " + forged, http.StatusUnauthorized},
+		{"farm API key on a cross-farm route", "/v1/ingestion/status", "X-API-Key", "farm-key", http.StatusForbidden},
+		{"farm JWT on a cross-farm route", "/v1/ingestion/status", "Authorization", "Bearer " + valid, http.StatusForbidden},
+		{"all-farms API key on a cross-farm route", "/v1/ingestion/status", "X-API-Key", "ops-key", http.StatusOK},
+		{"farm API key on a farm-agnostic route", "/v1/crops", "X-API-Key", "farm-key", http.StatusOK},
+		{"farm API key on the version route", "/v1/version", "X-API-Key", "farm-key", http.StatusOK},
+		{"JWT for another audience", "/v1/farms/2/irrigation/analytics", "Authorization", "Bearer " + wrongAudience, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("expected status %d, got %d", tt.expectedCode, w.Code)
+			}
+		})
+	}
+}
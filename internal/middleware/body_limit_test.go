@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.POST("/imports", BodyLimitMiddleware(32, slog.Default(), "application/json"), func(c *gin.Context) {
+		var body map[string]any
+		if err := c.ShouldBindJSON(&body); err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				AbortBodyTooLarge(c, maxErr.Limit)
+				return
+			}
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	large := `{"events":"` + strings.Repeat("x", 64) + `"}`
+	tests := []struct {
+		name          string
+		body          string
+		contentType   string
+		contentLength int64
+		expectedCode  int
+	}{
+		{"within limit", `{"events":[]}`, "application/json; charset=utf-8", 13, http.StatusOK},
+		{"declared length over limit", large, "application/json", int64(len(large)), http.StatusRequestEntityTooLarge},
+		{"chunked body over limit", large, "application/json", -1, http.StatusRequestEntityTooLarge},
+		{"wrong content type", `{"events":[]}`, "text/csv", 13, http.StatusUnsupportedMediaType},
+		{"missing content type", `{"events":[]}`, "", 13, http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/imports", strings.NewReader(tt.body))
+			req.ContentLength = tt.contentLength
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
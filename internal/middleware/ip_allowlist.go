@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ParseCIDRs parses a comma-separated list of CIDR ranges or single IPs
+// (e.g. "10.0.0.0/8, 192.168.1.20"); single IPs match only themselves
+func ParseCIDRs(list string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q", entry)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", entry, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// IPAllowlistMiddleware rejects requests whose client IP is outside every allowed network with 403.
+// An empty allowlist rejects everything, so a missing configuration fails closed.
+// The address checked is the TCP peer's, never X-Forwarded-For or X-Real-IP: gin trusts those
+// headers from every peer unless trusted proxies are set, so any client could claim an allowed
+// address. Admin routes are served on the application port, which Nginx does not proxy.
+func IPAllowlistMiddleware(allowed []*net.IPNet, logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientIP := c.RemoteIP()
+		ip := net.ParseIP(clientIP)
+		if ip != nil {
+			for _, network := range allowed {
+				if network.Contains(ip) {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		logger.Warn("request blocked by IP allowlist",
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"remote_addr", clientIP,
+		)
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "client address is not allowed to access this endpoint",
+		})
+	}
+}
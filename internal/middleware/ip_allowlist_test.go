@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseCIDRs(t *testing.T) {
+	networks, err := ParseCIDRs("10.0.0.0/8, 192.168.1.20,,fd00::/8")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(networks) != 3 {
+		t.Fatalf("expected 3 networks, got %d", len(networks))
+	}
+
+	for _, invalid := range []string{"10.0.0.0/33", "not-an-ip"} {
+		if _, err := ParseCIDRs(invalid); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestIPAllowlistMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	allowed, _ := ParseCIDRs("10.0.0.0/8,192.168.1.20")
+
+	r := gin.New()
+	admin := r.Group("/admin", IPAllowlistMiddleware(allowed, slog.Default()))
+	admin.GET("/cache", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		remoteAddr   string
+		forwarded    string
+		expectedCode int
+	}{
+		{"10.1.2.3:41000", "", http.StatusOK},
+		{"192.168.1.20:41000", "", http.StatusOK},
+		{"192.168.1.21:41000", "", http.StatusForbidden},
+		{"203.0.113.9:41000", "", http.StatusForbidden},
+		// Spoofed forwarding headers from an outside peer are ignored
+		{"203.0.113.9:41000", "10.1.2.3", http.StatusForbidden},
+		{"203.0.113.9:41000", "127.0.0.1, 10.1.2.3", http.StatusForbidden},
+		// and so are forwarding headers naming an outside client behind an allowed peer
+		{"10.1.2.3:41000", "203.0.113.9", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", "/admin/cache", nil)
+		req.RemoteAddr = tt.remoteAddr
+		if tt.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", tt.forwarded)
+			req.Header.Set("X-Real-IP", tt.forwarded)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.expectedCode {
+			t.Errorf("%s (X-Forwarded-For %q): expected status %d, got %d", tt.remoteAddr, tt.forwarded, tt.expectedCode, w.Code)
+		}
+	}
+
+	// An empty allowlist fails closed
+	r = gin.New()
+	r.GET("/admin/cache", IPAllowlistMiddleware(nil, slog.Default()), func(c *gin.Context) { c.Status(http.StatusOK) })
+	req, _ := http.NewRequest("GET", "/admin/cache", nil)
+	req.RemoteAddr = "10.1.2.3:41000"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status %d with an empty allowlist, got %d", http.StatusForbidden, w.Code)
+	}
+}
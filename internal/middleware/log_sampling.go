@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// SampledMessages are the high-volume messages sampled by default: the per-request start and
+// completion logs written by StructuredLoggingMiddleware
+var SampledMessages = []string{"request started", "request completed"}
+
+// SamplingHandler passes 1 in every N records for each sampled message and all other records.
+// Records at Warn or above are never sampled. Kept records carry a sample_rate attribute so
+// log queries can scale counts back up.
+type SamplingHandler struct {
+	next     slog.Handler
+	every    uint64
+	messages map[string]bool
+	counters *sync.Map // message -> *atomic.Uint64, shared by derived handlers
+}
+
+// NewSamplingHandler wraps next so the given messages are kept once every `every` records.
+// every <= 1 disables sampling.
+func NewSamplingHandler(next slog.Handler, every int, messages ...string) *SamplingHandler {
+	if every < 1 {
+		every = 1
+	}
+	sampled := make(map[string]bool, len(messages))
+	for _, message := range messages {
+		sampled[message] = true
+	}
+	return &SamplingHandler{
+		next:     next,
+		every:    uint64(every),
+		messages: sampled,
+		counters: &sync.Map{},
+	}
+}
+
+// Enabled defers to the wrapped handler, so the level stays controlled by its options
+func (h *SamplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle drops sampled-out records and forwards the rest
+func (h *SamplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.every > 1 && r.Level < slog.LevelWarn && h.messages[r.Message] {
+		counter, _ := h.counters.LoadOrStore(r.Message, &atomic.Uint64{})
+		if (counter.(*atomic.Uint64).Add(1)-1)%h.every != 0 {
+			return nil
+		}
+		r = r.Clone()
+		r.AddAttrs(slog.Uint64("sample_rate", h.every))
+	}
+	return h.next.Handle(ctx, r)
+}
+
+// WithAttrs returns a sampling handler over next.WithAttrs, sharing the sample counters
+func (h *SamplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	derived := *h
+	derived.next = h.next.WithAttrs(attrs)
+	return &derived
+}
+
+// WithGroup returns a sampling handler over next.WithGroup, sharing the sample counters
+func (h *SamplingHandler) WithGroup(name string) slog.Handler {
+	derived := *h
+	derived.next = h.next.WithGroup(name)
+	return &derived
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSamplingHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewSamplingHandler(slog.NewJSONHandler(&buf, nil), 10, SampledMessages...))
+
+	for i := 0; i < 25; i++ {
+		logger.Info("request completed", "status_code", 200)
+	}
+	logger.Info("farm cache flushed")
+	logger.With("component", "api").Warn("request completed", "status_code", 503)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	// Records 1, 11 and 21 of the sampled message, the unsampled message and the warning
+	if len(lines) != 5 {
+		t.Fatalf("expected 5 log lines, got %d:\n%s", len(lines), buf.String())
+	}
+
+	var first map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("failed to parse log line: %v", err)
+	}
+	if first["sample_rate"] != float64(10) {
+		t.Errorf("expected sample_rate 10 on sampled records, got %v", first["sample_rate"])
+	}
+	if strings.Contains(lines[3], "sample_rate") || strings.Contains(lines[4], "sample_rate") {
+		t.Error("expected unsampled records without sample_rate")
+	}
+}
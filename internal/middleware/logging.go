@@ -2,43 +2,13 @@ package middleware
 
 import (
 	"log/slog"
-	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// RequestMetrics holds in-memory request metrics
-type RequestMetrics struct {
-	mu            sync.RWMutex
-	TotalRequests uint64
-	RequestsByEndpoint map[string]uint64
-}
-
-var metrics = &RequestMetrics{
-	RequestsByEndpoint: make(map[string]uint64),
-}
-
-// GetMetrics returns the current request metrics
-func GetMetrics() RequestMetrics {
-	metrics.mu.RLock()
-	defer metrics.mu.RUnlock()
-	return RequestMetrics{
-		TotalRequests:      metrics.TotalRequests,
-		RequestsByEndpoint: copyMap(metrics.RequestsByEndpoint),
-	}
-}
-
-// copyMap creates a copy of the map
-func copyMap(src map[string]uint64) map[string]uint64 {
-	dst := make(map[string]uint64, len(src))
-	for k, v := range src {
-		dst[k] = v
-	}
-	return dst
-}
-
-// StructuredLoggingMiddleware provides structured logging with request latency and query parameters
+// StructuredLoggingMiddleware provides structured logging with request latency and query parameters.
+// Pass the logger from NewAccessLogger to write access logs to their own sink.
 func StructuredLoggingMiddleware(logger *slog.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
@@ -61,12 +31,16 @@ func StructuredLoggingMiddleware(logger *slog.Logger) gin.HandlerFunc {
 		latency := time.Since(start)
 		statusCode := c.Writer.Status()
 
-		// Update metrics
-		metrics.mu.Lock()
-		metrics.TotalRequests++
-		endpoint := method + " " + path
-		metrics.RequestsByEndpoint[endpoint]++
-		metrics.mu.Unlock()
+		// Update rolling-window metrics, keyed by route template and a normalized
+		// method to keep cardinality bounded
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		endpoint := metricsMethod(method) + " " + route
+		metrics.Record(endpoint, statusCode, latency)
+		sloTracker.Record(endpoint, statusCode, latency)
+		prometheusMetrics.RecordRequest(method, route, statusCode, latency)
 
 		// Log request completion
 		logger.Info("request completed",
@@ -91,4 +65,3 @@ func StructuredLoggingMiddleware(logger *slog.Logger) gin.HandlerFunc {
 		}
 	}
 }
-
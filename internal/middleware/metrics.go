@@ -1,17 +1,222 @@
 package middleware
 
 import (
+	"math"
 	"net/http"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// MetricsHandler returns current request metrics
+const (
+	// slotWidth is the resolution of the rolling windows
+	slotWidth = 10 * time.Second
+	// slotCount covers the longest window (1h) at slotWidth resolution
+	slotCount = int(time.Hour / slotWidth)
+)
+
+// latencyBounds are the upper bounds of the latency histogram buckets used for percentiles
+var latencyBounds = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// metricsWindows are the rolling windows exposed by the metrics handler
+var metricsWindows = []struct {
+	name     string
+	duration time.Duration
+}{
+	{"1m", time.Minute},
+	{"5m", 5 * time.Minute},
+	{"1h", time.Hour},
+}
+
+// metricsSlot holds the requests observed during one slotWidth interval
+type metricsSlot struct {
+	index      int64 // unix time / slotWidth, identifies which interval the slot holds
+	requests   uint64
+	errors     uint64
+	latencySum time.Duration
+	histogram  []uint64 // len(latencyBounds)+1, last bucket is overflow
+}
+
+// endpointWindow is a ring of slots for a single endpoint
+type endpointWindow struct {
+	slots [slotCount]metricsSlot
+}
+
+// RequestMetrics holds rolling-window request metrics per endpoint
+type RequestMetrics struct {
+	mu        sync.Mutex
+	endpoints map[string]*endpointWindow
+	now       func() time.Time
+}
+
+// WindowStats contains request statistics for one endpoint over one window
+type WindowStats struct {
+	Requests     uint64  `json:"requests"`
+	Errors       uint64  `json:"errors"`
+	ErrorRate    float64 `json:"error_rate"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	P50LatencyMs float64 `json:"p50_latency_ms"`
+	P95LatencyMs float64 `json:"p95_latency_ms"`
+	P99LatencyMs float64 `json:"p99_latency_ms"`
+}
+
+// MetricsSnapshot maps window name (1m, 5m, 1h) to per-endpoint statistics
+type MetricsSnapshot map[string]map[string]WindowStats
+
+// NewRequestMetrics creates an empty rolling-window metrics store
+func NewRequestMetrics() *RequestMetrics {
+	return &RequestMetrics{
+		endpoints: make(map[string]*endpointWindow),
+		now:       time.Now,
+	}
+}
+
+var metrics = NewRequestMetrics()
+
+// GetMetrics returns the current rolling-window request metrics
+func GetMetrics() MetricsSnapshot {
+	return metrics.Snapshot()
+}
+
+// metricsMethod returns method when it is a standard HTTP method and "OTHER"
+// otherwise. Clients choose the method freely, so recording it verbatim would
+// let them create an unbounded number of metric series.
+func metricsMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	default:
+		return "OTHER"
+	}
+}
+
+// Record adds a completed request to the rolling windows.
+// Responses with a 5xx status code count as errors.
+func (m *RequestMetrics) Record(endpoint string, statusCode int, latency time.Duration) {
+	index := m.now().UnixNano() / int64(slotWidth)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	window, ok := m.endpoints[endpoint]
+	if !ok {
+		window = &endpointWindow{}
+		m.endpoints[endpoint] = window
+	}
+
+	slot := &window.slots[index%int64(slotCount)]
+	if slot.index != index || slot.histogram == nil {
+		// Slot holds an interval that has rolled out of the longest window, reuse it
+		*slot = metricsSlot{index: index, histogram: make([]uint64, len(latencyBounds)+1)}
+	}
+
+	slot.requests++
+	if statusCode >= http.StatusInternalServerError {
+		slot.errors++
+	}
+	slot.latencySum += latency
+	slot.histogram[latencyBucket(latency)]++
+}
+
+// Snapshot computes statistics for every endpoint over each rolling window
+func (m *RequestMetrics) Snapshot() MetricsSnapshot {
+	current := m.now().UnixNano() / int64(slotWidth)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make(MetricsSnapshot, len(metricsWindows))
+	for _, w := range metricsWindows {
+		oldest := current - int64(w.duration/slotWidth) + 1
+		byEndpoint := make(map[string]WindowStats, len(m.endpoints))
+
+		for endpoint, window := range m.endpoints {
+			var requests, errors uint64
+			var latencySum time.Duration
+			histogram := make([]uint64, len(latencyBounds)+1)
+
+			for i := range window.slots {
+				slot := &window.slots[i]
+				if slot.histogram == nil || slot.index < oldest || slot.index > current {
+					continue
+				}
+				requests += slot.requests
+				errors += slot.errors
+				latencySum += slot.latencySum
+				for b, count := range slot.histogram {
+					histogram[b] += count
+				}
+			}
+
+			if requests == 0 {
+				continue
+			}
+
+			byEndpoint[endpoint] = WindowStats{
+				Requests:     requests,
+				Errors:       errors,
+				ErrorRate:    math.Round(float64(errors)/float64(requests)*10000) / 10000,
+				AvgLatencyMs: durationMs(latencySum / time.Duration(requests)),
+				P50LatencyMs: durationMs(histogramPercentile(histogram, requests, 0.50)),
+				P95LatencyMs: durationMs(histogramPercentile(histogram, requests, 0.95)),
+				P99LatencyMs: durationMs(histogramPercentile(histogram, requests, 0.99)),
+			}
+		}
+
+		snapshot[w.name] = byEndpoint
+	}
+
+	return snapshot
+}
+
+// latencyBucket returns the histogram bucket index for a latency
+func latencyBucket(latency time.Duration) int {
+	return sort.Search(len(latencyBounds), func(i int) bool {
+		return latency <= latencyBounds[i]
+	})
+}
+
+// histogramPercentile returns the upper bound of the bucket containing the q-th percentile.
+// Requests in the overflow bucket are reported at the largest bound.
+func histogramPercentile(histogram []uint64, total uint64, q float64) time.Duration {
+	rank := uint64(math.Ceil(q * float64(total)))
+	var cumulative uint64
+	for i, count := range histogram {
+		cumulative += count
+		if cumulative >= rank {
+			if i >= len(latencyBounds) {
+				break
+			}
+			return latencyBounds[i]
+		}
+	}
+	return latencyBounds[len(latencyBounds)-1]
+}
+
+// durationMs converts a duration to fractional milliseconds rounded to 2 decimal places
+func durationMs(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Millisecond)*100) / 100
+}
+
+// MetricsHandler returns rolling-window request metrics per endpoint
 func MetricsHandler(c *gin.Context) {
-	metrics := GetMetrics()
 	c.JSON(http.StatusOK, gin.H{
-		"total_requests":       metrics.TotalRequests,
-		"requests_by_endpoint": metrics.RequestsByEndpoint,
+		"window_resolution_seconds": int(slotWidth / time.Second),
+		"windows":                   GetMetrics(),
 	})
 }
-
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestMetrics_RollingWindows(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	m := NewRequestMetrics()
+	m.now = func() time.Time { return now }
+
+	// 30 minutes ago: only visible in the 1h window
+	now = now.Add(-30 * time.Minute)
+	m.Record("GET /v1/farms/:farm_id/irrigation/analytics", http.StatusOK, 20*time.Millisecond)
+
+	// 3 minutes ago: visible in 5m and 1h windows
+	now = now.Add(27 * time.Minute)
+	m.Record("GET /v1/farms/:farm_id/irrigation/analytics", http.StatusInternalServerError, 400*time.Millisecond)
+
+	// Now: visible in every window
+	now = now.Add(3 * time.Minute)
+	m.Record("GET /v1/farms/:farm_id/irrigation/analytics", http.StatusOK, 8*time.Millisecond)
+	m.Record("GET /v1/farms/:farm_id/irrigation/analytics", http.StatusBadRequest, 3*time.Millisecond)
+
+	snapshot := m.Snapshot()
+	endpoint := "GET /v1/farms/:farm_id/irrigation/analytics"
+
+	tests := []struct {
+		window   string
+		requests uint64
+		errors   uint64
+	}{
+		{"1m", 2, 0},
+		{"5m", 3, 1},
+		{"1h", 4, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.window, func(t *testing.T) {
+			stats, ok := snapshot[tt.window][endpoint]
+			if !ok {
+				t.Fatalf("expected stats for %s in %s window", endpoint, tt.window)
+			}
+			if stats.Requests != tt.requests {
+				t.Errorf("expected %d requests, got %d", tt.requests, stats.Requests)
+			}
+			if stats.Errors != tt.errors {
+				t.Errorf("expected %d errors, got %d", tt.errors, stats.Errors)
+			}
+		})
+	}
+
+	if rate := snapshot["5m"][endpoint].ErrorRate; rate != 0.3333 {
+		t.Errorf("expected 5m error rate 0.3333, got %f", rate)
+	}
+	if p99 := snapshot["5m"][endpoint].P99LatencyMs; p99 != 500 {
+		t.Errorf("expected 5m p99 latency 500ms bucket, got %f", p99)
+	}
+	if p50 := snapshot["1m"][endpoint].P50LatencyMs; p50 != 5 {
+		t.Errorf("expected 1m p50 latency 5ms bucket, got %f", p50)
+	}
+}
+
+func TestRequestMetrics_ExpiredSlotsAreReused(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	m := NewRequestMetrics()
+	m.now = func() time.Time { return now }
+
+	m.Record("GET /health", http.StatusOK, time.Millisecond)
+
+	// Exactly one ring length later the same slot is reused for the new interval
+	now = now.Add(time.Hour)
+	m.Record("GET /health", http.StatusOK, time.Millisecond)
+
+	if got := m.Snapshot()["1h"]["GET /health"].Requests; got != 1 {
+		t.Errorf("expected expired slot to be reset, got %d requests", got)
+	}
+}
+
+func TestStructuredLoggingMiddleware_NormalizesMethods(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(StructuredLoggingMiddleware(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	router.GET("/metrics-method", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, method := range []string{"FOO1", "FOO2", "BREW", http.MethodGet} {
+		req := httptest.NewRequest(method, "/metrics-method", nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	snapshot := GetMetrics()["1m"]
+	if got := snapshot["OTHER unmatched"].Requests; got != 3 {
+		t.Errorf("expected 3 requests under OTHER unmatched, got %d", got)
+	}
+	if got := snapshot["GET /metrics-method"].Requests; got != 1 {
+		t.Errorf("expected 1 request under GET /metrics-method, got %d", got)
+	}
+	for _, method := range []string{"FOO1", "FOO2", "BREW"} {
+		if _, ok := snapshot[method+" unmatched"]; ok {
+			t.Errorf("expected no metrics key for method %s", method)
+		}
+	}
+}
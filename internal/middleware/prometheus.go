@@ -0,0 +1,251 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// prometheusContentType is the content type of the Prometheus text exposition format
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// histogram is a cumulative Prometheus histogram over latencyBounds
+type histogram struct {
+	buckets []uint64 // len(latencyBounds)+1, last bucket is +Inf
+	sum     time.Duration
+	count   uint64
+}
+
+// observe adds one duration to the histogram
+func (h *histogram) observe(d time.Duration) {
+	if h.buckets == nil {
+		h.buckets = make([]uint64, len(latencyBounds)+1)
+	}
+	h.buckets[latencyBucket(d)]++
+	h.sum += d
+	h.count++
+}
+
+// requestKey identifies a request counter series
+type requestKey struct {
+	method string
+	route  string
+	status int
+}
+
+// PrometheusMetrics holds cumulative counters and histograms for the Prometheus exporter.
+// Unlike RequestMetrics these never reset, as Prometheus computes rates from the totals.
+type PrometheusMetrics struct {
+	mu          sync.Mutex
+	requests    map[requestKey]uint64
+	latency     map[string]*histogram // by "METHOD route"
+	dbQueries   map[string]*histogram // by operation
+	computation map[string]*histogram // by operation
+}
+
+// Gauge is a point-in-time value supplied by a GaugeCollector at scrape time
+type Gauge struct {
+	Name   string
+	Help   string
+	Labels map[string]string
+	Value  float64
+}
+
+// GaugeCollector computes gauges when /metrics/prometheus is scraped
+type GaugeCollector func() ([]Gauge, error)
+
+// NewPrometheusMetrics creates an empty Prometheus metrics store
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return &PrometheusMetrics{
+		requests:    make(map[requestKey]uint64),
+		latency:     make(map[string]*histogram),
+		dbQueries:   make(map[string]*histogram),
+		computation: make(map[string]*histogram),
+	}
+}
+
+var prometheusMetrics = NewPrometheusMetrics()
+
+// ObserveDBQuery records the duration of one database statement
+func ObserveDBQuery(operation string, d time.Duration) {
+	prometheusMetrics.ObserveDBQuery(operation, d)
+}
+
+// ObserveComputation records the duration of one analytics computation
+func ObserveComputation(operation string, d time.Duration) {
+	prometheusMetrics.ObserveComputation(operation, d)
+}
+
+// RecordRequest counts a completed request and adds its latency to the route's histogram.
+// Non-standard methods are recorded as "OTHER" so clients cannot add label values.
+func (p *PrometheusMetrics) RecordRequest(method, route string, statusCode int, latency time.Duration) {
+	method = metricsMethod(method)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.requests[requestKey{method: method, route: route, status: statusCode}]++
+	observeInto(p.latency, method+" "+route, latency)
+}
+
+// ObserveDBQuery records the duration of one database statement
+func (p *PrometheusMetrics) ObserveDBQuery(operation string, d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	observeInto(p.dbQueries, operation, d)
+}
+
+// ObserveComputation records the duration of one analytics computation
+func (p *PrometheusMetrics) ObserveComputation(operation string, d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	observeInto(p.computation, operation, d)
+}
+
+// observeInto adds d to the histogram stored under key, creating it if needed
+func observeInto(histograms map[string]*histogram, key string, d time.Duration) {
+	h, ok := histograms[key]
+	if !ok {
+		h = &histogram{}
+		histograms[key] = h
+	}
+	h.observe(d)
+}
+
+// Write writes every metric in the Prometheus text exposition format
+func (p *PrometheusMetrics) Write(w io.Writer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP irrigation_http_requests_total Completed HTTP requests by route and status code.")
+	fmt.Fprintln(w, "# TYPE irrigation_http_requests_total counter")
+	keys := make([]requestKey, 0, len(p.requests))
+	for key := range p.requests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		return keys[i].status < keys[j].status
+	})
+	for _, key := range keys {
+		fmt.Fprintf(w, "irrigation_http_requests_total{method=%s,route=%s,status=%s} %d\n",
+			quoteLabel(key.method), quoteLabel(key.route), quoteLabel(strconv.Itoa(key.status)), p.requests[key])
+	}
+
+	writeHistograms(w, "irrigation_http_request_duration_seconds", "HTTP request latency by route.", p.latency, func(key string) string {
+		method, route, _ := strings.Cut(key, " ")
+		return "method=" + quoteLabel(method) + ",route=" + quoteLabel(route)
+	})
+	writeHistograms(w, "irrigation_db_query_duration_seconds", "Database statement duration by operation.", p.dbQueries, operationLabel)
+	writeHistograms(w, "irrigation_analytics_computation_seconds", "Analytics computation time by operation, including its database queries.", p.computation, operationLabel)
+}
+
+// operationLabel formats the label set of an operation-keyed histogram
+func operationLabel(key string) string {
+	return "operation=" + quoteLabel(key)
+}
+
+// writeHistograms writes one histogram family with the series sorted by key
+func writeHistograms(w io.Writer, name, help string, histograms map[string]*histogram, labels func(key string) string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+
+	keys := make([]string, 0, len(histograms))
+	for key := range histograms {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		h := histograms[key]
+		labelSet := labels(key)
+		var cumulative uint64
+		for i, bound := range latencyBounds {
+			cumulative += h.buckets[i]
+			fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", name, labelSet, formatFloat(bound.Seconds()), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labelSet, h.count)
+		fmt.Fprintf(w, "%s_sum{%s} %s\n", name, labelSet, formatFloat(h.sum.Seconds()))
+		fmt.Fprintf(w, "%s_count{%s} %d\n", name, labelSet, h.count)
+	}
+}
+
+// writeGauges writes gauges grouped by name, each family with its HELP and TYPE lines
+func writeGauges(w io.Writer, gauges []Gauge) {
+	sort.SliceStable(gauges, func(i, j int) bool { return gauges[i].Name < gauges[j].Name })
+	written := make(map[string]bool)
+	for _, gauge := range gauges {
+		if !written[gauge.Name] {
+			written[gauge.Name] = true
+			fmt.Fprintf(w, "# HELP %s %s\n", gauge.Name, gauge.Help)
+			fmt.Fprintf(w, "# TYPE %s gauge\n", gauge.Name)
+		}
+
+		names := make([]string, 0, len(gauge.Labels))
+		for name := range gauge.Labels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		pairs := make([]string, 0, len(names))
+		for _, name := range names {
+			pairs = append(pairs, name+"="+quoteLabel(gauge.Labels[name]))
+		}
+
+		if len(pairs) == 0 {
+			fmt.Fprintf(w, "%s %s\n", gauge.Name, formatFloat(gauge.Value))
+		} else {
+			fmt.Fprintf(w, "%s{%s} %s\n", gauge.Name, strings.Join(pairs, ","), formatFloat(gauge.Value))
+		}
+	}
+}
+
+// quoteLabel quotes a label value, escaping backslashes, quotes and newlines
+func quoteLabel(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+	return `"` + value + `"`
+}
+
+// formatFloat formats a sample value without exponent noise for typical magnitudes
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// PrometheusHandler serves the cumulative metrics in the Prometheus text format, followed by
+// the gauges of each collector. A failing collector is skipped so the scrape still succeeds;
+// irrigation_gauge_collector_errors counts the failures in that scrape.
+func PrometheusHandler(collectors ...GaugeCollector) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var gauges []Gauge
+		failed := 0
+		for _, collect := range collectors {
+			collected, err := collect()
+			if err != nil {
+				failed++
+				continue
+			}
+			gauges = append(gauges, collected...)
+		}
+		gauges = append(gauges, Gauge{
+			Name:  "irrigation_gauge_collector_errors",
+			Help:  "Gauge collectors that failed during this scrape.",
+			Value: float64(failed),
+		})
+
+		c.Status(http.StatusOK)
+		c.Header("Content-Type", prometheusContentType)
+		prometheusMetrics.Write(c.Writer)
+		writeGauges(c.Writer, gauges)
+	}
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPrometheusMetrics_Write(t *testing.T) {
+	p := NewPrometheusMetrics()
+	p.RecordRequest("GET", "/v1/farms/:farm_id/irrigation/analytics", http.StatusOK, 20*time.Millisecond)
+	p.RecordRequest("GET", "/v1/farms/:farm_id/irrigation/analytics", http.StatusOK, 3*time.Millisecond)
+	p.RecordRequest("GET", "/v1/farms/:farm_id/irrigation/analytics", http.StatusInternalServerError, 20*time.Second)
+	p.ObserveDBQuery("row", 7*time.Millisecond)
+	p.ObserveComputation("analytics", 30*time.Millisecond)
+
+	var out strings.Builder
+	p.Write(&out)
+	body := out.String()
+
+	expected := []string{
+		`irrigation_http_requests_total{method="GET",route="/v1/farms/:farm_id/irrigation/analytics",status="200"} 2`,
+		`irrigation_http_requests_total{method="GET",route="/v1/farms/:farm_id/irrigation/analytics",status="500"} 1`,
+		`irrigation_http_request_duration_seconds_bucket{method="GET",route="/v1/farms/:farm_id/irrigation/analytics",le="0.005"} 1`,
+		`irrigation_http_request_duration_seconds_bucket{method="GET",route="/v1/farms/:farm_id/irrigation/analytics",le="0.025"} 2`,
+		`irrigation_http_request_duration_seconds_bucket{method="GET",route="/v1/farms/:farm_id/irrigation/analytics",le="10"} 2`,
+		`irrigation_http_request_duration_seconds_bucket{method="GET",route="/v1/farms/:farm_id/irrigation/analytics",le="+Inf"} 3`,
+		`irrigation_http_request_duration_seconds_count{method="GET",route="/v1/farms/:farm_id/irrigation/analytics"} 3`,
+		`irrigation_db_query_duration_seconds_bucket{operation="row",le="0.01"} 1`,
+		`irrigation_analytics_computation_seconds_sum{operation="analytics"} 0.03`,
+		"# TYPE irrigation_db_query_duration_seconds histogram",
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected line %q in output:\n%s", line, body)
+		}
+	}
+}
+
+func TestPrometheusMetrics_NormalizesMethods(t *testing.T) {
+	p := NewPrometheusMetrics()
+	for _, method := range []string{"FOO1", "FOO2", "BREW"} {
+		p.RecordRequest(method, "unmatched", http.StatusNotFound, time.Millisecond)
+	}
+
+	if len(p.requests) != 1 || len(p.latency) != 1 {
+		t.Fatalf("expected one series per metric, got %d request and %d latency series", len(p.requests), len(p.latency))
+	}
+
+	var out strings.Builder
+	p.Write(&out)
+	line := `irrigation_http_requests_total{method="OTHER",route="unmatched",status="404"} 3`
+	if !strings.Contains(out.String(), line+"\n") {
+		t.Errorf("expected line %q in output:\n%s", line, out.String())
+	}
+}
+
+func TestPrometheusHandler_Gauges(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/metrics/prometheus", PrometheusHandler(
+		func() ([]Gauge, error) {
+			return []Gauge{
+				{Name: "irrigation_ingested_events", Help: "Events.", Labels: map[string]string{"data_source": "seed"}, Value: 4410},
+				{Name: "irrigation_ingested_events", Help: "Events.", Labels: map[string]string{"data_source": "api"}, Value: 410},
+			}, nil
+		},
+		func() ([]Gauge, error) { return nil, errors.New("connection refused") },
+	))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/metrics/prometheus", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != prometheusContentType {
+		t.Errorf("expected Prometheus content type, got %q", ct)
+	}
+	body := w.Body.String()
+	if strings.Count(body, "# TYPE irrigation_ingested_events gauge") != 1 {
+		t.Errorf("expected a single TYPE line for the gauge family:\n%s", body)
+	}
+	for _, line := range []string{
+		`irrigation_ingested_events{data_source="seed"} 4410`,
+		`irrigation_ingested_events{data_source="api"} 410`,
+		`irrigation_gauge_collector_errors 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected line %q in output:\n%s", line, body)
+		}
+	}
+}
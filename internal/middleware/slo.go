@@ -0,0 +1,363 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// sloSlotWidth is the resolution of the SLO windows
+	sloSlotWidth = time.Minute
+	// sloSlotCount covers the longest SLO window (6h) at sloSlotWidth resolution
+	sloSlotCount = int(6 * time.Hour / sloSlotWidth)
+)
+
+// Error budgets tracked for every SLO
+const (
+	// SLOBudgetAvailability is spent by requests answered with a 5xx status
+	SLOBudgetAvailability = "availability"
+	// SLOBudgetLatency is spent by requests slower than the latency target
+	SLOBudgetLatency = "latency"
+)
+
+// Alert severities, following the multiwindow burn-rate alerts of the Google SRE workbook
+const (
+	// SLOAlertPage is raised when the 1h and 5m burn rates both reach 14.4, which spends 2% of a
+	// 30-day budget in an hour
+	SLOAlertPage = "page"
+	// SLOAlertTicket is raised when the 6h and 30m burn rates both reach 6, which spends 5% of a
+	// 30-day budget in six hours
+	SLOAlertTicket = "ticket"
+)
+
+// sloWindows are the burn-rate windows reported for every SLO
+var sloWindows = []struct {
+	name     string
+	duration time.Duration
+}{
+	{"5m", 5 * time.Minute},
+	{"30m", 30 * time.Minute},
+	{"1h", time.Hour},
+	{"6h", 6 * time.Hour},
+}
+
+// sloAlertRules pair a long and a short window whose burn rates must both reach the threshold
+var sloAlertRules = []struct {
+	severity    string
+	long, short string
+	threshold   float64
+}{
+	{SLOAlertPage, "1h", "5m", 14.4},
+	{SLOAlertTicket, "6h", "30m", 6},
+}
+
+// SLO is the service level objective of one endpoint
+type SLO struct {
+	// Endpoint is "METHOD route", as the request metrics key it
+	Endpoint string
+	// LatencyTarget is the latency a request must not exceed to count as fast
+	LatencyTarget time.Duration
+	// LatencyObjective is the share of requests that must be fast, e.g. 0.99
+	LatencyObjective float64
+	// AvailabilityObjective is the share of requests that must not fail with a 5xx, e.g. 0.999
+	AvailabilityObjective float64
+}
+
+// DefaultSLOs are tracked when SLO_TARGETS is unset: the analytics endpoints answer within one
+// second for 99% of requests and without a 5xx for 99.9%
+var DefaultSLOs = []SLO{
+	{Endpoint: "GET /v1/farms/:farm_id/irrigation/analytics", LatencyTarget: time.Second, LatencyObjective: 0.99, AvailabilityObjective: 0.999},
+	{Endpoint: "GET /v1/farms/:farm_id/sectors/:sector_id/irrigation/analytics", LatencyTarget: time.Second, LatencyObjective: 0.99, AvailabilityObjective: 0.999},
+}
+
+// ParseSLOs parses "endpoint=latency,latency_objective,availability_objective" entries separated
+// by semicolons, where latency is a Go duration and the objectives are percentages (e.g.
+// "GET /v1/farms/:farm_id/irrigation/analytics=1s,99,99.9")
+func ParseSLOs(spec string) ([]SLO, error) {
+	var slos []SLO
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		i := strings.LastIndex(entry, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid SLO entry %q: expected endpoint=latency,latency_objective,availability_objective", entry)
+		}
+		endpoint := strings.TrimSpace(entry[:i])
+		if method, route, ok := strings.Cut(endpoint, " "); !ok || metricsMethod(method) != method || !strings.HasPrefix(route, "/") {
+			return nil, fmt.Errorf("invalid SLO endpoint %q: expected METHOD /route with a standard HTTP method", endpoint)
+		}
+		fields := strings.Split(entry[i+1:], ",")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid SLO entry %q: expected endpoint=latency,latency_objective,availability_objective", entry)
+		}
+
+		target, err := time.ParseDuration(strings.TrimSpace(fields[0]))
+		if err != nil || target <= 0 {
+			return nil, fmt.Errorf("invalid latency target %q for %s", fields[0], endpoint)
+		}
+		var objectives [2]float64
+		for i, field := range fields[1:] {
+			percent, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+			if err != nil || percent <= 0 || percent >= 100 {
+				return nil, fmt.Errorf("invalid objective %q for %s: expected a percentage between 0 and 100", field, endpoint)
+			}
+			objectives[i] = math.Round(percent*1e4) / 1e6
+		}
+		slos = append(slos, SLO{Endpoint: endpoint, LatencyTarget: target, LatencyObjective: objectives[0], AvailabilityObjective: objectives[1]})
+	}
+	return slos, nil
+}
+
+// sloSlot holds the requests of one endpoint observed during one sloSlotWidth interval
+type sloSlot struct {
+	index    int64 // unix time / sloSlotWidth, identifies which interval the slot holds
+	requests uint64
+	errors   uint64
+	slow     uint64
+}
+
+// sloEndpoint is a ring of slots for an endpoint with an SLO
+type sloEndpoint struct {
+	slo   SLO
+	slots [sloSlotCount]sloSlot
+}
+
+// SLOTracker counts the requests of the endpoints with an SLO and computes their burn rates
+type SLOTracker struct {
+	mu        sync.Mutex
+	order     []string // endpoints in configuration order
+	endpoints map[string]*sloEndpoint
+	now       func() time.Time
+}
+
+// SLOWindow reports one endpoint's requests over one window and how fast they spend each budget.
+// A burn rate of 1 spends the budget exactly by the end of the objective's period.
+type SLOWindow struct {
+	Requests             uint64  `json:"requests"`
+	Errors               uint64  `json:"errors"`
+	Slow                 uint64  `json:"slow"`
+	AvailabilityBurnRate float64 `json:"availability_burn_rate"`
+	LatencyBurnRate      float64 `json:"latency_burn_rate"`
+}
+
+// SLOAlert is a budget burning fast enough in both of a rule's windows
+type SLOAlert struct {
+	Budget   string  `json:"budget"`
+	Severity string  `json:"severity"`
+	BurnRate float64 `json:"burn_rate"`
+}
+
+// SLOStatus is the state of one endpoint's SLO
+type SLOStatus struct {
+	Endpoint              string  `json:"endpoint"`
+	LatencyTargetMs       float64 `json:"latency_target_ms"`
+	LatencyObjective      float64 `json:"latency_objective"`
+	AvailabilityObjective float64 `json:"availability_objective"`
+	// AvailabilityBudgetRemaining and LatencyBudgetRemaining are the shares of each budget left
+	// over the last 6h; they are negative once the budget is overspent
+	AvailabilityBudgetRemaining float64              `json:"availability_budget_remaining"`
+	LatencyBudgetRemaining      float64              `json:"latency_budget_remaining"`
+	Windows                     map[string]SLOWindow `json:"windows"`
+	Alerts                      []SLOAlert           `json:"alerts"`
+}
+
+// SLOReport lists the status of every tracked SLO
+type SLOReport struct {
+	GeneratedAt time.Time   `json:"generated_at"`
+	SLOs        []SLOStatus `json:"slos"`
+}
+
+// NewSLOTracker creates a tracker for the given SLOs
+func NewSLOTracker(slos []SLO) *SLOTracker {
+	t := &SLOTracker{now: time.Now}
+	t.Configure(slos)
+	return t
+}
+
+var sloTracker = NewSLOTracker(DefaultSLOs)
+
+// ConfigureSLOs replaces the tracked SLOs, for example with those parsed from SLO_TARGETS
+func ConfigureSLOs(slos []SLO) {
+	sloTracker.Configure(slos)
+}
+
+// Configure replaces the tracked SLOs. Counts of endpoints that keep an SLO are kept.
+func (t *SLOTracker) Configure(slos []SLO) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	endpoints := make(map[string]*sloEndpoint, len(slos))
+	order := make([]string, 0, len(slos))
+	for _, slo := range slos {
+		endpoint, ok := t.endpoints[slo.Endpoint]
+		if !ok {
+			endpoint = &sloEndpoint{}
+		}
+		endpoint.slo = slo
+		if _, duplicate := endpoints[slo.Endpoint]; !duplicate {
+			order = append(order, slo.Endpoint)
+		}
+		endpoints[slo.Endpoint] = endpoint
+	}
+	t.order = order
+	t.endpoints = endpoints
+}
+
+// Record adds a completed request of an endpoint with an SLO; other endpoints are ignored.
+// Responses with a 5xx status count as errors, and requests over the latency target as slow.
+func (t *SLOTracker) Record(endpoint string, statusCode int, latency time.Duration) {
+	index := t.now().UnixNano() / int64(sloSlotWidth)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tracked, ok := t.endpoints[endpoint]
+	if !ok {
+		return
+	}
+
+	slot := &tracked.slots[index%int64(sloSlotCount)]
+	if slot.index != index {
+		// Slot holds an interval that has rolled out of the longest window, reuse it
+		*slot = sloSlot{index: index}
+	}
+	slot.requests++
+	if statusCode >= http.StatusInternalServerError {
+		slot.errors++
+	}
+	if latency > tracked.slo.LatencyTarget {
+		slot.slow++
+	}
+}
+
+// Report computes every SLO's windows, remaining budgets and alerts
+func (t *SLOTracker) Report() SLOReport {
+	now := t.now()
+	current := now.UnixNano() / int64(sloSlotWidth)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	report := SLOReport{GeneratedAt: now.UTC(), SLOs: make([]SLOStatus, 0, len(t.order))}
+	for _, name := range t.order {
+		tracked := t.endpoints[name]
+		slo := tracked.slo
+		status := SLOStatus{
+			Endpoint:              slo.Endpoint,
+			LatencyTargetMs:       durationMs(slo.LatencyTarget),
+			LatencyObjective:      slo.LatencyObjective,
+			AvailabilityObjective: slo.AvailabilityObjective,
+			Windows:               make(map[string]SLOWindow, len(sloWindows)),
+			Alerts:                []SLOAlert{},
+		}
+		for _, w := range sloWindows {
+			oldest := current - int64(w.duration/sloSlotWidth) + 1
+			var window SLOWindow
+			for i := range tracked.slots {
+				slot := &tracked.slots[i]
+				if slot.requests == 0 || slot.index < oldest || slot.index > current {
+					continue
+				}
+				window.Requests += slot.requests
+				window.Errors += slot.errors
+				window.Slow += slot.slow
+			}
+			window.AvailabilityBurnRate = burnRate(window.Errors, window.Requests, slo.AvailabilityObjective)
+			window.LatencyBurnRate = burnRate(window.Slow, window.Requests, slo.LatencyObjective)
+			status.Windows[w.name] = window
+		}
+
+		longest := status.Windows[sloWindows[len(sloWindows)-1].name]
+		status.AvailabilityBudgetRemaining = math.Round((1-longest.AvailabilityBurnRate)*10000) / 10000
+		status.LatencyBudgetRemaining = math.Round((1-longest.LatencyBurnRate)*10000) / 10000
+		for _, budget := range []string{SLOBudgetAvailability, SLOBudgetLatency} {
+			for _, rule := range sloAlertRules {
+				long, short := status.Windows[rule.long].burnRate(budget), status.Windows[rule.short].burnRate(budget)
+				if long >= rule.threshold && short >= rule.threshold {
+					status.Alerts = append(status.Alerts, SLOAlert{Budget: budget, Severity: rule.severity, BurnRate: long})
+					break
+				}
+			}
+		}
+		report.SLOs = append(report.SLOs, status)
+	}
+	return report
+}
+
+// burnRate returns the window's burn rate of the budget
+func (w SLOWindow) burnRate(budget string) float64 {
+	if budget == SLOBudgetLatency {
+		return w.LatencyBurnRate
+	}
+	return w.AvailabilityBurnRate
+}
+
+// burnRate is the share of bad requests divided by the share the objective allows, rounded to
+// 2 decimal places; a window without requests burns nothing
+func burnRate(bad, total uint64, objective float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	rate := float64(bad) / float64(total) / (1 - objective)
+	return math.Round(rate*100) / 100
+}
+
+// Help texts of the SLO gauge families
+const (
+	sloObjectiveHelp = "Share of requests that must meet the SLO, by budget."
+	sloBurnRateHelp  = "Error budget burn rate by budget and window; 1 spends the budget exactly over the objective's period."
+)
+
+// SLOGauges reports each SLO's objectives and burn rates as gauges. Pass it to PrometheusHandler.
+func SLOGauges() ([]Gauge, error) {
+	return sloTracker.Gauges(), nil
+}
+
+// Gauges returns each SLO's objectives, latency target and burn rates per budget and window
+func (t *SLOTracker) Gauges() []Gauge {
+	report := t.Report()
+	gauges := make([]Gauge, 0, len(report.SLOs)*(3+2*len(sloWindows)))
+	for _, status := range report.SLOs {
+		method, route, _ := strings.Cut(status.Endpoint, " ")
+		labels := func(extra ...string) map[string]string {
+			set := map[string]string{"method": method, "route": route}
+			for i := 0; i+1 < len(extra); i += 2 {
+				set[extra[i]] = extra[i+1]
+			}
+			return set
+		}
+		gauges = append(gauges,
+			Gauge{Name: "irrigation_slo_latency_target_seconds", Help: "Latency a request must not exceed to count as fast.", Labels: labels(), Value: status.LatencyTargetMs / 1000},
+			Gauge{Name: "irrigation_slo_objective", Help: sloObjectiveHelp, Labels: labels("budget", SLOBudgetAvailability), Value: status.AvailabilityObjective},
+			Gauge{Name: "irrigation_slo_objective", Help: sloObjectiveHelp, Labels: labels("budget", SLOBudgetLatency), Value: status.LatencyObjective},
+		)
+		windows := make([]string, 0, len(status.Windows))
+		for name := range status.Windows {
+			windows = append(windows, name)
+		}
+		sort.Strings(windows)
+		for _, name := range windows {
+			window := status.Windows[name]
+			gauges = append(gauges,
+				Gauge{Name: "irrigation_slo_burn_rate", Help: sloBurnRateHelp, Labels: labels("budget", SLOBudgetAvailability, "window", name), Value: window.AvailabilityBurnRate},
+				Gauge{Name: "irrigation_slo_burn_rate", Help: sloBurnRateHelp, Labels: labels("budget", SLOBudgetLatency, "window", name), Value: window.LatencyBurnRate},
+			)
+		}
+	}
+	return gauges
+}
+
+// SLOReportHandler returns the status of every tracked SLO
+func SLOReportHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, sloTracker.Report())
+}
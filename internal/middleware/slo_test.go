@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSLOTracker_BurnRates(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	endpoint := "GET /v1/farms/:farm_id/irrigation/analytics"
+	tracker := NewSLOTracker([]SLO{{Endpoint: endpoint, LatencyTarget: time.Second, LatencyObjective: 0.99, AvailabilityObjective: 0.999}})
+	tracker.now = func() time.Time { return now }
+
+	// Two hours ago: 100 requests, 2 slow; only in the 6h window
+	now = now.Add(-2 * time.Hour)
+	for i := 0; i < 100; i++ {
+		latency := 200 * time.Millisecond
+		if i < 2 {
+			latency = 3 * time.Second
+		}
+		tracker.Record(endpoint, http.StatusOK, latency)
+	}
+
+	// Now: 100 requests, 2 failing; in every window
+	now = now.Add(2 * time.Hour)
+	for i := 0; i < 100; i++ {
+		status := http.StatusOK
+		if i < 2 {
+			status = http.StatusServiceUnavailable
+		}
+		tracker.Record(endpoint, status, 100*time.Millisecond)
+	}
+	// Endpoints without an SLO are not tracked
+	tracker.Record("GET /healthz", http.StatusInternalServerError, time.Millisecond)
+
+	report := tracker.Report()
+	if len(report.SLOs) != 1 {
+		t.Fatalf("expected one SLO, got %+v", report.SLOs)
+	}
+	status := report.SLOs[0]
+
+	short := status.Windows["5m"]
+	if short.Requests != 100 || short.Errors != 2 || short.Slow != 0 || short.AvailabilityBurnRate != 20 || short.LatencyBurnRate != 0 {
+		t.Errorf("unexpected 5m window %+v", short)
+	}
+	long := status.Windows["6h"]
+	if long.Requests != 200 || long.Errors != 2 || long.Slow != 2 || long.AvailabilityBurnRate != 10 || long.LatencyBurnRate != 1 {
+		t.Errorf("unexpected 6h window %+v", long)
+	}
+	if status.AvailabilityBudgetRemaining != -9 || status.LatencyBudgetRemaining != 0 {
+		t.Errorf("expected the availability budget overspent and the latency budget used up, got %v and %v",
+			status.AvailabilityBudgetRemaining, status.LatencyBudgetRemaining)
+	}
+
+	// 20x in 1h and 5m pages; 10x over 6h and 20x over 30m would also ticket, but only the
+	// most severe alert of a budget is reported
+	if len(status.Alerts) != 1 || status.Alerts[0].Budget != SLOBudgetAvailability || status.Alerts[0].Severity != SLOAlertPage || status.Alerts[0].BurnRate != 20 {
+		t.Errorf("expected one availability page, got %+v", status.Alerts)
+	}
+}
+
+func TestSLOTracker_ConfigureKeepsCounts(t *testing.T) {
+	endpoint := "GET /v1/farms/:farm_id/irrigation/analytics"
+	tracker := NewSLOTracker([]SLO{{Endpoint: endpoint, LatencyTarget: time.Second, LatencyObjective: 0.99, AvailabilityObjective: 0.999}})
+	tracker.Record(endpoint, http.StatusOK, 2*time.Second)
+
+	tracker.Configure([]SLO{{Endpoint: endpoint, LatencyTarget: 3 * time.Second, LatencyObjective: 0.95, AvailabilityObjective: 0.99}})
+
+	status := tracker.Report().SLOs[0]
+	if status.LatencyTargetMs != 3000 || status.Windows["5m"].Requests != 1 || status.Windows["5m"].LatencyBurnRate != 20 {
+		t.Errorf("expected the new targets applied to the kept counts, got %+v", status)
+	}
+}
+
+func TestParseSLOs(t *testing.T) {
+	slos, err := ParseSLOs("GET /v1/farms/:farm_id/irrigation/analytics=750ms,99,99.9; GET /v1/farms/:farm_id/timeseries=2s,95,99")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(slos) != 2 {
+		t.Fatalf("expected 2 SLOs, got %+v", slos)
+	}
+	if slos[0].Endpoint != "GET /v1/farms/:farm_id/irrigation/analytics" || slos[0].LatencyTarget != 750*time.Millisecond ||
+		slos[0].LatencyObjective != 0.99 || slos[0].AvailabilityObjective != 0.999 {
+		t.Errorf("unexpected SLO %+v", slos[0])
+	}
+
+	for _, spec := range []string{
+		"/v1/farms=1s,99,99.9",
+		"BREW /v1/farms=1s,99,99.9",
+		"GET /v1/farms=1s,99",
+		"GET /v1/farms=soon,99,99.9",
+		"GET /v1/farms=1s,100,99.9",
+		"GET /v1/farms=1s,99,0",
+	} {
+		if _, err := ParseSLOs(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
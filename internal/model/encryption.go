@@ -0,0 +1,219 @@
+package model
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"gorm.io/gorm/schema"
+)
+
+// EncryptedSerializerName is the GORM serializer of sensitive columns: fields tagged
+// serializer:encrypted are encrypted on write and decrypted on read once field keys are
+// configured. Supported field types are string and *float64.
+const EncryptedSerializerName = "encrypted"
+
+// encryptedPrefix marks a stored ciphertext: enc:v1:<key id>:<base64 of nonce and sealed value>.
+// Values without it are read as plaintext, so rows written before encryption was enabled stay
+// readable until they are re-encrypted.
+const encryptedPrefix = "enc:v1:"
+
+// ErrFieldKeysMissing is returned when an encrypted value is read while no field keys are configured
+var ErrFieldKeysMissing = errors.New("encrypted field read without field keys configured")
+
+// fieldKeys holds the configured keyring; nil stores sensitive columns in plaintext
+var fieldKeys atomic.Pointer[FieldKeyring]
+
+func init() {
+	schema.RegisterSerializer(EncryptedSerializerName, EncryptedSerializer{})
+}
+
+// FieldKeyring holds the AES-256-GCM keys of sensitive columns by ID. Values are written with
+// the primary key; the others only decrypt, so keys can be rotated without downtime.
+type FieldKeyring struct {
+	primary string
+	order   []string
+	keys    map[string]cipher.AEAD
+}
+
+// ParseFieldKeys parses comma-separated id:key entries, each key 32 bytes in standard base64.
+// The first entry is the primary key.
+func ParseFieldKeys(spec string) (*FieldKeyring, error) {
+	keyring := &FieldKeyring{keys: make(map[string]cipher.AEAD)}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, found := strings.Cut(entry, ":")
+		if !found || id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid field key entry %q: expected id:base64-key", entry)
+		}
+		if _, exists := keyring.keys[id]; exists {
+			return nil, fmt.Errorf("duplicate field key id %q", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("field key %q must be 32 bytes in base64", id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		keyring.keys[id] = aead
+		keyring.order = append(keyring.order, id)
+	}
+	if len(keyring.order) == 0 {
+		return nil, errors.New("no field keys given")
+	}
+	keyring.primary = keyring.order[0]
+	return keyring, nil
+}
+
+// PrimaryKeyID is the ID of the key new values are encrypted with
+func (k *FieldKeyring) PrimaryKeyID() string {
+	return k.primary
+}
+
+// KeyIDs lists the key IDs, primary first
+func (k *FieldKeyring) KeyIDs() []string {
+	return append([]string(nil), k.order...)
+}
+
+// ConfigureFieldEncryption sets the keyring of sensitive columns. nil turns encryption off:
+// values are written in plaintext and stored ciphertexts fail to read.
+func ConfigureFieldEncryption(keyring *FieldKeyring) {
+	fieldKeys.Store(keyring)
+}
+
+// FieldEncryption returns the configured keyring, nil when encryption is off
+func FieldEncryption() *FieldKeyring {
+	return fieldKeys.Load()
+}
+
+// seal encrypts plaintext with the primary key, bound to the column by aad
+func (k *FieldKeyring) seal(plaintext, aad string) (string, error) {
+	aead := k.keys[k.primary]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(aad))
+	return encryptedPrefix + k.primary + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// open decrypts a stored ciphertext written by seal
+func (k *FieldKeyring) open(stored, aad string) (string, error) {
+	id, encoded, found := strings.Cut(strings.TrimPrefix(stored, encryptedPrefix), ":")
+	if !found {
+		return "", errors.New("malformed encrypted field")
+	}
+	aead, ok := k.keys[id]
+	if !ok {
+		return "", fmt.Errorf("encrypted field uses unknown key %q", id)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New("malformed encrypted field")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(aad))
+	if err != nil {
+		return "", fmt.Errorf("decrypting field with key %q: %w", id, err)
+	}
+	return string(plaintext), nil
+}
+
+// IsEncryptedValue reports whether a stored column value is a ciphertext
+func IsEncryptedValue(stored string) bool {
+	return strings.HasPrefix(stored, encryptedPrefix)
+}
+
+// EncryptedSerializer encrypts a field with the configured keyring. Each ciphertext is bound to
+// its table and column, so it can't be copied into another column and read back.
+type EncryptedSerializer struct{}
+
+// fieldAAD is the additional data binding a ciphertext to its column
+func fieldAAD(field *schema.Field) string {
+	return field.Schema.Table + "." + field.DBName
+}
+
+// Scan decrypts the stored value into the field; plaintext values are read as they are
+func (EncryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var stored string
+	switch v := dbValue.(type) {
+	case nil:
+		field.ReflectValueOf(ctx, dst).Set(reflect.Zero(field.FieldType))
+		return nil
+	case string:
+		stored = v
+	case []byte:
+		stored = string(v)
+	default:
+		return fmt.Errorf("unsupported encrypted field value %T", dbValue)
+	}
+
+	if IsEncryptedValue(stored) {
+		keyring := fieldKeys.Load()
+		if keyring == nil {
+			return fmt.Errorf("%s: %w", fieldAAD(field), ErrFieldKeysMissing)
+		}
+		plaintext, err := keyring.open(stored, fieldAAD(field))
+		if err != nil {
+			return fmt.Errorf("%s: %w", fieldAAD(field), err)
+		}
+		stored = plaintext
+	}
+
+	value := field.ReflectValueOf(ctx, dst)
+	switch field.FieldType {
+	case reflect.TypeOf(""):
+		value.SetString(stored)
+	case reflect.TypeOf((*float64)(nil)):
+		number, err := strconv.ParseFloat(stored, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %w", fieldAAD(field), err)
+		}
+		value.Set(reflect.ValueOf(&number))
+	default:
+		return fmt.Errorf("unsupported encrypted field type %s", field.FieldType)
+	}
+	return nil
+}
+
+// Value encrypts the field for storage. Empty strings and nil numbers are stored as they are,
+// so a missing value stays distinguishable without a key.
+func (EncryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	var plaintext string
+	switch v := fieldValue.(type) {
+	case string:
+		if v == "" {
+			return "", nil
+		}
+		plaintext = v
+	case *float64:
+		if v == nil {
+			return nil, nil
+		}
+		plaintext = strconv.FormatFloat(*v, 'f', -1, 64)
+	default:
+		return nil, fmt.Errorf("unsupported encrypted field type %T", fieldValue)
+	}
+
+	keyring := fieldKeys.Load()
+	if keyring == nil {
+		return plaintext, nil
+	}
+	return keyring.seal(plaintext, fieldAAD(field))
+}
@@ -0,0 +1,126 @@
+package model
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm/schema"
+)
+
+// testKey is a 32-byte key in base64, filled with b
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), 32)))
+}
+
+// farmField returns the parsed schema field of a Farm column
+func farmField(t *testing.T, column string) *schema.Field {
+	t.Helper()
+	sch, err := schema.Parse(&Farm{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("parsing the farm schema: %v", err)
+	}
+	return sch.LookUpField(column)
+}
+
+// store serializes a field of farm as it would be written
+func store(t *testing.T, field *schema.Field, farm *Farm) interface{} {
+	t.Helper()
+	dst := reflect.ValueOf(farm).Elem()
+	stored, err := EncryptedSerializer{}.Value(context.Background(), field, dst, dst.FieldByName(field.Name).Interface())
+	if err != nil {
+		t.Fatalf("serializing %s: %v", field.DBName, err)
+	}
+	return stored
+}
+
+// load deserializes a stored value into a new farm
+func load(field *schema.Field, stored interface{}) (*Farm, error) {
+	farm := &Farm{}
+	err := EncryptedSerializer{}.Scan(context.Background(), field, reflect.ValueOf(farm).Elem(), stored)
+	return farm, err
+}
+
+func TestEncryptedSerializer(t *testing.T) {
+	defer ConfigureFieldEncryption(nil)
+	old, err := ParseFieldKeys("2024:" + testKey('a'))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ConfigureFieldEncryption(old)
+
+	location := farmField(t, "location")
+	latitude := farmField(t, "latitude")
+	lat := 41.617592
+	farm := &Farm{Location: "Lleida", Latitude: &lat}
+
+	stored := store(t, location, farm)
+	if text, ok := stored.(string); !ok || !strings.HasPrefix(text, "enc:v1:2024:") || strings.Contains(text, "Lleida") {
+		t.Fatalf("expected a ciphertext under key 2024, got %v", stored)
+	}
+	storedLatitude := store(t, latitude, farm)
+
+	// After rotation the old key still decrypts, and new writes use the new primary key
+	rotated, err := ParseFieldKeys("2025:" + testKey('b') + ", 2024:" + testKey('a'))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ConfigureFieldEncryption(rotated)
+	if got, err := load(location, stored); err != nil || got.Location != "Lleida" {
+		t.Errorf("expected Lleida decrypted with the old key, got %+v (%v)", got, err)
+	}
+	if got, err := load(latitude, []byte(storedLatitude.(string))); err != nil || got.Latitude == nil || *got.Latitude != lat {
+		t.Errorf("expected the latitude decrypted, got %+v (%v)", got, err)
+	}
+	if text := store(t, location, farm).(string); !strings.HasPrefix(text, "enc:v1:2025:") {
+		t.Errorf("expected writes under the new primary key, got %s", text)
+	}
+
+	// A ciphertext copied into another column fails to decrypt
+	if _, err := load(farmField(t, "description"), stored); err == nil {
+		t.Error("expected a location ciphertext to fail as a description")
+	}
+
+	// Plaintext written before encryption was enabled, empty and NULL values read as they are
+	if got, err := load(location, "Huesca"); err != nil || got.Location != "Huesca" {
+		t.Errorf("expected plaintext read as is, got %+v (%v)", got, err)
+	}
+	if got, err := load(latitude, nil); err != nil || got.Latitude != nil {
+		t.Errorf("expected NULL read as nil, got %+v (%v)", got, err)
+	}
+	if text := store(t, location, &Farm{}); text != "" {
+		t.Errorf("expected an empty location stored empty, got %v", text)
+	}
+
+	// Without keys, ciphertexts fail loudly instead of leaking into responses
+	ConfigureFieldEncryption(nil)
+	if _, err := load(location, stored); !errors.Is(err, ErrFieldKeysMissing) {
+		t.Errorf("expected ErrFieldKeysMissing, got %v", err)
+	}
+	if text := store(t, location, farm); text != "Lleida" {
+		t.Errorf("expected plaintext writes without keys, got %v", text)
+	}
+}
+
+func TestParseFieldKeys(t *testing.T) {
+	keyring, err := ParseFieldKeys("new:" + testKey('n') + ",old:" + testKey('o'))
+	if err != nil || keyring.PrimaryKeyID() != "new" || len(keyring.KeyIDs()) != 2 {
+		t.Fatalf("expected new as the primary of two keys, got %+v (%v)", keyring, err)
+	}
+
+	for _, spec := range []string{
+		"",
+		testKey('a'),
+		"short:" + base64.StdEncoding.EncodeToString([]byte("16 bytes is less")),
+		"a:" + testKey('a') + ",a:" + testKey('b'),
+		"a:not base64!",
+	} {
+		if _, err := ParseFieldKeys(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
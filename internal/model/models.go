@@ -1,6 +1,10 @@
 package model
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
 	"time"
 
 	"gorm.io/gorm"
@@ -13,16 +17,106 @@ type Farm struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 
-	Name        string  `gorm:"not null;size:255" json:"name"`
-	Location    string  `gorm:"size:255" json:"location"`
-	TotalArea   float64 `gorm:"type:decimal(10,2)" json:"total_area"`
-	Description string  `gorm:"type:text" json:"description"`
+	Name      string  `gorm:"not null;size:255" json:"name"`
+	TotalArea float64 `gorm:"type:decimal(10,2)" json:"total_area"`
+	// Location, Description, the coordinates and WaterAccount are sensitive: they are encrypted
+	// at rest once field keys are configured (see EncryptedSerializer)
+	Location    string `gorm:"type:text;serializer:encrypted" json:"location"`
+	Description string `gorm:"type:text;serializer:encrypted" json:"description"`
+	// Latitude and Longitude locate the farm for weather lookups; weather is unavailable without them
+	Latitude  *float64 `gorm:"type:text;serializer:encrypted" json:"latitude,omitempty"`
+	Longitude *float64 `gorm:"type:text;serializer:encrypted" json:"longitude,omitempty"`
+	// ArchivedAt is set while the farm is archived. Archived farms are left out of cross-farm
+	// aggregates by default; their own data stays queryable.
+	ArchivedAt *time.Time `gorm:"index" json:"archived_at,omitempty"`
+	// AttributionPolicy decides which buckets a multi-hour event counts in: AttributionStart,
+	// AttributionEnd or AttributionProportional
+	AttributionPolicy string `gorm:"not null;size:20;default:start" json:"attribution_policy"`
+	// WaterAccount is the farm's account with its irrigation district, used to pull the farm's
+	// water delivery orders; orders are unavailable without it
+	WaterAccount string `gorm:"type:text;serializer:encrypted" json:"water_account,omitempty"`
+	// CropYearStart is the MM-DD day the farm's crop year starts, e.g. 10-01 for a season spanning
+	// new year; empty means 1 January. Season-aligned year-over-year comparisons use it.
+	CropYearStart string `gorm:"size:5" json:"crop_year_start,omitempty"`
 
 	// Relationships
 	IrrigationSectors []IrrigationSector `gorm:"foreignKey:FarmID;constraint:OnDelete:CASCADE" json:"irrigation_sectors,omitempty"`
 	IrrigationData    []IrrigationData   `gorm:"foreignKey:FarmID;constraint:OnDelete:CASCADE" json:"irrigation_data,omitempty"`
 }
 
+// Event attribution policies
+const (
+	// AttributionStart counts an event in the bucket it starts in
+	AttributionStart = "start"
+	// AttributionEnd counts an event in the bucket it ends in
+	AttributionEnd = "end"
+	// AttributionProportional spreads an event over the buckets it spans, by time spent in each
+	AttributionProportional = "proportional"
+)
+
+// IsValidAttributionPolicy reports whether policy is one of the known attribution policies
+func IsValidAttributionPolicy(policy string) bool {
+	switch policy {
+	case AttributionStart, AttributionEnd, AttributionProportional:
+		return true
+	}
+	return false
+}
+
+// CropYear is the day a crop year starts. The zero value starts on 1 January.
+type CropYear struct {
+	Month time.Month
+	Day   int
+}
+
+// ParseCropYear parses an MM-DD crop year start; an empty value is the zero CropYear. 29 February
+// is rejected, since most years have no such day to start on.
+func ParseCropYear(value string) (CropYear, error) {
+	if value == "" {
+		return CropYear{}, nil
+	}
+	// 2001 is not a leap year, so 02-29 fails to parse
+	day, err := time.Parse("2006-01-02", "2001-"+value)
+	if err != nil || len(value) != 5 {
+		return CropYear{}, fmt.Errorf("invalid crop year start %q: expected MM-DD", value)
+	}
+	return CropYear{Month: day.Month(), Day: day.Day()}, nil
+}
+
+// String formats the crop year start as MM-DD
+func (c CropYear) String() string {
+	month, day := c.startDay()
+	return fmt.Sprintf("%02d-%02d", int(month), day)
+}
+
+// startDay is the month and day the crop year starts, 1 January for the zero value
+func (c CropYear) startDay() (time.Month, int) {
+	if c.Month == 0 {
+		return time.January, 1
+	}
+	return c.Month, c.Day
+}
+
+// Start returns the start of the crop year containing t, at midnight in t's location
+func (c CropYear) Start(t time.Time) time.Time {
+	month, day := c.startDay()
+	start := time.Date(t.Year(), month, day, 0, 0, 0, 0, t.Location())
+	if start.After(t) {
+		start = start.AddDate(-1, 0, 0)
+	}
+	return start
+}
+
+// ShiftYears moves [start, end) to the same position in the crop year years back: it starts as
+// long after that crop year's start as start does after its own, and lasts as long. Unlike
+// AddDate(-years, 0, 0), a range keeps its length across a 29 February, and 29 February itself
+// doesn't collapse into an empty range on 1 March.
+func (c CropYear) ShiftYears(start, end time.Time, years int) (time.Time, time.Time) {
+	season := c.Start(start)
+	shifted := season.AddDate(-years, 0, 0).Add(start.Sub(season))
+	return shifted, shifted.Add(end.Sub(start))
+}
+
 // TableName specifies the table name for Farm
 func (Farm) TableName() string {
 	return "farms"
@@ -38,11 +132,21 @@ type IrrigationSector struct {
 	FarmID      uint    `gorm:"not null;index" json:"farm_id"`
 	Name        string  `gorm:"not null;size:255" json:"name"`
 	Area        float64 `gorm:"type:decimal(10,2)" json:"area"`
-	Description string  `gorm:"type:text" json:"description"`
+	Description string  `gorm:"type:text;serializer:encrypted" json:"description"`
+	// FallbackFlowRate is the nominal flow in liters per minute assumed for events without a
+	// recorded nominal_amount; NULL uses the 1 L/min default
+	FallbackFlowRate *float64 `gorm:"type:decimal(10,3)" json:"fallback_flow_rate,omitempty"`
+	// ParentID is the sector this one is a zone of; NULL for top-level sectors
+	ParentID *uint `gorm:"index" json:"parent_id,omitempty"`
+	// Crop is a key of the crop coefficient library and CropStage its growth stage; together they
+	// pick the Kc that scales ET0 to the crop's demand in recommendations
+	Crop      string `gorm:"size:64" json:"crop,omitempty"`
+	CropStage string `gorm:"size:16" json:"crop_stage,omitempty"`
 
 	// Relationships
-	Farm           Farm             `gorm:"foreignKey:FarmID" json:"farm,omitempty"`
-	IrrigationData []IrrigationData `gorm:"foreignKey:IrrigationSectorID;constraint:OnDelete:CASCADE" json:"irrigation_data,omitempty"`
+	Farm           Farm               `gorm:"foreignKey:FarmID" json:"farm,omitempty"`
+	Zones          []IrrigationSector `gorm:"foreignKey:ParentID" json:"zones,omitempty"`
+	IrrigationData []IrrigationData   `gorm:"foreignKey:IrrigationSectorID;constraint:OnDelete:CASCADE" json:"irrigation_data,omitempty"`
 }
 
 // TableName specifies the table name for IrrigationSector
@@ -58,20 +162,133 @@ type IrrigationData struct {
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 
 	// Foreign keys with composite indexes for Year-over-Year analytics optimization
-	FarmID            uint      `gorm:"not null;index:idx_farm_start_time,priority:1;index:idx_farm_sector_time,priority:1" json:"farm_id"`
+	FarmID             uint      `gorm:"not null;index:idx_farm_start_time,priority:1;index:idx_farm_sector_time,priority:1;uniqueIndex:idx_farm_external_id,priority:1" json:"farm_id"`
 	IrrigationSectorID uint      `gorm:"not null;index:idx_sector_start_time,priority:1;index:idx_farm_sector_time,priority:2;column:irrigation_sector_id" json:"irrigation_sector_id"`
-	StartTime         time.Time `gorm:"not null;index:idx_farm_start_time,priority:2;index:idx_sector_start_time,priority:2;index:idx_farm_sector_time,priority:3" json:"start_time"`
-	EndTime           time.Time `gorm:"not null" json:"end_time"`
-	
+	StartTime          time.Time `gorm:"not null;index:idx_farm_start_time,priority:2;index:idx_sector_start_time,priority:2;index:idx_farm_sector_time,priority:3" json:"start_time"`
+	EndTime            time.Time `gorm:"not null" json:"end_time"`
+
 	// Irrigation metrics
-	WaterVolume   float64 `gorm:"type:decimal(10,2);not null" json:"water_volume"`
-	Duration      int     `gorm:"not null" json:"duration"` // Duration in minutes
-	NominalAmount float64 `gorm:"type:numeric(10,2)" json:"nominal_amount"`
-	RealAmount    float64 `gorm:"type:numeric(10,2)" json:"real_amount"`
+	WaterVolume float64 `gorm:"type:decimal(10,2);not null" json:"water_volume"`
+	Duration    int     `gorm:"not null" json:"duration"` // Duration in minutes
+	// NominalAmount is nil when not recorded, which is distinct from a recorded 0
+	NominalAmount *float64 `gorm:"type:numeric(10,2)" json:"nominal_amount"`
+	RealAmount    float64  `gorm:"type:numeric(10,2)" json:"real_amount"`
+	// VolumeUnit is the unit the source reported volumes in; the stored volumes are always liters
+	VolumeUnit string `gorm:"size:8;not null;default:'L'" json:"volume_unit"`
+	// RawWaterVolume and RawRealAmount keep the reported values, in liters, when CalibrationID
+	// corrected them at ingestion; they are nil for uncalibrated events
+	RawWaterVolume *float64 `gorm:"type:decimal(10,2)" json:"raw_water_volume,omitempty"`
+	RawRealAmount  *float64 `gorm:"type:numeric(10,2)" json:"raw_real_amount,omitempty"`
+	CalibrationID  *uint    `json:"calibration_id,omitempty"`
+	// RawPayload is the record as received, kept so the event can be re-parsed when a mapping bug
+	// is found; PayloadFormat says how to read it. Both are empty for events stored without one.
+	RawPayload    RawPayload `gorm:"type:jsonb" json:"raw_payload,omitempty"`
+	PayloadFormat string     `gorm:"size:8" json:"payload_format,omitempty"`
+
+	// DataSource records which ingestion path produced the event
+	DataSource string `gorm:"size:32;not null;default:'api'" json:"data_source"`
+	// Purpose classifies why the water was applied; non-irrigation purposes skew efficiency
+	Purpose string `gorm:"size:32;not null;default:'irrigation'" json:"purpose"`
+	// DeviceID is the registered flow meter that measured the event, nil when not recorded
+	DeviceID *uint `gorm:"index" json:"device_id,omitempty"`
+	// ExternalID is the sender's ID for the event, unique per farm, so a retried upload can't
+	// store it twice; nil when the sender didn't give one
+	ExternalID *string `gorm:"size:128;uniqueIndex:idx_farm_external_id,priority:2" json:"external_id,omitempty"`
 
 	// Relationships
-	Farm   Farm           `gorm:"foreignKey:FarmID" json:"farm,omitempty"`
+	Farm   Farm             `gorm:"foreignKey:FarmID" json:"farm,omitempty"`
 	Sector IrrigationSector `gorm:"foreignKey:IrrigationSectorID" json:"sector,omitempty"`
+	Device *Device          `gorm:"foreignKey:DeviceID" json:"device,omitempty"`
+}
+
+// RawPayload is an ingested record as received, stored as jsonb and served as embedded JSON
+type RawPayload json.RawMessage
+
+// Value stores the payload as JSON text, or NULL when empty
+func (p RawPayload) Value() (driver.Value, error) {
+	if len(p) == 0 {
+		return nil, nil
+	}
+	return string(p), nil
+}
+
+// Scan reads a jsonb column
+func (p *RawPayload) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*p = nil
+	case []byte:
+		*p = append(RawPayload(nil), v...)
+	case string:
+		*p = RawPayload(v)
+	default:
+		return fmt.Errorf("unsupported raw payload type %T", value)
+	}
+	return nil
+}
+
+// MarshalJSON embeds the payload as is
+func (p RawPayload) MarshalJSON() ([]byte, error) {
+	if len(p) == 0 {
+		return []byte("null"), nil
+	}
+	return p, nil
+}
+
+// Data sources an irrigation event can come from
+const (
+	DataSourceSeed   = "seed"
+	DataSourceAPI    = "api"
+	DataSourceMQTT   = "mqtt"
+	DataSourceImport = "import"
+)
+
+// IsValidDataSource reports whether source is one of the known event data sources
+func IsValidDataSource(source string) bool {
+	switch source {
+	case DataSourceSeed, DataSourceAPI, DataSourceMQTT, DataSourceImport:
+		return true
+	}
+	return false
+}
+
+// Volume units a source can report in; liters is the canonical unit
+const (
+	VolumeUnitLiters  = "L"
+	VolumeUnitGallons = "gal"
+)
+
+// litersPerGallon converts US gallons to liters
+const litersPerGallon = 3.785411784
+
+// IsValidVolumeUnit reports whether unit is one of the known volume units
+func IsValidVolumeUnit(unit string) bool {
+	return unit == VolumeUnitLiters || unit == VolumeUnitGallons
+}
+
+// ToLiters converts a volume reported in unit to liters; unknown units are returned unchanged
+func ToLiters(volume float64, unit string) float64 {
+	if unit == VolumeUnitGallons {
+		return volume * litersPerGallon
+	}
+	return volume
+}
+
+// Event purposes
+const (
+	PurposeIrrigation      = "irrigation"
+	PurposeFrostProtection = "frost_protection"
+	PurposeLeaching        = "leaching"
+	PurposeSystemFlush     = "system_flush"
+)
+
+// IsValidPurpose reports whether purpose is one of the known event purposes
+func IsValidPurpose(purpose string) bool {
+	switch purpose {
+	case PurposeIrrigation, PurposeFrostProtection, PurposeLeaching, PurposeSystemFlush:
+		return true
+	}
+	return false
 }
 
 // TableName specifies the table name for IrrigationData
@@ -79,6 +296,14 @@ func (IrrigationData) TableName() string {
 	return "irrigation_data"
 }
 
+// NominalAmountOrZero returns the nominal amount, or 0 when it was not recorded
+func (id *IrrigationData) NominalAmountOrZero() float64 {
+	if id.NominalAmount == nil {
+		return 0
+	}
+	return *id.NominalAmount
+}
+
 // BeforeCreate hook to calculate duration if not set
 func (id *IrrigationData) BeforeCreate(tx *gorm.DB) error {
 	if id.Duration == 0 && !id.StartTime.IsZero() && !id.EndTime.IsZero() {
@@ -87,3 +312,471 @@ func (id *IrrigationData) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// Annotation marks an irrigation event or a day with a user-supplied label such as "pipe burst"
+type Annotation struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+
+	FarmID uint `gorm:"not null;index:idx_annotation_farm_date,priority:1" json:"farm_id"`
+	// IrrigationDataID is set for event annotations and nil for day annotations
+	IrrigationDataID *uint `gorm:"index" json:"irrigation_data_id,omitempty"`
+	// IrrigationSectorID limits a day annotation to one sector; event annotations copy the event's sector
+	IrrigationSectorID *uint `gorm:"column:irrigation_sector_id" json:"irrigation_sector_id,omitempty"`
+	// Date is the annotated day, or the start day of the annotated event
+	Date time.Time `gorm:"type:date;not null;index:idx_annotation_farm_date,priority:2" json:"date"`
+
+	Label                 string `gorm:"not null;size:255" json:"label"`
+	Note                  string `gorm:"type:text" json:"note,omitempty"`
+	ExcludeFromEfficiency bool   `gorm:"not null;default:false" json:"exclude_from_efficiency"`
+}
+
+// TableName specifies the table name for Annotation
+func (Annotation) TableName() string {
+	return "irrigation_annotations"
+}
+
+// Import job statuses
+const (
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+// ImportJob tracks the progress of a bulk import so a failed import can be resumed
+type ImportJob struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+
+	FarmID    uint   `gorm:"not null;index" json:"farm_id"`
+	Status    string `gorm:"size:16;not null" json:"status"`
+	TotalRows int    `gorm:"not null" json:"total_rows"`
+	// ProcessedRows is the number of leading rows whose chunk has committed; a resumed import starts here
+	ProcessedRows int `gorm:"not null;default:0" json:"processed_rows"`
+	ImportedRows  int `gorm:"not null;default:0" json:"imported_rows"`
+	RejectedRows  int `gorm:"not null;default:0" json:"rejected_rows"`
+	// DuplicateRows counts rows skipped because their external_id was already stored
+	DuplicateRows int    `gorm:"not null;default:0" json:"duplicate_rows"`
+	Error         string `gorm:"type:text" json:"error,omitempty"`
+}
+
+// TableName specifies the table name for ImportJob
+func (ImportJob) TableName() string {
+	return "import_jobs"
+}
+
+// PressureReading is a line pressure sample from a sector's sensor
+type PressureReading struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	FarmID             uint `gorm:"not null;index:idx_pressure_farm_time,priority:1" json:"farm_id"`
+	IrrigationSectorID uint `gorm:"not null;column:irrigation_sector_id" json:"irrigation_sector_id"`
+	// DeviceID identifies the sensor when a sector has more than one
+	DeviceID    string    `gorm:"size:64" json:"device_id,omitempty"`
+	RecordedAt  time.Time `gorm:"not null;index:idx_pressure_farm_time,priority:2" json:"recorded_at"`
+	PressureBar float64   `gorm:"type:numeric(6,3);not null" json:"pressure_bar"`
+	// RawPressureBar keeps the reported pressure when CalibrationID corrected it at ingestion
+	RawPressureBar *float64 `gorm:"type:numeric(6,3)" json:"raw_pressure_bar,omitempty"`
+	CalibrationID  *uint    `json:"calibration_id,omitempty"`
+}
+
+// TableName specifies the table name for PressureReading
+func (PressureReading) TableName() string {
+	return "pressure_readings"
+}
+
+// WeatherObservation is one day of weather at a farm's location, as reported by a weather provider
+type WeatherObservation struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	FarmID uint      `gorm:"not null;uniqueIndex:idx_weather_farm_date,priority:1" json:"farm_id"`
+	Date   time.Time `gorm:"type:date;not null;uniqueIndex:idx_weather_farm_date,priority:2" json:"date"`
+
+	// PrecipitationMM and ET0MM are nil when the provider had no value for the day
+	PrecipitationMM *float64 `gorm:"type:numeric(7,2);column:precipitation_mm" json:"precipitation_mm"`
+	// ET0MM is the FAO-56 reference evapotranspiration
+	ET0MM *float64 `gorm:"type:numeric(7,2);column:et0_mm" json:"et0_mm"`
+	// TempMinC and TempMaxC are the day's air temperature extremes in °C
+	TempMinC *float64 `gorm:"type:numeric(5,2);column:temp_min_c" json:"temp_min_c"`
+	TempMaxC *float64 `gorm:"type:numeric(5,2);column:temp_max_c" json:"temp_max_c"`
+	Source   string   `gorm:"size:32;not null" json:"source"`
+}
+
+// TableName specifies the table name for WeatherObservation
+func (WeatherObservation) TableName() string {
+	return "weather_observations"
+}
+
+// Calibration targets: what a calibration corrects and how it is matched
+const (
+	// CalibrationTargetVolume corrects the water_volume and real_amount of events from a data
+	// source, optionally limited to one sector's meter
+	CalibrationTargetVolume = "volume"
+	// CalibrationTargetPressure corrects the readings of one pressure sensor, by device_id
+	CalibrationTargetPressure = "pressure"
+)
+
+// Calibration corrects a known meter bias at ingestion: the stored value is the reported value
+// times Factor plus Offset. The reported value is kept alongside the corrected one.
+type Calibration struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+
+	FarmID uint   `gorm:"not null;index" json:"farm_id"`
+	Target string `gorm:"size:16;not null" json:"target"`
+	// DataSource and IrrigationSectorID match volume calibrations; a sector calibration takes
+	// precedence over the data source's farm-wide one
+	DataSource         string `gorm:"size:32" json:"data_source,omitempty"`
+	IrrigationSectorID *uint  `gorm:"column:irrigation_sector_id" json:"sector_id,omitempty"`
+	// DeviceID matches pressure calibrations
+	DeviceID string  `gorm:"size:64" json:"device_id,omitempty"`
+	Factor   float64 `gorm:"type:numeric(10,6);not null;default:1" json:"factor"`
+	// Offset is in liters per event for volume calibrations and in bar for pressure calibrations
+	Offset float64 `gorm:"type:numeric(12,4);not null;default:0" json:"offset"`
+	Note   string  `gorm:"type:text" json:"note,omitempty"`
+}
+
+// TableName specifies the table name for Calibration
+func (Calibration) TableName() string {
+	return "calibrations"
+}
+
+// Apply corrects a reported value; corrections below zero are clamped to zero
+func (c *Calibration) Apply(value float64) float64 {
+	return math.Max(value*c.Factor+c.Offset, 0)
+}
+
+// Dead letter statuses
+const (
+	DeadLetterPending   = "pending"
+	DeadLetterReplayed  = "replayed"
+	DeadLetterDiscarded = "discarded"
+)
+
+// DeadLetterSourceImport marks dead letters of rows rejected by a bulk or spreadsheet import
+const DeadLetterSourceImport = "import"
+
+// DeadLetter is an ingested message that was rejected, kept with its error so it can be fixed
+// and replayed, or discarded
+type DeadLetter struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	FarmID uint `gorm:"not null;index:idx_dead_letter_farm_status,priority:1" json:"farm_id"`
+	// Source is the ingestion path that rejected the message
+	Source      string `gorm:"size:32;not null" json:"source"`
+	ImportJobID *uint  `json:"import_id,omitempty"`
+	// Row locates the message in its batch: the row index, or the file line for spreadsheets
+	Row           int        `gorm:"not null" json:"row"`
+	Payload       RawPayload `gorm:"type:jsonb;not null" json:"payload"`
+	PayloadFormat string     `gorm:"size:8;not null" json:"payload_format"`
+	// Error is why the message was rejected, or why its latest replay was
+	Error    string `gorm:"type:text;not null" json:"error"`
+	Status   string `gorm:"size:16;not null;default:'pending';index:idx_dead_letter_farm_status,priority:2" json:"status"`
+	Attempts int    `gorm:"not null;default:0" json:"replay_attempts"`
+	// EventID is the event a successful replay created
+	EventID    *uint      `json:"event_id,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// TableName specifies the table name for DeadLetter
+func (DeadLetter) TableName() string {
+	return "dead_letters"
+}
+
+// Webhook alert event types
+const (
+	WebhookEventDailyVolumeExceeded  = "daily_volume_exceeded"
+	WebhookEventEfficiencyBelowFloor = "efficiency_below_floor"
+	WebhookEventNoData               = "no_data"
+)
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// Webhook is a URL registered for a farm's alerts. Each threshold that is set enables its alert.
+type Webhook struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+
+	FarmID uint   `gorm:"not null;index" json:"farm_id"`
+	URL    string `gorm:"size:2048;not null" json:"url"`
+	// Secret signs every delivery; it is only shown when the webhook is created
+	Secret string `gorm:"size:64;not null" json:"-"`
+	// MaxDailyWaterVolume alerts when a day's water_volume exceeds it, in liters
+	MaxDailyWaterVolume *float64 `gorm:"type:numeric(14,2)" json:"max_daily_water_volume,omitempty"`
+	// MinDailyEfficiency alerts when a finished day's efficiency falls below it
+	MinDailyEfficiency *float64 `gorm:"type:numeric(6,4)" json:"min_daily_efficiency,omitempty"`
+	// NoDataHours alerts when no event has arrived for that many hours
+	NoDataHours *int `json:"no_data_hours,omitempty"`
+}
+
+// TableName specifies the table name for Webhook
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+// WebhookDelivery is one alert queued for a webhook, retried with backoff until delivered
+type WebhookDelivery struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	WebhookID uint `gorm:"not null;uniqueIndex:idx_webhook_delivery_key,priority:1" json:"webhook_id"`
+	// Key identifies the alert, such as the event and the day it is about, so it is queued once
+	Key     string     `gorm:"size:128;not null;uniqueIndex:idx_webhook_delivery_key,priority:2" json:"key"`
+	Event   string     `gorm:"size:32;not null" json:"event"`
+	Payload RawPayload `gorm:"type:jsonb;not null" json:"payload"`
+
+	Status        string     `gorm:"size:16;not null;default:'pending';index:idx_webhook_delivery_due,priority:1" json:"status"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"not null;index:idx_webhook_delivery_due,priority:2" json:"next_attempt_at"`
+	LastError     string     `gorm:"type:text" json:"last_error,omitempty"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+}
+
+// TableName specifies the table name for WebhookDelivery
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// IrrigationDailyRollup holds one day of a sector's event totals for one purpose and data source,
+// so long daily, weekly and monthly aggregations read a row per day instead of every event.
+// Rows are rebuilt by the rollup refresher; see IrrigationRollupState for how far they reach.
+type IrrigationDailyRollup struct {
+	FarmID             uint      `gorm:"primaryKey;autoIncrement:false" json:"farm_id"`
+	Day                time.Time `gorm:"type:date;primaryKey" json:"day"`
+	IrrigationSectorID uint      `gorm:"primaryKey;autoIncrement:false;column:irrigation_sector_id" json:"irrigation_sector_id"`
+	Purpose            string    `gorm:"size:32;primaryKey" json:"purpose"`
+	DataSource         string    `gorm:"size:32;primaryKey" json:"data_source"`
+
+	WaterVolume   float64 `gorm:"type:numeric(14,2);not null" json:"water_volume"`
+	Duration      int64   `gorm:"not null" json:"duration"`
+	EventCount    int     `gorm:"not null" json:"event_count"`
+	NominalAmount float64 `gorm:"type:numeric(14,2);not null" json:"nominal_amount"`
+	// RealAmount sums only the events with a recorded nominal_amount
+	RealAmount float64 `gorm:"type:numeric(14,2);not null" json:"real_amount"`
+	// MissingNominalCount is the number of events without a recorded nominal_amount
+	MissingNominalCount int `gorm:"not null" json:"missing_nominal_count"`
+}
+
+// TableName specifies the table name for IrrigationDailyRollup
+func (IrrigationDailyRollup) TableName() string {
+	return "irrigation_daily_rollups"
+}
+
+// IrrigationRollupState records how far a farm's daily rollups reach. Days before RolledUpThrough
+// are read from the rollup table, later days from the events themselves.
+type IrrigationRollupState struct {
+	FarmID          uint      `gorm:"primaryKey;autoIncrement:false" json:"farm_id"`
+	RolledUpThrough time.Time `gorm:"type:date;not null" json:"rolled_up_through"`
+	// RefreshedAt is when the last refresh started; days with events updated since are rebuilt
+	RefreshedAt time.Time `gorm:"not null" json:"refreshed_at"`
+}
+
+// TableName specifies the table name for IrrigationRollupState
+func (IrrigationRollupState) TableName() string {
+	return "irrigation_rollup_states"
+}
+
+// IrrigationRollupInvalidation marks a farm's day whose rollup lost an event, because the event
+// was hard-deleted or moved to another day or farm. A trigger on irrigation_data writes it, since
+// such changes leave nothing behind on the day's remaining events, and the farm's next refresh
+// rebuilds the day and removes the row.
+type IrrigationRollupInvalidation struct {
+	FarmID uint      `gorm:"primaryKey;autoIncrement:false" json:"farm_id"`
+	Day    time.Time `gorm:"type:date;primaryKey" json:"day"`
+}
+
+// TableName specifies the table name for IrrigationRollupInvalidation
+func (IrrigationRollupInvalidation) TableName() string {
+	return "irrigation_rollup_invalidations"
+}
+
+// Report schedule frequencies
+const (
+	ReportFrequencyWeekly  = "weekly"
+	ReportFrequencyMonthly = "monthly"
+)
+
+// ReportSchedule emails a farm's analytics summary for every finished week or month
+type ReportSchedule struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+
+	FarmID     uint     `gorm:"not null;index" json:"farm_id"`
+	Frequency  string   `gorm:"size:16;not null" json:"frequency"`
+	Recipients []string `gorm:"serializer:json;type:jsonb;not null" json:"recipients"`
+	// AttachPDF adds the PDF report to each email
+	AttachPDF bool `gorm:"not null;default:false" json:"attach_pdf"`
+	// NextRunAt is when the next report is due; the scheduler moves it forward to claim a run
+	NextRunAt  time.Time  `gorm:"not null;index" json:"next_run_at"`
+	LastSentAt *time.Time `json:"last_sent_at,omitempty"`
+	LastError  string     `gorm:"type:text" json:"last_error,omitempty"`
+}
+
+// TableName specifies the table name for ReportSchedule
+func (ReportSchedule) TableName() string {
+	return "report_schedules"
+}
+
+// Crop growth stages a sector can be in, following FAO-56
+const (
+	CropStageInitial     = "initial"
+	CropStageDevelopment = "development"
+	CropStageMid         = "mid"
+	CropStageLate        = "late"
+)
+
+// IsValidCropStage reports whether stage is one of the crop growth stages
+func IsValidCropStage(stage string) bool {
+	switch stage {
+	case CropStageInitial, CropStageDevelopment, CropStageMid, CropStageLate:
+		return true
+	}
+	return false
+}
+
+// CropCoefficientOverride replaces a farm's library Kc values for one crop. Nil values keep the
+// library's.
+type CropCoefficientOverride struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	FarmID uint   `gorm:"not null;uniqueIndex:idx_crop_override_farm_crop,priority:1" json:"farm_id"`
+	Crop   string `gorm:"size:64;not null;uniqueIndex:idx_crop_override_farm_crop,priority:2" json:"crop"`
+
+	KcIni *float64 `gorm:"type:numeric(4,2)" json:"kc_ini,omitempty"`
+	KcMid *float64 `gorm:"type:numeric(4,2)" json:"kc_mid,omitempty"`
+	KcEnd *float64 `gorm:"type:numeric(4,2)" json:"kc_end,omitempty"`
+}
+
+// TableName specifies the table name for CropCoefficientOverride
+func (CropCoefficientOverride) TableName() string {
+	return "crop_coefficient_overrides"
+}
+
+// Device kinds that can be registered on a sector
+const (
+	DeviceKindFlowMeter = "flow_meter"
+	DeviceKindValve     = "valve"
+)
+
+// IsValidDeviceKind reports whether kind is one of the device kinds
+func IsValidDeviceKind(kind string) bool {
+	switch kind {
+	case DeviceKindFlowMeter, DeviceKindValve:
+		return true
+	}
+	return false
+}
+
+// Device is a flow meter or valve installed on a sector. Events record the meter that measured
+// them in DeviceID, so a faulty meter's readings can be traced and filtered.
+type Device struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+
+	FarmID             uint   `gorm:"not null;index" json:"farm_id"`
+	IrrigationSectorID uint   `gorm:"not null;index;column:irrigation_sector_id" json:"sector_id"`
+	Kind               string `gorm:"size:16;not null" json:"kind"`
+	Name               string `gorm:"size:128;not null" json:"name"`
+	// SerialNumber is the manufacturer's serial, to find the meter in the field
+	SerialNumber string `gorm:"size:64" json:"serial_number,omitempty"`
+	Note         string `gorm:"type:text" json:"note,omitempty"`
+}
+
+// TableName specifies the table name for Device
+func (Device) TableName() string {
+	return "devices"
+}
+
+// IdempotencyKey records the response to a write request sent with an Idempotency-Key header,
+// so a retry with the same key gets the original response instead of writing again. A key
+// without a StatusCode belongs to a request still in progress; once its ReservedAt is older than
+// the reservation lease, the request is presumed lost and the key can be reserved again.
+type IdempotencyKey struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	FarmID uint `gorm:"not null;uniqueIndex:idx_idempotency_farm_scope_key,priority:1" json:"farm_id"`
+	// Scope names the endpoint, so the same key can be used on different endpoints
+	Scope string `gorm:"size:32;not null;uniqueIndex:idx_idempotency_farm_scope_key,priority:2" json:"scope"`
+	Key   string `gorm:"size:255;not null;uniqueIndex:idx_idempotency_farm_scope_key,priority:3" json:"key"`
+	// RequestHash fingerprints the request body; a retry with a different body is refused
+	RequestHash string     `gorm:"size:64;not null" json:"request_hash"`
+	StatusCode  int        `gorm:"not null;default:0" json:"status_code"`
+	Response    RawPayload `gorm:"type:jsonb" json:"response,omitempty"`
+	ReservedAt  time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP" json:"reserved_at"`
+	ExpiresAt   time.Time  `gorm:"not null;index" json:"expires_at"`
+}
+
+// TableName specifies the table name for IdempotencyKey
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
+
+// FarmRegion routes a farm to the database of its region. It lives in the routing database,
+// not the regional ones, and hands out farm IDs so they stay unique across regions. Farms
+// without a row belong to the default region.
+type FarmRegion struct {
+	FarmID    uint      `gorm:"primaryKey" json:"farm_id"`
+	Region    string    `gorm:"size:32;not null;index" json:"region"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for FarmRegion
+func (FarmRegion) TableName() string {
+	return "farm_regions"
+}
+
+// Models lists every model migrated at startup, in migration order
+func Models() []interface{} {
+	return []interface{}{
+		&Farm{},
+		&IrrigationSector{},
+		&Device{},
+		&IrrigationData{},
+		&Annotation{},
+		&ImportJob{},
+		&PressureReading{},
+		&WeatherObservation{},
+		&IrrigationDailyRollup{},
+		&IrrigationRollupState{},
+		&IrrigationRollupInvalidation{},
+		&Calibration{},
+		&DeadLetter{},
+		&Webhook{},
+		&WebhookDelivery{},
+		&ReportSchedule{},
+		&CropCoefficientOverride{},
+		&IdempotencyKey{},
+	}
+}
+
+// RoutingModels lists the models migrated on the routing database when farms are sharded by region
+func RoutingModels() []interface{} {
+	return []interface{}{
+		&FarmRegion{},
+	}
+}
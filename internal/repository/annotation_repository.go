@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"irrigation-analytics/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// AnnotationRepository defines the interface for event and day annotation operations
+type AnnotationRepository interface {
+	Create(annotation *model.Annotation) error
+	List(farmID uint, startDate, endDate time.Time) ([]model.Annotation, error)
+	Delete(farmID, annotationID uint) (bool, error)
+	WithCapture(capture *QueryCapture) AnnotationRepository
+	WithContext(ctx context.Context) AnnotationRepository
+}
+
+// annotationRepository implements AnnotationRepository
+type annotationRepository struct {
+	db *gorm.DB
+}
+
+// NewAnnotationRepository creates a new annotation repository
+func NewAnnotationRepository(db *gorm.DB) AnnotationRepository {
+	return &annotationRepository{db: db}
+}
+
+// WithCapture returns a repository whose statements are recorded by capture
+func (r *annotationRepository) WithCapture(capture *QueryCapture) AnnotationRepository {
+	return &annotationRepository{db: capture.session(r.db)}
+}
+
+// WithContext returns a repository whose statements run under ctx, so they are cancelled with it
+func (r *annotationRepository) WithContext(ctx context.Context) AnnotationRepository {
+	return &annotationRepository{db: r.db.WithContext(ctx)}
+}
+
+// Create stores a new annotation
+func (r *annotationRepository) Create(annotation *model.Annotation) error {
+	return r.db.Create(annotation).Error
+}
+
+// List returns the farm's annotations whose date falls within the range, ordered by date
+func (r *annotationRepository) List(farmID uint, startDate, endDate time.Time) ([]model.Annotation, error) {
+	var annotations []model.Annotation
+	err := r.db.
+		Where("farm_id = ? AND date >= DATE(?) AND date < ?", farmID, startDate, endDate).
+		Order("date ASC, id ASC").
+		Find(&annotations).Error
+	if err != nil {
+		return nil, err
+	}
+	return annotations, nil
+}
+
+// Delete soft-deletes an annotation of the farm, reporting whether it existed
+func (r *annotationRepository) Delete(farmID, annotationID uint) (bool, error) {
+	result := r.db.Where("farm_id = ?", farmID).Delete(&model.Annotation{}, annotationID)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+
+	"irrigation-analytics/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CalibrationRepository defines the interface for ingestion calibration operations
+type CalibrationRepository interface {
+	Replace(calibration *model.Calibration) error
+	List(farmID uint) ([]model.Calibration, error)
+	Delete(farmID, calibrationID uint) (bool, error)
+	WithContext(ctx context.Context) CalibrationRepository
+}
+
+// calibrationRepository implements CalibrationRepository
+type calibrationRepository struct {
+	db *gorm.DB
+}
+
+// NewCalibrationRepository creates a new calibration repository
+func NewCalibrationRepository(db *gorm.DB) CalibrationRepository {
+	return &calibrationRepository{db: db}
+}
+
+// WithContext returns a repository whose statements run under ctx, so they are cancelled with it
+func (r *calibrationRepository) WithContext(ctx context.Context) CalibrationRepository {
+	return &calibrationRepository{db: r.db.WithContext(ctx)}
+}
+
+// Replace stores a calibration, soft-deleting in the same transaction the farm's calibration
+// with the same target and match, so each meter or sensor has at most one
+func (r *calibrationRepository) Replace(calibration *model.Calibration) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("farm_id = ? AND target = ? AND data_source = ? AND device_id = ?",
+			calibration.FarmID, calibration.Target, calibration.DataSource, calibration.DeviceID)
+		if calibration.IrrigationSectorID != nil {
+			query = query.Where("irrigation_sector_id = ?", *calibration.IrrigationSectorID)
+		} else {
+			query = query.Where("irrigation_sector_id IS NULL")
+		}
+		if err := query.Delete(&model.Calibration{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(calibration).Error
+	})
+}
+
+// List returns the farm's calibrations ordered by ID
+func (r *calibrationRepository) List(farmID uint) ([]model.Calibration, error) {
+	var calibrations []model.Calibration
+	if err := r.db.Where("farm_id = ?", farmID).Order("id ASC").Find(&calibrations).Error; err != nil {
+		return nil, err
+	}
+	return calibrations, nil
+}
+
+// Delete soft-deletes a calibration of the farm, reporting whether it existed
+func (r *calibrationRepository) Delete(farmID, calibrationID uint) (bool, error) {
+	result := r.db.Where("farm_id = ?", farmID).Delete(&model.Calibration{}, calibrationID)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
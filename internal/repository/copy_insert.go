@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+
+	"irrigation-analytics/internal/model"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// errCopyUnsupported is returned when the connection isn't a pgx PostgreSQL connection
+var errCopyUnsupported = errors.New("COPY requires a pgx PostgreSQL connection")
+
+// copyTable describes how events are written with COPY, derived from the model's GORM schema so
+// new columns are copied without changes here
+type copyTable struct {
+	name    string
+	fields  []*schema.Field
+	columns []string
+}
+
+var (
+	eventCopyTableOnce sync.Once
+	eventCopyTable     *copyTable
+	eventCopyTableErr  error
+)
+
+// irrigationCopyTable returns the COPY layout of irrigation_data: every column except the
+// auto-incremented ID
+func irrigationCopyTable(db *gorm.DB) (*copyTable, error) {
+	eventCopyTableOnce.Do(func() {
+		sch, err := schema.Parse(&model.IrrigationData{}, &sync.Map{}, db.NamingStrategy)
+		if err != nil {
+			eventCopyTableErr = err
+			return
+		}
+		table := &copyTable{name: sch.Table}
+		for _, field := range sch.Fields {
+			if field.DBName == "" || (field.PrimaryKey && field.AutoIncrement) {
+				continue
+			}
+			table.fields = append(table.fields, field)
+			table.columns = append(table.columns, field.DBName)
+		}
+		eventCopyTable = table
+	})
+	return eventCopyTable, eventCopyTableErr
+}
+
+// rows builds the COPY rows of events the way GORM's Create would fill them: BeforeCreate runs,
+// zero timestamps get now and zero values of columns with a default get the default
+func (t *copyTable) rows(ctx context.Context, db *gorm.DB, events []model.IrrigationData, now time.Time) ([][]any, error) {
+	rows := make([][]any, len(events))
+	for i := range events {
+		event := &events[i]
+		if err := event.BeforeCreate(db); err != nil {
+			return nil, err
+		}
+		value := reflect.ValueOf(event).Elem()
+
+		row := make([]any, len(t.fields))
+		for j, field := range t.fields {
+			v, zero := field.ValueOf(ctx, value)
+			switch {
+			case zero && (field.AutoCreateTime > 0 || field.AutoUpdateTime > 0):
+				v = now
+			case zero && field.DefaultValueInterface != nil:
+				v = field.DefaultValueInterface
+			}
+			if valuer, ok := v.(driver.Valuer); ok {
+				var err error
+				if v, err = valuer.Value(); err != nil {
+					return nil, err
+				}
+			}
+			row[j] = v
+		}
+		rows[i] = row
+	}
+	return rows, nil
+}
+
+// withPgxConn runs fn on a pgx connection pinned from db's pool
+func withPgxConn(db *gorm.DB, fn func(ctx context.Context, conn *pgx.Conn) error) error {
+	return db.Connection(func(tx *gorm.DB) error {
+		ctx := tx.Statement.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		conn, ok := tx.Statement.ConnPool.(interface {
+			Raw(func(driverConn any) error) error
+		})
+		if !ok {
+			return errCopyUnsupported
+		}
+		return conn.Raw(func(driverConn any) error {
+			pgxConn, ok := driverConn.(*stdlib.Conn)
+			if !ok {
+				return errCopyUnsupported
+			}
+			return fn(ctx, pgxConn.Conn())
+		})
+	})
+}
+
+// isDataError reports whether err is PostgreSQL rejecting the copied data, such as a constraint
+// violation or an out-of-range value, rather than a connection or server failure
+func isDataError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || len(pgErr.Code) < 2 {
+		return false
+	}
+	switch pgErr.Code[:2] {
+	case "22", "23": // data exception, integrity constraint violation
+		return true
+	}
+	return false
+}
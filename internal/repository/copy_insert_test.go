@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/model"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// TestIrrigationCopyTable verifies COPY rows are filled like GORM's Create would fill them
+func TestIrrigationCopyTable(t *testing.T) {
+	db := &gorm.DB{Config: &gorm.Config{NamingStrategy: schema.NamingStrategy{}}}
+	table, err := irrigationCopyTable(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if table.name != "irrigation_data" {
+		t.Errorf("expected table irrigation_data, got %q", table.name)
+	}
+	column := map[string]int{}
+	for i, name := range table.columns {
+		column[name] = i
+	}
+	if _, ok := column["id"]; ok {
+		t.Error("expected the auto-incremented id to be left to the database")
+	}
+
+	start := time.Date(2025, 5, 1, 6, 0, 0, 0, time.UTC)
+	now := time.Date(2025, 5, 2, 0, 0, 0, 0, time.UTC)
+	events := []model.IrrigationData{{
+		FarmID:             1,
+		IrrigationSectorID: 2,
+		StartTime:          start,
+		EndTime:            start.Add(90 * time.Minute),
+		WaterVolume:        120,
+		RawPayload:         model.RawPayload(`{"sector_id":2}`),
+	}}
+	rows, err := table.rows(context.Background(), db, events, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	row := rows[0]
+	expected := map[string]any{
+		"farm_id":        uint(1),
+		"duration":       90,
+		"data_source":    "api",
+		"purpose":        "irrigation",
+		"volume_unit":    "L",
+		"created_at":     now,
+		"raw_payload":    `{"sector_id":2}`,
+		"nominal_amount": (*float64)(nil),
+		"deleted_at":     nil,
+	}
+	for name, want := range expected {
+		i, ok := column[name]
+		if !ok {
+			t.Errorf("expected a %s column", name)
+			continue
+		}
+		if row[i] != want {
+			t.Errorf("%s: expected %#v, got %#v", name, want, row[i])
+		}
+	}
+}
+
+func TestIsDataError(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{&pgconn.PgError{Code: "23503"}, true},
+		{&pgconn.PgError{Code: "22003"}, true},
+		{&pgconn.PgError{Code: "57014"}, false},
+		{errors.New("connection reset"), false},
+	}
+	for _, tt := range tests {
+		if got := isDataError(tt.err); got != tt.expected {
+			t.Errorf("isDataError(%v) = %v, expected %v", tt.err, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+
+	"irrigation-analytics/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CropCoefficientRepository defines the interface for a farm's crop coefficient overrides and
+// its sectors' crops
+type CropCoefficientRepository interface {
+	ListOverrides(farmID uint) ([]model.CropCoefficientOverride, error)
+	SaveOverride(override *model.CropCoefficientOverride) error
+	DeleteOverride(farmID uint, crop string) (bool, error)
+	SetSectorCrop(farmID, sectorID uint, crop, stage string) (bool, error)
+	WithContext(ctx context.Context) CropCoefficientRepository
+}
+
+// cropCoefficientRepository implements CropCoefficientRepository
+type cropCoefficientRepository struct {
+	db *gorm.DB
+}
+
+// NewCropCoefficientRepository creates a new crop coefficient repository
+func NewCropCoefficientRepository(db *gorm.DB) CropCoefficientRepository {
+	return &cropCoefficientRepository{db: db}
+}
+
+// WithContext returns a repository whose statements run under ctx, so they are cancelled with it
+func (r *cropCoefficientRepository) WithContext(ctx context.Context) CropCoefficientRepository {
+	return &cropCoefficientRepository{db: r.db.WithContext(ctx)}
+}
+
+// ListOverrides returns the farm's overrides ordered by crop
+func (r *cropCoefficientRepository) ListOverrides(farmID uint) ([]model.CropCoefficientOverride, error) {
+	var overrides []model.CropCoefficientOverride
+	if err := r.db.Where("farm_id = ?", farmID).Order("crop ASC").Find(&overrides).Error; err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// SaveOverride stores the override, replacing the farm's existing override of the same crop
+func (r *cropCoefficientRepository) SaveOverride(override *model.CropCoefficientOverride) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "farm_id"}, {Name: "crop"}},
+		DoUpdates: clause.AssignmentColumns([]string{"kc_ini", "kc_mid", "kc_end", "updated_at"}),
+	}).Create(override).Error
+}
+
+// DeleteOverride removes the farm's override of crop, reporting whether it existed
+func (r *cropCoefficientRepository) DeleteOverride(farmID uint, crop string) (bool, error) {
+	result := r.db.Where("farm_id = ? AND crop = ?", farmID, crop).Delete(&model.CropCoefficientOverride{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// SetSectorCrop sets a sector's crop and growth stage, reporting whether the farm has the sector.
+// Empty values clear them.
+func (r *cropCoefficientRepository) SetSectorCrop(farmID, sectorID uint, crop, stage string) (bool, error) {
+	result := r.db.Model(&model.IrrigationSector{}).
+		Where("id = ? AND farm_id = ?", sectorID, farmID).
+		Updates(map[string]interface{}{"crop": crop, "crop_stage": stage})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"irrigation-analytics/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// deadLetterBatchSize is the number of dead letters inserted per statement
+const deadLetterBatchSize = 500
+
+// ErrDeadLetterResolved is returned when a dead letter was replayed or discarded concurrently
+var ErrDeadLetterResolved = errors.New("dead letter already resolved")
+
+// DeadLetterRepository defines the interface for rejected ingestion message operations
+type DeadLetterRepository interface {
+	Create(letters []model.DeadLetter) error
+	List(farmID uint, status string, limit int) ([]model.DeadLetter, error)
+	Get(farmID, letterID uint) (*model.DeadLetter, error)
+	Update(letter *model.DeadLetter) error
+	Replay(letter *model.DeadLetter, event *model.IrrigationData) error
+	WithContext(ctx context.Context) DeadLetterRepository
+}
+
+// deadLetterRepository implements DeadLetterRepository
+type deadLetterRepository struct {
+	db *gorm.DB
+}
+
+// NewDeadLetterRepository creates a new dead letter repository
+func NewDeadLetterRepository(db *gorm.DB) DeadLetterRepository {
+	return &deadLetterRepository{db: db}
+}
+
+// WithContext returns a repository whose statements run under ctx, so they are cancelled with it
+func (r *deadLetterRepository) WithContext(ctx context.Context) DeadLetterRepository {
+	return &deadLetterRepository{db: r.db.WithContext(ctx)}
+}
+
+// Create stores dead letters in batches
+func (r *deadLetterRepository) Create(letters []model.DeadLetter) error {
+	if len(letters) == 0 {
+		return nil
+	}
+	return r.db.CreateInBatches(letters, deadLetterBatchSize).Error
+}
+
+// List returns up to limit of the farm's dead letters with the status, oldest first
+func (r *deadLetterRepository) List(farmID uint, status string, limit int) ([]model.DeadLetter, error) {
+	var letters []model.DeadLetter
+	err := r.db.
+		Where("farm_id = ? AND status = ?", farmID, status).
+		Order("id ASC").
+		Limit(limit).
+		Find(&letters).Error
+	if err != nil {
+		return nil, err
+	}
+	return letters, nil
+}
+
+// Get fetches a dead letter of the farm, returning nil when it doesn't exist
+func (r *deadLetterRepository) Get(farmID, letterID uint) (*model.DeadLetter, error) {
+	var letter model.DeadLetter
+	err := r.db.Where("farm_id = ? AND id = ?", farmID, letterID).First(&letter).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &letter, nil
+}
+
+// Update saves a dead letter's payload, error, status and attempts
+func (r *deadLetterRepository) Update(letter *model.DeadLetter) error {
+	return r.db.Save(letter).Error
+}
+
+// Replay stores the event rebuilt from a dead letter and marks the letter replayed in one
+// transaction, so a letter is never replayed twice
+func (r *deadLetterRepository) Replay(letter *model.DeadLetter, event *model.IrrigationData) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(event).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		result := tx.Model(&model.DeadLetter{}).
+			Where("id = ? AND status = ?", letter.ID, model.DeadLetterPending).
+			Updates(map[string]interface{}{
+				"status":      model.DeadLetterReplayed,
+				"event_id":    event.ID,
+				"payload":     letter.Payload,
+				"attempts":    letter.Attempts,
+				"resolved_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrDeadLetterResolved
+		}
+		letter.Status, letter.EventID, letter.ResolvedAt = model.DeadLetterReplayed, &event.ID, &now
+		return nil
+	})
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"irrigation-analytics/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// DeviceRepository defines the interface for the device registry
+type DeviceRepository interface {
+	Create(device *model.Device) error
+	List(farmID uint, sectorID *uint) ([]model.Device, error)
+	Get(farmID, deviceID uint) (*model.Device, error)
+	Update(device *model.Device) error
+	Delete(farmID, deviceID uint) (bool, error)
+	WithContext(ctx context.Context) DeviceRepository
+}
+
+// deviceRepository implements DeviceRepository
+type deviceRepository struct {
+	db *gorm.DB
+}
+
+// NewDeviceRepository creates a new device repository
+func NewDeviceRepository(db *gorm.DB) DeviceRepository {
+	return &deviceRepository{db: db}
+}
+
+// WithContext returns a repository whose statements run under ctx, so they are cancelled with it
+func (r *deviceRepository) WithContext(ctx context.Context) DeviceRepository {
+	return &deviceRepository{db: r.db.WithContext(ctx)}
+}
+
+// Create stores a new device
+func (r *deviceRepository) Create(device *model.Device) error {
+	return r.db.Create(device).Error
+}
+
+// List returns the farm's devices ordered by ID, limited to one sector's when sectorID is set
+func (r *deviceRepository) List(farmID uint, sectorID *uint) ([]model.Device, error) {
+	query := r.db.Where("farm_id = ?", farmID)
+	if sectorID != nil {
+		query = query.Where("irrigation_sector_id = ?", *sectorID)
+	}
+	var devices []model.Device
+	if err := query.Order("id ASC").Find(&devices).Error; err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// Get returns a device of the farm, or nil when the farm has no such device
+func (r *deviceRepository) Get(farmID, deviceID uint) (*model.Device, error) {
+	var device model.Device
+	err := r.db.Where("farm_id = ?", farmID).First(&device, deviceID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// Update saves a device's sector, kind, name, serial number and note
+func (r *deviceRepository) Update(device *model.Device) error {
+	return r.db.Model(device).
+		Select("irrigation_sector_id", "kind", "name", "serial_number", "note").
+		Updates(device).Error
+}
+
+// Delete soft-deletes a device of the farm, reporting whether it existed. Events keep their
+// device_id, so they can still be traced to the removed meter.
+func (r *deviceRepository) Delete(farmID, deviceID uint) (bool, error) {
+	result := r.db.Where("farm_id = ?", farmID).Delete(&model.Device{}, deviceID)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
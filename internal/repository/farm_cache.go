@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"irrigation-analytics/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// DefaultFarmCacheTTL is how long a farm existence lookup is reused before hitting the database again
+const DefaultFarmCacheTTL = 30 * time.Second
+
+// farmCacheEntry holds a cached farm existence result
+type farmCacheEntry struct {
+	exists    bool
+	expiresAt time.Time
+}
+
+// FarmCache caches farm existence lookups for a short TTL
+type FarmCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	entries map[uint]farmCacheEntry
+	now     func() time.Time
+	hits    atomic.Uint64
+	misses  atomic.Uint64
+}
+
+// CachedFarm describes one cache entry for inspection
+type CachedFarm struct {
+	FarmID    uint      `json:"farm_id"`
+	Exists    bool      `json:"exists"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Expired   bool      `json:"expired"`
+}
+
+// FarmCacheStats reports cache size and lookup counters since startup
+type FarmCacheStats struct {
+	Entries    int     `json:"entries"`
+	Hits       uint64  `json:"hits"`
+	Misses     uint64  `json:"misses"`
+	HitRate    float64 `json:"hit_rate"`
+	TTLSeconds float64 `json:"ttl_seconds"`
+}
+
+// NewFarmCache creates a farm existence cache with the given TTL
+func NewFarmCache(ttl time.Duration) *FarmCache {
+	return &FarmCache{
+		ttl:     ttl,
+		entries: make(map[uint]farmCacheEntry),
+		now:     time.Now,
+	}
+}
+
+// Get returns the cached existence for a farm and whether a live entry was found
+func (c *FarmCache) Get(farmID uint) (exists bool, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, found := c.entries[farmID]
+	if !found || !c.now().Before(entry.expiresAt) {
+		c.misses.Add(1)
+		return false, false
+	}
+	c.hits.Add(1)
+	return entry.exists, true
+}
+
+// Set stores the existence of a farm until the TTL elapses
+func (c *FarmCache) Set(farmID uint, exists bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[farmID] = farmCacheEntry{
+		exists:    exists,
+		expiresAt: c.now().Add(c.ttl),
+	}
+}
+
+// Invalidate removes the cached entry for a single farm, reporting whether one was cached
+func (c *FarmCache) Invalidate(farmID uint) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, found := c.entries[farmID]
+	delete(c.entries, farmID)
+	return found
+}
+
+// Flush removes all cached entries
+func (c *FarmCache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[uint]farmCacheEntry)
+}
+
+// Entries returns every cached farm ordered by ID, including expired entries not yet overwritten
+func (c *FarmCache) Entries() []CachedFarm {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := c.now()
+	entries := make([]CachedFarm, 0, len(c.entries))
+	for farmID, entry := range c.entries {
+		entries = append(entries, CachedFarm{
+			FarmID:    farmID,
+			Exists:    entry.exists,
+			ExpiresAt: entry.expiresAt,
+			Expired:   !now.Before(entry.expiresAt),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].FarmID < entries[j].FarmID })
+	return entries
+}
+
+// Stats returns the cache size and hit/miss counters
+func (c *FarmCache) Stats() FarmCacheStats {
+	c.mu.RLock()
+	size := len(c.entries)
+	c.mu.RUnlock()
+
+	stats := FarmCacheStats{
+		Entries:    size,
+		Hits:       c.hits.Load(),
+		Misses:     c.misses.Load(),
+		TTLSeconds: c.ttl.Seconds(),
+	}
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRate = math.Round(float64(stats.Hits)/float64(lookups)*10000) / 10000
+	}
+	return stats
+}
+
+// RegisterInvalidation flushes the cache whenever farms are created or deleted through GORM.
+// Farm writes are rare, so flushing everything is cheaper than tracking which IDs changed.
+func (c *FarmCache) RegisterInvalidation(db *gorm.DB) error {
+	invalidate := func(tx *gorm.DB) {
+		if tx.Error == nil && tx.Statement.Schema != nil && tx.Statement.Schema.Table == (model.Farm{}).TableName() {
+			c.Flush()
+		}
+	}
+
+	if err := db.Callback().Create().After("gorm:create").Register("farm_cache:invalidate_on_create", invalidate); err != nil {
+		return err
+	}
+	return db.Callback().Delete().After("gorm:delete").Register("farm_cache:invalidate_on_delete", invalidate)
+}
+
+// cachedIrrigationRepository serves FarmExists from a FarmCache and delegates everything else
+type cachedIrrigationRepository struct {
+	IrrigationRepository
+	cache *FarmCache
+}
+
+// NewCachedIrrigationRepository wraps a repository so farm existence checks are cached
+func NewCachedIrrigationRepository(repo IrrigationRepository, cache *FarmCache) IrrigationRepository {
+	return &cachedIrrigationRepository{
+		IrrigationRepository: repo,
+		cache:                cache,
+	}
+}
+
+// FarmExists checks the cache before querying the underlying repository
+func (r *cachedIrrigationRepository) FarmExists(farmID uint) (bool, error) {
+	if exists, ok := r.cache.Get(farmID); ok {
+		return exists, nil
+	}
+
+	exists, err := r.IrrigationRepository.FarmExists(farmID)
+	if err != nil {
+		// Errors are not cached so a transient DB failure doesn't stick
+		return false, err
+	}
+
+	r.cache.Set(farmID, exists)
+	return exists, nil
+}
+
+// WithCapture keeps the cache in front of a repository whose statements are recorded by capture
+func (r *cachedIrrigationRepository) WithCapture(capture *QueryCapture) IrrigationRepository {
+	return &cachedIrrigationRepository{
+		IrrigationRepository: r.IrrigationRepository.WithCapture(capture),
+		cache:                r.cache,
+	}
+}
+
+// WithContext keeps the cache in front of a repository whose statements run under ctx
+func (r *cachedIrrigationRepository) WithContext(ctx context.Context) IrrigationRepository {
+	return &cachedIrrigationRepository{
+		IrrigationRepository: r.IrrigationRepository.WithContext(ctx),
+		cache:                r.cache,
+	}
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// countingRepository counts FarmExists calls that reach the database layer
+type countingRepository struct {
+	IrrigationRepository
+	calls  int
+	exists bool
+	err    error
+}
+
+func (r *countingRepository) FarmExists(farmID uint) (bool, error) {
+	r.calls++
+	return r.exists, r.err
+}
+
+func TestCachedIrrigationRepository_FarmExists(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := NewFarmCache(30 * time.Second)
+	cache.now = func() time.Time { return now }
+
+	inner := &countingRepository{exists: true}
+	repo := NewCachedIrrigationRepository(inner, cache)
+
+	for i := 0; i < 3; i++ {
+		exists, err := repo.FarmExists(1)
+		if err != nil || !exists {
+			t.Fatalf("expected farm to exist, got exists=%v err=%v", exists, err)
+		}
+	}
+	if inner.calls != 1 {
+		t.Errorf("expected 1 database lookup within TTL, got %d", inner.calls)
+	}
+
+	// After the TTL the lookup goes back to the database
+	now = now.Add(31 * time.Second)
+	repo.FarmExists(1)
+	if inner.calls != 2 {
+		t.Errorf("expected lookup after TTL expiry, got %d calls", inner.calls)
+	}
+
+	// Invalidation forces a fresh lookup
+	cache.Invalidate(1)
+	repo.FarmExists(1)
+	if inner.calls != 3 {
+		t.Errorf("expected lookup after invalidation, got %d calls", inner.calls)
+	}
+}
+
+func TestCachedIrrigationRepository_ErrorsNotCached(t *testing.T) {
+	cache := NewFarmCache(time.Minute)
+	inner := &countingRepository{err: errors.New("connection refused")}
+	repo := NewCachedIrrigationRepository(inner, cache)
+
+	if _, err := repo.FarmExists(1); err == nil {
+		t.Fatal("expected error from underlying repository")
+	}
+
+	inner.err = nil
+	inner.exists = true
+	exists, err := repo.FarmExists(1)
+	if err != nil || !exists {
+		t.Errorf("expected fresh lookup after error, got exists=%v err=%v", exists, err)
+	}
+	if inner.calls != 2 {
+		t.Errorf("expected 2 lookups, got %d", inner.calls)
+	}
+}
+
+func TestFarmCache_StatsAndEntries(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := NewFarmCache(30 * time.Second)
+	cache.now = func() time.Time { return now }
+
+	cache.Set(2, true)
+	now = now.Add(20 * time.Second)
+	cache.Set(1, false)
+	cache.Get(1)
+	cache.Get(3)
+	now = now.Add(15 * time.Second)
+	cache.Get(2)
+
+	stats := cache.Stats()
+	if stats.Entries != 2 || stats.Hits != 1 || stats.Misses != 2 || stats.HitRate != 0.3333 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	entries := cache.Entries()
+	if len(entries) != 2 || entries[0].FarmID != 1 || entries[0].Expired || !entries[1].Expired {
+		t.Errorf("unexpected entries: %+v", entries)
+	}
+
+	if !cache.Invalidate(1) || cache.Invalidate(1) {
+		t.Error("expected Invalidate to report whether an entry was removed")
+	}
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"irrigation-analytics/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// FarmRepository defines the interface for farm setup operations
+type FarmRepository interface {
+	CreateFarmWithSectors(farm *model.Farm) error
+	GetFarmWithSectors(farmID uint) (*model.Farm, error)
+	SetArchived(farmID uint, archivedAt *time.Time) (*model.Farm, error)
+	SetAttributionPolicy(farmID uint, policy string) (*model.Farm, error)
+	SetWaterAccount(farmID uint, account string) (*model.Farm, error)
+	SetCropYearStart(farmID uint, start string) (*model.Farm, error)
+}
+
+// farmRepository implements FarmRepository
+type farmRepository struct {
+	db    *gorm.DB
+	cache *FarmCache
+}
+
+// NewFarmRepository creates a new farm repository. cache may be nil; when set, created farms
+// are recorded as existing so a lookup cached before creation doesn't keep reporting them missing.
+func NewFarmRepository(db *gorm.DB, cache *FarmCache) FarmRepository {
+	return &farmRepository{db: db, cache: cache}
+}
+
+// CreateFarmWithSectors inserts the farm and its IrrigationSectors in one transaction, so a
+// failed sector insert leaves no farm behind
+func (r *farmRepository) CreateFarmWithSectors(farm *model.Farm) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		sectors := farm.IrrigationSectors
+		farm.IrrigationSectors = nil
+		if err := tx.Create(farm).Error; err != nil {
+			return err
+		}
+		setSectorFarm(sectors, farm.ID)
+		farm.IrrigationSectors = sectors
+		if len(sectors) == 0 {
+			return nil
+		}
+		return tx.Create(&farm.IrrigationSectors).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if r.cache != nil {
+		r.cache.Set(farm.ID, true)
+	}
+	return nil
+}
+
+// setSectorFarm sets the farm of the sectors and, recursively, of their zones, which are created
+// with their parent sector
+func setSectorFarm(sectors []model.IrrigationSector, farmID uint) {
+	for i := range sectors {
+		sectors[i].FarmID = farmID
+		setSectorFarm(sectors[i].Zones, farmID)
+	}
+}
+
+// GetFarmWithSectors loads a farm and all its sectors, zones included, ordered by ID, returning nil
+// when it doesn't exist
+func (r *farmRepository) GetFarmWithSectors(farmID uint) (*model.Farm, error) {
+	var farm model.Farm
+	err := r.db.
+		Preload("IrrigationSectors", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		First(&farm, farmID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &farm, nil
+}
+
+// SetArchived archives the farm at archivedAt, keeping the original time when it is already
+// archived, or unarchives it when archivedAt is nil. It returns the updated farm without its
+// sectors, or nil when it doesn't exist.
+func (r *farmRepository) SetArchived(farmID uint, archivedAt *time.Time) (*model.Farm, error) {
+	var value interface{}
+	if archivedAt != nil {
+		value = gorm.Expr("COALESCE(archived_at, ?)", *archivedAt)
+	}
+
+	return r.updateFarm(farmID, "archived_at", value)
+}
+
+// SetAttributionPolicy stores the farm's event attribution policy. It returns the updated farm
+// without its sectors, or nil when it doesn't exist.
+func (r *farmRepository) SetAttributionPolicy(farmID uint, policy string) (*model.Farm, error) {
+	return r.updateFarm(farmID, "attribution_policy", policy)
+}
+
+// SetWaterAccount stores the farm's irrigation district account; an empty account clears it. It
+// returns the updated farm without its sectors, or nil when it doesn't exist.
+func (r *farmRepository) SetWaterAccount(farmID uint, account string) (*model.Farm, error) {
+	return r.updateFarm(farmID, "water_account", &model.Farm{WaterAccount: account})
+}
+
+// SetCropYearStart stores the MM-DD start of the farm's crop year; an empty start resets it to
+// 1 January. It returns the updated farm without its sectors, or nil when it doesn't exist.
+func (r *farmRepository) SetCropYearStart(farmID uint, start string) (*model.Farm, error) {
+	return r.updateFarm(farmID, "crop_year_start", start)
+}
+
+// updateFarm sets one column of the farm and reloads it, returning nil when it doesn't exist.
+// value is the column's value, or a *model.Farm holding it: encrypted columns must be updated
+// from the struct, since GORM only applies their serializer to struct fields.
+func (r *farmRepository) updateFarm(farmID uint, column string, value interface{}) (*model.Farm, error) {
+	query := r.db.Model(&model.Farm{}).Where("id = ?", farmID)
+	var result *gorm.DB
+	if farm, ok := value.(*model.Farm); ok {
+		result = query.Select(column).Updates(farm)
+	} else {
+		result = query.Update(column, value)
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	var farm model.Farm
+	if err := r.db.First(&farm, farmID).Error; err != nil {
+		return nil, err
+	}
+	return &farm, nil
+}
@@ -0,0 +1,421 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"reflect"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"irrigation-analytics/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ErrInjectedFault is returned by calls failed on purpose by a FaultInjector
+var ErrInjectedFault = errors.New("injected fault")
+
+// FaultTargetDB targets every statement executed through the database handle the injector's
+// callbacks are registered on, whichever repository runs it
+const FaultTargetDB = "db"
+
+// FaultRule adds latency and random failures to one target: an IrrigationRepository method
+// name, or FaultTargetDB
+type FaultRule struct {
+	Target    string    `json:"target"`
+	LatencyMs int       `json:"latency_ms"`
+	ErrorRate float64   `json:"error_rate"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// Delayed and Failed count the calls the rule has slowed down and failed
+	Delayed uint64 `json:"delayed"`
+	Failed  uint64 `json:"failed"`
+}
+
+// faultRule is a FaultRule with its counters
+type faultRule struct {
+	latency   time.Duration
+	errorRate float64
+	expiresAt time.Time
+	delayed   atomic.Uint64
+	failed    atomic.Uint64
+}
+
+// FaultInjector holds the fault rules applied by NewFaultInjectingIrrigationRepository and
+// RegisterStatementFaults. Rules expire on their own so a forgotten one can't degrade staging
+// for good.
+type FaultInjector struct {
+	mu     sync.RWMutex
+	rules  map[string]*faultRule
+	now    func() time.Time
+	random func() float64
+}
+
+// NewFaultInjector creates an injector with no rules
+func NewFaultInjector() *FaultInjector {
+	return &FaultInjector{
+		rules:  make(map[string]*faultRule),
+		now:    time.Now,
+		random: rand.Float64,
+	}
+}
+
+// FaultTargets returns the targets rules can be set on: every IrrigationRepository method
+// except WithCapture and WithContext, then FaultTargetDB
+func FaultTargets() []string {
+	repoType := reflect.TypeOf((*IrrigationRepository)(nil)).Elem()
+	targets := make([]string, 0, repoType.NumMethod())
+	for i := 0; i < repoType.NumMethod(); i++ {
+		if name := repoType.Method(i).Name; name != "WithCapture" && name != "WithContext" {
+			targets = append(targets, name)
+		}
+	}
+	return append(targets, FaultTargetDB)
+}
+
+// IsFaultTarget reports whether target is one of FaultTargets
+func IsFaultTarget(target string) bool {
+	return slices.Contains(FaultTargets(), target)
+}
+
+// SetRule replaces the rule of a target, resetting its counters. The rule lasts for ttl.
+func (f *FaultInjector) SetRule(target string, latency time.Duration, errorRate float64, ttl time.Duration) FaultRule {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	rule := &faultRule{
+		latency:   latency,
+		errorRate: errorRate,
+		expiresAt: f.now().Add(ttl),
+	}
+	f.rules[target] = rule
+	return rule.snapshot(target)
+}
+
+// DeleteRule removes the rule of a target, reporting whether one was set
+func (f *FaultInjector) DeleteRule(target string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, found := f.rules[target]
+	delete(f.rules, target)
+	return found
+}
+
+// Clear removes every rule
+func (f *FaultInjector) Clear() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = make(map[string]*faultRule)
+}
+
+// Rules returns the live rules ordered by target, dropping the expired ones
+func (f *FaultInjector) Rules() []FaultRule {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now()
+	rules := make([]FaultRule, 0, len(f.rules))
+	for target, rule := range f.rules {
+		if !now.Before(rule.expiresAt) {
+			delete(f.rules, target)
+			continue
+		}
+		rules = append(rules, rule.snapshot(target))
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Target < rules[j].Target })
+	return rules
+}
+
+// snapshot copies the rule and its counters for reporting
+func (r *faultRule) snapshot(target string) FaultRule {
+	return FaultRule{
+		Target:    target,
+		LatencyMs: int(r.latency.Milliseconds()),
+		ErrorRate: r.errorRate,
+		ExpiresAt: r.expiresAt,
+		Delayed:   r.delayed.Load(),
+		Failed:    r.failed.Load(),
+	}
+}
+
+// inject applies the live rule of target, if any: it waits out the latency, returning the
+// context's error if ctx ends first, then fails with ErrInjectedFault at the rule's error rate
+func (f *FaultInjector) inject(ctx context.Context, target string) error {
+	f.mu.RLock()
+	rule, found := f.rules[target]
+	live := found && f.now().Before(rule.expiresAt)
+	f.mu.RUnlock()
+	if !live {
+		return nil
+	}
+
+	if rule.latency > 0 {
+		rule.delayed.Add(1)
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		timer := time.NewTimer(rule.latency)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+	if rule.errorRate > 0 && f.random() < rule.errorRate {
+		rule.failed.Add(1)
+		return ErrInjectedFault
+	}
+	return nil
+}
+
+// RegisterStatementFaults applies the FaultTargetDB rule to every statement executed through db,
+// before it reaches the database. Failed statements carry ErrInjectedFault like a driver error.
+// Register it after RegisterQueryTimeout so the injected latency counts against the query timeout.
+func (f *FaultInjector) RegisterStatementFaults(db *gorm.DB) error {
+	before := func(tx *gorm.DB) {
+		if err := f.inject(tx.Statement.Context, FaultTargetDB); err != nil {
+			_ = tx.AddError(err)
+		}
+	}
+
+	callbacks := db.Callback()
+	registrations := []func() error{
+		func() error { return callbacks.Query().Before("gorm:query").Register("fault_injection:query", before) },
+		func() error {
+			return callbacks.Create().Before("gorm:create").Register("fault_injection:create", before)
+		},
+		func() error {
+			return callbacks.Update().Before("gorm:update").Register("fault_injection:update", before)
+		},
+		func() error {
+			return callbacks.Delete().Before("gorm:delete").Register("fault_injection:delete", before)
+		},
+		func() error { return callbacks.Row().Before("gorm:row").Register("fault_injection:row", before) },
+		func() error { return callbacks.Raw().Before("gorm:raw").Register("fault_injection:raw", before) },
+	}
+	for _, register := range registrations {
+		if err := register(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// faultInjectingIrrigationRepository applies the injector's rules to every call before
+// delegating. Each method is its own target, so a single query can be failed to exercise one
+// degradation path.
+type faultInjectingIrrigationRepository struct {
+	IrrigationRepository
+	faults *FaultInjector
+	// ctx bounds injected latency like the statements of the wrapped repository
+	ctx context.Context
+}
+
+// NewFaultInjectingIrrigationRepository wraps a repository so its calls are slowed down or
+// failed by the injector's rules. It is meant for staging only.
+func NewFaultInjectingIrrigationRepository(repo IrrigationRepository, faults *FaultInjector) IrrigationRepository {
+	return &faultInjectingIrrigationRepository{
+		IrrigationRepository: repo,
+		faults:               faults,
+		ctx:                  context.Background(),
+	}
+}
+
+// WithCapture keeps the faults in front of a repository whose statements are recorded by capture
+func (r *faultInjectingIrrigationRepository) WithCapture(capture *QueryCapture) IrrigationRepository {
+	return &faultInjectingIrrigationRepository{
+		IrrigationRepository: r.IrrigationRepository.WithCapture(capture),
+		faults:               r.faults,
+		ctx:                  r.ctx,
+	}
+}
+
+// WithContext keeps the faults in front of a repository whose statements run under ctx, and
+// bounds injected latency by ctx too
+func (r *faultInjectingIrrigationRepository) WithContext(ctx context.Context) IrrigationRepository {
+	return &faultInjectingIrrigationRepository{
+		IrrigationRepository: r.IrrigationRepository.WithContext(ctx),
+		faults:               r.faults,
+		ctx:                  ctx,
+	}
+}
+
+// FarmExists applies the FarmExists rule before delegating
+func (r *faultInjectingIrrigationRepository) FarmExists(farmID uint) (bool, error) {
+	if err := r.faults.inject(r.ctx, "FarmExists"); err != nil {
+		return false, err
+	}
+	return r.IrrigationRepository.FarmExists(farmID)
+}
+
+// GetEvent applies the GetEvent rule before delegating
+func (r *faultInjectingIrrigationRepository) GetEvent(farmID, eventID uint) (*model.IrrigationData, error) {
+	if err := r.faults.inject(r.ctx, "GetEvent"); err != nil {
+		return nil, err
+	}
+	return r.IrrigationRepository.GetEvent(farmID, eventID)
+}
+
+// ListSectors applies the ListSectors rule before delegating
+func (r *faultInjectingIrrigationRepository) ListSectors(farmID uint) ([]model.IrrigationSector, error) {
+	if err := r.faults.inject(r.ctx, "ListSectors"); err != nil {
+		return nil, err
+	}
+	return r.IrrigationRepository.ListSectors(farmID)
+}
+
+// GetAttributionPolicy applies the GetAttributionPolicy rule before delegating
+func (r *faultInjectingIrrigationRepository) GetAttributionPolicy(farmID uint) (string, error) {
+	if err := r.faults.inject(r.ctx, "GetAttributionPolicy"); err != nil {
+		return "", err
+	}
+	return r.IrrigationRepository.GetAttributionPolicy(farmID)
+}
+
+// GetCropYear applies the GetCropYear rule before delegating
+func (r *faultInjectingIrrigationRepository) GetCropYear(farmID uint) (model.CropYear, error) {
+	if err := r.faults.inject(r.ctx, "GetCropYear"); err != nil {
+		return model.CropYear{}, err
+	}
+	return r.IrrigationRepository.GetCropYear(farmID)
+}
+
+// GetRolledUpThrough applies the GetRolledUpThrough rule before delegating
+func (r *faultInjectingIrrigationRepository) GetRolledUpThrough(farmID uint) (*time.Time, error) {
+	if err := r.faults.inject(r.ctx, "GetRolledUpThrough"); err != nil {
+		return nil, err
+	}
+	return r.IrrigationRepository.GetRolledUpThrough(farmID)
+}
+
+// GetAggregatedData applies the GetAggregatedData rule before delegating
+func (r *faultInjectingIrrigationRepository) GetAggregatedData(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string, opts QueryOptions) ([]AggregatedDataWithCount, error) {
+	if err := r.faults.inject(r.ctx, "GetAggregatedData"); err != nil {
+		return nil, err
+	}
+	return r.IrrigationRepository.GetAggregatedData(farmID, sectorID, startDate, endDate, aggregation, opts)
+}
+
+// GetYearOverYearData applies the GetYearOverYearData rule before delegating
+func (r *faultInjectingIrrigationRepository) GetYearOverYearData(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string, yearsBack int, opts QueryOptions) ([]AggregatedDataWithCount, error) {
+	if err := r.faults.inject(r.ctx, "GetYearOverYearData"); err != nil {
+		return nil, err
+	}
+	return r.IrrigationRepository.GetYearOverYearData(farmID, sectorID, startDate, endDate, aggregation, yearsBack, opts)
+}
+
+// GetComparisonData applies the GetComparisonData rule before delegating
+func (r *faultInjectingIrrigationRepository) GetComparisonData(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string, yearsBack []int, opts QueryOptions) (map[int][]AggregatedDataWithCount, error) {
+	if err := r.faults.inject(r.ctx, "GetComparisonData"); err != nil {
+		return nil, err
+	}
+	return r.IrrigationRepository.GetComparisonData(farmID, sectorID, startDate, endDate, aggregation, yearsBack, opts)
+}
+
+// GetRangesData applies the GetRangesData rule before delegating
+func (r *faultInjectingIrrigationRepository) GetRangesData(farmID uint, sectorID *uint, ranges []DateRange, aggregation string, opts QueryOptions) ([][]AggregatedDataWithCount, error) {
+	if err := r.faults.inject(r.ctx, "GetRangesData"); err != nil {
+		return nil, err
+	}
+	return r.IrrigationRepository.GetRangesData(farmID, sectorID, ranges, aggregation, opts)
+}
+
+// GetSummaryData applies the GetSummaryData rule before delegating
+func (r *faultInjectingIrrigationRepository) GetSummaryData(farmID uint, sectorID *uint, startDate, endDate time.Time, opts QueryOptions) (*SummaryResult, error) {
+	if err := r.faults.inject(r.ctx, "GetSummaryData"); err != nil {
+		return nil, err
+	}
+	return r.IrrigationRepository.GetSummaryData(farmID, sectorID, startDate, endDate, opts)
+}
+
+// GetSectorComparisonData applies the GetSectorComparisonData rule before delegating
+func (r *faultInjectingIrrigationRepository) GetSectorComparisonData(farmID uint, startDate, endDate time.Time, yearsBack []int, opts QueryOptions) (map[int][]AggregatedDataWithCount, error) {
+	if err := r.faults.inject(r.ctx, "GetSectorComparisonData"); err != nil {
+		return nil, err
+	}
+	return r.IrrigationRepository.GetSectorComparisonData(farmID, startDate, endDate, yearsBack, opts)
+}
+
+// GetSourceTotals applies the GetSourceTotals rule before delegating
+func (r *faultInjectingIrrigationRepository) GetSourceTotals(includeArchived bool) ([]SourceTotal, error) {
+	if err := r.faults.inject(r.ctx, "GetSourceTotals"); err != nil {
+		return nil, err
+	}
+	return r.IrrigationRepository.GetSourceTotals(includeArchived)
+}
+
+// GetPurposeTotals applies the GetPurposeTotals rule before delegating
+func (r *faultInjectingIrrigationRepository) GetPurposeTotals(farmID uint, sectorID *uint, startDate, endDate time.Time, opts QueryOptions) ([]PurposeTotal, error) {
+	if err := r.faults.inject(r.ctx, "GetPurposeTotals"); err != nil {
+		return nil, err
+	}
+	return r.IrrigationRepository.GetPurposeTotals(farmID, sectorID, startDate, endDate, opts)
+}
+
+// GetDistributionData applies the GetDistributionData rule before delegating
+func (r *faultInjectingIrrigationRepository) GetDistributionData(farmID uint, sectorID *uint, startDate, endDate time.Time, opts QueryOptions) (*DistributionResult, error) {
+	if err := r.faults.inject(r.ctx, "GetDistributionData"); err != nil {
+		return nil, err
+	}
+	return r.IrrigationRepository.GetDistributionData(farmID, sectorID, startDate, endDate, opts)
+}
+
+// GetHourOfDayTotals applies the GetHourOfDayTotals rule before delegating
+func (r *faultInjectingIrrigationRepository) GetHourOfDayTotals(farmID uint, startDate, endDate time.Time, opts QueryOptions) ([]HourOfDayTotal, error) {
+	if err := r.faults.inject(r.ctx, "GetHourOfDayTotals"); err != nil {
+		return nil, err
+	}
+	return r.IrrigationRepository.GetHourOfDayTotals(farmID, startDate, endDate, opts)
+}
+
+// ListEvents applies the ListEvents rule before delegating
+func (r *faultInjectingIrrigationRepository) ListEvents(farmID uint, startDate, endDate time.Time, limit int, opts QueryOptions) ([]model.IrrigationData, error) {
+	if err := r.faults.inject(r.ctx, "ListEvents"); err != nil {
+		return nil, err
+	}
+	return r.IrrigationRepository.ListEvents(farmID, startDate, endDate, limit, opts)
+}
+
+// ListEventPage applies the ListEventPage rule before delegating
+func (r *faultInjectingIrrigationRepository) ListEventPage(farmID uint, query EventPageQuery) ([]model.IrrigationData, int64, error) {
+	if err := r.faults.inject(r.ctx, "ListEventPage"); err != nil {
+		return nil, 0, err
+	}
+	return r.IrrigationRepository.ListEventPage(farmID, query)
+}
+
+// ListOverlappingEvents applies the ListOverlappingEvents rule before delegating
+func (r *faultInjectingIrrigationRepository) ListOverlappingEvents(farmID uint, sectorID *uint, startDate, endDate time.Time, limit int) ([]model.IrrigationData, error) {
+	if err := r.faults.inject(r.ctx, "ListOverlappingEvents"); err != nil {
+		return nil, err
+	}
+	return r.IrrigationRepository.ListOverlappingEvents(farmID, sectorID, startDate, endDate, limit)
+}
+
+// MergeEvents applies the MergeEvents rule before delegating
+func (r *faultInjectingIrrigationRepository) MergeEvents(farmID uint, merges []EventMerge) error {
+	if err := r.faults.inject(r.ctx, "MergeEvents"); err != nil {
+		return err
+	}
+	return r.IrrigationRepository.MergeEvents(farmID, merges)
+}
+
+// UpdateEventPurpose applies the UpdateEventPurpose rule before delegating
+func (r *faultInjectingIrrigationRepository) UpdateEventPurpose(farmID, eventID uint, purpose string) (bool, error) {
+	if err := r.faults.inject(r.ctx, "UpdateEventPurpose"); err != nil {
+		return false, err
+	}
+	return r.IrrigationRepository.UpdateEventPurpose(farmID, eventID, purpose)
+}
+
+// RecomputeDurations applies the RecomputeDurations rule before delegating
+func (r *faultInjectingIrrigationRepository) RecomputeDurations(farmID uint, startDate, endDate time.Time, apply bool) ([]DurationCorrection, int, error) {
+	if err := r.faults.inject(r.ctx, "RecomputeDurations"); err != nil {
+		return nil, 0, err
+	}
+	return r.IrrigationRepository.RecomputeDurations(farmID, startDate, endDate, apply)
+}
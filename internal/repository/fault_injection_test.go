@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// contextRepository is a countingRepository that can be bound to a context
+type contextRepository struct {
+	countingRepository
+}
+
+func (r *contextRepository) WithContext(ctx context.Context) IrrigationRepository {
+	return r
+}
+
+func TestFaultInjectingIrrigationRepository(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	faults := NewFaultInjector()
+	faults.now = func() time.Time { return now }
+	faults.random = func() float64 { return 0.5 }
+
+	inner := &countingRepository{exists: true}
+	repo := NewFaultInjectingIrrigationRepository(inner, faults)
+
+	// Below the random draw of 0.5 the call goes through, at or above it fails
+	faults.SetRule("FarmExists", 0, 0.4, time.Minute)
+	if exists, err := repo.FarmExists(1); err != nil || !exists || inner.calls != 1 {
+		t.Fatalf("expected the call to go through, got exists=%v err=%v calls=%d", exists, err, inner.calls)
+	}
+	faults.SetRule("FarmExists", 0, 0.6, time.Minute)
+	if _, err := repo.FarmExists(1); !errors.Is(err, ErrInjectedFault) || inner.calls != 1 {
+		t.Fatalf("expected an injected fault before the database, got err=%v calls=%d", err, inner.calls)
+	}
+
+	rules := faults.Rules()
+	if len(rules) != 1 || rules[0].Target != "FarmExists" || rules[0].Failed != 1 || !rules[0].ExpiresAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("unexpected rules %+v", rules)
+	}
+
+	// Expired rules stop applying and are dropped from the list
+	now = now.Add(time.Minute)
+	if _, err := repo.FarmExists(1); err != nil || inner.calls != 2 {
+		t.Errorf("expected the expired rule ignored, got err=%v calls=%d", err, inner.calls)
+	}
+	if rules := faults.Rules(); len(rules) != 0 {
+		t.Errorf("expected no live rules, got %+v", rules)
+	}
+}
+
+func TestFaultInjectingIrrigationRepository_LatencyFollowsContext(t *testing.T) {
+	faults := NewFaultInjector()
+	faults.SetRule("FarmExists", time.Hour, 0, time.Minute)
+
+	inner := &contextRepository{countingRepository{exists: true}}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	repo := NewFaultInjectingIrrigationRepository(inner, faults).WithContext(ctx)
+
+	if _, err := repo.FarmExists(1); !errors.Is(err, context.DeadlineExceeded) || inner.calls != 0 {
+		t.Errorf("expected the injected latency cut short by the request deadline, got err=%v calls=%d", err, inner.calls)
+	}
+	if rules := faults.Rules(); rules[0].Delayed != 1 {
+		t.Errorf("expected one delayed call, got %+v", rules[0])
+	}
+}
+
+func TestFaultTargets(t *testing.T) {
+	targets := FaultTargets()
+	if !IsFaultTarget("GetDistributionData") || !IsFaultTarget(FaultTargetDB) || IsFaultTarget("WithContext") {
+		t.Errorf("unexpected targets %v", targets)
+	}
+}
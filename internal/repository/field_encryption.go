@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"sync"
+
+	"irrigation-analytics/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// reencryptBatchSize is how many rows one re-encryption transaction rewrites
+const reencryptBatchSize = 500
+
+// EncryptedColumnStatus counts the stored values of one encrypted column by how they are stored.
+// Empty and NULL values are not counted.
+type EncryptedColumnStatus struct {
+	Table     string `json:"table"`
+	Column    string `json:"column"`
+	Plaintext int64  `json:"plaintext"`
+	// ByKey counts the values encrypted with each key ID
+	ByKey map[string]int64 `json:"by_key"`
+}
+
+// ReencryptionResult reports the rows rewritten with the primary field key
+type ReencryptionResult struct {
+	KeyID   string `json:"key_id"`
+	Farms   int    `json:"farms"`
+	Sectors int    `json:"sectors"`
+}
+
+// FieldEncryptionRepository inspects and rewrites the encrypted columns of stored rows
+type FieldEncryptionRepository interface {
+	ColumnStatus() ([]EncryptedColumnStatus, error)
+	Reencrypt(keyID string) (*ReencryptionResult, error)
+}
+
+// fieldEncryptionRepository implements FieldEncryptionRepository
+type fieldEncryptionRepository struct {
+	db *gorm.DB
+}
+
+// NewFieldEncryptionRepository creates a new field encryption repository
+func NewFieldEncryptionRepository(db *gorm.DB) FieldEncryptionRepository {
+	return &fieldEncryptionRepository{db: db}
+}
+
+// encryptedModels are the models with encrypted columns
+var encryptedModels = []interface{}{&model.Farm{}, &model.IrrigationSector{}}
+
+// schemaCache caches the schemas parsed by encryptedColumns
+var schemaCache sync.Map
+
+// encryptedColumns returns the table of a model and its columns using the encrypted serializer
+func (r *fieldEncryptionRepository) encryptedColumns(value interface{}) (string, []string, error) {
+	sch, err := schema.Parse(value, &schemaCache, r.db.NamingStrategy)
+	if err != nil {
+		return "", nil, err
+	}
+	var columns []string
+	for _, field := range sch.Fields {
+		if field.TagSettings["SERIALIZER"] == model.EncryptedSerializerName {
+			columns = append(columns, field.DBName)
+		}
+	}
+	return sch.Table, columns, nil
+}
+
+// ColumnStatus counts every encrypted column's values in plaintext and under each key, soft-deleted
+// rows included. A key can be dropped once no column has values under it.
+func (r *fieldEncryptionRepository) ColumnStatus() ([]EncryptedColumnStatus, error) {
+	var statuses []EncryptedColumnStatus
+	for _, value := range encryptedModels {
+		table, columns, err := r.encryptedColumns(value)
+		if err != nil {
+			return nil, err
+		}
+		for _, column := range columns {
+			var rows []struct {
+				KeyID string
+				Count int64
+			}
+			// Ciphertexts are enc:v1:<key id>:<data>; everything else is plaintext
+			err := r.db.Raw(`
+			SELECT CASE WHEN ` + column + ` LIKE 'enc:v1:%' THEN split_part(` + column + `, ':', 3) ELSE '' END AS key_id,
+				COUNT(*) AS count
+			FROM ` + table + `
+			WHERE ` + column + ` IS NOT NULL AND ` + column + ` <> ''
+			GROUP BY 1`).Scan(&rows).Error
+			if err != nil {
+				return nil, err
+			}
+
+			status := EncryptedColumnStatus{Table: table, Column: column, ByKey: make(map[string]int64)}
+			for _, row := range rows {
+				if row.KeyID == "" {
+					status.Plaintext = row.Count
+				} else {
+					status.ByKey[row.KeyID] = row.Count
+				}
+			}
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses, nil
+}
+
+// Reencrypt reads every farm and sector, soft-deleted ones included, and writes their encrypted
+// columns back, so plaintext values and values under older keys end up under the primary key,
+// keyID. Each batch is rewritten in one transaction; updated_at is left alone.
+func (r *fieldEncryptionRepository) Reencrypt(keyID string) (*ReencryptionResult, error) {
+	result := &ReencryptionResult{KeyID: keyID}
+
+	_, farmColumns, err := r.encryptedColumns(&model.Farm{})
+	if err != nil {
+		return nil, err
+	}
+	var farms []model.Farm
+	err = r.db.Unscoped().Select(append([]string{"id"}, farmColumns...)).FindInBatches(&farms, reencryptBatchSize, func(_ *gorm.DB, _ int) error {
+		return r.db.Transaction(func(tx *gorm.DB) error {
+			for i := range farms {
+				if err := tx.Unscoped().Model(&farms[i]).Select(farmColumns).UpdateColumns(&farms[i]).Error; err != nil {
+					return err
+				}
+			}
+			result.Farms += len(farms)
+			return nil
+		})
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	_, sectorColumns, err := r.encryptedColumns(&model.IrrigationSector{})
+	if err != nil {
+		return nil, err
+	}
+	var sectors []model.IrrigationSector
+	err = r.db.Unscoped().Select(append([]string{"id"}, sectorColumns...)).FindInBatches(&sectors, reencryptBatchSize, func(_ *gorm.DB, _ int) error {
+		return r.db.Transaction(func(tx *gorm.DB) error {
+			for i := range sectors {
+				if err := tx.Unscoped().Model(&sectors[i]).Select(sectorColumns).UpdateColumns(&sectors[i]).Error; err != nil {
+					return err
+				}
+			}
+			result.Sectors += len(sectors)
+			return nil
+		})
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
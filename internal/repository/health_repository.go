@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// HealthRepository defines the interface for database readiness checks
+type HealthRepository interface {
+	Ping(ctx context.Context) error
+	MissingSchema(ctx context.Context, models []interface{}) ([]string, error)
+}
+
+// healthRepository implements HealthRepository
+type healthRepository struct {
+	db *gorm.DB
+}
+
+// NewHealthRepository creates a new health repository
+func NewHealthRepository(db *gorm.DB) HealthRepository {
+	return &healthRepository{db: db}
+}
+
+// Ping checks that a database connection can be established and answers
+func (r *healthRepository) Ping(ctx context.Context) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// MissingSchema returns the tables, and the table.column pairs, the models need but the database
+// doesn't have; an empty result means the migrations are applied
+func (r *healthRepository) MissingSchema(ctx context.Context, models []interface{}) ([]string, error) {
+	db := r.db.WithContext(ctx)
+	migrator := db.Migrator()
+
+	missing := []string{}
+	for _, m := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(m); err != nil {
+			return nil, err
+		}
+		table := stmt.Schema.Table
+		if !migrator.HasTable(m) {
+			missing = append(missing, table)
+			continue
+		}
+
+		columnTypes, err := migrator.ColumnTypes(m)
+		if err != nil {
+			return nil, err
+		}
+		columns := make(map[string]bool, len(columnTypes))
+		for _, column := range columnTypes {
+			columns[column.Name()] = true
+		}
+		for _, name := range stmt.Schema.DBNames {
+			if !columns[name] {
+				missing = append(missing, table+"."+name)
+			}
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"irrigation-analytics/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IdempotencyRepository defines the interface for stored idempotency keys
+type IdempotencyRepository interface {
+	Reserve(key *model.IdempotencyKey, now time.Time, lease time.Duration) (*model.IdempotencyKey, error)
+	Complete(key *model.IdempotencyKey) error
+	Release(key *model.IdempotencyKey) error
+	DeleteExpired(now time.Time) (int64, error)
+	WithContext(ctx context.Context) IdempotencyRepository
+}
+
+// idempotencyRepository implements IdempotencyRepository
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+// NewIdempotencyRepository creates a new idempotency key repository
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+// WithContext returns a repository whose statements run under ctx, so they are cancelled with it
+func (r *idempotencyRepository) WithContext(ctx context.Context) IdempotencyRepository {
+	return &idempotencyRepository{db: r.db.WithContext(ctx)}
+}
+
+// Reserve stores key unless the farm already holds an unexpired key with the same scope and
+// value, in which case that key is returned instead and nothing is stored. An expired key is
+// replaced, and so is an in-progress key reserved more than lease ago, since the request that
+// reserved it has most likely died before completing or releasing it.
+func (r *idempotencyRepository) Reserve(key *model.IdempotencyKey, now time.Time, lease time.Duration) (*model.IdempotencyKey, error) {
+	var existing *model.IdempotencyKey
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("farm_id = ? AND scope = ? AND key = ?", key.FarmID, key.Scope, key.Key).
+			Where("(expires_at <= ? OR (status_code = 0 AND reserved_at <= ?))", now, now.Add(-lease)).
+			Delete(&model.IdempotencyKey{}).Error
+		if err != nil {
+			return err
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(key)
+		if result.Error != nil || result.RowsAffected > 0 {
+			return result.Error
+		}
+
+		var stored model.IdempotencyKey
+		if err := tx.Where("farm_id = ? AND scope = ? AND key = ?", key.FarmID, key.Scope, key.Key).First(&stored).Error; err != nil {
+			return err
+		}
+		existing = &stored
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// Complete saves the key's status code and response
+func (r *idempotencyRepository) Complete(key *model.IdempotencyKey) error {
+	return r.db.Model(key).Select("status_code", "response").Updates(key).Error
+}
+
+// Release deletes a key, so the request can be retried with it
+func (r *idempotencyRepository) Release(key *model.IdempotencyKey) error {
+	return r.db.Delete(&model.IdempotencyKey{}, key.ID).Error
+}
+
+// DeleteExpired removes the keys that expired by now, returning how many were removed
+func (r *idempotencyRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at <= ?", now).Delete(&model.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
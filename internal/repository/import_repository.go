@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"irrigation-analytics/internal/model"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+// importRowSavepoint is the savepoint taken before each row insert within a chunk
+const importRowSavepoint = "import_row"
+
+// errDryRunRollback aborts a trial insert transaction once every row has been attempted
+var errDryRunRollback = errors.New("dry run rollback")
+
+// ImportRepository defines the interface for bulk import operations
+type ImportRepository interface {
+	CreateJob(job *model.ImportJob) error
+	GetJob(farmID, jobID uint) (*model.ImportJob, error)
+	UpdateJob(job *model.ImportJob) error
+	CommitChunk(job *model.ImportJob, events []model.IrrigationData, processedRows, invalidRows, duplicateRows int) (map[int]error, error)
+	TrialInsert(events []model.IrrigationData) (map[int]error, error)
+	ExistingExternalIDs(farmID uint, externalIDs []string) (map[string]bool, error)
+}
+
+// importRepository implements ImportRepository
+type importRepository struct {
+	db *gorm.DB
+}
+
+// NewImportRepository creates a new import repository
+func NewImportRepository(db *gorm.DB) ImportRepository {
+	return &importRepository{db: db}
+}
+
+// CreateJob stores a new import job
+func (r *importRepository) CreateJob(job *model.ImportJob) error {
+	return r.db.Create(job).Error
+}
+
+// GetJob fetches an import job of the farm, returning nil when it doesn't exist
+func (r *importRepository) GetJob(farmID, jobID uint) (*model.ImportJob, error) {
+	var job model.ImportJob
+	err := r.db.Where("farm_id = ? AND id = ?", farmID, jobID).First(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// UpdateJob saves the job's status and counters
+func (r *importRepository) UpdateJob(job *model.ImportJob) error {
+	return r.db.Save(job).Error
+}
+
+// CommitChunk inserts a chunk of events and advances the job's progress in one transaction,
+// so progress never runs ahead of or behind the inserted rows. The chunk is first written with
+// a single COPY. If the database rejects any of its rows, the chunk is inserted again row by
+// row, each under a savepoint: a rejected row is rolled back alone and reported by its index in
+// events, while the rest of the chunk commits. invalidRows counts rows of the chunk rejected
+// before reaching the database, and duplicateRows those skipped as already stored. On error
+// nothing is written and job is unchanged.
+//
+// Events written by COPY keep an ID of 0.
+func (r *importRepository) CommitChunk(job *model.ImportJob, events []model.IrrigationData, processedRows, invalidRows, duplicateRows int) (map[int]error, error) {
+	if len(events) > 0 {
+		err := r.copyChunk(job, events, processedRows, invalidRows, duplicateRows)
+		if err == nil {
+			return map[int]error{}, nil
+		}
+		if !isDataError(err) && !errors.Is(err, errCopyUnsupported) {
+			return nil, err
+		}
+	}
+	return r.insertChunk(job, events, processedRows, invalidRows, duplicateRows)
+}
+
+// copyChunk writes the whole chunk with COPY and advances the job in one transaction, failing
+// if any row is rejected
+func (r *importRepository) copyChunk(job *model.ImportJob, events []model.IrrigationData, processedRows, invalidRows, duplicateRows int) error {
+	table, err := irrigationCopyTable(r.db)
+	if err != nil {
+		return err
+	}
+	updated := *job
+	updated.ProcessedRows = processedRows
+	updated.ImportedRows += len(events)
+	updated.RejectedRows += invalidRows
+	updated.DuplicateRows += duplicateRows
+	updated.UpdatedAt = time.Now()
+
+	err = withPgxConn(r.db, func(ctx context.Context, conn *pgx.Conn) error {
+		rows, err := table.rows(ctx, r.db, events, updated.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			if _, err := tx.CopyFrom(ctx, pgx.Identifier{table.name}, table.columns, pgx.CopyFromRows(rows)); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx,
+				"UPDATE import_jobs SET processed_rows = $1, imported_rows = $2, rejected_rows = $3, duplicate_rows = $4, updated_at = $5 WHERE id = $6",
+				updated.ProcessedRows, updated.ImportedRows, updated.RejectedRows, updated.DuplicateRows, updated.UpdatedAt, updated.ID)
+			return err
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	*job = updated
+	return nil
+}
+
+// insertChunk inserts the chunk row by row, each under a savepoint, and advances the job in one
+// transaction
+func (r *importRepository) insertChunk(job *model.ImportJob, events []model.IrrigationData, processedRows, invalidRows, duplicateRows int) (map[int]error, error) {
+	rejected := make(map[int]error)
+	updated := *job
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for i := range events {
+			if err := tx.SavePoint(importRowSavepoint).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&events[i]).Error; err != nil {
+				if rbErr := tx.RollbackTo(importRowSavepoint).Error; rbErr != nil {
+					return rbErr
+				}
+				rejected[i] = err
+			}
+		}
+
+		updated.ProcessedRows = processedRows
+		updated.ImportedRows += len(events) - len(rejected)
+		updated.RejectedRows += len(rejected) + invalidRows
+		updated.DuplicateRows += duplicateRows
+		return tx.Save(&updated).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	*job = updated
+	return rejected, nil
+}
+
+// TrialInsert inserts events the same way as CommitChunk inside a transaction that is always
+// rolled back, reporting which rows the database would reject without writing anything
+func (r *importRepository) TrialInsert(events []model.IrrigationData) (map[int]error, error) {
+	rejected := make(map[int]error)
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for i := range events {
+			if err := tx.SavePoint(importRowSavepoint).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&events[i]).Error; err != nil {
+				if rbErr := tx.RollbackTo(importRowSavepoint).Error; rbErr != nil {
+					return rbErr
+				}
+				rejected[i] = err
+			}
+		}
+		return errDryRunRollback
+	})
+	if err != nil && !errors.Is(err, errDryRunRollback) {
+		return nil, err
+	}
+
+	return rejected, nil
+}
+
+// ExistingExternalIDs returns which of the external IDs the farm's stored events already use,
+// including soft-deleted events, which still hold their ID
+func (r *importRepository) ExistingExternalIDs(farmID uint, externalIDs []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	if len(externalIDs) == 0 {
+		return existing, nil
+	}
+	var found []string
+	err := r.db.Unscoped().Model(&model.IrrigationData{}).
+		Where("farm_id = ? AND external_id IN ?", farmID, externalIDs).
+		Pluck("external_id", &found).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range found {
+		existing[id] = true
+	}
+	return existing, nil
+}
@@ -1,6 +1,12 @@
 package repository
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"irrigation-analytics/internal/model"
@@ -10,27 +16,199 @@ import (
 
 // AggregatedResult represents the result of an aggregation query
 type AggregatedResult struct {
-	StartTime          time.Time `gorm:"column:start_time"`
-	WaterVolume        float64   `gorm:"column:water_volume"`
-	Duration           int       `gorm:"column:duration"`
-	FarmID             uint      `gorm:"column:farm_id"`
+	StartTime           time.Time `gorm:"column:start_time"`
+	WaterVolume         float64   `gorm:"column:water_volume"`
+	Duration            int       `gorm:"column:duration"`
+	FarmID              uint      `gorm:"column:farm_id"`
+	IrrigationSectorID  uint      `gorm:"column:irrigation_sector_id"`
+	EventCount          int       `gorm:"column:event_count"`
+	NominalAmount       float64   `gorm:"column:nominal_amount"`
+	RealAmount          float64   `gorm:"column:real_amount"`
+	MissingNominalCount int       `gorm:"column:missing_nominal_count"`
+	ExcludedEventCount  int       `gorm:"column:excluded_event_count"`
+	YearsBack           int       `gorm:"column:years_back"`
+}
+
+// SummaryResult represents the single-row totals of a summary query
+type SummaryResult struct {
+	WaterVolume         float64 `gorm:"column:water_volume"`
+	Duration            int     `gorm:"column:duration"`
+	EventCount          int     `gorm:"column:event_count"`
+	NominalAmount       float64 `gorm:"column:nominal_amount"`
+	RealAmount          float64 `gorm:"column:real_amount"`
+	MissingNominalCount int     `gorm:"column:missing_nominal_count"`
+	ExcludedEventCount  int     `gorm:"column:excluded_event_count"`
+}
+
+// DistributionResult holds per-event distribution statistics for a period. A metric's columns are
+// nil when no event contributes to it.
+type DistributionResult struct {
+	VolumeMin        *float64 `gorm:"column:volume_min"`
+	VolumeMedian     *float64 `gorm:"column:volume_median"`
+	VolumeP90        *float64 `gorm:"column:volume_p90"`
+	VolumeP95        *float64 `gorm:"column:volume_p95"`
+	VolumeMax        *float64 `gorm:"column:volume_max"`
+	VolumeStdDev     *float64 `gorm:"column:volume_stddev"`
+	DurationMin      *float64 `gorm:"column:duration_min"`
+	DurationMedian   *float64 `gorm:"column:duration_median"`
+	DurationP90      *float64 `gorm:"column:duration_p90"`
+	DurationP95      *float64 `gorm:"column:duration_p95"`
+	DurationMax      *float64 `gorm:"column:duration_max"`
+	DurationStdDev   *float64 `gorm:"column:duration_stddev"`
+	EfficiencyMin    *float64 `gorm:"column:efficiency_min"`
+	EfficiencyMedian *float64 `gorm:"column:efficiency_median"`
+	EfficiencyP90    *float64 `gorm:"column:efficiency_p90"`
+	EfficiencyP95    *float64 `gorm:"column:efficiency_p95"`
+	EfficiencyMax    *float64 `gorm:"column:efficiency_max"`
+	EfficiencyStdDev *float64 `gorm:"column:efficiency_stddev"`
+}
+
+// DurationCorrection is an event whose stored duration disagrees with its start and end times
+type DurationCorrection struct {
+	ID                 uint      `gorm:"column:id"`
 	IrrigationSectorID uint      `gorm:"column:irrigation_sector_id"`
-	EventCount         int       `gorm:"column:event_count"`
-	NominalAmount      float64   `gorm:"column:nominal_amount"`
-	RealAmount         float64   `gorm:"column:real_amount"`
+	StartTime          time.Time `gorm:"column:start_time"`
+	OldDuration        int       `gorm:"column:old_duration"`
+	NewDuration        int       `gorm:"column:new_duration"`
+}
+
+// SourceTotal holds event count and volume for one data_source
+type SourceTotal struct {
+	DataSource     string    `gorm:"column:data_source"`
+	EventCount     int       `gorm:"column:event_count"`
+	WaterVolume    float64   `gorm:"column:water_volume"`
+	LastIngestedAt time.Time `gorm:"column:last_ingested_at"`
+}
+
+// PurposeTotal holds period totals for one event purpose
+type PurposeTotal struct {
+	Purpose       string `gorm:"column:purpose"`
+	SummaryResult `gorm:"embedded"`
+}
+
+// HourOfDayTotal holds one sector's totals for events starting in one hour of the day (UTC),
+// summed over every day of a range
+type HourOfDayTotal struct {
+	SectorID      uint `gorm:"column:irrigation_sector_id"`
+	Hour          int  `gorm:"column:hour"`
+	SummaryResult `gorm:"embedded"`
+}
+
+// EventMerge folds overlapping events into one: Survivor is stored with its merged times and
+// amounts, and the events in RemovedIDs are soft-deleted
+type EventMerge struct {
+	Survivor   model.IrrigationData
+	RemovedIDs []uint
+}
+
+// EventSortColumns are the columns a page of events can be sorted by
+var EventSortColumns = []string{"start_time", "end_time", "water_volume", "duration", "real_amount"}
+
+// EventPageQuery selects and orders a page of a farm's events
+type EventPageQuery struct {
+	SectorID           *uint
+	StartDate, EndDate time.Time
+	// MinVolume and MaxVolume bound water_volume inclusively when set
+	MinVolume, MaxVolume *float64
+	// Sort is one of EventSortColumns; ties are broken by id in the same direction
+	Sort       string
+	Descending bool
+	Limit      int
+	Offset     int
+	Options    QueryOptions
+}
+
+// DateRange is a range of event times, from Start up to End
+type DateRange struct {
+	Start time.Time
+	End   time.Time
 }
 
 // AggregatedDataWithCount wraps IrrigationData with event count
 type AggregatedDataWithCount struct {
 	Data       model.IrrigationData
 	EventCount int
+	// MissingNominalCount is the number of events in the bucket without a recorded nominal_amount
+	MissingNominalCount int
+	// ExcludedEventCount is the number of events left out of the bucket's nominal and real amounts
+	ExcludedEventCount int
+}
+
+// QueryOptions controls which events contribute to the aggregated amounts
+type QueryOptions struct {
+	// ExcludeAnnotated leaves events covered by an annotation flagged exclude_from_efficiency
+	// out of the nominal and real amounts, while still counting their volume and duration
+	ExcludeAnnotated bool
+	// ExcludeSeed drops events with data_source "seed" from the query entirely
+	ExcludeSeed bool
+	// Purposes limits the query to events with one of these purposes; empty means all purposes
+	Purposes []string
+	// SplitEvents spreads each event's volume, duration and amounts over the days (hours for
+	// hourly aggregation) it spans, in proportion to the time spent in each, instead of
+	// attributing them to the bucket it starts in. Events are still counted once, where they start.
+	// Only bucketed queries are split; period totals are the same either way.
+	SplitEvents bool
+	// Attribution is the farm's model.Attribution* policy; empty means AttributionStart.
+	// AttributionEnd selects and buckets events by end_time, AttributionProportional splits
+	// them as SplitEvents does.
+	Attribution string
+	// DeviceID limits the query to events measured by this device; nil means every event
+	DeviceID *uint
+	// IncludeDeleted keeps soft-deleted events in the query, for audits; by default they are
+	// left out as they are from GORM reads
+	IncludeDeleted bool
+	// CropYear, when set, places the periods years back at the same position in an earlier crop
+	// year (see model.CropYear.ShiftYears) instead of on the same calendar dates
+	CropYear *model.CropYear
+}
+
+// YearsBack returns the period [startDate, endDate) years back, by calendar date or by crop year
+func (o QueryOptions) YearsBack(startDate, endDate time.Time, years int) (time.Time, time.Time) {
+	if o.CropYear != nil {
+		return o.CropYear.ShiftYears(startDate, endDate, years)
+	}
+	return startDate.AddDate(-years, 0, 0), endDate.AddDate(-years, 0, 0)
+}
+
+// eventTime is the column that places an event in the range and in its bucket
+func (o QueryOptions) eventTime() string {
+	if o.Attribution == model.AttributionEnd {
+		return "end_time"
+	}
+	return "start_time"
+}
+
+// split reports whether bucketed queries spread events over the buckets they span
+func (o QueryOptions) split() bool {
+	return o.SplitEvents || o.Attribution == model.AttributionProportional
 }
 
 // IrrigationRepository defines the interface for irrigation data operations
 type IrrigationRepository interface {
 	FarmExists(farmID uint) (bool, error)
-	GetAggregatedData(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string) ([]AggregatedDataWithCount, error)
-	GetYearOverYearData(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string, yearsBack int) ([]AggregatedDataWithCount, error)
+	GetEvent(farmID, eventID uint) (*model.IrrigationData, error)
+	ListSectors(farmID uint) ([]model.IrrigationSector, error)
+	GetAttributionPolicy(farmID uint) (string, error)
+	GetCropYear(farmID uint) (model.CropYear, error)
+	GetRolledUpThrough(farmID uint) (*time.Time, error)
+	GetAggregatedData(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string, opts QueryOptions) ([]AggregatedDataWithCount, error)
+	GetYearOverYearData(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string, yearsBack int, opts QueryOptions) ([]AggregatedDataWithCount, error)
+	GetComparisonData(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string, yearsBack []int, opts QueryOptions) (map[int][]AggregatedDataWithCount, error)
+	GetRangesData(farmID uint, sectorID *uint, ranges []DateRange, aggregation string, opts QueryOptions) ([][]AggregatedDataWithCount, error)
+	GetSummaryData(farmID uint, sectorID *uint, startDate, endDate time.Time, opts QueryOptions) (*SummaryResult, error)
+	GetSectorComparisonData(farmID uint, startDate, endDate time.Time, yearsBack []int, opts QueryOptions) (map[int][]AggregatedDataWithCount, error)
+	GetSourceTotals(includeArchived bool) ([]SourceTotal, error)
+	GetPurposeTotals(farmID uint, sectorID *uint, startDate, endDate time.Time, opts QueryOptions) ([]PurposeTotal, error)
+	GetDistributionData(farmID uint, sectorID *uint, startDate, endDate time.Time, opts QueryOptions) (*DistributionResult, error)
+	GetHourOfDayTotals(farmID uint, startDate, endDate time.Time, opts QueryOptions) ([]HourOfDayTotal, error)
+	ListEvents(farmID uint, startDate, endDate time.Time, limit int, opts QueryOptions) ([]model.IrrigationData, error)
+	ListEventPage(farmID uint, query EventPageQuery) ([]model.IrrigationData, int64, error)
+	ListOverlappingEvents(farmID uint, sectorID *uint, startDate, endDate time.Time, limit int) ([]model.IrrigationData, error)
+	MergeEvents(farmID uint, merges []EventMerge) error
+	UpdateEventPurpose(farmID, eventID uint, purpose string) (bool, error)
+	RecomputeDurations(farmID uint, startDate, endDate time.Time, apply bool) ([]DurationCorrection, int, error)
+	WithCapture(capture *QueryCapture) IrrigationRepository
+	WithContext(ctx context.Context) IrrigationRepository
 }
 
 // irrigationRepository implements IrrigationRepository
@@ -43,6 +221,16 @@ func NewIrrigationRepository(db *gorm.DB) IrrigationRepository {
 	return &irrigationRepository{db: db}
 }
 
+// WithCapture returns a repository whose statements are recorded by capture
+func (r *irrigationRepository) WithCapture(capture *QueryCapture) IrrigationRepository {
+	return &irrigationRepository{db: capture.session(r.db)}
+}
+
+// WithContext returns a repository whose statements run under ctx, so they are cancelled with it
+func (r *irrigationRepository) WithContext(ctx context.Context) IrrigationRepository {
+	return &irrigationRepository{db: r.db.WithContext(ctx)}
+}
+
 // FarmExists checks if a farm with the given ID exists
 func (r *irrigationRepository) FarmExists(farmID uint) (bool, error) {
 	var count int64
@@ -53,205 +241,781 @@ func (r *irrigationRepository) FarmExists(farmID uint) (bool, error) {
 	return count > 0, nil
 }
 
+// GetEvent fetches a single irrigation event of the farm, returning nil when it doesn't exist
+func (r *irrigationRepository) GetEvent(farmID, eventID uint) (*model.IrrigationData, error) {
+	var event model.IrrigationData
+	err := r.db.Where("farm_id = ? AND id = ?", farmID, eventID).First(&event).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// ListSectors fetches the farm's irrigation sectors ordered by ID
+func (r *irrigationRepository) ListSectors(farmID uint) ([]model.IrrigationSector, error) {
+	var sectors []model.IrrigationSector
+	err := r.db.Where("farm_id = ?", farmID).Order("id ASC").Find(&sectors).Error
+	if err != nil {
+		return nil, err
+	}
+	return sectors, nil
+}
+
+// GetAttributionPolicy fetches the farm's event attribution policy, or an empty string when the
+// farm doesn't exist
+func (r *irrigationRepository) GetAttributionPolicy(farmID uint) (string, error) {
+	var policies []string
+	err := r.db.Model(&model.Farm{}).Where("id = ?", farmID).Pluck("attribution_policy", &policies).Error
+	if err != nil || len(policies) == 0 {
+		return "", err
+	}
+	return policies[0], nil
+}
+
+// GetCropYear fetches the start of the farm's crop year; farms without one, or missing, get the
+// zero CropYear starting on 1 January
+func (r *irrigationRepository) GetCropYear(farmID uint) (model.CropYear, error) {
+	var starts []string
+	err := r.db.Model(&model.Farm{}).Where("id = ?", farmID).Pluck("crop_year_start", &starts).Error
+	if err != nil || len(starts) == 0 {
+		return model.CropYear{}, err
+	}
+	return model.ParseCropYear(starts[0])
+}
+
+// GetRolledUpThrough returns the farm's rollup watermark: days before it are rolled up and no
+// longer change. It is nil when the farm's events were never rolled up.
+func (r *irrigationRepository) GetRolledUpThrough(farmID uint) (*time.Time, error) {
+	var states []model.IrrigationRollupState
+	if err := r.db.Where("farm_id = ?", farmID).Limit(1).Find(&states).Error; err != nil {
+		return nil, err
+	}
+	if len(states) == 0 {
+		return nil, nil
+	}
+	return &states[0].RolledUpThrough, nil
+}
+
 // GetAggregatedData fetches irrigation data with efficient SQL grouping
-func (r *irrigationRepository) GetAggregatedData(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string) ([]AggregatedDataWithCount, error) {
+func (r *irrigationRepository) GetAggregatedData(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string, opts QueryOptions) ([]AggregatedDataWithCount, error) {
 	var results []AggregatedResult
-	var modelResults []AggregatedDataWithCount
 
-	// Build base query
-	baseQuery := "farm_id = ? AND start_time >= ? AND start_time < ?"
-	args := []interface{}{farmID, startDate, endDate}
+	query, args := bucketedQuery(farmID, sectorID, startDate, endDate, aggregation, "", opts)
+	sqlQuery := query + `
+			ORDER BY start_time ASC`
 
-	if sectorID != nil {
-		baseQuery += " AND irrigation_sector_id = ?"
-		args = append(args, *sectorID)
+	err := r.db.Raw(sqlQuery, args...).Scan(&results).Error
+	if err != nil {
+		return nil, err
 	}
 
-	// Build aggregation query based on level
-	var sqlQuery string
-	switch aggregation {
-	case "daily":
-		sqlQuery = `
-			SELECT 
-				DATE(start_time)::timestamp as start_time,
-				SUM(water_volume) as water_volume,
-				SUM(duration) as duration,
-				COUNT(*) as event_count,
-				SUM(nominal_amount) as nominal_amount,
-				SUM(real_amount) as real_amount,
-				farm_id,
-				COALESCE(irrigation_sector_id, 0) as irrigation_sector_id
+	return toAggregatedData(results), nil
+}
+
+// GetYearOverYearData fetches data from the same period N years back
+func (r *irrigationRepository) GetYearOverYearData(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string, yearsBack int, opts QueryOptions) ([]AggregatedDataWithCount, error) {
+	// Calculate the date range for the previous year(s)
+	yearStart, yearEnd := opts.YearsBack(startDate, endDate, yearsBack)
+
+	return r.GetAggregatedData(farmID, sectorID, yearStart, yearEnd, aggregation, opts)
+}
+
+// GetComparisonData fetches the current period and each of the given years back in a single
+// UNION ALL statement. Results are keyed by years back, with 0 being the current period.
+func (r *irrigationRepository) GetComparisonData(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string, yearsBack []int, opts QueryOptions) (map[int][]AggregatedDataWithCount, error) {
+	var results []AggregatedResult
+
+	offsets := append([]int{0}, yearsBack...)
+	parts := make([]string, 0, len(offsets))
+	args := []interface{}{}
+
+	for _, offset := range offsets {
+		partStart, partEnd := opts.YearsBack(startDate, endDate, offset)
+		part, partArgs := bucketedQuery(farmID, sectorID, partStart, partEnd, aggregation, fmt.Sprintf("%d as years_back,", offset), opts)
+		parts = append(parts, part)
+		args = append(args, partArgs...)
+	}
+
+	sqlQuery := strings.Join(parts, `
+			UNION ALL`) + `
+			ORDER BY years_back ASC, start_time ASC`
+
+	err := r.db.Raw(sqlQuery, args...).Scan(&results).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return splitByYearsBack(results, offsets), nil
+}
+
+// GetRangesData fetches the buckets of each of the given date ranges in a single UNION ALL
+// statement. Results are in the order of ranges, with an entry for every range.
+func (r *irrigationRepository) GetRangesData(farmID uint, sectorID *uint, ranges []DateRange, aggregation string, opts QueryOptions) ([][]AggregatedDataWithCount, error) {
+	if len(ranges) == 0 {
+		return nil, nil
+	}
+	var results []AggregatedResult
+
+	// Each part is tagged with its range's index in the years_back column
+	offsets := make([]int, 0, len(ranges))
+	parts := make([]string, 0, len(ranges))
+	args := []interface{}{}
+
+	for i, dateRange := range ranges {
+		part, partArgs := bucketedQuery(farmID, sectorID, dateRange.Start, dateRange.End, aggregation, fmt.Sprintf("%d as years_back,", i), opts)
+		offsets = append(offsets, i)
+		parts = append(parts, part)
+		args = append(args, partArgs...)
+	}
+
+	sqlQuery := strings.Join(parts, `
+			UNION ALL`) + `
+			ORDER BY years_back ASC, start_time ASC`
+
+	err := r.db.Raw(sqlQuery, args...).Scan(&results).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byRange := splitByYearsBack(results, offsets)
+	data := make([][]AggregatedDataWithCount, len(ranges))
+	for i := range ranges {
+		data[i] = byRange[i]
+	}
+	return data, nil
+}
+
+// GetSummaryData fetches period totals as a single row without bucketing
+func (r *irrigationRepository) GetSummaryData(farmID uint, sectorID *uint, startDate, endDate time.Time, opts QueryOptions) (*SummaryResult, error) {
+	var result SummaryResult
+
+	whereClause, args := rangeFilter(farmID, sectorID, startDate, endDate, opts)
+	sqlQuery := `
+			SELECT` + totalColumns(opts, false) + `
 			FROM irrigation_data
-			WHERE ` + baseQuery + `
-			GROUP BY DATE(start_time), farm_id, irrigation_sector_id
-			ORDER BY DATE(start_time) ASC`
+			WHERE ` + whereClause
 
-	case "weekly":
-		sqlQuery = `
-			SELECT 
-				DATE_TRUNC('week', start_time) as start_time,
-				SUM(water_volume) as water_volume,
-				SUM(duration) as duration,
-				COUNT(*) as event_count,
-				SUM(nominal_amount) as nominal_amount,
-				SUM(real_amount) as real_amount,
-				farm_id,
-				COALESCE(irrigation_sector_id, 0) as irrigation_sector_id
+	err := r.db.Raw(sqlQuery, args...).Scan(&result).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetSectorComparisonData fetches per-sector totals for the current period and each of the given
+// years back in a single UNION ALL statement. Results are keyed by years back, with 0 being the
+// current period; each entry holds one row per sector with a zero StartTime.
+func (r *irrigationRepository) GetSectorComparisonData(farmID uint, startDate, endDate time.Time, yearsBack []int, opts QueryOptions) (map[int][]AggregatedDataWithCount, error) {
+	var results []AggregatedResult
+
+	offsets := append([]int{0}, yearsBack...)
+	parts := make([]string, 0, len(offsets))
+	args := []interface{}{}
+
+	for _, offset := range offsets {
+		partStart, partEnd := opts.YearsBack(startDate, endDate, offset)
+		whereClause, partArgs := rangeFilter(farmID, nil, partStart, partEnd, opts)
+		parts = append(parts, `
+			SELECT `+fmt.Sprintf("%d as years_back,", offset)+aggregateColumns(opts, false)+`
 			FROM irrigation_data
-			WHERE ` + baseQuery + `
-			GROUP BY DATE_TRUNC('week', start_time), farm_id, irrigation_sector_id
-			ORDER BY DATE_TRUNC('week', start_time) ASC`
+			WHERE `+whereClause+`
+			GROUP BY farm_id, irrigation_sector_id`)
+		args = append(args, partArgs...)
+	}
 
-	case "monthly":
-		sqlQuery = `
-			SELECT 
-				DATE_TRUNC('month', start_time) as start_time,
-				SUM(water_volume) as water_volume,
-				SUM(duration) as duration,
-				COUNT(*) as event_count,
-				SUM(nominal_amount) as nominal_amount,
-				SUM(real_amount) as real_amount,
-				farm_id,
-				COALESCE(irrigation_sector_id, 0) as irrigation_sector_id
+	sqlQuery := strings.Join(parts, `
+			UNION ALL`) + `
+			ORDER BY years_back ASC, irrigation_sector_id ASC`
+
+	err := r.db.Raw(sqlQuery, args...).Scan(&results).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return splitByYearsBack(results, offsets), nil
+}
+
+// GetPurposeTotals fetches period totals grouped by event purpose
+func (r *irrigationRepository) GetPurposeTotals(farmID uint, sectorID *uint, startDate, endDate time.Time, opts QueryOptions) ([]PurposeTotal, error) {
+	var totals []PurposeTotal
+
+	whereClause, args := rangeFilter(farmID, sectorID, startDate, endDate, opts)
+	sqlQuery := `
+			SELECT purpose,` + totalColumns(opts, false) + `
 			FROM irrigation_data
-			WHERE ` + baseQuery + `
-			GROUP BY DATE_TRUNC('month', start_time), farm_id, irrigation_sector_id
-			ORDER BY DATE_TRUNC('month', start_time) ASC`
+			WHERE ` + whereClause + `
+			GROUP BY purpose
+			ORDER BY purpose ASC`
 
-	default:
-		// Default to daily
-		sqlQuery = `
-			SELECT 
-				DATE(start_time)::timestamp as start_time,
-				SUM(water_volume) as water_volume,
-				SUM(duration) as duration,
-				COUNT(*) as event_count,
-				SUM(nominal_amount) as nominal_amount,
-				SUM(real_amount) as real_amount,
-				farm_id,
-				COALESCE(irrigation_sector_id, 0) as irrigation_sector_id
+	err := r.db.Raw(sqlQuery, args...).Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// GetHourOfDayTotals fetches each sector's totals per UTC hour of the day over the range, by
+// the hour events are attributed to; hours without events have no row
+func (r *irrigationRepository) GetHourOfDayTotals(farmID uint, startDate, endDate time.Time, opts QueryOptions) ([]HourOfDayTotal, error) {
+	var totals []HourOfDayTotal
+
+	hour := "EXTRACT(HOUR FROM " + opts.eventTime() + ")::int"
+	whereClause, args := rangeFilter(farmID, nil, startDate, endDate, opts)
+	sqlQuery := `
+			SELECT irrigation_sector_id, ` + hour + ` as hour,` + totalColumns(opts, false) + `
 			FROM irrigation_data
-			WHERE ` + baseQuery + `
-			GROUP BY DATE(start_time), farm_id, irrigation_sector_id
-			ORDER BY DATE(start_time) ASC`
+			WHERE ` + whereClause + `
+			GROUP BY irrigation_sector_id, ` + hour + `
+			ORDER BY irrigation_sector_id ASC, hour ASC`
+
+	err := r.db.Raw(sqlQuery, args...).Scan(&totals).Error
+	if err != nil {
+		return nil, err
 	}
+	return totals, nil
+}
 
-	err := r.db.Raw(sqlQuery, args...).Scan(&results).Error
+// ListEvents fetches up to limit of the farm's events in the range, ordered by start time, with
+// only their sector, times, volume and duration loaded
+func (r *irrigationRepository) ListEvents(farmID uint, startDate, endDate time.Time, limit int, opts QueryOptions) ([]model.IrrigationData, error) {
+	var events []model.IrrigationData
+
+	db := r.db
+	if opts.IncludeDeleted {
+		db = db.Unscoped()
+	}
+
+	whereClause, args := rangeFilter(farmID, nil, startDate, endDate, opts)
+	err := db.Select("id", "farm_id", "irrigation_sector_id", "start_time", "end_time", "water_volume", "duration").
+		Where(whereClause, args...).
+		Order("start_time ASC, id ASC").
+		Limit(limit).
+		Find(&events).Error
 	if err != nil {
 		return nil, err
 	}
+	return events, nil
+}
 
-	// Convert AggregatedResult to AggregatedDataWithCount
-	for _, r := range results {
-		modelResults = append(modelResults, AggregatedDataWithCount{
-			Data: model.IrrigationData{
-				StartTime:          r.StartTime,
-				WaterVolume:        r.WaterVolume,
-				Duration:           r.Duration,
-				FarmID:             r.FarmID,
-				IrrigationSectorID: r.IrrigationSectorID,
-				NominalAmount:      r.NominalAmount,
-				RealAmount:         r.RealAmount,
-			},
-			EventCount: r.EventCount,
-		})
+// ListEventPage fetches one page of the farm's events matching query, selected by the same filter
+// as the aggregation queries, along with the number of matching events across all pages. Raw
+// payloads are left out to keep pages small.
+func (r *irrigationRepository) ListEventPage(farmID uint, query EventPageQuery) ([]model.IrrigationData, int64, error) {
+	if !slices.Contains(EventSortColumns, query.Sort) {
+		return nil, 0, fmt.Errorf("unknown event sort column %q", query.Sort)
+	}
+
+	db := r.db
+	if query.Options.IncludeDeleted {
+		db = db.Unscoped()
 	}
 
-	return modelResults, nil
+	whereClause, args := rangeFilter(farmID, query.SectorID, query.StartDate, query.EndDate, query.Options)
+	if query.MinVolume != nil {
+		whereClause += " AND water_volume >= ?"
+		args = append(args, *query.MinVolume)
+	}
+	if query.MaxVolume != nil {
+		whereClause += " AND water_volume <= ?"
+		args = append(args, *query.MaxVolume)
+	}
+
+	var total int64
+	if err := db.Model(&model.IrrigationData{}).Where(whereClause, args...).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	direction := " ASC"
+	if query.Descending {
+		direction = " DESC"
+	}
+	var events []model.IrrigationData
+	err := db.Omit("raw_payload").
+		Where(whereClause, args...).
+		Order(query.Sort + direction + ", id" + direction).
+		Limit(query.Limit).
+		Offset(query.Offset).
+		Find(&events).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
 }
 
-// GetYearOverYearData fetches data from the same period N years back
-func (r *irrigationRepository) GetYearOverYearData(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string, yearsBack int) ([]AggregatedDataWithCount, error) {
-	var results []AggregatedResult
-	var modelResults []AggregatedDataWithCount
+// GetDistributionData fetches per-event percentiles, extremes and population standard deviation
+// of water volume, duration and efficiency as a single row. Efficiency is real_amount over
+// nominal_amount for events with a positive nominal_amount; with ExcludeAnnotated, annotated
+// events are left out of it as they are from the efficiency totals.
+func (r *irrigationRepository) GetDistributionData(farmID uint, sectorID *uint, startDate, endDate time.Time, opts QueryOptions) (*DistributionResult, error) {
+	var result DistributionResult
 
-	// Calculate the date range for the previous year(s)
-	yearStart := startDate.AddDate(-yearsBack, 0, 0)
-	yearEnd := endDate.AddDate(-yearsBack, 0, 0)
+	efficiencyFilter := "nominal_amount > 0"
+	if opts.ExcludeAnnotated {
+		efficiencyFilter += " AND NOT " + annotatedEventCondition
+	}
 
-	// Build base query
-	baseQuery := "farm_id = ? AND start_time >= ? AND start_time < ?"
-	args := []interface{}{farmID, yearStart, yearEnd}
+	whereClause, args := rangeFilter(farmID, sectorID, startDate, endDate, opts)
+	sqlQuery := `
+			SELECT` + distributionColumns("volume", "water_volume", "") + `,` +
+		distributionColumns("duration", "duration", "") + `,` +
+		distributionColumns("efficiency", "real_amount / nominal_amount", efficiencyFilter) + `
+			FROM irrigation_data
+			WHERE ` + whereClause
 
-	if sectorID != nil {
-		baseQuery += " AND irrigation_sector_id = ?"
-		args = append(args, *sectorID)
+	err := r.db.Raw(sqlQuery, args...).Scan(&result).Error
+	if err != nil {
+		return nil, err
 	}
 
-	// Build aggregation query based on level
-	var sqlQuery string
-	switch aggregation {
-	case "daily":
-		sqlQuery = `
-			SELECT 
-				DATE(start_time)::timestamp as start_time,
-				SUM(water_volume) as water_volume,
-				SUM(duration) as duration,
-				COUNT(*) as event_count,
-				SUM(nominal_amount) as nominal_amount,
-				SUM(real_amount) as real_amount,
-				farm_id,
-				COALESCE(irrigation_sector_id, 0) as irrigation_sector_id
+	return &result, nil
+}
+
+// distributionColumns selects the distribution statistics of expression as prefix_min, prefix_median,
+// prefix_p90, prefix_p95, prefix_max and prefix_stddev, limited to rows matching filter when set
+func distributionColumns(prefix, expression, filter string) string {
+	if filter != "" {
+		filter = " FILTER (WHERE " + filter + ")"
+	}
+	percentile := func(fraction string) string {
+		return "PERCENTILE_CONT(" + fraction + ") WITHIN GROUP (ORDER BY " + expression + ")" + filter
+	}
+	return `
+				MIN(` + expression + `)` + filter + ` as ` + prefix + `_min,
+				` + percentile("0.5") + ` as ` + prefix + `_median,
+				` + percentile("0.9") + ` as ` + prefix + `_p90,
+				` + percentile("0.95") + ` as ` + prefix + `_p95,
+				MAX(` + expression + `)` + filter + ` as ` + prefix + `_max,
+				STDDEV_POP(` + expression + `)` + filter + ` as ` + prefix + `_stddev`
+}
+
+// overlapsAnotherEvent keeps events whose interval overlaps another live event of the same sector
+// starting in the range; intervals that only touch don't overlap. It takes the range twice.
+const overlapsAnotherEvent = `EXISTS (
+			SELECT 1 FROM irrigation_data o
+			WHERE o.farm_id = irrigation_data.farm_id AND o.irrigation_sector_id = irrigation_data.irrigation_sector_id
+				AND o.id <> irrigation_data.id AND o.deleted_at IS NULL AND o.start_time >= ? AND o.start_time < ?
+				AND o.start_time < irrigation_data.end_time AND o.end_time > irrigation_data.start_time)`
+
+// ListOverlappingEvents fetches up to limit of the farm's events starting in the range that overlap
+// another event of their sector starting in the range, ordered by sector, start time and ID. A
+// sector filter includes its zones. Raw payloads are left out.
+func (r *irrigationRepository) ListOverlappingEvents(farmID uint, sectorID *uint, startDate, endDate time.Time, limit int) ([]model.IrrigationData, error) {
+	whereClause, args := rangeFilter(farmID, sectorID, startDate, endDate, QueryOptions{})
+	whereClause += " AND " + overlapsAnotherEvent
+	args = append(args, startDate, endDate)
+
+	var events []model.IrrigationData
+	err := r.db.Omit("raw_payload").
+		Where(whereClause, args...).
+		Order("irrigation_sector_id ASC, start_time ASC, id ASC").
+		Limit(limit).
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// MergeEvents applies the merges in one transaction: each survivor gets its merged end time,
+// duration and amounts, and the other events are soft-deleted, so the rollups rebuild their days
+func (r *irrigationRepository) MergeEvents(farmID uint, merges []EventMerge) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, merge := range merges {
+			survivor := merge.Survivor
+			err := tx.Model(&model.IrrigationData{}).
+				Where("farm_id = ? AND id = ?", farmID, survivor.ID).
+				Updates(map[string]interface{}{
+					"end_time":       survivor.EndTime,
+					"duration":       survivor.Duration,
+					"water_volume":   survivor.WaterVolume,
+					"nominal_amount": survivor.NominalAmount,
+					"real_amount":    survivor.RealAmount,
+				}).Error
+			if err != nil {
+				return err
+			}
+			if err := tx.Where("farm_id = ? AND id IN ?", farmID, merge.RemovedIDs).Delete(&model.IrrigationData{}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UpdateEventPurpose reclassifies an event of the farm, reporting whether it exists
+func (r *irrigationRepository) UpdateEventPurpose(farmID, eventID uint, purpose string) (bool, error) {
+	result := r.db.Model(&model.IrrigationData{}).
+		Where("farm_id = ? AND id = ?", farmID, eventID).
+		Update("purpose", purpose)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// derivedDuration is an event's duration in whole minutes from its start and end times, truncated
+// as IrrigationData.BeforeCreate does
+const derivedDuration = "FLOOR(EXTRACT(EPOCH FROM (end_time - start_time)) / 60)::int"
+
+// RecomputeDurations finds the farm's events in [startDate, endDate) whose duration differs from
+// their start and end times and, when apply is set, overwrites it in one statement. It returns the
+// corrections in start time order and the number of events ending before they start, which
+// cannot be derived and are left alone.
+func (r *irrigationRepository) RecomputeDurations(farmID uint, startDate, endDate time.Time, apply bool) ([]DurationCorrection, int, error) {
+	whereClause, args := rangeFilter(farmID, nil, startDate, endDate, QueryOptions{})
+	candidates := `
+			SELECT id, irrigation_sector_id, start_time, duration as old_duration, ` + derivedDuration + ` as new_duration
 			FROM irrigation_data
-			WHERE ` + baseQuery + `
-			GROUP BY DATE(start_time), farm_id, irrigation_sector_id
-			ORDER BY DATE(start_time) ASC`
+			WHERE ` + whereClause + ` AND end_time >= start_time AND duration <> ` + derivedDuration
 
-	case "weekly":
+	sqlQuery := candidates
+	if apply {
 		sqlQuery = `
-			SELECT 
-				DATE_TRUNC('week', start_time) as start_time,
-				SUM(water_volume) as water_volume,
-				SUM(duration) as duration,
-				COUNT(*) as event_count,
-				SUM(nominal_amount) as nominal_amount,
-				SUM(real_amount) as real_amount,
-				farm_id,
-				COALESCE(irrigation_sector_id, 0) as irrigation_sector_id
+			WITH candidates AS (` + candidates + `)
+			UPDATE irrigation_data SET duration = c.new_duration, updated_at = NOW()
+			FROM candidates c
+			WHERE irrigation_data.id = c.id
+			RETURNING c.id, c.irrigation_sector_id, c.start_time, c.old_duration, c.new_duration`
+	}
+
+	// Count the invalid events first, so a failure leaves nothing rewritten
+	var invalid int64
+	err := r.db.Raw(`
+			SELECT COUNT(*)
 			FROM irrigation_data
-			WHERE ` + baseQuery + `
-			GROUP BY DATE_TRUNC('week', start_time), farm_id, irrigation_sector_id
-			ORDER BY DATE_TRUNC('week', start_time) ASC`
+			WHERE `+whereClause+` AND end_time < start_time`, args...).Scan(&invalid).Error
+	if err != nil {
+		return nil, 0, err
+	}
 
-	case "monthly":
-		sqlQuery = `
-			SELECT 
-				DATE_TRUNC('month', start_time) as start_time,
-				SUM(water_volume) as water_volume,
-				SUM(duration) as duration,
+	var corrections []DurationCorrection
+	if err := r.db.Raw(sqlQuery, args...).Scan(&corrections).Error; err != nil {
+		return nil, 0, err
+	}
+	sort.Slice(corrections, func(i, j int) bool {
+		if !corrections[i].StartTime.Equal(corrections[j].StartTime) {
+			return corrections[i].StartTime.Before(corrections[j].StartTime)
+		}
+		return corrections[i].ID < corrections[j].ID
+	})
+	return corrections, int(invalid), nil
+}
+
+// GetSourceTotals fetches event counts, volumes and latest ingestion time per data_source across
+// all farms, leaving out archived farms unless includeArchived is set
+func (r *irrigationRepository) GetSourceTotals(includeArchived bool) ([]SourceTotal, error) {
+	archivedFilter := ""
+	if !includeArchived {
+		archivedFilter = `
+				AND NOT EXISTS (SELECT 1 FROM farms f WHERE f.id = irrigation_data.farm_id AND f.archived_at IS NOT NULL)`
+	}
+
+	var totals []SourceTotal
+	err := r.db.Raw(`
+			SELECT
+				data_source,
 				COUNT(*) as event_count,
-				SUM(nominal_amount) as nominal_amount,
-				SUM(real_amount) as real_amount,
-				farm_id,
-				COALESCE(irrigation_sector_id, 0) as irrigation_sector_id
+				COALESCE(SUM(water_volume), 0) as water_volume,
+				MAX(created_at) as last_ingested_at
 			FROM irrigation_data
-			WHERE ` + baseQuery + `
-			GROUP BY DATE_TRUNC('month', start_time), farm_id, irrigation_sector_id
-			ORDER BY DATE_TRUNC('month', start_time) ASC`
+			WHERE deleted_at IS NULL` + archivedFilter + `
+			GROUP BY data_source
+			ORDER BY data_source ASC`).Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// MaxCustomBucketDays is the longest custom aggregation bucket
+const MaxCustomBucketDays = 183
+
+// CustomAggregation returns the aggregation key for buckets of days days, e.g. "10d". Custom
+// buckets start on 1 January and every days days after it; each year's last bucket is cut
+// short at 31 December so buckets line up across years.
+func CustomAggregation(days int) string {
+	return fmt.Sprintf("%dd", days)
+}
 
+// ParseCustomAggregation returns the bucket length of a custom aggregation key; ok is false for
+// any other aggregation or a length outside 2 to MaxCustomBucketDays
+func ParseCustomAggregation(aggregation string) (days int, ok bool) {
+	digits, found := strings.CutSuffix(aggregation, "d")
+	if !found {
+		return 0, false
+	}
+	if _, err := fmt.Sscanf(digits, "%d", &days); err != nil || CustomAggregation(days) != aggregation {
+		return 0, false
+	}
+	return days, days >= 2 && days <= MaxCustomBucketDays
+}
+
+// bucketExpression returns the SQL expression truncating the timestamp column to the aggregation bucket
+func bucketExpression(aggregation, column string) string {
+	if days, ok := ParseCustomAggregation(aggregation); ok {
+		return fmt.Sprintf("(DATE_TRUNC('year', %[1]s) + FLOOR((EXTRACT(DOY FROM %[1]s) - 1) / %[2]d) * %[2]d * INTERVAL '1 day')", column, days)
+	}
+	switch aggregation {
+	case "hourly":
+		return "DATE_TRUNC('hour', " + column + ")"
+	case "weekly":
+		return "DATE_TRUNC('week', " + column + ")"
+	case "monthly":
+		return "DATE_TRUNC('month', " + column + ")"
+	case "quarterly":
+		return "DATE_TRUNC('quarter', " + column + ")"
+	case "yearly":
+		return "DATE_TRUNC('year', " + column + ")"
 	default:
-		sqlQuery = `
-			SELECT 
-				DATE(start_time)::timestamp as start_time,
-				SUM(water_volume) as water_volume,
-				SUM(duration) as duration,
-				COUNT(*) as event_count,
-				SUM(nominal_amount) as nominal_amount,
-				SUM(real_amount) as real_amount,
+		// Default to daily
+		return "DATE(" + column + ")::timestamp"
+	}
+}
+
+// rangeFilter builds the WHERE clause for a farm, optional sector, date range and data source.
+// farm_id comes first and start_time second to match the composite indexes. Farms attributing
+// events by end time filter on end_time instead. Soft-deleted events are left out unless
+// opts.IncludeDeleted is set.
+func rangeFilter(farmID uint, sectorID *uint, startDate, endDate time.Time, opts QueryOptions) (string, []interface{}) {
+	whereClause, args := rangeFilterOn(opts.eventTime(), farmID, sectorID, startDate, endDate, opts)
+	if !opts.IncludeDeleted {
+		whereClause += " AND deleted_at IS NULL"
+	}
+	return whereClause, args
+}
+
+// rangeFilterOn builds rangeFilter's clause with the range applied to column, without the
+// soft-delete filter, so it also applies to the daily rollups
+func rangeFilterOn(column string, farmID uint, sectorID *uint, startDate, endDate time.Time, opts QueryOptions) (string, []interface{}) {
+	whereClause := "farm_id = ? AND " + column + " >= ? AND " + column + " < ?"
+	args := []interface{}{farmID, startDate, endDate}
+
+	if sectorID != nil {
+		whereClause += " AND irrigation_sector_id IN (" + sectorSubtreeQuery + ")"
+		args = append(args, *sectorID)
+	}
+
+	if opts.ExcludeSeed {
+		whereClause += " AND data_source <> ?"
+		args = append(args, model.DataSourceSeed)
+	}
+
+	if len(opts.Purposes) > 0 {
+		whereClause += " AND purpose IN ?"
+		args = append(args, opts.Purposes)
+	}
+
+	if opts.DeviceID != nil {
+		whereClause += " AND device_id = ?"
+		args = append(args, *opts.DeviceID)
+	}
+
+	return whereClause, args
+}
+
+// bucketedQuery builds the bucketed aggregation SELECT for the range, reading the whole days the
+// farm has rolled up from irrigation_daily_rollups when usesRollups allows it.
+// extraColumns is prepended to the select list and must end with a comma when set.
+func bucketedQuery(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation, extraColumns string, opts QueryOptions) (string, []interface{}) {
+	whereClause, args := rangeFilter(farmID, sectorID, startDate, endDate, opts)
+	rollupStart, rollupEnd := wholeDays(startDate, endDate)
+	if !usesRollups(aggregation, opts) || !rollupStart.Before(rollupEnd) {
+		return aggregationQuery(aggregation, extraColumns, whereClause, opts), args
+	}
+
+	// The rolled-up days end at the farm's watermark; with no watermark none are rolled up
+	rolledUpEnd := "LEAST(?::date, COALESCE((SELECT rolled_up_through FROM irrigation_rollup_states WHERE farm_id = ?), ?::date))"
+	rolledUpArgs := []interface{}{rollupEnd, farmID, rollupStart}
+
+	rollupWhere, rollupArgs := rangeFilterOn("day", farmID, sectorID, rollupStart, rollupEnd, opts)
+	rollupArgs = append(rollupArgs, rolledUpArgs...)
+	args = append(append(args, rollupStart), rolledUpArgs...)
+
+	bucket := bucketExpression(aggregation, "start_time")
+	query := `
+			SELECT ` + extraColumns + `
+				` + bucket + ` as start_time,
+				COALESCE(SUM(water_volume), 0) as water_volume,
+				COALESCE(SUM(duration), 0)::bigint as duration,
+				COALESCE(SUM(event_count), 0)::bigint as event_count,
+				COALESCE(SUM(nominal_amount), 0) as nominal_amount,
+				COALESCE(SUM(real_amount), 0) as real_amount,
+				COALESCE(SUM(missing_nominal_count), 0)::bigint as missing_nominal_count,
+				0 as excluded_event_count,
 				farm_id,
 				COALESCE(irrigation_sector_id, 0) as irrigation_sector_id
-			FROM irrigation_data
-			WHERE ` + baseQuery + `
-			GROUP BY DATE(start_time), farm_id, irrigation_sector_id
-			ORDER BY DATE(start_time) ASC`
+			FROM (
+				SELECT day::timestamp as start_time, farm_id, irrigation_sector_id,
+					water_volume, duration, event_count, nominal_amount, real_amount, missing_nominal_count
+				FROM irrigation_daily_rollups
+				WHERE ` + rollupWhere + ` AND day < ` + rolledUpEnd + `
+				UNION ALL
+				SELECT DATE(start_time)::timestamp as start_time, farm_id, irrigation_sector_id,` + dayTotalColumns + `
+				FROM irrigation_data
+				WHERE ` + whereClause + ` AND NOT (start_time >= ? AND start_time < ` + rolledUpEnd + `)
+				GROUP BY DATE(start_time), farm_id, irrigation_sector_id
+			) days
+			GROUP BY ` + bucket + `, farm_id, irrigation_sector_id`
+	return query, append(rollupArgs, args...)
+}
+
+// usesRollups reports whether a bucketed query can read the daily rollups. Rollups hold whole
+// days by start time, so hourly buckets, split events and end attribution need the events, as
+// does ExcludeAnnotated, since annotations can change after a day is rolled up. Rollups don't
+// keep the device or soft-deleted events, so a device filter and IncludeDeleted need the events too.
+func usesRollups(aggregation string, opts QueryOptions) bool {
+	return aggregation != "hourly" && !opts.ExcludeAnnotated && !opts.split() && opts.eventTime() == "start_time" &&
+		opts.DeviceID == nil && !opts.IncludeDeleted
+}
+
+// wholeDays returns the first and the end of the whole UTC days within [startDate, endDate)
+func wholeDays(startDate, endDate time.Time) (time.Time, time.Time) {
+	first := startOfDay(startDate)
+	if first.Before(startDate) {
+		first = first.AddDate(0, 0, 1)
 	}
+	return first, startOfDay(endDate)
+}
 
-	err := r.db.Raw(sqlQuery, args...).Scan(&results).Error
-	if err != nil {
-		return nil, err
+// aggregationQuery builds the bucketed aggregation SELECT for the given level.
+// extraColumns is prepended to the select list and must end with a comma when set.
+func aggregationQuery(aggregation, extraColumns, whereClause string, opts QueryOptions) string {
+	bucket := bucketExpression(aggregation, opts.eventTime())
+	source := `irrigation_data
+			WHERE ` + whereClause
+	if opts.split() {
+		bucket = bucketExpression(aggregation, "start_time")
+		source = splitEventsSource(aggregation, whereClause)
 	}
+	return `
+			SELECT ` + extraColumns + `
+				` + bucket + ` as start_time,` + aggregateColumns(opts, opts.split()) + `
+			FROM ` + source + `
+			GROUP BY ` + bucket + `, farm_id, irrigation_sector_id`
+}
 
-	// Convert AggregatedResult to AggregatedDataWithCount
+// splitEventsSource selects the events matching whereClause cut into one piece per day they span
+// (per hour for hourly aggregation). Each piece starts at the later of the event start and the
+// day start, and carries the event's totals scaled by its share of the event's time. The result
+// is aliased as irrigation_data so the total columns and the annotation condition apply unchanged.
+// Events without a positive span stay in one piece.
+func splitEventsSource(aggregation, whereClause string) string {
+	unit := "day"
+	if aggregation == "hourly" {
+		unit = "hour"
+	}
+	return fmt.Sprintf(`(
+				SELECT irrigation_data.id, irrigation_data.farm_id, irrigation_data.irrigation_sector_id,
+					piece.piece_start as start_time,
+					irrigation_data.water_volume * piece.fraction as water_volume,
+					irrigation_data.duration * piece.fraction as duration,
+					irrigation_data.nominal_amount * piece.fraction as nominal_amount,
+					irrigation_data.real_amount * piece.fraction as real_amount,
+					piece.piece_start = irrigation_data.start_time as first_piece
+				FROM irrigation_data
+				CROSS JOIN LATERAL (
+					SELECT GREATEST(step, irrigation_data.start_time) as piece_start,
+						CASE WHEN irrigation_data.end_time > irrigation_data.start_time
+							THEN EXTRACT(EPOCH FROM LEAST(step + INTERVAL '1 %[1]s', irrigation_data.end_time) - GREATEST(step, irrigation_data.start_time))
+								/ EXTRACT(EPOCH FROM irrigation_data.end_time - irrigation_data.start_time)
+							ELSE 1 END as fraction
+					FROM generate_series(DATE_TRUNC('%[1]s', irrigation_data.start_time),
+						GREATEST(irrigation_data.start_time, irrigation_data.end_time - INTERVAL '1 microsecond'),
+						INTERVAL '1 %[1]s') as step
+				) piece
+				WHERE %[2]s
+			) irrigation_data`, unit, whereClause)
+}
+
+// aggregateColumns are the totals selected by every grouped aggregation query. split selects
+// from splitEventsSource pieces rather than whole events.
+func aggregateColumns(opts QueryOptions, split bool) string {
+	return totalColumns(opts, split) + `,
+				farm_id,
+				COALESCE(irrigation_sector_id, 0) as irrigation_sector_id`
+}
+
+// totalColumns are the sums and counts shared by the grouped and summary queries.
+// real_amount sums only the events that recorded a nominal_amount, so the two efficiency inputs
+// cover the same events; otherwise a bucket mixing both kinds would compare every event's real
+// amount with part of the nominal amount. With ExcludeAnnotated set, annotated events are
+// filtered out of the efficiency inputs only.
+// split counts only the first piece of each event selected from splitEventsSource.
+func totalColumns(opts QueryOptions, split bool) string {
+	efficiencyFilter := ""
+	realFilter := " FILTER (WHERE nominal_amount IS NOT NULL)"
+	missingFilter := "nominal_amount IS NULL"
+	excludedCount := "0"
+	duration := "SUM(duration)"
+	eventCount := "COUNT(*)"
+	if opts.ExcludeAnnotated {
+		efficiencyFilter = " FILTER (WHERE NOT " + annotatedEventCondition + ")"
+		realFilter = " FILTER (WHERE nominal_amount IS NOT NULL AND NOT " + annotatedEventCondition + ")"
+		missingFilter += " AND NOT " + annotatedEventCondition
+		excludedCount = "COUNT(*) FILTER (WHERE " + annotatedEventCondition + ")"
+	}
+	if split {
+		// Split events are counted in their first piece only, and durations are whole minutes
+		duration = "ROUND(SUM(duration))::bigint"
+		eventCount = "COUNT(*) FILTER (WHERE first_piece)"
+		missingFilter += " AND first_piece"
+		if opts.ExcludeAnnotated {
+			excludedCount = "COUNT(*) FILTER (WHERE first_piece AND " + annotatedEventCondition + ")"
+		}
+	}
+
+	return `
+				COALESCE(SUM(water_volume), 0) as water_volume,
+				COALESCE(` + duration + `, 0) as duration,
+				` + eventCount + ` as event_count,
+				COALESCE(SUM(nominal_amount)` + efficiencyFilter + `, 0) as nominal_amount,
+				COALESCE(SUM(real_amount)` + realFilter + `, 0) as real_amount,
+				COUNT(*) FILTER (WHERE ` + missingFilter + `) as missing_nominal_count,
+				` + excludedCount + ` as excluded_event_count`
+}
+
+// sectorSubtreeQuery selects a sector and all its zones at any depth, so a sector filter rolls up
+// the events recorded against its zones. UNION rather than UNION ALL stops on a parent_id cycle.
+const sectorSubtreeQuery = `WITH RECURSIVE subtree AS (
+					SELECT id FROM irrigation_sectors WHERE id = ?
+					UNION
+					SELECT s.id FROM irrigation_sectors s JOIN subtree ON s.parent_id = subtree.id
+					WHERE s.deleted_at IS NULL)
+				SELECT id FROM subtree`
+
+// annotatedEventCondition matches irrigation_data rows covered by an annotation flagged
+// exclude_from_efficiency, either directly by event or by day (optionally limited to a sector)
+const annotatedEventCondition = `EXISTS (
+					SELECT 1 FROM irrigation_annotations a
+					WHERE a.farm_id = irrigation_data.farm_id
+						AND a.exclude_from_efficiency
+						AND a.deleted_at IS NULL
+						AND (a.irrigation_data_id = irrigation_data.id
+							OR (a.irrigation_data_id IS NULL
+								AND a.date = DATE(irrigation_data.start_time)
+								AND (a.irrigation_sector_id IS NULL OR a.irrigation_sector_id = irrigation_data.irrigation_sector_id))))`
+
+// splitByYearsBack groups tagged comparison rows by years back, with an entry for every offset
+func splitByYearsBack(results []AggregatedResult, offsets []int) map[int][]AggregatedDataWithCount {
+	byOffset := make(map[int][]AggregatedResult, len(offsets))
+	for _, result := range results {
+		byOffset[result.YearsBack] = append(byOffset[result.YearsBack], result)
+	}
+
+	comparison := make(map[int][]AggregatedDataWithCount, len(offsets))
+	for _, offset := range offsets {
+		comparison[offset] = toAggregatedData(byOffset[offset])
+	}
+	return comparison
+}
+
+// toAggregatedData converts AggregatedResult rows to AggregatedDataWithCount
+func toAggregatedData(results []AggregatedResult) []AggregatedDataWithCount {
+	modelResults := make([]AggregatedDataWithCount, 0, len(results))
 	for _, r := range results {
 		modelResults = append(modelResults, AggregatedDataWithCount{
 			Data: model.IrrigationData{
@@ -260,12 +1024,13 @@ func (r *irrigationRepository) GetYearOverYearData(farmID uint, sectorID *uint,
 				Duration:           r.Duration,
 				FarmID:             r.FarmID,
 				IrrigationSectorID: r.IrrigationSectorID,
-				NominalAmount:      r.NominalAmount,
+				NominalAmount:      &r.NominalAmount,
 				RealAmount:         r.RealAmount,
 			},
-			EventCount: r.EventCount,
+			EventCount:          r.EventCount,
+			MissingNominalCount: r.MissingNominalCount,
+			ExcludedEventCount:  r.ExcludedEventCount,
 		})
 	}
-
-	return modelResults, nil
+	return modelResults
 }
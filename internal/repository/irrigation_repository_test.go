@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/model"
+)
+
+func TestRangeFilter(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	sectorID := uint(3)
+	deviceID := uint(7)
+
+	tests := []struct {
+		name     string
+		sectorID *uint
+		opts     QueryOptions
+		expected string
+		args     int
+	}{
+		{"farm only", nil, QueryOptions{}, "farm_id = ? AND start_time >= ? AND start_time < ? AND deleted_at IS NULL", 3},
+		{"with sector", &sectorID, QueryOptions{}, "farm_id = ? AND start_time >= ? AND start_time < ? AND irrigation_sector_id IN (" + sectorSubtreeQuery + ") AND deleted_at IS NULL", 4},
+		{"by purpose", nil, QueryOptions{Purposes: []string{"irrigation"}}, "farm_id = ? AND start_time >= ? AND start_time < ? AND purpose IN ? AND deleted_at IS NULL", 4},
+		{"excluding seed", nil, QueryOptions{ExcludeSeed: true}, "farm_id = ? AND start_time >= ? AND start_time < ? AND data_source <> ? AND deleted_at IS NULL", 4},
+		{"by device", nil, QueryOptions{DeviceID: &deviceID}, "farm_id = ? AND start_time >= ? AND start_time < ? AND device_id = ? AND deleted_at IS NULL", 4},
+		{"including deleted", nil, QueryOptions{IncludeDeleted: true}, "farm_id = ? AND start_time >= ? AND start_time < ?", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clause, args := rangeFilter(1, tt.sectorID, start, end, tt.opts)
+			if clause != tt.expected {
+				t.Errorf("expected clause %q, got %q", tt.expected, clause)
+			}
+			if len(args) != tt.args {
+				t.Errorf("expected %d args, got %d", tt.args, len(args))
+			}
+		})
+	}
+}
+
+func TestParseCustomAggregation(t *testing.T) {
+	tests := []struct {
+		aggregation string
+		days        int
+		ok          bool
+	}{
+		{"10d", 10, true},
+		{"183d", 183, true},
+		{"1d", 1, false},
+		{"184d", 184, false},
+		{"010d", 0, false},
+		{"daily", 0, false},
+		{"d", 0, false},
+	}
+
+	for _, tt := range tests {
+		days, ok := ParseCustomAggregation(tt.aggregation)
+		if ok != tt.ok || (ok && days != tt.days) {
+			t.Errorf("%s: expected %d/%v, got %d/%v", tt.aggregation, tt.days, tt.ok, days, ok)
+		}
+	}
+
+	expected := "(DATE_TRUNC('year', start_time) + FLOOR((EXTRACT(DOY FROM start_time) - 1) / 10) * 10 * INTERVAL '1 day')"
+	if got := bucketExpression(CustomAggregation(10), "start_time"); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestDistributionColumns(t *testing.T) {
+	columns := distributionColumns("efficiency", "real_amount / nominal_amount", "nominal_amount > 0")
+	for _, expected := range []string{
+		"MIN(real_amount / nominal_amount) FILTER (WHERE nominal_amount > 0) as efficiency_min",
+		"PERCENTILE_CONT(0.95) WITHIN GROUP (ORDER BY real_amount / nominal_amount) FILTER (WHERE nominal_amount > 0) as efficiency_p95",
+		"STDDEV_POP(real_amount / nominal_amount) FILTER (WHERE nominal_amount > 0) as efficiency_stddev",
+	} {
+		if !strings.Contains(columns, expected) {
+			t.Errorf("expected columns to contain %q, got %s", expected, columns)
+		}
+	}
+
+	if columns := distributionColumns("volume", "water_volume", ""); strings.Contains(columns, "FILTER") {
+		t.Errorf("expected no filter, got %s", columns)
+	}
+}
+
+func TestAggregationQuery_SplitEvents(t *testing.T) {
+	query := aggregationQuery("daily", "", "farm_id = ?", QueryOptions{SplitEvents: true})
+	for _, expected := range []string{
+		"generate_series(DATE_TRUNC('day', irrigation_data.start_time)",
+		"LEAST(step + INTERVAL '1 day', irrigation_data.end_time)",
+		"WHERE farm_id = ?",
+		"COUNT(*) FILTER (WHERE first_piece) as event_count",
+		"ROUND(SUM(duration))::bigint",
+	} {
+		if !strings.Contains(query, expected) {
+			t.Errorf("expected split query to contain %q, got %s", expected, query)
+		}
+	}
+
+	if query := aggregationQuery("hourly", "", "farm_id = ?", QueryOptions{SplitEvents: true}); !strings.Contains(query, "INTERVAL '1 hour'") {
+		t.Errorf("expected hourly pieces, got %s", query)
+	}
+	if query := aggregationQuery("daily", "", "farm_id = ?", QueryOptions{}); strings.Contains(query, "first_piece") {
+		t.Errorf("expected whole events without SplitEvents, got %s", query)
+	}
+}
+
+func TestAttributionPolicy(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	endOpts := QueryOptions{Attribution: model.AttributionEnd}
+	if where, _ := rangeFilter(1, nil, start, start.AddDate(0, 1, 0), endOpts); where != "farm_id = ? AND end_time >= ? AND end_time < ? AND deleted_at IS NULL" {
+		t.Errorf("expected end attribution to filter on end_time, got %s", where)
+	}
+	if query := aggregationQuery("daily", "", "farm_id = ?", endOpts); !strings.Contains(query, "DATE(end_time)::timestamp as start_time") {
+		t.Errorf("expected end attribution to bucket on end_time, got %s", query)
+	}
+
+	proportional := QueryOptions{Attribution: model.AttributionProportional}
+	if query := aggregationQuery("daily", "", "farm_id = ?", proportional); !strings.Contains(query, "first_piece") {
+		t.Errorf("expected proportional attribution to split events, got %s", query)
+	}
+	if where, _ := rangeFilter(1, nil, start, start.AddDate(0, 1, 0), proportional); !strings.HasPrefix(where, "farm_id = ? AND start_time >= ?") {
+		t.Errorf("expected proportional attribution to select events by start_time, got %s", where)
+	}
+}
+
+func TestBucketedQuery_Rollups(t *testing.T) {
+	start := time.Date(2025, 1, 1, 6, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	sectorID := uint(3)
+	deviceID := uint(7)
+
+	query, args := bucketedQuery(1, &sectorID, start, end, "weekly", "", QueryOptions{ExcludeSeed: true})
+	for _, expected := range []string{
+		"FROM irrigation_daily_rollups",
+		"SELECT rolled_up_through FROM irrigation_rollup_states WHERE farm_id = ?",
+		"NOT (start_time >= ? AND start_time <",
+		"AND deleted_at IS NULL AND NOT (start_time",
+		"DATE_TRUNC('week', start_time) as start_time",
+	} {
+		if !strings.Contains(query, expected) {
+			t.Errorf("expected rollup query to contain %q, got %s", expected, query)
+		}
+	}
+	if placeholders := strings.Count(query, "?"); placeholders != len(args) {
+		t.Errorf("expected %d args for %d placeholders", len(args), placeholders)
+	}
+	if first := args[1]; first != time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC) {
+		t.Errorf("expected the rolled-up days to start on the first whole day, got %v", first)
+	}
+
+	for name, tt := range map[string]struct {
+		aggregation string
+		start       time.Time
+		opts        QueryOptions
+	}{
+		"hourly":            {"hourly", start, QueryOptions{}},
+		"exclude annotated": {"daily", start, QueryOptions{ExcludeAnnotated: true}},
+		"split events":      {"daily", start, QueryOptions{SplitEvents: true}},
+		"end attribution":   {"daily", start, QueryOptions{Attribution: model.AttributionEnd}},
+		"device":            {"daily", start, QueryOptions{DeviceID: &deviceID}},
+		"including deleted": {"daily", start, QueryOptions{IncludeDeleted: true}},
+		"no whole day":      {"daily", end.Add(-time.Hour), QueryOptions{}},
+	} {
+		if query, _ := bucketedQuery(1, nil, tt.start, end, tt.aggregation, "", tt.opts); strings.Contains(query, "irrigation_daily_rollups") {
+			t.Errorf("%s: expected the events to be read directly, got %s", name, query)
+		}
+	}
+}
+
+func TestTotalColumns_RealAmountMatchesNominal(t *testing.T) {
+	realAmount := "COALESCE(SUM(real_amount) FILTER (WHERE nominal_amount IS NOT NULL), 0) as real_amount"
+	for name, columns := range map[string]string{
+		"events":  totalColumns(QueryOptions{}, false),
+		"split":   totalColumns(QueryOptions{}, true),
+		"rollups": dayTotalColumns,
+	} {
+		if !strings.Contains(columns, realAmount) {
+			t.Errorf("%s: expected real_amount limited to events with a nominal amount, got %s", name, columns)
+		}
+	}
+
+	annotated := totalColumns(QueryOptions{ExcludeAnnotated: true}, false)
+	if !strings.Contains(annotated, "SUM(real_amount) FILTER (WHERE nominal_amount IS NOT NULL AND NOT EXISTS") {
+		t.Errorf("expected real_amount limited to unannotated events with a nominal amount, got %s", annotated)
+	}
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"irrigation-analytics/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// pressureBatchSize is the number of readings inserted per statement
+const pressureBatchSize = 500
+
+// PressureBucket holds pressure statistics for one aggregation bucket and sector
+type PressureBucket struct {
+	StartTime          time.Time `gorm:"column:start_time"`
+	IrrigationSectorID uint      `gorm:"column:irrigation_sector_id"`
+	MinPressure        float64   `gorm:"column:min_pressure"`
+	AvgPressure        float64   `gorm:"column:avg_pressure"`
+	ReadingCount       int       `gorm:"column:reading_count"`
+}
+
+// PressureRepository defines the interface for pressure telemetry operations
+type PressureRepository interface {
+	CreateReadings(readings []model.PressureReading) error
+	GetPressureBuckets(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string) ([]PressureBucket, error)
+	WithCapture(capture *QueryCapture) PressureRepository
+	WithContext(ctx context.Context) PressureRepository
+}
+
+// pressureRepository implements PressureRepository
+type pressureRepository struct {
+	db *gorm.DB
+}
+
+// NewPressureRepository creates a new pressure repository
+func NewPressureRepository(db *gorm.DB) PressureRepository {
+	return &pressureRepository{db: db}
+}
+
+// WithCapture returns a repository whose statements are recorded by capture
+func (r *pressureRepository) WithCapture(capture *QueryCapture) PressureRepository {
+	return &pressureRepository{db: capture.session(r.db)}
+}
+
+// WithContext returns a repository whose statements run under ctx, so they are cancelled with it
+func (r *pressureRepository) WithContext(ctx context.Context) PressureRepository {
+	return &pressureRepository{db: r.db.WithContext(ctx)}
+}
+
+// CreateReadings stores readings in batches within a single transaction
+func (r *pressureRepository) CreateReadings(readings []model.PressureReading) error {
+	return r.db.CreateInBatches(readings, pressureBatchSize).Error
+}
+
+// GetPressureBuckets fetches min and average pressure per bucket and sector, using the same
+// bucket boundaries as the irrigation aggregation queries
+func (r *pressureRepository) GetPressureBuckets(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string) ([]PressureBucket, error) {
+	var buckets []PressureBucket
+
+	bucket := bucketExpression(aggregation, "recorded_at")
+	whereClause := "farm_id = ? AND recorded_at >= ? AND recorded_at < ?"
+	args := []interface{}{farmID, startDate, endDate}
+	if sectorID != nil {
+		whereClause += " AND irrigation_sector_id = ?"
+		args = append(args, *sectorID)
+	}
+
+	sqlQuery := `
+			SELECT
+				` + bucket + ` as start_time,
+				irrigation_sector_id,
+				MIN(pressure_bar) as min_pressure,
+				AVG(pressure_bar) as avg_pressure,
+				COUNT(*) as reading_count
+			FROM pressure_readings
+			WHERE ` + whereClause + `
+			GROUP BY ` + bucket + `, irrigation_sector_id
+			ORDER BY start_time ASC`
+
+	err := r.db.Raw(sqlQuery, args...).Scan(&buckets).Error
+	if err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// CapturedQuery is one statement recorded by a QueryCapture
+type CapturedQuery struct {
+	SQL        string  `json:"sql"`
+	Rows       int64   `json:"rows"`
+	DurationMs float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// QueryCapture is a GORM logger that records every statement with its bound values, row count
+// and duration. Repositories returned by WithCapture log to it instead of the application logger.
+type QueryCapture struct {
+	mu      sync.Mutex
+	queries []CapturedQuery
+}
+
+// NewQueryCapture creates an empty query capture
+func NewQueryCapture() *QueryCapture {
+	return &QueryCapture{}
+}
+
+// Queries returns the captured statements in execution order
+func (c *QueryCapture) Queries() []CapturedQuery {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]CapturedQuery(nil), c.queries...)
+}
+
+// session returns db with statements logged to the capture
+func (c *QueryCapture) session(db *gorm.DB) *gorm.DB {
+	return db.Session(&gorm.Session{Logger: c})
+}
+
+// LogMode implements logger.Interface; the capture records every statement regardless of level
+func (c *QueryCapture) LogMode(logger.LogLevel) logger.Interface {
+	return c
+}
+
+// Info implements logger.Interface; messages are not captured
+func (c *QueryCapture) Info(context.Context, string, ...interface{}) {}
+
+// Warn implements logger.Interface; messages are not captured
+func (c *QueryCapture) Warn(context.Context, string, ...interface{}) {}
+
+// Error implements logger.Interface; messages are not captured
+func (c *QueryCapture) Error(context.Context, string, ...interface{}) {}
+
+// Trace implements logger.Interface and records the statement
+func (c *QueryCapture) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	sql, rows := fc()
+	query := CapturedQuery{
+		SQL:        sql,
+		Rows:       rows,
+		DurationMs: math.Round(float64(time.Since(begin))/float64(time.Millisecond)*100) / 100,
+	}
+	if err != nil {
+		query.Error = err.Error()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queries = append(c.queries, query)
+}
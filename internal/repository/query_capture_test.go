@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQueryCapture_Trace(t *testing.T) {
+	capture := NewQueryCapture()
+	begin := time.Now().Add(-15 * time.Millisecond)
+
+	capture.Trace(context.Background(), begin, func() (string, int64) {
+		return "SELECT 1 FROM farms WHERE id = 7", 1
+	}, nil)
+	capture.Trace(context.Background(), begin, func() (string, int64) {
+		return "SELECT broken", 0
+	}, errors.New("syntax error"))
+
+	queries := capture.Queries()
+	if len(queries) != 2 {
+		t.Fatalf("expected 2 captured queries, got %d", len(queries))
+	}
+	if queries[0].SQL != "SELECT 1 FROM farms WHERE id = 7" || queries[0].Rows != 1 || queries[0].DurationMs < 15 {
+		t.Errorf("unexpected first query: %+v", queries[0])
+	}
+	if queries[1].Error != "syntax error" {
+		t.Errorf("expected error to be captured, got %+v", queries[1])
+	}
+}
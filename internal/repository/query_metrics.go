@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// queryStartKey is the statement instance key holding a statement's start time
+const queryStartKey = "query_metrics:start"
+
+// RegisterQueryMetrics reports the duration of every statement executed through db to observe,
+// labelled by GORM operation (query, create, update, delete, row, raw).
+// Aggregation queries use Raw(...).Scan, so they are reported as row.
+func RegisterQueryMetrics(db *gorm.DB, observe func(operation string, d time.Duration)) error {
+	before := func(tx *gorm.DB) {
+		tx.InstanceSet(queryStartKey, time.Now())
+	}
+	after := func(operation string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			if start, ok := tx.InstanceGet(queryStartKey); ok {
+				observe(operation, time.Since(start.(time.Time)))
+			}
+		}
+	}
+
+	callbacks := db.Callback()
+	processors := []struct {
+		operation string
+		name      string
+		register  func(name string, before, after func(*gorm.DB)) error
+	}{
+		{"query", "gorm:query", func(name string, b, a func(*gorm.DB)) error {
+			if err := callbacks.Query().Before(name).Register("query_metrics:before_query", b); err != nil {
+				return err
+			}
+			return callbacks.Query().After(name).Register("query_metrics:after_query", a)
+		}},
+		{"create", "gorm:create", func(name string, b, a func(*gorm.DB)) error {
+			if err := callbacks.Create().Before(name).Register("query_metrics:before_create", b); err != nil {
+				return err
+			}
+			return callbacks.Create().After(name).Register("query_metrics:after_create", a)
+		}},
+		{"update", "gorm:update", func(name string, b, a func(*gorm.DB)) error {
+			if err := callbacks.Update().Before(name).Register("query_metrics:before_update", b); err != nil {
+				return err
+			}
+			return callbacks.Update().After(name).Register("query_metrics:after_update", a)
+		}},
+		{"delete", "gorm:delete", func(name string, b, a func(*gorm.DB)) error {
+			if err := callbacks.Delete().Before(name).Register("query_metrics:before_delete", b); err != nil {
+				return err
+			}
+			return callbacks.Delete().After(name).Register("query_metrics:after_delete", a)
+		}},
+		{"row", "gorm:row", func(name string, b, a func(*gorm.DB)) error {
+			if err := callbacks.Row().Before(name).Register("query_metrics:before_row", b); err != nil {
+				return err
+			}
+			return callbacks.Row().After(name).Register("query_metrics:after_row", a)
+		}},
+		{"raw", "gorm:raw", func(name string, b, a func(*gorm.DB)) error {
+			if err := callbacks.Raw().Before(name).Register("query_metrics:before_raw", b); err != nil {
+				return err
+			}
+			return callbacks.Raw().After(name).Register("query_metrics:after_raw", a)
+		}},
+	}
+
+	for _, p := range processors {
+		if err := p.register(p.name, before, after(p.operation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// queryCancelKey is the statement instance key holding the cancel func of a statement's timeout
+const queryCancelKey = "query_timeout:cancel"
+
+// DefaultQueryTimeout bounds a single statement when no other timeout is configured
+const DefaultQueryTimeout = 30 * time.Second
+
+// RegisterQueryTimeout bounds every statement executed through db to timeout, on top of the
+// statement's own context (see the repositories' WithContext), so a cancelled request stops its
+// queries and a runaway query fails with context.DeadlineExceeded. A timeout of 0 disables it.
+//
+// Statements run through Raw(...).Scan or Rows are read after their callbacks finish, so their
+// timeout is not cancelled early; it ends with the deadline or with the request context.
+func RegisterQueryTimeout(db *gorm.DB, timeout time.Duration) error {
+	if timeout <= 0 {
+		return nil
+	}
+
+	before := func(tx *gorm.DB) {
+		parent := tx.Statement.Context
+		if parent == nil {
+			parent = context.Background()
+		}
+		ctx, cancel := context.WithTimeout(parent, timeout)
+		tx.Statement.Context = ctx
+		tx.InstanceSet(queryCancelKey, cancel)
+	}
+	after := func(tx *gorm.DB) {
+		if cancel, ok := tx.InstanceGet(queryCancelKey); ok {
+			cancel.(context.CancelFunc)()
+		}
+	}
+
+	callbacks := db.Callback()
+	processors := []func(before, after func(*gorm.DB)) error{
+		func(b, a func(*gorm.DB)) error {
+			if err := callbacks.Query().Before("gorm:query").Register("query_timeout:before_query", b); err != nil {
+				return err
+			}
+			return callbacks.Query().After("gorm:query").Register("query_timeout:after_query", a)
+		},
+		func(b, a func(*gorm.DB)) error {
+			if err := callbacks.Create().Before("gorm:create").Register("query_timeout:before_create", b); err != nil {
+				return err
+			}
+			return callbacks.Create().After("gorm:create").Register("query_timeout:after_create", a)
+		},
+		func(b, a func(*gorm.DB)) error {
+			if err := callbacks.Update().Before("gorm:update").Register("query_timeout:before_update", b); err != nil {
+				return err
+			}
+			return callbacks.Update().After("gorm:update").Register("query_timeout:after_update", a)
+		},
+		func(b, a func(*gorm.DB)) error {
+			if err := callbacks.Delete().Before("gorm:delete").Register("query_timeout:before_delete", b); err != nil {
+				return err
+			}
+			return callbacks.Delete().After("gorm:delete").Register("query_timeout:after_delete", a)
+		},
+		func(b, _ func(*gorm.DB)) error {
+			return callbacks.Row().Before("gorm:row").Register("query_timeout:before_row", b)
+		},
+		func(b, a func(*gorm.DB)) error {
+			if err := callbacks.Raw().Before("gorm:raw").Register("query_timeout:before_raw", b); err != nil {
+				return err
+			}
+			return callbacks.Raw().After("gorm:raw").Register("query_timeout:after_raw", a)
+		},
+	}
+
+	for _, register := range processors {
+		if err := register(before, after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
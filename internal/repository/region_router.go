@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"irrigation-analytics/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// DefaultRegionCacheTTL is how long a farm's region is reused before the routing table is read again
+const DefaultRegionCacheTTL = 5 * time.Minute
+
+// DefaultRegionCacheSize bounds how many farm regions are cached. Lookups of farm IDs that don't
+// exist are cached too, so without a bound a client walking IDs would grow the cache forever.
+const DefaultRegionCacheSize = 10000
+
+// ErrUnknownRegion is returned for a region without a configured database
+var ErrUnknownRegion = errors.New("unknown region")
+
+// regionCacheEntry holds a cached farm region
+type regionCacheEntry struct {
+	region    string
+	expiresAt time.Time
+}
+
+// RegionRouter shards farms across one database per region. The farm_regions routing table,
+// kept in its own database, says which region holds each farm; farms without a row belong to the
+// default region, so a deployment with a single region needs no rows at all.
+type RegionRouter struct {
+	routing       *gorm.DB
+	regions       map[string]*gorm.DB
+	defaultRegion string
+	ttl           time.Duration
+	maxEntries    int
+	now           func() time.Time
+
+	mu    sync.RWMutex
+	cache map[uint]regionCacheEntry
+}
+
+// NewRegionRouter creates a router over the regions' databases, with farm_regions in routing
+// (which may also be one of the regions' databases). defaultRegion must be one of regions.
+// Farm IDs are handed out by farm_regions, so its sequence is moved past every farm already in
+// the regions; farms must then only be created through a regional farm repository.
+func NewRegionRouter(routing *gorm.DB, regions map[string]*gorm.DB, defaultRegion string) (*RegionRouter, error) {
+	if _, ok := regions[defaultRegion]; !ok {
+		return nil, fmt.Errorf("%w: default region %q has no database", ErrUnknownRegion, defaultRegion)
+	}
+
+	router := &RegionRouter{
+		routing:       routing,
+		regions:       regions,
+		defaultRegion: defaultRegion,
+		ttl:           DefaultRegionCacheTTL,
+		maxEntries:    DefaultRegionCacheSize,
+		now:           time.Now,
+		cache:         make(map[uint]regionCacheEntry),
+	}
+	if err := router.alignFarmIDs(); err != nil {
+		return nil, err
+	}
+	return router, nil
+}
+
+// alignFarmIDs moves the farm_regions sequence past the highest farm ID of every region, so a
+// reserved ID never names a farm created before routing was enabled
+func (r *RegionRouter) alignFarmIDs() error {
+	var highest uint
+	for region, db := range r.regions {
+		var regionHighest uint
+		if err := db.Raw("SELECT COALESCE(MAX(id), 0) FROM farms").Scan(&regionHighest).Error; err != nil {
+			return fmt.Errorf("reading farm IDs of region %s: %w", region, err)
+		}
+		highest = max(highest, regionHighest)
+	}
+
+	return r.routing.Exec(`
+			SELECT setval(pg_get_serial_sequence('farm_regions', 'farm_id'),
+				GREATEST(?, (SELECT COALESCE(MAX(farm_id), 0) FROM farm_regions), 1))`, highest).Error
+}
+
+// Regions returns the configured regions in name order
+func (r *RegionRouter) Regions() []string {
+	regions := make([]string, 0, len(r.regions))
+	for region := range r.regions {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// DefaultRegion returns the region of farms without a routing row
+func (r *RegionRouter) DefaultRegion() string {
+	return r.defaultRegion
+}
+
+// DB returns the database of a region, and false when the region isn't configured
+func (r *RegionRouter) DB(region string) (*gorm.DB, bool) {
+	db, ok := r.regions[region]
+	return db, ok
+}
+
+// RegionOf returns the region holding a farm. The farm doesn't need to exist: unknown farms
+// belong to the default region, whose database then reports them missing.
+func (r *RegionRouter) RegionOf(farmID uint) (string, error) {
+	r.mu.RLock()
+	entry, found := r.cache[farmID]
+	r.mu.RUnlock()
+	if found && r.now().Before(entry.expiresAt) {
+		return entry.region, nil
+	}
+
+	region := r.defaultRegion
+	var row model.FarmRegion
+	err := r.routing.Where("farm_id = ?", farmID).Take(&row).Error
+	switch {
+	case err == nil:
+		region = row.Region
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return "", err
+	}
+	if _, ok := r.regions[region]; !ok {
+		return "", fmt.Errorf("%w: farm %d is routed to %q", ErrUnknownRegion, farmID, region)
+	}
+
+	r.remember(farmID, region)
+	return region, nil
+}
+
+// Reserve hands out a new farm ID routed to region, for a farm about to be created there
+func (r *RegionRouter) Reserve(region string) (uint, error) {
+	if _, ok := r.regions[region]; !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownRegion, region)
+	}
+
+	row := model.FarmRegion{Region: region}
+	if err := r.routing.Create(&row).Error; err != nil {
+		return 0, err
+	}
+	r.remember(row.FarmID, region)
+	return row.FarmID, nil
+}
+
+// Release removes a farm's routing row, for a reserved ID whose farm couldn't be created
+func (r *RegionRouter) Release(farmID uint) error {
+	r.mu.Lock()
+	delete(r.cache, farmID)
+	r.mu.Unlock()
+	return r.routing.Delete(&model.FarmRegion{}, farmID).Error
+}
+
+// remember caches a farm's region until the TTL elapses. When the cache is full, expired entries
+// are dropped first, and an arbitrary live one when none has expired.
+func (r *RegionRouter) remember(farmID uint, region string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if _, cached := r.cache[farmID]; !cached && len(r.cache) >= r.maxEntries {
+		for id, entry := range r.cache {
+			if !now.Before(entry.expiresAt) {
+				delete(r.cache, id)
+			}
+		}
+		for id := range r.cache {
+			if len(r.cache) < r.maxEntries {
+				break
+			}
+			delete(r.cache, id)
+		}
+	}
+	r.cache[farmID] = regionCacheEntry{region: region, expiresAt: now.Add(r.ttl)}
+}
+
+// regionalFarmRepository creates farms under IDs reserved for its region
+type regionalFarmRepository struct {
+	FarmRepository
+	router *RegionRouter
+	region string
+}
+
+// NewRegionalFarmRepository wraps the farm repository of a region's database so every farm it
+// creates is routed to that region, under an ID unique across regions
+func NewRegionalFarmRepository(repo FarmRepository, router *RegionRouter, region string) FarmRepository {
+	return &regionalFarmRepository{FarmRepository: repo, router: router, region: region}
+}
+
+// CreateFarmWithSectors reserves the farm's ID in the routing table before creating it, and
+// releases the ID again when the farm can't be created
+func (r *regionalFarmRepository) CreateFarmWithSectors(farm *model.Farm) error {
+	farmID, err := r.router.Reserve(r.region)
+	if err != nil {
+		return err
+	}
+
+	farm.ID = farmID
+	if err := r.FarmRepository.CreateFarmWithSectors(farm); err != nil {
+		farm.ID = 0
+		return errors.Join(err, r.router.Release(farmID))
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRegionRouter_RememberIsBounded(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	router := &RegionRouter{
+		defaultRegion: "eu",
+		ttl:           time.Minute,
+		maxEntries:    3,
+		now:           func() time.Time { return now },
+		cache:         make(map[uint]regionCacheEntry),
+	}
+
+	router.remember(1, "eu")
+	router.remember(2, "us")
+	now = now.Add(time.Minute)
+	router.remember(3, "eu")
+
+	// Full: the two expired entries make room
+	router.remember(4, "eu")
+	if _, ok := router.cache[1]; ok || len(router.cache) != 2 {
+		t.Fatalf("expected the expired entries evicted, got %+v", router.cache)
+	}
+
+	// Refreshing a cached farm never evicts
+	router.remember(5, "us")
+	router.remember(4, "us")
+	if len(router.cache) != 3 || router.cache[4].region != "us" {
+		t.Fatalf("expected farm 4 refreshed in a full cache, got %+v", router.cache)
+	}
+
+	// Full of live entries: one makes room, so the cache never grows past its bound
+	router.remember(6, "eu")
+	if _, ok := router.cache[6]; !ok || len(router.cache) != 3 {
+		t.Errorf("expected farm 6 cached within the bound, got %+v", router.cache)
+	}
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"irrigation-analytics/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ReportScheduleRepository defines the interface for emailed report schedule operations
+type ReportScheduleRepository interface {
+	Create(schedule *model.ReportSchedule) error
+	List(farmID uint) ([]model.ReportSchedule, error)
+	Delete(farmID, scheduleID uint) (bool, error)
+	Due(now time.Time, limit int) ([]model.ReportSchedule, error)
+	Claim(scheduleID uint, dueAt, until time.Time) (bool, error)
+	Update(schedule *model.ReportSchedule) error
+	WithContext(ctx context.Context) ReportScheduleRepository
+}
+
+// reportScheduleRepository implements ReportScheduleRepository
+type reportScheduleRepository struct {
+	db *gorm.DB
+}
+
+// NewReportScheduleRepository creates a new report schedule repository
+func NewReportScheduleRepository(db *gorm.DB) ReportScheduleRepository {
+	return &reportScheduleRepository{db: db}
+}
+
+// WithContext returns a repository whose statements run under ctx, so they are cancelled with it
+func (r *reportScheduleRepository) WithContext(ctx context.Context) ReportScheduleRepository {
+	return &reportScheduleRepository{db: r.db.WithContext(ctx)}
+}
+
+// Create stores a new schedule
+func (r *reportScheduleRepository) Create(schedule *model.ReportSchedule) error {
+	return r.db.Create(schedule).Error
+}
+
+// List returns the farm's schedules ordered by ID
+func (r *reportScheduleRepository) List(farmID uint) ([]model.ReportSchedule, error) {
+	var schedules []model.ReportSchedule
+	if err := r.db.Where("farm_id = ?", farmID).Order("id ASC").Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// Delete soft-deletes a schedule of the farm, reporting whether it existed
+func (r *reportScheduleRepository) Delete(farmID, scheduleID uint) (bool, error) {
+	result := r.db.Where("farm_id = ?", farmID).Delete(&model.ReportSchedule{}, scheduleID)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Due returns up to limit schedules whose next run is due, oldest first
+func (r *reportScheduleRepository) Due(now time.Time, limit int) ([]model.ReportSchedule, error) {
+	var schedules []model.ReportSchedule
+	err := r.db.Where("next_run_at <= ?", now).Order("next_run_at ASC, id ASC").Limit(limit).Find(&schedules).Error
+	if err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// Claim moves a schedule's next run from dueAt to until, reporting whether it still was dueAt.
+// Only one instance claims a run, and a run whose sender dies is retried after until.
+func (r *reportScheduleRepository) Claim(scheduleID uint, dueAt, until time.Time) (bool, error) {
+	result := r.db.Model(&model.ReportSchedule{}).
+		Where("id = ? AND next_run_at = ?", scheduleID, dueAt).
+		Update("next_run_at", until)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Update saves a schedule's next run and last outcome
+func (r *reportScheduleRepository) Update(schedule *model.ReportSchedule) error {
+	return r.db.Model(schedule).Select("next_run_at", "last_sent_at", "last_error").Updates(schedule).Error
+}
@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"irrigation-analytics/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MaxRollupDaysPerRefresh bounds how far one refresh advances a farm's rollups, so the first
+// refresh of a farm with years of history is spread over several runs instead of one long
+// statement. Reads stay correct in between: days past the watermark come from the events.
+const MaxRollupDaysPerRefresh = 366
+
+// rollupRefreshOverlap widens the window of updated events a refresh looks for, so events
+// committed while the previous refresh ran are not missed
+const rollupRefreshOverlap = time.Minute
+
+// RollupRefresh reports what one farm's refresh did. Days from FinalizedFrom up to
+// RolledUpThrough were rolled up for the first time.
+type RollupRefresh struct {
+	FarmID          uint      `json:"farm_id"`
+	RebuiltRows     int64     `json:"rebuilt_rows"`
+	FinalizedFrom   time.Time `json:"finalized_from"`
+	RolledUpThrough time.Time `json:"rolled_up_through"`
+}
+
+// RollupDayTotal is one day of a farm's rollups summed over sectors, purposes and data sources
+type RollupDayTotal struct {
+	Day                 time.Time `json:"day"`
+	WaterVolume         float64   `json:"water_volume"`
+	Duration            int64     `json:"duration"`
+	EventCount          int       `json:"event_count"`
+	NominalAmount       float64   `json:"nominal_amount"`
+	RealAmount          float64   `json:"real_amount"`
+	MissingNominalCount int       `json:"missing_nominal_count"`
+}
+
+// RollupRepository maintains the daily rollups read by the bucketed aggregation queries
+type RollupRepository interface {
+	ListFarmIDs() ([]uint, error)
+	RefreshFarm(farmID uint, through time.Time) (*RollupRefresh, error)
+	DayTotals(farmID uint, from, to time.Time) ([]RollupDayTotal, error)
+	WithContext(ctx context.Context) RollupRepository
+}
+
+// rollupRepository implements RollupRepository
+type rollupRepository struct {
+	db *gorm.DB
+}
+
+// NewRollupRepository creates a new rollup repository
+func NewRollupRepository(db *gorm.DB) RollupRepository {
+	return &rollupRepository{db: db}
+}
+
+// WithContext returns a repository whose statements run under ctx, so they are cancelled with it
+func (r *rollupRepository) WithContext(ctx context.Context) RollupRepository {
+	return &rollupRepository{db: r.db.WithContext(ctx)}
+}
+
+// ListFarmIDs fetches the IDs of every farm, archived or not, in ID order
+func (r *rollupRepository) ListFarmIDs() ([]uint, error) {
+	var ids []uint
+	if err := r.db.Model(&model.Farm{}).Order("id ASC").Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// rollupInvalidationDDL installs the trigger that records the days irrigation_rollup_invalidations
+// tracks: the old day of every hard-deleted event and of every event moved to another day or farm
+var rollupInvalidationDDL = []string{
+	`CREATE OR REPLACE FUNCTION invalidate_irrigation_rollup_day() RETURNS trigger AS $$
+	BEGIN
+		IF TG_OP = 'UPDATE' AND OLD.farm_id = NEW.farm_id AND DATE(OLD.start_time) = DATE(NEW.start_time) THEN
+			RETURN NULL;
+		END IF;
+		INSERT INTO irrigation_rollup_invalidations (farm_id, day)
+		VALUES (OLD.farm_id, DATE(OLD.start_time))
+		ON CONFLICT DO NOTHING;
+		RETURN NULL;
+	END;
+	$$ LANGUAGE plpgsql`,
+	`DROP TRIGGER IF EXISTS irrigation_rollup_invalidation ON irrigation_data`,
+	`CREATE TRIGGER irrigation_rollup_invalidation
+	AFTER UPDATE OF farm_id, start_time OR DELETE ON irrigation_data
+	FOR EACH ROW EXECUTE FUNCTION invalidate_irrigation_rollup_day()`,
+}
+
+// MigrateRollupInvalidation installs the irrigation_data trigger that fills
+// irrigation_rollup_invalidations. Run it after AutoMigrate; it is safe to run on every start.
+func MigrateRollupInvalidation(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range rollupInvalidationDDL {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RefreshFarm rebuilds the farm's stale rollup days before through, a UTC midnight, and moves the
+// farm's watermark up to it, at most MaxRollupDaysPerRefresh days past the previous one. A day is
+// stale when it is past the previous watermark, has an event created or updated since the
+// previous refresh started, or was invalidated by an event leaving it. Refreshes of the same
+// farm are serialised by an advisory lock.
+func (r *rollupRepository) RefreshFarm(farmID uint, through time.Time) (*RollupRefresh, error) {
+	refresh := &RollupRefresh{FarmID: farmID}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('irrigation_daily_rollups'), ?)", farmID).Error; err != nil {
+			return err
+		}
+
+		var startedAt time.Time
+		if err := tx.Raw("SELECT NOW()").Scan(&startedAt).Error; err != nil {
+			return err
+		}
+
+		var state model.IrrigationRollupState
+		err := tx.Where("farm_id = ?", farmID).Take(&state).Error
+		found := err == nil
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		from := state.RolledUpThrough
+		updatedSince := state.RefreshedAt.Add(-rollupRefreshOverlap)
+		if !found {
+			// Nothing is rolled up yet: start from the farm's first event
+			var first *time.Time
+			if err := tx.Raw("SELECT MIN(start_time) FROM irrigation_data WHERE farm_id = ? AND deleted_at IS NULL", farmID).Scan(&first).Error; err != nil {
+				return err
+			}
+			from, updatedSince = through, startedAt
+			if first != nil {
+				from = startOfDay(*first)
+			}
+		}
+		if limit := from.AddDate(0, 0, MaxRollupDaysPerRefresh); through.After(limit) {
+			through = limit
+		}
+
+		// Claim the days invalidated so far; ones recorded by writes committing later stay for the
+		// next refresh
+		var invalidated []time.Time
+		err = tx.Raw("DELETE FROM irrigation_rollup_invalidations WHERE farm_id = ? RETURNING day", farmID).
+			Scan(&invalidated).Error
+		if err != nil {
+			return err
+		}
+
+		staleArgs := []interface{}{from, farmID, updatedSince, updatedSince, invalidated}
+		result := tx.Exec(`
+			DELETE FROM irrigation_daily_rollups
+			WHERE farm_id = ? AND day < ? AND `+staleDaysFilter("day", "day"),
+			append([]interface{}{farmID, through}, staleArgs...)...)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		result = tx.Exec(`
+			INSERT INTO irrigation_daily_rollups (farm_id, day, irrigation_sector_id, purpose, data_source,
+				water_volume, duration, event_count, nominal_amount, real_amount, missing_nominal_count)
+			SELECT farm_id, DATE(start_time) as day, irrigation_sector_id, purpose, data_source,`+dayTotalColumns+`
+			FROM irrigation_data
+			WHERE farm_id = ? AND deleted_at IS NULL AND start_time < ? AND `+staleDaysFilter("start_time", "DATE(start_time)")+`
+			GROUP BY farm_id, DATE(start_time), irrigation_sector_id, purpose, data_source`,
+			append([]interface{}{farmID, through}, staleArgs...)...)
+		if result.Error != nil {
+			return result.Error
+		}
+		refresh.RebuiltRows = result.RowsAffected
+
+		state = model.IrrigationRollupState{FarmID: farmID, RolledUpThrough: through, RefreshedAt: startedAt}
+		refresh.FinalizedFrom, refresh.RolledUpThrough = from, through
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "farm_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"rolled_up_through", "refreshed_at"}),
+		}).Create(&state).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refresh, nil
+}
+
+// DayTotals returns the farm's rolled-up days in [from, to) summed per day, in day order; days
+// without events have no row
+func (r *rollupRepository) DayTotals(farmID uint, from, to time.Time) ([]RollupDayTotal, error) {
+	var totals []RollupDayTotal
+	err := r.db.Model(&model.IrrigationDailyRollup{}).
+		Select(`day,
+			SUM(water_volume) as water_volume,
+			SUM(duration) as duration,
+			SUM(event_count) as event_count,
+			SUM(nominal_amount) as nominal_amount,
+			SUM(real_amount) as real_amount,
+			SUM(missing_nominal_count) as missing_nominal_count`).
+		Where("farm_id = ? AND day >= ? AND day < ?", farmID, from, to).
+		Group("day").
+		Order("day ASC").
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// updatedDaysQuery selects the days of a farm's events created, updated or soft-deleted since a
+// time; soft deletes don't touch updated_at, so deleted_at is checked too. It takes the time twice.
+const updatedDaysQuery = `SELECT DISTINCT DATE(start_time) FROM irrigation_data WHERE farm_id = ? AND (updated_at >= ? OR deleted_at >= ?)`
+
+// staleDaysFilter matches the rows of a refresh's stale days, given their time column and day
+// expression: days from the previous watermark on, days in updatedDaysQuery and the claimed
+// invalidated days. It takes the watermark, updatedDaysQuery's arguments and the invalidated days.
+func staleDaysFilter(column, day string) string {
+	return "(" + column + " >= ? OR " + day + " IN (" + updatedDaysQuery + ") OR " + day + " IN ?)"
+}
+
+// dayTotalColumns are the per-day totals stored in irrigation_daily_rollups, in column order.
+// As in totalColumns, real_amount covers only the events that recorded a nominal_amount.
+const dayTotalColumns = `
+				COALESCE(SUM(water_volume), 0) as water_volume,
+				COALESCE(SUM(duration), 0) as duration,
+				COUNT(*) as event_count,
+				COALESCE(SUM(nominal_amount), 0) as nominal_amount,
+				COALESCE(SUM(real_amount) FILTER (WHERE nominal_amount IS NOT NULL), 0) as real_amount,
+				COUNT(*) FILTER (WHERE nominal_amount IS NULL) as missing_nominal_count`
+
+// startOfDay truncates t to midnight UTC
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
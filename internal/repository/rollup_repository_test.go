@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRollupInvalidationDDL_EventMovedAcrossDays(t *testing.T) {
+	ddl := strings.Join(rollupInvalidationDDL, ";\n")
+	for _, expected := range []string{
+		// Moving an event to another day or farm, or hard-deleting it, records its old day
+		"AFTER UPDATE OF farm_id, start_time OR DELETE ON irrigation_data",
+		"VALUES (OLD.farm_id, DATE(OLD.start_time))",
+		// Updates that keep the event on its day are left to updatedDaysQuery
+		"IF TG_OP = 'UPDATE' AND OLD.farm_id = NEW.farm_id AND DATE(OLD.start_time) = DATE(NEW.start_time) THEN",
+	} {
+		if !strings.Contains(ddl, expected) {
+			t.Errorf("expected the trigger to contain %q, got %s", expected, ddl)
+		}
+	}
+}
+
+func TestStaleDaysFilter(t *testing.T) {
+	filter := staleDaysFilter("start_time", "DATE(start_time)")
+	for _, expected := range []string{
+		"start_time >= ?",
+		"DATE(start_time) IN (SELECT DISTINCT DATE(start_time) FROM irrigation_data",
+		// The days invalidated by moved and hard-deleted events are rebuilt too
+		"OR DATE(start_time) IN ?)",
+	} {
+		if !strings.Contains(filter, expected) {
+			t.Errorf("expected the filter to contain %q, got %s", expected, filter)
+		}
+	}
+	// RefreshFarm passes the watermark, updatedDaysQuery's three arguments and the invalidated days
+	if placeholders := strings.Count(filter, "?"); placeholders != 5 {
+		t.Errorf("expected 5 placeholders, got %d in %s", placeholders, filter)
+	}
+}
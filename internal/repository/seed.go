@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
 	"time"
@@ -10,6 +11,49 @@ import (
 	"gorm.io/gorm"
 )
 
+// SeedProfile sizes the seeded data set
+type SeedProfile struct {
+	Farms          int
+	SectorsPerFarm int
+	// Start and End bound the seeded days, both inclusive
+	Start time.Time
+	End   time.Time
+	// Each farm gets between MinEventsPerDay and MaxEventsPerDay events a day
+	MinEventsPerDay int
+	MaxEventsPerDay int
+	// RandSeed makes the generated data reproducible; 0 seeds from the clock
+	RandSeed int64
+}
+
+// DefaultSeedProfile returns the demo data set: 2 farms of 3 sectors with 1-3 events a day over
+// 2023-2025, so YoY comparisons work
+func DefaultSeedProfile() SeedProfile {
+	return SeedProfile{
+		Farms:           2,
+		SectorsPerFarm:  3,
+		Start:           time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		End:             time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC),
+		MinEventsPerDay: 1,
+		MaxEventsPerDay: 3,
+	}
+}
+
+// Validate checks the profile describes a non-empty data set
+func (p SeedProfile) Validate() error {
+	switch {
+	case p.Farms < 1 || p.SectorsPerFarm < 1:
+		return errors.New("farms and sectors per farm must be at least 1")
+	case p.End.Before(p.Start):
+		return errors.New("end must not be before start")
+	case p.MinEventsPerDay < 0 || p.MaxEventsPerDay < p.MinEventsPerDay:
+		return errors.New("events per day must satisfy 0 <= min <= max")
+	}
+	return nil
+}
+
+// seedBatchSize is how many irrigation records go in one insert
+const seedBatchSize = 1000
+
 // SeedRepository handles database seeding operations
 type SeedRepository struct {
 	db *gorm.DB
@@ -20,28 +64,63 @@ func NewSeedRepository(db *gorm.DB) *SeedRepository {
 	return &SeedRepository{db: db}
 }
 
-// SeedDatabase seeds the database with farms, sectors, and irrigation data
-// Generates data from 2023 to 2025 to ensure YoY comparisons work
+// SeedDatabase seeds the database with the DefaultSeedProfile data set
 func (s *SeedRepository) SeedDatabase() error {
+	return s.SeedWithProfile(DefaultSeedProfile())
+}
+
+// SeedWithProfile replaces the farms, sectors and irrigation data with a generated data set
+func (s *SeedRepository) SeedWithProfile(profile SeedProfile) error {
+	if err := profile.Validate(); err != nil {
+		return fmt.Errorf("invalid seed profile: %w", err)
+	}
+	seed := profile.RandSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(seed))
+
 	// Clear existing data (optional - comment out if you want to keep existing data)
 	if err := s.clearExistingData(); err != nil {
 		return fmt.Errorf("failed to clear existing data: %w", err)
 	}
 
 	// Create farms
-	farms, err := s.createFarms()
+	farms, err := s.createFarms(profile.Farms)
 	if err != nil {
 		return fmt.Errorf("failed to create farms: %w", err)
 	}
 
 	// Create sectors for each farm
-	sectors, err := s.createSectors(farms)
+	sectors, err := s.createSectors(farms, profile.SectorsPerFarm)
 	if err != nil {
 		return fmt.Errorf("failed to create sectors: %w", err)
 	}
 
-	// Create irrigation data spanning 2023-2025
-	totalRecords, err := s.createIrrigationData(farms, sectors)
+	// Create irrigation data over the profile's days
+	totalRecords := 0
+	batch := make([]model.IrrigationData, 0, seedBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := s.db.Create(&batch).Error; err != nil {
+			return fmt.Errorf("failed to create irrigation data batch: %w", err)
+		}
+		totalRecords += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+	err = generateIrrigationData(profile, farms, sectors, rng, func(event model.IrrigationData) error {
+		batch = append(batch, event)
+		if len(batch) < seedBatchSize {
+			return nil
+		}
+		return flush()
+	})
+	if err == nil {
+		err = flush()
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create irrigation data: %w", err)
 	}
@@ -50,6 +129,8 @@ func (s *SeedRepository) SeedDatabase() error {
 	fmt.Printf("  - Farms: %d\n", len(farms))
 	fmt.Printf("  - Sectors: %d\n", len(sectors))
 	fmt.Printf("  - Irrigation records: %d\n", totalRecords)
+	fmt.Printf("  - Days: %s to %s\n", profile.Start.Format("2006-01-02"), profile.End.Format("2006-01-02"))
+	fmt.Printf("  - Random seed: %d\n", seed)
 
 	return nil
 }
@@ -68,9 +149,9 @@ func (s *SeedRepository) clearExistingData() error {
 	return nil
 }
 
-// createFarms creates farm entities
-func (s *SeedRepository) createFarms() ([]model.Farm, error) {
-	farms := []model.Farm{
+// createFarms creates count farm entities, starting with the two demo farms
+func (s *SeedRepository) createFarms(count int) ([]model.Farm, error) {
+	demoFarms := []model.Farm{
 		{
 			Name:        "Green Valley Farm",
 			Location:    "Valley County, CA",
@@ -85,56 +166,63 @@ func (s *SeedRepository) createFarms() ([]model.Farm, error) {
 		},
 	}
 
-	if err := s.db.Create(&farms).Error; err != nil {
+	farms := make([]model.Farm, 0, count)
+	for i := 0; i < count; i++ {
+		if i < len(demoFarms) {
+			farms = append(farms, demoFarms[i])
+			continue
+		}
+		farms = append(farms, model.Farm{
+			Name:        fmt.Sprintf("Load Test Farm %d", i+1),
+			Location:    "Generated",
+			TotalArea:   400.0,
+			Description: "Generated farm for load testing",
+		})
+	}
+
+	if err := s.db.CreateInBatches(&farms, seedBatchSize).Error; err != nil {
 		return nil, err
 	}
 
 	return farms, nil
 }
 
-// createSectors creates irrigation sectors for each farm
-func (s *SeedRepository) createSectors(farms []model.Farm) ([]model.IrrigationSector, error) {
+// createSectors creates perFarm irrigation sectors for each farm
+func (s *SeedRepository) createSectors(farms []model.Farm, perFarm int) ([]model.IrrigationSector, error) {
 	sectors := []model.IrrigationSector{}
 
 	for _, farm := range farms {
-		for i := 1; i <= 3; i++ {
+		for i := 1; i <= perFarm; i++ {
 			sector := model.IrrigationSector{
 				FarmID:      farm.ID,
 				Name:        fmt.Sprintf("Sector %d", i),
-				Area:        farm.TotalArea / 3.0,
+				Area:        farm.TotalArea / float64(perFarm),
 				Description: fmt.Sprintf("Irrigation sector %d for %s", i, farm.Name),
 			}
 			sectors = append(sectors, sector)
 		}
 	}
 
-	if err := s.db.Create(&sectors).Error; err != nil {
+	if err := s.db.CreateInBatches(&sectors, seedBatchSize).Error; err != nil {
 		return nil, err
 	}
 
 	return sectors, nil
 }
 
-// createIrrigationData creates irrigation records from 2023 to 2025
-func (s *SeedRepository) createIrrigationData(farms []model.Farm, sectors []model.IrrigationSector) (int, error) {
-	// Define date range: 2023-01-01 to 2025-12-31
-	startDate := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
-	endDate := time.Date(2025, 12, 31, 23, 59, 59, 0, time.UTC)
-
+// generateIrrigationData generates each farm's daily events over the profile's days and passes
+// them to emit; the same rng seed yields the same events
+func generateIrrigationData(profile SeedProfile, farms []model.Farm, sectors []model.IrrigationSector, rng *rand.Rand, emit func(model.IrrigationData) error) error {
 	// Create a map of sectors by farm for easy lookup
 	sectorsByFarm := make(map[uint][]model.IrrigationSector)
 	for _, sector := range sectors {
 		sectorsByFarm[sector.FarmID] = append(sectorsByFarm[sector.FarmID], sector)
 	}
 
-	totalRecords := 0
-	rand.Seed(time.Now().UnixNano())
-	batchSize := 100
-	batch := []model.IrrigationData{}
-
-	// Generate records for each day over the 3-year period
-	currentDate := startDate
-	for currentDate.Before(endDate) || currentDate.Equal(endDate) {
+	// Generate records for each day of the profile
+	startDate := time.Date(profile.Start.Year(), profile.Start.Month(), profile.Start.Day(), 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(profile.End.Year(), profile.End.Month(), profile.End.Day(), 0, 0, 0, 0, time.UTC)
+	for currentDate := startDate; !currentDate.After(endDate); currentDate = currentDate.AddDate(0, 0, 1) {
 		// For each farm
 		for _, farm := range farms {
 			// Get sectors for this farm
@@ -143,17 +231,15 @@ func (s *SeedRepository) createIrrigationData(farms []model.Farm, sectors []mode
 				continue
 			}
 
-			// Generate 1-3 irrigation events per day per sector
-			// This ensures we get over 1,000 records
-			eventsPerDay := rand.Intn(3) + 1
+			eventsPerDay := profile.MinEventsPerDay + rng.Intn(profile.MaxEventsPerDay-profile.MinEventsPerDay+1)
 
 			for i := 0; i < eventsPerDay; i++ {
 				// Pick a random sector
-				sector := farmSectors[rand.Intn(len(farmSectors))]
+				sector := farmSectors[rng.Intn(len(farmSectors))]
 
 				// Generate random start time during the day (between 6 AM and 8 PM)
-				hour := rand.Intn(14) + 6 // 6-19
-				minute := rand.Intn(60)
+				hour := rng.Intn(14) + 6 // 6-19
+				minute := rng.Intn(60)
 				startTime := time.Date(
 					currentDate.Year(),
 					currentDate.Month(),
@@ -166,7 +252,7 @@ func (s *SeedRepository) createIrrigationData(farms []model.Farm, sectors []mode
 				)
 
 				// Duration between 30 minutes and 4 hours
-				durationMinutes := rand.Intn(210) + 30 // 30-240 minutes
+				durationMinutes := rng.Intn(210) + 30 // 30-240 minutes
 				endTime := startTime.Add(time.Duration(durationMinutes) * time.Minute)
 
 				// Calculate nominal and real amounts
@@ -174,7 +260,7 @@ func (s *SeedRepository) createIrrigationData(farms []model.Farm, sectors []mode
 				nominalAmount := float64(durationMinutes) * 1.0
 
 				// Efficiency factor: 0.7 to 1.3 (some events more/less efficient)
-				efficiencyFactor := 0.7 + rand.Float64()*0.6
+				efficiencyFactor := 0.7 + rng.Float64()*0.6
 				realAmount := nominalAmount * efficiencyFactor
 
 				// Add some seasonal variation (more water in summer months)
@@ -193,34 +279,15 @@ func (s *SeedRepository) createIrrigationData(farms []model.Farm, sectors []mode
 					EndTime:            endTime,
 					WaterVolume:        waterVolume,
 					Duration:           durationMinutes,
-					NominalAmount:      nominalAmount,
+					NominalAmount:      &nominalAmount,
 					RealAmount:         realAmount,
+					DataSource:         model.DataSourceSeed,
 				}
-
-				batch = append(batch, irrigationData)
-				totalRecords++
-
-				// Insert in batches for better performance
-				if len(batch) >= batchSize {
-					if err := s.db.Create(&batch).Error; err != nil {
-						return 0, fmt.Errorf("failed to create irrigation data batch: %w", err)
-					}
-					batch = []model.IrrigationData{}
+				if err := emit(irrigationData); err != nil {
+					return err
 				}
 			}
 		}
-
-		// Move to next day
-		currentDate = currentDate.AddDate(0, 0, 1)
 	}
-
-	// Insert remaining records
-	if len(batch) > 0 {
-		if err := s.db.Create(&batch).Error; err != nil {
-			return 0, fmt.Errorf("failed to create final irrigation data batch: %w", err)
-		}
-	}
-
-	return totalRecords, nil
+	return nil
 }
-
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/model"
+)
+
+// TestGenerateIrrigationData verifies a fixed seed reproduces the same events within the profile
+func TestGenerateIrrigationData(t *testing.T) {
+	profile := SeedProfile{
+		Farms:           2,
+		SectorsPerFarm:  2,
+		Start:           time.Date(2024, 2, 27, 0, 0, 0, 0, time.UTC),
+		End:             time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC),
+		MinEventsPerDay: 2,
+		MaxEventsPerDay: 4,
+		RandSeed:        7,
+	}
+	farms := []model.Farm{{ID: 1}, {ID: 2}}
+	sectors := []model.IrrigationSector{{ID: 1, FarmID: 1}, {ID: 2, FarmID: 1}, {ID: 3, FarmID: 2}, {ID: 4, FarmID: 2}}
+
+	generate := func() []model.IrrigationData {
+		var events []model.IrrigationData
+		rng := rand.New(rand.NewSource(profile.RandSeed))
+		err := generateIrrigationData(profile, farms, sectors, rng, func(event model.IrrigationData) error {
+			events = append(events, event)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return events
+	}
+
+	events := generate()
+	// 5 days (2024 is a leap year) for 2 farms at 2-4 events a day
+	if len(events) < 20 || len(events) > 40 {
+		t.Fatalf("expected 20-40 events, got %d", len(events))
+	}
+	perFarmDay := map[[2]int]int{}
+	for _, event := range events {
+		if event.StartTime.Before(profile.Start) || !event.StartTime.Before(profile.End.AddDate(0, 0, 1)) {
+			t.Errorf("event outside the profile's days: %v", event.StartTime)
+		}
+		perFarmDay[[2]int{int(event.FarmID), event.StartTime.YearDay()}]++
+	}
+	for key, count := range perFarmDay {
+		if count < profile.MinEventsPerDay || count > profile.MaxEventsPerDay {
+			t.Errorf("farm %d day %d has %d events", key[0], key[1], count)
+		}
+	}
+	if len(perFarmDay) != 10 {
+		t.Errorf("expected every farm to have events every day, got %d farm-days", len(perFarmDay))
+	}
+
+	if !reflect.DeepEqual(events, generate()) {
+		t.Error("expected the same seed to generate the same events")
+	}
+}
+
+func TestSeedProfileValidate(t *testing.T) {
+	valid := DefaultSeedProfile()
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected the default profile to be valid, got %v", err)
+	}
+
+	noFarms := valid
+	noFarms.Farms = 0
+	reversed := valid
+	reversed.End = valid.Start.AddDate(0, 0, -1)
+	inverted := valid
+	inverted.MinEventsPerDay = 5
+	for name, profile := range map[string]SeedProfile{"no farms": noFarms, "reversed days": reversed, "min above max": inverted} {
+		if err := profile.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"irrigation-analytics/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WeatherRepository defines the interface for stored daily weather
+type WeatherRepository interface {
+	UpsertObservations(observations []model.WeatherObservation) error
+	GetObservations(farmID uint, startDate, endDate time.Time) ([]model.WeatherObservation, error)
+	WithCapture(capture *QueryCapture) WeatherRepository
+	WithContext(ctx context.Context) WeatherRepository
+}
+
+// weatherRepository implements WeatherRepository
+type weatherRepository struct {
+	db *gorm.DB
+}
+
+// NewWeatherRepository creates a new weather repository
+func NewWeatherRepository(db *gorm.DB) WeatherRepository {
+	return &weatherRepository{db: db}
+}
+
+// WithCapture returns a repository whose statements are recorded by capture
+func (r *weatherRepository) WithCapture(capture *QueryCapture) WeatherRepository {
+	return &weatherRepository{db: capture.session(r.db)}
+}
+
+// WithContext returns a repository whose statements run under ctx, so they are cancelled with it
+func (r *weatherRepository) WithContext(ctx context.Context) WeatherRepository {
+	return &weatherRepository{db: r.db.WithContext(ctx)}
+}
+
+// UpsertObservations stores observations, replacing any already stored for the same farm and day
+// so a re-sync picks up the provider's revised values
+func (r *weatherRepository) UpsertObservations(observations []model.WeatherObservation) error {
+	if len(observations) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "farm_id"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"precipitation_mm", "et0_mm", "temp_min_c", "temp_max_c", "source", "updated_at"}),
+	}).Create(&observations).Error
+}
+
+// GetObservations fetches the farm's observations for days in [startDate, endDate), ordered by day
+func (r *weatherRepository) GetObservations(farmID uint, startDate, endDate time.Time) ([]model.WeatherObservation, error) {
+	var observations []model.WeatherObservation
+	err := r.db.
+		Where("farm_id = ? AND date >= ? AND date < ?", farmID, startDate, endDate).
+		Order("date ASC").
+		Find(&observations).Error
+	if err != nil {
+		return nil, err
+	}
+	return observations, nil
+}
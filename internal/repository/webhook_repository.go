@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"irrigation-analytics/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EventDayTotal is one day of a farm's events summed for alert evaluation
+type EventDayTotal struct {
+	Day           time.Time
+	WaterVolume   float64
+	NominalAmount float64
+	// RealAmount covers only the events that recorded a nominal amount
+	RealAmount float64
+}
+
+// WebhookRepository defines the interface for webhook registration and delivery operations
+type WebhookRepository interface {
+	Create(webhook *model.Webhook) error
+	List(farmID uint) ([]model.Webhook, error)
+	Get(farmID, webhookID uint) (*model.Webhook, error)
+	Delete(farmID, webhookID uint) (bool, error)
+	ListAlerting() ([]model.Webhook, error)
+	GetForDelivery(webhookID uint) (*model.Webhook, error)
+
+	Enqueue(delivery *model.WebhookDelivery) (bool, error)
+	DueDeliveries(now time.Time, limit int) ([]model.WebhookDelivery, error)
+	UpdateDelivery(delivery *model.WebhookDelivery) error
+	ListDeliveries(webhookID uint, limit int) ([]model.WebhookDelivery, error)
+
+	EventDayTotals(farmID uint, from, to time.Time) ([]EventDayTotal, error)
+	LastEventCreatedAt(farmID uint) (*time.Time, error)
+	WithContext(ctx context.Context) WebhookRepository
+}
+
+// webhookRepository implements WebhookRepository
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+// WithContext returns a repository whose statements run under ctx, so they are cancelled with it
+func (r *webhookRepository) WithContext(ctx context.Context) WebhookRepository {
+	return &webhookRepository{db: r.db.WithContext(ctx)}
+}
+
+// Create stores a new webhook
+func (r *webhookRepository) Create(webhook *model.Webhook) error {
+	return r.db.Create(webhook).Error
+}
+
+// List returns the farm's webhooks ordered by ID
+func (r *webhookRepository) List(farmID uint) ([]model.Webhook, error) {
+	var webhooks []model.Webhook
+	if err := r.db.Where("farm_id = ?", farmID).Order("id ASC").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// Get fetches a webhook of the farm, returning nil when it doesn't exist
+func (r *webhookRepository) Get(farmID, webhookID uint) (*model.Webhook, error) {
+	var webhook model.Webhook
+	err := r.db.Where("farm_id = ? AND id = ?", farmID, webhookID).First(&webhook).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// Delete soft-deletes a webhook of the farm, reporting whether it existed. Its queued deliveries
+// fail when they come due.
+func (r *webhookRepository) Delete(farmID, webhookID uint) (bool, error) {
+	result := r.db.Where("farm_id = ?", farmID).Delete(&model.Webhook{}, webhookID)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ListAlerting returns every webhook with at least one threshold set, in ID order
+func (r *webhookRepository) ListAlerting() ([]model.Webhook, error) {
+	var webhooks []model.Webhook
+	err := r.db.
+		Where("max_daily_water_volume IS NOT NULL OR min_daily_efficiency IS NOT NULL OR no_data_hours IS NOT NULL").
+		Order("id ASC").
+		Find(&webhooks).Error
+	if err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// GetForDelivery fetches a webhook by ID, returning nil when it was deleted
+func (r *webhookRepository) GetForDelivery(webhookID uint) (*model.Webhook, error) {
+	var webhook model.Webhook
+	err := r.db.First(&webhook, webhookID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// Enqueue stores a delivery unless the webhook already has one with the same key, reporting
+// whether it was stored
+func (r *webhookRepository) Enqueue(delivery *model.WebhookDelivery) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "webhook_id"}, {Name: "key"}},
+		DoNothing: true,
+	}).Create(delivery)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DueDeliveries returns up to limit pending deliveries whose next attempt is due, oldest first
+func (r *webhookRepository) DueDeliveries(now time.Time, limit int) ([]model.WebhookDelivery, error) {
+	var deliveries []model.WebhookDelivery
+	err := r.db.
+		Where("status = ? AND next_attempt_at <= ?", model.WebhookDeliveryPending, now).
+		Order("next_attempt_at ASC, id ASC").
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// UpdateDelivery saves a delivery's status and attempts
+func (r *webhookRepository) UpdateDelivery(delivery *model.WebhookDelivery) error {
+	return r.db.Save(delivery).Error
+}
+
+// ListDeliveries returns up to limit of the webhook's deliveries, newest first
+func (r *webhookRepository) ListDeliveries(webhookID uint, limit int) ([]model.WebhookDelivery, error) {
+	var deliveries []model.WebhookDelivery
+	err := r.db.Where("webhook_id = ?", webhookID).Order("id DESC").Limit(limit).Find(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// EventDayTotals sums the farm's events per UTC day in [from, to), in day order; days without
+// events have no row
+func (r *webhookRepository) EventDayTotals(farmID uint, from, to time.Time) ([]EventDayTotal, error) {
+	var totals []EventDayTotal
+	err := r.db.Model(&model.IrrigationData{}).
+		Select(`DATE(start_time) as day,
+			COALESCE(SUM(water_volume), 0) as water_volume,
+			COALESCE(SUM(nominal_amount), 0) as nominal_amount,
+			COALESCE(SUM(real_amount) FILTER (WHERE nominal_amount IS NOT NULL), 0) as real_amount`).
+		Where("farm_id = ? AND start_time >= ? AND start_time < ?", farmID, from, to).
+		Group("DATE(start_time)").
+		Order("day ASC").
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// LastEventCreatedAt returns when the farm's latest event was ingested, or nil when it has none
+func (r *webhookRepository) LastEventCreatedAt(farmID uint) (*time.Time, error) {
+	var last *time.Time
+	if err := r.db.Raw("SELECT MAX(created_at) FROM irrigation_data WHERE farm_id = ? AND deleted_at IS NULL", farmID).Scan(&last).Error; err != nil {
+		return nil, err
+	}
+	return last, nil
+}
@@ -1,29 +1,179 @@
 package service
 
 import (
+	"context"
 	"math"
+	"slices"
+	"sort"
 	"time"
 
+	"irrigation-analytics/internal/model"
 	"irrigation-analytics/internal/repository"
 )
 
 // AnalyticsService defines the interface for analytics operations
 type AnalyticsService interface {
 	FarmExists(farmID uint) (bool, error)
-	GetIrrigationAnalytics(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string) (*AnalyticsResponse, error)
+	GetIrrigationAnalytics(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string, opts AnalyticsOptions) (*AnalyticsResponse, error)
+	GetIrrigationSummary(farmID uint, sectorID *uint, startDate, endDate time.Time, opts AnalyticsOptions) (*AnalyticsResponse, error)
+	GetTopContributors(farmID uint, startDate, endDate time.Time, dimension, baseline string, limit int) (*ContributorsResponse, error)
+	GetTimeSeries(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string, metrics []string) (*TimeSeriesResponse, error)
+	// WithContext returns a service whose queries run under ctx, typically the request context,
+	// so they stop when the client goes away
+	WithContext(ctx context.Context) AnalyticsService
+}
+
+// Efficiency weighting modes for summary and comparison efficiency
+const (
+	// EfficiencyWeightingMean averages bucket efficiencies, each bucket counting equally
+	EfficiencyWeightingMean = "mean"
+	// EfficiencyWeightingVolume computes sum(real) / sum(nominal) so buckets count by volume
+	EfficiencyWeightingVolume = "volume"
+)
+
+// Year alignments: how the periods one or more years back are placed
+const (
+	// YearAlignmentCalendar compares the same calendar dates years back
+	YearAlignmentCalendar = "calendar"
+	// YearAlignmentSeason compares the same position in the farm's crop year years back: the same
+	// time since the crop year started, over a period of the same length
+	YearAlignmentSeason = "season"
+)
+
+// Sections a request can omit besides the optional sections that report warnings
+const (
+	// SectionData is the data points, with their annotations, pressure, weather and trend
+	SectionData = "data"
+	// SectionComparisons is comparisons, period_comparison and year_over_year; omitting it queries
+	// the current period only
+	SectionComparisons = "comparisons"
+)
+
+// OmittableSections lists the sections AnalyticsOptions.Omit accepts
+var OmittableSections = []string{SectionData, SectionComparisons, SectionSectorBreakdown, SectionAnnotations, SectionPressure}
+
+// AnalyticsOptions holds optional behaviour for an analytics request
+type AnalyticsOptions struct {
+	// EfficiencyWeighting is EfficiencyWeightingMean (default) or EfficiencyWeightingVolume
+	EfficiencyWeighting string
+	// ExcludeAnnotated leaves events under an exclude_from_efficiency annotation out of efficiency
+	ExcludeAnnotated bool
+	// ExcludeSeed leaves seeded demo data out of every figure
+	ExcludeSeed bool
+	// Purposes limits every figure to events with these purposes; empty means all purposes
+	Purposes []string
+	// DeviceID limits every figure to events measured by this device; nil means every event
+	DeviceID *uint
+	// IncludeDeleted keeps soft-deleted events in every figure, for audits
+	IncludeDeleted bool
+	// BreakdownByPurpose adds per-purpose totals to the response
+	BreakdownByPurpose bool
+	// Debug adds the executed SQL and per-stage timings to the response
+	Debug bool
+	// NormalizeByArea adds per-hectare volume and event counts using sector areas
+	NormalizeByArea bool
+	// IncludeWeather adds each bucket's stored rainfall and reference evapotranspiration
+	IncludeWeather bool
+	// IncludeDistribution adds per-event percentiles, extremes and standard deviation to the summary
+	IncludeDistribution bool
+	// IncludeTrend adds each data point's moving averages and its series' trend slopes
+	IncludeTrend bool
+	// SplitEvents spreads events running past midnight over the days they span, in proportion to
+	// the time spent in each, instead of attributing them to their start day
+	SplitEvents bool
+	// FillGaps adds a zero-valued data point for every bucket of the range that has no events
+	FillGaps bool
+	// Units is UnitsMetric (default) or UnitsImperial to report gallons, acres and inches
+	Units string
+	// Level rolls data points and the sector breakdown up to the sectors at this depth of the
+	// hierarchy, 1 being top-level sectors; 0 keeps every sector and zone separate
+	Level int
+	// SectorScope serves one sector's own analytics: the sector must be on the farm, or the request
+	// fails with ErrSectorNotFound, and the sector breakdown lists the sector and its zones
+	SectorScope bool
+	// attribution is the farm's stored attribution policy, looked up by the service
+	attribution string
+	// Strict fails the request with a SectionError when an optional section's query fails,
+	// instead of omitting the section and adding a warning
+	Strict bool
+	// CompareTo lists the periods the response's comparisons cover, in order; nil compares to the
+	// same period one and two years back
+	CompareTo []ComparisonPeriod
+	// YearAlignment is YearAlignmentCalendar (default) or YearAlignmentSeason
+	YearAlignment string
+	// Omit lists OmittableSections to leave out of the response; the queries behind them are skipped
+	Omit []string
+	// cropYear is the farm's crop year, looked up by the service for season alignment
+	cropYear *model.CropYear
+}
+
+// omits reports whether the request leaves section out of the response
+func (o AnalyticsOptions) omits(section string) bool {
+	return slices.Contains(o.Omit, section)
+}
+
+// queryOptions maps the request options onto repository query options
+func (o AnalyticsOptions) queryOptions() repository.QueryOptions {
+	return repository.QueryOptions{
+		ExcludeAnnotated: o.ExcludeAnnotated,
+		ExcludeSeed:      o.ExcludeSeed,
+		Purposes:         o.Purposes,
+		SplitEvents:      o.SplitEvents,
+		Attribution:      o.attribution,
+		DeviceID:         o.DeviceID,
+		IncludeDeleted:   o.IncludeDeleted,
+		CropYear:         o.cropYear,
+	}
+}
+
+// cropYearStart formats the crop year the periods years back are aligned on, empty for calendar alignment
+func cropYearStart(cropYear *model.CropYear) string {
+	if cropYear == nil {
+		return ""
+	}
+	return cropYear.String()
 }
 
 // AnalyticsResponse represents the analytics data response
 type AnalyticsResponse struct {
-	FarmID           uint                   `json:"farm_id"`
-	SectorID         *uint                  `json:"sector_id,omitempty"`
-	Period           PeriodInfo             `json:"period"`
-	Aggregation      string                 `json:"aggregation"`
+	FarmID   uint  `json:"farm_id"`
+	SectorID *uint `json:"sector_id,omitempty"`
+	DeviceID *uint `json:"device_id,omitempty"`
+	// IncludeDeleted is set when the figures count soft-deleted events
+	IncludeDeleted      bool       `json:"include_deleted,omitempty"`
+	Period              PeriodInfo `json:"period"`
+	Aggregation         string     `json:"aggregation"`
+	EfficiencyWeighting string     `json:"efficiency_weighting"`
+	// CropYearStart is set when the periods years back are season-aligned: the MM-DD start of the
+	// farm's crop year they are aligned on
+	CropYearStart string `json:"crop_year_start,omitempty"`
+	// Units is the unit system of the figures: UnitsMetric, or UnitsImperial when requested
+	Units            string                 `json:"units"`
 	Data             []AggregatedDataPoint  `json:"data"`
 	Summary          AnalyticsSummary       `json:"summary"`
+	Comparisons      []ComparisonBlock      `json:"comparisons,omitempty"`
 	PeriodComparison PeriodComparison       `json:"period_comparison"`
 	SectorBreakdown  []SectorBreakdown      `json:"sector_breakdown,omitempty"`
+	PurposeBreakdown []PurposeBreakdown     `json:"purpose_breakdown,omitempty"`
 	YearOverYear     YearOverYearComparison `json:"year_over_year"`
+	DataQuality      DataQuality            `json:"data_quality"`
+	// FinalThrough is the farm's rollup watermark; buckets ending on or before it are final
+	FinalThrough *time.Time `json:"final_through,omitempty"`
+	// Warnings lists optional sections omitted because their query failed
+	Warnings []Warning  `json:"warnings,omitempty"`
+	Debug    *DebugInfo `json:"_debug,omitempty"`
+}
+
+// DataQuality reports how much of the response relied on substituted values instead of recorded data
+type DataQuality struct {
+	// EventsMissingNominal counts events with no recorded nominal_amount (NULL, not 0)
+	EventsMissingNominal int `json:"events_missing_nominal"`
+	// FallbackEvents counts events whose efficiency used the duration heuristic
+	FallbackEvents int `json:"fallback_events"`
+	// FallbackBuckets counts data points whose efficiency used the duration heuristic
+	FallbackBuckets int `json:"fallback_buckets"`
+	// EventsExcludedFromEfficiency counts annotated events left out of real and nominal amounts
+	EventsExcludedFromEfficiency int `json:"events_excluded_from_efficiency"`
 }
 
 // PeriodInfo contains date range information
@@ -41,6 +191,32 @@ type AggregatedDataPoint struct {
 	EventCount    int       `json:"event_count"`
 	RealAmount    float64   `json:"real_amount"`
 	NominalAmount float64   `json:"nominal_amount"`
+	// IsFinal marks points whose whole bucket was rolled up; other points may still change as
+	// late events arrive
+	IsFinal bool `json:"is_final"`
+	// UsedFallback marks points whose efficiency was estimated from duration because no event
+	// recorded nominal_amount; FallbackFlowRate is the liters per minute assumed
+	UsedFallback     bool     `json:"used_fallback,omitempty"`
+	FallbackFlowRate *float64 `json:"fallback_flow_rate,omitempty"`
+	// Annotations covering the bucket's period and sector
+	Annotations []model.Annotation `json:"annotations,omitempty"`
+	// MinPressure and AvgPressure are in bar, omitted when the sector reported no readings
+	MinPressure *float64 `json:"min_pressure,omitempty"`
+	AvgPressure *float64 `json:"avg_pressure,omitempty"`
+	// WaterPerHectare, EventsPerHectare and AppliedDepthMM are set with NormalizeByArea when the
+	// sector has an area
+	WaterPerHectare  *float64 `json:"water_per_hectare,omitempty"`
+	EventsPerHectare *float64 `json:"events_per_hectare,omitempty"`
+	AppliedDepthMM   *float64 `json:"applied_depth_mm,omitempty"`
+	// RainfallMM and ET0MM are the bucket's precipitation and reference evapotranspiration, set with
+	// IncludeWeather on daily and coarser buckets that have stored weather
+	RainfallMM *float64 `json:"rainfall_mm,omitempty"`
+	ET0MM      *float64 `json:"et0_mm,omitempty"`
+	// WeatherEvents lists WeatherEventFrost and WeatherEventHeat when a day of the bucket had
+	// them, set with IncludeWeather alongside the weather values
+	WeatherEvents []string `json:"weather_events,omitempty"`
+	// Trend is set with IncludeTrend
+	Trend *PointTrend `json:"trend,omitempty"`
 }
 
 // AnalyticsSummary contains summary statistics
@@ -51,9 +227,17 @@ type AnalyticsSummary struct {
 	TotalEvents        int     `json:"total_events"`
 	TotalRealAmount    float64 `json:"total_real_amount"`
 	TotalNominalAmount float64 `json:"total_nominal_amount"`
+	// WaterPerHectare, EventsPerHectare and AppliedDepthMM are set with NormalizeByArea when the
+	// irrigated area is known
+	WaterPerHectare  *float64 `json:"water_per_hectare,omitempty"`
+	EventsPerHectare *float64 `json:"events_per_hectare,omitempty"`
+	AppliedDepthMM   *float64 `json:"applied_depth_mm,omitempty"`
+	// Distribution is set with IncludeDistribution
+	Distribution *SummaryDistribution `json:"distribution,omitempty"`
 }
 
-// PeriodComparison contains comparison metrics between periods
+// PeriodComparison contains comparison metrics between the period and the same period one and two
+// years back. It predates Comparisons and is kept for backward compatibility.
 type PeriodComparison struct {
 	OneYearAgo  *PeriodMetrics `json:"one_year_ago,omitempty"`
 	TwoYearsAgo *PeriodMetrics `json:"two_years_ago,omitempty"`
@@ -72,12 +256,26 @@ type PeriodMetrics struct {
 
 // SectorBreakdown contains analytics broken down by sector
 type SectorBreakdown struct {
-	SectorID           uint    `json:"sector_id"`
-	TotalWaterVolume   float64 `json:"total_water_volume"`
-	TotalEvents        int     `json:"total_events"`
-	AverageEfficiency  float64 `json:"average_efficiency"`
-	TotalRealAmount    float64 `json:"total_real_amount"`
-	TotalNominalAmount float64 `json:"total_nominal_amount"`
+	SectorID           uint           `json:"sector_id"`
+	TotalWaterVolume   float64        `json:"total_water_volume"`
+	TotalEvents        int            `json:"total_events"`
+	AverageEfficiency  float64        `json:"average_efficiency"`
+	TotalRealAmount    float64        `json:"total_real_amount"`
+	TotalNominalAmount float64        `json:"total_nominal_amount"`
+	WaterPerHectare    *float64       `json:"water_per_hectare,omitempty"`
+	EventsPerHectare   *float64       `json:"events_per_hectare,omitempty"`
+	AppliedDepthMM     *float64       `json:"applied_depth_mm,omitempty"`
+	OneYearAgo         *PeriodMetrics `json:"one_year_ago,omitempty"`
+}
+
+// PurposeBreakdown contains period totals for one event purpose
+type PurposeBreakdown struct {
+	Purpose           string  `json:"purpose"`
+	TotalWaterVolume  float64 `json:"total_water_volume"`
+	TotalEvents       int     `json:"total_events"`
+	AverageEfficiency float64 `json:"average_efficiency"`
+	// ShareOfVolumePercent is the purpose's share of the period's total water volume
+	ShareOfVolumePercent float64 `json:"share_of_volume_percent"`
 }
 
 // YearOverYearComparison contains YoY comparison data
@@ -98,12 +296,38 @@ type YearComparison struct {
 
 // analyticsService implements AnalyticsService
 type analyticsService struct {
-	repo repository.IrrigationRepository
+	repo        repository.IrrigationRepository
+	annotations repository.AnnotationRepository
+	pressure    repository.PressureRepository
+	weather     repository.WeatherRepository
+	budget      QueryBudget
+	// trace is set only on the per-request copy made for debug requests
+	trace *debugTrace
+	// fallbackRates holds per-sector fallback flow rates on the per-request copy made by
+	// withFallbackRates; nil means every sector uses DefaultFallbackFlowRate
+	fallbackRates map[uint]float64
 }
 
-// NewAnalyticsService creates a new analytics service
-func NewAnalyticsService(repo repository.IrrigationRepository) AnalyticsService {
-	return &analyticsService{repo: repo}
+// NewAnalyticsService creates a new analytics service. annotations, pressure and weather may be
+// nil, in which case data points carry no annotations, pressure or weather.
+func NewAnalyticsService(repo repository.IrrigationRepository, annotations repository.AnnotationRepository, pressure repository.PressureRepository, weather repository.WeatherRepository) AnalyticsService {
+	return &analyticsService{repo: repo, annotations: annotations, pressure: pressure, weather: weather, budget: DefaultQueryBudget}
+}
+
+// WithContext returns a copy of the service whose repositories run their statements under ctx
+func (s *analyticsService) WithContext(ctx context.Context) AnalyticsService {
+	bound := *s
+	bound.repo = s.repo.WithContext(ctx)
+	if s.annotations != nil {
+		bound.annotations = s.annotations.WithContext(ctx)
+	}
+	if s.pressure != nil {
+		bound.pressure = s.pressure.WithContext(ctx)
+	}
+	if s.weather != nil {
+		bound.weather = s.weather.WithContext(ctx)
+	}
+	return &bound
 }
 
 // FarmExists checks if a farm exists
@@ -112,51 +336,346 @@ func (s *analyticsService) FarmExists(farmID uint) (bool, error) {
 }
 
 // GetIrrigationAnalytics retrieves and processes irrigation analytics
-func (s *analyticsService) GetIrrigationAnalytics(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string) (*AnalyticsResponse, error) {
+func (s *analyticsService) GetIrrigationAnalytics(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string, opts AnalyticsOptions) (*AnalyticsResponse, error) {
+	if opts.Debug && s.trace == nil {
+		return s.debugged(func(debug *analyticsService) (*AnalyticsResponse, error) {
+			return debug.GetIrrigationAnalytics(farmID, sectorID, startDate, endDate, aggregation, opts)
+		})
+	}
+
 	// Validate aggregation level
 	if aggregation == "" {
 		aggregation = "daily"
 	}
-	if aggregation != "daily" && aggregation != "weekly" && aggregation != "monthly" {
+	if !validAggregation(aggregation) {
 		aggregation = "daily"
 	}
+	weighting := opts.EfficiencyWeighting
+	if weighting != EfficiencyWeightingVolume {
+		weighting = EfficiencyWeightingMean
+	}
+	if err := s.budget.check(s.analyticsPlan(sectorID, startDate, endDate, aggregation, opts)); err != nil {
+		return nil, err
+	}
+	var subtree map[uint]bool
+	if opts.SectorScope && sectorID != nil {
+		var err error
+		if subtree, err = s.sectorSubtree(farmID, *sectorID); err != nil {
+			return nil, err
+		}
+	}
+	attribution, err := s.repo.GetAttributionPolicy(farmID)
+	if err != nil {
+		return nil, err
+	}
+	opts.attribution = attribution
+	if opts.YearAlignment == YearAlignmentSeason {
+		cropYear, err := s.repo.GetCropYear(farmID)
+		if err != nil {
+			return nil, err
+		}
+		opts.cropYear = &cropYear
+	}
 
-	// Fetch current period data
-	currentData, err := s.repo.GetAggregatedData(farmID, sectorID, startDate, endDate, aggregation)
+	// Fetch current, -1 year, -2 years and any other years back compared to in a single round
+	// trip, and the other comparison periods in a second one
+	comparisons := opts.CompareTo
+	if comparisons == nil {
+		comparisons = DefaultComparisons(startDate, endDate)
+	}
+	comparisons = alignYearsBack(comparisons, startDate, endDate, opts.queryOptions())
+	yearsBack, ranges := comparisonPlan(comparisons)
+	if opts.omits(SectionComparisons) {
+		// Only the current period is queried
+		yearsBack, ranges = nil, nil
+	}
+	comparisonData, err := s.repo.GetComparisonData(farmID, sectorID, startDate, endDate, aggregation, yearsBack, opts.queryOptions())
+	if err != nil {
+		return nil, err
+	}
+	s.trace.mark("comparison_query")
+	var rangesData [][]repository.AggregatedDataWithCount
+	if len(ranges) > 0 {
+		if rangesData, err = s.repo.GetRangesData(farmID, sectorID, ranges, aggregation, opts.queryOptions()); err != nil {
+			return nil, err
+		}
+		s.trace.mark("comparison_ranges_query")
+	}
+
+	// Merge zone buckets into their sectors at the requested level
+	rollUp, err := s.sectorHierarchy(farmID, opts)
+	if err != nil {
+		return nil, err
+	}
+	if rollUp != nil {
+		rollUpComparison(comparisonData, rollUp)
+		for i := range rangesData {
+			rangesData[i] = rollUpSectors(rangesData[i], rollUp)
+		}
+		s.trace.mark("sector_hierarchy")
+	}
+	currentData := comparisonData[0]
+
+	// Continue with a copy that applies each sector's own fallback flow rate
+	fallbackData := append([][]repository.AggregatedDataWithCount{comparisonData[0]}, rangesData...)
+	for _, offset := range yearsBack {
+		fallbackData = append(fallbackData, comparisonData[offset])
+	}
+	s, err = s.withFallbackRates(farmID, fallbackData...)
 	if err != nil {
 		return nil, err
 	}
 
 	// Process current period data
 	dataPoints := s.processDataPoints(currentData, aggregation)
-	summary := s.calculateSummary(currentData)
+	if opts.IncludeTrend && !opts.omits(SectionData) {
+		attachTrend(dataPoints, currentData)
+	}
+	summary := s.calculateSummary(currentData, weighting)
+	dataQuality := s.calculateDataQuality(currentData)
+	s.trace.mark("process_data_points")
+
+	// The remaining queries are independent of each other and run concurrently. Annotations,
+	// pressure, weather, distribution and the sector breakdown are optional: a failed lookup omits
+	// the section with a warning, or fails the request in strict mode. Annotations, pressure and
+	// weather only decorate data points, so omitting the data skips them too.
+	var sectorBreakdown []SectorBreakdown
+	var purposeBreakdown []PurposeBreakdown
+	var tasks []sectionTask
+	if !opts.omits(SectionAnnotations) && !opts.omits(SectionData) {
+		tasks = append(tasks, sectionTask{stage: "annotations", section: SectionAnnotations, run: func() error {
+			return s.attachAnnotations(dataPoints, currentData, farmID, startDate, endDate, aggregation)
+		}})
+	}
+	if !opts.omits(SectionPressure) && !opts.omits(SectionData) {
+		tasks = append(tasks, sectionTask{stage: "pressure", section: SectionPressure, run: func() error {
+			return s.attachPressure(dataPoints, currentData, farmID, sectorID, startDate, endDate, aggregation)
+		}})
+	}
+	if opts.IncludeWeather && !opts.omits(SectionData) {
+		tasks = append(tasks, sectionTask{stage: "weather", section: SectionWeather, run: func() error {
+			return s.attachWeather(dataPoints, currentData, farmID, startDate, endDate, aggregation)
+		}})
+	}
+	if opts.IncludeDistribution {
+		tasks = append(tasks, sectionTask{stage: "distribution", section: SectionDistribution, run: func() error {
+			return s.attachDistribution(&summary, farmID, sectorID, startDate, endDate, opts.queryOptions())
+		}})
+	}
+	// Sector breakdown (if not filtering by specific sector, or of the sector's own subtree for a
+	// sector-scoped request)
+	if (sectorID == nil || subtree != nil) && !opts.omits(SectionSectorBreakdown) {
+		tasks = append(tasks, sectionTask{stage: "sector_breakdown", section: SectionSectorBreakdown, run: func() error {
+			target := rollUp
+			if subtree != nil {
+				target = subtreeTarget(*sectorID, subtree, rollUp)
+			}
+			breakdown, err := s.calculateSectorBreakdown(farmID, startDate, endDate, opts.queryOptions(), target)
+			if err != nil {
+				return err
+			}
+			if subtree != nil {
+				breakdown = withoutSector(breakdown, 0)
+			}
+			sectorBreakdown = breakdown
+			return nil
+		}})
+	}
+	// Purpose breakdown (only when requested)
+	if opts.BreakdownByPurpose {
+		tasks = append(tasks, sectionTask{stage: "purpose_breakdown", run: func() error {
+			var err error
+			purposeBreakdown, err = s.calculatePurposeBreakdown(farmID, sectorID, startDate, endDate, opts.queryOptions())
+			return err
+		}})
+	}
 
-	// Calculate period comparison (YoY with detailed metrics)
-	periodComparison := s.calculatePeriodComparison(farmID, sectorID, startDate, endDate, aggregation, summary)
+	var warnings []Warning
+	for i, err := range s.runSections(tasks) {
+		if err == nil {
+			continue
+		}
+		if tasks[i].section == "" {
+			return nil, err
+		}
+		if err := sectionFailed(&warnings, opts.Strict, tasks[i].section, err); err != nil {
+			return nil, err
+		}
+	}
 
-	// Calculate sector breakdown (if not filtering by specific sector)
-	var sectorBreakdown []SectorBreakdown
-	if sectorID == nil {
-		sectorBreakdown = s.calculateSectorBreakdown(farmID, startDate, endDate, aggregation)
+	// Comparisons, the legacy period comparison and the legacy YoY all come from the comparison
+	// queries
+	var comparisonBlocks []ComparisonBlock
+	var periodComparison PeriodComparison
+	var yoy YearOverYearComparison
+	if !opts.omits(SectionComparisons) {
+		comparisonBlocks = s.calculateComparisons(comparisons, comparisonData, rangesData, summary, weighting)
+		periodComparison = s.calculatePeriodComparison(startDate, endDate, comparisonData, summary, weighting, opts.queryOptions())
+		s.trace.mark("period_comparison")
+
+		// Legacy YoY format kept for backward compatibility
+		yoy = s.calculateYearOverYear(startDate, endDate, comparisonData, summary, weighting, opts.queryOptions())
+		s.trace.mark("year_over_year")
+	}
+
+	response := &AnalyticsResponse{
+		FarmID:         farmID,
+		SectorID:       sectorID,
+		DeviceID:       opts.DeviceID,
+		IncludeDeleted: opts.IncludeDeleted,
+		Period: PeriodInfo{
+			StartDate: startDate,
+			EndDate:   endDate,
+		},
+		Aggregation:         aggregation,
+		EfficiencyWeighting: weighting,
+		CropYearStart:       cropYearStart(opts.cropYear),
+		Data:                dataPoints,
+		Summary:             summary,
+		Comparisons:         comparisonBlocks,
+		PeriodComparison:    periodComparison,
+		SectorBreakdown:     sectorBreakdown,
+		PurposeBreakdown:    purposeBreakdown,
+		YearOverYear:        yoy,
+		DataQuality:         dataQuality,
+		Warnings:            warnings,
+	}
+
+	if opts.NormalizeByArea {
+		if err := s.attachAreaNormalization(response, currentData, farmID, sectorID); err != nil {
+			return nil, err
+		}
+		s.trace.mark("area_normalization")
+	}
+
+	if opts.omits(SectionData) {
+		response.Data = []AggregatedDataPoint{}
+	} else if opts.FillGaps {
+		response.Data = fillGaps(response.Data, startDate, endDate, aggregation)
 	}
 
-	// Fetch YoY data (legacy format for backward compatibility)
-	yoy := s.calculateYearOverYear(farmID, sectorID, startDate, endDate, aggregation, summary)
+	finalThrough, err := s.repo.GetRolledUpThrough(farmID)
+	if err != nil {
+		return nil, err
+	}
+	markFinal(response, finalThrough, aggregation)
+	applyUnits(response, opts.Units)
+
+	return response, nil
+}
+
+// markFinal flags the points whose bucket ends on or before the rollup watermark; without a
+// watermark no point is final
+func markFinal(response *AnalyticsResponse, finalThrough *time.Time, aggregation string) {
+	if finalThrough == nil {
+		return
+	}
+	through := time.Date(finalThrough.Year(), finalThrough.Month(), finalThrough.Day(), 0, 0, 0, 0, time.UTC)
+	response.FinalThrough = &through
+	for i := range response.Data {
+		response.Data[i].IsFinal = !nextBucket(response.Data[i].Period, aggregation).After(through)
+	}
+}
+
+// GetIrrigationSummary retrieves only the summary section using a single-row totals query.
+// Data points, comparisons and sector breakdown are not computed. Because there are no buckets,
+// average efficiency is always volume-weighted (the ratio of period totals).
+func (s *analyticsService) GetIrrigationSummary(farmID uint, sectorID *uint, startDate, endDate time.Time, opts AnalyticsOptions) (*AnalyticsResponse, error) {
+	if opts.Debug && s.trace == nil {
+		return s.debugged(func(debug *analyticsService) (*AnalyticsResponse, error) {
+			return debug.GetIrrigationSummary(farmID, sectorID, startDate, endDate, opts)
+		})
+	}
 
-	return &AnalyticsResponse{
-		FarmID:   farmID,
-		SectorID: sectorID,
+	if opts.SectorScope && sectorID != nil {
+		if _, err := s.sectorSubtree(farmID, *sectorID); err != nil {
+			return nil, err
+		}
+	}
+	attribution, err := s.repo.GetAttributionPolicy(farmID)
+	if err != nil {
+		return nil, err
+	}
+	opts.attribution = attribution
+
+	totals, err := s.repo.GetSummaryData(farmID, sectorID, startDate, endDate, opts.queryOptions())
+	if err != nil {
+		return nil, err
+	}
+	s.trace.mark("summary_query")
+
+	// Treat the totals as one bucket so the same fallback rules apply, with the sector's own
+	// fallback flow rate when the summary covers a single sector
+	totalsBucket := []repository.AggregatedDataWithCount{summaryBucket(totals)}
+	if sectorID != nil {
+		totalsBucket[0].Data.IrrigationSectorID = *sectorID
+		s, err = s.withFallbackRates(farmID, totalsBucket)
+		if err != nil {
+			return nil, err
+		}
+	}
+	efficiency, _, _, _ := s.bucketEfficiency(totalsBucket[0])
+
+	summary := AnalyticsSummary{
+		TotalWaterVolume:   math.Round(totals.WaterVolume*100) / 100,
+		TotalDuration:      totals.Duration,
+		AverageEfficiency:  efficiency,
+		TotalEvents:        totals.EventCount,
+		TotalRealAmount:    math.Round(totals.RealAmount*100) / 100,
+		TotalNominalAmount: math.Round(totals.NominalAmount*100) / 100,
+	}
+	var warnings []Warning
+	if opts.IncludeDistribution {
+		if err := s.attachDistribution(&summary, farmID, sectorID, startDate, endDate, opts.queryOptions()); err != nil {
+			if err := sectionFailed(&warnings, opts.Strict, SectionDistribution, err); err != nil {
+				return nil, err
+			}
+		}
+		s.trace.mark("distribution")
+	}
+
+	response := &AnalyticsResponse{
+		FarmID:         farmID,
+		SectorID:       sectorID,
+		DeviceID:       opts.DeviceID,
+		IncludeDeleted: opts.IncludeDeleted,
 		Period: PeriodInfo{
 			StartDate: startDate,
 			EndDate:   endDate,
 		},
-		Aggregation:      aggregation,
-		Data:             dataPoints,
-		Summary:          summary,
-		PeriodComparison: periodComparison,
-		SectorBreakdown:  sectorBreakdown,
-		YearOverYear:     yoy,
-	}, nil
+		Aggregation:         "summary",
+		EfficiencyWeighting: EfficiencyWeightingVolume,
+		Data:                []AggregatedDataPoint{},
+		Summary:             summary,
+		DataQuality:         s.calculateDataQuality(totalsBucket),
+		Warnings:            warnings,
+	}
+
+	if opts.NormalizeByArea {
+		if err := s.attachAreaNormalization(response, nil, farmID, sectorID); err != nil {
+			return nil, err
+		}
+		s.trace.mark("area_normalization")
+	}
+	applyUnits(response, opts.Units)
+
+	return response, nil
+}
+
+// summaryBucket wraps period totals as a single aggregated bucket
+func summaryBucket(totals *repository.SummaryResult) repository.AggregatedDataWithCount {
+	nominalAmount := totals.NominalAmount
+	return repository.AggregatedDataWithCount{
+		Data: model.IrrigationData{
+			WaterVolume:   totals.WaterVolume,
+			Duration:      totals.Duration,
+			NominalAmount: &nominalAmount,
+			RealAmount:    totals.RealAmount,
+		},
+		EventCount:          totals.EventCount,
+		MissingNominalCount: totals.MissingNominalCount,
+		ExcludedEventCount:  totals.ExcludedEventCount,
+	}
 }
 
 // calculateEfficiency calculates efficiency = real_amount / nominal_amount
@@ -171,40 +690,70 @@ func (s *analyticsService) calculateEfficiency(realAmount, nominalAmount float64
 	return math.Round(efficiency*10000) / 10000 // Round to 4 decimal places
 }
 
+// bucketEfficiency computes the efficiency of an aggregated bucket together with the real and
+// nominal amounts it was derived from. Only buckets where no event recorded nominal_amount fall
+// back to water_volume against the sector's fallback flow rate times duration; a recorded 0 is
+// kept as is. Buckets with events excluded by annotation never fall back, since water_volume and
+// duration still include the excluded events.
+func (s *analyticsService) bucketEfficiency(item repository.AggregatedDataWithCount) (efficiency, realAmount, nominalAmount float64, usedFallback bool) {
+	d := item.Data
+	if needsFallback(item) {
+		// Fallback: use water_volume as real and calculate nominal from duration
+		nominalVolume := float64(d.Duration) * s.fallbackFlowRate(d.IrrigationSectorID)
+		return s.calculateEfficiency(d.WaterVolume, nominalVolume), d.WaterVolume, nominalVolume, true
+	}
+
+	nominal := d.NominalAmountOrZero()
+	return s.calculateEfficiency(d.RealAmount, nominal), d.RealAmount, nominal, false
+}
+
+// calculateDataQuality counts missing nominal amounts and fallback usage across buckets
+func (s *analyticsService) calculateDataQuality(data []repository.AggregatedDataWithCount) DataQuality {
+	quality := DataQuality{}
+	for _, item := range data {
+		quality.EventsMissingNominal += item.MissingNominalCount
+		quality.EventsExcludedFromEfficiency += item.ExcludedEventCount
+		if _, _, _, usedFallback := s.bucketEfficiency(item); usedFallback {
+			quality.FallbackEvents += item.EventCount
+			quality.FallbackBuckets++
+		}
+	}
+	return quality
+}
+
 // processDataPoints converts raw data to aggregated data points with efficiency calculation
 func (s *analyticsService) processDataPoints(data []repository.AggregatedDataWithCount, aggregation string) []AggregatedDataPoint {
 	points := make([]AggregatedDataPoint, 0, len(data))
 
 	for _, item := range data {
 		d := item.Data
-		// Calculate efficiency using RealAmount and NominalAmount
-		efficiency := s.calculateEfficiency(d.RealAmount, d.NominalAmount)
-
-		// If RealAmount or NominalAmount are not set, fall back to water_volume calculation
-		if d.RealAmount == 0 && d.NominalAmount == 0 && d.WaterVolume > 0 {
-			// Fallback: use water_volume as real and calculate nominal from duration
-			if d.Duration > 0 {
-				nominalVolume := float64(d.Duration) * 1.0 // 1 liter per minute
-				efficiency = s.calculateEfficiency(d.WaterVolume, nominalVolume)
-			}
-		}
+		// Calculate efficiency using RealAmount and NominalAmount, or the duration fallback
+		efficiency, _, _, usedFallback := s.bucketEfficiency(item)
 
-		points = append(points, AggregatedDataPoint{
+		point := AggregatedDataPoint{
 			Period:        d.StartTime,
 			WaterVolume:   d.WaterVolume,
 			Duration:      d.Duration,
 			Efficiency:    efficiency,
 			EventCount:    item.EventCount, // Use event_count from aggregation
 			RealAmount:    d.RealAmount,
-			NominalAmount: d.NominalAmount,
-		})
+			NominalAmount: d.NominalAmountOrZero(),
+			UsedFallback:  usedFallback,
+		}
+		if usedFallback {
+			rate := s.fallbackFlowRate(d.IrrigationSectorID)
+			point.FallbackFlowRate = &rate
+		}
+		points = append(points, point)
 	}
 
 	return points
 }
 
-// calculateSummary computes summary statistics
-func (s *analyticsService) calculateSummary(data []repository.AggregatedDataWithCount) AnalyticsSummary {
+// calculateSummary computes summary statistics.
+// weighting selects how AverageEfficiency combines buckets: EfficiencyWeightingMean averages
+// bucket efficiencies, EfficiencyWeightingVolume divides total real by total nominal amount.
+func (s *analyticsService) calculateSummary(data []repository.AggregatedDataWithCount, weighting string) AnalyticsSummary {
 	var totalWaterVolume float64
 	var totalDuration int
 	var totalEfficiency float64
@@ -212,32 +761,33 @@ func (s *analyticsService) calculateSummary(data []repository.AggregatedDataWith
 	var totalRealAmount float64
 	var totalNominalAmount float64
 	var totalEvents int
+	// Real and nominal totals including fallback amounts, used for volume weighting
+	var weightedReal float64
+	var weightedNominal float64
 
 	for _, item := range data {
 		d := item.Data
 		totalWaterVolume += d.WaterVolume
 		totalDuration += d.Duration
 		totalRealAmount += d.RealAmount
-		totalNominalAmount += d.NominalAmount
+		totalNominalAmount += d.NominalAmountOrZero()
 		totalEvents += item.EventCount // Sum event counts from aggregation
 
-		// Calculate efficiency for summary
-		efficiency := s.calculateEfficiency(d.RealAmount, d.NominalAmount)
-
-		// If efficiency couldn't be calculated from RealAmount/NominalAmount, use fallback
-		if efficiency == 0 && d.WaterVolume > 0 && d.Duration > 0 {
-			nominalVolume := float64(d.Duration) * 1.0
-			efficiency = s.calculateEfficiency(d.WaterVolume, nominalVolume)
-		}
+		// Calculate efficiency for summary, using the duration fallback when nominal wasn't recorded
+		efficiency, realAmount, nominalAmount, _ := s.bucketEfficiency(item)
 
 		if efficiency > 0 {
 			totalEfficiency += efficiency
 			efficiencyCount++
+			weightedReal += realAmount
+			weightedNominal += nominalAmount
 		}
 	}
 
 	avgEfficiency := 0.0
-	if efficiencyCount > 0 {
+	if weighting == EfficiencyWeightingVolume {
+		avgEfficiency = s.calculateEfficiency(weightedReal, weightedNominal)
+	} else if efficiencyCount > 0 {
 		avgEfficiency = totalEfficiency / float64(efficiencyCount)
 	}
 
@@ -251,124 +801,145 @@ func (s *analyticsService) calculateSummary(data []repository.AggregatedDataWith
 	}
 }
 
-// calculatePeriodComparison computes period comparison with percentage changes for volume, events, and efficiency
-func (s *analyticsService) calculatePeriodComparison(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string, currentSummary AnalyticsSummary) PeriodComparison {
+// calculatePeriodComparison computes the legacy comparison to one and two years back with
+// percentage changes for volume, events, and efficiency; years without data are omitted
+func (s *analyticsService) calculatePeriodComparison(startDate, endDate time.Time, comparisonData map[int][]repository.AggregatedDataWithCount, currentSummary AnalyticsSummary, weighting string, queryOpts repository.QueryOptions) PeriodComparison {
 	comparison := PeriodComparison{}
 
-	// Fetch data for -1 year
-	oneYearData, err := s.repo.GetYearOverYearData(farmID, sectorID, startDate, endDate, aggregation, 1)
-	if err == nil && len(oneYearData) > 0 {
-		oneYearSummary := s.calculateSummary(oneYearData)
-
-		comparison.OneYearAgo = &PeriodMetrics{
-			Period: PeriodInfo{
-				StartDate: startDate.AddDate(-1, 0, 0),
-				EndDate:   endDate.AddDate(-1, 0, 0),
-			},
-			TotalWaterVolume:        oneYearSummary.TotalWaterVolume,
-			TotalEvents:             oneYearSummary.TotalEvents,
-			AverageEfficiency:       oneYearSummary.AverageEfficiency,
-			VolumeChangePercent:     s.calculateChangePercent(currentSummary.TotalWaterVolume, oneYearSummary.TotalWaterVolume),
-			EventsChangePercent:     s.calculateChangePercent(float64(currentSummary.TotalEvents), float64(oneYearSummary.TotalEvents)),
-			EfficiencyChangePercent: s.calculateChangePercent(currentSummary.AverageEfficiency, oneYearSummary.AverageEfficiency),
-		}
+	// Data for -1 year
+	if oneYearData := comparisonData[1]; len(oneYearData) > 0 {
+		yearStart, yearEnd := queryOpts.YearsBack(startDate, endDate, 1)
+		metrics := s.periodMetrics(yearStart, yearEnd, oneYearData, currentSummary, weighting)
+		comparison.OneYearAgo = &metrics
 	}
 
-	// Fetch data for -2 years
-	twoYearsData, err := s.repo.GetYearOverYearData(farmID, sectorID, startDate, endDate, aggregation, 2)
-	if err == nil && len(twoYearsData) > 0 {
-		twoYearsSummary := s.calculateSummary(twoYearsData)
-
-		comparison.TwoYearsAgo = &PeriodMetrics{
-			Period: PeriodInfo{
-				StartDate: startDate.AddDate(-2, 0, 0),
-				EndDate:   endDate.AddDate(-2, 0, 0),
-			},
-			TotalWaterVolume:        twoYearsSummary.TotalWaterVolume,
-			TotalEvents:             twoYearsSummary.TotalEvents,
-			AverageEfficiency:       twoYearsSummary.AverageEfficiency,
-			VolumeChangePercent:     s.calculateChangePercent(currentSummary.TotalWaterVolume, twoYearsSummary.TotalWaterVolume),
-			EventsChangePercent:     s.calculateChangePercent(float64(currentSummary.TotalEvents), float64(twoYearsSummary.TotalEvents)),
-			EfficiencyChangePercent: s.calculateChangePercent(currentSummary.AverageEfficiency, twoYearsSummary.AverageEfficiency),
-		}
+	// Data for -2 years
+	if twoYearsData := comparisonData[2]; len(twoYearsData) > 0 {
+		yearStart, yearEnd := queryOpts.YearsBack(startDate, endDate, 2)
+		metrics := s.periodMetrics(yearStart, yearEnd, twoYearsData, currentSummary, weighting)
+		comparison.TwoYearsAgo = &metrics
 	}
 
 	return comparison
 }
 
-// calculateSectorBreakdown computes analytics broken down by sector
-func (s *analyticsService) calculateSectorBreakdown(farmID uint, startDate, endDate time.Time, aggregation string) []SectorBreakdown {
-	// Fetch data for all sectors (no sector filter)
-	data, err := s.repo.GetAggregatedData(farmID, nil, startDate, endDate, aggregation)
+// calculateSectorBreakdown computes analytics broken down by sector, each with its own
+// one-year-ago metrics so sector-level drivers of the farm-level change are visible.
+// Sectors that irrigated a year ago but not in the current period are included with zero totals.
+// rollUp, when set, merges each sector's totals into its roll-up target.
+func (s *analyticsService) calculateSectorBreakdown(farmID uint, startDate, endDate time.Time, queryOpts repository.QueryOptions, rollUp func(uint) uint) ([]SectorBreakdown, error) {
+	// Fetch per-sector totals for the current period and -1 year in one grouped query
+	data, err := s.repo.GetSectorComparisonData(farmID, startDate, endDate, []int{1}, queryOpts)
 	if err != nil {
-		return []SectorBreakdown{}
+		return nil, err
+	}
+	if rollUp != nil {
+		rollUpComparison(data, rollUp)
 	}
 
-	// Group data by sector
-	sectorMap := make(map[uint]*SectorBreakdown)
+	current := s.sectorTotals(data[0])
+	previous := s.sectorTotals(data[1])
 
-	for _, item := range data {
-		d := item.Data
-		sectorID := d.IrrigationSectorID
-		if breakdown, exists := sectorMap[sectorID]; exists {
-			// Update existing sector breakdown
-			breakdown.TotalWaterVolume += d.WaterVolume
-			breakdown.TotalEvents += item.EventCount // Sum event counts
-			breakdown.TotalRealAmount += d.RealAmount
-			breakdown.TotalNominalAmount += d.NominalAmount
-		} else {
-			// Create new sector breakdown
-			efficiency := s.calculateEfficiency(d.RealAmount, d.NominalAmount)
-			if efficiency == 0 && d.WaterVolume > 0 && d.Duration > 0 {
-				nominalVolume := float64(d.Duration) * 1.0
-				efficiency = s.calculateEfficiency(d.WaterVolume, nominalVolume)
-			}
+	// Sectors only present a year ago still get an entry
+	for sectorID := range previous {
+		if _, exists := current[sectorID]; !exists {
+			current[sectorID] = &SectorBreakdown{SectorID: sectorID}
+		}
+	}
 
-			sectorMap[sectorID] = &SectorBreakdown{
-				SectorID:           sectorID,
-				TotalWaterVolume:   d.WaterVolume,
-				TotalEvents:        item.EventCount, // Use event count from aggregation
-				AverageEfficiency:  efficiency,
-				TotalRealAmount:    d.RealAmount,
-				TotalNominalAmount: d.NominalAmount,
+	yearStart, yearEnd := queryOpts.YearsBack(startDate, endDate, 1)
+	breakdowns := make([]SectorBreakdown, 0, len(current))
+	for sectorID, breakdown := range current {
+		if prev, exists := previous[sectorID]; exists {
+			breakdown.OneYearAgo = &PeriodMetrics{
+				Period: PeriodInfo{
+					StartDate: yearStart,
+					EndDate:   yearEnd,
+				},
+				TotalWaterVolume:        prev.TotalWaterVolume,
+				TotalEvents:             prev.TotalEvents,
+				AverageEfficiency:       prev.AverageEfficiency,
+				VolumeChangePercent:     s.calculateChangePercent(breakdown.TotalWaterVolume, prev.TotalWaterVolume),
+				EventsChangePercent:     s.calculateChangePercent(float64(breakdown.TotalEvents), float64(prev.TotalEvents)),
+				EfficiencyChangePercent: s.calculateChangePercent(breakdown.AverageEfficiency, prev.AverageEfficiency),
 			}
 		}
+		breakdowns = append(breakdowns, *breakdown)
 	}
 
-	// Calculate average efficiency for each sector
-	breakdowns := make([]SectorBreakdown, 0, len(sectorMap))
-	for _, breakdown := range sectorMap {
-		// Recalculate average efficiency based on total real/nominal amounts
-		if breakdown.TotalNominalAmount > 0 {
-			breakdown.AverageEfficiency = s.calculateEfficiency(breakdown.TotalRealAmount, breakdown.TotalNominalAmount)
+	sort.Slice(breakdowns, func(i, j int) bool {
+		return breakdowns[i].SectorID < breakdowns[j].SectorID
+	})
+
+	return breakdowns, nil
+}
+
+// calculatePurposeBreakdown computes totals per event purpose with each purpose's share of the
+// period's volume. Efficiency is the ratio of the purpose's totals.
+func (s *analyticsService) calculatePurposeBreakdown(farmID uint, sectorID *uint, startDate, endDate time.Time, queryOpts repository.QueryOptions) ([]PurposeBreakdown, error) {
+	totals, err := s.repo.GetPurposeTotals(farmID, sectorID, startDate, endDate, queryOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	var totalVolume float64
+	for _, total := range totals {
+		totalVolume += total.WaterVolume
+	}
+
+	breakdowns := make([]PurposeBreakdown, 0, len(totals))
+	for _, total := range totals {
+		efficiency, _, _, _ := s.bucketEfficiency(summaryBucket(&total.SummaryResult))
+		share := 0.0
+		if totalVolume > 0 {
+			share = math.Round(total.WaterVolume/totalVolume*10000) / 100
 		}
+		breakdowns = append(breakdowns, PurposeBreakdown{
+			Purpose:              total.Purpose,
+			TotalWaterVolume:     math.Round(total.WaterVolume*100) / 100,
+			TotalEvents:          total.EventCount,
+			AverageEfficiency:    efficiency,
+			ShareOfVolumePercent: share,
+		})
+	}
+	return breakdowns, nil
+}
 
-		// Round values
-		breakdown.TotalWaterVolume = math.Round(breakdown.TotalWaterVolume*100) / 100
-		breakdown.TotalRealAmount = math.Round(breakdown.TotalRealAmount*100) / 100
-		breakdown.TotalNominalAmount = math.Round(breakdown.TotalNominalAmount*100) / 100
-		breakdown.AverageEfficiency = math.Round(breakdown.AverageEfficiency*10000) / 10000
+// sectorTotals converts per-sector total rows into rounded sector breakdowns keyed by sector ID
+func (s *analyticsService) sectorTotals(data []repository.AggregatedDataWithCount) map[uint]*SectorBreakdown {
+	sectorMap := make(map[uint]*SectorBreakdown, len(data))
 
-		breakdowns = append(breakdowns, *breakdown)
+	for _, item := range data {
+		d := item.Data
+		efficiency, _, _, _ := s.bucketEfficiency(item)
+
+		sectorMap[d.IrrigationSectorID] = &SectorBreakdown{
+			SectorID:           d.IrrigationSectorID,
+			TotalWaterVolume:   math.Round(d.WaterVolume*100) / 100,
+			TotalEvents:        item.EventCount,
+			AverageEfficiency:  efficiency,
+			TotalRealAmount:    math.Round(d.RealAmount*100) / 100,
+			TotalNominalAmount: math.Round(d.NominalAmountOrZero()*100) / 100,
+		}
 	}
 
-	return breakdowns
+	return sectorMap
 }
 
 // calculateYearOverYear computes YoY comparisons (legacy format)
-func (s *analyticsService) calculateYearOverYear(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string, currentSummary AnalyticsSummary) YearOverYearComparison {
+func (s *analyticsService) calculateYearOverYear(startDate, endDate time.Time, comparisonData map[int][]repository.AggregatedDataWithCount, currentSummary AnalyticsSummary, weighting string, queryOpts repository.QueryOptions) YearOverYearComparison {
 	yoy := YearOverYearComparison{}
 
-	// Fetch data for -1 year
-	oneYearData, err := s.repo.GetYearOverYearData(farmID, sectorID, startDate, endDate, aggregation, 1)
-	if err == nil && len(oneYearData) > 0 {
-		oneYearSummary := s.calculateSummary(oneYearData)
+	// Data for -1 year
+	if oneYearData := comparisonData[1]; len(oneYearData) > 0 {
+		oneYearSummary := s.calculateSummary(oneYearData, weighting)
 		changePercent := s.calculateChangePercent(currentSummary.TotalWaterVolume, oneYearSummary.TotalWaterVolume)
+		yearStart, yearEnd := queryOpts.YearsBack(startDate, endDate, 1)
 
 		yoy.OneYearAgo = &YearComparison{
 			Period: PeriodInfo{
-				StartDate: startDate.AddDate(-1, 0, 0),
-				EndDate:   endDate.AddDate(-1, 0, 0),
+				StartDate: yearStart,
+				EndDate:   yearEnd,
 			},
 			TotalWaterVolume:  oneYearSummary.TotalWaterVolume,
 			TotalDuration:     oneYearSummary.TotalDuration,
@@ -378,16 +949,16 @@ func (s *analyticsService) calculateYearOverYear(farmID uint, sectorID *uint, st
 		}
 	}
 
-	// Fetch data for -2 years
-	twoYearsData, err := s.repo.GetYearOverYearData(farmID, sectorID, startDate, endDate, aggregation, 2)
-	if err == nil && len(twoYearsData) > 0 {
-		twoYearsSummary := s.calculateSummary(twoYearsData)
+	// Data for -2 years
+	if twoYearsData := comparisonData[2]; len(twoYearsData) > 0 {
+		twoYearsSummary := s.calculateSummary(twoYearsData, weighting)
 		changePercent := s.calculateChangePercent(currentSummary.TotalWaterVolume, twoYearsSummary.TotalWaterVolume)
+		yearStart, yearEnd := queryOpts.YearsBack(startDate, endDate, 2)
 
 		yoy.TwoYearsAgo = &YearComparison{
 			Period: PeriodInfo{
-				StartDate: startDate.AddDate(-2, 0, 0),
-				EndDate:   endDate.AddDate(-2, 0, 0),
+				StartDate: yearStart,
+				EndDate:   yearEnd,
 			},
 			TotalWaterVolume:  twoYearsSummary.TotalWaterVolume,
 			TotalDuration:     twoYearsSummary.TotalDuration,
@@ -1,7 +1,12 @@
 package service
 
 import (
+	"context"
 	"testing"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
 )
 
 // TestCalculateEfficiency tests the calculateEfficiency function
@@ -308,3 +313,320 @@ func TestCalculateChangePercent_DivisionByZero_YoY(t *testing.T) {
 		}
 	})
 }
+
+// stubRepository is an in-memory IrrigationRepository for service tests
+type stubRepository struct {
+	repository.IrrigationRepository
+	comparison map[int][]repository.AggregatedDataWithCount
+	sectors    map[int][]repository.AggregatedDataWithCount
+	// ranges serves GetRangesData in order; yearsBack and dateRanges record the comparison periods queried
+	ranges     [][]repository.AggregatedDataWithCount
+	yearsBack  []int
+	dateRanges []repository.DateRange
+	purposes   []repository.PurposeTotal
+	calls      int
+	// attribution is the farm's stored policy; opts records the last comparison query's options
+	attribution string
+	opts        repository.QueryOptions
+	// ctx is the context the repository was last bound to
+	ctx context.Context
+	// rolledUpThrough is the farm's rollup watermark, nil when never rolled up
+	rolledUpThrough *time.Time
+	// cropYear is the farm's stored crop year start
+	cropYear model.CropYear
+}
+
+func (r *stubRepository) GetAttributionPolicy(farmID uint) (string, error) {
+	return r.attribution, nil
+}
+
+func (r *stubRepository) GetCropYear(farmID uint) (model.CropYear, error) {
+	return r.cropYear, nil
+}
+
+func (r *stubRepository) GetRolledUpThrough(farmID uint) (*time.Time, error) {
+	return r.rolledUpThrough, nil
+}
+
+func (r *stubRepository) GetComparisonData(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string, yearsBack []int, opts repository.QueryOptions) (map[int][]repository.AggregatedDataWithCount, error) {
+	r.calls++
+	r.opts = opts
+	r.yearsBack = yearsBack
+	return r.comparison, nil
+}
+
+func (r *stubRepository) GetRangesData(farmID uint, sectorID *uint, ranges []repository.DateRange, aggregation string, opts repository.QueryOptions) ([][]repository.AggregatedDataWithCount, error) {
+	r.calls++
+	r.dateRanges = ranges
+	return r.ranges, nil
+}
+
+func (r *stubRepository) WithContext(ctx context.Context) repository.IrrigationRepository {
+	r.ctx = ctx
+	return r
+}
+
+func (r *stubRepository) WithCapture(capture *repository.QueryCapture) repository.IrrigationRepository {
+	return r
+}
+
+func (r *stubRepository) GetAggregatedData(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string, opts repository.QueryOptions) ([]repository.AggregatedDataWithCount, error) {
+	r.calls++
+	return r.comparison[0], nil
+}
+
+func (r *stubRepository) GetSectorComparisonData(farmID uint, startDate, endDate time.Time, yearsBack []int, opts repository.QueryOptions) (map[int][]repository.AggregatedDataWithCount, error) {
+	r.calls++
+	return r.sectors, nil
+}
+
+func (r *stubRepository) GetPurposeTotals(farmID uint, sectorID *uint, startDate, endDate time.Time, opts repository.QueryOptions) ([]repository.PurposeTotal, error) {
+	r.calls++
+	return r.purposes, nil
+}
+
+// aggregatedPoint builds a single aggregated bucket for stub repositories
+func aggregatedPoint(day time.Time, sectorID uint, volume, nominal float64, events int) repository.AggregatedDataWithCount {
+	return repository.AggregatedDataWithCount{
+		Data: model.IrrigationData{
+			StartTime:          day,
+			IrrigationSectorID: sectorID,
+			WaterVolume:        volume,
+			Duration:           int(nominal),
+			RealAmount:         volume,
+			NominalAmount:      &nominal,
+		},
+		EventCount: events,
+	}
+}
+
+// TestGetIrrigationAnalytics_SingleComparisonQuery verifies that current and historical periods
+// come from one comparison query and the sector breakdown from one more
+func TestGetIrrigationAnalytics_SingleComparisonQuery(t *testing.T) {
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := &stubRepository{
+		comparison: map[int][]repository.AggregatedDataWithCount{
+			0: {aggregatedPoint(day, 1, 110, 100, 2)},
+			1: {aggregatedPoint(day.AddDate(-1, 0, 0), 1, 100, 100, 1)},
+			2: {},
+		},
+		sectors: map[int][]repository.AggregatedDataWithCount{
+			0: {aggregatedPoint(time.Time{}, 1, 110, 100, 2)},
+			1: {aggregatedPoint(time.Time{}, 1, 100, 100, 1)},
+		},
+	}
+	svc := NewAnalyticsService(repo, nil, nil, nil)
+
+	response, err := svc.GetIrrigationAnalytics(1, nil, day, day.AddDate(0, 1, 0), "daily", AnalyticsOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if repo.calls != 2 {
+		t.Errorf("expected 2 repository queries, got %d", repo.calls)
+	}
+	if response.Summary.TotalWaterVolume != 110 {
+		t.Errorf("expected current volume 110, got %f", response.Summary.TotalWaterVolume)
+	}
+	if response.PeriodComparison.OneYearAgo == nil || response.PeriodComparison.OneYearAgo.VolumeChangePercent != 10 {
+		t.Errorf("expected 10%% volume change vs one year ago, got %+v", response.PeriodComparison.OneYearAgo)
+	}
+	if response.YearOverYear.OneYearAgo == nil || response.YearOverYear.OneYearAgo.ChangePercent != 10 {
+		t.Errorf("expected legacy YoY change of 10%%, got %+v", response.YearOverYear.OneYearAgo)
+	}
+	if response.PeriodComparison.TwoYearsAgo != nil {
+		t.Errorf("expected no two-years-ago comparison without data, got %+v", response.PeriodComparison.TwoYearsAgo)
+	}
+}
+
+// TestGetIrrigationAnalytics_Omit verifies that omitted sections are left empty and their queries skipped
+func TestGetIrrigationAnalytics_Omit(t *testing.T) {
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	newRepo := func() *stubRepository {
+		return &stubRepository{
+			comparison: map[int][]repository.AggregatedDataWithCount{
+				0: {aggregatedPoint(day, 1, 110, 100, 2)},
+				1: {aggregatedPoint(day.AddDate(-1, 0, 0), 1, 100, 100, 1)},
+			},
+			sectors: map[int][]repository.AggregatedDataWithCount{
+				0: {aggregatedPoint(time.Time{}, 1, 110, 100, 2)},
+			},
+		}
+	}
+
+	repo := newRepo()
+	response, err := NewAnalyticsService(repo, nil, nil, nil).GetIrrigationAnalytics(1, nil, day, day.AddDate(0, 1, 0), "daily",
+		AnalyticsOptions{Omit: []string{SectionComparisons, SectionSectorBreakdown}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.calls != 1 || len(repo.yearsBack) != 0 {
+		t.Errorf("expected one query of the current period only, got %d queries for years back %v", repo.calls, repo.yearsBack)
+	}
+	if response.Comparisons != nil || response.PeriodComparison.OneYearAgo != nil || response.YearOverYear.OneYearAgo != nil {
+		t.Errorf("expected no comparisons, got %+v, %+v and %+v", response.Comparisons, response.PeriodComparison, response.YearOverYear)
+	}
+	if response.SectorBreakdown != nil {
+		t.Errorf("expected no sector breakdown, got %+v", response.SectorBreakdown)
+	}
+	if len(response.Data) != 1 || response.Summary.TotalWaterVolume != 110 {
+		t.Errorf("expected the data and summary to be unchanged, got %d points and volume %f", len(response.Data), response.Summary.TotalWaterVolume)
+	}
+
+	repo = newRepo()
+	response, err = NewAnalyticsService(repo, nil, nil, nil).GetIrrigationAnalytics(1, nil, day, day.AddDate(0, 1, 0), "daily",
+		AnalyticsOptions{Omit: []string{SectionData}, FillGaps: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.Data == nil || len(response.Data) != 0 {
+		t.Errorf("expected an empty data list, got %+v", response.Data)
+	}
+	if response.Summary.TotalWaterVolume != 110 || response.PeriodComparison.OneYearAgo == nil || len(response.SectorBreakdown) != 1 {
+		t.Errorf("expected the summary, comparison and sector breakdown to be kept, got %+v", response)
+	}
+}
+
+// TestCalculateSummary_EfficiencyWeighting verifies that volume weighting stops tiny buckets
+// from dominating the average efficiency
+func TestCalculateSummary_EfficiencyWeighting(t *testing.T) {
+	service := &analyticsService{}
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	data := []repository.AggregatedDataWithCount{
+		aggregatedPoint(day, 1, 900, 1000, 10),            // large bucket, efficiency 0.9
+		aggregatedPoint(day.AddDate(0, 0, 1), 1, 2, 1, 1), // tiny bucket, efficiency 2.0
+	}
+
+	mean := service.calculateSummary(data, EfficiencyWeightingMean)
+	if mean.AverageEfficiency != 1.45 {
+		t.Errorf("expected mean efficiency 1.45, got %f", mean.AverageEfficiency)
+	}
+
+	weighted := service.calculateSummary(data, EfficiencyWeightingVolume)
+	if weighted.AverageEfficiency != 0.9011 {
+		t.Errorf("expected volume-weighted efficiency 0.9011 (902/1001), got %f", weighted.AverageEfficiency)
+	}
+}
+
+// TestBucketEfficiency_MissingVersusZeroNominal verifies that only unrecorded nominal amounts
+// trigger the duration fallback and that the fallback is reported in data quality
+func TestBucketEfficiency_MissingVersusZeroNominal(t *testing.T) {
+	service := &analyticsService{}
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Nominal recorded as 0: no fallback, efficiency is 0
+	recordedZero := aggregatedPoint(day, 1, 50, 0, 2)
+	recordedZero.Data.Duration = 100
+	if eff, _, _, usedFallback := service.bucketEfficiency(recordedZero); usedFallback || eff != 0 {
+		t.Errorf("recorded zero nominal: expected no fallback and 0 efficiency, got %f (fallback=%v)", eff, usedFallback)
+	}
+
+	// Nominal not recorded for any event: duration fallback at 1 liter per minute
+	missing := aggregatedPoint(day, 1, 50, 0, 2)
+	missing.Data.Duration = 100
+	missing.MissingNominalCount = 2
+	if eff, _, _, usedFallback := service.bucketEfficiency(missing); !usedFallback || eff != 0.5 {
+		t.Errorf("missing nominal: expected fallback efficiency 0.5, got %f (fallback=%v)", eff, usedFallback)
+	}
+
+	quality := service.calculateDataQuality([]repository.AggregatedDataWithCount{recordedZero, missing})
+	expected := DataQuality{EventsMissingNominal: 2, FallbackEvents: 2, FallbackBuckets: 1}
+	if quality != expected {
+		t.Errorf("expected data quality %+v, got %+v", expected, quality)
+	}
+}
+
+// TestCalculateSectorBreakdown_OneYearAgo verifies per-sector YoY metrics, including sectors
+// that only irrigated in the previous year
+func TestCalculateSectorBreakdown_OneYearAgo(t *testing.T) {
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := &stubRepository{
+		sectors: map[int][]repository.AggregatedDataWithCount{
+			0: {
+				aggregatedPoint(time.Time{}, 1, 150, 150, 3),
+				aggregatedPoint(time.Time{}, 2, 80, 100, 2),
+			},
+			1: {
+				aggregatedPoint(time.Time{}, 1, 100, 100, 2),
+				aggregatedPoint(time.Time{}, 3, 40, 40, 1),
+			},
+		},
+	}
+	svc := &analyticsService{repo: repo}
+
+	breakdowns, err := svc.calculateSectorBreakdown(1, day, day.AddDate(0, 1, 0), repository.QueryOptions{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(breakdowns) != 3 {
+		t.Fatalf("expected 3 sectors, got %d", len(breakdowns))
+	}
+
+	sector1 := breakdowns[0]
+	if sector1.SectorID != 1 || sector1.OneYearAgo == nil || sector1.OneYearAgo.VolumeChangePercent != 50 {
+		t.Errorf("expected sector 1 volume change of 50%%, got %+v", sector1.OneYearAgo)
+	}
+
+	sector2 := breakdowns[1]
+	if sector2.SectorID != 2 || sector2.OneYearAgo != nil {
+		t.Errorf("expected sector 2 without one-year-ago metrics, got %+v", sector2.OneYearAgo)
+	}
+
+	sector3 := breakdowns[2]
+	if sector3.SectorID != 3 || sector3.TotalWaterVolume != 0 || sector3.OneYearAgo == nil || sector3.OneYearAgo.VolumeChangePercent != -100 {
+		t.Errorf("expected sector 3 to show a 100%% drop, got %+v", sector3)
+	}
+}
+
+// TestGetIrrigationAnalytics_PurposeBreakdown verifies per-purpose totals and volume shares
+func TestGetIrrigationAnalytics_PurposeBreakdown(t *testing.T) {
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := &stubRepository{
+		comparison: map[int][]repository.AggregatedDataWithCount{0: {aggregatedPoint(day, 1, 1000, 1000, 4)}},
+		purposes: []repository.PurposeTotal{
+			{Purpose: "frost_protection", SummaryResult: repository.SummaryResult{WaterVolume: 750, EventCount: 1, NominalAmount: 100, RealAmount: 750}},
+			{Purpose: "irrigation", SummaryResult: repository.SummaryResult{WaterVolume: 250, EventCount: 3, NominalAmount: 250, RealAmount: 225}},
+		},
+	}
+	svc := NewAnalyticsService(repo, nil, nil, nil)
+
+	sectorID := uint(1)
+	response, err := svc.GetIrrigationAnalytics(1, &sectorID, day, day.AddDate(0, 1, 0), "daily", AnalyticsOptions{BreakdownByPurpose: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(response.PurposeBreakdown) != 2 {
+		t.Fatalf("expected 2 purposes, got %d", len(response.PurposeBreakdown))
+	}
+	frost, irrigation := response.PurposeBreakdown[0], response.PurposeBreakdown[1]
+	if frost.ShareOfVolumePercent != 75 || irrigation.ShareOfVolumePercent != 25 {
+		t.Errorf("expected 75/25 volume shares, got %f/%f", frost.ShareOfVolumePercent, irrigation.ShareOfVolumePercent)
+	}
+	if irrigation.AverageEfficiency != 0.9 {
+		t.Errorf("expected irrigation efficiency 0.9, got %f", irrigation.AverageEfficiency)
+	}
+
+	// Without the option the breakdown isn't queried
+	repo.calls = 0
+	response, _ = svc.GetIrrigationAnalytics(1, &sectorID, day, day.AddDate(0, 1, 0), "daily", AnalyticsOptions{})
+	if response.PurposeBreakdown != nil || repo.calls != 1 {
+		t.Errorf("expected no purpose breakdown query, got %d queries", repo.calls)
+	}
+}
+
+// TestWithContext verifies a context-bound service runs its queries under that context
+func TestWithContext(t *testing.T) {
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	repo := &stubRepository{comparison: map[int][]repository.AggregatedDataWithCount{0: {aggregatedPoint(day, 1, 100, 100, 1)}}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if _, err := NewAnalyticsService(repo, nil, nil, nil).WithContext(ctx).GetIrrigationAnalytics(1, nil, day, day.AddDate(0, 0, 1), "daily", AnalyticsOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.ctx != ctx {
+		t.Error("expected the repository bound to the request context")
+	}
+}
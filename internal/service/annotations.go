@@ -0,0 +1,154 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// ErrEventNotFound is returned when an annotation targets an event the farm doesn't have
+var ErrEventNotFound = errors.New("irrigation event not found")
+
+// AnnotationService defines the interface for event and day annotation operations
+type AnnotationService interface {
+	FarmExists(farmID uint) (bool, error)
+	CreateAnnotation(farmID uint, input AnnotationInput) (*model.Annotation, error)
+	ListAnnotations(farmID uint, startDate, endDate time.Time) ([]model.Annotation, error)
+	DeleteAnnotation(farmID, annotationID uint) (bool, error)
+}
+
+// AnnotationInput describes a new annotation. Exactly one of EventID or Date is set;
+// SectorID optionally limits a day annotation to one sector.
+type AnnotationInput struct {
+	EventID               *uint
+	Date                  *time.Time
+	SectorID              *uint
+	Label                 string
+	Note                  string
+	ExcludeFromEfficiency bool
+}
+
+// annotationService implements AnnotationService
+type annotationService struct {
+	repo        repository.IrrigationRepository
+	annotations repository.AnnotationRepository
+}
+
+// NewAnnotationService creates a new annotation service
+func NewAnnotationService(repo repository.IrrigationRepository, annotations repository.AnnotationRepository) AnnotationService {
+	return &annotationService{repo: repo, annotations: annotations}
+}
+
+// FarmExists checks if a farm exists
+func (s *annotationService) FarmExists(farmID uint) (bool, error) {
+	return s.repo.FarmExists(farmID)
+}
+
+// CreateAnnotation stores an annotation. Event annotations take their date and sector from
+// the event so they can be matched to buckets like day annotations.
+func (s *annotationService) CreateAnnotation(farmID uint, input AnnotationInput) (*model.Annotation, error) {
+	annotation := &model.Annotation{
+		FarmID:                farmID,
+		IrrigationSectorID:    input.SectorID,
+		Label:                 input.Label,
+		Note:                  input.Note,
+		ExcludeFromEfficiency: input.ExcludeFromEfficiency,
+	}
+
+	if input.EventID != nil {
+		event, err := s.repo.GetEvent(farmID, *input.EventID)
+		if err != nil {
+			return nil, err
+		}
+		if event == nil {
+			return nil, ErrEventNotFound
+		}
+		sectorID := event.IrrigationSectorID
+		annotation.IrrigationDataID = &event.ID
+		annotation.IrrigationSectorID = &sectorID
+		annotation.Date = truncateToDay(event.StartTime)
+	} else if input.Date != nil {
+		annotation.Date = truncateToDay(*input.Date)
+	}
+
+	if err := s.annotations.Create(annotation); err != nil {
+		return nil, err
+	}
+	return annotation, nil
+}
+
+// ListAnnotations returns the farm's annotations dated within the range
+func (s *annotationService) ListAnnotations(farmID uint, startDate, endDate time.Time) ([]model.Annotation, error) {
+	return s.annotations.List(farmID, startDate, endDate)
+}
+
+// DeleteAnnotation removes an annotation, reporting whether it existed
+func (s *annotationService) DeleteAnnotation(farmID, annotationID uint) (bool, error) {
+	return s.annotations.Delete(farmID, annotationID)
+}
+
+// attachAnnotations adds each annotation in the range to the data points whose bucket contains
+// its date and whose sector it applies to. points[i] must correspond to data[i].
+// On a lookup failure the points are left unannotated and the error is returned.
+func (s *analyticsService) attachAnnotations(points []AggregatedDataPoint, data []repository.AggregatedDataWithCount, farmID uint, startDate, endDate time.Time, aggregation string) error {
+	if s.annotations == nil || len(points) == 0 {
+		return nil
+	}
+
+	annotations, err := s.annotations.List(farmID, startDate, endDate)
+	if err != nil {
+		return err
+	}
+
+	for _, annotation := range annotations {
+		for i, item := range data {
+			if !annotationCovers(annotation, item.Data.StartTime, aggregation) {
+				continue
+			}
+			if annotation.IrrigationSectorID != nil && *annotation.IrrigationSectorID != item.Data.IrrigationSectorID {
+				continue
+			}
+			points[i].Annotations = append(points[i].Annotations, annotation)
+		}
+	}
+	return nil
+}
+
+// annotationCovers reports whether an annotation's day falls in the bucket starting at bucket.
+// Buckets shorter than a day are covered when they fall on the annotated day.
+func annotationCovers(annotation model.Annotation, bucket time.Time, aggregation string) bool {
+	if aggregation == "hourly" {
+		return truncateToDay(bucket).Equal(annotation.Date)
+	}
+	return bucketStart(annotation.Date, aggregation).Equal(bucket)
+}
+
+// bucketStart returns the start of the aggregation bucket containing t, matching the
+// repository's bucket expressions (weeks start on Monday, custom buckets on 1 January)
+func bucketStart(t time.Time, aggregation string) time.Time {
+	day := truncateToDay(t)
+	if days, ok := repository.ParseCustomAggregation(aggregation); ok {
+		return day.AddDate(0, 0, -((day.YearDay() - 1) % days))
+	}
+	switch aggregation {
+	case "hourly":
+		return t.UTC().Truncate(time.Hour)
+	case "weekly":
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case "monthly":
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case "quarterly":
+		return time.Date(day.Year(), day.Month()-(day.Month()-1)%3, 1, 0, 0, 0, 0, time.UTC)
+	case "yearly":
+		return time.Date(day.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+// truncateToDay returns midnight UTC of t's calendar date
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
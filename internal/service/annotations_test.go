@@ -0,0 +1,105 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// stubAnnotationRepository returns a fixed set of annotations
+type stubAnnotationRepository struct {
+	repository.AnnotationRepository
+	annotations []model.Annotation
+}
+
+func (r *stubAnnotationRepository) List(farmID uint, startDate, endDate time.Time) ([]model.Annotation, error) {
+	return r.annotations, nil
+}
+
+func TestBucketStart(t *testing.T) {
+	// Wednesday
+	day := time.Date(2025, 3, 12, 15, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		aggregation string
+		expected    time.Time
+	}{
+		{"hourly", time.Date(2025, 3, 12, 15, 0, 0, 0, time.UTC)},
+		{"daily", time.Date(2025, 3, 12, 0, 0, 0, 0, time.UTC)},
+		{"weekly", time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)},
+		{"monthly", time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"quarterly", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"yearly", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"10d", time.Date(2025, 3, 12, 0, 0, 0, 0, time.UTC)}, // day 71 opens the 8th decad
+		{"7d", time.Date(2025, 3, 12, 0, 0, 0, 0, time.UTC)},
+		{"30d", time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.aggregation, func(t *testing.T) {
+			if got := bucketStart(day, tt.aggregation); !got.Equal(tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestGetIrrigationAnalytics_Annotations verifies annotations land on the bucket and sector they cover
+func TestGetIrrigationAnalytics_Annotations(t *testing.T) {
+	week := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	sectorTwo := uint(2)
+	repo := &stubRepository{
+		comparison: map[int][]repository.AggregatedDataWithCount{
+			0: {
+				aggregatedPoint(week, 1, 100, 100, 1),
+				aggregatedPoint(week, 2, 100, 100, 1),
+				aggregatedPoint(week.AddDate(0, 0, 7), 1, 100, 100, 1),
+			},
+		},
+	}
+	annotations := &stubAnnotationRepository{annotations: []model.Annotation{
+		{ID: 1, Label: "pipe burst", Date: week.AddDate(0, 0, 2), IrrigationSectorID: &sectorTwo},
+		{ID: 2, Label: "flush cycle", Date: week.AddDate(0, 0, 8)},
+	}}
+	svc := NewAnalyticsService(repo, annotations, nil, nil)
+
+	sectorID := uint(1)
+	response, err := svc.GetIrrigationAnalytics(1, &sectorID, week, week.AddDate(0, 0, 14), "weekly", AnalyticsOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := make([][]uint, len(response.Data))
+	for i, point := range response.Data {
+		for _, annotation := range point.Annotations {
+			got[i] = append(got[i], annotation.ID)
+		}
+	}
+	if len(got[0]) != 0 || len(got[1]) != 1 || got[1][0] != 1 || len(got[2]) != 1 || got[2][0] != 2 {
+		t.Errorf("unexpected annotation placement: %v", got)
+	}
+}
+
+// TestBucketEfficiency_ExcludedEvents verifies excluded events don't trigger the duration fallback
+func TestBucketEfficiency_ExcludedEvents(t *testing.T) {
+	svc := &analyticsService{}
+
+	item := aggregatedPoint(time.Time{}, 1, 300, 0, 3)
+	item.Data.RealAmount = 0
+	item.Data.Duration = 60
+	item.MissingNominalCount = 2
+	item.ExcludedEventCount = 1
+
+	efficiency, _, _, usedFallback := svc.bucketEfficiency(item)
+	if usedFallback || efficiency != 0 {
+		t.Errorf("expected no fallback for bucket with excluded events, got efficiency=%f fallback=%v", efficiency, usedFallback)
+	}
+
+	item.ExcludedEventCount = 0
+	item.MissingNominalCount = 3
+	if _, _, _, usedFallback := svc.bucketEfficiency(item); !usedFallback {
+		t.Error("expected fallback when every event is missing nominal and none are excluded")
+	}
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"irrigation-analytics/internal/repository"
+)
+
+// Anomaly detection methods
+const (
+	// AnomalyMethodZScore flags values more than threshold standard deviations from the baseline mean
+	AnomalyMethodZScore = "zscore"
+	// AnomalyMethodIQR flags values more than threshold interquartile ranges outside the baseline quartiles
+	AnomalyMethodIQR = "iqr"
+)
+
+// minBaselinePoints is the fewest baseline buckets a sector needs before its values are scored
+const minBaselinePoints = 5
+
+// AnomalyService defines the interface for anomaly detection
+type AnomalyService interface {
+	FarmExists(farmID uint) (bool, error)
+	DetectAnomalies(farmID uint, sectorID *uint, startDate, endDate time.Time, opts AnomalyOptions) (*AnomalyResponse, error)
+}
+
+// AnomalyOptions configures anomaly detection
+type AnomalyOptions struct {
+	// Aggregation is the bucket size scored (hourly, daily, weekly or monthly)
+	Aggregation string
+	// Method is AnomalyMethodZScore or AnomalyMethodIQR
+	Method string
+	// Threshold is the z-score, or the IQR multiplier, beyond which a value is anomalous
+	Threshold float64
+	// BaselineDays is the length of the baseline window ending at the start of the range
+	BaselineDays int
+}
+
+// AnomalyResponse lists the buckets whose metrics deviate from their sector's baseline
+type AnomalyResponse struct {
+	FarmID      uint       `json:"farm_id"`
+	SectorID    *uint      `json:"sector_id,omitempty"`
+	Period      PeriodInfo `json:"period"`
+	Baseline    PeriodInfo `json:"baseline"`
+	Aggregation string     `json:"aggregation"`
+	Method      string     `json:"method"`
+	Threshold   float64    `json:"threshold"`
+	Anomalies   []Anomaly  `json:"anomalies"`
+	// WeatherExplained lists the anomalies of buckets with a frost or heat day, which are expected
+	// to deviate and so are kept out of Anomalies
+	WeatherExplained []Anomaly `json:"weather_explained"`
+	// SectorsWithoutBaseline lists sectors with data in the period but too few baseline buckets to score
+	SectorsWithoutBaseline []uint `json:"sectors_without_baseline"`
+}
+
+// Anomaly is one metric of one bucket outside its sector's baseline bounds
+type Anomaly struct {
+	Period     time.Time `json:"period"`
+	SectorID   uint      `json:"sector_id"`
+	Metric     string    `json:"metric"`
+	Value      float64   `json:"value"`
+	LowerBound float64   `json:"lower_bound"`
+	UpperBound float64   `json:"upper_bound"`
+	// Score is the z-score, or the distance outside the quartiles in IQRs
+	Score float64 `json:"score"`
+	// Direction is high or low
+	Direction string `json:"direction"`
+	// WeatherEvents are the bucket's frost and heat events, set on weather-explained anomalies
+	WeatherEvents []string `json:"weather_events,omitempty"`
+}
+
+// anomalyMetrics are the bucket metrics scored, in response order
+var anomalyMetrics = []struct {
+	name  string
+	value func(s *analyticsService, item repository.AggregatedDataWithCount) float64
+}{
+	{"efficiency", func(s *analyticsService, item repository.AggregatedDataWithCount) float64 {
+		efficiency, _, _, _ := s.bucketEfficiency(item)
+		return efficiency
+	}},
+	{"water_volume", func(s *analyticsService, item repository.AggregatedDataWithCount) float64 {
+		return item.Data.WaterVolume
+	}},
+	{"duration", func(s *analyticsService, item repository.AggregatedDataWithCount) float64 {
+		return float64(item.Data.Duration)
+	}},
+}
+
+// anomalyService implements AnomalyService
+type anomalyService struct {
+	repo    repository.IrrigationRepository
+	weather repository.WeatherRepository
+	// analytics computes bucket efficiency with the same fallback rules as the analytics endpoint
+	analytics *analyticsService
+}
+
+// NewAnomalyService creates a new anomaly service. weather may be nil, in which case no anomaly
+// is explained by frost or heat.
+func NewAnomalyService(repo repository.IrrigationRepository, weather repository.WeatherRepository) AnomalyService {
+	return &anomalyService{
+		repo:      repo,
+		weather:   weather,
+		analytics: &analyticsService{repo: repo, budget: DefaultQueryBudget},
+	}
+}
+
+// FarmExists checks if a farm exists
+func (s *anomalyService) FarmExists(farmID uint) (bool, error) {
+	return s.repo.FarmExists(farmID)
+}
+
+// DetectAnomalies scores each bucket in the range against the same sector's buckets in the
+// baseline window. Both windows come from a single aggregation query. Anomalies of buckets with
+// a stored frost or heat day are listed as weather-explained instead.
+func (s *anomalyService) DetectAnomalies(farmID uint, sectorID *uint, startDate, endDate time.Time, opts AnomalyOptions) (*AnomalyResponse, error) {
+	baselineStart := startDate.AddDate(0, 0, -opts.BaselineDays)
+	plan := queryPlan{queries: 1, bucketCost: bucketCount(baselineStart, endDate, opts.Aggregation)}
+	if err := s.analytics.budget.check(plan); err != nil {
+		return nil, err
+	}
+
+	queryOpts, err := withAttribution(s.repo, farmID, repository.QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.repo.GetAggregatedData(farmID, sectorID, baselineStart, endDate, opts.Aggregation, queryOpts)
+	if err != nil {
+		return nil, err
+	}
+	analytics, err := s.analytics.withFallbackRates(farmID, data)
+	if err != nil {
+		return nil, err
+	}
+
+	baseline := make(map[uint][]repository.AggregatedDataWithCount)
+	current := make(map[uint][]repository.AggregatedDataWithCount)
+	for _, item := range data {
+		id := item.Data.IrrigationSectorID
+		if item.Data.StartTime.Before(bucketStart(startDate, opts.Aggregation)) {
+			baseline[id] = append(baseline[id], item)
+		} else {
+			current[id] = append(current[id], item)
+		}
+	}
+
+	response := &AnomalyResponse{
+		FarmID:                 farmID,
+		SectorID:               sectorID,
+		Period:                 PeriodInfo{StartDate: startDate, EndDate: endDate},
+		Baseline:               PeriodInfo{StartDate: baselineStart, EndDate: startDate},
+		Aggregation:            opts.Aggregation,
+		Method:                 opts.Method,
+		Threshold:              opts.Threshold,
+		Anomalies:              []Anomaly{},
+		WeatherExplained:       []Anomaly{},
+		SectorsWithoutBaseline: []uint{},
+	}
+
+	sectorIDs := make([]uint, 0, len(current))
+	for id := range current {
+		sectorIDs = append(sectorIDs, id)
+	}
+	sort.Slice(sectorIDs, func(i, j int) bool { return sectorIDs[i] < sectorIDs[j] })
+
+	for _, id := range sectorIDs {
+		if len(baseline[id]) < minBaselinePoints {
+			response.SectorsWithoutBaseline = append(response.SectorsWithoutBaseline, id)
+			continue
+		}
+		for _, metric := range anomalyMetrics {
+			history := make([]float64, len(baseline[id]))
+			for i, item := range baseline[id] {
+				history[i] = metric.value(analytics, item)
+			}
+			lower, upper, score := anomalyBounds(history, opts.Method, opts.Threshold)
+
+			for _, item := range current[id] {
+				value := metric.value(analytics, item)
+				if value >= lower && value <= upper {
+					continue
+				}
+				direction := "high"
+				if value < lower {
+					direction = "low"
+				}
+				response.Anomalies = append(response.Anomalies, Anomaly{
+					Period:     item.Data.StartTime,
+					SectorID:   id,
+					Metric:     metric.name,
+					Value:      math.Round(value*10000) / 10000,
+					LowerBound: math.Round(lower*10000) / 10000,
+					UpperBound: math.Round(upper*10000) / 10000,
+					Score:      math.Round(score(value)*100) / 100,
+					Direction:  direction,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(response.Anomalies, func(i, j int) bool {
+		return response.Anomalies[i].Period.Before(response.Anomalies[j].Period)
+	})
+
+	if s.weather != nil && len(response.Anomalies) > 0 {
+		observations, err := s.weather.GetObservations(farmID, truncateToDay(startDate), endDate)
+		if err != nil {
+			return nil, err
+		}
+		events := bucketWeatherEvents(observations, opts.Aggregation)
+		anomalies := make([]Anomaly, 0, len(response.Anomalies))
+		for _, anomaly := range response.Anomalies {
+			if bucketEvents := events[weatherEventKey(anomaly.Period, opts.Aggregation)]; len(bucketEvents) > 0 {
+				anomaly.WeatherEvents = bucketEvents
+				response.WeatherExplained = append(response.WeatherExplained, anomaly)
+				continue
+			}
+			anomalies = append(anomalies, anomaly)
+		}
+		response.Anomalies = anomalies
+	}
+	return response, nil
+}
+
+// anomalyBounds returns the range of normal values for the baseline and a function scoring how
+// far a value lies from it. A baseline with no spread accepts only its own value.
+func anomalyBounds(history []float64, method string, threshold float64) (lower, upper float64, score func(float64) float64) {
+	if method == AnomalyMethodIQR {
+		sorted := append([]float64(nil), history...)
+		sort.Float64s(sorted)
+		q1, q3 := quantile(sorted, 0.25), quantile(sorted, 0.75)
+		iqr := q3 - q1
+		lower, upper = q1-threshold*iqr, q3+threshold*iqr
+		return lower, upper, func(v float64) float64 {
+			if iqr == 0 {
+				return 0
+			}
+			if v < q1 {
+				return (v - q1) / iqr
+			}
+			return (v - q3) / iqr
+		}
+	}
+
+	mean, stddev := meanStddev(history)
+	lower, upper = mean-threshold*stddev, mean+threshold*stddev
+	return lower, upper, func(v float64) float64 {
+		if stddev == 0 {
+			return 0
+		}
+		return (v - mean) / stddev
+	}
+}
+
+// meanStddev returns the mean and sample standard deviation of values
+func meanStddev(values []float64) (float64, float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+
+	var squares float64
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(squares / float64(len(values)-1))
+}
+
+// quantile returns the q-th quantile of sorted values using linear interpolation
+func quantile(sorted []float64, q float64) float64 {
+	position := q * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	upper := int(math.Ceil(position))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(position-float64(lower))
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+func TestAnomalyBounds(t *testing.T) {
+	history := []float64{10, 12, 11, 13, 9, 10, 12, 11}
+
+	lower, upper, score := anomalyBounds(history, AnomalyMethodZScore, 2)
+	if lower >= 9 || upper <= 13 {
+		t.Errorf("expected z-score bounds to contain the baseline, got [%f, %f]", lower, upper)
+	}
+	if s := score(20); s < 2 {
+		t.Errorf("expected a high z-score for 20, got %f", s)
+	}
+
+	lower, upper, _ = anomalyBounds(history, AnomalyMethodIQR, 1.5)
+	// q1 = 10, q3 = 12, iqr = 2
+	if lower != 7 || upper != 15 {
+		t.Errorf("expected IQR bounds [7, 15], got [%f, %f]", lower, upper)
+	}
+}
+
+// TestDetectAnomalies verifies a stuck-meter day is flagged and sectors without enough
+// baseline are reported instead of scored
+func TestDetectAnomalies(t *testing.T) {
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	var data []repository.AggregatedDataWithCount
+	for i := 10; i > 0; i-- {
+		volume := 100 + float64(i%3)
+		data = append(data, aggregatedPoint(start.AddDate(0, 0, -i), 1, volume, volume, 2))
+	}
+	data = append(data,
+		aggregatedPoint(start.AddDate(0, 0, -1), 2, 100, 100, 1),
+		aggregatedPoint(start, 1, 101, 101, 2),
+		aggregatedPoint(start.AddDate(0, 0, 1), 1, 900, 101, 2),
+		aggregatedPoint(start, 2, 100, 100, 1),
+	)
+	repo := &stubRepository{comparison: map[int][]repository.AggregatedDataWithCount{0: data}}
+	svc := NewAnomalyService(repo, nil)
+
+	response, err := svc.DetectAnomalies(1, nil, start, start.AddDate(0, 0, 2), AnomalyOptions{
+		Aggregation:  "daily",
+		Method:       AnomalyMethodZScore,
+		Threshold:    3,
+		BaselineDays: 30,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(response.SectorsWithoutBaseline) != 1 || response.SectorsWithoutBaseline[0] != 2 {
+		t.Errorf("expected sector 2 to lack a baseline, got %v", response.SectorsWithoutBaseline)
+	}
+
+	flagged := make(map[string]Anomaly)
+	for _, anomaly := range response.Anomalies {
+		if !anomaly.Period.Equal(start.AddDate(0, 0, 1)) || anomaly.SectorID != 1 {
+			t.Errorf("unexpected anomaly: %+v", anomaly)
+		}
+		flagged[anomaly.Metric] = anomaly
+	}
+	if volume, ok := flagged["water_volume"]; !ok || volume.Direction != "high" {
+		t.Errorf("expected a high water_volume anomaly, got %+v", flagged)
+	}
+	if efficiency, ok := flagged["efficiency"]; !ok || efficiency.Direction != "high" {
+		t.Errorf("expected a high efficiency anomaly, got %+v", flagged)
+	}
+}
+
+// TestDetectAnomalies_WeatherExplained verifies anomalies on a frost day are listed apart from
+// the others with the day's weather events
+func TestDetectAnomalies_WeatherExplained(t *testing.T) {
+	start := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	var data []repository.AggregatedDataWithCount
+	for i := 10; i > 0; i-- {
+		volume := 100 + float64(i%3)
+		data = append(data, aggregatedPoint(start.AddDate(0, 0, -i), 1, volume, volume, 2))
+	}
+	data = append(data,
+		aggregatedPoint(start, 1, 900, 900, 2),
+		aggregatedPoint(start.AddDate(0, 0, 1), 1, 900, 900, 2),
+	)
+	repo := &stubRepository{comparison: map[int][]repository.AggregatedDataWithCount{0: data}}
+	weather := &stubWeatherRepository{observations: []model.WeatherObservation{
+		{Date: start, TempMinC: weatherFloat(-2.5), TempMaxC: weatherFloat(11)},
+		{Date: start.AddDate(0, 0, 1), TempMinC: weatherFloat(4), TempMaxC: weatherFloat(18)},
+	}}
+
+	response, err := NewAnomalyService(repo, weather).DetectAnomalies(1, nil, start, start.AddDate(0, 0, 2), AnomalyOptions{
+		Aggregation:  "daily",
+		Method:       AnomalyMethodZScore,
+		Threshold:    3,
+		BaselineDays: 30,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(response.WeatherExplained) == 0 || len(response.Anomalies) == 0 {
+		t.Fatalf("expected anomalies on both days, split by weather, got %+v", response)
+	}
+	for _, anomaly := range response.WeatherExplained {
+		if !anomaly.Period.Equal(start) || len(anomaly.WeatherEvents) != 1 || anomaly.WeatherEvents[0] != WeatherEventFrost {
+			t.Errorf("expected only the frost day to be weather-explained, got %+v", anomaly)
+		}
+	}
+	for _, anomaly := range response.Anomalies {
+		if !anomaly.Period.Equal(start.AddDate(0, 0, 1)) || anomaly.WeatherEvents != nil {
+			t.Errorf("expected only the mild day to remain anomalous, got %+v", anomaly)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"math"
+
+	"irrigation-analytics/internal/repository"
+)
+
+// sectorAreas returns each sector's area in hectares. Sectors without a recorded area are left out.
+func (s *analyticsService) sectorAreas(farmID uint) (map[uint]float64, error) {
+	sectors, err := s.repo.ListSectors(farmID)
+	if err != nil {
+		return nil, err
+	}
+	areas := make(map[uint]float64, len(sectors))
+	for _, sector := range sectors {
+		if sector.Area > 0 {
+			areas[sector.ID] = sector.Area
+		}
+	}
+	return areas, nil
+}
+
+// irrigatedArea is the area the response covers: the filtered sector's area, or the sum of
+// all sector areas. The farm's total_area is not used since it includes unirrigated land.
+func irrigatedArea(areas map[uint]float64, sectorID *uint) float64 {
+	if sectorID != nil {
+		return areas[*sectorID]
+	}
+	var total float64
+	for _, area := range areas {
+		total += area
+	}
+	return total
+}
+
+// perHectare divides value by area, returning nil when the area is unknown
+func perHectare(value, area float64) *float64 {
+	if area <= 0 {
+		return nil
+	}
+	normalized := math.Round(value/area*10000) / 10000
+	return &normalized
+}
+
+// attachAreaNormalization fills the per-hectare fields and applied depth of the summary, sector
+// breakdown and data points. data[i] must correspond to response.Data[i].
+func (s *analyticsService) attachAreaNormalization(response *AnalyticsResponse, data []repository.AggregatedDataWithCount, farmID uint, sectorID *uint) error {
+	areas, err := s.sectorAreas(farmID)
+	if err != nil {
+		return err
+	}
+
+	area := irrigatedArea(areas, sectorID)
+	response.Summary.WaterPerHectare = perHectare(response.Summary.TotalWaterVolume, area)
+	response.Summary.EventsPerHectare = perHectare(float64(response.Summary.TotalEvents), area)
+	response.Summary.AppliedDepthMM = depthMM(response.Summary.TotalWaterVolume, area)
+
+	for i := range response.SectorBreakdown {
+		breakdown := &response.SectorBreakdown[i]
+		breakdown.WaterPerHectare = perHectare(breakdown.TotalWaterVolume, areas[breakdown.SectorID])
+		breakdown.EventsPerHectare = perHectare(float64(breakdown.TotalEvents), areas[breakdown.SectorID])
+		breakdown.AppliedDepthMM = depthMM(breakdown.TotalWaterVolume, areas[breakdown.SectorID])
+	}
+
+	for i, item := range data {
+		sectorArea := areas[item.Data.IrrigationSectorID]
+		response.Data[i].WaterPerHectare = perHectare(response.Data[i].WaterVolume, sectorArea)
+		response.Data[i].EventsPerHectare = perHectare(float64(response.Data[i].EventCount), sectorArea)
+		response.Data[i].AppliedDepthMM = depthMM(response.Data[i].WaterVolume, sectorArea)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// areaRepository adds sector areas to stubRepository
+type areaRepository struct {
+	*stubRepository
+	sectorList []model.IrrigationSector
+}
+
+func (r *areaRepository) ListSectors(farmID uint) ([]model.IrrigationSector, error) {
+	return r.sectorList, nil
+}
+
+// TestGetIrrigationAnalytics_NormalizeByArea verifies per-hectare values for points, sectors and
+// the summary, and that sectors without an area are left unnormalized
+func TestGetIrrigationAnalytics_NormalizeByArea(t *testing.T) {
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	current := []repository.AggregatedDataWithCount{
+		aggregatedPoint(day, 1, 1000, 1000, 4),
+		aggregatedPoint(day, 2, 300, 300, 1),
+	}
+	repo := &areaRepository{
+		stubRepository: &stubRepository{
+			comparison: map[int][]repository.AggregatedDataWithCount{0: current},
+			sectors:    map[int][]repository.AggregatedDataWithCount{0: current},
+		},
+		sectorList: []model.IrrigationSector{{ID: 1, Area: 2.5}, {ID: 2}},
+	}
+	svc := NewAnalyticsService(repo, nil, nil, nil)
+
+	response, err := svc.GetIrrigationAnalytics(1, nil, day, day.AddDate(0, 0, 1), "daily", AnalyticsOptions{NormalizeByArea: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if response.Data[0].WaterPerHectare == nil || *response.Data[0].WaterPerHectare != 400 {
+		t.Errorf("expected sector 1 point at 400 per hectare, got %v", response.Data[0].WaterPerHectare)
+	}
+	if response.Data[0].EventsPerHectare == nil || *response.Data[0].EventsPerHectare != 1.6 {
+		t.Errorf("expected sector 1 point at 1.6 events per hectare, got %v", response.Data[0].EventsPerHectare)
+	}
+	if response.Data[1].WaterPerHectare != nil || response.SectorBreakdown[1].WaterPerHectare != nil {
+		t.Error("expected sector 2 without an area to be left unnormalized")
+	}
+	if response.SectorBreakdown[0].WaterPerHectare == nil || *response.SectorBreakdown[0].WaterPerHectare != 400 {
+		t.Errorf("expected sector 1 breakdown at 400 per hectare, got %v", response.SectorBreakdown[0].WaterPerHectare)
+	}
+	if response.Summary.WaterPerHectare == nil || *response.Summary.WaterPerHectare != 520 {
+		t.Errorf("expected summary at 520 per hectare of irrigated area, got %v", response.Summary.WaterPerHectare)
+	}
+	if response.Data[0].AppliedDepthMM == nil || *response.Data[0].AppliedDepthMM != 0.04 {
+		t.Errorf("expected sector 1 point at 0.04 mm, got %v", response.Data[0].AppliedDepthMM)
+	}
+	if response.SectorBreakdown[0].AppliedDepthMM == nil || response.SectorBreakdown[1].AppliedDepthMM != nil {
+		t.Error("expected an applied depth only for the sector with an area")
+	}
+	if response.Summary.AppliedDepthMM == nil || *response.Summary.AppliedDepthMM != 0.05 {
+		t.Errorf("expected summary at 0.05 mm over the irrigated area, got %v", response.Summary.AppliedDepthMM)
+	}
+
+	response, _ = svc.GetIrrigationAnalytics(1, nil, day, day.AddDate(0, 0, 1), "daily", AnalyticsOptions{})
+	if response.Summary.WaterPerHectare != nil || response.Data[0].WaterPerHectare != nil {
+		t.Error("expected no per-hectare values without normalize=area")
+	}
+}
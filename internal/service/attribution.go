@@ -0,0 +1,14 @@
+package service
+
+import "irrigation-analytics/internal/repository"
+
+// withAttribution returns opts with the farm's stored attribution policy, so every aggregation of
+// the farm places multi-hour events in buckets the same way
+func withAttribution(repo repository.IrrigationRepository, farmID uint, opts repository.QueryOptions) (repository.QueryOptions, error) {
+	policy, err := repo.GetAttributionPolicy(farmID)
+	if err != nil {
+		return opts, err
+	}
+	opts.Attribution = policy
+	return opts, nil
+}
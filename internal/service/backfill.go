@@ -0,0 +1,159 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// maxBackfillMonths caps how many calendar months a single backfill preview may touch
+const maxBackfillMonths = 36
+
+// ErrBackfillTooWide is returned when a backfill spans more than maxBackfillMonths months
+var ErrBackfillTooWide = errors.New("backfill spans too many months to preview")
+
+// BackfillPreview shows how a backfill would change the official numbers of each affected month
+type BackfillPreview struct {
+	Import  *ImportPreview         `json:"import"`
+	Periods []BackfillPeriodImpact `json:"periods"`
+}
+
+// BackfillPeriodImpact compares one affected calendar month before and after the backfill
+type BackfillPeriodImpact struct {
+	Period PeriodInfo       `json:"period"`
+	Before BackfillSnapshot `json:"before"`
+	After  BackfillSnapshot `json:"after"`
+	// VsPreviousYear compares this month with the same month a year earlier
+	VsPreviousYear YoYImpact `json:"vs_previous_year"`
+	// NextYearVsThis compares the same month a year later with this month, which the backfill changes as a baseline
+	NextYearVsThis YoYImpact `json:"next_year_vs_this"`
+}
+
+// BackfillSnapshot holds a month's summary figures; efficiency is the ratio of totals
+type BackfillSnapshot struct {
+	TotalWaterVolume  float64 `json:"total_water_volume"`
+	TotalEvents       int     `json:"total_events"`
+	AverageEfficiency float64 `json:"average_efficiency"`
+}
+
+// YoYImpact holds a year-over-year change before and after the backfill
+type YoYImpact struct {
+	VolumeChangePercentBefore     float64 `json:"volume_change_percent_before"`
+	VolumeChangePercentAfter      float64 `json:"volume_change_percent_after"`
+	EfficiencyChangePercentBefore float64 `json:"efficiency_change_percent_before"`
+	EfficiencyChangePercentAfter  float64 `json:"efficiency_change_percent_after"`
+}
+
+// PreviewBackfill validates rows like a dry-run import, then computes each affected month's
+// summary and year-over-year comparisons with and without the accepted rows
+func (s *importService) PreviewBackfill(farmID uint, rows []ImportRow) (*BackfillPreview, error) {
+	importPreview, accepted, err := s.trialRun(farmID, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	additions := make(map[time.Time]*repository.SummaryResult)
+	for _, event := range accepted {
+		month := time.Date(event.StartTime.Year(), event.StartTime.Month(), 1, 0, 0, 0, 0, time.UTC)
+		if additions[month] == nil {
+			additions[month] = &repository.SummaryResult{}
+		}
+		addToTotals(additions[month], event)
+	}
+	if len(additions) > maxBackfillMonths {
+		return nil, ErrBackfillTooWide
+	}
+
+	months := make([]time.Time, 0, len(additions))
+	for month := range additions {
+		months = append(months, month)
+	}
+	sort.Slice(months, func(i, j int) bool { return months[i].Before(months[j]) })
+
+	preview := &BackfillPreview{Import: importPreview, Periods: make([]BackfillPeriodImpact, 0, len(months))}
+	for _, month := range months {
+		impact, err := s.monthImpact(farmID, month, additions[month])
+		if err != nil {
+			return nil, err
+		}
+		preview.Periods = append(preview.Periods, *impact)
+	}
+	return preview, nil
+}
+
+// monthImpact compares a month with its neighbours a year either side, before and after adding totals
+func (s *importService) monthImpact(farmID uint, month time.Time, added *repository.SummaryResult) (*BackfillPeriodImpact, error) {
+	end := month.AddDate(0, 1, 0)
+
+	current, err := s.repo.GetSummaryData(farmID, nil, month, end, repository.QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+	previousYear, err := s.repo.GetSummaryData(farmID, nil, month.AddDate(-1, 0, 0), end.AddDate(-1, 0, 0), repository.QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+	nextYear, err := s.repo.GetSummaryData(farmID, nil, month.AddDate(1, 0, 0), end.AddDate(1, 0, 0), repository.QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	combined := *current
+	combined.WaterVolume += added.WaterVolume
+	combined.Duration += added.Duration
+	combined.EventCount += added.EventCount
+	combined.NominalAmount += added.NominalAmount
+	combined.RealAmount += added.RealAmount
+	combined.MissingNominalCount += added.MissingNominalCount
+
+	before := s.snapshot(current)
+	after := s.snapshot(&combined)
+	previous := s.snapshot(previousYear)
+	next := s.snapshot(nextYear)
+
+	return &BackfillPeriodImpact{
+		Period: PeriodInfo{StartDate: month, EndDate: end},
+		Before: before,
+		After:  after,
+		VsPreviousYear: YoYImpact{
+			VolumeChangePercentBefore:     s.analytics.calculateChangePercent(before.TotalWaterVolume, previous.TotalWaterVolume),
+			VolumeChangePercentAfter:      s.analytics.calculateChangePercent(after.TotalWaterVolume, previous.TotalWaterVolume),
+			EfficiencyChangePercentBefore: s.analytics.calculateChangePercent(before.AverageEfficiency, previous.AverageEfficiency),
+			EfficiencyChangePercentAfter:  s.analytics.calculateChangePercent(after.AverageEfficiency, previous.AverageEfficiency),
+		},
+		NextYearVsThis: YoYImpact{
+			VolumeChangePercentBefore:     s.analytics.calculateChangePercent(next.TotalWaterVolume, before.TotalWaterVolume),
+			VolumeChangePercentAfter:      s.analytics.calculateChangePercent(next.TotalWaterVolume, after.TotalWaterVolume),
+			EfficiencyChangePercentBefore: s.analytics.calculateChangePercent(next.AverageEfficiency, before.AverageEfficiency),
+			EfficiencyChangePercentAfter:  s.analytics.calculateChangePercent(next.AverageEfficiency, after.AverageEfficiency),
+		},
+	}, nil
+}
+
+// snapshot summarises period totals using the same fallback rules as summary-only analytics
+func (s *importService) snapshot(totals *repository.SummaryResult) BackfillSnapshot {
+	efficiency, _, _, _ := s.analytics.bucketEfficiency(summaryBucket(totals))
+	return BackfillSnapshot{
+		TotalWaterVolume:  math.Round(totals.WaterVolume*100) / 100,
+		TotalEvents:       totals.EventCount,
+		AverageEfficiency: efficiency,
+	}
+}
+
+// addToTotals adds a single event to running totals
+func addToTotals(totals *repository.SummaryResult, event model.IrrigationData) {
+	totals.WaterVolume += event.WaterVolume
+	totals.Duration += event.Duration
+	totals.EventCount++
+	// As in the summary query, real amounts count only alongside a recorded nominal amount
+	if event.NominalAmount == nil {
+		totals.MissingNominalCount++
+	} else {
+		totals.NominalAmount += *event.NominalAmount
+		totals.RealAmount += event.RealAmount
+	}
+}
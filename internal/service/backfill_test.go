@@ -0,0 +1,88 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/repository"
+)
+
+// summaryRepository serves fixed period totals keyed by period start
+type summaryRepository struct {
+	sectorRepository
+	totals map[time.Time]repository.SummaryResult
+}
+
+func (r *summaryRepository) GetSummaryData(farmID uint, sectorID *uint, startDate, endDate time.Time, opts repository.QueryOptions) (*repository.SummaryResult, error) {
+	totals := r.totals[startDate]
+	return &totals, nil
+}
+
+// TestPreviewBackfill verifies the before/after figures of the affected month and both YoY comparisons
+func TestPreviewBackfill(t *testing.T) {
+	march := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	repo := &summaryRepository{totals: map[time.Time]repository.SummaryResult{
+		march.AddDate(-1, 0, 0): {WaterVolume: 1000, EventCount: 10, NominalAmount: 1000, RealAmount: 900},
+		march:                   {WaterVolume: 500, EventCount: 5, NominalAmount: 500, RealAmount: 450},
+		march.AddDate(1, 0, 0):  {WaterVolume: 1000, EventCount: 10, NominalAmount: 1000, RealAmount: 900},
+	}}
+	svc := NewImportService(repo, &stubImportRepository{failAt: -1}, nil, nil, nil)
+
+	rows := importRows(5)
+	for i := range rows {
+		rows[i].StartTime = rows[i].StartTime.AddDate(-1, 2, 0)
+		rows[i].EndTime = rows[i].EndTime.AddDate(-1, 2, 0)
+	}
+
+	preview, err := svc.PreviewBackfill(1, rows)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(preview.Periods) != 1 {
+		t.Fatalf("expected 1 affected month, got %d", len(preview.Periods))
+	}
+
+	impact := preview.Periods[0]
+	if !impact.Period.StartDate.Equal(march) {
+		t.Errorf("expected March 2024, got %v", impact.Period.StartDate)
+	}
+	if impact.Before.TotalWaterVolume != 500 || impact.After.TotalWaterVolume != 1000 || impact.After.TotalEvents != 10 {
+		t.Errorf("unexpected before/after: %+v -> %+v", impact.Before, impact.After)
+	}
+	if impact.VsPreviousYear.VolumeChangePercentBefore != -50 || impact.VsPreviousYear.VolumeChangePercentAfter != 0 {
+		t.Errorf("unexpected change vs previous year: %+v", impact.VsPreviousYear)
+	}
+	if impact.NextYearVsThis.VolumeChangePercentBefore != 100 || impact.NextYearVsThis.VolumeChangePercentAfter != 0 {
+		t.Errorf("unexpected next year change: %+v", impact.NextYearVsThis)
+	}
+}
+
+// TestPreviewBackfill_MixedNominal verifies events without a nominal amount don't inflate the
+// efficiency of a month that also has recorded nominal amounts
+func TestPreviewBackfill_MixedNominal(t *testing.T) {
+	march := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	repo := &summaryRepository{totals: map[time.Time]repository.SummaryResult{
+		march: {WaterVolume: 500, Duration: 150, EventCount: 5, NominalAmount: 500, RealAmount: 450},
+	}}
+	svc := NewImportService(repo, &stubImportRepository{failAt: -1}, nil, nil, nil)
+
+	// One event recorded 100 nominal and 90 real; the other recorded no nominal amount
+	rows := importRows(2)
+	nominal := 100.0
+	rows[0].NominalAmount, rows[0].RealAmount = &nominal, 90
+	rows[1].RealAmount = 200
+	for i := range rows {
+		rows[i].StartTime = rows[i].StartTime.AddDate(-1, 2, 0)
+		rows[i].EndTime = rows[i].EndTime.AddDate(-1, 2, 0)
+	}
+
+	preview, err := svc.PreviewBackfill(1, rows)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 540 real over 600 nominal, rather than 740 over 600 with the second event's real amount
+	impact := preview.Periods[0]
+	if impact.Before.AverageEfficiency != 0.9 || impact.After.AverageEfficiency != 0.9 || impact.After.TotalWaterVolume != 700 {
+		t.Errorf("expected efficiency to stay 0.9, got %+v -> %+v", impact.Before, impact.After)
+	}
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"irrigation-analytics/internal/repository"
+)
+
+// QueryBudget bounds the database work a single request may plan. Requests over budget are
+// rejected before any query runs.
+type QueryBudget struct {
+	// MaxQueries is the most repository queries one request may issue
+	MaxQueries int
+	// MaxBucketCost is the most aggregation buckets one request may compute, summed over
+	// every window it queries (current period and each comparison year)
+	MaxBucketCost int
+}
+
+// DefaultQueryBudget allows daily analytics with its two comparison years over roughly 8-10 years
+var DefaultQueryBudget = QueryBudget{
+	MaxQueries:    8,
+	MaxBucketCost: 12000,
+}
+
+// BudgetError reports which budget limit a request exceeded
+type BudgetError struct {
+	Limit     string `json:"limit"`
+	Max       int    `json:"max"`
+	Estimated int    `json:"estimated"`
+	Hint      string `json:"hint"`
+}
+
+// Error implements error
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("query budget exceeded: %s estimated at %d, limit is %d", e.Limit, e.Estimated, e.Max)
+}
+
+// queryPlan is the estimated database work of a request
+type queryPlan struct {
+	queries    int
+	bucketCost int
+}
+
+// check returns a *BudgetError when the plan exceeds the budget
+func (b QueryBudget) check(plan queryPlan) error {
+	if b.MaxQueries > 0 && plan.queries > b.MaxQueries {
+		return &BudgetError{
+			Limit:     "queries",
+			Max:       b.MaxQueries,
+			Estimated: plan.queries,
+			Hint:      "request fewer breakdowns or metrics at once",
+		}
+	}
+	if b.MaxBucketCost > 0 && plan.bucketCost > b.MaxBucketCost {
+		return &BudgetError{
+			Limit:     "bucket_cost",
+			Max:       b.MaxBucketCost,
+			Estimated: plan.bucketCost,
+			Hint:      "use a coarser aggregation or a shorter date range",
+		}
+	}
+	return nil
+}
+
+// bucketCount estimates how many aggregation buckets cover [startDate, endDate)
+func bucketCount(startDate, endDate time.Time, aggregation string) int {
+	start := bucketStart(startDate, aggregation)
+	if !endDate.After(start) {
+		return 1
+	}
+	span := endDate.Sub(start)
+	if _, ok := repository.ParseCustomAggregation(aggregation); ok {
+		return len(bucketStarts(startDate, endDate, aggregation))
+	}
+	switch aggregation {
+	case "hourly":
+		return int(math.Ceil(span.Hours()))
+	case "weekly":
+		return int(math.Ceil(span.Hours() / (7 * 24)))
+	case "monthly":
+		return (endDate.Year()-start.Year())*12 + int(endDate.Month()) - int(start.Month()) + 1
+	case "quarterly":
+		return ((endDate.Year()-start.Year())*12+int(endDate.Month())-int(start.Month()))/3 + 1
+	case "yearly":
+		return endDate.Year() - start.Year() + 1
+	default:
+		return int(math.Ceil(span.Hours() / 24))
+	}
+}
+
+// analyticsPlan estimates the work of GetIrrigationAnalytics: the comparison query covers the
+// current period, two comparison years and any other years compared to, other comparison periods
+// add one query, and each supplementary section adds one query. Omitted sections cost nothing.
+func (s *analyticsService) analyticsPlan(sectorID *uint, startDate, endDate time.Time, aggregation string, opts AnalyticsOptions) queryPlan {
+	plan := queryPlan{queries: 1, bucketCost: bucketCount(startDate, endDate, aggregation)}
+	if !opts.omits(SectionComparisons) {
+		// One and two years back, and the comparison periods beyond them
+		yearsBack, ranges := comparisonPlan(opts.CompareTo)
+		plan.bucketCost += len(yearsBack) * bucketCount(startDate, endDate, aggregation)
+		if len(ranges) > 0 {
+			plan.queries++
+		}
+		for _, dateRange := range ranges {
+			plan.bucketCost += bucketCount(dateRange.Start, dateRange.End, aggregation)
+		}
+	}
+	withData := !opts.omits(SectionData)
+	if s.annotations != nil && withData && !opts.omits(SectionAnnotations) {
+		plan.queries++
+	}
+	if s.pressure != nil && withData && !opts.omits(SectionPressure) {
+		plan.queries++
+		plan.bucketCost += bucketCount(startDate, endDate, aggregation)
+	}
+	switch {
+	case opts.omits(SectionSectorBreakdown):
+		if sectorID != nil && opts.SectorScope {
+			// The sector list
+			plan.queries++
+		}
+	case sectorID == nil:
+		plan.queries++
+	case opts.SectorScope:
+		// The sector list and the breakdown of the sector's subtree
+		plan.queries += 2
+	}
+	if opts.BreakdownByPurpose {
+		plan.queries++
+	}
+	if opts.NormalizeByArea {
+		plan.queries++
+	}
+	if opts.IncludeWeather && s.weather != nil && aggregation != "hourly" && withData {
+		plan.queries++
+	}
+	if opts.IncludeDistribution {
+		plan.queries++
+	}
+	if opts.Level > 0 {
+		plan.queries++
+	}
+	if opts.FillGaps {
+		plan.bucketCost += bucketCount(startDate, endDate, aggregation)
+	}
+	return plan
+}
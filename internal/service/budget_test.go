@@ -0,0 +1,71 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/repository"
+)
+
+func TestBucketCount(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		aggregation string
+		end         time.Time
+		expected    int
+	}{
+		{"hourly", start.AddDate(0, 0, 2), 48},
+		{"daily", start.AddDate(0, 0, 31), 31},
+		{"daily", start.Add(36 * time.Hour), 2},
+		{"weekly", start.AddDate(0, 0, 28), 5}, // 2025-01-01 is a Wednesday, so the first week starts Dec 30
+		{"monthly", time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC), 12},
+		{"monthly", time.Date(2027, 1, 15, 0, 0, 0, 0, time.UTC), 25},
+		{"quarterly", time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC), 4},
+		{"quarterly", time.Date(2027, 1, 15, 0, 0, 0, 0, time.UTC), 9},
+		{"yearly", time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), 6},
+		{"10d", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), 37}, // 36 full decads and a 5-day remainder
+		{"10d", time.Date(2025, 1, 21, 0, 0, 0, 0, time.UTC), 2},
+	}
+
+	for _, tt := range tests {
+		if got := bucketCount(start, tt.end, tt.aggregation); got != tt.expected {
+			t.Errorf("%s to %s: expected %d buckets, got %d", tt.aggregation, tt.end.Format(time.DateOnly), tt.expected, got)
+		}
+	}
+}
+
+// TestGetIrrigationAnalytics_BudgetExceeded verifies over-budget requests fail before any query runs
+func TestGetIrrigationAnalytics_BudgetExceeded(t *testing.T) {
+	repo := &stubRepository{comparison: map[int][]repository.AggregatedDataWithCount{0: {}}}
+	svc := NewAnalyticsService(repo, nil, nil, nil)
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := svc.GetIrrigationAnalytics(1, nil, start, start.AddDate(15, 0, 0), "daily", AnalyticsOptions{})
+	var budgetErr *BudgetError
+	if !errors.As(err, &budgetErr) || budgetErr.Limit != "bucket_cost" {
+		t.Fatalf("expected a bucket_cost budget error, got %v", err)
+	}
+	if repo.calls != 0 {
+		t.Errorf("expected no queries for an over-budget request, got %d", repo.calls)
+	}
+
+	if _, err := svc.GetIrrigationAnalytics(1, nil, start, start.AddDate(15, 0, 0), "weekly", AnalyticsOptions{}); err != nil {
+		t.Errorf("expected weekly aggregation over 15 years to fit the budget, got %v", err)
+	}
+	omit := AnalyticsOptions{Omit: []string{SectionComparisons}}
+	if _, err := svc.GetIrrigationAnalytics(1, nil, start, start.AddDate(15, 0, 0), "daily", omit); err != nil {
+		t.Errorf("expected daily aggregation over 15 years to fit the budget without comparisons, got %v", err)
+	}
+}
+
+func TestNextBucket_CustomRestartsEachYear(t *testing.T) {
+	last := time.Date(2025, 12, 27, 0, 0, 0, 0, time.UTC) // day 361 opens the final, 5-day decad
+	if got := bucketStart(time.Date(2025, 12, 31, 12, 0, 0, 0, time.UTC), "10d"); !got.Equal(last) {
+		t.Errorf("expected the final decad to start %v, got %v", last, got)
+	}
+	if got := nextBucket(last, "10d"); !got.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the next decad to start on 1 January, got %v", got)
+	}
+}
@@ -0,0 +1,44 @@
+package service
+
+import "irrigation-analytics/internal/repository"
+
+// CacheAdminService defines the interface for operator inspection of the farm existence cache
+type CacheAdminService interface {
+	GetFarmCache() *FarmCacheReport
+	EvictFarm(farmID uint) bool
+	FlushFarmCache()
+}
+
+// FarmCacheReport combines cache statistics with the cached entries
+type FarmCacheReport struct {
+	Stats   repository.FarmCacheStats `json:"stats"`
+	Entries []repository.CachedFarm   `json:"entries"`
+}
+
+// cacheAdminService implements CacheAdminService
+type cacheAdminService struct {
+	cache *repository.FarmCache
+}
+
+// NewCacheAdminService creates a new cache admin service
+func NewCacheAdminService(cache *repository.FarmCache) CacheAdminService {
+	return &cacheAdminService{cache: cache}
+}
+
+// GetFarmCache returns the cache statistics and every cached entry
+func (s *cacheAdminService) GetFarmCache() *FarmCacheReport {
+	return &FarmCacheReport{
+		Stats:   s.cache.Stats(),
+		Entries: s.cache.Entries(),
+	}
+}
+
+// EvictFarm removes a farm's cached entry, reporting whether one was cached
+func (s *cacheAdminService) EvictFarm(farmID uint) bool {
+	return s.cache.Invalidate(farmID)
+}
+
+// FlushFarmCache removes every cached entry
+func (s *cacheAdminService) FlushFarmCache() {
+	s.cache.Flush()
+}
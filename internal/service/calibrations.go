@@ -0,0 +1,174 @@
+package service
+
+import (
+	"math"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// CalibrationService defines the interface for managing ingestion calibrations
+type CalibrationService interface {
+	FarmExists(farmID uint) (bool, error)
+	SetCalibration(farmID uint, input CalibrationInput) (*model.Calibration, error)
+	ListCalibrations(farmID uint) ([]model.Calibration, error)
+	DeleteCalibration(farmID, calibrationID uint) (bool, error)
+}
+
+// CalibrationInput describes a calibration. Volume calibrations set DataSource and optionally
+// SectorID; pressure calibrations set DeviceID.
+type CalibrationInput struct {
+	Target     string
+	DataSource string
+	SectorID   *uint
+	DeviceID   string
+	Factor     float64
+	Offset     float64
+	Note       string
+}
+
+// calibrationService implements CalibrationService
+type calibrationService struct {
+	repo         repository.IrrigationRepository
+	calibrations repository.CalibrationRepository
+}
+
+// NewCalibrationService creates a new calibration service
+func NewCalibrationService(repo repository.IrrigationRepository, calibrations repository.CalibrationRepository) CalibrationService {
+	return &calibrationService{repo: repo, calibrations: calibrations}
+}
+
+// FarmExists checks if a farm exists
+func (s *calibrationService) FarmExists(farmID uint) (bool, error) {
+	return s.repo.FarmExists(farmID)
+}
+
+// SetCalibration stores a calibration, replacing the one with the same match. It applies to
+// values ingested from now on; stored values are not recalibrated.
+func (s *calibrationService) SetCalibration(farmID uint, input CalibrationInput) (*model.Calibration, error) {
+	if input.SectorID != nil {
+		sectors, err := s.repo.ListSectors(farmID)
+		if err != nil {
+			return nil, err
+		}
+		if !sectorInList(sectors, *input.SectorID) {
+			return nil, ErrSectorNotFound
+		}
+	}
+
+	calibration := &model.Calibration{
+		FarmID:             farmID,
+		Target:             input.Target,
+		DataSource:         input.DataSource,
+		IrrigationSectorID: input.SectorID,
+		DeviceID:           input.DeviceID,
+		Factor:             input.Factor,
+		Offset:             input.Offset,
+		Note:               input.Note,
+	}
+	if err := s.calibrations.Replace(calibration); err != nil {
+		return nil, err
+	}
+	return calibration, nil
+}
+
+// ListCalibrations returns the farm's calibrations
+func (s *calibrationService) ListCalibrations(farmID uint) ([]model.Calibration, error) {
+	return s.calibrations.List(farmID)
+}
+
+// DeleteCalibration removes a calibration of the farm, reporting whether it existed
+func (s *calibrationService) DeleteCalibration(farmID, calibrationID uint) (bool, error) {
+	return s.calibrations.Delete(farmID, calibrationID)
+}
+
+// sectorInList reports whether the sectors include sectorID
+func sectorInList(sectors []model.IrrigationSector, sectorID uint) bool {
+	for _, sector := range sectors {
+		if sector.ID == sectorID {
+			return true
+		}
+	}
+	return false
+}
+
+// volumeCalibrationKey matches a volume calibration; sectorID 0 is the data source's farm-wide one
+type volumeCalibrationKey struct {
+	dataSource string
+	sectorID   uint
+}
+
+// calibrator applies a farm's calibrations to values being ingested. A nil calibrator leaves
+// values unchanged.
+type calibrator struct {
+	volume   map[volumeCalibrationKey]*model.Calibration
+	pressure map[string]*model.Calibration
+}
+
+// loadCalibrator fetches the farm's calibrations, returning nil when there are none or no
+// calibration repository is configured
+func loadCalibrator(calibrations repository.CalibrationRepository, farmID uint) (*calibrator, error) {
+	if calibrations == nil {
+		return nil, nil
+	}
+	list, err := calibrations.List(farmID)
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+
+	c := &calibrator{
+		volume:   map[volumeCalibrationKey]*model.Calibration{},
+		pressure: map[string]*model.Calibration{},
+	}
+	for i := range list {
+		calibration := &list[i]
+		switch calibration.Target {
+		case model.CalibrationTargetVolume:
+			key := volumeCalibrationKey{dataSource: calibration.DataSource}
+			if calibration.IrrigationSectorID != nil {
+				key.sectorID = *calibration.IrrigationSectorID
+			}
+			c.volume[key] = calibration
+		case model.CalibrationTargetPressure:
+			c.pressure[calibration.DeviceID] = calibration
+		}
+	}
+	return c, nil
+}
+
+// calibrateEvent corrects the event's water_volume and real_amount with the calibration of its
+// sector, or else of its data source, keeping the reported values
+func (c *calibrator) calibrateEvent(event *model.IrrigationData) {
+	if c == nil {
+		return
+	}
+	calibration, ok := c.volume[volumeCalibrationKey{event.DataSource, event.IrrigationSectorID}]
+	if !ok {
+		if calibration, ok = c.volume[volumeCalibrationKey{dataSource: event.DataSource}]; !ok {
+			return
+		}
+	}
+
+	rawVolume, rawReal := event.WaterVolume, event.RealAmount
+	event.RawWaterVolume, event.RawRealAmount = &rawVolume, &rawReal
+	event.WaterVolume = math.Round(calibration.Apply(rawVolume)*100) / 100
+	event.RealAmount = math.Round(calibration.Apply(rawReal)*100) / 100
+	event.CalibrationID = &calibration.ID
+}
+
+// calibrateReading corrects the reading's pressure with its device's calibration, keeping the
+// reported value
+func (c *calibrator) calibrateReading(reading *model.PressureReading) {
+	if c == nil || reading.DeviceID == "" {
+		return
+	}
+	calibration, ok := c.pressure[reading.DeviceID]
+	if !ok {
+		return
+	}
+
+	raw := reading.PressureBar
+	reading.RawPressureBar = &raw
+	reading.PressureBar = math.Round(calibration.Apply(raw)*1000) / 1000
+	reading.CalibrationID = &calibration.ID
+}
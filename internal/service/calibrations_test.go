@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// stubCalibrationRepository serves a fixed list of calibrations
+type stubCalibrationRepository struct {
+	calibrations []model.Calibration
+}
+
+func (r *stubCalibrationRepository) Replace(calibration *model.Calibration) error {
+	r.calibrations = append(r.calibrations, *calibration)
+	return nil
+}
+
+func (r *stubCalibrationRepository) List(farmID uint) ([]model.Calibration, error) {
+	return r.calibrations, nil
+}
+
+func (r *stubCalibrationRepository) Delete(farmID, calibrationID uint) (bool, error) {
+	return false, nil
+}
+
+func (r *stubCalibrationRepository) WithContext(ctx context.Context) repository.CalibrationRepository {
+	return r
+}
+
+// TestCalibrator verifies the sector calibration wins over the data source's and raw values are kept
+func TestCalibrator(t *testing.T) {
+	sectorID := uint(2)
+	calibrations := &stubCalibrationRepository{calibrations: []model.Calibration{
+		{ID: 1, Target: model.CalibrationTargetVolume, DataSource: model.DataSourceImport, Factor: 1.1},
+		{ID: 2, Target: model.CalibrationTargetVolume, DataSource: model.DataSourceImport, IrrigationSectorID: &sectorID, Factor: 0.9, Offset: -5},
+		{ID: 3, Target: model.CalibrationTargetPressure, DeviceID: "pt-7", Factor: 1, Offset: 0.25},
+	}}
+	cal, err := loadCalibrator(calibrations, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	farmWide := model.IrrigationData{IrrigationSectorID: 1, DataSource: model.DataSourceImport, WaterVolume: 100, RealAmount: 80}
+	cal.calibrateEvent(&farmWide)
+	if farmWide.WaterVolume != 110 || farmWide.RealAmount != 88 || *farmWide.RawWaterVolume != 100 || *farmWide.CalibrationID != 1 {
+		t.Errorf("expected the data source calibration, got %+v", farmWide)
+	}
+
+	sector := model.IrrigationData{IrrigationSectorID: 2, DataSource: model.DataSourceImport, WaterVolume: 100, RealAmount: 4}
+	cal.calibrateEvent(&sector)
+	if sector.WaterVolume != 85 || sector.RealAmount != 0 || *sector.RawRealAmount != 4 || *sector.CalibrationID != 2 {
+		t.Errorf("expected the sector calibration clamped at zero, got %+v", sector)
+	}
+
+	other := model.IrrigationData{IrrigationSectorID: 1, DataSource: model.DataSourceAPI, WaterVolume: 100}
+	cal.calibrateEvent(&other)
+	if other.WaterVolume != 100 || other.RawWaterVolume != nil || other.CalibrationID != nil {
+		t.Errorf("expected other sources to be left alone, got %+v", other)
+	}
+
+	reading := model.PressureReading{DeviceID: "pt-7", PressureBar: 2.5}
+	cal.calibrateReading(&reading)
+	if reading.PressureBar != 2.75 || *reading.RawPressureBar != 2.5 {
+		t.Errorf("expected the device calibration, got %+v", reading)
+	}
+
+	var none *calibrator
+	none.calibrateEvent(&other)
+	none.calibrateReading(&reading)
+}
+
+// TestImportEvents_Calibrated verifies imported events are stored corrected
+func TestImportEvents_Calibrated(t *testing.T) {
+	imports := &stubImportRepository{failAt: -1}
+	calibrations := &stubCalibrationRepository{calibrations: []model.Calibration{
+		{ID: 1, Target: model.CalibrationTargetVolume, DataSource: model.DataSourceImport, Factor: 1.02},
+	}}
+	svc := NewImportService(&sectorRepository{}, imports, calibrations, nil, nil)
+
+	preview, err := svc.PreviewImport(1, importRows(10))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if preview.Impact.WaterVolume != 1020 || preview.Impact.RealAmount != 1020 {
+		t.Errorf("expected calibrated totals of 1020, got %+v", preview.Impact)
+	}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"irrigation-analytics/internal/repository"
+)
+
+// ComparePreviousPeriod compares against the period of the same length ending where the
+// requested period starts
+const ComparePreviousPeriod = "previous_period"
+
+// Comparison bounds: the periods one request compares against and how far back an offset reaches
+const (
+	MaxComparisons         = 5
+	maxComparisonYearsBack = 10
+)
+
+// ComparisonPeriod is one period the analytics are compared against
+type ComparisonPeriod struct {
+	// Label is the compare_to entry the period was requested as
+	Label     string
+	StartDate time.Time
+	EndDate   time.Time
+	// YearsBack is set for whole-year offsets, which the comparison query fetches alongside the
+	// current period; other periods are fetched by a second query
+	YearsBack int
+}
+
+// ComparisonBlock reports the metrics of one comparison period and their change to the current period
+type ComparisonBlock struct {
+	CompareTo string `json:"compare_to"`
+	PeriodMetrics
+}
+
+// DefaultComparisons returns the comparison periods used when a request sets no compare_to: the
+// same period one and two years back
+func DefaultComparisons(startDate, endDate time.Time) []ComparisonPeriod {
+	return []ComparisonPeriod{yearsBackComparison(1, startDate, endDate), yearsBackComparison(2, startDate, endDate)}
+}
+
+// ComparisonOffset parses a compare_to offset relative to [startDate, endDate):
+// ComparePreviousPeriod, or -N followed by d, w, m or y to shift the period back by N days,
+// weeks, months or years, up to 10 years
+func ComparisonOffset(offset string, startDate, endDate time.Time) (ComparisonPeriod, error) {
+	if offset == ComparePreviousPeriod {
+		return ComparisonPeriod{
+			Label:     offset,
+			StartDate: startDate.Add(-endDate.Sub(startDate)),
+			EndDate:   startDate,
+		}, nil
+	}
+
+	if len(offset) < 3 || offset[0] != '-' {
+		return ComparisonPeriod{}, fmt.Errorf("unknown comparison %q", offset)
+	}
+	n, err := strconv.Atoi(offset[1 : len(offset)-1])
+	if err != nil || n < 1 {
+		return ComparisonPeriod{}, fmt.Errorf("unknown comparison %q", offset)
+	}
+	var years, months, days int
+	switch offset[len(offset)-1] {
+	case 'd':
+		days = n
+	case 'w':
+		days = 7 * n
+	case 'm':
+		months = n
+	case 'y':
+		years = n
+	default:
+		return ComparisonPeriod{}, fmt.Errorf("unknown comparison %q", offset)
+	}
+	comparison := ComparisonPeriod{
+		Label:     offset,
+		StartDate: startDate.AddDate(-years, -months, -days),
+		EndDate:   endDate.AddDate(-years, -months, -days),
+	}
+	if comparison.StartDate.Before(startDate.AddDate(-maxComparisonYearsBack, 0, 0)) {
+		return ComparisonPeriod{}, fmt.Errorf("comparison %q reaches more than %d years back", offset, maxComparisonYearsBack)
+	}
+	if years > 0 {
+		comparison.YearsBack = years
+	}
+	return comparison, nil
+}
+
+// yearsBackComparison is the period years back, labeled as the -Ny offset
+func yearsBackComparison(years int, startDate, endDate time.Time) ComparisonPeriod {
+	return ComparisonPeriod{
+		Label:     fmt.Sprintf("-%dy", years),
+		StartDate: startDate.AddDate(-years, 0, 0),
+		EndDate:   endDate.AddDate(-years, 0, 0),
+		YearsBack: years,
+	}
+}
+
+// alignYearsBack places the whole-year comparisons as the comparison query does with queryOpts,
+// so with season alignment they report the periods actually compared
+func alignYearsBack(comparisons []ComparisonPeriod, startDate, endDate time.Time, queryOpts repository.QueryOptions) []ComparisonPeriod {
+	aligned := make([]ComparisonPeriod, len(comparisons))
+	for i, comparison := range comparisons {
+		if comparison.YearsBack > 0 {
+			comparison.StartDate, comparison.EndDate = queryOpts.YearsBack(startDate, endDate, comparison.YearsBack)
+		}
+		aligned[i] = comparison
+	}
+	return aligned
+}
+
+// comparisonPlan splits the comparison periods into the years back added to the comparison query,
+// beyond the one and two years it always fetches, and the ranges fetched by a second query
+func comparisonPlan(comparisons []ComparisonPeriod) (yearsBack []int, ranges []repository.DateRange) {
+	yearsBack = []int{1, 2}
+	for _, comparison := range comparisons {
+		if comparison.YearsBack > 0 {
+			if !slices.Contains(yearsBack, comparison.YearsBack) {
+				yearsBack = append(yearsBack, comparison.YearsBack)
+			}
+			continue
+		}
+		ranges = append(ranges, repository.DateRange{Start: comparison.StartDate, End: comparison.EndDate})
+	}
+	return yearsBack, ranges
+}
+
+// calculateComparisons computes a block for every comparison period, in the requested order.
+// Periods without events get a block with zero totals.
+func (s *analyticsService) calculateComparisons(comparisons []ComparisonPeriod, comparisonData map[int][]repository.AggregatedDataWithCount, rangesData [][]repository.AggregatedDataWithCount, currentSummary AnalyticsSummary, weighting string) []ComparisonBlock {
+	blocks := make([]ComparisonBlock, 0, len(comparisons))
+	rangeIndex := 0
+	for _, comparison := range comparisons {
+		var data []repository.AggregatedDataWithCount
+		if comparison.YearsBack > 0 {
+			data = comparisonData[comparison.YearsBack]
+		} else {
+			data = rangesData[rangeIndex]
+			rangeIndex++
+		}
+		blocks = append(blocks, ComparisonBlock{
+			CompareTo:     comparison.Label,
+			PeriodMetrics: s.periodMetrics(comparison.StartDate, comparison.EndDate, data, currentSummary, weighting),
+		})
+	}
+	return blocks
+}
+
+// periodMetrics summarizes a comparison period's buckets with their change to the current summary
+func (s *analyticsService) periodMetrics(startDate, endDate time.Time, data []repository.AggregatedDataWithCount, currentSummary AnalyticsSummary, weighting string) PeriodMetrics {
+	summary := s.calculateSummary(data, weighting)
+	return PeriodMetrics{
+		Period: PeriodInfo{
+			StartDate: startDate,
+			EndDate:   endDate,
+		},
+		TotalWaterVolume:        summary.TotalWaterVolume,
+		TotalEvents:             summary.TotalEvents,
+		AverageEfficiency:       summary.AverageEfficiency,
+		VolumeChangePercent:     s.calculateChangePercent(currentSummary.TotalWaterVolume, summary.TotalWaterVolume),
+		EventsChangePercent:     s.calculateChangePercent(float64(currentSummary.TotalEvents), float64(summary.TotalEvents)),
+		EfficiencyChangePercent: s.calculateChangePercent(currentSummary.AverageEfficiency, summary.AverageEfficiency),
+	}
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+func TestGetIrrigationAnalytics_CompareTo(t *testing.T) {
+	day := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	end := day.AddDate(0, 3, 0)
+	repo := &stubRepository{
+		comparison: map[int][]repository.AggregatedDataWithCount{
+			0: {aggregatedPoint(day, 1, 200, 200, 4)},
+			1: {aggregatedPoint(day.AddDate(-1, 0, 0), 1, 100, 100, 2)},
+			2: {},
+			3: {aggregatedPoint(day.AddDate(-3, 0, 0), 1, 400, 400, 8)},
+		},
+		ranges: [][]repository.AggregatedDataWithCount{
+			{aggregatedPoint(day.AddDate(0, -3, 0), 1, 160, 160, 4)},
+			{},
+		},
+	}
+	svc := NewAnalyticsService(repo, nil, nil, nil)
+
+	previous, err := ComparisonOffset(ComparePreviousPeriod, day, end)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	threeYears, err := ComparisonOffset("-3y", day, end)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	explicit := ComparisonPeriod{Label: "2020-04-01/2020-07-01", StartDate: day.AddDate(-5, 0, 0), EndDate: end.AddDate(-5, 0, 0)}
+
+	response, err := svc.GetIrrigationAnalytics(1, nil, day, end, "monthly", AnalyticsOptions{
+		CompareTo: []ComparisonPeriod{previous, threeYears, explicit},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(repo.yearsBack) != 3 || repo.yearsBack[2] != 3 || len(repo.dateRanges) != 2 || !repo.dateRanges[0].End.Equal(day) {
+		t.Errorf("expected -3y in the comparison query and two ranges queried, got %v and %+v", repo.yearsBack, repo.dateRanges)
+	}
+	if len(response.Comparisons) != 3 {
+		t.Fatalf("expected 3 comparison blocks, got %+v", response.Comparisons)
+	}
+	if block := response.Comparisons[0]; block.CompareTo != ComparePreviousPeriod || block.TotalWaterVolume != 160 || block.VolumeChangePercent != 25 {
+		t.Errorf("unexpected previous period block %+v", block)
+	}
+	if block := response.Comparisons[1]; block.CompareTo != "-3y" || block.TotalEvents != 8 || block.VolumeChangePercent != -50 {
+		t.Errorf("unexpected -3y block %+v", block)
+	}
+	if block := response.Comparisons[2]; block.CompareTo != explicit.Label || block.TotalEvents != 0 || !block.Period.StartDate.Equal(explicit.StartDate) {
+		t.Errorf("expected an empty block for the explicit range, got %+v", block)
+	}
+	if response.PeriodComparison.OneYearAgo == nil || response.PeriodComparison.OneYearAgo.VolumeChangePercent != 100 {
+		t.Errorf("expected the legacy one-year-ago comparison kept, got %+v", response.PeriodComparison.OneYearAgo)
+	}
+}
+
+func TestGetIrrigationAnalytics_DefaultComparisons(t *testing.T) {
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := &stubRepository{comparison: map[int][]repository.AggregatedDataWithCount{
+		0: {aggregatedPoint(day, 1, 110, 100, 2)},
+		1: {aggregatedPoint(day.AddDate(-1, 0, 0), 1, 100, 100, 1)},
+		2: {},
+	}}
+	svc := NewAnalyticsService(repo, nil, nil, nil)
+
+	response, err := svc.GetIrrigationAnalytics(1, &[]uint{1}[0], day, day.AddDate(0, 1, 0), "daily", AnalyticsOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.dateRanges != nil || len(response.Comparisons) != 2 {
+		t.Fatalf("expected the -1y and -2y blocks from the comparison query alone, got %+v", response.Comparisons)
+	}
+	if response.Comparisons[0].CompareTo != "-1y" || response.Comparisons[0].VolumeChangePercent != 10 || response.Comparisons[1].CompareTo != "-2y" {
+		t.Errorf("unexpected default comparisons %+v", response.Comparisons)
+	}
+}
+
+func TestGetIrrigationAnalytics_SeasonAlignment(t *testing.T) {
+	// 29 February 2024 is the 152nd day of the crop year starting 1 October 2023, and 1 March 2023
+	// that of the crop year before. Calendar alignment would move both ends of the day onto
+	// 1 March, leaving an empty period.
+	day := time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)
+	repo := &stubRepository{
+		comparison: map[int][]repository.AggregatedDataWithCount{
+			0: {aggregatedPoint(day, 1, 100, 100, 1)},
+			1: {aggregatedPoint(time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC), 1, 50, 50, 1)},
+			2: {},
+		},
+		cropYear: model.CropYear{Month: time.October, Day: 1},
+	}
+	svc := NewAnalyticsService(repo, nil, nil, nil)
+
+	response, err := svc.GetIrrigationAnalytics(1, nil, day, day.AddDate(0, 0, 1), "daily", AnalyticsOptions{YearAlignment: YearAlignmentSeason})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.opts.CropYear == nil || response.CropYearStart != "10-01" {
+		t.Fatalf("expected the farm's crop year in the query options and response, got %+v and %q", repo.opts.CropYear, response.CropYearStart)
+	}
+
+	oneYear := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	if period := response.YearOverYear.OneYearAgo.Period; !period.StartDate.Equal(oneYear) || !period.EndDate.Equal(oneYear.AddDate(0, 0, 1)) {
+		t.Errorf("expected the legacy YoY period to cover 1 March 2023, got %+v", period)
+	}
+	if block := response.Comparisons[0]; block.CompareTo != "-1y" || !block.Period.StartDate.Equal(oneYear) || block.VolumeChangePercent != 100 {
+		t.Errorf("expected the -1y block season-aligned, got %+v", block)
+	}
+	if period := response.Comparisons[1].Period; !period.EndDate.Equal(time.Date(2022, 3, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the -2y block to cover 1 March 2022, got %+v", period)
+	}
+}
+
+func TestCropYearShiftYears(t *testing.T) {
+	season := model.CropYear{Month: time.October, Day: 1}
+	tests := []struct {
+		name       string
+		cropYear   model.CropYear
+		start, end time.Time
+		years      int
+		wantStart  time.Time
+	}{
+		// February 2024 has 29 days; calendar alignment would compare it with 28 days of 2023
+		{"leap month keeps its length", model.CropYear{}, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), 1, time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)},
+		// No February lies between the season start and 2 January, so the calendar date is kept
+		{"season spanning new year", season, time.Date(2025, 1, 2, 6, 0, 0, 0, time.UTC), time.Date(2025, 1, 9, 6, 0, 0, 0, time.UTC), 1, time.Date(2024, 1, 2, 6, 0, 0, 0, time.UTC)},
+		// 1 March 2025 is the 152nd day of its season, as 29 February 2024 is of the season before
+		{"after a leap day", season, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 8, 0, 0, 0, 0, time.UTC), 1, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"before the season start", season, time.Date(2025, 9, 30, 0, 0, 0, 0, time.UTC), time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC), 2, time.Date(2023, 9, 30, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		start, end := tt.cropYear.ShiftYears(tt.start, tt.end, tt.years)
+		if !start.Equal(tt.wantStart) || end.Sub(start) != tt.end.Sub(tt.start) {
+			t.Errorf("%s: expected %v lasting %v, got %v to %v", tt.name, tt.wantStart, tt.end.Sub(tt.start), start, end)
+		}
+	}
+
+	if _, err := model.ParseCropYear("02-29"); err == nil {
+		t.Error("expected 02-29 rejected")
+	}
+	if cropYear, err := model.ParseCropYear("10-01"); err != nil || cropYear != season {
+		t.Errorf("expected 10-01 parsed, got %+v (%v)", cropYear, err)
+	}
+}
+
+func TestComparisonOffset(t *testing.T) {
+	start := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 7)
+
+	tests := []struct {
+		offset    string
+		start     time.Time
+		yearsBack int
+	}{
+		{ComparePreviousPeriod, start.AddDate(0, 0, -7), 0},
+		{"-7d", start.AddDate(0, 0, -7), 0},
+		{"-2w", start.AddDate(0, 0, -14), 0},
+		{"-1m", start.AddDate(0, -1, 0), 0},
+		{"-10y", start.AddDate(-10, 0, 0), 10},
+	}
+	for _, tt := range tests {
+		comparison, err := ComparisonOffset(tt.offset, start, end)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.offset, err)
+			continue
+		}
+		if !comparison.StartDate.Equal(tt.start) || comparison.YearsBack != tt.yearsBack || comparison.Label != tt.offset {
+			t.Errorf("%s: unexpected comparison %+v", tt.offset, comparison)
+		}
+	}
+
+	for _, offset := range []string{"", "1y", "-y", "-0d", "-3q", "-121m", "previous"} {
+		if _, err := ComparisonOffset(offset, start, end); err == nil {
+			t.Errorf("%q: expected an error", offset)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"irrigation-analytics/internal/repository"
+)
+
+// Contributor dimensions
+const (
+	ContributorDimensionSector = "sector"
+	ContributorDimensionDay    = "day"
+)
+
+// Baselines a period can be compared against
+const (
+	// BaselinePreviousYear compares against the same dates one year earlier
+	BaselinePreviousYear = "previous_year"
+	// BaselinePreviousPeriod compares against the equal-length range ending at start_date
+	BaselinePreviousPeriod = "previous_period"
+)
+
+// ContributorsResponse attributes a period-over-period volume change to its largest contributors
+type ContributorsResponse struct {
+	FarmID              uint          `json:"farm_id"`
+	Dimension           string        `json:"dimension"`
+	Baseline            string        `json:"baseline"`
+	Period              PeriodInfo    `json:"period"`
+	BaselinePeriod      PeriodInfo    `json:"baseline_period"`
+	CurrentVolume       float64       `json:"current_volume"`
+	BaselineVolume      float64       `json:"baseline_volume"`
+	VolumeChange        float64       `json:"volume_change"`
+	VolumeChangePercent float64       `json:"volume_change_percent"`
+	Contributors        []Contributor `json:"contributors"`
+}
+
+// Contributor is one sector's or day's share of the total volume change.
+// ShareOfChangePercent is positive when the contributor moved in the same direction as the total
+// and negative when it offset it; shares across all contributors sum to 100.
+type Contributor struct {
+	SectorID             *uint      `json:"sector_id,omitempty"`
+	Date                 *time.Time `json:"date,omitempty"`
+	CurrentVolume        float64    `json:"current_volume"`
+	BaselineVolume       float64    `json:"baseline_volume"`
+	VolumeChange         float64    `json:"volume_change"`
+	ShareOfChangePercent float64    `json:"share_of_change_percent"`
+}
+
+// GetTopContributors decomposes the volume change between the period and its baseline into
+// per-sector or per-day contributions and returns the largest ones.
+// Days are aligned by their offset from the start of each range.
+func (s *analyticsService) GetTopContributors(farmID uint, startDate, endDate time.Time, dimension, baseline string, limit int) (*ContributorsResponse, error) {
+	baseStart, baseEnd := baselinePeriod(startDate, endDate, baseline)
+	opts, err := withAttribution(s.repo, farmID, repository.QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var current, previous map[int]float64
+	switch dimension {
+	case ContributorDimensionSector:
+		if current, err = s.sectorVolumes(farmID, startDate, endDate, opts); err != nil {
+			return nil, err
+		}
+		if previous, err = s.sectorVolumes(farmID, baseStart, baseEnd, opts); err != nil {
+			return nil, err
+		}
+	case ContributorDimensionDay:
+		plan := queryPlan{queries: 2, bucketCost: 2 * bucketCount(startDate, endDate, "daily")}
+		if err = s.budget.check(plan); err != nil {
+			return nil, err
+		}
+		if current, err = s.dayVolumes(farmID, startDate, endDate, opts); err != nil {
+			return nil, err
+		}
+		if previous, err = s.dayVolumes(farmID, baseStart, baseEnd, opts); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported contributor dimension: %s", dimension)
+	}
+
+	contributions, currentTotal, baselineTotal := decomposeVolumeChange(current, previous)
+
+	contributors := make([]Contributor, 0, len(contributions))
+	for _, c := range contributions {
+		contributor := Contributor{
+			CurrentVolume:        c.current,
+			BaselineVolume:       c.baseline,
+			VolumeChange:         c.change,
+			ShareOfChangePercent: c.share,
+		}
+		if dimension == ContributorDimensionSector {
+			sectorID := uint(c.key)
+			contributor.SectorID = &sectorID
+		} else {
+			day := startDate.Truncate(24*time.Hour).AddDate(0, 0, c.key)
+			contributor.Date = &day
+		}
+		contributors = append(contributors, contributor)
+	}
+
+	if limit > 0 && len(contributors) > limit {
+		contributors = contributors[:limit]
+	}
+
+	return &ContributorsResponse{
+		FarmID:              farmID,
+		Dimension:           dimension,
+		Baseline:            baseline,
+		Period:              PeriodInfo{StartDate: startDate, EndDate: endDate},
+		BaselinePeriod:      PeriodInfo{StartDate: baseStart, EndDate: baseEnd},
+		CurrentVolume:       currentTotal,
+		BaselineVolume:      baselineTotal,
+		VolumeChange:        math.Round((currentTotal-baselineTotal)*100) / 100,
+		VolumeChangePercent: s.calculateChangePercent(currentTotal, baselineTotal),
+		Contributors:        contributors,
+	}, nil
+}
+
+// baselinePeriod returns the date range a period is compared against
+func baselinePeriod(startDate, endDate time.Time, baseline string) (time.Time, time.Time) {
+	if baseline == BaselinePreviousPeriod {
+		length := endDate.Sub(startDate)
+		return startDate.Add(-length), startDate
+	}
+	return startDate.AddDate(-1, 0, 0), endDate.AddDate(-1, 0, 0)
+}
+
+// sectorVolumes returns total water volume per sector ID for a date range
+func (s *analyticsService) sectorVolumes(farmID uint, startDate, endDate time.Time, opts repository.QueryOptions) (map[int]float64, error) {
+	data, err := s.repo.GetSectorComparisonData(farmID, startDate, endDate, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	volumes := make(map[int]float64)
+	for _, item := range data[0] {
+		volumes[int(item.Data.IrrigationSectorID)] += item.Data.WaterVolume
+	}
+	return volumes, nil
+}
+
+// dayVolumes returns total water volume per day, keyed by day offset from startDate
+func (s *analyticsService) dayVolumes(farmID uint, startDate, endDate time.Time, opts repository.QueryOptions) (map[int]float64, error) {
+	data, err := s.repo.GetAggregatedData(farmID, nil, startDate, endDate, "daily", opts)
+	if err != nil {
+		return nil, err
+	}
+
+	origin := startDate.Truncate(24 * time.Hour)
+	volumes := make(map[int]float64)
+	for _, item := range data {
+		offset := int(item.Data.StartTime.Sub(origin).Hours() / 24)
+		volumes[offset] += item.Data.WaterVolume
+	}
+	return volumes, nil
+}
+
+// contribution is one key's part of a total change
+type contribution struct {
+	key      int
+	current  float64
+	baseline float64
+	change   float64
+	share    float64
+}
+
+// decomposeVolumeChange splits the total change into additive per-key contributions.
+// Each key's share is its change divided by the total change, so shares sum to 100%.
+// Contributions are ordered by share, largest driver of the total change first; when the total
+// did not change, they are ordered by the size of their own change.
+func decomposeVolumeChange(current, baseline map[int]float64) ([]contribution, float64, float64) {
+	keys := make(map[int]struct{}, len(current)+len(baseline))
+	var currentTotal, baselineTotal float64
+	for k, v := range current {
+		keys[k] = struct{}{}
+		currentTotal += v
+	}
+	for k, v := range baseline {
+		keys[k] = struct{}{}
+		baselineTotal += v
+	}
+	totalChange := currentTotal - baselineTotal
+
+	contributions := make([]contribution, 0, len(keys))
+	for k := range keys {
+		c := contribution{
+			key:      k,
+			current:  math.Round(current[k]*100) / 100,
+			baseline: math.Round(baseline[k]*100) / 100,
+		}
+		change := current[k] - baseline[k]
+		c.change = math.Round(change*100) / 100
+		if totalChange != 0 {
+			c.share = math.Round(change/totalChange*10000) / 100
+		}
+		contributions = append(contributions, c)
+	}
+
+	sort.Slice(contributions, func(i, j int) bool {
+		a, b := contributions[i], contributions[j]
+		if totalChange == 0 {
+			if math.Abs(a.change) != math.Abs(b.change) {
+				return math.Abs(a.change) > math.Abs(b.change)
+			}
+		} else if a.share != b.share {
+			return a.share > b.share
+		}
+		return a.key < b.key
+	})
+
+	return contributions, math.Round(currentTotal*100) / 100, math.Round(baselineTotal*100) / 100
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecomposeVolumeChange(t *testing.T) {
+	// Total change: (1000 + 300 + 200) - (500 + 400 + 200) = +400
+	current := map[int]float64{1: 1000, 2: 300, 4: 200}
+	baseline := map[int]float64{1: 500, 2: 400, 3: 200}
+
+	contributions, currentTotal, baselineTotal := decomposeVolumeChange(current, baseline)
+
+	if currentTotal != 1500 || baselineTotal != 1100 {
+		t.Fatalf("expected totals 1500/1100, got %f/%f", currentTotal, baselineTotal)
+	}
+
+	expected := []struct {
+		key   int
+		share float64
+	}{
+		{1, 125}, // +500 of +400
+		{4, 50},  // +200, new sector
+		{2, -25}, // -100, offsets the increase
+		{3, -50}, // -200, sector stopped irrigating
+	}
+
+	if len(contributions) != len(expected) {
+		t.Fatalf("expected %d contributions, got %d", len(expected), len(contributions))
+	}
+
+	var totalShare float64
+	for i, e := range expected {
+		if contributions[i].key != e.key || contributions[i].share != e.share {
+			t.Errorf("position %d: expected key %d with share %.2f, got key %d with share %.2f",
+				i, e.key, e.share, contributions[i].key, contributions[i].share)
+		}
+		totalShare += contributions[i].share
+	}
+	if totalShare != 100 {
+		t.Errorf("expected shares to sum to 100, got %f", totalShare)
+	}
+}
+
+func TestDecomposeVolumeChange_NoTotalChange(t *testing.T) {
+	contributions, _, _ := decomposeVolumeChange(
+		map[int]float64{1: 100, 2: 300},
+		map[int]float64{1: 300, 2: 100},
+	)
+
+	for _, c := range contributions {
+		if c.share != 0 {
+			t.Errorf("expected zero shares when total is unchanged, got %f for key %d", c.share, c.key)
+		}
+	}
+	if contributions[0].key != 1 {
+		t.Errorf("expected ties on magnitude to be ordered by key, got %d first", contributions[0].key)
+	}
+}
+
+func TestBaselinePeriod(t *testing.T) {
+	start := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	baseStart, baseEnd := baselinePeriod(start, end, BaselinePreviousYear)
+	if !baseStart.Equal(start.AddDate(-1, 0, 0)) || !baseEnd.Equal(end.AddDate(-1, 0, 0)) {
+		t.Errorf("previous_year: got %v - %v", baseStart, baseEnd)
+	}
+
+	baseStart, baseEnd = baselinePeriod(start, end, BaselinePreviousPeriod)
+	if !baseStart.Equal(time.Date(2025, 1, 30, 0, 0, 0, 0, time.UTC)) || !baseEnd.Equal(start) {
+		t.Errorf("previous_period: got %v - %v", baseStart, baseEnd)
+	}
+}
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// Crop name languages
+const (
+	CropLanguageEnglish    = "en"
+	CropLanguageSpanish    = "es"
+	CropLanguagePortuguese = "pt"
+	CropLanguageFrench     = "fr"
+)
+
+// ErrUnknownCrop is returned for a crop that is not in the crop coefficient library
+var ErrUnknownCrop = errors.New("unknown crop")
+
+// CropCoefficientService defines the interface for browsing the crop coefficient library and
+// overriding its values per farm
+type CropCoefficientService interface {
+	FarmExists(farmID uint) (bool, error)
+	ListCrops(lang string) []CropCoefficients
+	ListFarmCrops(farmID uint, lang string) ([]CropCoefficients, error)
+	SetOverride(farmID uint, crop string, input CropOverrideInput) (*CropCoefficients, error)
+	DeleteOverride(farmID uint, crop string) (bool, error)
+	SetSectorCrop(farmID, sectorID uint, crop, stage string) error
+}
+
+// CropCoefficients are a crop's Kc values for the initial, mid-season and end of late season
+// stages, as in FAO-56 Table 12
+type CropCoefficients struct {
+	Crop  string  `json:"crop"`
+	Name  string  `json:"name"`
+	Group string  `json:"group"`
+	KcIni float64 `json:"kc_ini"`
+	KcMid float64 `json:"kc_mid"`
+	KcEnd float64 `json:"kc_end"`
+	// Overridden is set when the farm replaced any of the library's values
+	Overridden bool `json:"overridden"`
+}
+
+// CropOverrideInput replaces a crop's Kc values; nil values keep the library's
+type CropOverrideInput struct {
+	KcIni *float64
+	KcMid *float64
+	KcEnd *float64
+}
+
+// StageKc is the crop's Kc for a growth stage. The development and late stages, over which FAO-56
+// interpolates linearly, take the mean of the values at either end.
+func (c CropCoefficients) StageKc(stage string) float64 {
+	switch stage {
+	case model.CropStageInitial:
+		return c.KcIni
+	case model.CropStageDevelopment:
+		return math.Round((c.KcIni+c.KcMid)/2*1000) / 1000
+	case model.CropStageLate:
+		return math.Round((c.KcMid+c.KcEnd)/2*1000) / 1000
+	default:
+		return c.KcMid
+	}
+}
+
+// libraryCrop is one crop of the built-in library, with its names by language
+type libraryCrop struct {
+	key                 string
+	group               string
+	names               map[string]string
+	kcIni, kcMid, kcEnd float64
+}
+
+// cropLibrary holds FAO-56 Table 12 single crop coefficients for a sub-humid climate (RHmin
+// about 45%, wind about 2 m/s), ordered by key. Where the table gives a range, the middle is used.
+var cropLibrary = []libraryCrop{
+	{"alfalfa", "forage", map[string]string{"en": "Alfalfa (hay, averaged cuttings)", "es": "Alfalfa (heno, promedio de cortes)", "pt": "Alfafa (feno, média dos cortes)", "fr": "Luzerne (foin, moyenne des coupes)"}, 0.40, 0.95, 0.90},
+	{"almond", "fruit_trees", map[string]string{"en": "Almonds, no ground cover", "es": "Almendro, sin cubierta vegetal", "pt": "Amendoeira, sem cobertura do solo", "fr": "Amandier, sans couvert végétal"}, 0.40, 0.90, 0.65},
+	{"apple", "fruit_trees", map[string]string{"en": "Apples, no ground cover", "es": "Manzano, sin cubierta vegetal", "pt": "Macieira, sem cobertura do solo", "fr": "Pommier, sans couvert végétal"}, 0.45, 0.95, 0.70},
+	{"avocado", "fruit_trees", map[string]string{"en": "Avocado, no ground cover", "es": "Aguacate, sin cubierta vegetal", "pt": "Abacateiro, sem cobertura do solo", "fr": "Avocatier, sans couvert végétal"}, 0.60, 0.85, 0.75},
+	{"banana", "tropical_fruits", map[string]string{"en": "Banana, first year", "es": "Banano, primer año", "pt": "Bananeira, primeiro ano", "fr": "Bananier, première année"}, 0.50, 1.10, 1.00},
+	{"barley", "cereals", map[string]string{"en": "Barley", "es": "Cebada", "pt": "Cevada", "fr": "Orge"}, 0.30, 1.15, 0.25},
+	{"carrot", "vegetables", map[string]string{"en": "Carrots", "es": "Zanahoria", "pt": "Cenoura", "fr": "Carotte"}, 0.70, 1.05, 0.95},
+	{"citrus", "fruit_trees", map[string]string{"en": "Citrus, 70% canopy, no ground cover", "es": "Cítricos, 70% de cobertura, sin cubierta vegetal", "pt": "Citros, 70% de copa, sem cobertura do solo", "fr": "Agrumes, 70% de couvert, sans couvert végétal"}, 0.70, 0.65, 0.70},
+	{"cotton", "fibre", map[string]string{"en": "Cotton", "es": "Algodón", "pt": "Algodão", "fr": "Coton"}, 0.35, 1.18, 0.60},
+	{"grape_wine", "vines", map[string]string{"en": "Grapes, wine", "es": "Vid, uva de vinificación", "pt": "Videira, uva para vinho", "fr": "Vigne, raisin de cuve"}, 0.30, 0.70, 0.45},
+	{"lettuce", "vegetables", map[string]string{"en": "Lettuce", "es": "Lechuga", "pt": "Alface", "fr": "Laitue"}, 0.70, 1.00, 0.95},
+	{"maize", "cereals", map[string]string{"en": "Maize, grain", "es": "Maíz, grano", "pt": "Milho, grão", "fr": "Maïs, grain"}, 0.30, 1.20, 0.48},
+	{"melon", "vegetables", map[string]string{"en": "Melons", "es": "Melón", "pt": "Melão", "fr": "Melon"}, 0.50, 0.85, 0.60},
+	{"olive", "fruit_trees", map[string]string{"en": "Olives, 40-60% ground coverage", "es": "Olivo, 40-60% de cobertura", "pt": "Oliveira, 40-60% de cobertura", "fr": "Olivier, 40-60% de couvert"}, 0.65, 0.70, 0.70},
+	{"onion", "vegetables", map[string]string{"en": "Onions, dry", "es": "Cebolla, seca", "pt": "Cebola, seca", "fr": "Oignon, sec"}, 0.70, 1.05, 0.75},
+	{"pepper", "vegetables", map[string]string{"en": "Peppers, sweet", "es": "Pimiento", "pt": "Pimentão", "fr": "Poivron"}, 0.60, 1.05, 0.90},
+	{"potato", "roots_tubers", map[string]string{"en": "Potato", "es": "Patata", "pt": "Batata", "fr": "Pomme de terre"}, 0.50, 1.15, 0.75},
+	{"rice", "cereals", map[string]string{"en": "Rice", "es": "Arroz", "pt": "Arroz", "fr": "Riz"}, 1.05, 1.20, 0.75},
+	{"soybean", "legumes", map[string]string{"en": "Soybeans", "es": "Soja", "pt": "Soja", "fr": "Soja"}, 0.40, 1.15, 0.50},
+	{"strawberry", "vegetables", map[string]string{"en": "Strawberries", "es": "Fresa", "pt": "Morango", "fr": "Fraise"}, 0.40, 0.85, 0.75},
+	{"sugar_beet", "roots_tubers", map[string]string{"en": "Sugar beet", "es": "Remolacha azucarera", "pt": "Beterraba sacarina", "fr": "Betterave sucrière"}, 0.35, 1.20, 0.70},
+	{"sugarcane", "sugar_cane", map[string]string{"en": "Sugar cane", "es": "Caña de azúcar", "pt": "Cana-de-açúcar", "fr": "Canne à sucre"}, 0.40, 1.25, 0.75},
+	{"sunflower", "oil_crops", map[string]string{"en": "Sunflower", "es": "Girasol", "pt": "Girassol", "fr": "Tournesol"}, 0.35, 1.08, 0.35},
+	{"tomato", "vegetables", map[string]string{"en": "Tomato", "es": "Tomate", "pt": "Tomate", "fr": "Tomate"}, 0.60, 1.15, 0.80},
+	{"wheat_spring", "cereals", map[string]string{"en": "Wheat, spring", "es": "Trigo de primavera", "pt": "Trigo de primavera", "fr": "Blé de printemps"}, 0.30, 1.15, 0.33},
+	{"wheat_winter", "cereals", map[string]string{"en": "Wheat, winter", "es": "Trigo de invierno", "pt": "Trigo de inverno", "fr": "Blé d'hiver"}, 0.70, 1.15, 0.33},
+}
+
+// IsValidCropLanguage reports whether lang is a language the library names crops in
+func IsValidCropLanguage(lang string) bool {
+	switch lang {
+	case CropLanguageEnglish, CropLanguageSpanish, CropLanguagePortuguese, CropLanguageFrench:
+		return true
+	}
+	return false
+}
+
+// IsKnownCrop reports whether crop is a key of the crop coefficient library
+func IsKnownCrop(crop string) bool {
+	_, ok := findLibraryCrop(crop)
+	return ok
+}
+
+// findLibraryCrop looks crop up by key
+func findLibraryCrop(crop string) (libraryCrop, bool) {
+	i := sort.Search(len(cropLibrary), func(i int) bool { return cropLibrary[i].key >= crop })
+	if i < len(cropLibrary) && cropLibrary[i].key == crop {
+		return cropLibrary[i], true
+	}
+	return libraryCrop{}, false
+}
+
+// coefficients returns the crop's library values, named in lang
+func (c libraryCrop) coefficients(lang string) CropCoefficients {
+	return CropCoefficients{Crop: c.key, Name: c.names[lang], Group: c.group, KcIni: c.kcIni, KcMid: c.kcMid, KcEnd: c.kcEnd}
+}
+
+// applyOverride replaces the values the override sets
+func (c *CropCoefficients) applyOverride(override model.CropCoefficientOverride) {
+	if override.KcIni != nil {
+		c.KcIni = *override.KcIni
+	}
+	if override.KcMid != nil {
+		c.KcMid = *override.KcMid
+	}
+	if override.KcEnd != nil {
+		c.KcEnd = *override.KcEnd
+	}
+	c.Overridden = override.KcIni != nil || override.KcMid != nil || override.KcEnd != nil
+}
+
+// farmCropCoefficients returns the library with the farm's overrides applied, by crop key.
+// crops may be nil, in which case the library's values are used as they are.
+func farmCropCoefficients(crops repository.CropCoefficientRepository, farmID uint, lang string) (map[string]CropCoefficients, error) {
+	coefficients := make(map[string]CropCoefficients, len(cropLibrary))
+	for _, crop := range cropLibrary {
+		coefficients[crop.key] = crop.coefficients(lang)
+	}
+	if crops == nil {
+		return coefficients, nil
+	}
+	overrides, err := crops.ListOverrides(farmID)
+	if err != nil {
+		return nil, err
+	}
+	for _, override := range overrides {
+		if c, ok := coefficients[override.Crop]; ok {
+			c.applyOverride(override)
+			coefficients[override.Crop] = c
+		}
+	}
+	return coefficients, nil
+}
+
+// cropCoefficientService implements CropCoefficientService
+type cropCoefficientService struct {
+	repo  repository.IrrigationRepository
+	crops repository.CropCoefficientRepository
+}
+
+// NewCropCoefficientService creates a new crop coefficient service
+func NewCropCoefficientService(repo repository.IrrigationRepository, crops repository.CropCoefficientRepository) CropCoefficientService {
+	return &cropCoefficientService{repo: repo, crops: crops}
+}
+
+// FarmExists checks if a farm exists
+func (s *cropCoefficientService) FarmExists(farmID uint) (bool, error) {
+	return s.repo.FarmExists(farmID)
+}
+
+// ListCrops returns the built-in library, named in lang
+func (s *cropCoefficientService) ListCrops(lang string) []CropCoefficients {
+	crops := make([]CropCoefficients, 0, len(cropLibrary))
+	for _, crop := range cropLibrary {
+		crops = append(crops, crop.coefficients(lang))
+	}
+	return crops
+}
+
+// ListFarmCrops returns the library as the farm sees it, with its overrides applied
+func (s *cropCoefficientService) ListFarmCrops(farmID uint, lang string) ([]CropCoefficients, error) {
+	coefficients, err := farmCropCoefficients(s.crops, farmID, lang)
+	if err != nil {
+		return nil, err
+	}
+	crops := make([]CropCoefficients, 0, len(cropLibrary))
+	for _, crop := range cropLibrary {
+		crops = append(crops, coefficients[crop.key])
+	}
+	return crops, nil
+}
+
+// SetOverride replaces the farm's override of a library crop, returning the crop's values with
+// it applied, in English
+func (s *cropCoefficientService) SetOverride(farmID uint, crop string, input CropOverrideInput) (*CropCoefficients, error) {
+	libraryEntry, ok := findLibraryCrop(crop)
+	if !ok {
+		return nil, ErrUnknownCrop
+	}
+	override := model.CropCoefficientOverride{FarmID: farmID, Crop: crop, KcIni: input.KcIni, KcMid: input.KcMid, KcEnd: input.KcEnd}
+	if err := s.crops.SaveOverride(&override); err != nil {
+		return nil, err
+	}
+	coefficients := libraryEntry.coefficients(CropLanguageEnglish)
+	coefficients.applyOverride(override)
+	return &coefficients, nil
+}
+
+// DeleteOverride removes the farm's override of a crop, reporting whether it had one
+func (s *cropCoefficientService) DeleteOverride(farmID uint, crop string) (bool, error) {
+	return s.crops.DeleteOverride(farmID, crop)
+}
+
+// SetSectorCrop sets the crop and growth stage a sector is growing, or clears them when crop is
+// empty. It returns ErrUnknownCrop for a crop not in the library and ErrSectorNotFound when the
+// farm doesn't have the sector.
+func (s *cropCoefficientService) SetSectorCrop(farmID, sectorID uint, crop, stage string) error {
+	if crop != "" && !IsKnownCrop(crop) {
+		return ErrUnknownCrop
+	}
+	found, err := s.crops.SetSectorCrop(farmID, sectorID, crop, stage)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrSectorNotFound
+	}
+	return nil
+}
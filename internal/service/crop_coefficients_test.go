@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// stubCropRepository serves fixed overrides and records sector crop updates
+type stubCropRepository struct {
+	overrides []model.CropCoefficientOverride
+	saved     *model.CropCoefficientOverride
+	sectors   map[uint]string
+}
+
+func (r *stubCropRepository) ListOverrides(farmID uint) ([]model.CropCoefficientOverride, error) {
+	return r.overrides, nil
+}
+
+func (r *stubCropRepository) SaveOverride(override *model.CropCoefficientOverride) error {
+	r.saved = override
+	return nil
+}
+
+func (r *stubCropRepository) DeleteOverride(farmID uint, crop string) (bool, error) {
+	return false, nil
+}
+
+func (r *stubCropRepository) SetSectorCrop(farmID, sectorID uint, crop, stage string) (bool, error) {
+	if _, ok := r.sectors[sectorID]; !ok {
+		return false, nil
+	}
+	r.sectors[sectorID] = crop + "/" + stage
+	return true, nil
+}
+
+func (r *stubCropRepository) WithContext(ctx context.Context) repository.CropCoefficientRepository {
+	return r
+}
+
+// TestCropLibrary verifies the library is sorted for lookups and names every crop in every language
+func TestCropLibrary(t *testing.T) {
+	if !sort.SliceIsSorted(cropLibrary, func(i, j int) bool { return cropLibrary[i].key < cropLibrary[j].key }) {
+		t.Fatal("expected the crop library to be ordered by key")
+	}
+	for _, crop := range cropLibrary {
+		for _, lang := range []string{CropLanguageEnglish, CropLanguageSpanish, CropLanguagePortuguese, CropLanguageFrench} {
+			if crop.names[lang] == "" {
+				t.Errorf("crop %s has no %s name", crop.key, lang)
+			}
+		}
+		if !IsKnownCrop(crop.key) {
+			t.Errorf("expected %s to be found", crop.key)
+		}
+	}
+	if IsKnownCrop("kale") {
+		t.Error("expected a crop outside the library to be unknown")
+	}
+}
+
+func TestCropCoefficients_StageKc(t *testing.T) {
+	maize, _ := findLibraryCrop("maize")
+	c := maize.coefficients(CropLanguageEnglish)
+	tests := map[string]float64{
+		model.CropStageInitial:     0.3,
+		model.CropStageDevelopment: 0.75,
+		model.CropStageMid:         1.2,
+		model.CropStageLate:        0.84,
+	}
+	for stage, want := range tests {
+		if got := c.StageKc(stage); got != want {
+			t.Errorf("StageKc(%s) = %v, want %v", stage, got, want)
+		}
+	}
+}
+
+func TestListFarmCrops_AppliesOverrides(t *testing.T) {
+	crops := &stubCropRepository{overrides: []model.CropCoefficientOverride{
+		{FarmID: 1, Crop: "olive", KcEnd: weatherFloat(0.55)},
+		{FarmID: 1, Crop: "retired_crop", KcMid: weatherFloat(1)},
+	}}
+	svc := NewCropCoefficientService(&stubRepository{}, crops)
+
+	list, err := svc.ListFarmCrops(1, CropLanguageSpanish)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list) != len(cropLibrary) {
+		t.Fatalf("expected every library crop once, got %d", len(list))
+	}
+	for _, crop := range list {
+		switch crop.Crop {
+		case "olive":
+			if crop.Name != "Olivo, 40-60% de cobertura" || crop.KcIni != 0.65 || crop.KcEnd != 0.55 || !crop.Overridden {
+				t.Errorf("expected olive named in Spanish with only kc_end overridden, got %+v", crop)
+			}
+		case "tomato":
+			if crop.Overridden {
+				t.Errorf("expected tomato to keep the library's values, got %+v", crop)
+			}
+		}
+	}
+}
+
+func TestSetOverrideAndSectorCrop(t *testing.T) {
+	crops := &stubCropRepository{sectors: map[uint]string{4: ""}}
+	svc := NewCropCoefficientService(&stubRepository{}, crops)
+
+	if _, err := svc.SetOverride(1, "kale", CropOverrideInput{KcMid: weatherFloat(1)}); !errors.Is(err, ErrUnknownCrop) {
+		t.Errorf("expected ErrUnknownCrop, got %v", err)
+	}
+	coefficients, err := svc.SetOverride(1, "potato", CropOverrideInput{KcMid: weatherFloat(1.1)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if coefficients.KcMid != 1.1 || coefficients.KcIni != 0.5 || crops.saved == nil || crops.saved.FarmID != 1 {
+		t.Errorf("expected the override saved and applied, got %+v", coefficients)
+	}
+
+	if err := svc.SetSectorCrop(1, 4, "potato", model.CropStageLate); err != nil || crops.sectors[4] != "potato/late" {
+		t.Errorf("expected sector 4 to grow potatoes, got %q (%v)", crops.sectors[4], err)
+	}
+	if err := svc.SetSectorCrop(1, 5, "potato", model.CropStageLate); !errors.Is(err, ErrSectorNotFound) {
+		t.Errorf("expected ErrSectorNotFound, got %v", err)
+	}
+	if err := svc.SetSectorCrop(1, 4, "kale", model.CropStageLate); !errors.Is(err, ErrUnknownCrop) {
+		t.Errorf("expected ErrUnknownCrop, got %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// Dead letter listing limits
+const (
+	DefaultDeadLetterLimit = 100
+	MaxDeadLetterLimit     = 1000
+)
+
+var (
+	// ErrDeadLetterNotFound is returned when the farm has no dead letter with the ID
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+	// ErrDeadLetterResolved is returned when replaying or discarding a letter that was already
+	// replayed or discarded
+	ErrDeadLetterResolved = errors.New("dead letter already resolved")
+	// ErrReplayRejected is returned, wrapping the reason, when a replayed message is still invalid
+	ErrReplayRejected = errors.New("replay rejected")
+)
+
+// DeadLetterService defines the interface for inspecting, replaying and discarding rejected
+// ingestion messages
+type DeadLetterService interface {
+	FarmExists(farmID uint) (bool, error)
+	ListDeadLetters(farmID uint, status string, limit int) ([]model.DeadLetter, error)
+	ReplayDeadLetter(farmID, letterID uint, payload json.RawMessage) (*model.DeadLetter, error)
+	DiscardDeadLetter(farmID, letterID uint) (*model.DeadLetter, error)
+}
+
+// deadLetterService implements DeadLetterService
+type deadLetterService struct {
+	repo         repository.IrrigationRepository
+	deadLetters  repository.DeadLetterRepository
+	calibrations repository.CalibrationRepository
+	devices      repository.DeviceRepository
+	now          func() time.Time
+}
+
+// NewDeadLetterService creates a new dead letter service; replayed events are calibrated like
+// imported ones when calibrations is set, and their device_id is checked against devices
+func NewDeadLetterService(repo repository.IrrigationRepository, deadLetters repository.DeadLetterRepository, calibrations repository.CalibrationRepository, devices repository.DeviceRepository) DeadLetterService {
+	return &deadLetterService{repo: repo, deadLetters: deadLetters, calibrations: calibrations, devices: devices, now: time.Now}
+}
+
+// FarmExists checks if a farm exists
+func (s *deadLetterService) FarmExists(farmID uint) (bool, error) {
+	return s.repo.FarmExists(farmID)
+}
+
+// ListDeadLetters returns up to limit of the farm's dead letters with the status, oldest first
+func (s *deadLetterService) ListDeadLetters(farmID uint, status string, limit int) ([]model.DeadLetter, error) {
+	return s.deadLetters.List(farmID, status, limit)
+}
+
+// ReplayDeadLetter parses a pending letter's payload again, replaced by payload when set, and
+// stores the event it describes. A payload that still fails is kept on the letter with the new
+// error and returned as ErrReplayRejected, so it can be fixed again.
+func (s *deadLetterService) ReplayDeadLetter(farmID, letterID uint, payload json.RawMessage) (*model.DeadLetter, error) {
+	letter, err := s.pendingLetter(farmID, letterID)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) > 0 {
+		letter.Payload = model.RawPayload(payload)
+	}
+	letter.Attempts++
+
+	event, reason, err := s.rebuildEvent(farmID, letter)
+	if err != nil {
+		return nil, err
+	}
+	if reason != "" {
+		letter.Error = reason
+		if err := s.deadLetters.Update(letter); err != nil {
+			return nil, err
+		}
+		return letter, fmt.Errorf("%w: %s", ErrReplayRejected, reason)
+	}
+
+	err = s.deadLetters.Replay(letter, event)
+	if errors.Is(err, repository.ErrDeadLetterResolved) {
+		return nil, ErrDeadLetterResolved
+	}
+	if err != nil {
+		return nil, err
+	}
+	return letter, nil
+}
+
+// DiscardDeadLetter marks a pending letter discarded; it stays listed under that status
+func (s *deadLetterService) DiscardDeadLetter(farmID, letterID uint) (*model.DeadLetter, error) {
+	letter, err := s.pendingLetter(farmID, letterID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	letter.Status, letter.ResolvedAt = model.DeadLetterDiscarded, &now
+	if err := s.deadLetters.Update(letter); err != nil {
+		return nil, err
+	}
+	return letter, nil
+}
+
+// pendingLetter fetches a letter that can still be replayed or discarded
+func (s *deadLetterService) pendingLetter(farmID, letterID uint) (*model.DeadLetter, error) {
+	letter, err := s.deadLetters.Get(farmID, letterID)
+	if err != nil {
+		return nil, err
+	}
+	if letter == nil {
+		return nil, ErrDeadLetterNotFound
+	}
+	if letter.Status != model.DeadLetterPending {
+		return nil, ErrDeadLetterResolved
+	}
+	return letter, nil
+}
+
+// rebuildEvent parses and validates the letter's payload as an import row, returning the
+// calibrated event, or why the row is still rejected
+func (s *deadLetterService) rebuildEvent(farmID uint, letter *model.DeadLetter) (*model.IrrigationData, string, error) {
+	row, err := ReparsePayload(letter.PayloadFormat, letter.Payload)
+	if err != nil {
+		return nil, err.Error(), nil
+	}
+
+	sectors, err := s.repo.ListSectors(farmID)
+	if err != nil {
+		return nil, "", err
+	}
+	sectorIDs := make(map[uint]bool, len(sectors))
+	for _, sector := range sectors {
+		sectorIDs[sector.ID] = true
+	}
+	deviceSectors, err := loadDeviceSectors(s.devices, farmID)
+	if err != nil {
+		return nil, "", err
+	}
+	if reason := validateImportRow(row, sectorIDs, deviceSectors); reason != "" {
+		return nil, reason, nil
+	}
+
+	cal, err := loadCalibrator(s.calibrations, farmID)
+	if err != nil {
+		return nil, "", err
+	}
+	event := row.toEvent(farmID)
+	cal.calibrateEvent(&event)
+	return &event, "", nil
+}
+
+// storeDeadLetters keeps rejected import rows for replay when a dead letter repository is set
+func (s *importService) storeDeadLetters(letters []model.DeadLetter) error {
+	if s.deadLetters == nil || len(letters) == 0 {
+		return nil
+	}
+	return s.deadLetters.Create(letters)
+}
+
+// rejectedDeadLetters builds the dead letters of rows rejected by validation or insertion,
+// identified in rejections by row index
+func rejectedDeadLetters(farmID, importID uint, rows []ImportRow, rejections []RowRejection) []model.DeadLetter {
+	letters := make([]model.DeadLetter, 0, len(rejections))
+	for _, rejection := range rejections {
+		row := rows[rejection.Row]
+		payload, format := row.payload, row.payloadFormat
+		if len(payload) == 0 {
+			// Rows built in code rather than decoded keep their fields instead
+			payload, _ = json.Marshal(row)
+			format = ImportFormatJSON
+		}
+		position := rejection.Row
+		if row.line > 0 {
+			position = row.line
+		}
+		id := importID
+		letters = append(letters, model.DeadLetter{
+			FarmID:        farmID,
+			Source:        model.DeadLetterSourceImport,
+			ImportJobID:   &id,
+			Row:           position,
+			Payload:       model.RawPayload(payload),
+			PayloadFormat: format,
+			Error:         rejection.Reason,
+			Status:        model.DeadLetterPending,
+		})
+	}
+	return letters
+}
+
+// unparsedDeadLetters builds the dead letters of spreadsheet rows that failed to parse
+func unparsedDeadLetters(farmID uint, importID *uint, file *ImportFile) []model.DeadLetter {
+	letters := make([]model.DeadLetter, 0, len(file.rejected))
+	for i, rejection := range file.rejected {
+		letters = append(letters, model.DeadLetter{
+			FarmID:        farmID,
+			Source:        model.DeadLetterSourceImport,
+			ImportJobID:   importID,
+			Row:           rejection.Row,
+			Payload:       model.RawPayload(file.rejectedPayloads[i]),
+			PayloadFormat: file.Format,
+			Error:         rejection.Reason,
+			Status:        model.DeadLetterPending,
+		})
+	}
+	return letters
+}
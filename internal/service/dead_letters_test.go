@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// stubDeadLetterRepository keeps dead letters in memory
+type stubDeadLetterRepository struct {
+	letters []model.DeadLetter
+}
+
+func (r *stubDeadLetterRepository) Create(letters []model.DeadLetter) error {
+	for _, letter := range letters {
+		letter.ID = uint(len(r.letters) + 1)
+		r.letters = append(r.letters, letter)
+	}
+	return nil
+}
+
+func (r *stubDeadLetterRepository) List(farmID uint, status string, limit int) ([]model.DeadLetter, error) {
+	var letters []model.DeadLetter
+	for _, letter := range r.letters {
+		if letter.FarmID == farmID && letter.Status == status && len(letters) < limit {
+			letters = append(letters, letter)
+		}
+	}
+	return letters, nil
+}
+
+func (r *stubDeadLetterRepository) Get(farmID, letterID uint) (*model.DeadLetter, error) {
+	for _, letter := range r.letters {
+		if letter.FarmID == farmID && letter.ID == letterID {
+			return &letter, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *stubDeadLetterRepository) Update(letter *model.DeadLetter) error {
+	r.letters[letter.ID-1] = *letter
+	return nil
+}
+
+func (r *stubDeadLetterRepository) Replay(letter *model.DeadLetter, event *model.IrrigationData) error {
+	if r.letters[letter.ID-1].Status != model.DeadLetterPending {
+		return repository.ErrDeadLetterResolved
+	}
+	eventID := uint(42)
+	letter.Status, letter.EventID = model.DeadLetterReplayed, &eventID
+	r.letters[letter.ID-1] = *letter
+	return nil
+}
+
+func (r *stubDeadLetterRepository) WithContext(ctx context.Context) repository.DeadLetterRepository {
+	return r
+}
+
+// TestDeadLetters_ImportRejectionReplay verifies a rejected import row is kept as a dead letter
+// that fails replay until its payload is fixed, and can't be replayed twice
+func TestDeadLetters_ImportRejectionReplay(t *testing.T) {
+	deadLetters := &stubDeadLetterRepository{}
+	imports := NewImportService(&sectorRepository{}, &stubImportRepository{failAt: -1}, nil, deadLetters, nil)
+	rows := importRows(20)
+	rows[10].SectorID = 99
+
+	if _, err := imports.ImportEvents(1, nil, rows); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pending, _ := deadLetters.List(1, model.DeadLetterPending, DefaultDeadLetterLimit)
+	if len(pending) != 1 || pending[0].Row != 10 || *pending[0].ImportJobID != 1 || pending[0].PayloadFormat != ImportFormatJSON {
+		t.Fatalf("expected one dead letter for row 10, got %+v", pending)
+	}
+
+	svc := NewDeadLetterService(&sectorRepository{}, deadLetters, nil, nil)
+	letter, err := svc.ReplayDeadLetter(1, 1, nil)
+	if !errors.Is(err, ErrReplayRejected) || letter.Attempts != 1 || letter.Status != model.DeadLetterPending {
+		t.Fatalf("expected the unchanged payload to be rejected again, got %+v, %v", letter, err)
+	}
+
+	fixed := rows[10]
+	fixed.SectorID = 1
+	payload, _ := json.Marshal(fixed)
+	letter, err = svc.ReplayDeadLetter(1, 1, payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if letter.Status != model.DeadLetterReplayed || letter.Attempts != 2 || *letter.EventID != 42 {
+		t.Errorf("expected the fixed payload to be replayed, got %+v", letter)
+	}
+
+	if _, err := svc.ReplayDeadLetter(1, 1, payload); !errors.Is(err, ErrDeadLetterResolved) {
+		t.Errorf("expected ErrDeadLetterResolved replaying twice, got %v", err)
+	}
+	if _, err := svc.DiscardDeadLetter(1, 1); !errors.Is(err, ErrDeadLetterResolved) {
+		t.Errorf("expected ErrDeadLetterResolved discarding a replayed letter, got %v", err)
+	}
+	if _, err := svc.DiscardDeadLetter(2, 1); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Errorf("expected ErrDeadLetterNotFound for another farm, got %v", err)
+	}
+}
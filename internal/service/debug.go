@@ -0,0 +1,97 @@
+package service
+
+import (
+	"math"
+	"time"
+
+	"irrigation-analytics/internal/repository"
+)
+
+// DebugInfo describes how an analytics response was computed: the SQL executed with its row
+// counts and durations, and the time spent in each stage
+type DebugInfo struct {
+	TotalMs float64                    `json:"total_ms"`
+	Stages  []StageTiming              `json:"stages"`
+	Queries []repository.CapturedQuery `json:"queries"`
+}
+
+// StageTiming is the time spent in one stage of an analytics computation, including its queries
+type StageTiming struct {
+	Stage      string  `json:"stage"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// debugTrace records stage timings for a debug request. A nil trace ignores marks, so regular
+// requests pay nothing for the instrumentation.
+type debugTrace struct {
+	start   time.Time
+	last    time.Time
+	stages  []StageTiming
+	capture *repository.QueryCapture
+}
+
+// mark ends the current stage, attributing the time since the previous mark to name
+func (t *debugTrace) mark(name string) {
+	if t == nil {
+		return
+	}
+	now := time.Now()
+	t.stages = append(t.stages, StageTiming{Stage: name, DurationMs: roundMs(now.Sub(t.last))})
+	t.last = now
+}
+
+// record adds a stage that ran concurrently with others, with its own duration, and restarts the
+// current stage
+func (t *debugTrace) record(name string, d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.stages = append(t.stages, StageTiming{Stage: name, DurationMs: roundMs(d)})
+	t.last = time.Now()
+}
+
+// info returns the collected timings and queries
+func (t *debugTrace) info() *DebugInfo {
+	return &DebugInfo{
+		TotalMs: roundMs(time.Since(t.start)),
+		Stages:  t.stages,
+		Queries: t.capture.Queries(),
+	}
+}
+
+// withDebugTrace returns a copy of the service whose repositories record their statements
+// and whose stage marks are kept, together with the trace collecting both
+func (s *analyticsService) withDebugTrace() (*analyticsService, *debugTrace) {
+	now := time.Now()
+	trace := &debugTrace{start: now, last: now, capture: repository.NewQueryCapture()}
+
+	debug := *s
+	debug.trace = trace
+	debug.repo = s.repo.WithCapture(trace.capture)
+	if s.annotations != nil {
+		debug.annotations = s.annotations.WithCapture(trace.capture)
+	}
+	if s.pressure != nil {
+		debug.pressure = s.pressure.WithCapture(trace.capture)
+	}
+	if s.weather != nil {
+		debug.weather = s.weather.WithCapture(trace.capture)
+	}
+	return &debug, trace
+}
+
+// debugged runs compute against a traced copy of the service and attaches the trace to its response
+func (s *analyticsService) debugged(compute func(*analyticsService) (*AnalyticsResponse, error)) (*AnalyticsResponse, error) {
+	debug, trace := s.withDebugTrace()
+	response, err := compute(debug)
+	if err != nil {
+		return nil, err
+	}
+	response.Debug = trace.info()
+	return response, nil
+}
+
+// roundMs converts a duration to fractional milliseconds rounded to 2 decimal places
+func roundMs(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Millisecond)*100) / 100
+}
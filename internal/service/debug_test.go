@@ -0,0 +1,48 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/repository"
+)
+
+// TestGetIrrigationAnalytics_Debug verifies debug requests report their stages and
+// regular requests carry no debug section
+func TestGetIrrigationAnalytics_Debug(t *testing.T) {
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	repo := &stubRepository{
+		comparison: map[int][]repository.AggregatedDataWithCount{0: {aggregatedPoint(day, 1, 100, 100, 1)}},
+	}
+	svc := NewAnalyticsService(repo, nil, nil, nil)
+
+	response, err := svc.GetIrrigationAnalytics(1, nil, day, day.AddDate(0, 0, 1), "daily", AnalyticsOptions{Debug: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.Debug == nil {
+		t.Fatal("expected a debug section")
+	}
+
+	stages := make([]string, 0, len(response.Debug.Stages))
+	for _, stage := range response.Debug.Stages {
+		stages = append(stages, stage.Stage)
+	}
+	expected := []string{"comparison_query", "process_data_points", "annotations", "pressure", "sector_breakdown", "period_comparison", "year_over_year"}
+	if len(stages) != len(expected) {
+		t.Fatalf("expected stages %v, got %v", expected, stages)
+	}
+	for i := range expected {
+		if stages[i] != expected[i] {
+			t.Errorf("expected stage %d to be %s, got %s", i, expected[i], stages[i])
+		}
+	}
+
+	response, err = svc.GetIrrigationAnalytics(1, nil, day, day.AddDate(0, 0, 1), "daily", AnalyticsOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.Debug != nil {
+		t.Error("expected no debug section without the debug option")
+	}
+}
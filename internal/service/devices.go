@@ -0,0 +1,136 @@
+package service
+
+import (
+	"errors"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// ErrDeviceNotFound is returned when fetching or updating a device the farm doesn't have
+var ErrDeviceNotFound = errors.New("device not found")
+
+// DeviceService defines the interface for managing the farm's device registry
+type DeviceService interface {
+	FarmExists(farmID uint) (bool, error)
+	CreateDevice(farmID uint, input DeviceInput) (*model.Device, error)
+	ListDevices(farmID uint, sectorID *uint) ([]model.Device, error)
+	GetDevice(farmID, deviceID uint) (*model.Device, error)
+	UpdateDevice(farmID, deviceID uint, input DeviceInput) (*model.Device, error)
+	DeleteDevice(farmID, deviceID uint) (bool, error)
+}
+
+// DeviceInput describes a flow meter or valve and the sector it is installed on
+type DeviceInput struct {
+	SectorID     uint
+	Kind         string
+	Name         string
+	SerialNumber string
+	Note         string
+}
+
+// deviceService implements DeviceService
+type deviceService struct {
+	repo    repository.IrrigationRepository
+	devices repository.DeviceRepository
+}
+
+// NewDeviceService creates a new device service
+func NewDeviceService(repo repository.IrrigationRepository, devices repository.DeviceRepository) DeviceService {
+	return &deviceService{repo: repo, devices: devices}
+}
+
+// FarmExists checks if a farm exists
+func (s *deviceService) FarmExists(farmID uint) (bool, error) {
+	return s.repo.FarmExists(farmID)
+}
+
+// CreateDevice registers a device on one of the farm's sectors
+func (s *deviceService) CreateDevice(farmID uint, input DeviceInput) (*model.Device, error) {
+	if err := s.checkSector(farmID, input.SectorID); err != nil {
+		return nil, err
+	}
+	device := &model.Device{FarmID: farmID}
+	input.apply(device)
+	if err := s.devices.Create(device); err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// ListDevices returns the farm's devices, or one sector's when sectorID is set
+func (s *deviceService) ListDevices(farmID uint, sectorID *uint) ([]model.Device, error) {
+	return s.devices.List(farmID, sectorID)
+}
+
+// GetDevice returns a device of the farm, or ErrDeviceNotFound
+func (s *deviceService) GetDevice(farmID, deviceID uint) (*model.Device, error) {
+	device, err := s.devices.Get(farmID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if device == nil {
+		return nil, ErrDeviceNotFound
+	}
+	return device, nil
+}
+
+// UpdateDevice replaces a device's details. Moving a device to another sector leaves the events
+// it already measured on their sector.
+func (s *deviceService) UpdateDevice(farmID, deviceID uint, input DeviceInput) (*model.Device, error) {
+	device, err := s.GetDevice(farmID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkSector(farmID, input.SectorID); err != nil {
+		return nil, err
+	}
+	input.apply(device)
+	if err := s.devices.Update(device); err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// DeleteDevice removes a device of the farm, reporting whether it existed
+func (s *deviceService) DeleteDevice(farmID, deviceID uint) (bool, error) {
+	return s.devices.Delete(farmID, deviceID)
+}
+
+// checkSector returns ErrSectorNotFound unless the farm has the sector
+func (s *deviceService) checkSector(farmID, sectorID uint) error {
+	sectors, err := s.repo.ListSectors(farmID)
+	if err != nil {
+		return err
+	}
+	if !sectorInList(sectors, sectorID) {
+		return ErrSectorNotFound
+	}
+	return nil
+}
+
+// apply copies the input onto the device
+func (input DeviceInput) apply(device *model.Device) {
+	device.IrrigationSectorID = input.SectorID
+	device.Kind = input.Kind
+	device.Name = input.Name
+	device.SerialNumber = input.SerialNumber
+	device.Note = input.Note
+}
+
+// loadDeviceSectors maps each of the farm's devices to the sector it is installed on, returning
+// nil when no device repository is configured
+func loadDeviceSectors(devices repository.DeviceRepository, farmID uint) (map[uint]uint, error) {
+	if devices == nil {
+		return nil, nil
+	}
+	list, err := devices.List(farmID, nil)
+	if err != nil {
+		return nil, err
+	}
+	deviceSectors := make(map[uint]uint, len(list))
+	for _, device := range list {
+		deviceSectors[device.ID] = device.IrrigationSectorID
+	}
+	return deviceSectors, nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// stubDeviceRepository is an in-memory DeviceRepository for service tests
+type stubDeviceRepository struct {
+	repository.DeviceRepository
+	devices []model.Device
+	updated *model.Device
+}
+
+func (r *stubDeviceRepository) Create(device *model.Device) error {
+	device.ID = uint(len(r.devices) + 1)
+	r.devices = append(r.devices, *device)
+	return nil
+}
+
+func (r *stubDeviceRepository) List(farmID uint, sectorID *uint) ([]model.Device, error) {
+	return r.devices, nil
+}
+
+func (r *stubDeviceRepository) Get(farmID, deviceID uint) (*model.Device, error) {
+	for _, device := range r.devices {
+		if device.ID == deviceID {
+			return &device, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *stubDeviceRepository) Update(device *model.Device) error {
+	r.updated = device
+	return nil
+}
+
+func TestDeviceService(t *testing.T) {
+	devices := &stubDeviceRepository{}
+	svc := NewDeviceService(&sectorRepository{}, devices)
+
+	device, err := svc.CreateDevice(1, DeviceInput{SectorID: 1, Kind: model.DeviceKindFlowMeter, Name: "Meter A"})
+	if err != nil || device.ID != 1 || device.FarmID != 1 || device.IrrigationSectorID != 1 {
+		t.Fatalf("expected the device to be created on sector 1, got %+v, %v", device, err)
+	}
+	if _, err := svc.CreateDevice(1, DeviceInput{SectorID: 2, Kind: model.DeviceKindValve, Name: "Valve"}); !errors.Is(err, ErrSectorNotFound) {
+		t.Errorf("expected ErrSectorNotFound for another farm's sector, got %v", err)
+	}
+
+	if _, err := svc.GetDevice(1, 2); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("expected ErrDeviceNotFound, got %v", err)
+	}
+	if _, err := svc.UpdateDevice(1, 2, DeviceInput{SectorID: 1}); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("expected ErrDeviceNotFound when updating an unknown device, got %v", err)
+	}
+	if _, err := svc.UpdateDevice(1, 1, DeviceInput{SectorID: 1, Kind: model.DeviceKindFlowMeter, Name: "Meter B"}); err != nil ||
+		devices.updated == nil || devices.updated.Name != "Meter B" {
+		t.Errorf("expected the device to be renamed, got %+v, %v", devices.updated, err)
+	}
+
+	deviceSectors, err := loadDeviceSectors(devices, 1)
+	if err != nil || deviceSectors[1] != 1 {
+		t.Errorf("expected device 1 on sector 1, got %v, %v", deviceSectors, err)
+	}
+	if deviceSectors, err := loadDeviceSectors(nil, 1); deviceSectors != nil || err != nil {
+		t.Errorf("expected no devices without a repository, got %v, %v", deviceSectors, err)
+	}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"math"
+	"time"
+
+	"irrigation-analytics/internal/repository"
+)
+
+// SummaryDistribution describes how individual events spread around the summary's totals and means.
+// A metric is omitted when no event in the period contributes to it.
+type SummaryDistribution struct {
+	WaterVolume *DistributionStats `json:"water_volume,omitempty"`
+	Duration    *DistributionStats `json:"duration,omitempty"` // in minutes
+	// Efficiency covers events with a recorded, positive nominal_amount; the duration fallback
+	// only applies to buckets and is not used here
+	Efficiency *DistributionStats `json:"efficiency,omitempty"`
+}
+
+// DistributionStats holds per-event statistics of one metric. Percentiles are interpolated
+// (percentile_cont) and StdDev is the population standard deviation.
+type DistributionStats struct {
+	Min    float64 `json:"min"`
+	Median float64 `json:"median"`
+	P90    float64 `json:"p90"`
+	P95    float64 `json:"p95"`
+	Max    float64 `json:"max"`
+	StdDev float64 `json:"stddev"`
+}
+
+// attachDistribution sets the summary's per-event distribution statistics
+func (s *analyticsService) attachDistribution(summary *AnalyticsSummary, farmID uint, sectorID *uint, startDate, endDate time.Time, opts repository.QueryOptions) error {
+	result, err := s.repo.GetDistributionData(farmID, sectorID, startDate, endDate, opts)
+	if err != nil {
+		return err
+	}
+
+	summary.Distribution = &SummaryDistribution{
+		WaterVolume: distributionStats(2, result.VolumeMin, result.VolumeMedian, result.VolumeP90, result.VolumeP95, result.VolumeMax, result.VolumeStdDev),
+		Duration:    distributionStats(2, result.DurationMin, result.DurationMedian, result.DurationP90, result.DurationP95, result.DurationMax, result.DurationStdDev),
+		Efficiency:  distributionStats(4, result.EfficiencyMin, result.EfficiencyMedian, result.EfficiencyP90, result.EfficiencyP95, result.EfficiencyMax, result.EfficiencyStdDev),
+	}
+	return nil
+}
+
+// distributionStats rounds one metric's statistics to the given decimals, or returns nil when the
+// metric had no rows (the aggregates are NULL together)
+func distributionStats(decimals int, min, median, p90, p95, max, stddev *float64) *DistributionStats {
+	if min == nil || median == nil || p90 == nil || p95 == nil || max == nil || stddev == nil {
+		return nil
+	}
+	scale := math.Pow(10, float64(decimals))
+	round := func(v *float64) float64 {
+		return math.Round(*v*scale) / scale
+	}
+	return &DistributionStats{
+		Min:    round(min),
+		Median: round(median),
+		P90:    round(p90),
+		P95:    round(p95),
+		Max:    round(max),
+		StdDev: round(stddev),
+	}
+}
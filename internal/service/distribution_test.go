@@ -0,0 +1,90 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/repository"
+)
+
+// distributionRepository serves fixed distribution statistics, or fails when err is set
+type distributionRepository struct {
+	*stubRepository
+	distribution *repository.DistributionResult
+	err          error
+}
+
+func (r *distributionRepository) GetDistributionData(farmID uint, sectorID *uint, startDate, endDate time.Time, opts repository.QueryOptions) (*repository.DistributionResult, error) {
+	return r.distribution, r.err
+}
+
+func (r *distributionRepository) GetSummaryData(farmID uint, sectorID *uint, startDate, endDate time.Time, opts repository.QueryOptions) (*repository.SummaryResult, error) {
+	return &repository.SummaryResult{WaterVolume: 100, Duration: 100, EventCount: 1, NominalAmount: 100, RealAmount: 100}, nil
+}
+
+// TestGetIrrigationAnalytics_Distribution verifies statistics are rounded per metric and a metric
+// without contributing events is omitted
+func TestGetIrrigationAnalytics_Distribution(t *testing.T) {
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	f := weatherFloat
+	repo := &distributionRepository{
+		stubRepository: &stubRepository{
+			comparison: map[int][]repository.AggregatedDataWithCount{0: {aggregatedPoint(day, 1, 100, 100, 1)}},
+		},
+		distribution: &repository.DistributionResult{
+			VolumeMin: f(120), VolumeMedian: f(840.504), VolumeP90: f(1420), VolumeP95: f(1610.251), VolumeMax: f(4980), VolumeStdDev: f(412.3749),
+			DurationMin: f(10), DurationMedian: f(45), DurationP90: f(75), DurationP95: f(84.5), DurationMax: f(240), DurationStdDev: f(22.1),
+		},
+	}
+	svc := NewAnalyticsService(repo, nil, nil, nil)
+
+	response, err := svc.GetIrrigationAnalytics(1, nil, day, day.AddDate(0, 0, 1), "daily", AnalyticsOptions{IncludeDistribution: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	distribution := response.Summary.Distribution
+	if distribution == nil || distribution.WaterVolume == nil || distribution.Duration == nil {
+		t.Fatalf("expected volume and duration statistics, got %+v", distribution)
+	}
+	expected := DistributionStats{Min: 120, Median: 840.5, P90: 1420, P95: 1610.25, Max: 4980, StdDev: 412.37}
+	if *distribution.WaterVolume != expected {
+		t.Errorf("expected %+v, got %+v", expected, *distribution.WaterVolume)
+	}
+	if distribution.Efficiency != nil {
+		t.Errorf("expected no efficiency statistics without nominal amounts, got %+v", distribution.Efficiency)
+	}
+
+	response, err = svc.GetIrrigationSummary(1, nil, day, day.AddDate(0, 0, 1), AnalyticsOptions{IncludeDistribution: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.Summary.Distribution == nil || response.Summary.Distribution.Duration.P95 != 84.5 {
+		t.Errorf("expected the summary-only path to carry the distribution, got %+v", response.Summary.Distribution)
+	}
+}
+
+// TestGetIrrigationAnalytics_DistributionWarning verifies a failed distribution query omits the
+// section with a warning
+func TestGetIrrigationAnalytics_DistributionWarning(t *testing.T) {
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	repo := &distributionRepository{
+		stubRepository: &stubRepository{
+			comparison: map[int][]repository.AggregatedDataWithCount{0: {aggregatedPoint(day, 1, 100, 100, 1)}},
+		},
+		err: errors.New("canceling statement due to statement timeout"),
+	}
+	sectorID := uint(1)
+	svc := NewAnalyticsService(repo, nil, nil, nil)
+
+	response, err := svc.GetIrrigationAnalytics(1, &sectorID, day, day.AddDate(0, 0, 1), "daily", AnalyticsOptions{IncludeDistribution: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(response.Warnings) != 1 || response.Warnings[0].Section != SectionDistribution {
+		t.Errorf("expected one distribution warning, got %+v", response.Warnings)
+	}
+	if response.Summary.Distribution != nil {
+		t.Errorf("expected no distribution, got %+v", response.Summary.Distribution)
+	}
+}
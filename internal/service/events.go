@@ -0,0 +1,248 @@
+package service
+
+import (
+	"slices"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// EventService defines the interface for operations on individual irrigation events
+type EventService interface {
+	FarmExists(farmID uint) (bool, error)
+	ClassifyEvent(farmID, eventID uint, purpose string) (*model.IrrigationData, error)
+	RecomputeDurations(farmID uint, startDate, endDate time.Time, dryRun bool) (*DurationRecompute, error)
+	ListEvents(farmID uint, startDate, endDate time.Time, opts EventListOptions) (*EventPage, error)
+}
+
+// Event listing page sizes
+const (
+	DefaultEventPageLimit = 100
+	MaxEventPageLimit     = 1000
+)
+
+// DefaultEventSort orders events by start time
+const DefaultEventSort = "start_time"
+
+// IsValidEventSort reports whether events can be sorted by the field
+func IsValidEventSort(sort string) bool {
+	return slices.Contains(repository.EventSortColumns, sort)
+}
+
+// EventListOptions filters, orders and pages the events listed by ListEvents
+type EventListOptions struct {
+	// SectorID limits the events to the sector and its zones, as the analytics sector filter does
+	SectorID *uint
+	// MinVolume and MaxVolume bound the water volume inclusively when set
+	MinVolume *float64
+	MaxVolume *float64
+	// Purposes limits the events to these purposes; empty means all purposes
+	Purposes []string
+	// DeviceID limits the events to those measured by this device
+	DeviceID *uint
+	// Sort is a field accepted by IsValidEventSort, DefaultEventSort when empty
+	Sort       string
+	Descending bool
+	// Limit is the page size, DefaultEventPageLimit when 0
+	Limit  int
+	Offset int
+}
+
+// EventPage is one page of a farm's events
+type EventPage struct {
+	FarmID uint       `json:"farm_id"`
+	Period PeriodInfo `json:"period"`
+	// Total counts the matching events across all pages
+	Total  int64 `json:"total"`
+	Limit  int   `json:"limit"`
+	Offset int   `json:"offset"`
+	// NextOffset is the offset of the next page, nil on the last page
+	NextOffset *int          `json:"next_offset,omitempty"`
+	Events     []EventRecord `json:"events"`
+}
+
+// EventRecord is one raw event as listed by ListEvents
+type EventRecord struct {
+	ID         uint      `json:"id"`
+	SectorID   uint      `json:"sector_id"`
+	DeviceID   *uint     `json:"device_id,omitempty"`
+	ExternalID *string   `json:"external_id,omitempty"`
+	StartTime  time.Time `json:"start_time"`
+	EndTime    time.Time `json:"end_time"`
+	// WaterVolume is in liters and Duration in minutes
+	WaterVolume   float64  `json:"water_volume"`
+	Duration      int      `json:"duration"`
+	NominalAmount *float64 `json:"nominal_amount"`
+	RealAmount    float64  `json:"real_amount"`
+	// Efficiency is real_amount / nominal_amount, nil without a positive nominal_amount
+	Efficiency *float64 `json:"efficiency"`
+	Purpose    string   `json:"purpose"`
+	DataSource string   `json:"data_source"`
+	// CalibrationID is set when the volumes were corrected by a calibration at ingestion
+	CalibrationID *uint     `json:"calibration_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// toEventRecord maps a stored event onto its listed form
+func toEventRecord(event model.IrrigationData) EventRecord {
+	record := EventRecord{
+		ID:            event.ID,
+		SectorID:      event.IrrigationSectorID,
+		DeviceID:      event.DeviceID,
+		ExternalID:    event.ExternalID,
+		StartTime:     event.StartTime,
+		EndTime:       event.EndTime,
+		WaterVolume:   event.WaterVolume,
+		Duration:      event.Duration,
+		NominalAmount: event.NominalAmount,
+		RealAmount:    event.RealAmount,
+		Purpose:       event.Purpose,
+		DataSource:    event.DataSource,
+		CalibrationID: event.CalibrationID,
+		CreatedAt:     event.CreatedAt,
+	}
+	if event.NominalAmount != nil && *event.NominalAmount > 0 {
+		efficiency := event.RealAmount / *event.NominalAmount
+		record.Efficiency = &efficiency
+	}
+	return record
+}
+
+// maxListedCorrections caps the corrections listed in a DurationRecompute; Corrected counts them all
+const maxListedCorrections = 1000
+
+// DurationRecompute reports the durations rederived from event start and end times
+type DurationRecompute struct {
+	FarmID uint       `json:"farm_id"`
+	Period PeriodInfo `json:"period"`
+	DryRun bool       `json:"dry_run"`
+	// Corrected counts events whose duration was (or with dry_run, would be) rewritten
+	Corrected int `json:"corrected"`
+	// InvalidEvents counts events that end before they start, whose duration can't be derived
+	InvalidEvents int `json:"invalid_events"`
+	// Corrections lists the first 1,000 corrections in start time order
+	Corrections []DurationCorrection `json:"corrections"`
+	Truncated   bool                 `json:"truncated,omitempty"`
+}
+
+// DurationCorrection is one event's stored and derived duration, in minutes
+type DurationCorrection struct {
+	EventID     uint      `json:"event_id"`
+	SectorID    uint      `json:"sector_id"`
+	StartTime   time.Time `json:"start_time"`
+	OldDuration int       `json:"old_duration"`
+	NewDuration int       `json:"new_duration"`
+}
+
+// eventService implements EventService
+type eventService struct {
+	repo repository.IrrigationRepository
+}
+
+// NewEventService creates a new event service
+func NewEventService(repo repository.IrrigationRepository) EventService {
+	return &eventService{repo: repo}
+}
+
+// FarmExists checks if a farm exists
+func (s *eventService) FarmExists(farmID uint) (bool, error) {
+	return s.repo.FarmExists(farmID)
+}
+
+// ClassifyEvent sets an event's purpose and returns the updated event
+func (s *eventService) ClassifyEvent(farmID, eventID uint, purpose string) (*model.IrrigationData, error) {
+	updated, err := s.repo.UpdateEventPurpose(farmID, eventID, purpose)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrEventNotFound
+	}
+	return s.repo.GetEvent(farmID, eventID)
+}
+
+// ListEvents lists a page of the farm's events in [startDate, endDate), the raw events behind
+// the analytics data points. Events are placed in the range by the farm's attribution policy,
+// as they are in the analytics buckets.
+func (s *eventService) ListEvents(farmID uint, startDate, endDate time.Time, opts EventListOptions) (*EventPage, error) {
+	attribution, err := s.repo.GetAttributionPolicy(farmID)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Sort == "" {
+		opts.Sort = DefaultEventSort
+	}
+	if opts.Limit == 0 {
+		opts.Limit = DefaultEventPageLimit
+	}
+
+	events, total, err := s.repo.ListEventPage(farmID, repository.EventPageQuery{
+		SectorID:   opts.SectorID,
+		StartDate:  startDate,
+		EndDate:    endDate,
+		MinVolume:  opts.MinVolume,
+		MaxVolume:  opts.MaxVolume,
+		Sort:       opts.Sort,
+		Descending: opts.Descending,
+		Limit:      opts.Limit,
+		Offset:     opts.Offset,
+		Options: repository.QueryOptions{
+			Purposes:    opts.Purposes,
+			DeviceID:    opts.DeviceID,
+			Attribution: attribution,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &EventPage{
+		FarmID: farmID,
+		Period: PeriodInfo{StartDate: startDate, EndDate: endDate},
+		Total:  total,
+		Limit:  opts.Limit,
+		Offset: opts.Offset,
+		Events: make([]EventRecord, 0, len(events)),
+	}
+	for _, event := range events {
+		page.Events = append(page.Events, toEventRecord(event))
+	}
+	if next := opts.Offset + len(events); int64(next) < total {
+		page.NextOffset = &next
+	}
+	return page, nil
+}
+
+// RecomputeDurations rederives the duration of the farm's events in [startDate, endDate) from their
+// start and end times, fixing rows imported with a wrong or missing duration. BeforeCreate only
+// derives a duration when none is given, so such rows are never corrected otherwise.
+func (s *eventService) RecomputeDurations(farmID uint, startDate, endDate time.Time, dryRun bool) (*DurationRecompute, error) {
+	corrections, invalid, err := s.repo.RecomputeDurations(farmID, startDate, endDate, !dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DurationRecompute{
+		FarmID:        farmID,
+		Period:        PeriodInfo{StartDate: startDate, EndDate: endDate},
+		DryRun:        dryRun,
+		Corrected:     len(corrections),
+		InvalidEvents: invalid,
+		Corrections:   make([]DurationCorrection, 0, min(len(corrections), maxListedCorrections)),
+	}
+	for i, correction := range corrections {
+		if i == maxListedCorrections {
+			result.Truncated = true
+			break
+		}
+		result.Corrections = append(result.Corrections, DurationCorrection{
+			EventID:     correction.ID,
+			SectorID:    correction.IrrigationSectorID,
+			StartTime:   correction.StartTime,
+			OldDuration: correction.OldDuration,
+			NewDuration: correction.NewDuration,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// durationRepository serves a fixed set of duration corrections
+type durationRepository struct {
+	*stubRepository
+	corrections []repository.DurationCorrection
+	applied     bool
+}
+
+func (r *durationRepository) RecomputeDurations(farmID uint, startDate, endDate time.Time, apply bool) ([]repository.DurationCorrection, int, error) {
+	r.applied = apply
+	return r.corrections, 2, nil
+}
+
+// eventPageRepository serves a fixed page of events, recording the query it was given
+type eventPageRepository struct {
+	*stubRepository
+	events []model.IrrigationData
+	total  int64
+	query  repository.EventPageQuery
+}
+
+func (r *eventPageRepository) ListEventPage(farmID uint, query repository.EventPageQuery) ([]model.IrrigationData, int64, error) {
+	r.query = query
+	return r.events, r.total, nil
+}
+
+func TestListEvents(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	nominal, zero := 80.0, 0.0
+	repo := &eventPageRepository{
+		stubRepository: &stubRepository{attribution: model.AttributionEnd},
+		events: []model.IrrigationData{
+			{ID: 4, IrrigationSectorID: 2, StartTime: start, WaterVolume: 100, NominalAmount: &nominal, RealAmount: 90},
+			{ID: 5, IrrigationSectorID: 2, StartTime: start, WaterVolume: 50, NominalAmount: &zero, RealAmount: 40},
+		},
+		total: 5,
+	}
+	svc := NewEventService(repo)
+
+	page, err := svc.ListEvents(1, start, start.AddDate(0, 1, 0), EventListOptions{Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.query.Sort != DefaultEventSort || repo.query.Options.Attribution != model.AttributionEnd {
+		t.Errorf("expected the default sort and the farm's attribution, got %+v", repo.query)
+	}
+	if page.Total != 5 || len(page.Events) != 2 || page.NextOffset == nil || *page.NextOffset != 3 {
+		t.Errorf("expected 2 of 5 events with a next offset of 3, got %+v", page)
+	}
+	if first := page.Events[0]; first.SectorID != 2 || first.Efficiency == nil || *first.Efficiency != 1.125 {
+		t.Errorf("expected sector 2 with efficiency 1.125, got %+v", first)
+	}
+	if page.Events[1].Efficiency != nil {
+		t.Errorf("expected no efficiency without a positive nominal amount, got %v", *page.Events[1].Efficiency)
+	}
+
+	// The last page has no next offset
+	if page, _ := svc.ListEvents(1, start, start.AddDate(0, 1, 0), EventListOptions{Limit: 2, Offset: 3}); page.NextOffset != nil {
+		t.Errorf("expected no next offset on the last page, got %d", *page.NextOffset)
+	}
+	if page, _ := svc.ListEvents(1, start, start.AddDate(0, 1, 0), EventListOptions{}); repo.query.Limit != DefaultEventPageLimit || page.Limit != DefaultEventPageLimit {
+		t.Errorf("expected the default page size, got %d", repo.query.Limit)
+	}
+}
+
+func TestRecomputeDurations(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	corrections := make([]repository.DurationCorrection, maxListedCorrections+5)
+	for i := range corrections {
+		corrections[i] = repository.DurationCorrection{ID: uint(i + 1), IrrigationSectorID: 3, StartTime: start, OldDuration: 0, NewDuration: 45}
+	}
+	repo := &durationRepository{stubRepository: &stubRepository{}, corrections: corrections}
+	svc := NewEventService(repo)
+
+	result, err := svc.RecomputeDurations(1, start, start.AddDate(1, 0, 0), true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.applied {
+		t.Error("expected a dry run not to apply the corrections")
+	}
+	if result.Corrected != maxListedCorrections+5 || len(result.Corrections) != maxListedCorrections || !result.Truncated {
+		t.Errorf("expected %d corrected with %d listed, got %d with %d listed", maxListedCorrections+5, maxListedCorrections, result.Corrected, len(result.Corrections))
+	}
+	if first := result.Corrections[0]; first.EventID != 1 || first.SectorID != 3 || first.NewDuration != 45 || result.InvalidEvents != 2 {
+		t.Errorf("unexpected report %+v", result)
+	}
+
+	repo.corrections = corrections[:1]
+	if result, _ := svc.RecomputeDurations(1, start, start.AddDate(1, 0, 0), false); !repo.applied || result.Truncated {
+		t.Errorf("expected the corrections applied without truncation, got %+v", result)
+	}
+}
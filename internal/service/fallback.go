@@ -0,0 +1,63 @@
+package service
+
+import (
+	"irrigation-analytics/internal/repository"
+)
+
+// DefaultFallbackFlowRate is the nominal flow, in liters per minute, assumed for buckets without a
+// recorded nominal_amount when their sector has no fallback_flow_rate
+const DefaultFallbackFlowRate = 1.0
+
+// fallbackFlowRate returns the fallback flow rate of a sector. Buckets combining several sectors
+// carry sector ID 0 and get the default.
+func (s *analyticsService) fallbackFlowRate(sectorID uint) float64 {
+	if rate, ok := s.fallbackRates[sectorID]; ok {
+		return rate
+	}
+	return DefaultFallbackFlowRate
+}
+
+// needsFallback reports whether bucketEfficiency falls back to the duration heuristic for item:
+// no included event recorded nominal_amount, none was excluded by annotation, and there is
+// volume and duration to work with
+func needsFallback(item repository.AggregatedDataWithCount) bool {
+	includedEvents := item.EventCount - item.ExcludedEventCount
+	allMissing := includedEvents > 0 && item.MissingNominalCount >= includedEvents
+	return allMissing && item.ExcludedEventCount == 0 && item.Data.WaterVolume > 0 && item.Data.Duration > 0
+}
+
+// withFallbackRates returns a copy of the service that knows the farm's per-sector fallback flow
+// rates. Sectors are only queried when a bucket in data is missing nominal amounts, so farms with
+// complete data pay nothing.
+func (s *analyticsService) withFallbackRates(farmID uint, data ...[]repository.AggregatedDataWithCount) (*analyticsService, error) {
+	if s.fallbackRates != nil || !anyMissingNominal(data...) {
+		return s, nil
+	}
+
+	sectors, err := s.repo.ListSectors(farmID)
+	if err != nil {
+		return nil, err
+	}
+	rates := make(map[uint]float64, len(sectors))
+	for _, sector := range sectors {
+		if sector.FallbackFlowRate != nil && *sector.FallbackFlowRate > 0 {
+			rates[sector.ID] = *sector.FallbackFlowRate
+		}
+	}
+
+	withRates := *s
+	withRates.fallbackRates = rates
+	return &withRates, nil
+}
+
+// anyMissingNominal reports whether any bucket has events without a recorded nominal_amount
+func anyMissingNominal(data ...[]repository.AggregatedDataWithCount) bool {
+	for _, items := range data {
+		for _, item := range items {
+			if item.MissingNominalCount > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// TestGetIrrigationAnalytics_SectorFallbackFlowRate verifies buckets without nominal amounts use
+// their sector's fallback flow rate and are marked in the response
+func TestGetIrrigationAnalytics_SectorFallbackFlowRate(t *testing.T) {
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	drip := aggregatedPoint(day, 1, 60, 0, 1)
+	drip.Data.Duration = 120
+	drip.MissingNominalCount = 1
+	sprinkler := aggregatedPoint(day, 2, 60, 0, 1)
+	sprinkler.Data.Duration = 120
+	sprinkler.MissingNominalCount = 1
+	recorded := aggregatedPoint(day.AddDate(0, 0, 1), 1, 90, 100, 1)
+
+	dripRate := 0.5
+	repo := &areaRepository{
+		stubRepository: &stubRepository{
+			comparison: map[int][]repository.AggregatedDataWithCount{0: {drip, sprinkler, recorded}},
+		},
+		sectorList: []model.IrrigationSector{{ID: 1, FallbackFlowRate: &dripRate}, {ID: 2}},
+	}
+	svc := NewAnalyticsService(repo, nil, nil, nil)
+
+	sectorID := uint(1)
+	response, err := svc.GetIrrigationAnalytics(1, &sectorID, day, day.AddDate(0, 0, 2), "daily", AnalyticsOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if point := response.Data[0]; !point.UsedFallback || point.Efficiency != 1 || *point.FallbackFlowRate != 0.5 {
+		t.Errorf("expected drip sector efficiency 1 at 0.5 L/min, got %+v", point)
+	}
+	if point := response.Data[1]; !point.UsedFallback || point.Efficiency != 0.5 || *point.FallbackFlowRate != DefaultFallbackFlowRate {
+		t.Errorf("expected default rate for a sector without one, got %+v", point)
+	}
+	if point := response.Data[2]; point.UsedFallback || point.FallbackFlowRate != nil {
+		t.Errorf("expected recorded nominal amounts not to be marked, got %+v", point)
+	}
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// FarmService defines the interface for farm lifecycle operations
+type FarmService interface {
+	ArchiveFarm(farmID uint) (*model.Farm, error)
+	UnarchiveFarm(farmID uint) (*model.Farm, error)
+	SetAttributionPolicy(farmID uint, policy string) (*model.Farm, error)
+	SetWaterAccount(farmID uint, account string) (*model.Farm, error)
+	SetCropYearStart(farmID uint, start model.CropYear) (*model.Farm, error)
+}
+
+// farmService implements FarmService
+type farmService struct {
+	farms repository.FarmRepository
+	now   func() time.Time
+}
+
+// NewFarmService creates a new farm service
+func NewFarmService(farms repository.FarmRepository) FarmService {
+	return &farmService{farms: farms, now: time.Now}
+}
+
+// ArchiveFarm archives the farm, keeping the original archive time when it is already archived.
+// It returns ErrFarmNotFound when the farm doesn't exist.
+func (s *farmService) ArchiveFarm(farmID uint) (*model.Farm, error) {
+	now := s.now().UTC()
+	return s.setArchived(farmID, &now)
+}
+
+// UnarchiveFarm makes an archived farm active again; unarchiving an active farm does nothing.
+// It returns ErrFarmNotFound when the farm doesn't exist.
+func (s *farmService) UnarchiveFarm(farmID uint) (*model.Farm, error) {
+	return s.setArchived(farmID, nil)
+}
+
+// SetAttributionPolicy stores the model.Attribution* policy used to place the farm's events in
+// buckets. It returns ErrFarmNotFound when the farm doesn't exist.
+func (s *farmService) SetAttributionPolicy(farmID uint, policy string) (*model.Farm, error) {
+	return farmOrNotFound(s.farms.SetAttributionPolicy(farmID, policy))
+}
+
+// SetWaterAccount stores the farm's irrigation district account, which water order reconciliation
+// needs; an empty account clears it. It returns ErrFarmNotFound when the farm doesn't exist.
+func (s *farmService) SetWaterAccount(farmID uint, account string) (*model.Farm, error) {
+	return farmOrNotFound(s.farms.SetWaterAccount(farmID, account))
+}
+
+// SetCropYearStart stores the start of the farm's crop year, which season-aligned year-over-year
+// comparisons align on. It returns ErrFarmNotFound when the farm doesn't exist.
+func (s *farmService) SetCropYearStart(farmID uint, start model.CropYear) (*model.Farm, error) {
+	return farmOrNotFound(s.farms.SetCropYearStart(farmID, start.String()))
+}
+
+func (s *farmService) setArchived(farmID uint, archivedAt *time.Time) (*model.Farm, error) {
+	return farmOrNotFound(s.farms.SetArchived(farmID, archivedAt))
+}
+
+// farmOrNotFound turns the nil farm of a missing farm into ErrFarmNotFound
+func farmOrNotFound(farm *model.Farm, err error) (*model.Farm, error) {
+	if err != nil {
+		return nil, err
+	}
+	if farm == nil {
+		return nil, ErrFarmNotFound
+	}
+	return farm, nil
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+func TestArchiveFarm(t *testing.T) {
+	repo := &stubFarmRepository{existing: &model.Farm{ID: 3}}
+	first := time.Date(2025, 11, 3, 9, 0, 0, 0, time.UTC)
+	svc := &farmService{farms: repo, now: func() time.Time { return first }}
+
+	farm, err := svc.ArchiveFarm(3)
+	if err != nil || farm.ArchivedAt == nil || !farm.ArchivedAt.Equal(first) {
+		t.Fatalf("expected the farm archived at %v, got %+v (%v)", first, farm, err)
+	}
+
+	svc.now = func() time.Time { return first.AddDate(0, 1, 0) }
+	if farm, _ := svc.ArchiveFarm(3); !farm.ArchivedAt.Equal(first) {
+		t.Errorf("expected archiving again to keep %v, got %v", first, farm.ArchivedAt)
+	}
+
+	if farm, err := svc.UnarchiveFarm(3); err != nil || farm.ArchivedAt != nil {
+		t.Errorf("expected the farm active again, got %+v (%v)", farm, err)
+	}
+
+	if _, err := svc.ArchiveFarm(9); !errors.Is(err, ErrFarmNotFound) {
+		t.Errorf("expected ErrFarmNotFound, got %v", err)
+	}
+}
+
+func TestSetAttributionPolicy(t *testing.T) {
+	repo := &stubFarmRepository{existing: &model.Farm{ID: 3, AttributionPolicy: model.AttributionStart}}
+	svc := NewFarmService(repo)
+
+	farm, err := svc.SetAttributionPolicy(3, model.AttributionProportional)
+	if err != nil || farm.AttributionPolicy != model.AttributionProportional {
+		t.Errorf("expected the proportional policy stored, got %+v (%v)", farm, err)
+	}
+	if _, err := svc.SetAttributionPolicy(9, model.AttributionEnd); !errors.Is(err, ErrFarmNotFound) {
+		t.Errorf("expected ErrFarmNotFound, got %v", err)
+	}
+}
+
+func TestSetWaterAccount(t *testing.T) {
+	repo := &stubFarmRepository{existing: &model.Farm{ID: 3}}
+	svc := NewFarmService(repo)
+
+	farm, err := svc.SetWaterAccount(3, "WD-4471")
+	if err != nil || farm.WaterAccount != "WD-4471" {
+		t.Errorf("expected the account stored, got %+v (%v)", farm, err)
+	}
+	if _, err := svc.SetWaterAccount(9, "WD-1"); !errors.Is(err, ErrFarmNotFound) {
+		t.Errorf("expected ErrFarmNotFound, got %v", err)
+	}
+}
+
+func TestSetCropYearStart(t *testing.T) {
+	repo := &stubFarmRepository{existing: &model.Farm{ID: 3}}
+	svc := NewFarmService(repo)
+
+	farm, err := svc.SetCropYearStart(3, model.CropYear{Month: time.October, Day: 1})
+	if err != nil || farm.CropYearStart != "10-01" {
+		t.Errorf("expected 10-01 stored, got %+v (%v)", farm, err)
+	}
+	if farm, _ := svc.SetCropYearStart(3, model.CropYear{}); farm.CropYearStart != "01-01" {
+		t.Errorf("expected the zero crop year stored as 01-01, got %q", farm.CropYearStart)
+	}
+	if _, err := svc.SetCropYearStart(9, model.CropYear{}); !errors.Is(err, ErrFarmNotFound) {
+		t.Errorf("expected ErrFarmNotFound, got %v", err)
+	}
+}
+
+// TestGetIrrigationAnalytics_AttributionPolicy verifies the farm's stored policy reaches the queries
+func TestGetIrrigationAnalytics_AttributionPolicy(t *testing.T) {
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	repo := &stubRepository{
+		comparison:  map[int][]repository.AggregatedDataWithCount{0: {aggregatedPoint(day, 1, 100, 100, 1)}},
+		attribution: model.AttributionEnd,
+	}
+	svc := NewAnalyticsService(repo, nil, nil, nil)
+
+	if _, err := svc.GetIrrigationAnalytics(1, nil, day, day.AddDate(0, 0, 1), "daily", AnalyticsOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.opts.Attribution != model.AttributionEnd {
+		t.Errorf("expected the end policy in the query options, got %q", repo.opts.Attribution)
+	}
+}
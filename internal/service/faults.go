@@ -0,0 +1,100 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"irrigation-analytics/internal/repository"
+)
+
+// Fault rule bounds: injected latency, and how long a rule lasts before it expires on its own
+const (
+	maxFaultLatencyMs       = 60000
+	DefaultFaultDurationSec = 600
+	maxFaultDurationSec     = 3600
+)
+
+// ErrInvalidFault wraps validation failures of a fault rule
+var ErrInvalidFault = errors.New("invalid fault rule")
+
+// FaultAdminService defines the interface for operator control of fault injection in staging
+type FaultAdminService interface {
+	ListFaults() *FaultReport
+	SetFault(input FaultInput) (*repository.FaultRule, error)
+	DeleteFault(target string) bool
+	ClearFaults()
+}
+
+// FaultInput sets the rule of one target
+type FaultInput struct {
+	// Target is an irrigation repository method, e.g. GetDistributionData, or "db" for every statement
+	Target    string  `json:"target"`
+	LatencyMs int     `json:"latency_ms"`
+	ErrorRate float64 `json:"error_rate"`
+	// DurationSeconds is how long the rule lasts (default 600, at most 3600)
+	DurationSeconds int `json:"duration_seconds"`
+}
+
+// FaultReport lists the live rules with the targets rules can be set on
+type FaultReport struct {
+	Rules   []repository.FaultRule `json:"rules"`
+	Targets []string               `json:"targets"`
+}
+
+// faultAdminService implements FaultAdminService
+type faultAdminService struct {
+	faults *repository.FaultInjector
+}
+
+// NewFaultAdminService creates a new fault admin service
+func NewFaultAdminService(faults *repository.FaultInjector) FaultAdminService {
+	return &faultAdminService{faults: faults}
+}
+
+// ListFaults returns the live rules and the known targets
+func (s *faultAdminService) ListFaults() *FaultReport {
+	return &FaultReport{
+		Rules:   s.faults.Rules(),
+		Targets: repository.FaultTargets(),
+	}
+}
+
+// SetFault validates the input and replaces the rule of its target
+func (s *faultAdminService) SetFault(input FaultInput) (*repository.FaultRule, error) {
+	if !repository.IsFaultTarget(input.Target) {
+		return nil, fmt.Errorf("%w: unknown target %q", ErrInvalidFault, input.Target)
+	}
+	if input.LatencyMs < 0 || input.LatencyMs > maxFaultLatencyMs {
+		return nil, fmt.Errorf("%w: latency_ms must be between 0 and %d", ErrInvalidFault, maxFaultLatencyMs)
+	}
+	if input.ErrorRate < 0 || input.ErrorRate > 1 {
+		return nil, fmt.Errorf("%w: error_rate must be between 0 and 1", ErrInvalidFault)
+	}
+	if input.LatencyMs == 0 && input.ErrorRate == 0 {
+		return nil, fmt.Errorf("%w: set latency_ms or error_rate", ErrInvalidFault)
+	}
+	if input.DurationSeconds == 0 {
+		input.DurationSeconds = DefaultFaultDurationSec
+	}
+	if input.DurationSeconds < 0 || input.DurationSeconds > maxFaultDurationSec {
+		return nil, fmt.Errorf("%w: duration_seconds must be between 1 and %d", ErrInvalidFault, maxFaultDurationSec)
+	}
+
+	rule := s.faults.SetRule(input.Target,
+		time.Duration(input.LatencyMs)*time.Millisecond,
+		input.ErrorRate,
+		time.Duration(input.DurationSeconds)*time.Second,
+	)
+	return &rule, nil
+}
+
+// DeleteFault removes the rule of a target, reporting whether one was set
+func (s *faultAdminService) DeleteFault(target string) bool {
+	return s.faults.DeleteRule(target)
+}
+
+// ClearFaults removes every rule
+func (s *faultAdminService) ClearFaults() {
+	s.faults.Clear()
+}
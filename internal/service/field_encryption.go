@@ -0,0 +1,64 @@
+package service
+
+import (
+	"errors"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// ErrFieldEncryptionDisabled is returned when re-encryption is requested without field keys
+var ErrFieldEncryptionDisabled = errors.New("field encryption is not configured")
+
+// FieldEncryptionService defines the interface for operator control of encrypted columns
+type FieldEncryptionService interface {
+	GetStatus() (*FieldEncryptionStatus, error)
+	Reencrypt() (*repository.ReencryptionResult, error)
+}
+
+// FieldEncryptionStatus reports the configured keys and how each encrypted column is stored
+type FieldEncryptionStatus struct {
+	Enabled      bool   `json:"enabled"`
+	PrimaryKeyID string `json:"primary_key_id,omitempty"`
+	// KeyIDs lists every configured key, primary first
+	KeyIDs  []string                           `json:"key_ids"`
+	Columns []repository.EncryptedColumnStatus `json:"columns"`
+}
+
+// fieldEncryptionService implements FieldEncryptionService
+type fieldEncryptionService struct {
+	repo repository.FieldEncryptionRepository
+	// keys returns the configured keyring, nil when encryption is off
+	keys func() *model.FieldKeyring
+}
+
+// NewFieldEncryptionService creates a new field encryption service over the keyring configured
+// with model.ConfigureFieldEncryption
+func NewFieldEncryptionService(repo repository.FieldEncryptionRepository) FieldEncryptionService {
+	return &fieldEncryptionService{repo: repo, keys: model.FieldEncryption}
+}
+
+// GetStatus returns the configured keys and the stored values of every encrypted column
+func (s *fieldEncryptionService) GetStatus() (*FieldEncryptionStatus, error) {
+	columns, err := s.repo.ColumnStatus()
+	if err != nil {
+		return nil, err
+	}
+	status := &FieldEncryptionStatus{KeyIDs: []string{}, Columns: columns}
+	if keyring := s.keys(); keyring != nil {
+		status.Enabled = true
+		status.PrimaryKeyID = keyring.PrimaryKeyID()
+		status.KeyIDs = keyring.KeyIDs()
+	}
+	return status, nil
+}
+
+// Reencrypt rewrites every encrypted column with the primary key: run it after enabling
+// encryption to encrypt existing rows, and after adding a primary key to retire the old one
+func (s *fieldEncryptionService) Reencrypt() (*repository.ReencryptionResult, error) {
+	keyring := s.keys()
+	if keyring == nil {
+		return nil, ErrFieldEncryptionDisabled
+	}
+	return s.repo.Reencrypt(keyring.PrimaryKeyID())
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/repository"
+)
+
+// TestGetIrrigationAnalytics_MarksFinalBuckets verifies only buckets ending by the rollup
+// watermark are final, including filled ones, and that nothing is final before any rollup
+func TestGetIrrigationAnalytics_MarksFinalBuckets(t *testing.T) {
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	through := day.AddDate(0, 0, 2)
+	repo := &stubRepository{
+		comparison: map[int][]repository.AggregatedDataWithCount{0: {
+			aggregatedPoint(day, 1, 100, 100, 1),
+			aggregatedPoint(day.AddDate(0, 0, 2), 1, 80, 100, 1),
+		}},
+		rolledUpThrough: &through,
+	}
+	svc := NewAnalyticsService(repo, nil, nil, nil)
+
+	response, err := svc.GetIrrigationAnalytics(1, nil, day, day.AddDate(0, 0, 4), "daily", AnalyticsOptions{FillGaps: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []bool{true, true, false, false}
+	if len(response.Data) != len(expected) {
+		t.Fatalf("expected %d points, got %d", len(expected), len(response.Data))
+	}
+	for i, want := range expected {
+		if response.Data[i].IsFinal != want {
+			t.Errorf("point %d (%v): expected is_final %v", i, response.Data[i].Period, want)
+		}
+	}
+	if response.FinalThrough == nil || !response.FinalThrough.Equal(through) {
+		t.Errorf("expected final_through %v, got %v", through, response.FinalThrough)
+	}
+
+	// A weekly bucket is final only once all of its days are
+	response, err = svc.GetIrrigationAnalytics(1, nil, day, day.AddDate(0, 0, 4), "weekly", AnalyticsOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, point := range response.Data {
+		if point.IsFinal {
+			t.Errorf("expected weekly bucket %v past the watermark not to be final", point.Period)
+		}
+	}
+
+	repo.rolledUpThrough = nil
+	response, err = svc.GetIrrigationAnalytics(1, nil, day, day.AddDate(0, 0, 4), "daily", AnalyticsOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, point := range response.Data {
+		if point.IsFinal {
+			t.Errorf("expected no final points without a rollup, got %v", point.Period)
+		}
+	}
+	if response.FinalThrough != nil {
+		t.Errorf("expected no final_through without a rollup, got %v", response.FinalThrough)
+	}
+}
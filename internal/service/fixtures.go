@@ -0,0 +1,152 @@
+package service
+
+import "time"
+
+// FixtureVersions returns the schema versions with fixtures, in order: every registered version
+func FixtureVersions() []string {
+	return SchemaVersions()
+}
+
+// Fixtures returns the canonical example responses for a schema version, keyed by fixture name.
+// They are built from the response structs and passed through the version's serializers, so
+// they serialize exactly as the real endpoints do. ok is false for an unknown version.
+func Fixtures(version string) (fixtures map[string]any, ok bool) {
+	schema, ok := LookupSchemaVersion(version)
+	if !ok {
+		return nil, false
+	}
+	analytics, summaryOnly, timeseries, anomalies := exampleResponses(schema.EfficiencyWeighting)
+	return map[string]any{
+		"analytics":         schema.SerializeAnalytics(analytics),
+		"analytics_summary": schema.SerializeAnalytics(summaryOnly),
+		"timeseries":        timeseries,
+		"anomalies":         anomalies,
+	}, true
+}
+
+// fixtureTime is a fixed instant so fixtures never change between calls
+func fixtureTime(month time.Month, day int) time.Time {
+	return time.Date(2024, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// fixtureFloat returns a pointer for optional fixture fields
+func fixtureFloat(v float64) *float64 {
+	return &v
+}
+
+// exampleResponses builds the fixture responses with fixed values
+func exampleResponses(weighting string) (analytics, summaryOnly *AnalyticsResponse, timeseries *TimeSeriesResponse, anomalies *AnomalyResponse) {
+	sectorID := uint(3)
+	period := PeriodInfo{StartDate: fixtureTime(time.June, 1), EndDate: fixtureTime(time.June, 3)}
+	lastYear := PeriodInfo{StartDate: fixtureTime(time.June, 1).AddDate(-1, 0, 0), EndDate: fixtureTime(time.June, 3).AddDate(-1, 0, 0)}
+	twoYearsAgo := PeriodInfo{StartDate: fixtureTime(time.June, 1).AddDate(-2, 0, 0), EndDate: fixtureTime(time.June, 3).AddDate(-2, 0, 0)}
+
+	finalThrough := fixtureTime(time.June, 3)
+
+	summary := AnalyticsSummary{
+		TotalWaterVolume:   2450.5,
+		TotalDuration:      540,
+		AverageEfficiency:  0.9213,
+		TotalEvents:        9,
+		TotalRealAmount:    2450.5,
+		TotalNominalAmount: 2660,
+	}
+
+	analytics = &AnalyticsResponse{
+		FarmID:              1,
+		Period:              period,
+		Aggregation:         "daily",
+		EfficiencyWeighting: weighting,
+		Units:               UnitsMetric,
+		Data: []AggregatedDataPoint{
+			{Period: fixtureTime(time.June, 1), WaterVolume: 820, Duration: 180, Efficiency: 0.9318, EventCount: 3, RealAmount: 820, NominalAmount: 880, IsFinal: true},
+			{Period: fixtureTime(time.June, 2), WaterVolume: 830.5, Duration: 180, Efficiency: 0.9438, EventCount: 3, RealAmount: 830.5, NominalAmount: 880, IsFinal: true, MinPressure: fixtureFloat(1.8), AvgPressure: fixtureFloat(2.1)},
+			{Period: fixtureTime(time.June, 3), WaterVolume: 800, Duration: 180, Efficiency: 0.8889, EventCount: 3, RealAmount: 800, NominalAmount: 900},
+		},
+		Summary: summary,
+		Comparisons: []ComparisonBlock{
+			{CompareTo: "-1y", PeriodMetrics: PeriodMetrics{
+				Period:                  lastYear,
+				TotalWaterVolume:        2300,
+				TotalEvents:             8,
+				AverageEfficiency:       0.9,
+				VolumeChangePercent:     6.54,
+				EventsChangePercent:     12.5,
+				EfficiencyChangePercent: 2.37,
+			}},
+			{CompareTo: "-2y", PeriodMetrics: PeriodMetrics{
+				Period:                  twoYearsAgo,
+				VolumeChangePercent:     100,
+				EventsChangePercent:     100,
+				EfficiencyChangePercent: 100,
+			}},
+		},
+		PeriodComparison: PeriodComparison{
+			OneYearAgo: &PeriodMetrics{
+				Period:                  lastYear,
+				TotalWaterVolume:        2300,
+				TotalEvents:             8,
+				AverageEfficiency:       0.9,
+				VolumeChangePercent:     6.54,
+				EventsChangePercent:     12.5,
+				EfficiencyChangePercent: 2.37,
+			},
+		},
+		SectorBreakdown: []SectorBreakdown{
+			{SectorID: sectorID, TotalWaterVolume: 2450.5, TotalEvents: 9, AverageEfficiency: 0.9213, TotalRealAmount: 2450.5, TotalNominalAmount: 2660},
+		},
+		YearOverYear: YearOverYearComparison{
+			OneYearAgo: &YearComparison{
+				Period:            lastYear,
+				TotalWaterVolume:  2300,
+				TotalDuration:     500,
+				AverageEfficiency: 0.9,
+				TotalEvents:       8,
+				ChangePercent:     6.54,
+			},
+		},
+		DataQuality:  DataQuality{},
+		FinalThrough: &finalThrough,
+	}
+
+	summaryOnly = &AnalyticsResponse{
+		FarmID:              1,
+		SectorID:            &sectorID,
+		Period:              period,
+		Aggregation:         "daily",
+		EfficiencyWeighting: weighting,
+		Units:               UnitsMetric,
+		Data:                []AggregatedDataPoint{},
+		Summary:             summary,
+		PeriodComparison:    analytics.PeriodComparison,
+		YearOverYear:        analytics.YearOverYear,
+		DataQuality:         DataQuality{},
+	}
+
+	timeseries = &TimeSeriesResponse{
+		FarmID:      1,
+		Period:      period,
+		Aggregation: "daily",
+		Timestamps:  []time.Time{fixtureTime(time.June, 1), fixtureTime(time.June, 2), fixtureTime(time.June, 3)},
+		Series: map[string][]*float64{
+			"water_volume": {fixtureFloat(820), fixtureFloat(830.5), fixtureFloat(800)},
+			"min_pressure": {nil, fixtureFloat(1.8), nil},
+		},
+	}
+
+	anomalies = &AnomalyResponse{
+		FarmID:      1,
+		Period:      period,
+		Baseline:    PeriodInfo{StartDate: fixtureTime(time.May, 2), EndDate: fixtureTime(time.June, 1)},
+		Aggregation: "daily",
+		Method:      AnomalyMethodZScore,
+		Threshold:   3,
+		Anomalies: []Anomaly{
+			{Period: fixtureTime(time.June, 3), SectorID: sectorID, Metric: "efficiency", Value: 0.8889, LowerBound: 0.9012, UpperBound: 0.9844, Score: -3.4, Direction: "low"},
+		},
+		WeatherExplained:       []Anomaly{},
+		SectorsWithoutBaseline: []uint{},
+	}
+
+	return analytics, summaryOnly, timeseries, anomalies
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFixtures_StableAcrossCalls(t *testing.T) {
+	for _, version := range FixtureVersions() {
+		first, ok := Fixtures(version)
+		if !ok {
+			t.Fatalf("Fixtures(%q) not found", version)
+		}
+		second, _ := Fixtures(version)
+
+		a, err := json.Marshal(first)
+		if err != nil {
+			t.Fatalf("marshal %s: %v", version, err)
+		}
+		b, _ := json.Marshal(second)
+		if string(a) != string(b) {
+			t.Errorf("%s fixtures differ between calls", version)
+		}
+	}
+}
+
+func TestFixtures_RoundTripThroughResponseTypes(t *testing.T) {
+	fixtures, _ := Fixtures(SchemaV1)
+	targets := map[string]any{
+		"analytics":         &AnalyticsResponse{},
+		"analytics_summary": &AnalyticsResponse{},
+		"timeseries":        &TimeSeriesResponse{},
+		"anomalies":         &AnomalyResponse{},
+	}
+	for name, target := range targets {
+		fixture, ok := fixtures[name]
+		if !ok {
+			t.Fatalf("missing fixture %q", name)
+		}
+		data, err := json.Marshal(fixture)
+		if err != nil {
+			t.Fatalf("marshal %s: %v", name, err)
+		}
+		if err := json.Unmarshal(data, target); err != nil {
+			t.Fatalf("unmarshal %s: %v", name, err)
+		}
+		roundTrip, _ := json.Marshal(target)
+		if string(roundTrip) != string(data) {
+			t.Errorf("%s did not round-trip:\n%s\n%s", name, data, roundTrip)
+		}
+	}
+}
+
+func TestFixtures_UnknownVersion(t *testing.T) {
+	if _, ok := Fixtures("v0"); ok {
+		t.Error("expected unknown version to be rejected")
+	}
+}
+
+func TestFixtures_V2UsesVolumeWeighting(t *testing.T) {
+	fixtures, ok := Fixtures(SchemaV2)
+	if !ok {
+		t.Fatal("expected v2 fixtures")
+	}
+	data, _ := json.Marshal(fixtures["analytics"])
+	var body struct {
+		SchemaVersion       string `json:"schema_version"`
+		EfficiencyWeighting string `json:"efficiency_weighting"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if body.SchemaVersion != SchemaV2 || body.EfficiencyWeighting != EfficiencyWeightingVolume {
+		t.Errorf("expected v2 body with volume weighting, got %+v", body)
+	}
+}
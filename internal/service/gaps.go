@@ -0,0 +1,28 @@
+package service
+
+import "time"
+
+// fillGaps returns points with a zero-valued point added for every bucket in [startDate, endDate)
+// that has none, keeping period order. Points sharing a period, one per sector, are kept together.
+func fillGaps(points []AggregatedDataPoint, startDate, endDate time.Time, aggregation string) []AggregatedDataPoint {
+	starts := bucketStarts(startDate, endDate, aggregation)
+	filled := make([]AggregatedDataPoint, 0, len(points)+len(starts))
+
+	i := 0
+	for _, start := range starts {
+		// Points before this bucket (none from a well-formed query) are kept in place
+		for i < len(points) && points[i].Period.Before(start) {
+			filled = append(filled, points[i])
+			i++
+		}
+		if i < len(points) && points[i].Period.Equal(start) {
+			for i < len(points) && points[i].Period.Equal(start) {
+				filled = append(filled, points[i])
+				i++
+			}
+			continue
+		}
+		filled = append(filled, AggregatedDataPoint{Period: start})
+	}
+	return append(filled, points[i:]...)
+}
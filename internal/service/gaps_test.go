@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/repository"
+)
+
+// TestGetIrrigationAnalytics_FillGaps verifies every empty bucket of the range gets a zero point
+// while buckets with several sectors keep all of them
+func TestGetIrrigationAnalytics_FillGaps(t *testing.T) {
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	data := []repository.AggregatedDataWithCount{
+		aggregatedPoint(day.AddDate(0, 0, 1), 1, 100, 100, 1),
+		aggregatedPoint(day.AddDate(0, 0, 1), 2, 50, 50, 1),
+		aggregatedPoint(day.AddDate(0, 0, 3), 1, 80, 100, 2),
+	}
+	svc := NewAnalyticsService(&stubRepository{comparison: map[int][]repository.AggregatedDataWithCount{0: data}}, nil, nil, nil)
+
+	response, err := svc.GetIrrigationAnalytics(1, nil, day, day.AddDate(0, 0, 5), "daily", AnalyticsOptions{FillGaps: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []struct {
+		day    int
+		volume float64
+	}{{0, 0}, {1, 100}, {1, 50}, {2, 0}, {3, 80}, {4, 0}}
+	if len(response.Data) != len(expected) {
+		t.Fatalf("expected %d points, got %d", len(expected), len(response.Data))
+	}
+	for i, want := range expected {
+		point := response.Data[i]
+		if !point.Period.Equal(day.AddDate(0, 0, want.day)) || point.WaterVolume != want.volume {
+			t.Errorf("point %d: expected day %d with %v L, got %v with %v L", i, want.day, want.volume, point.Period, point.WaterVolume)
+		}
+	}
+	if response.Summary.TotalEvents != 4 {
+		t.Errorf("expected filled points to leave the summary unchanged, got %d events", response.Summary.TotalEvents)
+	}
+
+	response, err = svc.GetIrrigationAnalytics(1, nil, day, day.AddDate(0, 0, 5), "daily", AnalyticsOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(response.Data) != len(data) {
+		t.Errorf("expected only buckets with events without fill_gaps, got %d points", len(response.Data))
+	}
+}
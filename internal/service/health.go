@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// DefaultReadinessTimeout bounds each readiness check, so a hung database fails the probe
+// instead of outlasting it
+const DefaultReadinessTimeout = 2 * time.Second
+
+// Health statuses of a report and of its checks
+const (
+	HealthStatusOK          = "ok"
+	HealthStatusUnavailable = "unavailable"
+)
+
+// HealthService defines the interface for liveness and readiness probes
+type HealthService interface {
+	Liveness() *HealthReport
+	Readiness(ctx context.Context) *HealthReport
+}
+
+// HealthReport is the outcome of a probe; Status is HealthStatusOK only when every check passed
+type HealthReport struct {
+	Status string        `json:"status"`
+	Checks []HealthCheck `json:"checks,omitempty"`
+}
+
+// HealthCheck is the outcome of one readiness check
+type HealthCheck struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+// healthService implements HealthService
+type healthService struct {
+	repo    repository.HealthRepository
+	timeout time.Duration
+	// schemaVerified is set once the migrations were found applied; they aren't undone at runtime,
+	// so later probes only ping the database
+	schemaVerified atomic.Bool
+}
+
+// NewHealthService creates a health service whose readiness checks each run within timeout;
+// a timeout of 0 or less uses DefaultReadinessTimeout
+func NewHealthService(repo repository.HealthRepository, timeout time.Duration) HealthService {
+	if timeout <= 0 {
+		timeout = DefaultReadinessTimeout
+	}
+	return &healthService{repo: repo, timeout: timeout}
+}
+
+// Liveness reports that the process is serving requests; it checks no dependencies, so a
+// database outage doesn't get the process restarted
+func (s *healthService) Liveness() *HealthReport {
+	return &HealthReport{Status: HealthStatusOK}
+}
+
+// Readiness checks the database answers and that every model's table and columns exist
+func (s *healthService) Readiness(ctx context.Context) *HealthReport {
+	report := &HealthReport{Status: HealthStatusOK}
+	database := s.check(ctx, "database", s.repo.Ping)
+	report.add(database)
+
+	if database.Status != HealthStatusOK {
+		report.add(HealthCheck{Name: "migrations", Status: HealthStatusUnavailable, Error: "database unavailable"})
+		return report
+	}
+	if s.schemaVerified.Load() {
+		report.add(HealthCheck{Name: "migrations", Status: HealthStatusOK})
+		return report
+	}
+
+	migrations := s.check(ctx, "migrations", func(ctx context.Context) error {
+		missing, err := s.repo.MissingSchema(ctx, model.Models())
+		if err != nil {
+			return err
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("missing %s", strings.Join(missing, ", "))
+		}
+		s.schemaVerified.Store(true)
+		return nil
+	})
+	report.add(migrations)
+	return report
+}
+
+// check runs one readiness check under the timeout
+func (s *healthService) check(ctx context.Context, name string, run func(ctx context.Context) error) HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	started := time.Now()
+	check := HealthCheck{Name: name, Status: HealthStatusOK}
+	if err := run(ctx); err != nil {
+		check.Status = HealthStatusUnavailable
+		check.Error = err.Error()
+	}
+	check.LatencyMS = time.Since(started).Milliseconds()
+	return check
+}
+
+// add records a check, marking the report unavailable when it failed
+func (r *HealthReport) add(check HealthCheck) {
+	r.Checks = append(r.Checks, check)
+	if check.Status != HealthStatusOK {
+		r.Status = HealthStatusUnavailable
+	}
+}
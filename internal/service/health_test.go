@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// stubHealthRepository fails the ping with pingErr and reports missing as the missing schema
+type stubHealthRepository struct {
+	pingErr      error
+	missing      []string
+	schemaChecks int
+}
+
+func (r *stubHealthRepository) Ping(ctx context.Context) error { return r.pingErr }
+
+func (r *stubHealthRepository) MissingSchema(ctx context.Context, models []interface{}) ([]string, error) {
+	r.schemaChecks++
+	return r.missing, nil
+}
+
+// TestReadiness verifies failing checks make the report unavailable and a verified schema isn't rechecked
+func TestReadiness(t *testing.T) {
+	repo := &stubHealthRepository{pingErr: errors.New("connection refused")}
+	svc := NewHealthService(repo, 0)
+
+	report := svc.Readiness(context.Background())
+	if report.Status != HealthStatusUnavailable || len(report.Checks) != 2 || repo.schemaChecks != 0 {
+		t.Errorf("expected the database to fail without checking the schema, got %+v", report)
+	}
+
+	repo.pingErr, repo.missing = nil, []string{"calibrations", "irrigation_data.volume_unit"}
+	report = svc.Readiness(context.Background())
+	if report.Status != HealthStatusUnavailable || report.Checks[1].Error != "missing calibrations, irrigation_data.volume_unit" {
+		t.Errorf("expected missing migrations to be reported, got %+v", report)
+	}
+
+	repo.missing = nil
+	for i := 0; i < 2; i++ {
+		if report = svc.Readiness(context.Background()); report.Status != HealthStatusOK {
+			t.Errorf("expected ready, got %+v", report)
+		}
+	}
+	if repo.schemaChecks != 2 {
+		t.Errorf("expected the schema to be checked until verified, got %d checks", repo.schemaChecks)
+	}
+
+	if svc.Liveness().Status != HealthStatusOK {
+		t.Error("expected liveness to be ok")
+	}
+}
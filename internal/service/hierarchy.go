@@ -0,0 +1,162 @@
+package service
+
+import (
+	"errors"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// ErrSectorNotFound is returned when a sector-scoped request names a sector the farm doesn't have
+var ErrSectorNotFound = errors.New("sector not found")
+
+// sectorAncestors maps every sector to its ancestor at the given depth, 1 being top-level
+// sectors. Sectors at or above that depth map to themselves.
+func sectorAncestors(sectors []model.IrrigationSector, level int) map[uint]uint {
+	parents := make(map[uint]uint, len(sectors))
+	for _, sector := range sectors {
+		if sector.ParentID != nil {
+			parents[sector.ID] = *sector.ParentID
+		}
+	}
+
+	ancestors := make(map[uint]uint, len(sectors))
+	for _, sector := range sectors {
+		// path runs from the sector up to its root; its length bounds the walk on a parent_id cycle
+		path := []uint{sector.ID}
+		for parent, ok := parents[sector.ID]; ok && len(path) <= len(sectors); parent, ok = parents[parent] {
+			path = append(path, parent)
+		}
+		if depth := len(path); depth > level {
+			ancestors[sector.ID] = path[depth-level]
+		} else {
+			ancestors[sector.ID] = sector.ID
+		}
+	}
+	return ancestors
+}
+
+// rollUpSectors merges the buckets of sectors mapped to the same target into one bucket per period
+// and target, in order of first appearance. Sectors target returns unchanged keep their buckets.
+func rollUpSectors(data []repository.AggregatedDataWithCount, target func(sectorID uint) uint) []repository.AggregatedDataWithCount {
+	type bucketKey struct {
+		start    int64
+		sectorID uint
+	}
+	merged := make([]repository.AggregatedDataWithCount, 0, len(data))
+	index := make(map[bucketKey]int, len(data))
+	for _, item := range data {
+		item.Data.IrrigationSectorID = target(item.Data.IrrigationSectorID)
+		key := bucketKey{item.Data.StartTime.UnixNano(), item.Data.IrrigationSectorID}
+		i, exists := index[key]
+		if !exists {
+			index[key] = len(merged)
+			merged = append(merged, item)
+			continue
+		}
+
+		bucket := &merged[i]
+		nominal := bucket.Data.NominalAmountOrZero() + item.Data.NominalAmountOrZero()
+		bucket.Data.NominalAmount = &nominal
+		bucket.Data.WaterVolume += item.Data.WaterVolume
+		bucket.Data.Duration += item.Data.Duration
+		bucket.Data.RealAmount += item.Data.RealAmount
+		bucket.EventCount += item.EventCount
+		bucket.MissingNominalCount += item.MissingNominalCount
+		bucket.ExcludedEventCount += item.ExcludedEventCount
+	}
+	return merged
+}
+
+// rollUpComparison applies rollUpSectors to every period of comparison data
+func rollUpComparison(comparisonData map[int][]repository.AggregatedDataWithCount, target func(sectorID uint) uint) {
+	for offset, data := range comparisonData {
+		comparisonData[offset] = rollUpSectors(data, target)
+	}
+}
+
+// sectorHierarchy returns the roll-up target of each sector, its ancestor at opts.Level, or nil
+// when buckets are kept per sector and zone
+func (s *analyticsService) sectorHierarchy(farmID uint, opts AnalyticsOptions) (func(uint) uint, error) {
+	if opts.Level <= 0 {
+		return nil, nil
+	}
+
+	sectors, err := s.repo.ListSectors(farmID)
+	if err != nil {
+		return nil, err
+	}
+	ancestors := sectorAncestors(sectors, opts.Level)
+	return func(id uint) uint {
+		if ancestor, ok := ancestors[id]; ok {
+			return ancestor
+		}
+		return id
+	}, nil
+}
+
+// sectorSubtree returns the IDs of a sector and all its zones at any depth, or ErrSectorNotFound
+// when the sector is not on the farm
+func (s *analyticsService) sectorSubtree(farmID, sectorID uint) (map[uint]bool, error) {
+	sectors, err := s.repo.ListSectors(farmID)
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[uint][]uint, len(sectors))
+	found := false
+	for _, sector := range sectors {
+		if sector.ID == sectorID {
+			found = true
+		}
+		if sector.ParentID != nil {
+			children[*sector.ParentID] = append(children[*sector.ParentID], sector.ID)
+		}
+	}
+	if !found {
+		return nil, ErrSectorNotFound
+	}
+
+	subtree := map[uint]bool{sectorID: true}
+	pending := []uint{sectorID}
+	for len(pending) > 0 {
+		id := pending[0]
+		pending = pending[1:]
+		for _, child := range children[id] {
+			// Skipping visited sectors stops on a parent_id cycle
+			if !subtree[child] {
+				subtree[child] = true
+				pending = append(pending, child)
+			}
+		}
+	}
+	return subtree, nil
+}
+
+// subtreeTarget limits a sector breakdown to a sector's subtree: sectors outside it map to 0, and
+// zones that rollUp would merge into an ancestor above the sector map to the sector itself
+func subtreeTarget(sectorID uint, subtree map[uint]bool, rollUp func(uint) uint) func(uint) uint {
+	return func(id uint) uint {
+		if !subtree[id] {
+			return 0
+		}
+		if rollUp == nil {
+			return id
+		}
+		if target := rollUp(id); subtree[target] {
+			return target
+		}
+		return sectorID
+	}
+}
+
+// withoutSector drops the breakdown entry of the given sector ID, if any
+func withoutSector(breakdowns []SectorBreakdown, sectorID uint) []SectorBreakdown {
+	kept := breakdowns[:0]
+	for _, breakdown := range breakdowns {
+		if breakdown.SectorID != sectorID {
+			kept = append(kept, breakdown)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+func TestSectorAncestors(t *testing.T) {
+	parent := func(id uint) *uint { return &id }
+	sectors := []model.IrrigationSector{
+		{ID: 1},
+		{ID: 2, ParentID: parent(1)},
+		{ID: 3, ParentID: parent(2)},
+		{ID: 4},
+		{ID: 5, ParentID: parent(6)}, // a parent_id cycle must not hang
+		{ID: 6, ParentID: parent(5)},
+	}
+
+	tests := []struct {
+		level    int
+		expected map[uint]uint
+	}{
+		{1, map[uint]uint{1: 1, 2: 1, 3: 1, 4: 4}},
+		{2, map[uint]uint{1: 1, 2: 2, 3: 2, 4: 4}},
+		{3, map[uint]uint{1: 1, 2: 2, 3: 3, 4: 4}},
+	}
+
+	for _, tt := range tests {
+		ancestors := sectorAncestors(sectors, tt.level)
+		for id, expected := range tt.expected {
+			if ancestors[id] != expected {
+				t.Errorf("level %d: expected sector %d to roll up to %d, got %d", tt.level, id, expected, ancestors[id])
+			}
+		}
+	}
+}
+
+// TestGetIrrigationAnalytics_Level verifies zone buckets merge into their sector per period, in
+// data points and the sector breakdown
+func TestGetIrrigationAnalytics_Level(t *testing.T) {
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	parentID := uint(1)
+	repo := &areaRepository{
+		stubRepository: &stubRepository{
+			comparison: map[int][]repository.AggregatedDataWithCount{
+				0: {
+					aggregatedPoint(day, 2, 80, 100, 2),
+					aggregatedPoint(day, 3, 40, 50, 1),
+					aggregatedPoint(day, 4, 10, 10, 1),
+					aggregatedPoint(day.AddDate(0, 0, 1), 3, 30, 30, 1),
+				},
+			},
+			sectors: map[int][]repository.AggregatedDataWithCount{
+				0: {aggregatedPoint(time.Time{}, 2, 80, 100, 2), aggregatedPoint(time.Time{}, 3, 70, 80, 2)},
+			},
+		},
+		sectorList: []model.IrrigationSector{{ID: 1}, {ID: 2, ParentID: &parentID}, {ID: 3, ParentID: &parentID}, {ID: 4}},
+	}
+	svc := NewAnalyticsService(repo, nil, nil, nil)
+
+	response, err := svc.GetIrrigationAnalytics(1, nil, day, day.AddDate(0, 0, 2), "daily", AnalyticsOptions{Level: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(response.Data) != 3 {
+		t.Fatalf("expected 3 data points, got %+v", response.Data)
+	}
+	if point := response.Data[0]; point.WaterVolume != 120 || point.EventCount != 3 || point.NominalAmount != 150 || point.Efficiency != 0.8 {
+		t.Errorf("expected zones 2 and 3 merged into one point, got %+v", point)
+	}
+	if point := response.Data[1]; point.WaterVolume != 10 {
+		t.Errorf("expected the top-level sector without zones unchanged, got %+v", point)
+	}
+	if response.Summary.TotalWaterVolume != 160 {
+		t.Errorf("expected the summary unchanged by the roll-up, got %v", response.Summary.TotalWaterVolume)
+	}
+	if len(response.SectorBreakdown) != 1 || response.SectorBreakdown[0].SectorID != 1 || response.SectorBreakdown[0].TotalWaterVolume != 150 {
+		t.Errorf("expected one breakdown entry for sector 1, got %+v", response.SectorBreakdown)
+	}
+}
+
+// TestGetIrrigationAnalytics_SectorScope verifies a sector-scoped request breaks down the sector's
+// own subtree, never rolls zones up past the sector, and rejects sectors the farm doesn't have
+func TestGetIrrigationAnalytics_SectorScope(t *testing.T) {
+	parent := func(id uint) *uint { return &id }
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	totals := []repository.AggregatedDataWithCount{
+		aggregatedPoint(day, 1, 100, 100, 1),
+		aggregatedPoint(day, 2, 200, 200, 1),
+		aggregatedPoint(day, 3, 300, 300, 1),
+		aggregatedPoint(day, 4, 400, 400, 1),
+	}
+	repo := &areaRepository{
+		stubRepository: &stubRepository{sectors: map[int][]repository.AggregatedDataWithCount{0: totals}},
+		sectorList: []model.IrrigationSector{
+			{ID: 1},
+			{ID: 2, ParentID: parent(1)},
+			{ID: 3, ParentID: parent(2)},
+			{ID: 4},
+		},
+	}
+	svc := NewAnalyticsService(repo, nil, nil, nil)
+	sectorID := uint(2)
+
+	response, err := svc.GetIrrigationAnalytics(1, &sectorID, day, day.AddDate(0, 0, 1), "daily", AnalyticsOptions{SectorScope: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(response.SectorBreakdown) != 2 || response.SectorBreakdown[0].SectorID != 2 || response.SectorBreakdown[1].SectorID != 3 {
+		t.Errorf("expected a breakdown of sector 2 and its zone 3, got %+v", response.SectorBreakdown)
+	}
+
+	response, err = svc.GetIrrigationAnalytics(1, &sectorID, day, day.AddDate(0, 0, 1), "daily", AnalyticsOptions{SectorScope: true, Level: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(response.SectorBreakdown) != 1 || response.SectorBreakdown[0].SectorID != 2 || response.SectorBreakdown[0].TotalWaterVolume != 500 {
+		t.Errorf("expected level 1 to merge zone 3 into sector 2 only, got %+v", response.SectorBreakdown)
+	}
+
+	response, err = svc.GetIrrigationAnalytics(1, &sectorID, day, day.AddDate(0, 0, 1), "daily", AnalyticsOptions{})
+	if err != nil || response.SectorBreakdown != nil {
+		t.Errorf("expected no breakdown for a sector_id filter, got %+v, %v", response.SectorBreakdown, err)
+	}
+
+	unknown := uint(9)
+	if _, err := svc.GetIrrigationAnalytics(1, &unknown, day, day.AddDate(0, 0, 1), "daily", AnalyticsOptions{SectorScope: true}); !errors.Is(err, ErrSectorNotFound) {
+		t.Errorf("expected ErrSectorNotFound, got %v", err)
+	}
+	if _, err := svc.GetIrrigationSummary(1, &unknown, day, day.AddDate(0, 0, 1), AnalyticsOptions{SectorScope: true}); !errors.Is(err, ErrSectorNotFound) {
+		t.Errorf("expected ErrSectorNotFound from the summary, got %v", err)
+	}
+}
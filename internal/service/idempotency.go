@@ -0,0 +1,127 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// DefaultIdempotencyTTL is how long a completed request's response is replayed for its key
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// IdempotencyLease is how long a key stays reserved by a request that neither completed nor
+// released it. It must outlast the slowest request; after it a retry takes the key over, so a
+// crashed request doesn't block its key until the TTL runs out.
+const IdempotencyLease = 5 * time.Minute
+
+// Idempotency scopes, one per endpoint that honours Idempotency-Key
+const (
+	IdempotencyScopeImports = "imports"
+)
+
+var (
+	// ErrIdempotencyKeyReused is returned when a key is sent again with a different request body
+	ErrIdempotencyKeyReused = errors.New("idempotency key was used with a different request")
+	// ErrIdempotencyInProgress is returned when the first request with a key hasn't finished
+	ErrIdempotencyInProgress = errors.New("a request with this idempotency key is in progress")
+)
+
+// IdempotencyService defines the interface for replaying write requests sent with an
+// Idempotency-Key header
+type IdempotencyService interface {
+	Begin(farmID uint, scope, key string, request any) (record *model.IdempotencyKey, replay bool, err error)
+	Complete(record *model.IdempotencyKey, statusCode int, response any) error
+	Release(record *model.IdempotencyKey) error
+	PurgeExpired() (int64, error)
+}
+
+// idempotencyService implements IdempotencyService
+type idempotencyService struct {
+	keys  repository.IdempotencyRepository
+	ttl   time.Duration
+	lease time.Duration
+	now   func() time.Time
+}
+
+// NewIdempotencyService creates an idempotency service keeping responses for ttl; a ttl of 0
+// or less uses DefaultIdempotencyTTL
+func NewIdempotencyService(keys repository.IdempotencyRepository, ttl time.Duration) IdempotencyService {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	return &idempotencyService{keys: keys, ttl: ttl, lease: IdempotencyLease, now: time.Now}
+}
+
+// Begin reserves the key for the request. When the key already holds a completed request with
+// the same body, the stored record is returned with replay set, and the caller sends its
+// response instead of running the request again. Otherwise the caller runs the request and
+// passes the record to Complete or Release.
+func (s *idempotencyService) Begin(farmID uint, scope, key string, request any) (*model.IdempotencyKey, bool, error) {
+	hash, err := requestHash(request)
+	if err != nil {
+		return nil, false, err
+	}
+
+	now := s.now()
+	record := &model.IdempotencyKey{
+		FarmID:      farmID,
+		Scope:       scope,
+		Key:         key,
+		RequestHash: hash,
+		ReservedAt:  now,
+		ExpiresAt:   now.Add(s.ttl),
+	}
+	existing, err := s.keys.Reserve(record, now, s.lease)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing == nil {
+		return record, false, nil
+	}
+
+	switch {
+	case existing.RequestHash != hash:
+		return nil, false, ErrIdempotencyKeyReused
+	case existing.StatusCode == 0:
+		return nil, false, ErrIdempotencyInProgress
+	}
+	return existing, true, nil
+}
+
+// Complete stores the response to replay for the record's key
+func (s *idempotencyService) Complete(record *model.IdempotencyKey, statusCode int, response any) error {
+	body, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	record.StatusCode = statusCode
+	record.Response = model.RawPayload(body)
+	return s.keys.Complete(record)
+}
+
+// Release frees the record's key without storing a response, so a failed request can be retried
+// with the same key
+func (s *idempotencyService) Release(record *model.IdempotencyKey) error {
+	return s.keys.Release(record)
+}
+
+// PurgeExpired deletes the keys past their TTL; expired keys are already ignored, so this only
+// reclaims space
+func (s *idempotencyService) PurgeExpired() (int64, error) {
+	return s.keys.DeleteExpired(s.now())
+}
+
+// requestHash fingerprints a request body by its JSON encoding
+func requestHash(request any) (string, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
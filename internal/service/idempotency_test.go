@@ -0,0 +1,109 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// stubIdempotencyRepository keeps idempotency keys in memory by key value
+type stubIdempotencyRepository struct {
+	repository.IdempotencyRepository
+	keys map[string]*model.IdempotencyKey
+}
+
+func (r *stubIdempotencyRepository) Reserve(key *model.IdempotencyKey, now time.Time, lease time.Duration) (*model.IdempotencyKey, error) {
+	existing, ok := r.keys[key.Key]
+	stale := ok && existing.StatusCode == 0 && !existing.ReservedAt.After(now.Add(-lease))
+	if ok && existing.ExpiresAt.After(now) && !stale {
+		return existing, nil
+	}
+	r.keys[key.Key] = key
+	return nil, nil
+}
+
+func (r *stubIdempotencyRepository) Complete(key *model.IdempotencyKey) error {
+	r.keys[key.Key] = key
+	return nil
+}
+
+func (r *stubIdempotencyRepository) Release(key *model.IdempotencyKey) error {
+	delete(r.keys, key.Key)
+	return nil
+}
+
+func TestIdempotencyService(t *testing.T) {
+	now := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	keys := &stubIdempotencyRepository{keys: map[string]*model.IdempotencyKey{}}
+	svc := &idempotencyService{keys: keys, ttl: time.Hour, lease: 5 * time.Minute, now: func() time.Time { return now }}
+	request := map[string]any{"events": []int{1, 2}}
+
+	record, replay, err := svc.Begin(1, IdempotencyScopeImports, "batch-1", request)
+	if err != nil || replay || record.ExpiresAt != now.Add(time.Hour) {
+		t.Fatalf("expected a new reservation, got %+v, %v, %v", record, replay, err)
+	}
+	if _, _, err := svc.Begin(1, IdempotencyScopeImports, "batch-1", request); !errors.Is(err, ErrIdempotencyInProgress) {
+		t.Errorf("expected ErrIdempotencyInProgress before completion, got %v", err)
+	}
+
+	if err := svc.Complete(record, 200, map[string]int{"imported_rows": 2}); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	stored, replay, err := svc.Begin(1, IdempotencyScopeImports, "batch-1", request)
+	if err != nil || !replay || stored.StatusCode != 200 || string(stored.Response) != `{"imported_rows":2}` {
+		t.Errorf("expected the stored response to be replayed, got %+v, %v, %v", stored, replay, err)
+	}
+	if _, _, err := svc.Begin(1, IdempotencyScopeImports, "batch-1", map[string]any{"events": []int{3}}); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Errorf("expected ErrIdempotencyKeyReused for another body, got %v", err)
+	}
+
+	// An expired key is reserved afresh
+	now = now.Add(2 * time.Hour)
+	if _, replay, err := svc.Begin(1, IdempotencyScopeImports, "batch-1", request); err != nil || replay {
+		t.Errorf("expected the expired key to be reserved again, got %v, %v", replay, err)
+	}
+
+	// A released key can be reused right away
+	record, _, _ = svc.Begin(1, IdempotencyScopeImports, "batch-2", request)
+	if err := svc.Release(record); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if _, replay, err := svc.Begin(1, IdempotencyScopeImports, "batch-2", request); err != nil || replay {
+		t.Errorf("expected the released key to be reserved again, got %v, %v", replay, err)
+	}
+}
+
+func TestIdempotencyService_StaleReservationIsTakenOver(t *testing.T) {
+	now := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	keys := &stubIdempotencyRepository{keys: map[string]*model.IdempotencyKey{}}
+	svc := &idempotencyService{keys: keys, ttl: time.Hour, lease: 5 * time.Minute, now: func() time.Time { return now }}
+	request := map[string]any{"events": []int{1, 2}}
+
+	// The first request reserves the key and dies without completing or releasing it
+	if _, _, err := svc.Begin(1, IdempotencyScopeImports, "batch-1", request); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+
+	now = now.Add(4 * time.Minute)
+	if _, _, err := svc.Begin(1, IdempotencyScopeImports, "batch-1", request); !errors.Is(err, ErrIdempotencyInProgress) {
+		t.Errorf("expected ErrIdempotencyInProgress within the lease, got %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	record, replay, err := svc.Begin(1, IdempotencyScopeImports, "batch-1", request)
+	if err != nil || replay || record.ReservedAt != now || record.ExpiresAt != now.Add(time.Hour) {
+		t.Fatalf("expected the stale reservation to be taken over, got %+v, %v, %v", record, replay, err)
+	}
+
+	// A completed key is replayed however old it is, until it expires
+	if err := svc.Complete(record, 200, map[string]int{"imported_rows": 2}); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	now = now.Add(30 * time.Minute)
+	if _, replay, err := svc.Begin(1, IdempotencyScopeImports, "batch-1", request); err != nil || !replay {
+		t.Errorf("expected the completed key to be replayed after the lease, got %v, %v", replay, err)
+	}
+}
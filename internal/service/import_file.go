@@ -0,0 +1,480 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"irrigation-analytics/internal/model"
+)
+
+// Spreadsheet formats accepted by file imports
+const (
+	ImportFormatCSV  = "csv"
+	ImportFormatXLSX = "xlsx"
+)
+
+// maxXLSXPartBytes caps the uncompressed size of each part read from an XLSX file, so a small
+// upload can't expand into gigabytes of XML
+const maxXLSXPartBytes = 256 << 20
+
+// requiredImportColumns must appear in the header; nominal_amount, purpose, volume_unit,
+// device_id and external_id are optional
+var requiredImportColumns = []string{"sector_id", "start_time", "end_time", "water_volume", "real_amount"}
+
+// importTimeLayouts are the timestamp formats accepted in text cells. Layouts without a zone are UTC.
+var importTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// excelEpoch is day 0 of Excel's 1900 date system, accounting for its phantom 1900-02-29
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// ErrInvalidImportFile is returned when a file can't be read as a spreadsheet of import rows
+var ErrInvalidImportFile = errors.New("invalid import file")
+
+// ImportFile holds the rows parsed from an uploaded spreadsheet
+type ImportFile struct {
+	Format string
+	// TotalRows counts the non-blank data rows, including those that failed to parse
+	TotalRows int
+	// Rows are the parsed rows, in file order
+	Rows []ImportRow
+	// lines is the file line of each entry in Rows, counting the header as line 1
+	lines []int
+	// rejected are the rows that failed to parse, identified by file line
+	rejected []RowRejection
+	// rejectedPayloads are the cells of each row in rejected, for its dead letter
+	rejectedPayloads [][]byte
+}
+
+// FileImportReport is the result of importing or dry-running a spreadsheet
+type FileImportReport struct {
+	Format        string `json:"format"`
+	DryRun        bool   `json:"dry_run"`
+	TotalRows     int    `json:"total_rows"`
+	AcceptedRows  int    `json:"accepted_rows"`
+	RejectedRows  int    `json:"rejected_rows"`
+	DuplicateRows int    `json:"duplicate_rows"`
+	// Rejected identifies rows by their line in the file, counting the header as line 1
+	Rejected []RowRejection `json:"rejected"`
+	// Duplicates are the lines of rows skipped because their external_id was already stored
+	Duplicates []int `json:"duplicates"`
+	// Import is the job a real import was written under, for checking progress or resuming it
+	Import *model.ImportJob `json:"import,omitempty"`
+	// Impact totals the rows a dry run would add
+	Impact *ImportImpact `json:"impact,omitempty"`
+}
+
+// ParseImportCSV reads a comma-separated file whose first record is the header
+func ParseImportCSV(r io.Reader) (*ImportFile, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var records [][]string
+	var lines []int
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+		}
+		line, _ := reader.FieldPos(0)
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+	return parseImportRecords(ImportFormatCSV, records, lines, false)
+}
+
+// ParseImportXLSX reads the first worksheet of an Excel workbook whose first row is the header.
+// Dates may be Excel date cells or text in one of the accepted timestamp formats.
+func ParseImportXLSX(r io.ReaderAt, size int64) (*ImportFile, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: not an XLSX workbook", ErrInvalidImportFile)
+	}
+	parts := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		parts[f.Name] = f
+	}
+
+	sheetPath, err := firstSheetPath(parts)
+	if err != nil {
+		return nil, err
+	}
+
+	var sharedStrings xlsxSharedStrings
+	if _, ok := parts["xl/sharedStrings.xml"]; ok {
+		if err := decodeXLSXPart(parts, "xl/sharedStrings.xml", &sharedStrings); err != nil {
+			return nil, err
+		}
+	}
+
+	var sheet xlsxWorksheet
+	if err := decodeXLSXPart(parts, sheetPath, &sheet); err != nil {
+		return nil, err
+	}
+
+	records := make([][]string, 0, len(sheet.Rows))
+	lines := make([]int, 0, len(sheet.Rows))
+	for i, row := range sheet.Rows {
+		line := row.Number
+		if line == 0 {
+			line = i + 1
+		}
+		var record []string
+		for j, cell := range row.Cells {
+			column := j
+			if cell.Ref != "" {
+				column = xlsxColumn(cell.Ref)
+			}
+			for len(record) <= column {
+				record = append(record, "")
+			}
+			value, err := cell.text(sharedStrings)
+			if err != nil {
+				return nil, err
+			}
+			record[column] = value
+		}
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+	return parseImportRecords(ImportFormatXLSX, records, lines, true)
+}
+
+// parseImportRecords maps each record to an ImportRow using the header in records[0].
+// excelDates accepts numeric date cells as Excel serial dates.
+func parseImportRecords(format string, records [][]string, lines []int, excelDates bool) (*ImportFile, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInvalidImportFile)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		name = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), " ", "_"))
+		if _, exists := columns[name]; !exists && name != "" {
+			columns[name] = i
+		}
+	}
+	var missing []string
+	for _, name := range requiredImportColumns {
+		if _, ok := columns[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: missing columns %s", ErrInvalidImportFile, strings.Join(missing, ", "))
+	}
+
+	file := &ImportFile{Format: format}
+	for i := 1; i < len(records); i++ {
+		record := records[i]
+		if isBlankRecord(record) {
+			continue
+		}
+		file.TotalRows++
+
+		field := func(name string) string {
+			index, ok := columns[name]
+			if !ok || index >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[index])
+		}
+		payload, err := recordPayload(columns, field)
+		if err != nil {
+			return nil, err
+		}
+		row, reason := parseImportRow(field, excelDates)
+		if reason != "" {
+			file.rejected = append(file.rejected, RowRejection{Row: lines[i], Reason: reason})
+			file.rejectedPayloads = append(file.rejectedPayloads, payload)
+			continue
+		}
+		row.payload, row.payloadFormat, row.line = payload, format, lines[i]
+		file.Rows = append(file.Rows, row)
+		file.lines = append(file.lines, lines[i])
+	}
+	return file, nil
+}
+
+// parseImportRow converts one record's fields, returning why it can't be parsed or "" on success.
+// Value checks such as negative volumes are left to validateImportRow.
+func parseImportRow(field func(name string) string, excelDates bool) (ImportRow, string) {
+	var row ImportRow
+
+	sectorID, err := strconv.ParseUint(field("sector_id"), 10, 32)
+	if err != nil {
+		return row, fmt.Sprintf("sector_id %q is not a valid ID", field("sector_id"))
+	}
+	row.SectorID = uint(sectorID)
+
+	if row.StartTime, err = parseImportTime(field("start_time"), excelDates); err != nil {
+		return row, fmt.Sprintf("start_time %q is not a valid timestamp", field("start_time"))
+	}
+	if row.EndTime, err = parseImportTime(field("end_time"), excelDates); err != nil {
+		return row, fmt.Sprintf("end_time %q is not a valid timestamp", field("end_time"))
+	}
+
+	if row.WaterVolume, err = strconv.ParseFloat(field("water_volume"), 64); err != nil {
+		return row, fmt.Sprintf("water_volume %q is not a number", field("water_volume"))
+	}
+	if row.RealAmount, err = strconv.ParseFloat(field("real_amount"), 64); err != nil {
+		return row, fmt.Sprintf("real_amount %q is not a number", field("real_amount"))
+	}
+	// A blank nominal_amount is unrecorded (NULL), which is not the same as 0
+	if nominal := field("nominal_amount"); nominal != "" {
+		value, err := strconv.ParseFloat(nominal, 64)
+		if err != nil {
+			return row, fmt.Sprintf("nominal_amount %q is not a number", nominal)
+		}
+		row.NominalAmount = &value
+	}
+	row.Purpose = field("purpose")
+	row.VolumeUnit = field("volume_unit")
+	row.ExternalID = field("external_id")
+	if device := field("device_id"); device != "" {
+		deviceID, err := strconv.ParseUint(device, 10, 32)
+		if err != nil {
+			return row, fmt.Sprintf("device_id %q is not a valid ID", device)
+		}
+		id := uint(deviceID)
+		row.DeviceID = &id
+	}
+	return row, ""
+}
+
+// parseImportTime parses a timestamp cell, including Excel serial dates when excelDates is set
+func parseImportTime(value string, excelDates bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("empty timestamp")
+	}
+	if excelDates {
+		if serial, err := strconv.ParseFloat(value, 64); err == nil {
+			// Round to the second to drop floating point noise in the day fraction
+			seconds := math.Round(serial * 24 * 60 * 60)
+			return excelEpoch.Add(time.Duration(seconds) * time.Second), nil
+		}
+	}
+	for _, layout := range importTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised timestamp %q", value)
+}
+
+// isBlankRecord reports whether every field of the record is empty
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// xlsxWorkbook lists the workbook's sheets in tab order
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RelationshipID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+// xlsxRelationships maps relationship IDs to part paths
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText is a plain or rich text string; rich text is split into runs
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+// String joins the text and its runs
+func (t xlsxText) String() string {
+	var b strings.Builder
+	b.WriteString(t.Text)
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+// xlsxSharedStrings is the workbook's shared string table
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+// xlsxWorksheet holds a sheet's rows; empty rows and cells may be left out of the XML
+type xlsxWorksheet struct {
+	Rows []struct {
+		Number int        `xml:"r,attr"`
+		Cells  []xlsxCell `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// xlsxCell is one cell; Type is s (shared string), inlineStr, str, b or empty for numbers
+type xlsxCell struct {
+	Ref    string   `xml:"r,attr"`
+	Type   string   `xml:"t,attr"`
+	Value  string   `xml:"v"`
+	Inline xlsxText `xml:"is"`
+}
+
+// text returns the cell's value as text, resolving shared strings
+func (c xlsxCell) text(sharedStrings xlsxSharedStrings) (string, error) {
+	switch c.Type {
+	case "s":
+		index, err := strconv.Atoi(c.Value)
+		if err != nil || index < 0 || index >= len(sharedStrings.Items) {
+			return "", fmt.Errorf("%w: cell %s refers to a missing shared string", ErrInvalidImportFile, c.Ref)
+		}
+		return sharedStrings.Items[index].String(), nil
+	case "inlineStr":
+		return c.Inline.String(), nil
+	default:
+		return c.Value, nil
+	}
+}
+
+// cellColumnPattern captures the column letters of a cell reference such as AB12
+var cellColumnPattern = regexp.MustCompile(`^[A-Z]+`)
+
+// xlsxColumn returns the zero-based column of a cell reference
+func xlsxColumn(ref string) int {
+	column := 0
+	for _, letter := range cellColumnPattern.FindString(ref) {
+		column = column*26 + int(letter-'A'+1)
+	}
+	return column - 1
+}
+
+// firstSheetPath resolves the part path of the workbook's first sheet
+func firstSheetPath(parts map[string]*zip.File) (string, error) {
+	var workbook xlsxWorkbook
+	if err := decodeXLSXPart(parts, "xl/workbook.xml", &workbook); err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", fmt.Errorf("%w: the workbook has no sheets", ErrInvalidImportFile)
+	}
+
+	var relationships xlsxRelationships
+	if err := decodeXLSXPart(parts, "xl/_rels/workbook.xml.rels", &relationships); err != nil {
+		return "", err
+	}
+	for _, rel := range relationships.Relationships {
+		if rel.ID != workbook.Sheets[0].RelationshipID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return "", fmt.Errorf("%w: the first sheet's part is missing", ErrInvalidImportFile)
+}
+
+// decodeXLSXPart unmarshals one XML part of the workbook
+func decodeXLSXPart(parts map[string]*zip.File, name string, v any) error {
+	part, ok := parts[name]
+	if !ok {
+		return fmt.Errorf("%w: missing %s", ErrInvalidImportFile, name)
+	}
+	if part.UncompressedSize64 > maxXLSXPartBytes {
+		return fmt.Errorf("%w: %s is too large", ErrInvalidImportFile, name)
+	}
+	reader, err := part.Open()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+	}
+	defer reader.Close()
+
+	if err := xml.NewDecoder(io.LimitReader(reader, maxXLSXPartBytes)).Decode(v); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidImportFile, name, err)
+	}
+	return nil
+}
+
+// ImportFile imports, or with dryRun previews, the parsed rows of a spreadsheet. Rejections
+// from parsing, validation and the database are merged and reported by file line.
+func (s *importService) ImportFile(farmID uint, importID *uint, file *ImportFile, dryRun bool) (*FileImportReport, error) {
+	report := &FileImportReport{
+		Format:     file.Format,
+		DryRun:     dryRun,
+		TotalRows:  file.TotalRows,
+		Rejected:   append([]RowRejection{}, file.rejected...),
+		Duplicates: []int{},
+	}
+
+	var rejected []RowRejection
+	var duplicates []int
+	switch {
+	case len(file.Rows) == 0:
+		// Every row failed to parse; there is nothing to validate or insert
+		if !dryRun && importID == nil {
+			if err := s.storeDeadLetters(unparsedDeadLetters(farmID, nil, file)); err != nil {
+				return nil, err
+			}
+		}
+	case dryRun:
+		preview, err := s.PreviewImport(farmID, file.Rows)
+		if err != nil {
+			return nil, err
+		}
+		report.Impact = &preview.Impact
+		rejected, duplicates = preview.Rejected, preview.Duplicates
+	default:
+		result, err := s.ImportEvents(farmID, importID, file.Rows)
+		if result != nil {
+			report.Import = result.Import
+			// Rows that failed to parse are dead-lettered by the first upload only, not by resumes
+			if importID == nil {
+				if err := s.storeDeadLetters(unparsedDeadLetters(farmID, &result.Import.ID, file)); err != nil {
+					return report, err
+				}
+			}
+		}
+		if err != nil {
+			return report, err
+		}
+		rejected, duplicates = result.Rejected, result.Duplicates
+	}
+
+	for _, rejection := range rejected {
+		report.Rejected = append(report.Rejected, RowRejection{Row: file.lines[rejection.Row], Reason: rejection.Reason})
+	}
+
+	for _, row := range duplicates {
+		report.Duplicates = append(report.Duplicates, file.lines[row])
+	}
+
+	sortRejections(report.Rejected)
+	report.RejectedRows = len(report.Rejected)
+	report.DuplicateRows = len(report.Duplicates)
+	report.AcceptedRows = report.TotalRows - report.RejectedRows - report.DuplicateRows
+	return report, nil
+}
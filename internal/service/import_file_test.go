@@ -0,0 +1,129 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseImportCSV(t *testing.T) {
+	data := "Sector ID,start_time,end_time,water_volume,nominal_amount,real_amount,notes\n" +
+		"1,2021-03-01T06:00:00Z,2021-03-01 07:30:00,420.5,400,380,ok\n" +
+		"\n" +
+		"1,yesterday,2021-03-02T07:00:00Z,100,,90,\n" +
+		"1,2021-03-03 06:00,2021-03-03 07:00,100,,90,\n"
+
+	file, err := ParseImportCSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if file.TotalRows != 3 || len(file.Rows) != 2 {
+		t.Fatalf("expected 3 rows with 2 parsed, got %d with %d parsed", file.TotalRows, len(file.Rows))
+	}
+	if len(file.rejected) != 1 || file.rejected[0].Row != 4 {
+		t.Errorf("expected line 4 to be rejected, got %+v", file.rejected)
+	}
+
+	first := file.Rows[0]
+	if first.NominalAmount == nil || *first.NominalAmount != 400 || !first.EndTime.Equal(time.Date(2021, 3, 1, 7, 30, 0, 0, time.UTC)) {
+		t.Errorf("unexpected first row %+v", first)
+	}
+	if file.Rows[1].NominalAmount != nil {
+		t.Error("expected a blank nominal_amount to stay unrecorded")
+	}
+
+	if _, err := ParseImportCSV(strings.NewReader("sector_id,start_time\n1,2021-03-01\n")); !errors.Is(err, ErrInvalidImportFile) {
+		t.Errorf("expected missing columns to be rejected, got %v", err)
+	}
+}
+
+// buildXLSX writes a minimal workbook with one sheet, using shared strings for the header
+func buildXLSX(t *testing.T, sheetRows string) []byte {
+	t.Helper()
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Events" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<si><t>sector_id</t></si><si><t>start_time</t></si><si><t>end_time</t></si>` +
+			`<si><t>water_volume</t></si><si><r><t>real_</t></r><r><t>amount</t></r></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` + sheetRows + `</sheetData></worksheet>`,
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseImportXLSX(t *testing.T) {
+	rows := `<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="s"><v>2</v></c><c r="D1" t="s"><v>3</v></c><c r="E1" t="s"><v>4</v></c></row>` +
+		// 44256.25 is 2021-03-01 06:00 in Excel's date system
+		`<row r="2"><c r="A2"><v>1</v></c><c r="B2"><v>44256.25</v></c><c r="C2" t="inlineStr"><is><t>2021-03-01 07:00</t></is></c><c r="D2"><v>420.5</v></c><c r="E2"><v>380</v></c></row>` +
+		`<row r="5"><c r="A5"><v>1</v></c><c r="C5"><v>44256.3</v></c><c r="D5"><v>10</v></c><c r="E5"><v>9</v></c></row>`
+	data := buildXLSX(t, rows)
+
+	file, err := ParseImportXLSX(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(file.Rows) != 1 || len(file.rejected) != 1 {
+		t.Fatalf("expected 1 parsed and 1 rejected row, got %d and %+v", len(file.Rows), file.rejected)
+	}
+	row := file.Rows[0]
+	if !row.StartTime.Equal(time.Date(2021, 3, 1, 6, 0, 0, 0, time.UTC)) || !row.EndTime.Equal(time.Date(2021, 3, 1, 7, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected times %s - %s", row.StartTime, row.EndTime)
+	}
+	if row.RealAmount != 380 {
+		t.Errorf("expected real_amount from a rich text header, got %+v", row)
+	}
+	if file.rejected[0].Row != 5 {
+		t.Errorf("expected the sheet's row number 5 in the rejection, got %d", file.rejected[0].Row)
+	}
+
+	if _, err := ParseImportXLSX(strings.NewReader("not a zip"), 9); !errors.Is(err, ErrInvalidImportFile) {
+		t.Errorf("expected a non-zip upload to be rejected, got %v", err)
+	}
+}
+
+// TestImportFile_ReportsFileLines verifies parse, validation and database rejections are all
+// reported by file line
+func TestImportFile_ReportsFileLines(t *testing.T) {
+	data := "sector_id,start_time,end_time,water_volume,real_amount\n" +
+		"1,2021-03-01T06:00:00Z,2021-03-01T07:00:00Z,100,90\n" +
+		"x,2021-03-01T06:00:00Z,2021-03-01T07:00:00Z,100,90\n" +
+		"2,2021-03-01T06:00:00Z,2021-03-01T07:00:00Z,100,90\n" +
+		"1,2021-03-01T06:00:00Z,2021-03-01T07:00:00Z,20000,90\n"
+	file, err := ParseImportCSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	svc := NewImportService(&sectorRepository{}, &stubImportRepository{failAt: -1}, nil, nil, nil)
+	report, err := svc.ImportFile(1, nil, file, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if report.TotalRows != 4 || report.AcceptedRows != 1 || report.RejectedRows != 3 {
+		t.Fatalf("expected 1 of 4 rows accepted, got %+v", report)
+	}
+	for i, line := range []int{3, 4, 5} {
+		if report.Rejected[i].Row != line {
+			t.Errorf("expected rejection %d on line %d, got %+v", i, line, report.Rejected[i])
+		}
+	}
+}
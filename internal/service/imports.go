@@ -0,0 +1,467 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// importChunkSize is the number of rows committed per transaction
+const importChunkSize = 1000
+
+// maxExternalIDLength matches the external_id column size
+const maxExternalIDLength = 128
+
+var (
+	// ErrImportNotFound is returned when resuming or fetching an import the farm doesn't have
+	ErrImportNotFound = errors.New("import not found")
+	// ErrImportMismatch is returned when a resumed import doesn't have the original row count
+	ErrImportMismatch = errors.New("import row count does not match the original import")
+)
+
+// ImportService defines the interface for bulk import operations
+type ImportService interface {
+	FarmExists(farmID uint) (bool, error)
+	ImportEvents(farmID uint, importID *uint, rows []ImportRow) (*ImportResult, error)
+	PreviewImport(farmID uint, rows []ImportRow) (*ImportPreview, error)
+	PreviewBackfill(farmID uint, rows []ImportRow) (*BackfillPreview, error)
+	ImportFile(farmID uint, importID *uint, file *ImportFile, dryRun bool) (*FileImportReport, error)
+	GetImport(farmID, importID uint) (*model.ImportJob, error)
+}
+
+// ImportRow is a single irrigation event in a bulk import
+type ImportRow struct {
+	SectorID      uint      `json:"sector_id"`
+	StartTime     time.Time `json:"start_time"`
+	EndTime       time.Time `json:"end_time"`
+	WaterVolume   float64   `json:"water_volume"`
+	NominalAmount *float64  `json:"nominal_amount"`
+	RealAmount    float64   `json:"real_amount"`
+	// Purpose defaults to irrigation when empty
+	Purpose string `json:"purpose"`
+	// VolumeUnit is the unit of water_volume, nominal_amount and real_amount; defaults to liters
+	VolumeUnit string `json:"volume_unit"`
+	// DeviceID is the registered device that measured the event, which must be on its sector
+	DeviceID *uint `json:"device_id,omitempty"`
+	// ExternalID is the sender's ID for the event; a row whose ID the farm already stored, or
+	// that repeats an earlier row's, is skipped as a duplicate
+	ExternalID string `json:"external_id,omitempty"`
+
+	// payload is the row as received, stored with the event in payloadFormat
+	payload       []byte
+	payloadFormat string
+	// line is the row's file line for spreadsheet imports, 0 otherwise
+	line int
+}
+
+// ImportResult reports the job's progress and the rows rejected or skipped as duplicates by
+// this call
+type ImportResult struct {
+	Import   *model.ImportJob `json:"import"`
+	Rejected []RowRejection   `json:"rejected"`
+	// Duplicates are the indexes of rows skipped because their external_id was already stored
+	Duplicates []int `json:"duplicates"`
+}
+
+// ImportPreview reports what an import would do without writing anything
+type ImportPreview struct {
+	DryRun        bool           `json:"dry_run"`
+	TotalRows     int            `json:"total_rows"`
+	AcceptedRows  int            `json:"accepted_rows"`
+	RejectedRows  int            `json:"rejected_rows"`
+	DuplicateRows int            `json:"duplicate_rows"`
+	Rejected      []RowRejection `json:"rejected"`
+	Duplicates    []int          `json:"duplicates"`
+	Impact        ImportImpact   `json:"impact"`
+}
+
+// ImportImpact totals the rows an import would add
+type ImportImpact struct {
+	Events        int          `json:"events"`
+	WaterVolume   float64      `json:"water_volume"`
+	RealAmount    float64      `json:"real_amount"`
+	NominalAmount float64      `json:"nominal_amount"`
+	FirstStart    *time.Time   `json:"first_start_time,omitempty"`
+	LastStart     *time.Time   `json:"last_start_time,omitempty"`
+	SectorEvents  map[uint]int `json:"sector_events"`
+}
+
+// RowRejection explains why a row, identified by its index in the submitted rows, was not imported
+type RowRejection struct {
+	Row    int    `json:"row"`
+	Reason string `json:"reason"`
+}
+
+// importService implements ImportService
+type importService struct {
+	repo    repository.IrrigationRepository
+	imports repository.ImportRepository
+	// calibrations corrects imported volumes; nil imports them as reported
+	calibrations repository.CalibrationRepository
+	// deadLetters keeps rejected rows for replay; nil drops them once reported
+	deadLetters repository.DeadLetterRepository
+	// devices validates rows' device_id; nil rejects rows that set one
+	devices repository.DeviceRepository
+	// analytics computes summaries for backfill previews
+	analytics *analyticsService
+}
+
+// NewImportService creates a new import service
+func NewImportService(repo repository.IrrigationRepository, imports repository.ImportRepository, calibrations repository.CalibrationRepository, deadLetters repository.DeadLetterRepository, devices repository.DeviceRepository) ImportService {
+	return &importService{
+		repo:         repo,
+		imports:      imports,
+		calibrations: calibrations,
+		deadLetters:  deadLetters,
+		devices:      devices,
+		analytics:    &analyticsService{repo: repo},
+	}
+}
+
+// FarmExists checks if a farm exists
+func (s *importService) FarmExists(farmID uint) (bool, error) {
+	return s.repo.FarmExists(farmID)
+}
+
+// GetImport returns an import job of the farm
+func (s *importService) GetImport(farmID, importID uint) (*model.ImportJob, error) {
+	job, err := s.imports.GetJob(farmID, importID)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, ErrImportNotFound
+	}
+	return job, nil
+}
+
+// ImportEvents imports rows in chunks of importChunkSize, each chunk committed together with the
+// job's progress. When importID is set the same rows are being resubmitted after a failure, and
+// rows before the job's ProcessedRows are skipped. On a chunk failure the job is marked failed
+// and returned alongside the error so the caller can resume it.
+func (s *importService) ImportEvents(farmID uint, importID *uint, rows []ImportRow) (*ImportResult, error) {
+	job, err := s.startJob(farmID, importID, len(rows))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{Import: job, Rejected: []RowRejection{}, Duplicates: []int{}}
+	if job.Status == model.ImportStatusCompleted {
+		return result, nil
+	}
+
+	sectorIDs, err := s.sectorIDs(farmID)
+	if err != nil {
+		return nil, err
+	}
+	deviceSectors, err := loadDeviceSectors(s.devices, farmID)
+	if err != nil {
+		return nil, err
+	}
+	cal, err := loadCalibrator(s.calibrations, farmID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	for chunkStart := job.ProcessedRows; chunkStart < len(rows); chunkStart += importChunkSize {
+		chunkEnd := min(chunkStart+importChunkSize, len(rows))
+		events, rowIndexes, invalid := prepareChunk(farmID, rows, chunkStart, chunkEnd, sectorIDs, deviceSectors, cal)
+		events, rowIndexes, duplicates, err := s.dropDuplicates(farmID, events, rowIndexes, seen)
+		if err != nil {
+			return result, err
+		}
+		result.Duplicates = append(result.Duplicates, duplicates...)
+
+		rejected, err := s.imports.CommitChunk(job, events, chunkEnd, len(invalid), len(duplicates))
+		if err != nil {
+			job.Status = model.ImportStatusFailed
+			job.Error = fmt.Sprintf("rows %d-%d: %v", chunkStart, chunkEnd-1, err)
+			// Progress is already persisted per chunk; a failed status update only loses the message
+			_ = s.imports.UpdateJob(job)
+			return result, err
+		}
+
+		chunkRejected := invalid
+		for i, rowErr := range rejected {
+			chunkRejected = append(chunkRejected, RowRejection{Row: rowIndexes[i], Reason: rowErr.Error()})
+		}
+		result.Rejected = append(result.Rejected, chunkRejected...)
+
+		// The chunk is committed, so a failure here loses only its dead letters, not its rows
+		if err := s.storeDeadLetters(rejectedDeadLetters(farmID, job.ID, rows, chunkRejected)); err != nil {
+			job.Status = model.ImportStatusFailed
+			job.Error = fmt.Sprintf("rows %d-%d: storing dead letters: %v", chunkStart, chunkEnd-1, err)
+			_ = s.imports.UpdateJob(job)
+			return result, err
+		}
+	}
+	sortRejections(result.Rejected)
+
+	job.Status = model.ImportStatusCompleted
+	job.Error = ""
+	if err := s.imports.UpdateJob(job); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// PreviewImport runs the import pipeline, including database constraints, inside transactions
+// that are rolled back, and reports the rows that would be accepted and their aggregate impact
+func (s *importService) PreviewImport(farmID uint, rows []ImportRow) (*ImportPreview, error) {
+	preview, _, err := s.trialRun(farmID, rows)
+	return preview, err
+}
+
+// trialRun builds an import preview and also returns the events that would be accepted
+func (s *importService) trialRun(farmID uint, rows []ImportRow) (*ImportPreview, []model.IrrigationData, error) {
+	sectorIDs, err := s.sectorIDs(farmID)
+	if err != nil {
+		return nil, nil, err
+	}
+	deviceSectors, err := loadDeviceSectors(s.devices, farmID)
+	if err != nil {
+		return nil, nil, err
+	}
+	cal, err := loadCalibrator(s.calibrations, farmID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var accepted []model.IrrigationData
+
+	preview := &ImportPreview{
+		DryRun:     true,
+		TotalRows:  len(rows),
+		Rejected:   []RowRejection{},
+		Duplicates: []int{},
+		Impact:     ImportImpact{SectorEvents: map[uint]int{}},
+	}
+
+	seen := map[string]bool{}
+	for chunkStart := 0; chunkStart < len(rows); chunkStart += importChunkSize {
+		chunkEnd := min(chunkStart+importChunkSize, len(rows))
+		events, rowIndexes, invalid := prepareChunk(farmID, rows, chunkStart, chunkEnd, sectorIDs, deviceSectors, cal)
+		events, rowIndexes, duplicates, err := s.dropDuplicates(farmID, events, rowIndexes, seen)
+		if err != nil {
+			return nil, nil, err
+		}
+		preview.Duplicates = append(preview.Duplicates, duplicates...)
+
+		rejected, err := s.imports.TrialInsert(events)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		preview.Rejected = append(preview.Rejected, invalid...)
+		for i, rowErr := range rejected {
+			preview.Rejected = append(preview.Rejected, RowRejection{Row: rowIndexes[i], Reason: rowErr.Error()})
+		}
+		for i, event := range events {
+			if _, isRejected := rejected[i]; !isRejected {
+				preview.Impact.add(event)
+				accepted = append(accepted, event)
+			}
+		}
+	}
+	sortRejections(preview.Rejected)
+
+	preview.RejectedRows = len(preview.Rejected)
+	preview.DuplicateRows = len(preview.Duplicates)
+	preview.AcceptedRows = preview.TotalRows - preview.RejectedRows - preview.DuplicateRows
+	preview.Impact.WaterVolume = math.Round(preview.Impact.WaterVolume*100) / 100
+	preview.Impact.RealAmount = math.Round(preview.Impact.RealAmount*100) / 100
+	preview.Impact.NominalAmount = math.Round(preview.Impact.NominalAmount*100) / 100
+	return preview, accepted, nil
+}
+
+// add counts an accepted event towards the impact totals
+func (impact *ImportImpact) add(event model.IrrigationData) {
+	impact.Events++
+	impact.WaterVolume += event.WaterVolume
+	impact.RealAmount += event.RealAmount
+	impact.NominalAmount += event.NominalAmountOrZero()
+	impact.SectorEvents[event.IrrigationSectorID]++
+
+	start := event.StartTime
+	if impact.FirstStart == nil || start.Before(*impact.FirstStart) {
+		impact.FirstStart = &start
+	}
+	if impact.LastStart == nil || start.After(*impact.LastStart) {
+		impact.LastStart = &start
+	}
+}
+
+// sectorIDs returns the set of the farm's sector IDs for row validation
+func (s *importService) sectorIDs(farmID uint) (map[uint]bool, error) {
+	sectors, err := s.repo.ListSectors(farmID)
+	if err != nil {
+		return nil, err
+	}
+	sectorIDs := make(map[uint]bool, len(sectors))
+	for _, sector := range sectors {
+		sectorIDs[sector.ID] = true
+	}
+	return sectorIDs, nil
+}
+
+// prepareChunk validates and calibrates rows[start:end], returning the events to insert, the row
+// index of each event, and the rows rejected by validation. deviceSectors maps the farm's devices
+// to their sectors, as loadDeviceSectors returns.
+func prepareChunk(farmID uint, rows []ImportRow, start, end int, sectorIDs map[uint]bool, deviceSectors map[uint]uint, cal *calibrator) ([]model.IrrigationData, []int, []RowRejection) {
+	events := make([]model.IrrigationData, 0, end-start)
+	rowIndexes := make([]int, 0, end-start)
+	var invalid []RowRejection
+	for i := start; i < end; i++ {
+		if reason := validateImportRow(rows[i], sectorIDs, deviceSectors); reason != "" {
+			invalid = append(invalid, RowRejection{Row: i, Reason: reason})
+			continue
+		}
+		event := rows[i].toEvent(farmID)
+		cal.calibrateEvent(&event)
+		events = append(events, event)
+		rowIndexes = append(rowIndexes, i)
+	}
+	return events, rowIndexes, invalid
+}
+
+// dropDuplicates removes the events whose external_id the farm already stored, or that an
+// earlier event of the import used, returning the remaining events and row indexes and the row
+// indexes of the duplicates. seen collects the external IDs kept so far.
+func (s *importService) dropDuplicates(farmID uint, events []model.IrrigationData, rowIndexes []int, seen map[string]bool) ([]model.IrrigationData, []int, []int, error) {
+	var externalIDs []string
+	for _, event := range events {
+		if event.ExternalID != nil {
+			externalIDs = append(externalIDs, *event.ExternalID)
+		}
+	}
+	if len(externalIDs) == 0 {
+		return events, rowIndexes, nil, nil
+	}
+	stored, err := s.imports.ExistingExternalIDs(farmID, externalIDs)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	kept, keptIndexes := events[:0], rowIndexes[:0]
+	var duplicates []int
+	for i, event := range events {
+		if event.ExternalID != nil {
+			id := *event.ExternalID
+			if stored[id] || seen[id] {
+				duplicates = append(duplicates, rowIndexes[i])
+				continue
+			}
+			seen[id] = true
+		}
+		kept, keptIndexes = append(kept, event), append(keptIndexes, rowIndexes[i])
+	}
+	return kept, keptIndexes, duplicates, nil
+}
+
+// sortRejections orders rejections by row index
+func sortRejections(rejections []RowRejection) {
+	sort.Slice(rejections, func(i, j int) bool {
+		return rejections[i].Row < rejections[j].Row
+	})
+}
+
+// startJob creates a new import job, or loads the job being resumed and checks it matches the rows
+func (s *importService) startJob(farmID uint, importID *uint, totalRows int) (*model.ImportJob, error) {
+	if importID == nil {
+		job := &model.ImportJob{
+			FarmID:    farmID,
+			Status:    model.ImportStatusRunning,
+			TotalRows: totalRows,
+		}
+		if err := s.imports.CreateJob(job); err != nil {
+			return nil, err
+		}
+		return job, nil
+	}
+
+	job, err := s.GetImport(farmID, *importID)
+	if err != nil {
+		return nil, err
+	}
+	if job.TotalRows != totalRows {
+		return nil, ErrImportMismatch
+	}
+	if job.Status == model.ImportStatusFailed {
+		job.Status = model.ImportStatusRunning
+	}
+	return job, nil
+}
+
+// validateImportRow returns why a row can't be imported, or "" when it is valid
+func validateImportRow(row ImportRow, sectorIDs map[uint]bool, deviceSectors map[uint]uint) string {
+	switch {
+	case !sectorIDs[row.SectorID]:
+		return fmt.Sprintf("sector %d does not belong to the farm", row.SectorID)
+	case row.DeviceID != nil && deviceSectors[*row.DeviceID] != row.SectorID:
+		return fmt.Sprintf("device %d is not installed on sector %d", *row.DeviceID, row.SectorID)
+	case row.StartTime.IsZero() || row.EndTime.IsZero():
+		return "start_time and end_time are required"
+	case !row.EndTime.After(row.StartTime):
+		return "end_time must be after start_time"
+	case row.WaterVolume < 0 || row.RealAmount < 0:
+		return "water_volume and real_amount must not be negative"
+	case row.NominalAmount != nil && *row.NominalAmount < 0:
+		return "nominal_amount must not be negative"
+	case row.Purpose != "" && !model.IsValidPurpose(row.Purpose):
+		return fmt.Sprintf("unknown purpose %q", row.Purpose)
+	case row.VolumeUnit != "" && !model.IsValidVolumeUnit(row.VolumeUnit):
+		return fmt.Sprintf("unknown volume_unit %q", row.VolumeUnit)
+	case len(row.ExternalID) > maxExternalIDLength:
+		return fmt.Sprintf("external_id must be at most %d characters", maxExternalIDLength)
+	}
+	return ""
+}
+
+// toEvent converts the row to an irrigation event attributed to the import data source, with its
+// volumes converted to liters
+func (row ImportRow) toEvent(farmID uint) model.IrrigationData {
+	purpose := row.Purpose
+	if purpose == "" {
+		purpose = model.PurposeIrrigation
+	}
+	unit := row.VolumeUnit
+	if unit == "" {
+		unit = model.VolumeUnitLiters
+	}
+	var nominal *float64
+	if row.NominalAmount != nil {
+		liters := litersOf(*row.NominalAmount, unit)
+		nominal = &liters
+	}
+	var externalID *string
+	if row.ExternalID != "" {
+		externalID = &row.ExternalID
+	}
+	return model.IrrigationData{
+		FarmID:             farmID,
+		IrrigationSectorID: row.SectorID,
+		StartTime:          row.StartTime,
+		EndTime:            row.EndTime,
+		WaterVolume:        litersOf(row.WaterVolume, unit),
+		Duration:           int(row.EndTime.Sub(row.StartTime).Minutes()),
+		NominalAmount:      nominal,
+		RealAmount:         litersOf(row.RealAmount, unit),
+		VolumeUnit:         unit,
+		RawPayload:         model.RawPayload(row.payload),
+		PayloadFormat:      row.payloadFormat,
+		DataSource:         model.DataSourceImport,
+		Purpose:            purpose,
+		DeviceID:           row.DeviceID,
+		ExternalID:         externalID,
+	}
+}
+
+// litersOf converts a volume to liters, rounded to the two decimals the volume columns store
+func litersOf(volume float64, unit string) float64 {
+	return math.Round(model.ToLiters(volume, unit)*100) / 100
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// stubImportRepository keeps import jobs in memory and can fail the chunk starting at failAt.
+// stored holds the external IDs of events already stored.
+type stubImportRepository struct {
+	job      *model.ImportJob
+	inserted int
+	failAt   int
+	stored   map[string]bool
+}
+
+func (r *stubImportRepository) CreateJob(job *model.ImportJob) error {
+	job.ID = 1
+	r.job = job
+	return nil
+}
+
+func (r *stubImportRepository) GetJob(farmID, jobID uint) (*model.ImportJob, error) {
+	if r.job == nil || r.job.ID != jobID {
+		return nil, nil
+	}
+	return r.job, nil
+}
+
+func (r *stubImportRepository) UpdateJob(job *model.ImportJob) error {
+	r.job = job
+	return nil
+}
+
+func (r *stubImportRepository) CommitChunk(job *model.ImportJob, events []model.IrrigationData, processedRows, invalidRows, duplicateRows int) (map[int]error, error) {
+	if r.failAt >= 0 && job.ProcessedRows == r.failAt {
+		r.failAt = -1
+		return nil, errors.New("connection reset")
+	}
+	r.inserted += len(events)
+	job.ProcessedRows = processedRows
+	job.ImportedRows += len(events)
+	job.RejectedRows += invalidRows
+	job.DuplicateRows += duplicateRows
+	return map[int]error{}, nil
+}
+
+func (r *stubImportRepository) ExistingExternalIDs(farmID uint, externalIDs []string) (map[string]bool, error) {
+	existing := map[string]bool{}
+	for _, id := range externalIDs {
+		existing[id] = r.stored[id]
+	}
+	return existing, nil
+}
+
+func (r *stubImportRepository) TrialInsert(events []model.IrrigationData) (map[int]error, error) {
+	rejected := map[int]error{}
+	for i, event := range events {
+		if event.WaterVolume > 10000 {
+			rejected[i] = errors.New("numeric field overflow")
+		}
+	}
+	return rejected, nil
+}
+
+// sectorRepository returns a single sector for import validation
+type sectorRepository struct {
+	repository.IrrigationRepository
+}
+
+func (r *sectorRepository) ListSectors(farmID uint) ([]model.IrrigationSector, error) {
+	return []model.IrrigationSector{{ID: 1, FarmID: farmID}}, nil
+}
+
+func importRows(n int) []ImportRow {
+	start := time.Date(2025, 1, 1, 6, 0, 0, 0, time.UTC)
+	rows := make([]ImportRow, n)
+	for i := range rows {
+		rows[i] = ImportRow{
+			SectorID:    1,
+			StartTime:   start.Add(time.Duration(i) * time.Hour),
+			EndTime:     start.Add(time.Duration(i)*time.Hour + 30*time.Minute),
+			WaterVolume: 100,
+			RealAmount:  100,
+		}
+	}
+	return rows
+}
+
+// TestImportEvents_ResumeAfterFailure verifies a failed chunk leaves earlier chunks committed
+// and a resumed import continues from the first uncommitted row
+func TestImportEvents_ResumeAfterFailure(t *testing.T) {
+	imports := &stubImportRepository{failAt: importChunkSize}
+	svc := NewImportService(&sectorRepository{}, imports, nil, nil, nil)
+	rows := importRows(2500)
+	rows[10].SectorID = 99
+
+	result, err := svc.ImportEvents(1, nil, rows)
+	if err == nil {
+		t.Fatal("expected the second chunk to fail")
+	}
+	if result.Import.Status != model.ImportStatusFailed || result.Import.ProcessedRows != importChunkSize {
+		t.Fatalf("expected failed import after %d rows, got %+v", importChunkSize, result.Import)
+	}
+
+	importID := result.Import.ID
+	if _, err := svc.ImportEvents(1, &importID, rows[:100]); !errors.Is(err, ErrImportMismatch) {
+		t.Errorf("expected ErrImportMismatch for different rows, got %v", err)
+	}
+
+	result, err = svc.ImportEvents(1, &importID, rows)
+	if err != nil {
+		t.Fatalf("unexpected error resuming import: %v", err)
+	}
+	if result.Import.Status != model.ImportStatusCompleted {
+		t.Errorf("expected completed import, got %q", result.Import.Status)
+	}
+	if imports.inserted != 2499 || result.Import.ImportedRows != 2499 || result.Import.RejectedRows != 1 {
+		t.Errorf("expected 2499 rows inserted once and 1 rejected, got inserted=%d %+v", imports.inserted, result.Import)
+	}
+
+	// Resubmitting a completed import is a no-op
+	if _, err := svc.ImportEvents(1, &importID, rows); err != nil || imports.inserted != 2499 {
+		t.Errorf("expected completed import to be skipped, got err=%v inserted=%d", err, imports.inserted)
+	}
+}
+
+// TestImportEvents_ExternalIDs verifies rows whose external_id is already stored, or repeats an
+// earlier row's, are skipped as duplicates rather than imported or rejected
+func TestImportEvents_ExternalIDs(t *testing.T) {
+	imports := &stubImportRepository{failAt: -1, stored: map[string]bool{"gw-1": true}}
+	svc := NewImportService(&sectorRepository{}, imports, nil, nil, nil)
+	rows := importRows(4)
+	rows[0].ExternalID = "gw-1"
+	rows[1].ExternalID = "gw-2"
+	rows[2].ExternalID = "gw-2"
+
+	preview, err := svc.PreviewImport(1, rows)
+	if err != nil {
+		t.Fatalf("PreviewImport: %v", err)
+	}
+	if preview.DuplicateRows != 2 || preview.AcceptedRows != 2 || preview.Impact.Events != 2 {
+		t.Errorf("expected 2 duplicates and 2 accepted rows in the preview, got %+v", preview)
+	}
+
+	result, err := svc.ImportEvents(1, nil, rows)
+	if err != nil {
+		t.Fatalf("ImportEvents: %v", err)
+	}
+	if len(result.Duplicates) != 2 || result.Duplicates[0] != 0 || result.Duplicates[1] != 2 {
+		t.Errorf("expected rows 0 and 2 to be duplicates, got %v", result.Duplicates)
+	}
+	if imports.inserted != 2 || result.Import.DuplicateRows != 2 || len(result.Rejected) != 0 {
+		t.Errorf("expected 2 rows inserted and 2 duplicates, got inserted=%d %+v", imports.inserted, result.Import)
+	}
+}
+
+func TestValidateImportRow(t *testing.T) {
+	sectors := map[uint]bool{1: true}
+	devices := map[uint]uint{7: 1, 8: 3}
+	valid := importRows(1)[0]
+	negative := -1.0
+	meter, otherMeter, unknownMeter := uint(7), uint(8), uint(9)
+
+	tests := []struct {
+		name   string
+		modify func(row *ImportRow)
+		valid  bool
+	}{
+		{"valid", func(row *ImportRow) {}, true},
+		{"unknown sector", func(row *ImportRow) { row.SectorID = 2 }, false},
+		{"missing end time", func(row *ImportRow) { row.EndTime = time.Time{} }, false},
+		{"end before start", func(row *ImportRow) { row.EndTime = row.StartTime.Add(-time.Minute) }, false},
+		{"negative volume", func(row *ImportRow) { row.WaterVolume = -5 }, false},
+		{"negative nominal", func(row *ImportRow) { row.NominalAmount = &negative }, false},
+		{"gallons", func(row *ImportRow) { row.VolumeUnit = model.VolumeUnitGallons }, true},
+		{"unknown unit", func(row *ImportRow) { row.VolumeUnit = "m3" }, false},
+		{"sector meter", func(row *ImportRow) { row.DeviceID = &meter }, true},
+		{"other sector's meter", func(row *ImportRow) { row.DeviceID = &otherMeter }, false},
+		{"unknown meter", func(row *ImportRow) { row.DeviceID = &unknownMeter }, false},
+		{"long external id", func(row *ImportRow) { row.ExternalID = strings.Repeat("x", 129) }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row := valid
+			tt.modify(&row)
+			if reason := validateImportRow(row, sectors, devices); (reason == "") != tt.valid {
+				t.Errorf("expected valid=%v, got reason %q", tt.valid, reason)
+			}
+		})
+	}
+}
+
+// TestPreviewImport verifies a dry run reports rejections and impact without writing
+func TestPreviewImport(t *testing.T) {
+	imports := &stubImportRepository{failAt: -1}
+	svc := NewImportService(&sectorRepository{}, imports, nil, nil, nil)
+	rows := importRows(1500)
+	rows[3].SectorID = 99
+	rows[1200].WaterVolume = 20000
+
+	preview, err := svc.PreviewImport(1, rows)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if imports.job != nil || imports.inserted != 0 {
+		t.Errorf("expected no writes, got job=%+v inserted=%d", imports.job, imports.inserted)
+	}
+	if preview.AcceptedRows != 1498 || preview.RejectedRows != 2 {
+		t.Errorf("expected 1498 accepted and 2 rejected, got %d/%d", preview.AcceptedRows, preview.RejectedRows)
+	}
+	if preview.Rejected[0].Row != 3 || preview.Rejected[1].Row != 1200 {
+		t.Errorf("expected rejections for rows 3 and 1200, got %+v", preview.Rejected)
+	}
+	if preview.Impact.Events != 1498 || preview.Impact.WaterVolume != 149800 || preview.Impact.SectorEvents[1] != 1498 {
+		t.Errorf("unexpected impact: %+v", preview.Impact)
+	}
+	if !preview.Impact.FirstStart.Equal(rows[0].StartTime) || !preview.Impact.LastStart.Equal(rows[1499].StartTime) {
+		t.Errorf("unexpected impact range: %v - %v", preview.Impact.FirstStart, preview.Impact.LastStart)
+	}
+}
+
+// TestImportRowToEvent_ConvertsGallons verifies gallon volumes are stored in liters with the source unit kept
+func TestImportRowToEvent_ConvertsGallons(t *testing.T) {
+	row := importRows(1)[0]
+	nominal := 50.0
+	row.WaterVolume, row.RealAmount, row.NominalAmount = 100, 40, &nominal
+	row.VolumeUnit = model.VolumeUnitGallons
+
+	event := row.toEvent(1)
+	if event.WaterVolume != 378.54 || event.RealAmount != 151.42 || *event.NominalAmount != 189.27 {
+		t.Errorf("expected volumes in liters, got water=%v real=%v nominal=%v", event.WaterVolume, event.RealAmount, *event.NominalAmount)
+	}
+	if event.VolumeUnit != model.VolumeUnitGallons {
+		t.Errorf("expected the source unit to be kept, got %q", event.VolumeUnit)
+	}
+
+	row.VolumeUnit = ""
+	if event := row.toEvent(1); event.WaterVolume != 100 || event.VolumeUnit != model.VolumeUnitLiters {
+		t.Errorf("expected liters by default, got %v %q", event.WaterVolume, event.VolumeUnit)
+	}
+}
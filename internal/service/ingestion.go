@@ -0,0 +1,78 @@
+package service
+
+import (
+	"math"
+	"time"
+
+	"irrigation-analytics/internal/repository"
+)
+
+// IngestionService defines the interface for ingestion monitoring
+type IngestionService interface {
+	GetIngestionStatus(includeArchived bool) (*IngestionStatus, error)
+}
+
+// IngestionStatus reports how much data each ingestion path has produced
+type IngestionStatus struct {
+	// IncludesArchived is true when archived farms are counted
+	IncludesArchived bool           `json:"includes_archived"`
+	TotalEvents      int            `json:"total_events"`
+	TotalWaterVolume float64        `json:"total_water_volume"`
+	Sources          []SourceStatus `json:"sources"`
+}
+
+// SourceStatus contains attribution metrics for one data_source
+type SourceStatus struct {
+	DataSource     string    `json:"data_source"`
+	EventCount     int       `json:"event_count"`
+	WaterVolume    float64   `json:"water_volume"`
+	ShareOfEvents  float64   `json:"share_of_events_percent"`
+	LastIngestedAt time.Time `json:"last_ingested_at"`
+}
+
+// ingestionService implements IngestionService
+type ingestionService struct {
+	repo repository.IrrigationRepository
+}
+
+// NewIngestionService creates a new ingestion service
+func NewIngestionService(repo repository.IrrigationRepository) IngestionService {
+	return &ingestionService{repo: repo}
+}
+
+// GetIngestionStatus returns per-source event counts and volumes across all farms, archived farms
+// included only when includeArchived is set
+func (s *ingestionService) GetIngestionStatus(includeArchived bool) (*IngestionStatus, error) {
+	totals, err := s.repo.GetSourceTotals(includeArchived)
+	if err != nil {
+		return nil, err
+	}
+	status := buildIngestionStatus(totals)
+	status.IncludesArchived = includeArchived
+	return status, nil
+}
+
+// buildIngestionStatus rounds volumes and computes each source's share of all events
+func buildIngestionStatus(totals []repository.SourceTotal) *IngestionStatus {
+	status := &IngestionStatus{Sources: make([]SourceStatus, 0, len(totals))}
+	for _, total := range totals {
+		status.TotalEvents += total.EventCount
+		status.TotalWaterVolume += total.WaterVolume
+	}
+	status.TotalWaterVolume = math.Round(status.TotalWaterVolume*100) / 100
+
+	for _, total := range totals {
+		share := 0.0
+		if status.TotalEvents > 0 {
+			share = math.Round(float64(total.EventCount)/float64(status.TotalEvents)*10000) / 100
+		}
+		status.Sources = append(status.Sources, SourceStatus{
+			DataSource:     total.DataSource,
+			EventCount:     total.EventCount,
+			WaterVolume:    math.Round(total.WaterVolume*100) / 100,
+			ShareOfEvents:  share,
+			LastIngestedAt: total.LastIngestedAt,
+		})
+	}
+	return status
+}
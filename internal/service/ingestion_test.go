@@ -0,0 +1,28 @@
+package service
+
+import (
+	"testing"
+
+	"irrigation-analytics/internal/repository"
+)
+
+func TestBuildIngestionStatus(t *testing.T) {
+	status := buildIngestionStatus([]repository.SourceTotal{
+		{DataSource: "api", EventCount: 30, WaterVolume: 1200.555},
+		{DataSource: "seed", EventCount: 90, WaterVolume: 3600},
+	})
+
+	if status.TotalEvents != 120 {
+		t.Errorf("expected 120 total events, got %d", status.TotalEvents)
+	}
+	if status.TotalWaterVolume != 4800.56 {
+		t.Errorf("expected total volume 4800.56, got %f", status.TotalWaterVolume)
+	}
+	if status.Sources[0].ShareOfEvents != 25 || status.Sources[1].ShareOfEvents != 75 {
+		t.Errorf("expected shares 25/75, got %f/%f", status.Sources[0].ShareOfEvents, status.Sources[1].ShareOfEvents)
+	}
+
+	if empty := buildIngestionStatus(nil); empty.TotalEvents != 0 || len(empty.Sources) != 0 {
+		t.Errorf("expected empty status, got %+v", empty)
+	}
+}
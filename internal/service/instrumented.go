@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+// instrumentedAnalyticsService reports the duration of each analytics computation and
+// delegates everything to the wrapped service
+type instrumentedAnalyticsService struct {
+	AnalyticsService
+	observe func(operation string, d time.Duration)
+}
+
+// NewInstrumentedAnalyticsService wraps a service so each computation's duration is passed to observe
+func NewInstrumentedAnalyticsService(inner AnalyticsService, observe func(operation string, d time.Duration)) AnalyticsService {
+	return &instrumentedAnalyticsService{
+		AnalyticsService: inner,
+		observe:          observe,
+	}
+}
+
+// GetIrrigationAnalytics times the full analytics computation
+func (s *instrumentedAnalyticsService) GetIrrigationAnalytics(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string, opts AnalyticsOptions) (*AnalyticsResponse, error) {
+	defer s.timed("analytics")()
+	return s.AnalyticsService.GetIrrigationAnalytics(farmID, sectorID, startDate, endDate, aggregation, opts)
+}
+
+// GetIrrigationSummary times the summary-only computation
+func (s *instrumentedAnalyticsService) GetIrrigationSummary(farmID uint, sectorID *uint, startDate, endDate time.Time, opts AnalyticsOptions) (*AnalyticsResponse, error) {
+	defer s.timed("summary")()
+	return s.AnalyticsService.GetIrrigationSummary(farmID, sectorID, startDate, endDate, opts)
+}
+
+// GetTopContributors times the contribution analysis
+func (s *instrumentedAnalyticsService) GetTopContributors(farmID uint, startDate, endDate time.Time, dimension, baseline string, limit int) (*ContributorsResponse, error) {
+	defer s.timed("contributors")()
+	return s.AnalyticsService.GetTopContributors(farmID, startDate, endDate, dimension, baseline, limit)
+}
+
+// GetTimeSeries times the multi-metric series computation
+func (s *instrumentedAnalyticsService) GetTimeSeries(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string, metrics []string) (*TimeSeriesResponse, error) {
+	defer s.timed("timeseries")()
+	return s.AnalyticsService.GetTimeSeries(farmID, sectorID, startDate, endDate, aggregation, metrics)
+}
+
+// WithContext keeps the instrumentation around a service whose queries run under ctx
+func (s *instrumentedAnalyticsService) WithContext(ctx context.Context) AnalyticsService {
+	return &instrumentedAnalyticsService{
+		AnalyticsService: s.AnalyticsService.WithContext(ctx),
+		observe:          s.observe,
+	}
+}
+
+// timed starts a timer and returns a function that reports the elapsed time for operation
+func (s *instrumentedAnalyticsService) timed(operation string) func() {
+	start := time.Now()
+	return func() {
+		s.observe(operation, time.Since(start))
+	}
+}
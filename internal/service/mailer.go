@@ -0,0 +1,150 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Email is one message to send; Text is the plain text body
+type Email struct {
+	To          []string
+	Subject     string
+	Text        string
+	Attachments []EmailAttachment
+}
+
+// EmailAttachment is a file attached to an email
+type EmailAttachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+// Mailer sends emails. NewSMTPMailer covers SMTP relays and Amazon SES through its SMTP
+// interface; other providers plug in by implementing Send.
+type Mailer interface {
+	Send(ctx context.Context, email Email) error
+}
+
+// smtpMailer implements Mailer over SMTP
+type smtpMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+	now  func() time.Time
+}
+
+// NewSMTPMailer creates a mailer sending from the given address through the SMTP server at addr,
+// host:port. The connection is upgraded with STARTTLS when the server offers it, and PLAIN auth is
+// used when username is set, so SES takes its SMTP endpoint, e.g.
+// email-smtp.eu-west-1.amazonaws.com:587, and SMTP credentials.
+func NewSMTPMailer(addr, username, password, from string) Mailer {
+	var auth smtp.Auth
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &smtpMailer{addr: addr, auth: auth, from: from, now: time.Now}
+}
+
+// Send delivers the email to all its recipients. net/smtp has no context support, so ctx is only
+// checked before connecting.
+func (m *smtpMailer) Send(ctx context.Context, email Email) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	message, err := buildEmailMessage(m.from, email, m.now())
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(m.addr, m.auth, m.from, email.To, message)
+}
+
+// logMailer implements Mailer by logging the emails instead of sending them
+type logMailer struct {
+	logger *slog.Logger
+}
+
+// NewLogMailer creates a mailer for development that only logs what it would send
+func NewLogMailer(logger *slog.Logger) Mailer {
+	return &logMailer{logger: logger}
+}
+
+// Send logs the email's recipients and subject
+func (m *logMailer) Send(ctx context.Context, email Email) error {
+	m.logger.Info("email not sent, no mailer configured",
+		"to", strings.Join(email.To, ", "),
+		"subject", email.Subject,
+		"attachments", len(email.Attachments),
+	)
+	return nil
+}
+
+// buildEmailMessage renders the email as a MIME message: the text alone, or a multipart/mixed
+// message of the text and base64 encoded attachments
+func buildEmailMessage(from string, email Email, date time.Time) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(email.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", email.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	text := strings.ReplaceAll(strings.ReplaceAll(email.Text, "\r\n", "\n"), "\n", "\r\n")
+	if len(email.Attachments) == 0 {
+		b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+		b.WriteString(text)
+		return b.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(&b)
+	fmt.Fprintf(&b, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", parts.Boundary())
+	body, err := parts.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := body.Write([]byte(text)); err != nil {
+		return nil, err
+	}
+	for _, attachment := range email.Attachments {
+		part, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {attachment.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBase64Lines(part, attachment.Content); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// writeBase64Lines writes content base64 encoded in lines of 76 characters, as MIME requires
+func writeBase64Lines(w io.Writer, content []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(content)
+	for len(encoded) > 0 {
+		n := min(76, len(encoded))
+		if _, err := w.Write([]byte(encoded[:n] + "\r\n")); err != nil {
+			return err
+		}
+		encoded = encoded[n:]
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestBuildEmailMessage verifies the message parses back into its text and attachment
+func TestBuildEmailMessage(t *testing.T) {
+	pdf := bytes.Repeat([]byte("%PDF-1.4 report "), 20)
+	message, err := buildEmailMessage("reports@example.com", Email{
+		To:          []string{"a@example.com", "b@example.com"},
+		Subject:     "Weekly report – Farm 1",
+		Text:        "Water volume: 2,000 L\nEvents: 3\n",
+		Attachments: []EmailAttachment{{Filename: "report.pdf", ContentType: "application/pdf", Content: pdf}},
+	}, time.Date(2025, 6, 16, 6, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(message))
+	if err != nil {
+		t.Fatalf("unparseable message: %v", err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if subject != "Weekly report – Farm 1" || msg.Header.Get("To") != "a@example.com, b@example.com" {
+		t.Errorf("unexpected headers %v", msg.Header)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("expected a multipart message, got %q", msg.Header.Get("Content-Type"))
+	}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	text, err := reader.NextPart()
+	if err != nil {
+		t.Fatalf("missing text part: %v", err)
+	}
+	body, _ := io.ReadAll(text)
+	if string(body) != "Water volume: 2,000 L\r\nEvents: 3\r\n" {
+		t.Errorf("unexpected text %q", body)
+	}
+
+	attachment, err := reader.NextPart()
+	if err != nil {
+		t.Fatalf("missing attachment: %v", err)
+	}
+	encoded, _ := io.ReadAll(attachment)
+	for _, line := range strings.Split(strings.TrimSpace(string(encoded)), "\r\n") {
+		if len(line) > 76 {
+			t.Errorf("expected base64 lines of at most 76 characters, got %d", len(line))
+		}
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+	if err != nil || !bytes.Equal(decoded, pdf) || attachment.FileName() != "report.pdf" {
+		t.Errorf("expected the attachment to round-trip, got %q (%v)", attachment.FileName(), err)
+	}
+}
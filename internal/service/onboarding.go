@@ -0,0 +1,279 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// maxOnboardingSectors caps the sectors, zones included, created with a farm in one request
+const maxOnboardingSectors = 500
+
+// maxLocationLength caps a farm's location, which is stored encrypted in an unbounded column
+const maxLocationLength = 255
+
+var (
+	// ErrInvalidOnboarding wraps validation failures of an onboarding or clone request
+	ErrInvalidOnboarding = errors.New("invalid onboarding request")
+	// ErrFarmNotFound is returned when the farm to clone doesn't exist
+	ErrFarmNotFound = errors.New("farm not found")
+)
+
+// OnboardingService defines the interface for creating a farm with its sectors in one step
+type OnboardingService interface {
+	OnboardFarm(input OnboardFarmInput) (*model.Farm, error)
+	CloneFarm(sourceFarmID uint, input CloneFarmInput) (*model.Farm, error)
+}
+
+// OnboardFarmInput describes a new farm and its sectors
+type OnboardFarmInput struct {
+	Name        string  `json:"name"`
+	Location    string  `json:"location"`
+	TotalArea   float64 `json:"total_area"`
+	Description string  `json:"description"`
+	// Latitude and Longitude are optional but must be given together; weather needs them
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	// WaterAccount is the farm's irrigation district account; water order reconciliation needs it
+	WaterAccount string               `json:"water_account"`
+	Sectors      []OnboardSectorInput `json:"sectors"`
+}
+
+// OnboardSectorInput describes one sector of a new farm
+type OnboardSectorInput struct {
+	Name        string  `json:"name"`
+	Area        float64 `json:"area"`
+	Description string  `json:"description"`
+	// FallbackFlowRate is the liters per minute assumed for events without a nominal_amount
+	FallbackFlowRate *float64 `json:"fallback_flow_rate"`
+	// Crop is a crop coefficient library key and CropStage its growth stage, given together
+	Crop      string `json:"crop"`
+	CropStage string `json:"crop_stage"`
+	// Zones are created as sectors nested under this one; their areas lie within its area
+	Zones []OnboardSectorInput `json:"zones"`
+}
+
+// CloneFarmInput names the farm created by a clone; everything else is copied from the source
+type CloneFarmInput struct {
+	Name        string `json:"name"`
+	Location    string `json:"location"`
+	Description string `json:"description"`
+}
+
+// onboardingService implements OnboardingService
+type onboardingService struct {
+	farms repository.FarmRepository
+}
+
+// NewOnboardingService creates a new onboarding service
+func NewOnboardingService(farms repository.FarmRepository) OnboardingService {
+	return &onboardingService{farms: farms}
+}
+
+// OnboardFarm validates the input and creates the farm and its sectors atomically
+func (s *onboardingService) OnboardFarm(input OnboardFarmInput) (*model.Farm, error) {
+	if err := input.validate(); err != nil {
+		return nil, err
+	}
+
+	farm := &model.Farm{
+		Name:         strings.TrimSpace(input.Name),
+		Location:     input.Location,
+		TotalArea:    input.TotalArea,
+		Description:  input.Description,
+		Latitude:     input.Latitude,
+		Longitude:    input.Longitude,
+		WaterAccount: strings.TrimSpace(input.WaterAccount),
+	}
+	farm.IrrigationSectors = newSectors(input.Sectors)
+
+	if err := s.farms.CreateFarmWithSectors(farm); err != nil {
+		return nil, err
+	}
+	return farm, nil
+}
+
+// newSectors builds the sectors of an onboarding request with their zones nested
+func newSectors(inputs []OnboardSectorInput) []model.IrrigationSector {
+	sectors := make([]model.IrrigationSector, 0, len(inputs))
+	for _, sector := range inputs {
+		sectors = append(sectors, model.IrrigationSector{
+			Name:             strings.TrimSpace(sector.Name),
+			Area:             sector.Area,
+			Description:      sector.Description,
+			FallbackFlowRate: sector.FallbackFlowRate,
+			Crop:             sector.Crop,
+			CropStage:        sector.CropStage,
+			Zones:            newSectors(sector.Zones),
+		})
+	}
+	return sectors
+}
+
+// validate checks the farm and sectors, returning an ErrInvalidOnboarding-wrapped error on failure
+func (input OnboardFarmInput) validate() error {
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %s", ErrInvalidOnboarding, fmt.Sprintf(format, args...))
+	}
+
+	if strings.TrimSpace(input.Name) == "" {
+		return invalid("name is required")
+	}
+	if input.TotalArea < 0 {
+		return invalid("total_area must not be negative")
+	}
+	switch {
+	case (input.Latitude == nil) != (input.Longitude == nil):
+		return invalid("latitude and longitude must be given together")
+	case input.Latitude != nil && (*input.Latitude < -90 || *input.Latitude > 90):
+		return invalid("latitude must be between -90 and 90")
+	case input.Longitude != nil && (*input.Longitude < -180 || *input.Longitude > 180):
+		return invalid("longitude must be between -180 and 180")
+	}
+	if len(input.Location) > maxLocationLength {
+		return invalid("location must be at most %d characters", maxLocationLength)
+	}
+	if len(strings.TrimSpace(input.WaterAccount)) > MaxWaterAccountLength {
+		return invalid("water_account must be at most %d characters", MaxWaterAccountLength)
+	}
+	if len(input.Sectors) == 0 || countSectors(input.Sectors) > maxOnboardingSectors {
+		return invalid("sectors must contain between 1 and %d sectors, zones included", maxOnboardingSectors)
+	}
+
+	names := make(map[string]bool)
+	if err := validateSectors(input.Sectors, "sectors", names); err != nil {
+		return err
+	}
+
+	// Sector areas are stored with two decimals, so allow for rounding in the sum
+	if sectorArea := sumArea(input.Sectors); input.TotalArea > 0 && sectorArea > input.TotalArea+0.005*float64(len(input.Sectors)) {
+		return invalid("sector areas add up to %.2f ha, more than the farm's total_area of %.2f ha",
+			math.Round(sectorArea*100)/100, input.TotalArea)
+	}
+	return nil
+}
+
+// validateSectors checks sectors and, recursively, their zones. Names are unique across the farm,
+// and zone areas must fit within their sector's area when it is set.
+func validateSectors(sectors []OnboardSectorInput, path string, names map[string]bool) error {
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %s", ErrInvalidOnboarding, fmt.Sprintf(format, args...))
+	}
+
+	for i, sector := range sectors {
+		at := fmt.Sprintf("%s[%d]", path, i)
+		name := strings.ToLower(strings.TrimSpace(sector.Name))
+		switch {
+		case name == "":
+			return invalid("%s: name is required", at)
+		case names[name]:
+			return invalid("%s: duplicate sector name %q", at, sector.Name)
+		case sector.Area < 0:
+			return invalid("%s: area must not be negative", at)
+		case sector.FallbackFlowRate != nil && *sector.FallbackFlowRate <= 0:
+			return invalid("%s: fallback_flow_rate must be positive", at)
+		case sector.Crop != "" && !IsKnownCrop(sector.Crop):
+			return invalid("%s: unknown crop %q", at, sector.Crop)
+		case (sector.Crop == "") != (sector.CropStage == "") || (sector.CropStage != "" && !model.IsValidCropStage(sector.CropStage)):
+			return invalid("%s: crop and crop_stage must be given together, with crop_stage one of: initial, development, mid, late", at)
+		}
+		names[name] = true
+
+		if err := validateSectors(sector.Zones, at+".zones", names); err != nil {
+			return err
+		}
+		if zoneArea := sumArea(sector.Zones); sector.Area > 0 && zoneArea > sector.Area+0.005*float64(len(sector.Zones)) {
+			return invalid("%s: zone areas add up to %.2f ha, more than the sector's area of %.2f ha",
+				at, math.Round(zoneArea*100)/100, sector.Area)
+		}
+	}
+	return nil
+}
+
+// countSectors counts sectors and their zones at any depth
+func countSectors(sectors []OnboardSectorInput) int {
+	count := len(sectors)
+	for _, sector := range sectors {
+		count += countSectors(sector.Zones)
+	}
+	return count
+}
+
+// sumArea adds up the areas of sectors, not of their zones
+func sumArea(sectors []OnboardSectorInput) float64 {
+	var area float64
+	for _, sector := range sectors {
+		area += sector.Area
+	}
+	return area
+}
+
+// CloneFarm creates a new farm with copies of the source farm's sectors and zones, total area and, unless
+// overridden, location (with its coordinates) and description. Event data, annotations and imports are not copied.
+func (s *onboardingService) CloneFarm(sourceFarmID uint, input CloneFarmInput) (*model.Farm, error) {
+	if strings.TrimSpace(input.Name) == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidOnboarding)
+	}
+	if len(input.Location) > maxLocationLength {
+		return nil, fmt.Errorf("%w: location must be at most %d characters", ErrInvalidOnboarding, maxLocationLength)
+	}
+
+	source, err := s.farms.GetFarmWithSectors(sourceFarmID)
+	if err != nil {
+		return nil, err
+	}
+	if source == nil {
+		return nil, ErrFarmNotFound
+	}
+
+	farm := &model.Farm{
+		Name:        strings.TrimSpace(input.Name),
+		Location:    source.Location,
+		TotalArea:   source.TotalArea,
+		Description: source.Description,
+	}
+	if input.Location != "" {
+		farm.Location = input.Location
+	} else if source.Latitude != nil && source.Longitude != nil {
+		latitude, longitude := *source.Latitude, *source.Longitude
+		farm.Latitude, farm.Longitude = &latitude, &longitude
+	}
+	if input.Description != "" {
+		farm.Description = input.Description
+	}
+	farm.IrrigationSectors = cloneSectors(source.IrrigationSectors, nil)
+
+	if err := s.farms.CreateFarmWithSectors(farm); err != nil {
+		return nil, err
+	}
+	return farm, nil
+}
+
+// cloneSectors copies the sectors whose parent is parentID, with their zones nested, from the
+// source farm's flat sector list
+func cloneSectors(sectors []model.IrrigationSector, parentID *uint) []model.IrrigationSector {
+	var clones []model.IrrigationSector
+	for _, sector := range sectors {
+		if (sector.ParentID == nil) != (parentID == nil) || (parentID != nil && *sector.ParentID != *parentID) {
+			continue
+		}
+		clone := model.IrrigationSector{
+			Name:        sector.Name,
+			Area:        sector.Area,
+			Description: sector.Description,
+			Crop:        sector.Crop,
+			CropStage:   sector.CropStage,
+			Zones:       cloneSectors(sectors, &sector.ID),
+		}
+		if sector.FallbackFlowRate != nil {
+			rate := *sector.FallbackFlowRate
+			clone.FallbackFlowRate = &rate
+		}
+		clones = append(clones, clone)
+	}
+	return clones
+}
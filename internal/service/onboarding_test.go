@@ -0,0 +1,157 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/model"
+)
+
+// stubFarmRepository records the farm it was asked to create and serves one existing farm
+type stubFarmRepository struct {
+	created  *model.Farm
+	existing *model.Farm
+}
+
+func (r *stubFarmRepository) GetFarmWithSectors(farmID uint) (*model.Farm, error) {
+	if r.existing == nil || r.existing.ID != farmID {
+		return nil, nil
+	}
+	return r.existing, nil
+}
+
+func (r *stubFarmRepository) CreateFarmWithSectors(farm *model.Farm) error {
+	farm.ID = 7
+	r.created = farm
+	return nil
+}
+
+func (r *stubFarmRepository) SetArchived(farmID uint, archivedAt *time.Time) (*model.Farm, error) {
+	if r.existing == nil || r.existing.ID != farmID {
+		return nil, nil
+	}
+	if archivedAt == nil || r.existing.ArchivedAt == nil {
+		r.existing.ArchivedAt = archivedAt
+	}
+	return r.existing, nil
+}
+
+func (r *stubFarmRepository) SetAttributionPolicy(farmID uint, policy string) (*model.Farm, error) {
+	if r.existing == nil || r.existing.ID != farmID {
+		return nil, nil
+	}
+	r.existing.AttributionPolicy = policy
+	return r.existing, nil
+}
+
+func (r *stubFarmRepository) SetWaterAccount(farmID uint, account string) (*model.Farm, error) {
+	if r.existing == nil || r.existing.ID != farmID {
+		return nil, nil
+	}
+	r.existing.WaterAccount = account
+	return r.existing, nil
+}
+
+func (r *stubFarmRepository) SetCropYearStart(farmID uint, start string) (*model.Farm, error) {
+	if r.existing == nil || r.existing.ID != farmID {
+		return nil, nil
+	}
+	r.existing.CropYearStart = start
+	return r.existing, nil
+}
+
+func TestOnboardFarm_Validation(t *testing.T) {
+	valid := OnboardFarmInput{
+		Name:      "North Orchard",
+		TotalArea: 10,
+		Sectors:   []OnboardSectorInput{{Name: "A", Area: 6}, {Name: "B", Area: 4}},
+	}
+
+	tests := []struct {
+		name   string
+		modify func(input *OnboardFarmInput)
+	}{
+		{"missing name", func(input *OnboardFarmInput) { input.Name = " " }},
+		{"no sectors", func(input *OnboardFarmInput) { input.Sectors = nil }},
+		{"duplicate sector name", func(input *OnboardFarmInput) { input.Sectors[1].Name = " a" }},
+		{"negative area", func(input *OnboardFarmInput) { input.Sectors[0].Area = -1 }},
+		{"location too long", func(input *OnboardFarmInput) { input.Location = strings.Repeat("x", 256) }},
+		{"sectors exceed farm area", func(input *OnboardFarmInput) { input.Sectors[0].Area = 7 }},
+		{"zone name repeats a sector", func(input *OnboardFarmInput) {
+			input.Sectors[0].Zones = []OnboardSectorInput{{Name: "B", Area: 1}}
+		}},
+		{"zones exceed sector area", func(input *OnboardFarmInput) {
+			input.Sectors[0].Zones = []OnboardSectorInput{{Name: "A1", Area: 4}, {Name: "A2", Area: 3}}
+		}},
+		{"unknown crop", func(input *OnboardFarmInput) { input.Sectors[0].Crop, input.Sectors[0].CropStage = "kale", "mid" }},
+		{"crop without stage", func(input *OnboardFarmInput) { input.Sectors[0].Crop = "tomato" }},
+		{"latitude without longitude", func(input *OnboardFarmInput) { lat := 38.5; input.Latitude = &lat }},
+		{"latitude out of range", func(input *OnboardFarmInput) {
+			lat, lon := 91.0, 0.0
+			input.Latitude, input.Longitude = &lat, &lon
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := valid
+			input.Sectors = append([]OnboardSectorInput(nil), valid.Sectors...)
+			tt.modify(&input)
+
+			repo := &stubFarmRepository{}
+			_, err := NewOnboardingService(repo).OnboardFarm(input)
+			if !errors.Is(err, ErrInvalidOnboarding) {
+				t.Errorf("expected a validation error, got %v", err)
+			}
+			if repo.created != nil {
+				t.Error("expected nothing to be created")
+			}
+		})
+	}
+
+	repo := &stubFarmRepository{}
+	farm, err := NewOnboardingService(repo).OnboardFarm(valid)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if farm.ID != 7 || len(farm.IrrigationSectors) != 2 || farm.IrrigationSectors[1].Area != 4 {
+		t.Errorf("unexpected farm %+v", farm)
+	}
+}
+
+func TestCloneFarm(t *testing.T) {
+	rate := 0.4
+	parentID := uint(11)
+	repo := &stubFarmRepository{existing: &model.Farm{
+		ID:        3,
+		Name:      "Ranch 3",
+		Location:  "Huesca",
+		TotalArea: 40,
+		IrrigationSectors: []model.IrrigationSector{
+			{ID: 11, FarmID: 3, Name: "North", Area: 25, FallbackFlowRate: &rate},
+			{ID: 12, FarmID: 3, Name: "South", Area: 15},
+			{ID: 13, FarmID: 3, Name: "North 1", Area: 10, ParentID: &parentID},
+		},
+	}}
+	svc := NewOnboardingService(repo)
+
+	farm, err := svc.CloneFarm(3, CloneFarmInput{Name: "Ranch 4"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if farm.Name != "Ranch 4" || farm.Location != "Huesca" || farm.TotalArea != 40 {
+		t.Errorf("unexpected farm %+v", farm)
+	}
+	if len(farm.IrrigationSectors) != 2 || farm.IrrigationSectors[0].ID != 0 || *farm.IrrigationSectors[0].FallbackFlowRate != rate {
+		t.Errorf("expected copies of both sectors without their IDs, got %+v", farm.IrrigationSectors)
+	}
+	if zones := farm.IrrigationSectors[0].Zones; len(zones) != 1 || zones[0].Name != "North 1" || zones[0].ParentID != nil {
+		t.Errorf("expected the zone nested under its copied sector, got %+v", zones)
+	}
+
+	if _, err := svc.CloneFarm(9, CloneFarmInput{Name: "Ranch 5"}); !errors.Is(err, ErrFarmNotFound) {
+		t.Errorf("expected ErrFarmNotFound, got %v", err)
+	}
+}
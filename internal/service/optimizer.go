@@ -0,0 +1,289 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"irrigation-analytics/internal/repository"
+)
+
+// ErrFlowRateUnknown is returned for a sector requirement without a flow rate whose sector has no
+// fallback_flow_rate either
+var ErrFlowRateUnknown = errors.New("flow_rate is required for a sector without a fallback_flow_rate")
+
+// OptimizerService defines the interface for planning a week of irrigation at least cost and
+// comparing plans with what was recorded
+type OptimizerService interface {
+	FarmExists(farmID uint) (bool, error)
+	Optimize(farmID uint, input PlanInput) (*IrrigationPlan, error)
+	Variance(farmID uint, plan IrrigationPlan, tolerance VarianceTolerance) (*PlanVariance, error)
+}
+
+// PlanInput is what the optimizer plans a week of irrigation for
+type PlanInput struct {
+	// WeekStart is the UTC midnight the planned week starts at
+	WeekStart time.Time
+	Tariff    Tariff
+	// PumpCapacity is the most liters per minute the sectors running at once may draw together
+	PumpCapacity float64
+	Requirements []SectorRequirement
+}
+
+// SectorRequirement is the water a sector needs over the week. FlowRate, in liters per minute,
+// defaults to the sector's fallback_flow_rate.
+type SectorRequirement struct {
+	SectorID     uint     `json:"sector_id"`
+	WeeklyVolume float64  `json:"weekly_volume"`
+	FlowRate     *float64 `json:"flow_rate,omitempty"`
+}
+
+// IrrigationPlan is a proposed week of irrigation: the runs to make and what they add up to
+type IrrigationPlan struct {
+	FarmID       uint       `json:"farm_id"`
+	Period       PeriodInfo `json:"period"`
+	Tariff       Tariff     `json:"tariff"`
+	PumpCapacity float64    `json:"pump_capacity"`
+	// Feasible is false when some sector's requirement does not fit in the week
+	Feasible bool            `json:"feasible"`
+	Totals   ScenarioTotals  `json:"totals"`
+	Sectors  []PlannedSector `json:"sectors"`
+	Runs     []PlannedRun    `json:"runs"`
+}
+
+// PlannedSector sums a sector's runs against its requirement
+type PlannedSector struct {
+	SectorID        uint    `json:"sector_id"`
+	FlowRate        float64 `json:"flow_rate"`
+	RequiredVolume  float64 `json:"required_volume"`
+	ScheduledVolume float64 `json:"scheduled_volume"`
+	UnmetVolume     float64 `json:"unmet_volume"`
+	RuntimeMinutes  int     `json:"runtime_minutes"`
+	Cost            float64 `json:"cost"`
+	// Note explains why a requirement is not met
+	Note string `json:"note,omitempty"`
+}
+
+// PlannedRun is one uninterrupted irrigation of a sector at its flow rate, within one tariff band
+type PlannedRun struct {
+	SectorID        uint      `json:"sector_id"`
+	Start           time.Time `json:"start"`
+	End             time.Time `json:"end"`
+	DurationMinutes int       `json:"duration_minutes"`
+	Volume          float64   `json:"volume"`
+	OffPeak         bool      `json:"off_peak"`
+	Cost            float64   `json:"cost"`
+}
+
+// planSlot is one hour of the planned week
+type planSlot struct {
+	start   time.Time
+	offPeak bool
+	price   float64
+	// rank orders the hours of a day from the start of their tariff band, so that equally priced
+	// hours are taken night by night rather than all on the first day
+	rank int
+	day  int
+}
+
+// planSector is a sector's state while hours are assigned
+type planSector struct {
+	id        uint
+	flowRate  float64
+	required  float64
+	remaining float64
+}
+
+// optimizerService implements OptimizerService
+type optimizerService struct {
+	repo repository.IrrigationRepository
+}
+
+// NewOptimizerService creates a new optimizer service
+func NewOptimizerService(repo repository.IrrigationRepository) OptimizerService {
+	return &optimizerService{repo: repo}
+}
+
+// FarmExists checks if a farm exists
+func (s *optimizerService) FarmExists(farmID uint) (bool, error) {
+	return s.repo.FarmExists(farmID)
+}
+
+// Optimize plans the week greedily. Hours are taken cheapest first, interleaving the days, and
+// each hour goes to the sectors with the most water still to apply whose flow rate fits in the
+// pump capacity left, running a full hour or until their requirement is met. With one price per
+// tariff band this puts as much water as the pump allows into off-peak hours. A requirement for a
+// sector the farm doesn't have is rejected with ErrSectorNotFound.
+func (s *optimizerService) Optimize(farmID uint, input PlanInput) (*IrrigationPlan, error) {
+	sectors, err := s.repo.ListSectors(farmID)
+	if err != nil {
+		return nil, err
+	}
+	fallback := make(map[uint]*float64, len(sectors))
+	for _, sector := range sectors {
+		fallback[sector.ID] = sector.FallbackFlowRate
+	}
+
+	state := make([]*planSector, 0, len(input.Requirements))
+	for i, requirement := range input.Requirements {
+		flowRate, known := fallback[requirement.SectorID]
+		if !known {
+			return nil, fmt.Errorf("sectors[%d]: %w: %d", i, ErrSectorNotFound, requirement.SectorID)
+		}
+		if requirement.FlowRate != nil {
+			flowRate = requirement.FlowRate
+		}
+		if flowRate == nil || *flowRate <= 0 {
+			return nil, fmt.Errorf("sectors[%d]: %w", i, ErrFlowRateUnknown)
+		}
+		state = append(state, &planSector{
+			id:        requirement.SectorID,
+			flowRate:  *flowRate,
+			required:  requirement.WeeklyVolume,
+			remaining: requirement.WeeklyVolume,
+		})
+	}
+
+	var runs []PlannedRun
+	for _, slot := range planSlots(input.WeekStart, input.Tariff) {
+		candidates := make([]*planSector, 0, len(state))
+		for _, sector := range state {
+			if sector.remaining > 0 {
+				candidates = append(candidates, sector)
+			}
+		}
+		sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].remaining > candidates[j].remaining })
+
+		capacity := input.PumpCapacity
+		for _, sector := range candidates {
+			if sector.flowRate > capacity {
+				continue
+			}
+			capacity -= sector.flowRate
+			minutes := int(math.Min(60, math.Ceil(sector.remaining/sector.flowRate)))
+			volume := math.Min(sector.remaining, float64(minutes)*sector.flowRate)
+			sector.remaining -= volume
+			runs = append(runs, PlannedRun{
+				SectorID:        sector.id,
+				Start:           slot.start,
+				End:             slot.start.Add(time.Duration(minutes) * time.Minute),
+				DurationMinutes: minutes,
+				Volume:          volume,
+				OffPeak:         slot.offPeak,
+			})
+		}
+	}
+	runs = mergeRuns(runs)
+
+	plan := &IrrigationPlan{
+		FarmID:       farmID,
+		Period:       PeriodInfo{StartDate: input.WeekStart, EndDate: input.WeekStart.AddDate(0, 0, 7)},
+		Tariff:       input.Tariff,
+		PumpCapacity: input.PumpCapacity,
+		Feasible:     true,
+		Sectors:      make([]PlannedSector, 0, len(state)),
+		Runs:         runs,
+	}
+	planned := make(map[uint]*PlannedSector, len(state))
+	for _, sector := range state {
+		plan.Sectors = append(plan.Sectors, PlannedSector{
+			SectorID:       sector.id,
+			FlowRate:       sector.flowRate,
+			RequiredVolume: sector.required,
+		})
+	}
+	for i := range plan.Sectors {
+		planned[plan.Sectors[i].SectorID] = &plan.Sectors[i]
+	}
+
+	var peak, offPeak float64
+	for i := range plan.Runs {
+		run := &plan.Runs[i]
+		sector := planned[run.SectorID]
+		if run.OffPeak {
+			offPeak += run.Volume
+			run.Cost = input.Tariff.cost(0, run.Volume)
+		} else {
+			peak += run.Volume
+			run.Cost = input.Tariff.cost(run.Volume, 0)
+		}
+		sector.ScheduledVolume += run.Volume
+		sector.RuntimeMinutes += run.DurationMinutes
+		sector.Cost += run.Cost
+		run.Volume = math.Round(run.Volume*100) / 100
+		run.Cost = math.Round(run.Cost*100) / 100
+	}
+	for i, sector := range state {
+		result := &plan.Sectors[i]
+		result.UnmetVolume = math.Round(sector.remaining*100) / 100
+		if result.UnmetVolume > 0 {
+			plan.Feasible = false
+			result.Note = "requirement does not fit in the week at this flow rate and pump capacity"
+			if sector.flowRate > input.PumpCapacity {
+				result.Note = "flow rate exceeds the pump capacity"
+			}
+		}
+		result.ScheduledVolume = math.Round(result.ScheduledVolume*100) / 100
+		result.Cost = math.Round(result.Cost*100) / 100
+	}
+	plan.Totals = roundedTotals(peak, offPeak, input.Tariff)
+	return plan, nil
+}
+
+// planSlots lists the hours of the week starting at weekStart in the order they are filled:
+// cheapest first, then by rank within the day and by day
+func planSlots(weekStart time.Time, tariff Tariff) []planSlot {
+	slots := make([]planSlot, 0, 7*24)
+	for h := 0; h < 7*24; h++ {
+		start := weekStart.Add(time.Duration(h) * time.Hour)
+		slot := planSlot{start: start, offPeak: tariff.offPeak(start.Hour()), price: tariff.PeakPricePerM3, day: h / 24}
+		bandStart := tariff.OffPeakEndHour
+		if slot.offPeak {
+			slot.price, bandStart = tariff.OffPeakPricePerM3, tariff.OffPeakStartHour
+		}
+		slot.rank = (start.Hour() - bandStart + 24) % 24
+		slots = append(slots, slot)
+	}
+	sort.SliceStable(slots, func(i, j int) bool {
+		a, b := slots[i], slots[j]
+		if a.price != b.price {
+			return a.price < b.price
+		}
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		return a.day < b.day
+	})
+	return slots
+}
+
+// mergeRuns joins each sector's back-to-back runs in the same tariff band and orders the runs by
+// start, then sector
+func mergeRuns(runs []PlannedRun) []PlannedRun {
+	sort.Slice(runs, func(i, j int) bool {
+		if runs[i].SectorID != runs[j].SectorID {
+			return runs[i].SectorID < runs[j].SectorID
+		}
+		return runs[i].Start.Before(runs[j].Start)
+	})
+	merged := make([]PlannedRun, 0, len(runs))
+	for _, run := range runs {
+		last := len(merged) - 1
+		if last >= 0 && merged[last].SectorID == run.SectorID && merged[last].End.Equal(run.Start) && merged[last].OffPeak == run.OffPeak {
+			merged[last].End = run.End
+			merged[last].DurationMinutes += run.DurationMinutes
+			merged[last].Volume += run.Volume
+			continue
+		}
+		merged = append(merged, run)
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		if !merged[i].Start.Equal(merged[j].Start) {
+			return merged[i].Start.Before(merged[j].Start)
+		}
+		return merged[i].SectorID < merged[j].SectorID
+	})
+	return merged
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/model"
+)
+
+// TestOptimize verifies off-peak hours are filled first and merged into nightly runs, that the
+// remainder spills into the cheapest peak hours, and that a sector the pump can't feed is unmet
+func TestOptimize(t *testing.T) {
+	highFlow := 300.0
+	repo := &stubScenarioRepository{
+		stubRepository: &stubRepository{},
+		sectorList:     []model.IrrigationSector{{ID: 1}, {ID: 2, FallbackFlowRate: &highFlow}, {ID: 3}},
+	}
+	week := time.Date(2025, 6, 16, 0, 0, 0, 0, time.UTC)
+	tariff := Tariff{PeakPricePerM3: 0.4, OffPeakPricePerM3: 0.1, OffPeakStartHour: 22, OffPeakEndHour: 6}
+	flowRate := 100.0
+	svc := NewOptimizerService(repo)
+
+	// 60 hours at 100 L/min: the week's 56 off-peak hours and 4 peak hours
+	plan, err := svc.Optimize(1, PlanInput{
+		WeekStart:    week,
+		Tariff:       tariff,
+		PumpCapacity: 250,
+		Requirements: []SectorRequirement{
+			{SectorID: 1, WeeklyVolume: 360000, FlowRate: &flowRate},
+			{SectorID: 2, WeeklyVolume: 1000},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if plan.Feasible {
+		t.Error("expected a sector above the pump capacity to make the plan infeasible")
+	}
+	if plan.Totals != (ScenarioTotals{WaterVolume: 360000, PeakVolume: 24000, OffPeakVolume: 336000, Cost: 43.2}) {
+		t.Errorf("unexpected totals %+v", plan.Totals)
+	}
+	if s := plan.Sectors[1]; s.UnmetVolume != 1000 || s.Note != "flow rate exceeds the pump capacity" || s.FlowRate != 300 {
+		t.Errorf("expected sector 2 unmet, got %+v", s)
+	}
+	if s := plan.Sectors[0]; s.ScheduledVolume != 360000 || s.RuntimeMinutes != 3600 || s.Cost != 43.2 {
+		t.Errorf("unexpected sector 1 %+v", s)
+	}
+
+	// Off-peak: Monday's early hours, six nights across midnight and Sunday's last two hours;
+	// peak: 06:00 on the first four days
+	if len(plan.Runs) != 12 {
+		t.Fatalf("expected 12 runs, got %d: %+v", len(plan.Runs), plan.Runs)
+	}
+	first, second := plan.Runs[0], plan.Runs[1]
+	if !first.Start.Equal(week) || first.DurationMinutes != 360 || !first.OffPeak {
+		t.Errorf("expected an off-peak run from Monday 00:00 to 06:00, got %+v", first)
+	}
+	if !second.Start.Equal(week.Add(6*time.Hour)) || second.DurationMinutes != 60 || second.OffPeak || second.Cost != 2.4 {
+		t.Errorf("expected a peak hour at 06:00 after the night, got %+v", second)
+	}
+	third := plan.Runs[2]
+	if !third.Start.Equal(week.Add(22*time.Hour)) || third.DurationMinutes != 480 || third.Volume != 48000 {
+		t.Errorf("expected the night from 22:00 merged into one run, got %+v", third)
+	}
+
+	// A small requirement runs only the minutes it needs, in the first off-peak hour
+	plan, err = svc.Optimize(1, PlanInput{
+		WeekStart:    week,
+		Tariff:       tariff,
+		PumpCapacity: 250,
+		Requirements: []SectorRequirement{{SectorID: 1, WeeklyVolume: 130, FlowRate: &flowRate}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(plan.Runs) != 1 || !plan.Runs[0].Start.Equal(week.Add(22*time.Hour)) || plan.Runs[0].DurationMinutes != 2 || plan.Runs[0].Volume != 130 || !plan.Feasible {
+		t.Errorf("expected a 2 minute run at 22:00, got %+v", plan.Runs)
+	}
+
+	_, err = svc.Optimize(1, PlanInput{WeekStart: week, Tariff: tariff, PumpCapacity: 250, Requirements: []SectorRequirement{{SectorID: 9, WeeklyVolume: 100}}})
+	if !errors.Is(err, ErrSectorNotFound) {
+		t.Errorf("expected ErrSectorNotFound, got %v", err)
+	}
+	_, err = svc.Optimize(1, PlanInput{WeekStart: week, Tariff: tariff, PumpCapacity: 250, Requirements: []SectorRequirement{{SectorID: 3, WeeklyVolume: 100}}})
+	if !errors.Is(err, ErrFlowRateUnknown) {
+		t.Errorf("expected ErrFlowRateUnknown, got %v", err)
+	}
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// Overlap resolution bounds: the overlapping events read and the groups listed
+const (
+	maxOverlapEvents       = 50000
+	maxListedOverlapGroups = 1000
+)
+
+// Overlap resolution actions
+const (
+	// OverlapActionMerge folds each group into its earliest event and soft-deletes the others
+	OverlapActionMerge = "merge"
+	// OverlapActionFlag annotates every event of a group but its earliest, changing no event
+	OverlapActionFlag = "flag"
+)
+
+// OverlapAnnotationLabel labels the annotations left by OverlapActionFlag
+const OverlapAnnotationLabel = "overlapping event"
+
+// ErrTooManyOverlaps is returned when a range holds more overlapping events than one resolution reads
+var ErrTooManyOverlaps = fmt.Errorf("the range has more than %d overlapping events; resolve a shorter range", maxOverlapEvents)
+
+// OverlapService defines the interface for finding and resolving overlapping events, which
+// duplicate uploads create and which double-count volume
+type OverlapService interface {
+	FarmExists(farmID uint) (bool, error)
+	ResolveOverlaps(farmID uint, sectorID *uint, startDate, endDate time.Time, action string, dryRun bool) (*OverlapResolution, error)
+}
+
+// OverlapResolution reports the overlapping event groups of a range and what was (or with
+// dry_run, would be) done with them
+type OverlapResolution struct {
+	FarmID uint       `json:"farm_id"`
+	Period PeriodInfo `json:"period"`
+	Action string     `json:"action"`
+	DryRun bool       `json:"dry_run"`
+	// Groups and Events count every group and the events in them
+	Groups int `json:"groups"`
+	Events int `json:"events"`
+	// DoubleCountedVolume is the volume in liters counted more than once across the groups, which
+	// merging removes
+	DoubleCountedVolume float64 `json:"double_counted_volume"`
+	// Merged counts the events soft-deleted into their group's survivor, Flagged the events
+	// annotated; events flagged by an earlier run aren't flagged again
+	Merged  int `json:"merged"`
+	Flagged int `json:"flagged"`
+	// OverlapGroups lists the first 1,000 groups by sector and start time
+	OverlapGroups []OverlapGroup `json:"overlap_groups"`
+	Truncated     bool           `json:"truncated,omitempty"`
+}
+
+// OverlapGroup is a run of events of one sector whose intervals overlap in a chain. The survivor
+// is its earliest event; merged, it spans the group with the volume of the union of the intervals.
+type OverlapGroup struct {
+	SectorID     uint      `json:"sector_id"`
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	EventIDs     []uint    `json:"event_ids"`
+	SurvivorID   uint      `json:"survivor_id"`
+	Volume       float64   `json:"volume"`
+	MergedVolume float64   `json:"merged_volume"`
+}
+
+// overlapService implements OverlapService
+type overlapService struct {
+	repo        repository.IrrigationRepository
+	annotations repository.AnnotationRepository
+}
+
+// NewOverlapService creates a new overlap service
+func NewOverlapService(repo repository.IrrigationRepository, annotations repository.AnnotationRepository) OverlapService {
+	return &overlapService{repo: repo, annotations: annotations}
+}
+
+// FarmExists checks if a farm exists
+func (s *overlapService) FarmExists(farmID uint) (bool, error) {
+	return s.repo.FarmExists(farmID)
+}
+
+// ResolveOverlaps groups the farm's events starting in [startDate, endDate) whose intervals
+// overlap another event of their sector, and merges or flags each group. Merging keeps the
+// earliest event, extends it to the group's end and adds the part of every other event's volume,
+// real and nominal amounts that falls outside the time already covered, prorated by time; the
+// other events are soft-deleted in one transaction. Flagging leaves the events alone and adds an
+// OverlapAnnotationLabel annotation to each event but the earliest. Nothing is written on a dry run.
+func (s *overlapService) ResolveOverlaps(farmID uint, sectorID *uint, startDate, endDate time.Time, action string, dryRun bool) (*OverlapResolution, error) {
+	events, err := s.repo.ListOverlappingEvents(farmID, sectorID, startDate, endDate, maxOverlapEvents+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) > maxOverlapEvents {
+		return nil, ErrTooManyOverlaps
+	}
+
+	result := &OverlapResolution{
+		FarmID:        farmID,
+		Period:        PeriodInfo{StartDate: startDate, EndDate: endDate},
+		Action:        action,
+		DryRun:        dryRun,
+		OverlapGroups: []OverlapGroup{},
+	}
+	groups := groupOverlaps(events)
+	merges := make([]repository.EventMerge, 0, len(groups))
+	var doubleCounted float64
+	for _, group := range groups {
+		merge := mergeOverlap(group)
+		merges = append(merges, merge)
+
+		summary := OverlapGroup{
+			SectorID:     merge.Survivor.IrrigationSectorID,
+			Start:        merge.Survivor.StartTime,
+			End:          merge.Survivor.EndTime,
+			EventIDs:     make([]uint, 0, len(group)),
+			SurvivorID:   merge.Survivor.ID,
+			MergedVolume: math.Round(merge.Survivor.WaterVolume*100) / 100,
+		}
+		for _, event := range group {
+			summary.EventIDs = append(summary.EventIDs, event.ID)
+			summary.Volume += event.WaterVolume
+		}
+		doubleCounted += summary.Volume - merge.Survivor.WaterVolume
+		summary.Volume = math.Round(summary.Volume*100) / 100
+
+		result.Groups++
+		result.Events += len(group)
+		if len(result.OverlapGroups) < maxListedOverlapGroups {
+			result.OverlapGroups = append(result.OverlapGroups, summary)
+		} else {
+			result.Truncated = true
+		}
+	}
+	result.DoubleCountedVolume = math.Round(doubleCounted*100) / 100
+
+	switch action {
+	case OverlapActionMerge:
+		for _, merge := range merges {
+			result.Merged += len(merge.RemovedIDs)
+		}
+		if !dryRun && len(merges) > 0 {
+			if err := s.repo.MergeEvents(farmID, merges); err != nil {
+				return nil, err
+			}
+		}
+	case OverlapActionFlag:
+		flagged, err := s.flagOverlaps(farmID, startDate, endDate, groups, dryRun)
+		if err != nil {
+			return nil, err
+		}
+		result.Flagged = flagged
+	default:
+		return nil, fmt.Errorf("unknown overlap action %q", action)
+	}
+	return result, nil
+}
+
+// groupOverlaps splits events ordered by sector and start time into groups of overlapping events.
+// An event joins the current group while it starts before the group's latest end.
+func groupOverlaps(events []model.IrrigationData) [][]model.IrrigationData {
+	var groups [][]model.IrrigationData
+	var current []model.IrrigationData
+	var coveredUntil time.Time
+	flush := func() {
+		if len(current) > 1 {
+			groups = append(groups, current)
+		}
+		current = nil
+	}
+	for _, event := range events {
+		if len(current) > 0 && (event.IrrigationSectorID != current[0].IrrigationSectorID || !event.StartTime.Before(coveredUntil)) {
+			flush()
+		}
+		if len(current) == 0 || event.EndTime.After(coveredUntil) {
+			coveredUntil = event.EndTime
+		}
+		current = append(current, event)
+	}
+	flush()
+	return groups
+}
+
+// mergeOverlap folds a group into its first event. Each later event contributes the share of its
+// amounts recorded after the time the group already covers, so overlapping time counts once.
+func mergeOverlap(group []model.IrrigationData) repository.EventMerge {
+	survivor := group[0]
+	if survivor.NominalAmount != nil {
+		nominal := *survivor.NominalAmount
+		survivor.NominalAmount = &nominal
+	}
+	merge := repository.EventMerge{RemovedIDs: make([]uint, 0, len(group)-1)}
+	for _, event := range group[1:] {
+		merge.RemovedIDs = append(merge.RemovedIDs, event.ID)
+		if !event.EndTime.After(survivor.EndTime) {
+			continue
+		}
+		share := float64(event.EndTime.Sub(survivor.EndTime)) / float64(event.EndTime.Sub(event.StartTime))
+		survivor.WaterVolume += share * event.WaterVolume
+		survivor.RealAmount += share * event.RealAmount
+		if event.NominalAmount != nil {
+			if survivor.NominalAmount == nil {
+				survivor.NominalAmount = new(float64)
+			}
+			*survivor.NominalAmount += share * *event.NominalAmount
+		}
+		survivor.EndTime = event.EndTime
+	}
+	survivor.Duration = int(survivor.EndTime.Sub(survivor.StartTime).Minutes())
+	merge.Survivor = survivor
+	return merge
+}
+
+// flagOverlaps annotates every event of each group but the first, skipping events an earlier run
+// already flagged, and returns how many events are (or on a dry run, would be) flagged
+func (s *overlapService) flagOverlaps(farmID uint, startDate, endDate time.Time, groups [][]model.IrrigationData, dryRun bool) (int, error) {
+	if len(groups) == 0 {
+		return 0, nil
+	}
+	existing, err := s.annotations.List(farmID, startDate, endDate)
+	if err != nil {
+		return 0, err
+	}
+	flagged := make(map[uint]bool)
+	for _, annotation := range existing {
+		if annotation.Label == OverlapAnnotationLabel && annotation.IrrigationDataID != nil {
+			flagged[*annotation.IrrigationDataID] = true
+		}
+	}
+
+	count := 0
+	for _, group := range groups {
+		ids := make([]string, 0, len(group))
+		for _, event := range group {
+			ids = append(ids, strconv.FormatUint(uint64(event.ID), 10))
+		}
+		for _, event := range group[1:] {
+			if flagged[event.ID] {
+				continue
+			}
+			count++
+			if dryRun {
+				continue
+			}
+			eventID, sectorID := event.ID, event.IrrigationSectorID
+			err := s.annotations.Create(&model.Annotation{
+				FarmID:             farmID,
+				IrrigationDataID:   &eventID,
+				IrrigationSectorID: &sectorID,
+				Date:               truncateToDay(event.StartTime),
+				Label:              OverlapAnnotationLabel,
+				Note:               fmt.Sprintf("overlaps event %d in the group of events %s", group[0].ID, strings.Join(ids, ", ")),
+			})
+			if err != nil {
+				return count, err
+			}
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// stubOverlapRepository serves fixed overlapping events and records merges
+type stubOverlapRepository struct {
+	repository.IrrigationRepository
+	events []model.IrrigationData
+	merged []repository.EventMerge
+}
+
+func (r *stubOverlapRepository) ListOverlappingEvents(farmID uint, sectorID *uint, startDate, endDate time.Time, limit int) ([]model.IrrigationData, error) {
+	return r.events, nil
+}
+
+func (r *stubOverlapRepository) MergeEvents(farmID uint, merges []repository.EventMerge) error {
+	r.merged = merges
+	return nil
+}
+
+// recordingAnnotationRepository serves fixed annotations and records created ones
+type recordingAnnotationRepository struct {
+	stubAnnotationRepository
+	created []model.Annotation
+}
+
+func (r *recordingAnnotationRepository) Create(annotation *model.Annotation) error {
+	r.created = append(r.created, *annotation)
+	return nil
+}
+
+// overlapEvent builds an event of the sector running for minutes from start
+func overlapEvent(id, sectorID uint, start time.Time, minutes int, volume float64) model.IrrigationData {
+	nominal := volume
+	return model.IrrigationData{
+		ID:                 id,
+		IrrigationSectorID: sectorID,
+		StartTime:          start,
+		EndTime:            start.Add(time.Duration(minutes) * time.Minute),
+		Duration:           minutes,
+		WaterVolume:        volume,
+		RealAmount:         volume,
+		NominalAmount:      &nominal,
+	}
+}
+
+func TestResolveOverlaps_Merge(t *testing.T) {
+	day := time.Date(2025, 6, 1, 6, 0, 0, 0, time.UTC)
+	repo := &stubOverlapRepository{events: []model.IrrigationData{
+		// An exact duplicate upload
+		overlapEvent(1, 1, day, 60, 600),
+		overlapEvent(4, 1, day, 60, 600),
+		// A re-upload shifted by 30 minutes, chained to a third event
+		overlapEvent(2, 1, day.Add(3*time.Hour), 60, 1200),
+		overlapEvent(5, 1, day.Add(3*time.Hour+30*time.Minute), 60, 1200),
+		overlapEvent(6, 1, day.Add(4*time.Hour+15*time.Minute), 30, 300),
+		// Same times in another sector
+		overlapEvent(3, 2, day, 60, 500),
+		overlapEvent(7, 2, day.Add(30*time.Minute), 60, 500),
+	}}
+	svc := NewOverlapService(repo, &recordingAnnotationRepository{})
+
+	preview, err := svc.ResolveOverlaps(1, nil, day, day.AddDate(0, 0, 1), OverlapActionMerge, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if preview.Groups != 3 || preview.Events != 7 || preview.Merged != 4 || repo.merged != nil {
+		t.Fatalf("expected 3 groups previewed without writing, got %+v", preview)
+	}
+	// 600 of the duplicate, 750 of the shifted chain (1950 kept of 2700) and 250 of the other sector
+	if preview.DoubleCountedVolume != 1600 {
+		t.Errorf("expected 1600 L double-counted, got %v", preview.DoubleCountedVolume)
+	}
+
+	shifted := preview.OverlapGroups[1]
+	if shifted.SurvivorID != 2 || len(shifted.EventIDs) != 3 || shifted.Volume != 2700 || shifted.MergedVolume != 1950 ||
+		!shifted.End.Equal(day.Add(4*time.Hour+45*time.Minute)) {
+		t.Errorf("unexpected shifted group %+v", shifted)
+	}
+
+	if _, err := svc.ResolveOverlaps(1, nil, day, day.AddDate(0, 0, 1), OverlapActionMerge, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.merged) != 3 {
+		t.Fatalf("expected 3 merges written, got %d", len(repo.merged))
+	}
+	survivor := repo.merged[1].Survivor
+	if survivor.ID != 2 || survivor.Duration != 105 || survivor.WaterVolume != 1950 || *survivor.NominalAmount != 1950 ||
+		len(repo.merged[1].RemovedIDs) != 2 || repo.merged[1].RemovedIDs[0] != 5 {
+		t.Errorf("unexpected merge %+v", repo.merged[1])
+	}
+	if *repo.events[2].NominalAmount != 1200 {
+		t.Error("expected the loaded event's nominal amount left alone")
+	}
+}
+
+func TestResolveOverlaps_Flag(t *testing.T) {
+	day := time.Date(2025, 6, 1, 6, 0, 0, 0, time.UTC)
+	repo := &stubOverlapRepository{events: []model.IrrigationData{
+		overlapEvent(1, 1, day, 60, 600),
+		overlapEvent(4, 1, day.Add(10*time.Minute), 60, 600),
+		overlapEvent(8, 1, day.Add(20*time.Minute), 60, 600),
+	}}
+	flaggedID := uint(4)
+	annotations := &recordingAnnotationRepository{stubAnnotationRepository: stubAnnotationRepository{
+		annotations: []model.Annotation{{IrrigationDataID: &flaggedID, Label: OverlapAnnotationLabel}},
+	}}
+	svc := NewOverlapService(repo, annotations)
+
+	result, err := svc.ResolveOverlaps(1, nil, day, day.AddDate(0, 0, 1), OverlapActionFlag, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Flagged != 1 || len(annotations.created) != 1 || repo.merged != nil {
+		t.Fatalf("expected only the unflagged duplicate annotated, got %+v (%d created)", result, len(annotations.created))
+	}
+	created := annotations.created[0]
+	if *created.IrrigationDataID != 8 || created.Label != OverlapAnnotationLabel || created.Note != "overlaps event 1 in the group of events 1, 4, 8" {
+		t.Errorf("unexpected annotation %+v", created)
+	}
+}
+
+func TestGroupOverlaps(t *testing.T) {
+	day := time.Date(2025, 6, 1, 6, 0, 0, 0, time.UTC)
+	groups := groupOverlaps([]model.IrrigationData{
+		overlapEvent(1, 1, day, 120, 100),
+		overlapEvent(2, 1, day.Add(30*time.Minute), 30, 100),
+		// Starts as the first event ends, so it only touches the group
+		overlapEvent(3, 1, day.Add(2*time.Hour), 60, 100),
+		overlapEvent(4, 1, day.Add(2*time.Hour+30*time.Minute), 60, 100),
+	})
+	if len(groups) != 2 || len(groups[0]) != 2 || groups[1][0].ID != 3 {
+		t.Errorf("expected touching intervals to start a new group, got %+v", groups)
+	}
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ImportFormatJSON is the payload format of rows received by the JSON bulk import; spreadsheet
+// rows are stored as ImportFormatCSV or ImportFormatXLSX
+const ImportFormatJSON = "json"
+
+// ErrUnknownPayloadFormat is returned when re-parsing a payload in a format this version can't read
+var ErrUnknownPayloadFormat = errors.New("unknown payload format")
+
+// UnmarshalJSON decodes a JSON import row, keeping the row as received for its event's payload
+func (row *ImportRow) UnmarshalJSON(data []byte) error {
+	type fields ImportRow
+	if err := json.Unmarshal(data, (*fields)(row)); err != nil {
+		return err
+	}
+	row.payload = append([]byte(nil), data...)
+	row.payloadFormat = ImportFormatJSON
+	return nil
+}
+
+// recordPayload encodes a spreadsheet record as an object of its cells keyed by normalised column
+// name. Every named column is kept, including those the import ignores.
+func recordPayload(columns map[string]int, field func(name string) string) ([]byte, error) {
+	cells := make(map[string]string, len(columns))
+	for name := range columns {
+		cells[name] = field(name)
+	}
+	return json.Marshal(cells)
+}
+
+// ReparsePayload parses an event's stored payload again with the current row mapping, so a
+// reprocessing job can rebuild events after a mapping fix. Rows that no longer parse return
+// an error carrying the rejection reason; validation against the farm is left to the caller.
+func ReparsePayload(format string, payload []byte) (ImportRow, error) {
+	switch format {
+	case ImportFormatJSON:
+		var row ImportRow
+		if err := json.Unmarshal(payload, &row); err != nil {
+			return ImportRow{}, err
+		}
+		return row, nil
+	case ImportFormatCSV, ImportFormatXLSX:
+		var cells map[string]string
+		if err := json.Unmarshal(payload, &cells); err != nil {
+			return ImportRow{}, err
+		}
+		row, reason := parseImportRow(func(name string) string { return cells[name] }, format == ImportFormatXLSX)
+		if reason != "" {
+			return ImportRow{}, errors.New(reason)
+		}
+		row.payload, row.payloadFormat = payload, format
+		return row, nil
+	}
+	return ImportRow{}, fmt.Errorf("%w %q", ErrUnknownPayloadFormat, format)
+}
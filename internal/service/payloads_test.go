@@ -0,0 +1,54 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestPayloads_JSONRow verifies a JSON row keeps its payload, unknown fields included, and re-parses
+func TestPayloads_JSONRow(t *testing.T) {
+	var body struct {
+		Events []ImportRow `json:"events"`
+	}
+	data := `{"events": [{"sector_id": 1, "start_time": "2025-07-01T06:00:00Z", "end_time": "2025-07-01T07:00:00Z", "water_volume": 420.5, "real_amount": 380, "meter_serial": "A-17"}]}`
+	if err := json.Unmarshal([]byte(data), &body); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	event := body.Events[0].toEvent(1)
+	if event.PayloadFormat != ImportFormatJSON || !strings.Contains(string(event.RawPayload), `"meter_serial": "A-17"`) {
+		t.Errorf("expected the row as received, got %s %s", event.PayloadFormat, event.RawPayload)
+	}
+
+	row, err := ReparsePayload(event.PayloadFormat, event.RawPayload)
+	if err != nil || row.SectorID != 1 || row.WaterVolume != 420.5 {
+		t.Errorf("expected the row to re-parse, got %+v, %v", row, err)
+	}
+}
+
+// TestPayloads_SpreadsheetRow verifies spreadsheet rows keep every named column and re-parse
+func TestPayloads_SpreadsheetRow(t *testing.T) {
+	data := "sector_id,start_time,end_time,water_volume,real_amount,Valve Note\n" +
+		"1,2021-03-01T06:00:00Z,2021-03-01 07:30:00,420.5,380,stuck open\n"
+	file, err := ParseImportCSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	event := file.Rows[0].toEvent(1)
+	var cells map[string]string
+	if err := json.Unmarshal(event.RawPayload, &cells); err != nil || cells["valve_note"] != "stuck open" || cells["end_time"] != "2021-03-01 07:30:00" {
+		t.Errorf("expected every cell in the payload, got %s (%v)", event.RawPayload, err)
+	}
+
+	row, err := ReparsePayload(ImportFormatCSV, event.RawPayload)
+	if err != nil || row.RealAmount != 380 || !row.EndTime.Equal(file.Rows[0].EndTime) {
+		t.Errorf("expected the row to re-parse, got %+v, %v", row, err)
+	}
+
+	if _, err := ReparsePayload("xml", event.RawPayload); !errors.Is(err, ErrUnknownPayloadFormat) {
+		t.Errorf("expected ErrUnknownPayloadFormat, got %v", err)
+	}
+}
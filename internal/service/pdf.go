@@ -0,0 +1,133 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// A4 page size and margin in PDF points
+const (
+	pdfPageWidth  = 595.0
+	pdfPageHeight = 842.0
+	pdfMargin     = 50.0
+)
+
+// PDF font resource names: the standard Helvetica faces, which viewers supply themselves
+const (
+	pdfFontRegular = "F1"
+	pdfFontBold    = "F2"
+)
+
+// pdfDocument builds a PDF of text, lines and filled rectangles on A4 pages. It covers what the
+// irrigation report needs and nothing more: no images, no embedded fonts, WinAnsi text only.
+type pdfDocument struct {
+	pages   []*bytes.Buffer
+	current int
+}
+
+// newPage starts a new page, which later drawing calls write to
+func (d *pdfDocument) newPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.current = len(d.pages) - 1
+}
+
+// selectPage makes later drawing calls write to an earlier page, zero-based
+func (d *pdfDocument) selectPage(i int) {
+	d.current = i
+}
+
+// page returns the current page's content stream
+func (d *pdfDocument) page() *bytes.Buffer {
+	if len(d.pages) == 0 {
+		d.newPage()
+	}
+	return d.pages[d.current]
+}
+
+// text writes s with its baseline starting at (x, y), y measured from the bottom of the page
+func (d *pdfDocument) text(x, y, size float64, font, s string) {
+	fmt.Fprintf(d.page(), "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfString(s))
+}
+
+// textRight writes s ending at x, using Helvetica's average glyph width to estimate its length
+func (d *pdfDocument) textRight(x, y, size float64, font, s string) {
+	d.text(x-pdfTextWidth(s, size), y, size, font, s)
+}
+
+// rect fills a rectangle with its lower left corner at (x, y) in the given gray, 0 being black
+func (d *pdfDocument) rect(x, y, w, h, gray float64) {
+	fmt.Fprintf(d.page(), "%.3f g %.2f %.2f %.2f %.2f re f 0 g\n", gray, x, y, w, h)
+}
+
+// line strokes a thin line from (x1, y1) to (x2, y2) in the given gray
+func (d *pdfDocument) line(x1, y1, x2, y2, gray float64) {
+	fmt.Fprintf(d.page(), "%.3f G 0.5 w %.2f %.2f m %.2f %.2f l S 0 G\n", gray, x1, y1, x2, y2)
+}
+
+// bytes serializes the document: catalog, page tree, the two fonts, then each page and its
+// content stream, followed by the cross-reference table the format requires
+func (d *pdfDocument) bytes() []byte {
+	if len(d.pages) == 0 {
+		d.newPage()
+	}
+
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	// Objects 1-4 are fixed; page n's dictionary is 5+2n and its content stream 6+2n
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, content := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /%s 3 0 R /%s 4 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, pdfFontRegular, pdfFontBold, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.Bytes()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
+
+// pdfString escapes s for a PDF literal string. Latin-1 characters map onto WinAnsiEncoding;
+// anything else is replaced by '?'.
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '–' || r == '—':
+			b.WriteByte('-')
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// pdfTextWidth estimates the width of s in Helvetica at size; digits, which dominate the report's
+// right-aligned columns, are exactly 0.556 em
+func pdfTextWidth(s string, size float64) float64 {
+	return float64(len([]rune(s))) * 0.556 * size
+}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// Plan variance bounds: the events compared and the deviations listed
+const (
+	maxVarianceEvents     = 50000
+	maxVarianceDeviations = 1000
+)
+
+// ErrTooManyEvents is returned when a plan's period holds more events than a variance report reads
+var ErrTooManyEvents = fmt.Errorf("the plan period has more than %d events; compare a shorter plan", maxVarianceEvents)
+
+// Deviation types
+const (
+	// DeviationUnplannedEvent is an event outside every planned run of its sector
+	DeviationUnplannedEvent = "unplanned_event"
+	// DeviationMissedRun is a planned run no event of its sector overlaps
+	DeviationMissedRun = "missed_run"
+)
+
+// VarianceTolerance decides when actual irrigation deviates from a plan
+type VarianceTolerance struct {
+	// TimingMinutes widens each planned run on both sides when matching events to it
+	TimingMinutes int
+	// Volume is the fraction a week's actual volume may differ from its planned volume
+	Volume float64
+}
+
+// PlanVariance compares a plan with the irrigation recorded over its period, per sector and week
+type PlanVariance struct {
+	FarmID                 uint                 `json:"farm_id"`
+	Period                 PeriodInfo           `json:"period"`
+	Tariff                 Tariff               `json:"tariff"`
+	TimingToleranceMinutes int                  `json:"timing_tolerance_minutes"`
+	VolumeTolerance        float64              `json:"volume_tolerance"`
+	Totals                 VarianceTotals       `json:"totals"`
+	Weeks                  []SectorWeekVariance `json:"weeks"`
+	// Deviations lists the first 1,000 unplanned events and missed runs in start time order
+	Deviations []PlanDeviation `json:"deviations"`
+	Truncated  bool            `json:"truncated,omitempty"`
+}
+
+// VarianceTotals are planned and actual volumes in liters and their cost under the plan's
+// tariff; the variances are actual minus planned
+type VarianceTotals struct {
+	PlannedVolume  float64 `json:"planned_volume"`
+	ActualVolume   float64 `json:"actual_volume"`
+	VolumeVariance float64 `json:"volume_variance"`
+	PlannedCost    float64 `json:"planned_cost"`
+	ActualCost     float64 `json:"actual_cost"`
+	CostVariance   float64 `json:"cost_variance"`
+}
+
+// SectorWeekVariance compares one sector's plan and recorded irrigation over one week of the plan.
+// Weeks are counted from the plan's start date.
+type SectorWeekVariance struct {
+	SectorID  uint      `json:"sector_id"`
+	WeekStart time.Time `json:"week_start"`
+	VarianceTotals
+	// VolumeVariancePercent is nil when nothing was planned
+	VolumeVariancePercent *float64 `json:"volume_variance_percent"`
+	PlannedMinutes        int      `json:"planned_minutes"`
+	ActualMinutes         int      `json:"actual_minutes"`
+	PlannedRuns           int      `json:"planned_runs"`
+	MissedRuns            int      `json:"missed_runs"`
+	Events                int      `json:"events"`
+	UnplannedEvents       int      `json:"unplanned_events"`
+	// Deviating is set when the volume is off by more than the tolerance, or a run was missed,
+	// or an event fell outside the planned runs
+	Deviating bool `json:"deviating"`
+}
+
+// PlanDeviation is an unplanned event or a missed run; Volume is the event's recorded or the
+// run's planned volume
+type PlanDeviation struct {
+	Type      string    `json:"type"`
+	SectorID  uint      `json:"sector_id"`
+	WeekStart time.Time `json:"week_start"`
+	EventID   *uint     `json:"event_id,omitempty"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Volume    float64   `json:"volume"`
+}
+
+// varianceKey identifies a sector's week
+type varianceKey struct {
+	sectorID uint
+	week     int
+}
+
+// Variance matches the recorded events of the plan's period to its runs. An event is on plan
+// when it overlaps one of its sector's runs widened by the timing tolerance; every run it
+// overlaps counts as made. Actual cost prices each event's volume by the hour it starts, or ends
+// under the farm's end attribution policy. Sectors with events but no runs are compared with a
+// plan of nothing. A run for a sector the farm doesn't have is rejected with ErrSectorNotFound.
+func (s *optimizerService) Variance(farmID uint, plan IrrigationPlan, tolerance VarianceTolerance) (*PlanVariance, error) {
+	sectors, err := s.repo.ListSectors(farmID)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[uint]bool, len(sectors))
+	for _, sector := range sectors {
+		known[sector.ID] = true
+	}
+	for i, run := range plan.Runs {
+		if !known[run.SectorID] {
+			return nil, fmt.Errorf("runs[%d]: %w: %d", i, ErrSectorNotFound, run.SectorID)
+		}
+	}
+
+	queryOpts, err := withAttribution(s.repo, farmID, repository.QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.repo.ListEvents(farmID, plan.Period.StartDate, plan.Period.EndDate, maxVarianceEvents+1, queryOpts)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) > maxVarianceEvents {
+		return nil, ErrTooManyEvents
+	}
+
+	start := plan.Period.StartDate
+	week := func(t time.Time) int { return int(t.Sub(start) / (7 * 24 * time.Hour)) }
+	weeks := make(map[varianceKey]*SectorWeekVariance)
+	row := func(sectorID uint, t time.Time) *SectorWeekVariance {
+		key := varianceKey{sectorID, week(t)}
+		if weeks[key] == nil {
+			weeks[key] = &SectorWeekVariance{SectorID: sectorID, WeekStart: start.AddDate(0, 0, 7*key.week)}
+		}
+		return weeks[key]
+	}
+	var deviations []PlanDeviation
+
+	runsBySector := make(map[uint][]PlannedRun)
+	for _, run := range plan.Runs {
+		runsBySector[run.SectorID] = append(runsBySector[run.SectorID], run)
+		r := row(run.SectorID, run.Start)
+		r.PlannedRuns++
+		r.PlannedVolume += run.Volume
+		r.PlannedMinutes += int(run.End.Sub(run.Start).Minutes())
+		r.PlannedCost += bandCost(plan.Tariff, run.Start.UTC().Hour(), run.Volume)
+	}
+
+	slack := time.Duration(tolerance.TimingMinutes) * time.Minute
+	made := make(map[uint][]bool, len(runsBySector))
+	for sectorID, runs := range runsBySector {
+		made[sectorID] = make([]bool, len(runs))
+	}
+	for i := range events {
+		event := &events[i]
+		sectorID := event.IrrigationSectorID
+		eventTime := event.StartTime
+		if queryOpts.Attribution == model.AttributionEnd {
+			eventTime = event.EndTime
+		}
+		r := row(sectorID, eventTime)
+		r.Events++
+		r.ActualVolume += event.WaterVolume
+		r.ActualMinutes += event.Duration
+		r.ActualCost += bandCost(plan.Tariff, eventTime.UTC().Hour(), event.WaterVolume)
+
+		planned := false
+		for j, run := range runsBySector[sectorID] {
+			if event.StartTime.Before(run.End.Add(slack)) && event.EndTime.After(run.Start.Add(-slack)) {
+				made[sectorID][j] = true
+				planned = true
+			}
+		}
+		if !planned {
+			r.UnplannedEvents++
+			id := event.ID
+			deviations = append(deviations, PlanDeviation{
+				Type: DeviationUnplannedEvent, SectorID: sectorID, WeekStart: r.WeekStart,
+				EventID: &id, Start: event.StartTime, End: event.EndTime, Volume: event.WaterVolume,
+			})
+		}
+	}
+	for sectorID, runs := range runsBySector {
+		for j, run := range runs {
+			if made[sectorID][j] {
+				continue
+			}
+			r := row(sectorID, run.Start)
+			r.MissedRuns++
+			deviations = append(deviations, PlanDeviation{
+				Type: DeviationMissedRun, SectorID: sectorID, WeekStart: r.WeekStart,
+				Start: run.Start, End: run.End, Volume: run.Volume,
+			})
+		}
+	}
+
+	response := &PlanVariance{
+		FarmID:                 farmID,
+		Period:                 plan.Period,
+		Tariff:                 plan.Tariff,
+		TimingToleranceMinutes: tolerance.TimingMinutes,
+		VolumeTolerance:        tolerance.Volume,
+		Weeks:                  make([]SectorWeekVariance, 0, len(weeks)),
+	}
+	var totals VarianceTotals
+	for _, r := range weeks {
+		totals.PlannedVolume += r.PlannedVolume
+		totals.ActualVolume += r.ActualVolume
+		totals.PlannedCost += r.PlannedCost
+		totals.ActualCost += r.ActualCost
+
+		r.VolumeVariancePercent = changePercent(r.ActualVolume, r.PlannedVolume)
+		offVolume := r.PlannedVolume > 0 && math.Abs(r.ActualVolume-r.PlannedVolume) > tolerance.Volume*r.PlannedVolume
+		r.Deviating = offVolume || r.MissedRuns > 0 || r.UnplannedEvents > 0
+		r.VarianceTotals = roundedVariance(r.VarianceTotals)
+		response.Weeks = append(response.Weeks, *r)
+	}
+	response.Totals = roundedVariance(totals)
+	sort.Slice(response.Weeks, func(i, j int) bool {
+		a, b := response.Weeks[i], response.Weeks[j]
+		if !a.WeekStart.Equal(b.WeekStart) {
+			return a.WeekStart.Before(b.WeekStart)
+		}
+		return a.SectorID < b.SectorID
+	})
+
+	sort.SliceStable(deviations, func(i, j int) bool {
+		if !deviations[i].Start.Equal(deviations[j].Start) {
+			return deviations[i].Start.Before(deviations[j].Start)
+		}
+		return deviations[i].SectorID < deviations[j].SectorID
+	})
+	if len(deviations) > maxVarianceDeviations {
+		deviations, response.Truncated = deviations[:maxVarianceDeviations], true
+	}
+	response.Deviations = deviations
+	if response.Deviations == nil {
+		response.Deviations = []PlanDeviation{}
+	}
+	return response, nil
+}
+
+// bandCost prices a volume in liters used at the given UTC hour
+func bandCost(tariff Tariff, hour int, volume float64) float64 {
+	if tariff.offPeak(hour) {
+		return tariff.cost(0, volume)
+	}
+	return tariff.cost(volume, 0)
+}
+
+// roundedVariance computes the variances and rounds everything to 2 decimals
+func roundedVariance(totals VarianceTotals) VarianceTotals {
+	return VarianceTotals{
+		PlannedVolume:  math.Round(totals.PlannedVolume*100) / 100,
+		ActualVolume:   math.Round(totals.ActualVolume*100) / 100,
+		VolumeVariance: math.Round((totals.ActualVolume-totals.PlannedVolume)*100) / 100,
+		PlannedCost:    math.Round(totals.PlannedCost*100) / 100,
+		ActualCost:     math.Round(totals.ActualCost*100) / 100,
+		CostVariance:   math.Round((totals.ActualCost-totals.PlannedCost)*100) / 100,
+	}
+}
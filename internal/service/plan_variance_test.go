@@ -0,0 +1,105 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// stubVarianceRepository serves fixed sectors and events
+type stubVarianceRepository struct {
+	*stubScenarioRepository
+	events []model.IrrigationData
+}
+
+func (r *stubVarianceRepository) ListEvents(farmID uint, startDate, endDate time.Time, limit int, opts repository.QueryOptions) ([]model.IrrigationData, error) {
+	return r.events, nil
+}
+
+func varianceEvent(id, sectorID uint, start time.Time, minutes int, volume float64) model.IrrigationData {
+	return model.IrrigationData{
+		ID: id, IrrigationSectorID: sectorID, StartTime: start, EndTime: start.Add(time.Duration(minutes) * time.Minute),
+		Duration: minutes, WaterVolume: volume,
+	}
+}
+
+// TestPlanVariance verifies events are matched to runs within the timing tolerance, per sector
+// and plan week, and that unplanned events and missed runs are listed in start order
+func TestPlanVariance(t *testing.T) {
+	week := time.Date(2025, 6, 16, 0, 0, 0, 0, time.UTC)
+	at := func(day, hour, minute int) time.Time {
+		return week.AddDate(0, 0, day).Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+	repo := &stubVarianceRepository{
+		stubScenarioRepository: &stubScenarioRepository{
+			stubRepository: &stubRepository{},
+			sectorList:     []model.IrrigationSector{{ID: 1}, {ID: 2}},
+		},
+		events: []model.IrrigationData{
+			varianceEvent(1, 1, at(0, 22, 30), 180, 20000),
+			varianceEvent(2, 1, at(3, 12, 0), 60, 5000),
+			varianceEvent(3, 1, at(7, 21, 30), 25, 6000),
+			varianceEvent(4, 2, at(8, 8, 0), 60, 1000),
+		},
+	}
+	plan := IrrigationPlan{
+		Period: PeriodInfo{StartDate: week, EndDate: week.AddDate(0, 0, 14)},
+		Tariff: Tariff{PeakPricePerM3: 0.4, OffPeakPricePerM3: 0.1, OffPeakStartHour: 22, OffPeakEndHour: 6},
+		Runs: []PlannedRun{
+			{SectorID: 1, Start: at(0, 22, 0), End: at(1, 2, 0), Volume: 24000},
+			{SectorID: 1, Start: at(2, 22, 0), End: at(3, 2, 0), Volume: 24000},
+			{SectorID: 1, Start: at(7, 22, 0), End: at(7, 23, 0), Volume: 6000},
+		},
+	}
+
+	variance, err := NewOptimizerService(repo).Variance(1, plan, VarianceTolerance{TimingMinutes: 60, Volume: 0.1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if variance.Totals != (VarianceTotals{PlannedVolume: 54000, ActualVolume: 32000, VolumeVariance: -22000, PlannedCost: 5.4, ActualCost: 6.8, CostVariance: 1.4}) {
+		t.Errorf("unexpected totals %+v", variance.Totals)
+	}
+	if len(variance.Weeks) != 3 {
+		t.Fatalf("expected 3 sector weeks, got %+v", variance.Weeks)
+	}
+
+	first := variance.Weeks[0]
+	if first.SectorID != 1 || !first.WeekStart.Equal(week) || first.VolumeVariance != -23000 || *first.VolumeVariancePercent != -47.92 ||
+		first.PlannedMinutes != 480 || first.ActualMinutes != 240 || first.MissedRuns != 1 || first.UnplannedEvents != 1 || !first.Deviating {
+		t.Errorf("unexpected first week %+v", first)
+	}
+	// Ending 5 minutes before the run is within the timing tolerance, and the volume is as planned
+	second := variance.Weeks[1]
+	if second.SectorID != 1 || !second.WeekStart.Equal(week.AddDate(0, 0, 7)) || second.Deviating || second.ActualCost != 2.4 || second.PlannedCost != 0.6 {
+		t.Errorf("unexpected second week %+v", second)
+	}
+	unplanned := variance.Weeks[2]
+	if unplanned.SectorID != 2 || unplanned.PlannedVolume != 0 || unplanned.VolumeVariancePercent != nil || !unplanned.Deviating {
+		t.Errorf("expected sector 2 compared with an empty plan, got %+v", unplanned)
+	}
+
+	expected := []struct {
+		kind    string
+		eventID uint
+	}{{DeviationMissedRun, 0}, {DeviationUnplannedEvent, 2}, {DeviationUnplannedEvent, 4}}
+	if len(variance.Deviations) != len(expected) {
+		t.Fatalf("expected %d deviations, got %+v", len(expected), variance.Deviations)
+	}
+	for i, want := range expected {
+		got := variance.Deviations[i]
+		if got.Type != want.kind || (want.eventID != 0 && (got.EventID == nil || *got.EventID != want.eventID)) {
+			t.Errorf("deviation %d: expected %s %d, got %+v", i, want.kind, want.eventID, got)
+		}
+	}
+
+	// Without tolerance the early event misses its run
+	variance, err = NewOptimizerService(repo).Variance(1, plan, VarianceTolerance{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if variance.Weeks[1].MissedRuns != 1 || variance.Weeks[1].UnplannedEvents != 1 {
+		t.Errorf("expected the early event off plan without tolerance, got %+v", variance.Weeks[1])
+	}
+}
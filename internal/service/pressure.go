@@ -0,0 +1,137 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// maxPressureBar is the highest plausible line pressure; larger values are sensor faults
+const maxPressureBar = 100
+
+// PressureService defines the interface for pressure telemetry ingestion
+type PressureService interface {
+	FarmExists(farmID uint) (bool, error)
+	RecordReadings(farmID uint, readings []PressureInput) ([]RowRejection, error)
+}
+
+// PressureInput is a single pressure reading submitted for ingestion
+type PressureInput struct {
+	SectorID    uint      `json:"sector_id"`
+	DeviceID    string    `json:"device_id"`
+	RecordedAt  time.Time `json:"recorded_at"`
+	PressureBar float64   `json:"pressure_bar"`
+}
+
+// pressureService implements PressureService
+type pressureService struct {
+	repo     repository.IrrigationRepository
+	pressure repository.PressureRepository
+	// calibrations corrects readings by device; nil stores them as reported
+	calibrations repository.CalibrationRepository
+}
+
+// NewPressureService creates a new pressure service
+func NewPressureService(repo repository.IrrigationRepository, pressure repository.PressureRepository, calibrations repository.CalibrationRepository) PressureService {
+	return &pressureService{repo: repo, pressure: pressure, calibrations: calibrations}
+}
+
+// FarmExists checks if a farm exists
+func (s *pressureService) FarmExists(farmID uint) (bool, error) {
+	return s.repo.FarmExists(farmID)
+}
+
+// RecordReadings validates, calibrates and stores readings. The batch is all-or-nothing: when any
+// reading is invalid nothing is stored and the rejections are returned. The range check applies
+// to the reported pressure.
+func (s *pressureService) RecordReadings(farmID uint, readings []PressureInput) ([]RowRejection, error) {
+	sectors, err := s.repo.ListSectors(farmID)
+	if err != nil {
+		return nil, err
+	}
+	sectorIDs := make(map[uint]bool, len(sectors))
+	for _, sector := range sectors {
+		sectorIDs[sector.ID] = true
+	}
+
+	rejected := []RowRejection{}
+	models := make([]model.PressureReading, 0, len(readings))
+	for i, reading := range readings {
+		if reason := validatePressureInput(reading, sectorIDs); reason != "" {
+			rejected = append(rejected, RowRejection{Row: i, Reason: reason})
+			continue
+		}
+		models = append(models, model.PressureReading{
+			FarmID:             farmID,
+			IrrigationSectorID: reading.SectorID,
+			DeviceID:           reading.DeviceID,
+			RecordedAt:         reading.RecordedAt,
+			PressureBar:        reading.PressureBar,
+		})
+	}
+	if len(rejected) > 0 {
+		return rejected, nil
+	}
+
+	cal, err := loadCalibrator(s.calibrations, farmID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range models {
+		cal.calibrateReading(&models[i])
+	}
+	return rejected, s.pressure.CreateReadings(models)
+}
+
+// validatePressureInput returns why a reading can't be stored, or "" when it is valid
+func validatePressureInput(reading PressureInput, sectorIDs map[uint]bool) string {
+	switch {
+	case !sectorIDs[reading.SectorID]:
+		return fmt.Sprintf("sector %d does not belong to the farm", reading.SectorID)
+	case reading.RecordedAt.IsZero():
+		return "recorded_at is required"
+	case reading.PressureBar < 0 || reading.PressureBar > maxPressureBar:
+		return fmt.Sprintf("pressure_bar must be between 0 and %d", maxPressureBar)
+	case len(reading.DeviceID) > 64:
+		return "device_id must be at most 64 characters"
+	}
+	return ""
+}
+
+// attachPressure adds min and average pressure to the data points with matching bucket and sector.
+// points[i] must correspond to data[i]. On a lookup failure the points carry no pressure and the
+// error is returned.
+func (s *analyticsService) attachPressure(points []AggregatedDataPoint, data []repository.AggregatedDataWithCount, farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string) error {
+	if s.pressure == nil || len(points) == 0 {
+		return nil
+	}
+
+	buckets, err := s.pressure.GetPressureBuckets(farmID, sectorID, startDate, endDate, aggregation)
+	if err != nil {
+		return err
+	}
+
+	type bucketKey struct {
+		start    int64
+		sectorID uint
+	}
+	byKey := make(map[bucketKey]repository.PressureBucket, len(buckets))
+	for _, bucket := range buckets {
+		byKey[bucketKey{bucket.StartTime.Unix(), bucket.IrrigationSectorID}] = bucket
+	}
+
+	for i, item := range data {
+		bucket, exists := byKey[bucketKey{item.Data.StartTime.Unix(), item.Data.IrrigationSectorID}]
+		if !exists {
+			continue
+		}
+		minPressure := math.Round(bucket.MinPressure*1000) / 1000
+		avgPressure := math.Round(bucket.AvgPressure*1000) / 1000
+		points[i].MinPressure = &minPressure
+		points[i].AvgPressure = &avgPressure
+	}
+	return nil
+}
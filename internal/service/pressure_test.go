@@ -0,0 +1,73 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/repository"
+)
+
+// stubPressureRepository returns fixed pressure buckets
+type stubPressureRepository struct {
+	repository.PressureRepository
+	buckets []repository.PressureBucket
+}
+
+func (r *stubPressureRepository) GetPressureBuckets(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string) ([]repository.PressureBucket, error) {
+	return r.buckets, nil
+}
+
+// TestGetIrrigationAnalytics_Pressure verifies pressure is attached to the matching bucket and sector only
+func TestGetIrrigationAnalytics_Pressure(t *testing.T) {
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	repo := &stubRepository{
+		comparison: map[int][]repository.AggregatedDataWithCount{
+			0: {
+				aggregatedPoint(day, 1, 100, 100, 1),
+				aggregatedPoint(day, 2, 100, 100, 1),
+			},
+		},
+	}
+	pressure := &stubPressureRepository{buckets: []repository.PressureBucket{
+		{StartTime: day, IrrigationSectorID: 2, MinPressure: 1.2, AvgPressure: 2.45678, ReadingCount: 12},
+	}}
+	svc := NewAnalyticsService(repo, nil, pressure, nil)
+
+	sectorID := uint(1)
+	response, err := svc.GetIrrigationAnalytics(1, &sectorID, day, day.AddDate(0, 0, 1), "daily", AnalyticsOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if response.Data[0].MinPressure != nil {
+		t.Errorf("expected no pressure for sector 1, got %v", *response.Data[0].MinPressure)
+	}
+	if response.Data[1].MinPressure == nil || *response.Data[1].MinPressure != 1.2 || *response.Data[1].AvgPressure != 2.457 {
+		t.Errorf("expected min 1.2 and avg 2.457 for sector 2, got %v/%v", response.Data[1].MinPressure, response.Data[1].AvgPressure)
+	}
+}
+
+func TestValidatePressureInput(t *testing.T) {
+	sectors := map[uint]bool{1: true}
+	recordedAt := time.Date(2025, 6, 1, 6, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		reading PressureInput
+		valid   bool
+	}{
+		{"valid", PressureInput{SectorID: 1, RecordedAt: recordedAt, PressureBar: 2.5}, true},
+		{"unknown sector", PressureInput{SectorID: 3, RecordedAt: recordedAt, PressureBar: 2.5}, false},
+		{"missing time", PressureInput{SectorID: 1, PressureBar: 2.5}, false},
+		{"negative pressure", PressureInput{SectorID: 1, RecordedAt: recordedAt, PressureBar: -0.1}, false},
+		{"implausible pressure", PressureInput{SectorID: 1, RecordedAt: recordedAt, PressureBar: 250}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if reason := validatePressureInput(tt.reading, sectors); (reason == "") != tt.valid {
+				t.Errorf("expected valid=%v, got reason %q", tt.valid, reason)
+			}
+		})
+	}
+}
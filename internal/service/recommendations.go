@@ -0,0 +1,291 @@
+package service
+
+import (
+	"math"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// Recommendation statuses
+const (
+	// RecommendationScheduled carries a next window and volume
+	RecommendationScheduled = "scheduled"
+	// RecommendationDefer means rainfall covered the crop's demand over the lookback window
+	RecommendationDefer = "defer"
+	// RecommendationInsufficientHistory means the sector irrigated on fewer than two days in the window
+	RecommendationInsufficientHistory = "insufficient_history"
+)
+
+// Recommendation bases
+const (
+	// BasisHistory repeats the sector's recent cadence and volume
+	BasisHistory = "history"
+	// BasisWeather replaces the water lost to ET0, net of rainfall, since the last irrigation
+	BasisWeather = "weather"
+)
+
+// RecommendationService defines the interface for irrigation scheduling recommendations
+type RecommendationService interface {
+	FarmExists(farmID uint) (bool, error)
+	Recommend(farmID uint, asOf time.Time, opts RecommendationOptions) (*RecommendationResponse, error)
+}
+
+// RecommendationOptions configures a recommendation request
+type RecommendationOptions struct {
+	// LookbackDays is the length of the history window ending at asOf
+	LookbackDays int
+	// UseWeather sizes volumes from stored ET0 and rainfall when the window has any
+	UseWeather bool
+	// Units is UnitsMetric (default) or UnitsImperial to report gallons, acres and inches
+	Units string
+}
+
+// RecommendationResponse holds one recommendation per sector
+type RecommendationResponse struct {
+	FarmID       uint      `json:"farm_id"`
+	AsOf         time.Time `json:"as_of"`
+	LookbackDays int       `json:"lookback_days"`
+	// Units is the unit system of the figures: UnitsMetric, or UnitsImperial when requested
+	Units string `json:"units"`
+	// UsedWeather is false when weather was requested but none is stored for the window
+	UsedWeather     bool                   `json:"used_weather"`
+	Recommendations []SectorRecommendation `json:"recommendations"`
+}
+
+// SectorRecommendation suggests a sector's next irrigation window and volume, along with the
+// recent figures it was derived from. Depths are omitted for sectors without a recorded area.
+type SectorRecommendation struct {
+	SectorID   uint    `json:"sector_id"`
+	SectorName string  `json:"sector_name"`
+	Area       float64 `json:"area"`
+	Status     string  `json:"status"`
+	Basis      string  `json:"basis,omitempty"`
+	// LastIrrigation is the latest day in the window with irrigation events
+	LastIrrigation *time.Time `json:"last_irrigation,omitempty"`
+	// IntervalDays is the mean gap between irrigation days in the window
+	IntervalDays *float64 `json:"interval_days,omitempty"`
+	// AppliedVolume and AppliedDepthMM are the mean water applied per irrigation day
+	AppliedVolume  *float64 `json:"applied_volume,omitempty"`
+	AppliedDepthMM *float64 `json:"applied_depth_mm,omitempty"`
+	// FlowRate is the mean liters per minute while irrigating
+	FlowRate *float64 `json:"flow_rate,omitempty"`
+	// Crop and CropStage are the sector's; Kc is the crop coefficient that scaled ET0 to the crop's
+	// demand, set when weather was used
+	Crop      string   `json:"crop,omitempty"`
+	CropStage string   `json:"crop_stage,omitempty"`
+	Kc        *float64 `json:"kc,omitempty"`
+	// NetDemandMM is the mean daily crop demand (ET0 times Kc, or ET0 for sectors without a crop)
+	// minus rainfall over the window's days with weather
+	NetDemandMM *float64 `json:"net_demand_mm,omitempty"`
+	// NextWindowStart is the suggested day for the next irrigation, never before asOf
+	NextWindowStart     *time.Time `json:"next_window_start,omitempty"`
+	RecommendedVolume   *float64   `json:"recommended_volume,omitempty"`
+	RecommendedDepthMM  *float64   `json:"recommended_depth_mm,omitempty"`
+	RecommendedDuration *int       `json:"recommended_duration,omitempty"` // in minutes
+}
+
+// recommendationService implements RecommendationService
+type recommendationService struct {
+	repo    repository.IrrigationRepository
+	weather repository.WeatherRepository
+	crops   repository.CropCoefficientRepository
+}
+
+// NewRecommendationService creates a new recommendation service. weather may be nil, in which
+// case recommendations are always based on history. crops may be nil, in which case sectors'
+// crops use the library's coefficients without the farm's overrides.
+func NewRecommendationService(repo repository.IrrigationRepository, weather repository.WeatherRepository, crops repository.CropCoefficientRepository) RecommendationService {
+	return &recommendationService{repo: repo, weather: weather, crops: crops}
+}
+
+// FarmExists checks if a farm exists
+func (s *recommendationService) FarmExists(farmID uint) (bool, error) {
+	return s.repo.FarmExists(farmID)
+}
+
+// Recommend derives each sector's next irrigation from its irrigation-purpose events over the
+// LookbackDays before asOf. The next window follows the sector's mean interval from its last
+// irrigation day. Its volume repeats the mean volume per irrigation day, or with weather,
+// replaces the net demand (ET0 minus rainfall) accumulated from the last irrigation to the
+// window. ET0 is scaled by the crop coefficient of sectors with a crop and growth stage, with
+// the farm's overrides applied. When rainfall exceeded the demand over the window, the sector
+// is told to defer instead.
+func (s *recommendationService) Recommend(farmID uint, asOf time.Time, opts RecommendationOptions) (*RecommendationResponse, error) {
+	asOf = truncateToDay(asOf)
+	windowStart := asOf.AddDate(0, 0, -opts.LookbackDays)
+
+	sectors, err := s.repo.ListSectors(farmID)
+	if err != nil {
+		return nil, err
+	}
+	queryOpts, err := withAttribution(s.repo, farmID, repository.QueryOptions{
+		Purposes: []string{model.PurposeIrrigation},
+	})
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.repo.GetAggregatedData(farmID, nil, windowStart, asOf, "daily", queryOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	var observations []model.WeatherObservation
+	if opts.UseWeather && s.weather != nil {
+		observations, err = s.weather.GetObservations(farmID, windowStart, asOf)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// netDemand maps each day with weather to its ET0 minus rainfall
+	netDemand := dailyNetDemand(observations, 1)
+
+	var coefficients map[string]CropCoefficients
+	if len(netDemand) > 0 {
+		coefficients, err = farmCropCoefficients(s.crops, farmID, CropLanguageEnglish)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	bySector := make(map[uint][]repository.AggregatedDataWithCount)
+	for _, item := range data {
+		if item.EventCount > 0 {
+			id := item.Data.IrrigationSectorID
+			bySector[id] = append(bySector[id], item)
+		}
+	}
+
+	response := &RecommendationResponse{
+		FarmID:          farmID,
+		AsOf:            asOf,
+		LookbackDays:    opts.LookbackDays,
+		UsedWeather:     len(netDemand) > 0,
+		Recommendations: make([]SectorRecommendation, 0, len(sectors)),
+	}
+	for _, sector := range sectors {
+		sectorDemand := netDemand
+		var kc *float64
+		if c, ok := coefficients[sector.Crop]; ok && sector.CropStage != "" {
+			kc = roundedPtr(c.StageKc(sector.CropStage), 3)
+			sectorDemand = dailyNetDemand(observations, *kc)
+		}
+		rec := recommendSector(sector, bySector[sector.ID], sectorDemand, asOf)
+		if rec.Basis == BasisWeather {
+			rec.Kc = kc
+		}
+		response.Recommendations = append(response.Recommendations, rec)
+	}
+	applyRecommendationUnits(response, opts.Units)
+	return response, nil
+}
+
+// dailyNetDemand returns ET0 times kc minus rainfall for each day with an ET0 value; missing
+// rainfall counts as none
+func dailyNetDemand(observations []model.WeatherObservation, kc float64) map[int64]float64 {
+	demand := make(map[int64]float64, len(observations))
+	for _, observation := range observations {
+		if observation.ET0MM == nil {
+			continue
+		}
+		net := *observation.ET0MM * kc
+		if observation.PrecipitationMM != nil {
+			net -= *observation.PrecipitationMM
+		}
+		demand[truncateToDay(observation.Date).Unix()] = net
+	}
+	return demand
+}
+
+// recommendSector builds one sector's recommendation from its irrigation days, in date order
+func recommendSector(sector model.IrrigationSector, days []repository.AggregatedDataWithCount, netDemand map[int64]float64, asOf time.Time) SectorRecommendation {
+	rec := SectorRecommendation{SectorID: sector.ID, SectorName: sector.Name, Area: sector.Area, Crop: sector.Crop, CropStage: sector.CropStage}
+	if len(days) < 2 {
+		rec.Status = RecommendationInsufficientHistory
+		if len(days) == 1 {
+			last := truncateToDay(days[0].Data.StartTime)
+			rec.LastIrrigation = &last
+		}
+		return rec
+	}
+
+	var volume float64
+	var duration int
+	for _, day := range days {
+		volume += day.Data.WaterVolume
+		duration += day.Data.Duration
+	}
+	first := truncateToDay(days[0].Data.StartTime)
+	last := truncateToDay(days[len(days)-1].Data.StartTime)
+	interval := last.Sub(first).Hours() / 24 / float64(len(days)-1)
+	appliedVolume := volume / float64(len(days))
+
+	rec.LastIrrigation = &last
+	rec.IntervalDays = roundedPtr(interval, 1)
+	rec.AppliedVolume = roundedPtr(appliedVolume, 2)
+	rec.AppliedDepthMM = depthMM(appliedVolume, sector.Area)
+	var flowRate float64
+	if duration > 0 {
+		flowRate = volume / float64(duration)
+		rec.FlowRate = roundedPtr(flowRate, 3)
+	}
+
+	next := last.AddDate(0, 0, int(math.Round(interval)))
+	if next.Before(asOf) {
+		next = asOf
+	}
+
+	recommendedVolume := appliedVolume
+	rec.Basis = BasisHistory
+	if len(netDemand) > 0 && sector.Area > 0 {
+		var total float64
+		for _, net := range netDemand {
+			total += net
+		}
+		meanDemand := total / float64(len(netDemand))
+		rec.NetDemandMM = roundedPtr(meanDemand, 2)
+		if meanDemand <= 0 {
+			rec.Status = RecommendationDefer
+			rec.Basis = BasisWeather
+			return rec
+		}
+
+		// Stored days count as recorded; days without weather, including those after asOf, at the mean
+		var deficit float64
+		for day := last.AddDate(0, 0, 1); !day.After(next); day = day.AddDate(0, 0, 1) {
+			if net, ok := netDemand[day.Unix()]; ok && day.Before(asOf) {
+				deficit += net
+			} else {
+				deficit += meanDemand
+			}
+		}
+		recommendedVolume = math.Max(deficit, 0) * sector.Area * 10000
+		rec.Basis = BasisWeather
+	}
+
+	rec.Status = RecommendationScheduled
+	rec.NextWindowStart = &next
+	rec.RecommendedVolume = roundedPtr(recommendedVolume, 2)
+	rec.RecommendedDepthMM = depthMM(recommendedVolume, sector.Area)
+	if flowRate > 0 {
+		minutes := int(math.Round(recommendedVolume / flowRate))
+		rec.RecommendedDuration = &minutes
+	}
+	return rec
+}
+
+// depthMM converts liters spread over area hectares to millimeters (1 L/m² = 1 mm), or nil without an area
+func depthMM(volume, area float64) *float64 {
+	if area <= 0 {
+		return nil
+	}
+	return roundedPtr(volume/(area*10000), 2)
+}
+
+// roundedPtr rounds v to the given decimals and returns a pointer for optional fields
+func roundedPtr(v float64, decimals int) *float64 {
+	scale := math.Pow(10, float64(decimals))
+	rounded := math.Round(v*scale) / scale
+	return &rounded
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+func TestRecommend(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 6, d, 0, 0, 0, 0, time.UTC) }
+	repo := &areaRepository{
+		stubRepository: &stubRepository{comparison: map[int][]repository.AggregatedDataWithCount{
+			0: {
+				aggregatedPoint(day(1), 1, 10000, 100, 2),
+				aggregatedPoint(day(2), 2, 500, 10, 1),
+				aggregatedPoint(day(4), 1, 10000, 100, 2),
+				aggregatedPoint(day(7), 1, 10000, 100, 2),
+			},
+		}},
+		sectorList: []model.IrrigationSector{
+			{ID: 1, Name: "Block A", Area: 0.5},
+			{ID: 2, Name: "Block B", Area: 1},
+		},
+	}
+	asOf := day(9)
+
+	t.Run("history", func(t *testing.T) {
+		response, err := NewRecommendationService(repo, nil, nil).Recommend(1, asOf, RecommendationOptions{LookbackDays: 14, UseWeather: true})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if response.UsedWeather {
+			t.Error("expected no weather without a weather repository")
+		}
+
+		rec := response.Recommendations[0]
+		if rec.Status != RecommendationScheduled || rec.Basis != BasisHistory {
+			t.Fatalf("expected a history-based schedule, got %s/%s", rec.Status, rec.Basis)
+		}
+		if *rec.IntervalDays != 3 || *rec.AppliedDepthMM != 2 || *rec.FlowRate != 100 {
+			t.Errorf("expected 3 day interval, 2 mm and 100 L/min, got %v, %v, %v", *rec.IntervalDays, *rec.AppliedDepthMM, *rec.FlowRate)
+		}
+		if !rec.NextWindowStart.Equal(day(10)) || *rec.RecommendedVolume != 10000 || *rec.RecommendedDuration != 100 {
+			t.Errorf("expected 10000 L over 100 min on June 10, got %v L over %v min on %v", *rec.RecommendedVolume, *rec.RecommendedDuration, rec.NextWindowStart)
+		}
+
+		if response.Recommendations[1].Status != RecommendationInsufficientHistory || response.Recommendations[1].NextWindowStart != nil {
+			t.Errorf("expected insufficient history for a sector irrigated once, got %+v", response.Recommendations[1])
+		}
+	})
+
+	t.Run("weather", func(t *testing.T) {
+		var observations []model.WeatherObservation
+		for d := 1; d <= 8; d++ {
+			observations = append(observations, model.WeatherObservation{Date: day(d), ET0MM: weatherFloat(5), PrecipitationMM: weatherFloat(1)})
+		}
+		weather := &stubWeatherRepository{observations: observations}
+
+		response, err := NewRecommendationService(repo, weather, nil).Recommend(1, asOf, RecommendationOptions{LookbackDays: 14, UseWeather: true})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		rec := response.Recommendations[0]
+		// June 8 is recorded and June 9-10 are projected, each at 4 mm net demand: 12 mm over 0.5 ha
+		if !response.UsedWeather || rec.Basis != BasisWeather || *rec.NetDemandMM != 4 {
+			t.Fatalf("expected weather basis with 4 mm/day demand, got %+v", rec)
+		}
+		if *rec.RecommendedDepthMM != 12 || *rec.RecommendedVolume != 60000 || *rec.RecommendedDuration != 600 {
+			t.Errorf("expected 12 mm = 60000 L over 600 min, got %v mm, %v L, %v min", *rec.RecommendedDepthMM, *rec.RecommendedVolume, *rec.RecommendedDuration)
+		}
+
+		for i := range observations {
+			observations[i].PrecipitationMM = weatherFloat(8)
+		}
+		response, _ = NewRecommendationService(repo, weather, nil).Recommend(1, asOf, RecommendationOptions{LookbackDays: 14, UseWeather: true})
+		if rec := response.Recommendations[0]; rec.Status != RecommendationDefer || rec.NextWindowStart != nil {
+			t.Errorf("expected defer when rainfall exceeds ET0, got %+v", rec)
+		}
+	})
+
+	t.Run("crop coefficient", func(t *testing.T) {
+		var observations []model.WeatherObservation
+		for d := 1; d <= 8; d++ {
+			observations = append(observations, model.WeatherObservation{Date: day(d), ET0MM: weatherFloat(5), PrecipitationMM: weatherFloat(1)})
+		}
+		weather := &stubWeatherRepository{observations: observations}
+		cropRepo := *repo
+		cropRepo.sectorList = []model.IrrigationSector{
+			{ID: 1, Name: "Block A", Area: 0.5, Crop: "tomato", CropStage: model.CropStageMid},
+			{ID: 2, Name: "Block B", Area: 1},
+		}
+		crops := &stubCropRepository{overrides: []model.CropCoefficientOverride{{FarmID: 1, Crop: "tomato", KcMid: weatherFloat(0.8)}}}
+
+		response, err := NewRecommendationService(&cropRepo, weather, crops).Recommend(1, asOf, RecommendationOptions{LookbackDays: 14, UseWeather: true})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		rec := response.Recommendations[0]
+		// The farm's Kc of 0.8 makes each day's demand 5 * 0.8 - 1 = 3 mm: 9 mm over 0.5 ha
+		if rec.Kc == nil || *rec.Kc != 0.8 || *rec.NetDemandMM != 3 || rec.Crop != "tomato" {
+			t.Fatalf("expected the overridden Kc of 0.8 and 3 mm/day demand, got %+v", rec)
+		}
+		if *rec.RecommendedVolume != 45000 {
+			t.Errorf("expected 9 mm = 45000 L, got %v L", *rec.RecommendedVolume)
+		}
+		if response.Recommendations[1].Kc != nil {
+			t.Errorf("expected no Kc for a sector without a crop, got %v", *response.Recommendations[1].Kc)
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package service
+
+import "irrigation-analytics/internal/repository"
+
+// RegionService defines the interface for finding which region's database holds a farm
+type RegionService interface {
+	RegionOf(farmID uint) (string, error)
+	Regions() []string
+	DefaultRegion() string
+}
+
+// regionService implements RegionService
+type regionService struct {
+	router *repository.RegionRouter
+}
+
+// NewRegionService creates a new region service
+func NewRegionService(router *repository.RegionRouter) RegionService {
+	return &regionService{router: router}
+}
+
+// RegionOf returns the region holding the farm; unknown farms belong to the default region
+func (s *regionService) RegionOf(farmID uint) (string, error) {
+	return s.router.RegionOf(farmID)
+}
+
+// Regions returns the configured regions in name order
+func (s *regionService) Regions() []string {
+	return s.router.Regions()
+}
+
+// DefaultRegion returns the region of farms without a routing row
+func (s *regionService) DefaultRegion() string {
+	return s.router.DefaultRegion()
+}
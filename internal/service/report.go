@@ -0,0 +1,244 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReportFormatPDF is the only format the irrigation report is rendered in so far
+const ReportFormatPDF = "pdf"
+
+// Report layout in PDF points
+const (
+	reportLineHeight  = 16.0
+	reportChartHeight = 140.0
+)
+
+// reportWriter lays out the report top to bottom, starting a new page when one is full
+type reportWriter struct {
+	doc *pdfDocument
+	y   float64
+}
+
+// RenderReportPDF renders an analytics response as a PDF report for farm owners: the summary,
+// the comparison with previous years, the sector breakdown and charts of water volume and
+// efficiency per bucket. Data points of the same bucket are summed over sectors.
+func RenderReportPDF(analytics *AnalyticsResponse, generatedAt time.Time) []byte {
+	w := &reportWriter{doc: &pdfDocument{}}
+	w.startPage()
+
+	w.doc.text(pdfMargin, w.y, 18, pdfFontBold, fmt.Sprintf("Irrigation report - Farm %d", analytics.FarmID))
+	w.y -= 22
+	w.doc.text(pdfMargin, w.y, 11, pdfFontRegular, fmt.Sprintf("%s, %s buckets", reportPeriod(analytics.Period), analytics.Aggregation))
+	w.y -= reportLineHeight * 2
+
+	summary := analytics.Summary
+	w.heading("Summary")
+	w.header("", "Water volume (L)", "Duration (h)", "Events", "Efficiency")
+	w.row(pdfFontRegular, "Total", reportNumber(summary.TotalWaterVolume, 0), reportNumber(float64(summary.TotalDuration)/60, 1),
+		reportNumber(float64(summary.TotalEvents), 0), reportPercent(summary.AverageEfficiency))
+	w.y -= reportLineHeight
+
+	w.heading("Year over year")
+	w.header("Period", "Water volume (L)", "Events", "Efficiency", "Change")
+	w.row(pdfFontRegular, reportPeriod(analytics.Period), reportNumber(summary.TotalWaterVolume, 0),
+		reportNumber(float64(summary.TotalEvents), 0), reportPercent(summary.AverageEfficiency), "")
+	for _, year := range []*YearComparison{analytics.YearOverYear.OneYearAgo, analytics.YearOverYear.TwoYearsAgo} {
+		if year != nil {
+			w.row(pdfFontRegular, reportPeriod(year.Period), reportNumber(year.TotalWaterVolume, 0),
+				reportNumber(float64(year.TotalEvents), 0), reportPercent(year.AverageEfficiency), reportSigned(year.ChangePercent))
+		}
+	}
+	w.y -= reportLineHeight
+
+	if len(analytics.SectorBreakdown) > 0 {
+		w.heading("Sectors")
+		w.header("Sector", "Water volume (L)", "Events", "Efficiency", "Share of volume")
+		for _, sector := range analytics.SectorBreakdown {
+			share := ""
+			if summary.TotalWaterVolume > 0 {
+				share = reportPercent(sector.TotalWaterVolume / summary.TotalWaterVolume)
+			}
+			w.row(pdfFontRegular, strconv.FormatUint(uint64(sector.SectorID), 10), reportNumber(sector.TotalWaterVolume, 0),
+				reportNumber(float64(sector.TotalEvents), 0), reportPercent(sector.AverageEfficiency), share)
+		}
+		w.y -= reportLineHeight
+	}
+
+	periods, volumes, efficiencies := reportSeries(analytics.Data)
+	if len(periods) > 0 {
+		labels := make([]string, len(periods))
+		for i, period := range periods {
+			labels[i] = reportBucketLabel(period, analytics.Aggregation)
+		}
+		w.chart("Water volume (L)", labels, volumes)
+		for i := range efficiencies {
+			efficiencies[i] *= 100
+		}
+		w.chart("Efficiency (%)", labels, efficiencies)
+	}
+
+	for i := range w.doc.pages {
+		w.doc.selectPage(i)
+		w.doc.text(pdfMargin, pdfMargin/2, 8, pdfFontRegular, "Generated "+generatedAt.UTC().Format("2006-01-02 15:04 UTC"))
+		w.doc.textRight(pdfPageWidth-pdfMargin, pdfMargin/2, 8, pdfFontRegular, fmt.Sprintf("Page %d of %d", i+1, len(w.doc.pages)))
+	}
+	return w.doc.bytes()
+}
+
+// startPage begins a new page with the cursor at its top margin
+func (w *reportWriter) startPage() {
+	w.doc.newPage()
+	w.y = pdfPageHeight - pdfMargin
+}
+
+// reserve starts a new page unless height points fit above the bottom margin
+func (w *reportWriter) reserve(height float64) {
+	if w.y-height < pdfMargin {
+		w.startPage()
+	}
+}
+
+// heading writes a section title, keeping it on the page of at least two of its lines
+func (w *reportWriter) heading(title string) {
+	w.reserve(reportLineHeight * 3)
+	w.doc.text(pdfMargin, w.y, 13, pdfFontBold, title)
+	w.y -= reportLineHeight * 1.25
+}
+
+// header writes a table's underlined header row
+func (w *reportWriter) header(cells ...string) {
+	w.row(pdfFontBold, cells...)
+	w.doc.line(pdfMargin, w.y+reportLineHeight-4, pdfPageWidth-pdfMargin, w.y+reportLineHeight-4, 0.6)
+}
+
+// row writes one table row of equal-width columns. The first column holds labels and is left
+// aligned; the others hold figures and are right aligned.
+func (w *reportWriter) row(font string, cells ...string) {
+	w.reserve(reportLineHeight)
+	width := (pdfPageWidth - 2*pdfMargin) / float64(len(cells))
+	for i, cell := range cells {
+		if i == 0 {
+			w.doc.text(pdfMargin, w.y, 10, font, cell)
+		} else {
+			w.doc.textRight(pdfMargin+width*float64(i+1), w.y, 10, font, cell)
+		}
+	}
+	w.y -= reportLineHeight
+}
+
+// chart draws a bar chart of values with the maximum marked on the axis; labels are thinned
+// out to about a dozen so they don't overlap
+func (w *reportWriter) chart(title string, labels []string, values []float64) {
+	w.reserve(reportChartHeight + reportLineHeight*3)
+	w.doc.text(pdfMargin, w.y, 13, pdfFontBold, title)
+	w.y -= reportLineHeight
+
+	maxValue := 0.0
+	for _, v := range values {
+		maxValue = math.Max(maxValue, v)
+	}
+	left, bottom := pdfMargin+50, w.y-reportChartHeight
+	width := pdfPageWidth - pdfMargin - left
+	w.doc.line(left, bottom, left+width, bottom, 0)
+	w.doc.line(left, bottom, left, w.y, 0)
+	w.doc.textRight(left-4, w.y-8, 8, pdfFontRegular, reportNumber(maxValue, 0))
+	w.doc.textRight(left-4, bottom, 8, pdfFontRegular, "0")
+
+	slot := width / float64(len(values))
+	step := (len(values) + 11) / 12
+	for i, v := range values {
+		if maxValue > 0 && v > 0 {
+			w.doc.rect(left+slot*(float64(i)+0.15), bottom, slot*0.7, v/maxValue*(reportChartHeight-8), 0.45)
+		}
+		if i%step == 0 {
+			w.doc.text(left+slot*float64(i), bottom-11, 7, pdfFontRegular, labels[i])
+		}
+	}
+	w.y = bottom - reportLineHeight*2
+}
+
+// reportSeries sums the data points of each bucket over sectors, returning the buckets in order
+// with their water volume and efficiency, real over nominal amount
+func reportSeries(points []AggregatedDataPoint) ([]time.Time, []float64, []float64) {
+	var periods []time.Time
+	var volumes, realAmounts, nominalAmounts []float64
+	for _, point := range points {
+		last := len(periods) - 1
+		if last < 0 || !periods[last].Equal(point.Period) {
+			periods = append(periods, point.Period)
+			volumes, realAmounts, nominalAmounts = append(volumes, 0), append(realAmounts, 0), append(nominalAmounts, 0)
+			last++
+		}
+		volumes[last] += point.WaterVolume
+		realAmounts[last] += point.RealAmount
+		nominalAmounts[last] += point.NominalAmount
+	}
+
+	efficiencies := make([]float64, len(periods))
+	for i := range periods {
+		if nominalAmounts[i] > 0 {
+			efficiencies[i] = realAmounts[i] / nominalAmounts[i]
+		}
+	}
+	return periods, volumes, efficiencies
+}
+
+// reportPeriod formats a period with its inclusive last day, as end dates are exclusive
+func reportPeriod(period PeriodInfo) string {
+	last := period.EndDate.Add(-time.Nanosecond)
+	if last.Before(period.StartDate) {
+		last = period.StartDate
+	}
+	return period.StartDate.Format("2 Jan 2006") + " - " + last.Format("2 Jan 2006")
+}
+
+// reportBucketLabel formats a bucket start for a chart axis
+func reportBucketLabel(period time.Time, aggregation string) string {
+	switch aggregation {
+	case "monthly", "quarterly":
+		return period.Format("Jan 06")
+	case "yearly":
+		return period.Format("2006")
+	case "hourly":
+		return period.Format("2 Jan 15h")
+	default:
+		return period.Format("2 Jan")
+	}
+}
+
+// reportNumber formats v with the given decimals and thousands separators
+func reportNumber(v float64, decimals int) string {
+	s := strconv.FormatFloat(math.Abs(v), 'f', decimals, 64)
+	whole, fraction, _ := strings.Cut(s, ".")
+	var b strings.Builder
+	if v < 0 && strings.Trim(s, "0.") != "" {
+		b.WriteByte('-')
+	}
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	if fraction != "" {
+		b.WriteString("." + fraction)
+	}
+	return b.String()
+}
+
+// reportPercent formats a ratio as a percentage
+func reportPercent(ratio float64) string {
+	return reportNumber(ratio*100, 1) + "%"
+}
+
+// reportSigned formats a percentage change with its sign
+func reportSigned(percent float64) string {
+	if percent > 0 {
+		return "+" + reportNumber(percent, 1) + "%"
+	}
+	return reportNumber(percent, 1) + "%"
+}
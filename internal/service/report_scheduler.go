@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// DefaultReportScheduleInterval is how often the scheduler looks for due reports
+const DefaultReportScheduleInterval = 5 * time.Minute
+
+// Report sending: a claimed run whose sender dies is picked up again after reportClaimLease, and
+// a report that fails to send is retried every reportRetryDelay
+const (
+	reportClaimLease = 15 * time.Minute
+	reportRetryDelay = time.Hour
+	reportBatch      = 50
+)
+
+// ReportScheduler emails the reports of due schedules
+type ReportScheduler interface {
+	SendDue(ctx context.Context) error
+	Run(ctx context.Context, interval time.Duration)
+}
+
+// reportScheduler implements ReportScheduler
+type reportScheduler struct {
+	schedules repository.ReportScheduleRepository
+	analytics AnalyticsService
+	mailer    Mailer
+	logger    *slog.Logger
+	now       func() time.Time
+}
+
+// NewReportScheduler creates a scheduler reading analytics from the given service and sending
+// through mailer
+func NewReportScheduler(schedules repository.ReportScheduleRepository, analytics AnalyticsService, mailer Mailer, logger *slog.Logger) ReportScheduler {
+	return &reportScheduler{
+		schedules: schedules,
+		analytics: analytics,
+		mailer:    mailer,
+		logger:    logger,
+		now:       time.Now,
+	}
+}
+
+// SendDue sends the report of every due schedule it can claim. Claiming moves the schedule's
+// next run forward first, so several instances can run the scheduler without sending a report
+// twice. A sent report schedules the next week or month; a failed one is retried after
+// reportRetryDelay until the next report is due, which then replaces it. A schedule that fails is
+// logged without stopping the others.
+func (s *reportScheduler) SendDue(ctx context.Context) error {
+	repo := s.schedules.WithContext(ctx)
+	due, err := repo.Due(s.now(), reportBatch)
+	if err != nil {
+		return err
+	}
+
+	for i := range due {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		schedule := &due[i]
+		dueAt := schedule.NextRunAt
+		claimed, err := repo.Claim(schedule.ID, dueAt, s.now().Add(reportClaimLease))
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		sendErr := s.send(ctx, schedule, dueAt)
+		now := s.now()
+		schedule.NextRunAt = nextReportRun(schedule.Frequency, dueAt)
+		if sendErr == nil {
+			schedule.LastSentAt, schedule.LastError = &now, ""
+		} else {
+			schedule.LastError = sendErr.Error()
+			if retry := now.Add(reportRetryDelay); retry.Before(schedule.NextRunAt) {
+				schedule.NextRunAt = retry
+			}
+			s.logger.Error("scheduled report failed",
+				"schedule_id", schedule.ID,
+				"farm_id", schedule.FarmID,
+				"error", sendErr,
+			)
+		}
+		if err := repo.Update(schedule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send builds and emails the report a schedule owes at dueAt
+func (s *reportScheduler) send(ctx context.Context, schedule *model.ReportSchedule, dueAt time.Time) error {
+	start, end := scheduledReportPeriod(schedule.Frequency, dueAt)
+	analytics, err := s.analytics.WithContext(ctx).GetIrrigationAnalytics(schedule.FarmID, nil, start, end, "daily", AnalyticsOptions{FillGaps: true})
+	if err != nil {
+		return err
+	}
+
+	email := Email{
+		To:      schedule.Recipients,
+		Subject: fmt.Sprintf("%s irrigation report - Farm %d, %s", reportFrequencyTitle(schedule.Frequency), schedule.FarmID, reportPeriod(analytics.Period)),
+		Text:    reportEmailText(analytics),
+	}
+	if schedule.AttachPDF {
+		email.Attachments = []EmailAttachment{{
+			Filename:    fmt.Sprintf("farm-%d-irrigation-report-%s.pdf", schedule.FarmID, start.Format("2006-01-02")),
+			ContentType: "application/pdf",
+			Content:     RenderReportPDF(analytics, s.now()),
+		}}
+	}
+	return s.mailer.Send(ctx, email)
+}
+
+// reportFrequencyTitle names a schedule's report in a subject line
+func reportFrequencyTitle(frequency string) string {
+	if frequency == model.ReportFrequencyMonthly {
+		return "Monthly"
+	}
+	return "Weekly"
+}
+
+// reportEmailText summarizes an analytics response as the plain text body of a report email
+func reportEmailText(analytics *AnalyticsResponse) string {
+	summary := analytics.Summary
+	var b strings.Builder
+	fmt.Fprintf(&b, "Irrigation report for farm %d\n%s\n\n", analytics.FarmID, reportPeriod(analytics.Period))
+	fmt.Fprintf(&b, "Water volume: %s L\n", reportNumber(summary.TotalWaterVolume, 0))
+	fmt.Fprintf(&b, "Irrigation time: %s h\n", reportNumber(float64(summary.TotalDuration)/60, 1))
+	fmt.Fprintf(&b, "Events: %s\n", reportNumber(float64(summary.TotalEvents), 0))
+	fmt.Fprintf(&b, "Efficiency: %s\n", reportPercent(summary.AverageEfficiency))
+	if year := analytics.YearOverYear.OneYearAgo; year != nil {
+		fmt.Fprintf(&b, "Water volume a year earlier: %s L (%s)\n", reportNumber(year.TotalWaterVolume, 0), reportSigned(year.ChangePercent))
+	}
+
+	if len(analytics.SectorBreakdown) > 0 {
+		b.WriteString("\nSectors:\n")
+		for _, sector := range analytics.SectorBreakdown {
+			fmt.Fprintf(&b, "  Sector %d: %s L, %s events, efficiency %s\n", sector.SectorID,
+				reportNumber(sector.TotalWaterVolume, 0), reportNumber(float64(sector.TotalEvents), 0), reportPercent(sector.AverageEfficiency))
+		}
+	}
+	return b.String()
+}
+
+// Run sends due reports once immediately and then every interval until ctx ends; an interval of
+// 0 or less uses DefaultReportScheduleInterval
+func (s *reportScheduler) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultReportScheduleInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.SendDue(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Error("scheduled reports failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
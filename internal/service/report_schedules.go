@@ -0,0 +1,106 @@
+package service
+
+import (
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// reportSendHour is the UTC hour scheduled reports go out, once the period's last day has had
+// time to arrive
+const reportSendHour = 6
+
+// ReportScheduleService defines the interface for managing a farm's emailed reports
+type ReportScheduleService interface {
+	FarmExists(farmID uint) (bool, error)
+	CreateSchedule(farmID uint, input ReportScheduleInput) (*model.ReportSchedule, error)
+	ListSchedules(farmID uint) ([]model.ReportSchedule, error)
+	DeleteSchedule(farmID, scheduleID uint) (bool, error)
+}
+
+// ReportScheduleInput describes a report schedule
+type ReportScheduleInput struct {
+	Frequency  string
+	Recipients []string
+	AttachPDF  bool
+}
+
+// reportScheduleService implements ReportScheduleService
+type reportScheduleService struct {
+	repo      repository.IrrigationRepository
+	schedules repository.ReportScheduleRepository
+	now       func() time.Time
+}
+
+// NewReportScheduleService creates a new report schedule service
+func NewReportScheduleService(repo repository.IrrigationRepository, schedules repository.ReportScheduleRepository) ReportScheduleService {
+	return &reportScheduleService{repo: repo, schedules: schedules, now: time.Now}
+}
+
+// FarmExists checks if a farm exists
+func (s *reportScheduleService) FarmExists(farmID uint) (bool, error) {
+	return s.repo.FarmExists(farmID)
+}
+
+// CreateSchedule stores a schedule whose first report covers the current week or month
+func (s *reportScheduleService) CreateSchedule(farmID uint, input ReportScheduleInput) (*model.ReportSchedule, error) {
+	schedule := model.ReportSchedule{
+		FarmID:     farmID,
+		Frequency:  input.Frequency,
+		Recipients: input.Recipients,
+		AttachPDF:  input.AttachPDF,
+		NextRunAt:  nextReportRun(input.Frequency, s.now()),
+	}
+	if err := s.schedules.Create(&schedule); err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// ListSchedules returns the farm's report schedules
+func (s *reportScheduleService) ListSchedules(farmID uint) ([]model.ReportSchedule, error) {
+	return s.schedules.List(farmID)
+}
+
+// DeleteSchedule removes a schedule of the farm, reporting whether it existed
+func (s *reportScheduleService) DeleteSchedule(farmID, scheduleID uint) (bool, error) {
+	return s.schedules.Delete(farmID, scheduleID)
+}
+
+// reportPeriodStart is the start of the UTC week, from Monday, or month containing t
+func reportPeriodStart(frequency string, t time.Time) time.Time {
+	t = t.UTC()
+	if frequency == model.ReportFrequencyMonthly {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	day := truncateToDay(t)
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// nextReportPeriod is the start of the week or month after the one starting at start
+func nextReportPeriod(frequency string, start time.Time) time.Time {
+	if frequency == model.ReportFrequencyMonthly {
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 7)
+}
+
+// nextReportRun is the first send time after t: reportSendHour on the day a week or month ends
+func nextReportRun(frequency string, t time.Time) time.Time {
+	start := reportPeriodStart(frequency, t)
+	run := start.Add(reportSendHour * time.Hour)
+	if !run.After(t) {
+		run = nextReportPeriod(frequency, start).Add(reportSendHour * time.Hour)
+	}
+	return run
+}
+
+// scheduledReportPeriod is the finished week or month a report sent at dueAt covers
+func scheduledReportPeriod(frequency string, dueAt time.Time) (time.Time, time.Time) {
+	end := reportPeriodStart(frequency, dueAt)
+	if frequency == model.ReportFrequencyMonthly {
+		return end.AddDate(0, -1, 0), end
+	}
+	return end.AddDate(0, 0, -7), end
+}
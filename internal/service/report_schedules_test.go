@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// stubReportScheduleRepository keeps schedules in memory
+type stubReportScheduleRepository struct {
+	schedules []model.ReportSchedule
+}
+
+func (r *stubReportScheduleRepository) Create(schedule *model.ReportSchedule) error {
+	schedule.ID = uint(len(r.schedules) + 1)
+	r.schedules = append(r.schedules, *schedule)
+	return nil
+}
+
+func (r *stubReportScheduleRepository) List(farmID uint) ([]model.ReportSchedule, error) {
+	return r.schedules, nil
+}
+
+func (r *stubReportScheduleRepository) Delete(farmID, scheduleID uint) (bool, error) {
+	return false, nil
+}
+
+func (r *stubReportScheduleRepository) Due(now time.Time, limit int) ([]model.ReportSchedule, error) {
+	var due []model.ReportSchedule
+	for _, schedule := range r.schedules {
+		if !schedule.NextRunAt.After(now) {
+			due = append(due, schedule)
+		}
+	}
+	return due, nil
+}
+
+func (r *stubReportScheduleRepository) Claim(scheduleID uint, dueAt, until time.Time) (bool, error) {
+	schedule := &r.schedules[scheduleID-1]
+	if !schedule.NextRunAt.Equal(dueAt) {
+		return false, nil
+	}
+	schedule.NextRunAt = until
+	return true, nil
+}
+
+func (r *stubReportScheduleRepository) Update(schedule *model.ReportSchedule) error {
+	r.schedules[schedule.ID-1] = *schedule
+	return nil
+}
+
+func (r *stubReportScheduleRepository) WithContext(ctx context.Context) repository.ReportScheduleRepository {
+	return r
+}
+
+// stubMailer records the emails it is given, failing while err is set
+type stubMailer struct {
+	sent []Email
+	err  error
+}
+
+func (m *stubMailer) Send(ctx context.Context, email Email) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, email)
+	return nil
+}
+
+func TestNextReportRun(t *testing.T) {
+	tests := []struct {
+		frequency string
+		after     time.Time
+		want      time.Time
+	}{
+		{model.ReportFrequencyWeekly, time.Date(2025, 6, 11, 10, 0, 0, 0, time.UTC), time.Date(2025, 6, 16, 6, 0, 0, 0, time.UTC)},
+		{model.ReportFrequencyWeekly, time.Date(2025, 6, 16, 5, 59, 0, 0, time.UTC), time.Date(2025, 6, 16, 6, 0, 0, 0, time.UTC)},
+		{model.ReportFrequencyWeekly, time.Date(2025, 6, 16, 6, 0, 0, 0, time.UTC), time.Date(2025, 6, 23, 6, 0, 0, 0, time.UTC)},
+		{model.ReportFrequencyWeekly, time.Date(2025, 6, 15, 23, 0, 0, 0, time.UTC), time.Date(2025, 6, 16, 6, 0, 0, 0, time.UTC)},
+		{model.ReportFrequencyMonthly, time.Date(2025, 6, 11, 10, 0, 0, 0, time.UTC), time.Date(2025, 7, 1, 6, 0, 0, 0, time.UTC)},
+		{model.ReportFrequencyMonthly, time.Date(2025, 12, 1, 6, 0, 0, 0, time.UTC), time.Date(2026, 1, 1, 6, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := nextReportRun(tt.frequency, tt.after); !got.Equal(tt.want) {
+			t.Errorf("nextReportRun(%s, %v) = %v, want %v", tt.frequency, tt.after, got, tt.want)
+		}
+	}
+
+	start, end := scheduledReportPeriod(model.ReportFrequencyMonthly, time.Date(2025, 3, 1, 7, 0, 0, 0, time.UTC))
+	if !start.Equal(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected a retried monthly report to still cover February, got %v - %v", start, end)
+	}
+}
+
+// TestReportScheduler verifies a due report is claimed once, retried hourly while the mailer
+// fails, and that sending it schedules the next week
+func TestReportScheduler(t *testing.T) {
+	week := time.Date(2025, 6, 9, 0, 0, 0, 0, time.UTC)
+	repo := &stubRepository{
+		comparison: map[int][]repository.AggregatedDataWithCount{0: {
+			aggregatedPoint(week, 1, 1200, 1000, 2),
+			aggregatedPoint(week.AddDate(0, 0, 3), 2, 800, 1000, 1),
+		}},
+		sectors: map[int][]repository.AggregatedDataWithCount{0: {
+			aggregatedPoint(week, 1, 1200, 1000, 2),
+			aggregatedPoint(week, 2, 800, 1000, 1),
+		}},
+	}
+	schedules := &stubReportScheduleRepository{}
+	scheduleService := NewReportScheduleService(repo, schedules).(*reportScheduleService)
+	scheduleService.now = func() time.Time { return time.Date(2025, 6, 11, 10, 0, 0, 0, time.UTC) }
+	if _, err := scheduleService.CreateSchedule(1, ReportScheduleInput{
+		Frequency:  model.ReportFrequencyWeekly,
+		Recipients: []string{"owner@example.com"},
+		AttachPDF:  true,
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mailer := &stubMailer{err: errors.New("connection refused")}
+	scheduler := NewReportScheduler(schedules, NewAnalyticsService(repo, nil, nil, nil), mailer, slog.New(slog.NewTextHandler(io.Discard, nil))).(*reportScheduler)
+	now := time.Date(2025, 6, 16, 6, 5, 0, 0, time.UTC)
+	scheduler.now = func() time.Time { return now }
+
+	if err := scheduler.SendDue(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	schedule := schedules.schedules[0]
+	if schedule.LastError != "connection refused" || !schedule.NextRunAt.Equal(now.Add(time.Hour)) || schedule.LastSentAt != nil {
+		t.Fatalf("expected a retry in an hour, got %+v", schedule)
+	}
+
+	mailer.err = nil
+	now = now.Add(time.Hour)
+	if err := scheduler.SendDue(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := scheduler.SendDue(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(mailer.sent) != 1 {
+		t.Fatalf("expected one email, got %d", len(mailer.sent))
+	}
+	schedule = schedules.schedules[0]
+	if schedule.LastError != "" || schedule.LastSentAt == nil || !schedule.NextRunAt.Equal(time.Date(2025, 6, 23, 6, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the next report on Monday the 23rd, got %+v", schedule)
+	}
+
+	email := mailer.sent[0]
+	if email.Subject != "Weekly irrigation report - Farm 1, 9 Jun 2025 - 15 Jun 2025" {
+		t.Errorf("unexpected subject %q", email.Subject)
+	}
+	if !strings.Contains(email.Text, "Water volume: 2,000 L") || !strings.Contains(email.Text, "Sector 2: 800 L") {
+		t.Errorf("unexpected text:\n%s", email.Text)
+	}
+	if len(email.Attachments) != 1 || email.Attachments[0].Filename != "farm-1-irrigation-report-2025-06-09.pdf" ||
+		!strings.HasPrefix(string(email.Attachments[0].Content), "%PDF-") {
+		t.Errorf("expected the PDF report attached, got %+v", email.Attachments)
+	}
+}
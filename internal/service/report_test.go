@@ -0,0 +1,85 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestRenderReportPDF verifies the report is a well-formed PDF whose cross-reference table points
+// at its objects, and that a long sector breakdown flows onto further pages
+func TestRenderReportPDF(t *testing.T) {
+	analytics, _, _, _ := exampleResponses(EfficiencyWeightingMean)
+	for i := 0; i < 60; i++ {
+		analytics.SectorBreakdown = append(analytics.SectorBreakdown, SectorBreakdown{SectorID: uint(100 + i), TotalWaterVolume: 10})
+	}
+
+	pdf := RenderReportPDF(analytics, time.Date(2024, 7, 1, 8, 0, 0, 0, time.UTC))
+
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatal("expected a PDF header and trailer")
+	}
+	for _, text := range []string{"(Irrigation report - Farm 1)", "(1 Jun 2024 - 2 Jun 2024, daily buckets)", "(92.1%)", "(+6.5%)", "(Generated 2024-07-01 08:00 UTC)"} {
+		if !bytes.Contains(pdf, []byte(text)) {
+			t.Errorf("expected the report to contain %s", text)
+		}
+	}
+
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	if startxref == nil {
+		t.Fatal("missing startxref")
+	}
+	xref, _ := strconv.Atoi(string(startxref[1]))
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf[xref:], -1)
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		if want := fmt.Sprintf("%d 0 obj", i+1); !bytes.HasPrefix(pdf[offset:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q, want %q", i+1, pdf[offset:offset+10], want)
+		}
+	}
+
+	pages := regexp.MustCompile(`/Count (\d+)`).FindSubmatch(pdf)
+	if pages == nil || string(pages[1]) == "1" {
+		t.Errorf("expected the sector breakdown to continue on a second page, got %s", pages)
+	}
+	if !bytes.Contains(pdf, []byte("(Page 2 of ")) {
+		t.Error("expected every page to be numbered")
+	}
+}
+
+func TestPDFString(t *testing.T) {
+	tests := map[string]string{
+		"Farm (north)": `Farm \(north\)`,
+		`C:\data`:      `C:\\data`,
+		"Jun – Aug":    "Jun - Aug",
+		"Zoë":          `Zo\353`,
+		"Farm 灌溉":      "Farm ??",
+	}
+	for input, want := range tests {
+		if got := pdfString(input); got != want {
+			t.Errorf("pdfString(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestReportNumber(t *testing.T) {
+	tests := []struct {
+		value    float64
+		decimals int
+		want     string
+	}{
+		{0, 0, "0"},
+		{999, 0, "999"},
+		{1234567.891, 2, "1,234,567.89"},
+		{-2450.5, 1, "-2,450.5"},
+		{-0.01, 0, "0"},
+	}
+	for _, tt := range tests {
+		if got := reportNumber(tt.value, tt.decimals); got != tt.want {
+			t.Errorf("reportNumber(%v, %d) = %q, want %q", tt.value, tt.decimals, got, tt.want)
+		}
+	}
+}
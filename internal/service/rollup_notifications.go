@@ -0,0 +1,97 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"irrigation-analytics/internal/repository"
+)
+
+// RollupFinalizedEvent is the event type of a RollupFinalization
+const RollupFinalizedEvent = "rollup.finalized"
+
+// RollupFinalization announces that a farm's days in [From, Through) were rolled up: their
+// totals are final, where the days after Through are still accumulating
+type RollupFinalization struct {
+	Event       string                      `json:"event"`
+	FarmID      uint                        `json:"farm_id"`
+	From        time.Time                   `json:"from"`
+	Through     time.Time                   `json:"through"`
+	FinalizedAt time.Time                   `json:"finalized_at"`
+	Days        []repository.RollupDayTotal `json:"days"`
+}
+
+// RollupListener is told about each finalization once its refresh has committed. Listeners run
+// in turn on the refresher's goroutine, so a slow listener delays the next farm.
+type RollupListener func(ctx context.Context, finalization RollupFinalization)
+
+// notifyFinalized tells the listeners about the days a farm's refresh rolled up for the first
+// time. A failure to read the totals is logged; the days stay final and are not announced again.
+func (s *rollupService) notifyFinalized(ctx context.Context, repo repository.RollupRepository, refresh *repository.RollupRefresh) {
+	if len(s.listeners) == 0 || !refresh.RolledUpThrough.After(refresh.FinalizedFrom) {
+		return
+	}
+	days, err := repo.DayTotals(refresh.FarmID, refresh.FinalizedFrom, refresh.RolledUpThrough)
+	if err != nil {
+		s.logger.Error("reading finalized rollup days failed", "farm_id", refresh.FarmID, "error", err)
+		return
+	}
+	if days == nil {
+		days = []repository.RollupDayTotal{}
+	}
+
+	finalization := RollupFinalization{
+		Event:       RollupFinalizedEvent,
+		FarmID:      refresh.FarmID,
+		From:        refresh.FinalizedFrom,
+		Through:     refresh.RolledUpThrough,
+		FinalizedAt: s.now().UTC(),
+		Days:        days,
+	}
+	for _, listener := range s.listeners {
+		listener(ctx, finalization)
+	}
+}
+
+// NewRollupWebhook returns a listener that POSTs each finalization as JSON to url. Failed
+// deliveries are logged and not retried.
+func NewRollupWebhook(url string, logger *slog.Logger) RollupListener {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return func(ctx context.Context, finalization RollupFinalization) {
+		if err := postRollupWebhook(ctx, client, url, finalization); err != nil {
+			logger.Error("rollup webhook failed",
+				"farm_id", finalization.FarmID,
+				"through", finalization.Through.Format("2006-01-02"),
+				"error", err,
+			)
+		}
+	}
+}
+
+// postRollupWebhook delivers one finalization, failing on any non-2xx response
+func postRollupWebhook(ctx context.Context, client *http.Client, url string, finalization RollupFinalization) error {
+	body, err := json.Marshal(finalization)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"irrigation-analytics/internal/repository"
+)
+
+// DefaultRollupInterval is how often the background refresher rebuilds stale rollup days
+const DefaultRollupInterval = 15 * time.Minute
+
+// DefaultRollupRecentDays is how many days, today included, are left out of the rollups and
+// always aggregated from the events, since late events mostly land on them
+const DefaultRollupRecentDays = 2
+
+// RollupService keeps the daily rollups behind the bucketed aggregations current
+type RollupService interface {
+	RefreshAll(ctx context.Context) (*RollupReport, error)
+	Run(ctx context.Context, interval time.Duration)
+}
+
+// RollupReport summarises a refresh of every farm
+type RollupReport struct {
+	Farms       int                        `json:"farms"`
+	RebuiltRows int64                      `json:"rebuilt_rows"`
+	Failed      []uint                     `json:"failed_farm_ids"`
+	Refreshes   []repository.RollupRefresh `json:"refreshes"`
+}
+
+// rollupService implements RollupService
+type rollupService struct {
+	repo       repository.RollupRepository
+	recentDays int
+	listeners  []RollupListener
+	logger     *slog.Logger
+	now        func() time.Time
+}
+
+// NewRollupService creates a rollup service that leaves the last recentDays days to the events;
+// recentDays below 1 uses DefaultRollupRecentDays. Each listener is told about the days every
+// refresh finalizes.
+func NewRollupService(repo repository.RollupRepository, recentDays int, logger *slog.Logger, listeners ...RollupListener) RollupService {
+	if recentDays < 1 {
+		recentDays = DefaultRollupRecentDays
+	}
+	return &rollupService{repo: repo, recentDays: recentDays, listeners: listeners, logger: logger, now: time.Now}
+}
+
+// RefreshAll refreshes every farm's rollups up to the start of the recent days. A farm that fails
+// is logged and listed in the report without stopping the others; only listing the farms or
+// ctx ending fails the whole refresh.
+func (s *rollupService) RefreshAll(ctx context.Context) (*RollupReport, error) {
+	repo := s.repo.WithContext(ctx)
+	farmIDs, err := repo.ListFarmIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now().UTC()
+	through := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1-s.recentDays)
+
+	report := &RollupReport{Farms: len(farmIDs), Failed: []uint{}, Refreshes: []repository.RollupRefresh{}}
+	for _, farmID := range farmIDs {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		refresh, err := repo.RefreshFarm(farmID, through)
+		if err != nil {
+			s.logger.Error("rollup refresh failed", "farm_id", farmID, "error", err)
+			report.Failed = append(report.Failed, farmID)
+			continue
+		}
+		report.RebuiltRows += refresh.RebuiltRows
+		report.Refreshes = append(report.Refreshes, *refresh)
+		s.notifyFinalized(ctx, repo, refresh)
+	}
+	return report, nil
+}
+
+// Run refreshes the rollups once immediately and then every interval until ctx ends; an interval
+// of 0 or less uses DefaultRollupInterval
+func (s *rollupService) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultRollupInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		started := s.now()
+		report, err := s.RefreshAll(ctx)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			s.logger.Error("rollup refresh failed", "error", err)
+		default:
+			s.logger.Info("rollups refreshed",
+				"farms", report.Farms,
+				"rebuilt_rows", report.RebuiltRows,
+				"failed_farms", len(report.Failed),
+				"duration_ms", s.now().Sub(started).Milliseconds())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
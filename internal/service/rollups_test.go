@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/repository"
+)
+
+// stubRollupRepository records refreshes and fails the farms listed in failing
+type stubRollupRepository struct {
+	farmIDs []uint
+	failing map[uint]bool
+	through []time.Time
+}
+
+func (r *stubRollupRepository) ListFarmIDs() ([]uint, error) { return r.farmIDs, nil }
+
+func (r *stubRollupRepository) RefreshFarm(farmID uint, through time.Time) (*repository.RollupRefresh, error) {
+	r.through = append(r.through, through)
+	if r.failing[farmID] {
+		return nil, errors.New("lock timeout")
+	}
+	return &repository.RollupRefresh{FarmID: farmID, RebuiltRows: 10, FinalizedFrom: through.AddDate(0, 0, -1), RolledUpThrough: through}, nil
+}
+
+func (r *stubRollupRepository) DayTotals(farmID uint, from, to time.Time) ([]repository.RollupDayTotal, error) {
+	return []repository.RollupDayTotal{{Day: from, WaterVolume: 1500, EventCount: 3}}, nil
+}
+
+func (r *stubRollupRepository) WithContext(ctx context.Context) repository.RollupRepository {
+	return r
+}
+
+// TestRefreshAll verifies recent days are left out and a failing farm doesn't stop the others
+func TestRefreshAll(t *testing.T) {
+	repo := &stubRollupRepository{farmIDs: []uint{1, 2, 3}, failing: map[uint]bool{2: true}}
+	svc := NewRollupService(repo, 0, slog.New(slog.NewTextHandler(io.Discard, nil))).(*rollupService)
+	svc.now = func() time.Time { return time.Date(2025, 7, 10, 15, 30, 0, 0, time.UTC) }
+
+	report, err := svc.RefreshAll(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Farms != 3 || report.RebuiltRows != 20 || len(report.Failed) != 1 || report.Failed[0] != 2 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if expected := time.Date(2025, 7, 9, 0, 0, 0, 0, time.UTC); !repo.through[0].Equal(expected) {
+		t.Errorf("expected rollups through %v, got %v", expected, repo.through[0])
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := svc.RefreshAll(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancelled refresh to stop, got %v", err)
+	}
+}
+
+// TestRefreshAll_NotifiesFinalizedDays verifies listeners, including the webhook, get each farm's
+// newly rolled-up days
+func TestRefreshAll_NotifiesFinalizedDays(t *testing.T) {
+	var delivered []RollupFinalization
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var finalization RollupFinalization
+		if err := json.NewDecoder(r.Body).Decode(&finalization); err != nil {
+			t.Errorf("invalid webhook body: %v", err)
+		}
+		delivered = append(delivered, finalization)
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	var received []RollupFinalization
+	listener := func(ctx context.Context, finalization RollupFinalization) {
+		received = append(received, finalization)
+	}
+	repo := &stubRollupRepository{farmIDs: []uint{1, 2}, failing: map[uint]bool{2: true}}
+	svc := NewRollupService(repo, 0, logger, listener, NewRollupWebhook(server.URL, logger)).(*rollupService)
+	svc.now = func() time.Time { return time.Date(2025, 7, 10, 15, 30, 0, 0, time.UTC) }
+
+	if _, err := svc.RefreshAll(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(received) != 1 || len(delivered) != 1 {
+		t.Fatalf("expected one finalization for the farm that refreshed, got %d and %d delivered", len(received), len(delivered))
+	}
+	finalization := delivered[0]
+	day := time.Date(2025, 7, 8, 0, 0, 0, 0, time.UTC)
+	if finalization.Event != RollupFinalizedEvent || finalization.FarmID != 1 || !finalization.From.Equal(day) ||
+		len(finalization.Days) != 1 || finalization.Days[0].WaterVolume != 1500 {
+		t.Errorf("unexpected finalization %+v", finalization)
+	}
+}
@@ -0,0 +1,282 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"irrigation-analytics/internal/repository"
+)
+
+// Scenario change types
+const (
+	// ScenarioShiftOffPeak moves a fraction of the peak-hour volume into off-peak hours
+	ScenarioShiftOffPeak = "shift_off_peak"
+	// ScenarioSetEfficiency sets a sector's efficiency, scaling its volume to deliver the same
+	// real amount
+	ScenarioSetEfficiency = "set_efficiency"
+)
+
+// ScenarioService defines the interface for what-if evaluation of irrigation changes
+type ScenarioService interface {
+	FarmExists(farmID uint) (bool, error)
+	Evaluate(farmID uint, startDate, endDate time.Time, tariff Tariff, changes []ScenarioChange) (*ScenarioResponse, error)
+}
+
+// Tariff prices water by the hour it is used. Hours are UTC; the off-peak window runs from
+// OffPeakStartHour up to OffPeakEndHour and wraps past midnight when it starts later than it ends.
+type Tariff struct {
+	PeakPricePerM3    float64 `json:"peak_price_per_m3"`
+	OffPeakPricePerM3 float64 `json:"off_peak_price_per_m3"`
+	OffPeakStartHour  int     `json:"off_peak_start_hour"`
+	OffPeakEndHour    int     `json:"off_peak_end_hour"`
+}
+
+// offPeak reports whether the UTC hour falls in the off-peak window
+func (t Tariff) offPeak(hour int) bool {
+	if t.OffPeakStartHour <= t.OffPeakEndHour {
+		return hour >= t.OffPeakStartHour && hour < t.OffPeakEndHour
+	}
+	return hour >= t.OffPeakStartHour || hour < t.OffPeakEndHour
+}
+
+// cost prices peak and off-peak volumes given in liters
+func (t Tariff) cost(peakVolume, offPeakVolume float64) float64 {
+	return (peakVolume*t.PeakPricePerM3 + offPeakVolume*t.OffPeakPricePerM3) / 1000
+}
+
+// ScenarioChange is one hypothetical change. SectorID limits a shift to one sector and is
+// required to set an efficiency.
+type ScenarioChange struct {
+	Type       string  `json:"type"`
+	SectorID   *uint   `json:"sector_id,omitempty"`
+	Fraction   float64 `json:"fraction,omitempty"`
+	Efficiency float64 `json:"efficiency,omitempty"`
+}
+
+// ScenarioResponse compares the recorded volume and cost of a period with the same period
+// under the requested changes
+type ScenarioResponse struct {
+	FarmID   uint           `json:"farm_id"`
+	Period   PeriodInfo     `json:"period"`
+	Tariff   Tariff         `json:"tariff"`
+	Baseline ScenarioTotals `json:"baseline"`
+	Scenario ScenarioTotals `json:"scenario"`
+	// VolumeChange and CostChange are scenario minus baseline; the percentages are nil when the
+	// baseline is 0
+	VolumeChange        float64                `json:"volume_change"`
+	VolumeChangePercent *float64               `json:"volume_change_percent"`
+	CostChange          float64                `json:"cost_change"`
+	CostChangePercent   *float64               `json:"cost_change_percent"`
+	Changes             []ScenarioChangeResult `json:"changes"`
+	Sectors             []ScenarioSector       `json:"sectors"`
+}
+
+// ScenarioTotals are volumes in liters and their cost under the tariff
+type ScenarioTotals struct {
+	WaterVolume   float64 `json:"water_volume"`
+	PeakVolume    float64 `json:"peak_volume"`
+	OffPeakVolume float64 `json:"off_peak_volume"`
+	Cost          float64 `json:"cost"`
+}
+
+// ScenarioChangeResult reports what one change did, applied after the changes before it
+type ScenarioChangeResult struct {
+	ScenarioChange
+	Applied      bool    `json:"applied"`
+	VolumeChange float64 `json:"volume_change"`
+	CostChange   float64 `json:"cost_change"`
+	// Note explains why a change was not applied
+	Note string `json:"note,omitempty"`
+}
+
+// ScenarioSector compares one sector's baseline and scenario
+type ScenarioSector struct {
+	SectorID           uint           `json:"sector_id"`
+	Efficiency         float64        `json:"efficiency"`
+	ScenarioEfficiency float64        `json:"scenario_efficiency"`
+	Baseline           ScenarioTotals `json:"baseline"`
+	Scenario           ScenarioTotals `json:"scenario"`
+}
+
+// scenarioSector is a sector's state while changes are applied
+type scenarioSector struct {
+	peak, offPeak             float64
+	realAmount, nominalAmount float64
+	efficiency                float64
+}
+
+// scenarioService implements ScenarioService
+type scenarioService struct {
+	repo repository.IrrigationRepository
+}
+
+// NewScenarioService creates a new scenario service
+func NewScenarioService(repo repository.IrrigationRepository) ScenarioService {
+	return &scenarioService{repo: repo}
+}
+
+// FarmExists checks if a farm exists
+func (s *scenarioService) FarmExists(farmID uint) (bool, error) {
+	return s.repo.FarmExists(farmID)
+}
+
+// Evaluate splits each sector's recorded volume into peak and off-peak hours by event start and
+// applies the changes in order. Setting an efficiency scales the sector's volume by its recorded
+// efficiency over the target, both hour bands alike, assuming the real amount the crop needs
+// stays the same. A change to a sector is rejected with ErrSectorNotFound when the farm doesn't
+// have it, and skipped with a note when the sector has no data to change.
+func (s *scenarioService) Evaluate(farmID uint, startDate, endDate time.Time, tariff Tariff, changes []ScenarioChange) (*ScenarioResponse, error) {
+	sectors, err := s.repo.ListSectors(farmID)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[uint]bool, len(sectors))
+	for _, sector := range sectors {
+		known[sector.ID] = true
+	}
+	for i, change := range changes {
+		if change.SectorID != nil && !known[*change.SectorID] {
+			return nil, fmt.Errorf("changes[%d]: %w: %d", i, ErrSectorNotFound, *change.SectorID)
+		}
+	}
+
+	queryOpts, err := withAttribution(s.repo, farmID, repository.QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+	totals, err := s.repo.GetHourOfDayTotals(farmID, startDate, endDate, queryOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	state := make(map[uint]*scenarioSector)
+	for _, total := range totals {
+		sector := state[total.SectorID]
+		if sector == nil {
+			sector = &scenarioSector{}
+			state[total.SectorID] = sector
+		}
+		if tariff.offPeak(total.Hour) {
+			sector.offPeak += total.WaterVolume
+		} else {
+			sector.peak += total.WaterVolume
+		}
+		sector.realAmount += total.RealAmount
+		sector.nominalAmount += total.NominalAmount
+	}
+	ids := make([]uint, 0, len(state))
+	for id, sector := range state {
+		if sector.nominalAmount > 0 {
+			sector.efficiency = sector.realAmount / sector.nominalAmount
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	baseline := make(map[uint]*scenarioSector, len(state))
+	for id, sector := range state {
+		copied := *sector
+		baseline[id] = &copied
+	}
+
+	response := &ScenarioResponse{
+		FarmID:  farmID,
+		Period:  PeriodInfo{StartDate: startDate, EndDate: endDate},
+		Tariff:  tariff,
+		Changes: make([]ScenarioChangeResult, 0, len(changes)),
+		Sectors: make([]ScenarioSector, 0, len(ids)),
+	}
+	for _, change := range changes {
+		before := scenarioTotals(state, tariff)
+		result := ScenarioChangeResult{ScenarioChange: change}
+		result.Note = applyScenarioChange(state, change)
+		result.Applied = result.Note == ""
+		after := scenarioTotals(state, tariff)
+		result.VolumeChange = math.Round((after.WaterVolume-before.WaterVolume)*100) / 100
+		result.CostChange = math.Round((after.Cost-before.Cost)*100) / 100
+		response.Changes = append(response.Changes, result)
+	}
+
+	for _, id := range ids {
+		base, current := baseline[id], state[id]
+		response.Sectors = append(response.Sectors, ScenarioSector{
+			SectorID:           id,
+			Efficiency:         math.Round(base.efficiency*10000) / 10000,
+			ScenarioEfficiency: math.Round(current.efficiency*10000) / 10000,
+			Baseline:           roundedTotals(base.peak, base.offPeak, tariff),
+			Scenario:           roundedTotals(current.peak, current.offPeak, tariff),
+		})
+	}
+
+	base, scenario := scenarioTotals(baseline, tariff), scenarioTotals(state, tariff)
+	response.Baseline = roundedTotals(base.PeakVolume, base.OffPeakVolume, tariff)
+	response.Scenario = roundedTotals(scenario.PeakVolume, scenario.OffPeakVolume, tariff)
+	response.VolumeChange = math.Round((scenario.WaterVolume-base.WaterVolume)*100) / 100
+	response.CostChange = math.Round((scenario.Cost-base.Cost)*100) / 100
+	response.VolumeChangePercent = changePercent(scenario.WaterVolume, base.WaterVolume)
+	response.CostChangePercent = changePercent(scenario.Cost, base.Cost)
+	return response, nil
+}
+
+// applyScenarioChange applies one change to the sectors it targets, returning why it could not
+// be applied, or "" when it was
+func applyScenarioChange(state map[uint]*scenarioSector, change ScenarioChange) string {
+	switch change.Type {
+	case ScenarioShiftOffPeak:
+		shifted := false
+		for id, sector := range state {
+			if change.SectorID != nil && id != *change.SectorID {
+				continue
+			}
+			moved := sector.peak * change.Fraction
+			sector.peak -= moved
+			sector.offPeak += moved
+			shifted = shifted || moved > 0
+		}
+		if !shifted {
+			return "no peak-hour volume to shift"
+		}
+	case ScenarioSetEfficiency:
+		sector := state[*change.SectorID]
+		if sector == nil || sector.efficiency <= 0 {
+			return "sector has no recorded efficiency in the period"
+		}
+		scale := sector.efficiency / change.Efficiency
+		sector.peak *= scale
+		sector.offPeak *= scale
+		sector.efficiency = change.Efficiency
+	}
+	return ""
+}
+
+// scenarioTotals sums the sectors' volumes and prices them, unrounded
+func scenarioTotals(state map[uint]*scenarioSector, tariff Tariff) ScenarioTotals {
+	var totals ScenarioTotals
+	for _, sector := range state {
+		totals.PeakVolume += sector.peak
+		totals.OffPeakVolume += sector.offPeak
+	}
+	totals.WaterVolume = totals.PeakVolume + totals.OffPeakVolume
+	totals.Cost = tariff.cost(totals.PeakVolume, totals.OffPeakVolume)
+	return totals
+}
+
+// roundedTotals prices peak and off-peak volumes, rounding everything to 2 decimals
+func roundedTotals(peak, offPeak float64, tariff Tariff) ScenarioTotals {
+	return ScenarioTotals{
+		WaterVolume:   math.Round((peak+offPeak)*100) / 100,
+		PeakVolume:    math.Round(peak*100) / 100,
+		OffPeakVolume: math.Round(offPeak*100) / 100,
+		Cost:          math.Round(tariff.cost(peak, offPeak)*100) / 100,
+	}
+}
+
+// changePercent is the change from baseline to scenario in percent, nil when baseline is 0
+func changePercent(scenario, baseline float64) *float64 {
+	if baseline == 0 {
+		return nil
+	}
+	return roundedPtr((scenario-baseline)/baseline*100, 2)
+}
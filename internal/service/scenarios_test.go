@@ -0,0 +1,113 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// stubScenarioRepository serves fixed sectors and hour-of-day totals
+type stubScenarioRepository struct {
+	*stubRepository
+	sectorList []model.IrrigationSector
+	totals     []repository.HourOfDayTotal
+}
+
+func (r *stubScenarioRepository) ListSectors(farmID uint) ([]model.IrrigationSector, error) {
+	return r.sectorList, nil
+}
+
+func (r *stubScenarioRepository) GetHourOfDayTotals(farmID uint, startDate, endDate time.Time, opts repository.QueryOptions) ([]repository.HourOfDayTotal, error) {
+	return r.totals, nil
+}
+
+func hourTotal(sectorID uint, hour int, volume, real, nominal float64) repository.HourOfDayTotal {
+	return repository.HourOfDayTotal{SectorID: sectorID, Hour: hour, SummaryResult: repository.SummaryResult{
+		WaterVolume: volume, RealAmount: real, NominalAmount: nominal, EventCount: 1,
+	}}
+}
+
+// TestScenarioEvaluate verifies changes apply in order, each reporting its own effect, with
+// volumes split by the off-peak window wrapping midnight
+func TestScenarioEvaluate(t *testing.T) {
+	repo := &stubScenarioRepository{
+		stubRepository: &stubRepository{},
+		sectorList:     []model.IrrigationSector{{ID: 1}, {ID: 2}, {ID: 3}},
+		totals: []repository.HourOfDayTotal{
+			hourTotal(1, 8, 1000, 900, 1000),
+			hourTotal(1, 23, 500, 450, 500),
+			hourTotal(2, 12, 2000, 1600, 2000),
+		},
+	}
+	sector2, sector3 := uint(2), uint(3)
+	tariff := Tariff{PeakPricePerM3: 0.4, OffPeakPricePerM3: 0.1, OffPeakStartHour: 22, OffPeakEndHour: 6}
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	response, err := NewScenarioService(repo).Evaluate(1, day, day.AddDate(0, 1, 0), tariff, []ScenarioChange{
+		{Type: ScenarioShiftOffPeak, Fraction: 0.5},
+		{Type: ScenarioSetEfficiency, SectorID: &sector2, Efficiency: 1},
+		{Type: ScenarioSetEfficiency, SectorID: &sector3, Efficiency: 0.9},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if response.Baseline != (ScenarioTotals{WaterVolume: 3500, PeakVolume: 3000, OffPeakVolume: 500, Cost: 1.25}) {
+		t.Errorf("unexpected baseline %+v", response.Baseline)
+	}
+	if response.Scenario != (ScenarioTotals{WaterVolume: 3100, PeakVolume: 1300, OffPeakVolume: 1800, Cost: 0.7}) {
+		t.Errorf("unexpected scenario %+v", response.Scenario)
+	}
+	if response.VolumeChange != -400 || *response.VolumeChangePercent != -11.43 || response.CostChange != -0.55 || *response.CostChangePercent != -44 {
+		t.Errorf("unexpected changes: volume %v (%v%%), cost %v (%v%%)",
+			response.VolumeChange, *response.VolumeChangePercent, response.CostChange, *response.CostChangePercent)
+	}
+
+	expected := []struct {
+		applied      bool
+		volumeChange float64
+		costChange   float64
+	}{{true, 0, -0.45}, {true, -400, -0.1}, {false, 0, 0}}
+	for i, want := range expected {
+		got := response.Changes[i]
+		if got.Applied != want.applied || got.VolumeChange != want.volumeChange || got.CostChange != want.costChange {
+			t.Errorf("change %d: expected applied %v, volume %v, cost %v, got %+v", i, want.applied, want.volumeChange, want.costChange, got)
+		}
+	}
+	if response.Changes[2].Note == "" {
+		t.Error("expected a note on the change to a sector without data")
+	}
+
+	if len(response.Sectors) != 2 || response.Sectors[1].SectorID != 2 || response.Sectors[1].Efficiency != 0.8 || response.Sectors[1].ScenarioEfficiency != 1 {
+		t.Errorf("unexpected sectors %+v", response.Sectors)
+	}
+}
+
+func TestScenarioEvaluate_UnknownSector(t *testing.T) {
+	repo := &stubScenarioRepository{stubRepository: &stubRepository{}, sectorList: []model.IrrigationSector{{ID: 1}}}
+	sectorID := uint(7)
+	_, err := NewScenarioService(repo).Evaluate(1, time.Now(), time.Now(), Tariff{OffPeakEndHour: 6}, []ScenarioChange{
+		{Type: ScenarioShiftOffPeak, Fraction: 0.2, SectorID: &sectorID},
+	})
+	if !errors.Is(err, ErrSectorNotFound) {
+		t.Errorf("expected ErrSectorNotFound, got %v", err)
+	}
+}
+
+func TestTariffOffPeak(t *testing.T) {
+	overnight := Tariff{OffPeakStartHour: 22, OffPeakEndHour: 6}
+	daytime := Tariff{OffPeakStartHour: 10, OffPeakEndHour: 16}
+	for hour, want := range map[int]bool{21: false, 22: true, 0: true, 5: true, 6: false} {
+		if got := overnight.offPeak(hour); got != want {
+			t.Errorf("overnight window, hour %d: expected %v", hour, want)
+		}
+	}
+	for hour, want := range map[int]bool{9: false, 10: true, 15: true, 16: false} {
+		if got := daytime.offPeak(hour); got != want {
+			t.Errorf("daytime window, hour %d: expected %v", hour, want)
+		}
+	}
+}
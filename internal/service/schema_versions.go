@@ -0,0 +1,62 @@
+package service
+
+import "sort"
+
+// Analytics response schema versions
+const (
+	// SchemaV1 is the original analytics response schema
+	SchemaV1 = "v1"
+	// SchemaV2 weights efficiency by volume by default and names its version in the body
+	SchemaV2 = "v2"
+)
+
+// DefaultSchemaVersion is served when a request does not ask for a version
+const DefaultSchemaVersion = SchemaV1
+
+// SchemaVersion describes how one response schema version is produced and serialized
+type SchemaVersion struct {
+	Name string
+	// EfficiencyWeighting is used when the request does not set efficiency_weighting
+	EfficiencyWeighting string
+	// SerializeAnalytics returns the value written as the analytics response body
+	SerializeAnalytics func(*AnalyticsResponse) any
+}
+
+// schemaVersions is the registry of supported response schema versions. Add a version here,
+// rather than changing an existing serializer, when AnalyticsResponse changes incompatibly.
+var schemaVersions = map[string]SchemaVersion{
+	SchemaV1: {
+		Name:                SchemaV1,
+		EfficiencyWeighting: EfficiencyWeightingMean,
+		SerializeAnalytics:  func(r *AnalyticsResponse) any { return r },
+	},
+	SchemaV2: {
+		Name:                SchemaV2,
+		EfficiencyWeighting: EfficiencyWeightingVolume,
+		SerializeAnalytics: func(r *AnalyticsResponse) any {
+			return analyticsResponseV2{SchemaVersion: SchemaV2, AnalyticsResponse: r}
+		},
+	},
+}
+
+// analyticsResponseV2 is the v2 analytics body: the v1 fields plus schema_version
+type analyticsResponseV2 struct {
+	SchemaVersion string `json:"schema_version"`
+	*AnalyticsResponse
+}
+
+// LookupSchemaVersion returns a registered schema version; ok is false for an unknown name
+func LookupSchemaVersion(name string) (version SchemaVersion, ok bool) {
+	version, ok = schemaVersions[name]
+	return version, ok
+}
+
+// SchemaVersions returns the names of every registered schema version, in order
+func SchemaVersions() []string {
+	names := make([]string, 0, len(schemaVersions))
+	for name := range schemaVersions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
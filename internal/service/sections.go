@@ -0,0 +1,46 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// sectionTask is one independent query of an analytics request
+type sectionTask struct {
+	// stage names the task's timing in debug responses
+	stage string
+	// section is the optional Section* the task fills; empty when a failure fails the request
+	section string
+	run     func() error
+}
+
+// runSections runs the tasks concurrently and returns their errors in task order, so warnings
+// come out in the same order whichever query finishes first. A panicking task fails with an
+// error instead of taking the process down. Each task's duration is recorded as its own stage.
+func (s *analyticsService) runSections(tasks []sectionTask) []error {
+	errs := make([]error, len(tasks))
+	durations := make([]time.Duration, len(tasks))
+
+	var wg sync.WaitGroup
+	for i, task := range tasks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			defer func() {
+				durations[i] = time.Since(start)
+				if r := recover(); r != nil {
+					errs[i] = fmt.Errorf("%s panicked: %v", task.stage, r)
+				}
+			}()
+			errs[i] = task.run()
+		}()
+	}
+	wg.Wait()
+
+	for i, task := range tasks {
+		s.trace.record(task.stage, durations[i])
+	}
+	return errs
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestRunSections verifies tasks run concurrently, errors keep task order and a panic becomes an error
+func TestRunSections(t *testing.T) {
+	failed := errors.New("query failed")
+	release := make(chan struct{})
+	tasks := []sectionTask{
+		// The first task only finishes once the second has started, so they must overlap
+		{stage: "slow", run: func() error { <-release; return failed }},
+		{stage: "fast", run: func() error { close(release); return nil }},
+		{stage: "broken", run: func() error { panic("nil map") }},
+	}
+
+	done := make(chan []error)
+	go func() { done <- (&analyticsService{}).runSections(tasks) }()
+
+	select {
+	case errs := <-done:
+		if !errors.Is(errs[0], failed) || errs[1] != nil || errs[2] == nil {
+			t.Errorf("expected errors in task order with the panic reported, got %v", errs)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the sections to run concurrently")
+	}
+}
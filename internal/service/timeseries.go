@@ -0,0 +1,256 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// Time series data sources; each source needed by a request is queried once
+const (
+	sourceIrrigation = "irrigation"
+	sourcePressure   = "pressure"
+)
+
+// TimeSeriesResponse holds aligned series: Series[metric][i] is the value at Timestamps[i],
+// or null when the bucket has no data for that metric
+type TimeSeriesResponse struct {
+	FarmID      uint                  `json:"farm_id"`
+	SectorID    *uint                 `json:"sector_id,omitempty"`
+	Period      PeriodInfo            `json:"period"`
+	Aggregation string                `json:"aggregation"`
+	Timestamps  []time.Time           `json:"timestamps"`
+	Series      map[string][]*float64 `json:"series"`
+}
+
+// timeseriesBucket accumulates every source's values for one bucket across sectors
+type timeseriesBucket struct {
+	irrigation    *repository.AggregatedDataWithCount
+	minPressure   float64
+	pressureSum   float64
+	pressureCount int
+}
+
+// metricDefinition describes how a metric is sourced and read from a bucket
+type metricDefinition struct {
+	source string
+	value  func(s *analyticsService, bucket *timeseriesBucket) *float64
+}
+
+// metricRegistry lists the metrics available to the time series endpoint
+var metricRegistry = map[string]metricDefinition{
+	"volume": {sourceIrrigation, func(s *analyticsService, b *timeseriesBucket) *float64 {
+		return roundedValue(b.irrigation.Data.WaterVolume, 2)
+	}},
+	"duration": {sourceIrrigation, func(s *analyticsService, b *timeseriesBucket) *float64 {
+		return roundedValue(float64(b.irrigation.Data.Duration), 0)
+	}},
+	"events": {sourceIrrigation, func(s *analyticsService, b *timeseriesBucket) *float64 {
+		return roundedValue(float64(b.irrigation.EventCount), 0)
+	}},
+	"real_amount": {sourceIrrigation, func(s *analyticsService, b *timeseriesBucket) *float64 {
+		return roundedValue(b.irrigation.Data.RealAmount, 2)
+	}},
+	"nominal_amount": {sourceIrrigation, func(s *analyticsService, b *timeseriesBucket) *float64 {
+		return roundedValue(b.irrigation.Data.NominalAmountOrZero(), 2)
+	}},
+	"efficiency": {sourceIrrigation, func(s *analyticsService, b *timeseriesBucket) *float64 {
+		efficiency, _, _, _ := s.bucketEfficiency(*b.irrigation)
+		return &efficiency
+	}},
+	"min_pressure": {sourcePressure, func(s *analyticsService, b *timeseriesBucket) *float64 {
+		return roundedValue(b.minPressure, 3)
+	}},
+	"avg_pressure": {sourcePressure, func(s *analyticsService, b *timeseriesBucket) *float64 {
+		return roundedValue(b.pressureSum/float64(b.pressureCount), 3)
+	}},
+}
+
+// TimeSeriesMetrics returns the names of all available metrics, sorted
+func TimeSeriesMetrics() []string {
+	names := make([]string, 0, len(metricRegistry))
+	for name := range metricRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateMetrics returns an error naming the first metric that isn't in the registry
+func ValidateMetrics(metrics []string) error {
+	for _, metric := range metrics {
+		if _, exists := metricRegistry[metric]; !exists {
+			return fmt.Errorf("unknown metric %q; available metrics: %s", metric, strings.Join(TimeSeriesMetrics(), ", "))
+		}
+	}
+	return nil
+}
+
+// GetTimeSeries returns the requested metrics as series aligned on every bucket of the range.
+// The sources behind the metrics are each queried once, and values are combined across sectors.
+func (s *analyticsService) GetTimeSeries(farmID uint, sectorID *uint, startDate, endDate time.Time, aggregation string, metrics []string) (*TimeSeriesResponse, error) {
+	if err := ValidateMetrics(metrics); err != nil {
+		return nil, err
+	}
+
+	sources := make(map[string]bool)
+	for _, metric := range metrics {
+		sources[metricRegistry[metric].source] = true
+	}
+	plan := queryPlan{queries: len(sources), bucketCost: len(sources) * bucketCount(startDate, endDate, aggregation)}
+	if err := s.budget.check(plan); err != nil {
+		return nil, err
+	}
+
+	buckets := make(map[int64]*timeseriesBucket)
+	bucketFor := func(start time.Time) *timeseriesBucket {
+		key := start.Unix()
+		if buckets[key] == nil {
+			buckets[key] = &timeseriesBucket{}
+		}
+		return buckets[key]
+	}
+
+	if sources[sourceIrrigation] {
+		opts, err := withAttribution(s.repo, farmID, repository.QueryOptions{})
+		if err != nil {
+			return nil, err
+		}
+		data, err := s.repo.GetAggregatedData(farmID, sectorID, startDate, endDate, aggregation, opts)
+		if err != nil {
+			return nil, err
+		}
+		if s, err = s.withFallbackRates(farmID, data); err != nil {
+			return nil, err
+		}
+		for _, item := range data {
+			mergeIrrigation(bucketFor(item.Data.StartTime), item)
+		}
+	}
+
+	if sources[sourcePressure] && s.pressure != nil {
+		data, err := s.pressure.GetPressureBuckets(farmID, sectorID, startDate, endDate, aggregation)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range data {
+			bucket := bucketFor(item.StartTime)
+			if bucket.pressureCount == 0 || item.MinPressure < bucket.minPressure {
+				bucket.minPressure = item.MinPressure
+			}
+			bucket.pressureSum += item.AvgPressure * float64(item.ReadingCount)
+			bucket.pressureCount += item.ReadingCount
+		}
+	}
+
+	timestamps := bucketStarts(startDate, endDate, aggregation)
+	series := make(map[string][]*float64, len(metrics))
+	for _, metric := range metrics {
+		definition := metricRegistry[metric]
+		values := make([]*float64, len(timestamps))
+		for i, timestamp := range timestamps {
+			bucket := buckets[timestamp.Unix()]
+			if bucket == nil {
+				continue
+			}
+			if definition.source == sourceIrrigation && bucket.irrigation == nil {
+				continue
+			}
+			if definition.source == sourcePressure && bucket.pressureCount == 0 {
+				continue
+			}
+			values[i] = definition.value(s, bucket)
+		}
+		series[metric] = values
+	}
+
+	return &TimeSeriesResponse{
+		FarmID:      farmID,
+		SectorID:    sectorID,
+		Period:      PeriodInfo{StartDate: startDate, EndDate: endDate},
+		Aggregation: aggregation,
+		Timestamps:  timestamps,
+		Series:      series,
+	}, nil
+}
+
+// mergeIrrigation adds one sector's aggregated bucket to the combined bucket
+func mergeIrrigation(bucket *timeseriesBucket, item repository.AggregatedDataWithCount) {
+	if bucket.irrigation == nil {
+		nominal := 0.0
+		bucket.irrigation = &repository.AggregatedDataWithCount{
+			Data: model.IrrigationData{StartTime: item.Data.StartTime, IrrigationSectorID: item.Data.IrrigationSectorID, NominalAmount: &nominal},
+		}
+	}
+	combined := bucket.irrigation
+	if combined.Data.IrrigationSectorID != item.Data.IrrigationSectorID {
+		// Mixed sectors have no single fallback flow rate
+		combined.Data.IrrigationSectorID = 0
+	}
+	combined.Data.WaterVolume += item.Data.WaterVolume
+	combined.Data.Duration += item.Data.Duration
+	combined.Data.RealAmount += item.Data.RealAmount
+	*combined.Data.NominalAmount += item.Data.NominalAmountOrZero()
+	combined.EventCount += item.EventCount
+	combined.MissingNominalCount += item.MissingNominalCount
+	combined.ExcludedEventCount += item.ExcludedEventCount
+}
+
+// bucketStarts lists the start of every bucket overlapping [startDate, endDate)
+func bucketStarts(startDate, endDate time.Time, aggregation string) []time.Time {
+	var starts []time.Time
+	for start := bucketStart(startDate, aggregation); start.Before(endDate); start = nextBucket(start, aggregation) {
+		starts = append(starts, start)
+	}
+	return starts
+}
+
+// validAggregation reports whether aggregation is a supported aggregation level
+func validAggregation(aggregation string) bool {
+	if _, ok := repository.ParseCustomAggregation(aggregation); ok {
+		return true
+	}
+	switch aggregation {
+	case "hourly", "daily", "weekly", "monthly", "quarterly", "yearly":
+		return true
+	}
+	return false
+}
+
+// nextBucket returns the start of the bucket following the one starting at start
+func nextBucket(start time.Time, aggregation string) time.Time {
+	if days, ok := repository.ParseCustomAggregation(aggregation); ok {
+		// Each year's last custom bucket ends at 31 December
+		next := start.AddDate(0, 0, days)
+		if next.Year() != start.Year() {
+			return time.Date(next.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		}
+		return next
+	}
+	switch aggregation {
+	case "hourly":
+		return start.Add(time.Hour)
+	case "weekly":
+		return start.AddDate(0, 0, 7)
+	case "monthly":
+		return start.AddDate(0, 1, 0)
+	case "quarterly":
+		return start.AddDate(0, 3, 0)
+	case "yearly":
+		return start.AddDate(1, 0, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// roundedValue rounds v to the given number of decimal places and returns a pointer to it
+func roundedValue(v float64, decimals int) *float64 {
+	scale := math.Pow(10, float64(decimals))
+	rounded := math.Round(v*scale) / scale
+	return &rounded
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/repository"
+)
+
+// TestGetTimeSeries verifies sectors are combined per bucket, series align on every bucket of
+// the range, and each source is queried once
+func TestGetTimeSeries(t *testing.T) {
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	repo := &stubRepository{
+		comparison: map[int][]repository.AggregatedDataWithCount{
+			0: {
+				aggregatedPoint(day, 1, 120, 100, 1),
+				aggregatedPoint(day, 2, 80, 100, 1),
+				aggregatedPoint(day.AddDate(0, 0, 2), 1, 50, 50, 1),
+			},
+		},
+	}
+	pressure := &stubPressureRepository{buckets: []repository.PressureBucket{
+		{StartTime: day.AddDate(0, 0, 1), IrrigationSectorID: 1, MinPressure: 2, AvgPressure: 3, ReadingCount: 1},
+		{StartTime: day.AddDate(0, 0, 1), IrrigationSectorID: 2, MinPressure: 1.5, AvgPressure: 2, ReadingCount: 3},
+	}}
+	svc := NewAnalyticsService(repo, nil, pressure, nil).(*analyticsService)
+
+	response, err := svc.GetTimeSeries(1, nil, day, day.AddDate(0, 0, 3), "daily", []string{"volume", "efficiency", "min_pressure", "avg_pressure"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.calls != 1 {
+		t.Errorf("expected 1 irrigation query, got %d", repo.calls)
+	}
+	if len(response.Timestamps) != 3 {
+		t.Fatalf("expected 3 timestamps, got %d", len(response.Timestamps))
+	}
+
+	volume := response.Series["volume"]
+	if volume[0] == nil || *volume[0] != 200 || volume[1] != nil || volume[2] == nil || *volume[2] != 50 {
+		t.Errorf("unexpected volume series: %v", volume)
+	}
+	if efficiency := response.Series["efficiency"]; efficiency[0] == nil || *efficiency[0] != 1 {
+		t.Errorf("expected combined efficiency 1 on day 1, got %v", efficiency[0])
+	}
+	if minPressure := response.Series["min_pressure"]; minPressure[0] != nil || minPressure[1] == nil || *minPressure[1] != 1.5 {
+		t.Errorf("unexpected min_pressure series: %v", minPressure)
+	}
+	if avgPressure := response.Series["avg_pressure"]; avgPressure[1] == nil || *avgPressure[1] != 2.25 {
+		t.Errorf("expected reading-weighted avg_pressure 2.25, got %v", avgPressure[1])
+	}
+}
+
+func TestValidateMetrics(t *testing.T) {
+	if err := ValidateMetrics([]string{"volume", "efficiency"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateMetrics([]string{"volume", "rainfall"}); err == nil {
+		t.Error("expected an error for rainfall, which has no data source")
+	}
+}
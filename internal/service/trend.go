@@ -0,0 +1,79 @@
+package service
+
+import "irrigation-analytics/internal/repository"
+
+// movingAverageWindow is the number of points in a trend moving average
+const movingAverageWindow = 7
+
+// PointTrend holds the smoothed values and trend of a data point's series: the points of the same
+// sector, in period order
+type PointTrend struct {
+	// VolumeMovingAvg and EfficiencyMovingAvg average this point and the six before it in the
+	// series; they are omitted until the series has seven points
+	VolumeMovingAvg     *float64 `json:"volume_moving_avg,omitempty"`
+	EfficiencyMovingAvg *float64 `json:"efficiency_moving_avg,omitempty"`
+	// VolumeSlope and EfficiencySlope are the least-squares slopes of the whole series, per data
+	// point; every point of a series carries the same slopes
+	VolumeSlope     float64 `json:"volume_slope"`
+	EfficiencySlope float64 `json:"efficiency_slope"`
+}
+
+// attachTrend sets each point's Trend from the series of its sector
+func attachTrend(points []AggregatedDataPoint, data []repository.AggregatedDataWithCount) {
+	series := make(map[uint][]int)
+	var order []uint
+	for i, item := range data {
+		id := item.Data.IrrigationSectorID
+		if _, exists := series[id]; !exists {
+			order = append(order, id)
+		}
+		series[id] = append(series[id], i)
+	}
+
+	for _, id := range order {
+		indexes := series[id]
+		volumes := make([]float64, len(indexes))
+		efficiencies := make([]float64, len(indexes))
+		for n, i := range indexes {
+			volumes[n] = points[i].WaterVolume
+			efficiencies[n] = points[i].Efficiency
+		}
+		volumeSlope := *roundedValue(linearSlope(volumes), 4)
+		efficiencySlope := *roundedValue(linearSlope(efficiencies), 6)
+
+		for n, i := range indexes {
+			trend := &PointTrend{VolumeSlope: volumeSlope, EfficiencySlope: efficiencySlope}
+			if n+1 >= movingAverageWindow {
+				trend.VolumeMovingAvg = roundedValue(mean(volumes[n+1-movingAverageWindow:n+1]), 2)
+				trend.EfficiencyMovingAvg = roundedValue(mean(efficiencies[n+1-movingAverageWindow:n+1]), 4)
+			}
+			points[i].Trend = trend
+		}
+	}
+}
+
+// linearSlope returns the least-squares slope of values against their index, or 0 for fewer than two values
+func linearSlope(values []float64) float64 {
+	n := float64(len(values))
+	if n < 2 {
+		return 0
+	}
+	var sumX, sumY, sumXY, sumXX float64
+	for i, y := range values {
+		x := float64(i)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	return (n*sumXY - sumX*sumY) / (n*sumXX - sumX*sumX)
+}
+
+// mean returns the arithmetic mean of values, which must not be empty
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/repository"
+)
+
+// TestGetIrrigationAnalytics_Trend verifies moving averages start at the seventh point of each
+// sector's series and slopes are fitted per series
+func TestGetIrrigationAnalytics_Trend(t *testing.T) {
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	var data []repository.AggregatedDataWithCount
+	for i := 0; i < 8; i++ {
+		// Sector 1 grows by 10 L a day; sector 2 stays flat
+		data = append(data,
+			aggregatedPoint(day.AddDate(0, 0, i), 1, 100+10*float64(i), 200, 1),
+			aggregatedPoint(day.AddDate(0, 0, i), 2, 50, 50, 1),
+		)
+	}
+	svc := NewAnalyticsService(&stubRepository{comparison: map[int][]repository.AggregatedDataWithCount{0: data}}, nil, nil, nil)
+
+	response, err := svc.GetIrrigationAnalytics(1, nil, day, day.AddDate(0, 0, 8), "daily", AnalyticsOptions{IncludeTrend: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, point := range response.Data {
+		if point.Trend == nil {
+			t.Fatalf("point %d: expected a trend", i)
+		}
+		if day := i / 2; (day < 6) != (point.Trend.VolumeMovingAvg == nil) {
+			t.Errorf("point %d: expected a moving average from the seventh day only, got %v", i, point.Trend.VolumeMovingAvg)
+		}
+	}
+
+	growing, flat := response.Data[14].Trend, response.Data[15].Trend
+	if growing.VolumeSlope != 10 || *growing.VolumeMovingAvg != 140 {
+		t.Errorf("expected slope 10 and moving average 140 for sector 1, got %+v", growing)
+	}
+	if growing.EfficiencySlope != 0.05 {
+		t.Errorf("expected efficiency slope 0.05, got %v", growing.EfficiencySlope)
+	}
+	if flat.VolumeSlope != 0 || *flat.VolumeMovingAvg != 50 || *flat.EfficiencyMovingAvg != 1 {
+		t.Errorf("expected a flat series for sector 2, got %+v", flat)
+	}
+
+	if linearSlope([]float64{3}) != 0 {
+		t.Error("expected no slope for a single point")
+	}
+}
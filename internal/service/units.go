@@ -0,0 +1,152 @@
+package service
+
+import "math"
+
+// Unit systems figures are reported in
+const (
+	// UnitsMetric reports liters, hectares and millimeters, as stored
+	UnitsMetric = "metric"
+	// UnitsImperial reports US gallons, acres and inches
+	UnitsImperial = "imperial"
+)
+
+// Imperial conversion factors
+const (
+	litersPerUSGallon = 3.785411784
+	hectaresPerAcre   = 0.40468564224
+	mmPerInch         = 25.4
+)
+
+// IsValidUnitSystem reports whether units is one of the known unit systems
+func IsValidUnitSystem(units string) bool {
+	return units == UnitsMetric || units == UnitsImperial
+}
+
+// gallons converts liters to US gallons, rounded to 2 decimals
+func gallons(liters float64) float64 {
+	return math.Round(liters/litersPerUSGallon*100) / 100
+}
+
+// gallonsPtr converts an optional volume or flow rate in liters to US gallons
+func gallonsPtr(liters *float64) *float64 {
+	if liters == nil {
+		return nil
+	}
+	converted := gallons(*liters)
+	return &converted
+}
+
+// perAcre converts an optional per-hectare figure to per acre, with volumes also converted
+// to gallons when volume is set
+func perAcre(perHectare *float64, volume bool) *float64 {
+	if perHectare == nil {
+		return nil
+	}
+	converted := *perHectare * hectaresPerAcre
+	if volume {
+		converted /= litersPerUSGallon
+	}
+	converted = math.Round(converted*10000) / 10000
+	return &converted
+}
+
+// inches converts an optional depth in millimeters to inches, rounded to 3 decimals
+func inches(mm *float64) *float64 {
+	if mm == nil {
+		return nil
+	}
+	converted := math.Round(*mm/mmPerInch*1000) / 1000
+	return &converted
+}
+
+// applyUnits sets the response's unit system, converting its metric figures for imperial: volumes
+// and flow rates to US gallons, per-hectare figures to per acre and depths to inches. Field names
+// keep their metric suffixes, so under imperial water_per_hectare is gallons per acre and
+// rainfall_mm is in inches. Efficiencies, durations and pressures are unchanged.
+func applyUnits(response *AnalyticsResponse, units string) {
+	if units != UnitsImperial {
+		response.Units = UnitsMetric
+		return
+	}
+	response.Units = UnitsImperial
+
+	for i := range response.Data {
+		point := &response.Data[i]
+		point.WaterVolume = gallons(point.WaterVolume)
+		point.RealAmount = gallons(point.RealAmount)
+		point.NominalAmount = gallons(point.NominalAmount)
+		point.FallbackFlowRate = gallonsPtr(point.FallbackFlowRate)
+		point.WaterPerHectare = perAcre(point.WaterPerHectare, true)
+		point.EventsPerHectare = perAcre(point.EventsPerHectare, false)
+		point.AppliedDepthMM = inches(point.AppliedDepthMM)
+		point.RainfallMM = inches(point.RainfallMM)
+		point.ET0MM = inches(point.ET0MM)
+		if trend := point.Trend; trend != nil {
+			trend.VolumeMovingAvg = gallonsPtr(trend.VolumeMovingAvg)
+			trend.VolumeSlope = math.Round(trend.VolumeSlope/litersPerUSGallon*10000) / 10000
+		}
+	}
+
+	summary := &response.Summary
+	summary.TotalWaterVolume = gallons(summary.TotalWaterVolume)
+	summary.TotalRealAmount = gallons(summary.TotalRealAmount)
+	summary.TotalNominalAmount = gallons(summary.TotalNominalAmount)
+	summary.WaterPerHectare = perAcre(summary.WaterPerHectare, true)
+	summary.EventsPerHectare = perAcre(summary.EventsPerHectare, false)
+	summary.AppliedDepthMM = inches(summary.AppliedDepthMM)
+	if distribution := summary.Distribution; distribution != nil && distribution.WaterVolume != nil {
+		stats := distribution.WaterVolume
+		stats.Min, stats.Median, stats.P90 = gallons(stats.Min), gallons(stats.Median), gallons(stats.P90)
+		stats.P95, stats.Max, stats.StdDev = gallons(stats.P95), gallons(stats.Max), gallons(stats.StdDev)
+	}
+
+	for i := range response.Comparisons {
+		response.Comparisons[i].TotalWaterVolume = gallons(response.Comparisons[i].TotalWaterVolume)
+	}
+	for _, metrics := range []*PeriodMetrics{response.PeriodComparison.OneYearAgo, response.PeriodComparison.TwoYearsAgo} {
+		if metrics != nil {
+			metrics.TotalWaterVolume = gallons(metrics.TotalWaterVolume)
+		}
+	}
+	for _, year := range []*YearComparison{response.YearOverYear.OneYearAgo, response.YearOverYear.TwoYearsAgo} {
+		if year != nil {
+			year.TotalWaterVolume = gallons(year.TotalWaterVolume)
+		}
+	}
+	for i := range response.SectorBreakdown {
+		sector := &response.SectorBreakdown[i]
+		sector.TotalWaterVolume = gallons(sector.TotalWaterVolume)
+		sector.TotalRealAmount = gallons(sector.TotalRealAmount)
+		sector.TotalNominalAmount = gallons(sector.TotalNominalAmount)
+		sector.WaterPerHectare = perAcre(sector.WaterPerHectare, true)
+		sector.EventsPerHectare = perAcre(sector.EventsPerHectare, false)
+		sector.AppliedDepthMM = inches(sector.AppliedDepthMM)
+		if sector.OneYearAgo != nil {
+			sector.OneYearAgo.TotalWaterVolume = gallons(sector.OneYearAgo.TotalWaterVolume)
+		}
+	}
+	for i := range response.PurposeBreakdown {
+		response.PurposeBreakdown[i].TotalWaterVolume = gallons(response.PurposeBreakdown[i].TotalWaterVolume)
+	}
+}
+
+// applyRecommendationUnits sets the response's unit system, converting its metric figures for
+// imperial as applyUnits does; areas are converted to acres
+func applyRecommendationUnits(response *RecommendationResponse, units string) {
+	if units != UnitsImperial {
+		response.Units = UnitsMetric
+		return
+	}
+	response.Units = UnitsImperial
+
+	for i := range response.Recommendations {
+		recommendation := &response.Recommendations[i]
+		recommendation.Area = math.Round(recommendation.Area/hectaresPerAcre*100) / 100
+		recommendation.AppliedVolume = gallonsPtr(recommendation.AppliedVolume)
+		recommendation.AppliedDepthMM = inches(recommendation.AppliedDepthMM)
+		recommendation.FlowRate = gallonsPtr(recommendation.FlowRate)
+		recommendation.NetDemandMM = inches(recommendation.NetDemandMM)
+		recommendation.RecommendedVolume = gallonsPtr(recommendation.RecommendedVolume)
+		recommendation.RecommendedDepthMM = inches(recommendation.RecommendedDepthMM)
+	}
+}
@@ -0,0 +1,80 @@
+package service
+
+import "testing"
+
+func TestApplyUnits_Imperial(t *testing.T) {
+	analytics, _, _, _ := exampleResponses(EfficiencyWeightingMean)
+	analytics.Data[0].WaterPerHectare = fixtureFloat(1000)
+	analytics.Data[0].RainfallMM = fixtureFloat(25.4)
+
+	applyUnits(analytics, UnitsImperial)
+
+	if analytics.Units != UnitsImperial {
+		t.Errorf("Expected units %q, got %q", UnitsImperial, analytics.Units)
+	}
+	if got := analytics.Summary.TotalWaterVolume; got != 647.35 {
+		t.Errorf("Expected 2450.5 L to be 647.35 gal, got %v", got)
+	}
+	if got := analytics.Data[0].WaterVolume; got != 216.62 {
+		t.Errorf("Expected 820 L to be 216.62 gal, got %v", got)
+	}
+	if got := analytics.Data[0].WaterPerHectare; got == nil || *got != 106.9066 {
+		t.Errorf("Expected 1000 L/ha to be 106.9066 gal/ac, got %v", got)
+	}
+	if got := analytics.Data[0].RainfallMM; got == nil || *got != 1 {
+		t.Errorf("Expected 25.4 mm to be 1 in, got %v", got)
+	}
+	if got := analytics.SectorBreakdown[0].TotalWaterVolume; got != 647.35 {
+		t.Errorf("Expected the sector breakdown in gallons, got %v", got)
+	}
+	if got := analytics.YearOverYear.OneYearAgo.TotalWaterVolume; got != 607.6 {
+		t.Errorf("Expected last year's volume in gallons, got %v", got)
+	}
+	if got := analytics.Summary.AverageEfficiency; got != 0.9213 {
+		t.Errorf("Expected efficiency to be unchanged, got %v", got)
+	}
+}
+
+func TestApplyUnits_Metric(t *testing.T) {
+	analytics, _, _, _ := exampleResponses(EfficiencyWeightingMean)
+	analytics.Units = ""
+
+	applyUnits(analytics, UnitsMetric)
+
+	if analytics.Units != UnitsMetric {
+		t.Errorf("Expected units %q, got %q", UnitsMetric, analytics.Units)
+	}
+	if got := analytics.Summary.TotalWaterVolume; got != 2450.5 {
+		t.Errorf("Expected volumes to stay in liters, got %v", got)
+	}
+}
+
+func TestApplyRecommendationUnits_Imperial(t *testing.T) {
+	response := &RecommendationResponse{
+		Recommendations: []SectorRecommendation{
+			{SectorID: 1, Area: 2, AppliedVolume: fixtureFloat(3785.41), AppliedDepthMM: fixtureFloat(12.7), FlowRate: fixtureFloat(37.85)},
+		},
+	}
+
+	applyRecommendationUnits(response, UnitsImperial)
+
+	recommendation := response.Recommendations[0]
+	if response.Units != UnitsImperial {
+		t.Errorf("Expected units %q, got %q", UnitsImperial, response.Units)
+	}
+	if recommendation.Area != 4.94 {
+		t.Errorf("Expected 2 ha to be 4.94 ac, got %v", recommendation.Area)
+	}
+	if recommendation.AppliedVolume == nil || *recommendation.AppliedVolume != 1000 {
+		t.Errorf("Expected 3785.41 L to be 1000 gal, got %v", recommendation.AppliedVolume)
+	}
+	if recommendation.AppliedDepthMM == nil || *recommendation.AppliedDepthMM != 0.5 {
+		t.Errorf("Expected 12.7 mm to be 0.5 in, got %v", recommendation.AppliedDepthMM)
+	}
+	if recommendation.FlowRate == nil || *recommendation.FlowRate != 10 {
+		t.Errorf("Expected 37.85 L/min to be 10 gal/min, got %v", recommendation.FlowRate)
+	}
+	if recommendation.RecommendedVolume != nil {
+		t.Errorf("Expected missing figures to stay nil, got %v", recommendation.RecommendedVolume)
+	}
+}
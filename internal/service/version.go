@@ -0,0 +1,119 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+
+	"irrigation-analytics/internal/model"
+
+	"gorm.io/gorm/schema"
+)
+
+// Build details, set at link time with
+//
+//	-ldflags "-X irrigation-analytics/internal/service.Version=1.4.0
+//	          -X irrigation-analytics/internal/service.GitCommit=$(git rev-parse HEAD)
+//	          -X irrigation-analytics/internal/service.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// GitCommit and BuildTime fall back to the VCS details go build embeds when left empty.
+var (
+	Version   = "dev"
+	GitCommit = ""
+	BuildTime = ""
+)
+
+// VersionInfo describes what a deployment is running
+type VersionInfo struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"git_commit"`
+	BuildTime string `json:"build_time"`
+	// Modified is true when the build had uncommitted changes, as recorded by go build
+	Modified  bool   `json:"modified"`
+	GoVersion string `json:"go_version"`
+	// SchemaVersion fingerprints the tables and columns the migrations create; deployments
+	// with the same value have the same schema
+	SchemaVersion string `json:"schema_version"`
+	// APIVersions are the analytics response schema versions served
+	APIVersions []string `json:"api_versions"`
+	// Features are the optional features the server was started with, and whether each is enabled
+	Features map[string]bool `json:"features"`
+}
+
+// BuildVersionInfo collects the build details, the schema version of model.Models() and the
+// given feature flags
+func BuildVersionInfo(features map[string]bool) (*VersionInfo, error) {
+	schemaVersion, err := schemaFingerprint(model.Models())
+	if err != nil {
+		return nil, err
+	}
+
+	info := &VersionInfo{
+		Version:       Version,
+		GitCommit:     GitCommit,
+		BuildTime:     BuildTime,
+		GoVersion:     runtime.Version(),
+		SchemaVersion: schemaVersion,
+		APIVersions:   SchemaVersions(),
+		Features:      map[string]bool{},
+	}
+	for name, enabled := range features {
+		info.Features[name] = enabled
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		info.applyVCS(build.Settings)
+	}
+	if info.GitCommit == "" {
+		info.GitCommit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info, nil
+}
+
+// applyVCS fills the commit and build time left unset at link time from go build's VCS settings
+func (info *VersionInfo) applyVCS(settings []debug.BuildSetting) {
+	for _, setting := range settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if info.GitCommit == "" {
+				info.GitCommit = setting.Value
+			}
+		case "vcs.time":
+			if info.BuildTime == "" {
+				info.BuildTime = setting.Value
+			}
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+}
+
+// schemaFingerprint hashes every table, column and column type of the models, so it changes
+// whenever a release migrates the schema
+func schemaFingerprint(models []interface{}) (string, error) {
+	var columns []string
+	cache := &sync.Map{}
+	for _, m := range models {
+		parsed, err := schema.Parse(m, cache, schema.NamingStrategy{})
+		if err != nil {
+			return "", err
+		}
+		for _, field := range parsed.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			columns = append(columns, fmt.Sprintf("%s.%s:%s:%d", parsed.Table, field.DBName, field.DataType, field.Size))
+		}
+	}
+	sort.Strings(columns)
+
+	sum := sha256.Sum256([]byte(strings.Join(columns, "\n")))
+	return hex.EncodeToString(sum[:6]), nil
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"runtime/debug"
+	"testing"
+
+	"irrigation-analytics/internal/model"
+)
+
+func TestBuildVersionInfo(t *testing.T) {
+	info, err := BuildVersionInfo(map[string]bool{"webhooks": true, "debug_capture": false})
+	if err != nil {
+		t.Fatalf("BuildVersionInfo: %v", err)
+	}
+	if info.Version != Version || info.GitCommit == "" || info.BuildTime == "" || info.GoVersion == "" {
+		t.Errorf("expected every build detail to be set, got %+v", info)
+	}
+	if len(info.SchemaVersion) != 12 {
+		t.Errorf("expected a 12 character schema version, got %q", info.SchemaVersion)
+	}
+	if !info.Features["webhooks"] || info.Features["debug_capture"] || len(info.Features) != 2 {
+		t.Errorf("expected the feature flags as given, got %v", info.Features)
+	}
+	if len(info.APIVersions) == 0 || info.APIVersions[0] != SchemaV1 {
+		t.Errorf("expected the analytics schema versions, got %v", info.APIVersions)
+	}
+}
+
+func TestVersionInfo_ApplyVCS(t *testing.T) {
+	settings := []debug.BuildSetting{
+		{Key: "vcs.revision", Value: "9b325af"},
+		{Key: "vcs.time", Value: "2026-10-16T09:00:00Z"},
+		{Key: "vcs.modified", Value: "true"},
+	}
+
+	info := &VersionInfo{}
+	info.applyVCS(settings)
+	if info.GitCommit != "9b325af" || info.BuildTime != "2026-10-16T09:00:00Z" || !info.Modified {
+		t.Errorf("expected the VCS details, got %+v", info)
+	}
+
+	// Values set at link time take precedence
+	info = &VersionInfo{GitCommit: "abc123", BuildTime: "2026-10-01T00:00:00Z"}
+	info.applyVCS(settings)
+	if info.GitCommit != "abc123" || info.BuildTime != "2026-10-01T00:00:00Z" {
+		t.Errorf("expected the link-time details to be kept, got %+v", info)
+	}
+}
+
+func TestSchemaFingerprint(t *testing.T) {
+	full, err := schemaFingerprint(model.Models())
+	if err != nil {
+		t.Fatalf("schemaFingerprint: %v", err)
+	}
+	again, _ := schemaFingerprint(model.Models())
+	if full != again {
+		t.Errorf("expected a stable fingerprint, got %q and %q", full, again)
+	}
+	fewer, _ := schemaFingerprint(model.Models()[:3])
+	if fewer == full {
+		t.Error("expected the fingerprint to change with the schema")
+	}
+}
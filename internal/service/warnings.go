@@ -0,0 +1,52 @@
+package service
+
+import "fmt"
+
+// Optional response sections that can fail without failing the request
+const (
+	SectionAnnotations     = "annotations"
+	SectionPressure        = "pressure"
+	SectionSectorBreakdown = "sector_breakdown"
+	SectionWeather         = "weather"
+	SectionDistribution    = "distribution"
+)
+
+// sectionMessages describe what a client loses when a section fails
+var sectionMessages = map[string]string{
+	SectionAnnotations:     "annotation lookup failed; data points carry no annotations",
+	SectionPressure:        "pressure lookup failed; data points carry no pressure",
+	SectionSectorBreakdown: "sector comparison query failed; sector_breakdown and its one_year_ago metrics are omitted",
+	SectionWeather:         "weather lookup failed; data points carry no rainfall or ET0",
+	SectionDistribution:    "distribution query failed; summary.distribution is omitted",
+}
+
+// Warning notes an optional section left out of a response because its query failed.
+// Cause holds the underlying error for logging and is never sent to clients.
+type Warning struct {
+	Section string `json:"section"`
+	Message string `json:"message"`
+	Cause   error  `json:"-"`
+}
+
+// SectionError is returned instead of a partial response when AnalyticsOptions.Strict is set
+type SectionError struct {
+	Section string
+	Err     error
+}
+
+func (e *SectionError) Error() string {
+	return fmt.Sprintf("%s failed: %v", e.Section, e.Err)
+}
+
+func (e *SectionError) Unwrap() error {
+	return e.Err
+}
+
+// sectionFailed records a failed optional section as a warning, or returns a SectionError in strict mode
+func sectionFailed(warnings *[]Warning, strict bool, section string, err error) error {
+	if strict {
+		return &SectionError{Section: section, Err: err}
+	}
+	*warnings = append(*warnings, Warning{Section: section, Message: sectionMessages[section], Cause: err})
+	return nil
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/repository"
+)
+
+// failingSectorRepository fails the sector comparison query
+type failingSectorRepository struct {
+	*stubRepository
+}
+
+func (r *failingSectorRepository) GetSectorComparisonData(farmID uint, startDate, endDate time.Time, yearsBack []int, opts repository.QueryOptions) (map[int][]repository.AggregatedDataWithCount, error) {
+	return nil, errors.New("canceling statement due to statement timeout")
+}
+
+// TestGetIrrigationAnalytics_SectionWarnings verifies a failed sector breakdown becomes a warning,
+// or a SectionError in strict mode
+func TestGetIrrigationAnalytics_SectionWarnings(t *testing.T) {
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	repo := &failingSectorRepository{&stubRepository{
+		comparison: map[int][]repository.AggregatedDataWithCount{0: {aggregatedPoint(day, 1, 100, 100, 1)}},
+	}}
+	svc := NewAnalyticsService(repo, nil, nil, nil)
+
+	response, err := svc.GetIrrigationAnalytics(1, nil, day, day.AddDate(0, 0, 1), "daily", AnalyticsOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(response.Warnings) != 1 || response.Warnings[0].Section != SectionSectorBreakdown {
+		t.Fatalf("expected one sector_breakdown warning, got %+v", response.Warnings)
+	}
+	if response.SectorBreakdown != nil {
+		t.Errorf("expected no sector breakdown, got %+v", response.SectorBreakdown)
+	}
+
+	_, err = svc.GetIrrigationAnalytics(1, nil, day, day.AddDate(0, 0, 1), "daily", AnalyticsOptions{Strict: true})
+	var sectionErr *SectionError
+	if !errors.As(err, &sectionErr) || sectionErr.Section != SectionSectorBreakdown {
+		t.Errorf("expected a sector_breakdown SectionError, got %v", err)
+	}
+}
@@ -0,0 +1,310 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"irrigation-analytics/internal/repository"
+)
+
+// MaxWaterAccountLength bounds a farm's irrigation district account
+const MaxWaterAccountLength = 64
+
+// maxWaterOrdersResponseBytes bounds a district API response, so a misbehaving API can't exhaust memory
+const maxWaterOrdersResponseBytes = 8 << 20
+
+// Water order reconciliation periods
+const (
+	WaterOrderPeriodDaily   = "daily"
+	WaterOrderPeriodWeekly  = "weekly"
+	WaterOrderPeriodMonthly = "monthly"
+)
+
+// WaterOrderStatusCancelled marks an order the district won't deliver; it is listed but not counted
+const WaterOrderStatusCancelled = "cancelled"
+
+var (
+	// ErrFarmWithoutWaterAccount is returned when water orders are requested for a farm without a district account
+	ErrFarmWithoutWaterAccount = errors.New("farm has no water account")
+	// ErrWaterOrderProvider wraps failures of the external water order provider
+	ErrWaterOrderProvider = errors.New("water order provider request failed")
+)
+
+// WaterOrder is a water delivery ordered from the irrigation district. Volumes are in liters;
+// DeliveredVolume is nil until the district has metered the delivery.
+type WaterOrder struct {
+	ID              string    `json:"id"`
+	Start           time.Time `json:"start"`
+	End             time.Time `json:"end"`
+	Status          string    `json:"status"`
+	OrderedVolume   float64   `json:"ordered_volume"`
+	DeliveredVolume *float64  `json:"delivered_volume"`
+}
+
+// WaterOrderProvider fetches a district account's water orders. Implementations must return the
+// orders starting in [startDate, endDate) only, and give up once ctx is done.
+type WaterOrderProvider interface {
+	// Name identifies the provider in reconciliation reports
+	Name() string
+	WaterOrders(ctx context.Context, account string, startDate, endDate time.Time) ([]WaterOrder, error)
+}
+
+// WaterOrderService defines the interface for reconciling district water orders with recorded irrigation
+type WaterOrderService interface {
+	FarmExists(farmID uint) (bool, error)
+	Reconcile(ctx context.Context, farmID uint, startDate, endDate time.Time, period string) (*WaterOrderReconciliation, error)
+}
+
+// WaterOrderReconciliation compares the water ordered from the district, the water it delivered
+// and the water applied by the farm's irrigation events, per period
+type WaterOrderReconciliation struct {
+	FarmID       uint                    `json:"farm_id"`
+	WaterAccount string                  `json:"water_account"`
+	Provider     string                  `json:"provider"`
+	Period       PeriodInfo              `json:"period"`
+	Aggregation  string                  `json:"aggregation"`
+	Totals       WaterBalance            `json:"totals"`
+	Periods      []WaterOrderPeriodTotal `json:"periods"`
+	Orders       []WaterOrder            `json:"orders"`
+}
+
+// WaterBalance holds ordered, delivered and applied volumes in liters. UndeliveredVolume is
+// ordered minus delivered and UnaccountedVolume delivered minus applied; the percentages are nil
+// when their base is zero.
+type WaterBalance struct {
+	Orders             int      `json:"orders"`
+	PendingOrders      int      `json:"pending_orders"`
+	OrderedVolume      float64  `json:"ordered_volume"`
+	DeliveredVolume    float64  `json:"delivered_volume"`
+	AppliedVolume      float64  `json:"applied_volume"`
+	UndeliveredVolume  float64  `json:"undelivered_volume"`
+	UnaccountedVolume  float64  `json:"unaccounted_volume"`
+	DeliveryPercent    *float64 `json:"delivery_percent"`
+	ApplicationPercent *float64 `json:"application_percent"`
+}
+
+// WaterOrderPeriodTotal is the water balance of one period
+type WaterOrderPeriodTotal struct {
+	Period time.Time `json:"period"`
+	WaterBalance
+}
+
+// waterOrderService implements WaterOrderService
+type waterOrderService struct {
+	repo     repository.IrrigationRepository
+	farms    repository.FarmRepository
+	provider WaterOrderProvider
+}
+
+// NewWaterOrderService creates a new water order service
+func NewWaterOrderService(repo repository.IrrigationRepository, farms repository.FarmRepository, provider WaterOrderProvider) WaterOrderService {
+	return &waterOrderService{repo: repo, farms: farms, provider: provider}
+}
+
+// FarmExists checks if a farm exists
+func (s *waterOrderService) FarmExists(farmID uint) (bool, error) {
+	return s.repo.FarmExists(farmID)
+}
+
+// Reconcile reads the farm's orders from the provider and its applied volume from the recorded
+// events, and balances them per day, week or month of [startDate, endDate). Nothing is stored:
+// every call reads the district's current orders. An order counts in the period it starts in;
+// cancelled orders are listed but count nowhere, and an order not yet metered counts as ordered
+// and pending but delivers nothing. Events are placed by the farm's attribution policy. The
+// provider request and the event queries are abandoned when ctx is done, e.g. because the client
+// went away.
+func (s *waterOrderService) Reconcile(ctx context.Context, farmID uint, startDate, endDate time.Time, period string) (*WaterOrderReconciliation, error) {
+	startDate, endDate = truncateToDay(startDate), truncateToDay(endDate)
+
+	farm, err := s.farms.GetFarmWithSectors(farmID)
+	if err != nil {
+		return nil, err
+	}
+	if farm == nil {
+		return nil, ErrFarmNotFound
+	}
+	if farm.WaterAccount == "" {
+		return nil, ErrFarmWithoutWaterAccount
+	}
+
+	orders, err := s.provider.WaterOrders(ctx, farm.WaterAccount, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrWaterOrderProvider, err)
+	}
+
+	repo := s.repo.WithContext(ctx)
+	queryOpts, err := withAttribution(repo, farmID, repository.QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+	applied, err := repo.GetAggregatedData(farmID, nil, startDate, endDate, period, queryOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make(map[int64]*WaterBalance)
+	starts := bucketStarts(startDate, endDate, period)
+	for _, start := range starts {
+		balances[start.Unix()] = &WaterBalance{}
+	}
+	for _, item := range applied {
+		if balance := balances[bucketStart(item.Data.StartTime, period).Unix()]; balance != nil {
+			balance.AppliedVolume += item.Data.WaterVolume
+		}
+	}
+
+	sort.SliceStable(orders, func(i, j int) bool { return orders[i].Start.Before(orders[j].Start) })
+	listed := make([]WaterOrder, 0, len(orders))
+	for _, order := range orders {
+		if order.Start.Before(startDate) || !order.Start.Before(endDate) {
+			continue
+		}
+		listed = append(listed, order)
+		balance := balances[bucketStart(order.Start, period).Unix()]
+		if order.Status == WaterOrderStatusCancelled || balance == nil {
+			continue
+		}
+		balance.Orders++
+		balance.OrderedVolume += order.OrderedVolume
+		if order.DeliveredVolume == nil {
+			balance.PendingOrders++
+		} else {
+			balance.DeliveredVolume += *order.DeliveredVolume
+		}
+	}
+
+	response := &WaterOrderReconciliation{
+		FarmID:       farmID,
+		WaterAccount: farm.WaterAccount,
+		Provider:     s.provider.Name(),
+		Period:       PeriodInfo{StartDate: startDate, EndDate: endDate},
+		Aggregation:  period,
+		Periods:      make([]WaterOrderPeriodTotal, 0, len(starts)),
+		Orders:       listed,
+	}
+	var totals WaterBalance
+	for _, start := range starts {
+		balance := balances[start.Unix()]
+		totals.Orders += balance.Orders
+		totals.PendingOrders += balance.PendingOrders
+		totals.OrderedVolume += balance.OrderedVolume
+		totals.DeliveredVolume += balance.DeliveredVolume
+		totals.AppliedVolume += balance.AppliedVolume
+		response.Periods = append(response.Periods, WaterOrderPeriodTotal{Period: start, WaterBalance: balance.rounded()})
+	}
+	response.Totals = totals.rounded()
+	return response, nil
+}
+
+// rounded computes the balance's differences and percentages and rounds volumes to 2 decimals
+func (b WaterBalance) rounded() WaterBalance {
+	round := func(v float64) float64 { return math.Round(v*100) / 100 }
+	b.UndeliveredVolume = round(b.OrderedVolume - b.DeliveredVolume)
+	b.UnaccountedVolume = round(b.DeliveredVolume - b.AppliedVolume)
+	if b.OrderedVolume > 0 {
+		b.DeliveryPercent = roundedPtr(b.DeliveredVolume/b.OrderedVolume*100, 2)
+	}
+	if b.DeliveredVolume > 0 {
+		b.ApplicationPercent = roundedPtr(b.AppliedVolume/b.DeliveredVolume*100, 2)
+	}
+	b.OrderedVolume = round(b.OrderedVolume)
+	b.DeliveredVolume = round(b.DeliveredVolume)
+	b.AppliedVolume = round(b.AppliedVolume)
+	return b
+}
+
+// HTTPWaterOrderProvider reads orders from a district or ERP API serving
+// GET {BaseURL}/accounts/{account}/water-orders?from=YYYY-MM-DD&to=YYYY-MM-DD, with to exclusive,
+// answering {"orders": [{"id", "start", "end", "status", "ordered_m3", "delivered_m3"}]}. Volumes
+// are in cubic meters, and delivered_m3 is null until metered. Districts with another API plug in
+// by implementing WaterOrderProvider.
+type HTTPWaterOrderProvider struct {
+	BaseURL string
+	// APIKey is sent as a bearer token when set
+	APIKey string
+	Client *http.Client
+}
+
+// NewHTTPWaterOrderProvider creates a provider for the API at baseURL with a 10 second request timeout
+func NewHTTPWaterOrderProvider(baseURL, apiKey string) *HTTPWaterOrderProvider {
+	return &HTTPWaterOrderProvider{BaseURL: baseURL, APIKey: apiKey, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Name identifies the provider in reconciliation reports
+func (p *HTTPWaterOrderProvider) Name() string {
+	return "district-api"
+}
+
+// httpWaterOrdersResponse is the body of a district API response
+type httpWaterOrdersResponse struct {
+	Orders []struct {
+		ID          string    `json:"id"`
+		Start       time.Time `json:"start"`
+		End         time.Time `json:"end"`
+		Status      string    `json:"status"`
+		OrderedM3   float64   `json:"ordered_m3"`
+		DeliveredM3 *float64  `json:"delivered_m3"`
+	} `json:"orders"`
+}
+
+// WaterOrders fetches the account's orders starting in [startDate, endDate), converted to liters.
+// Responses over 8 MiB are rejected.
+func (p *HTTPWaterOrderProvider) WaterOrders(ctx context.Context, account string, startDate, endDate time.Time) ([]WaterOrder, error) {
+	query := url.Values{}
+	query.Set("from", startDate.Format(time.DateOnly))
+	query.Set("to", endDate.Format(time.DateOnly))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.BaseURL+"/accounts/"+url.PathEscape(account)+"/water-orders?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if p.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.APIKey)
+	}
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("district API responded %d", resp.StatusCode)
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxWaterOrdersResponseBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading district API response: %w", err)
+	}
+	if len(raw) > maxWaterOrdersResponseBytes {
+		return nil, fmt.Errorf("district API response exceeds %d bytes", maxWaterOrdersResponseBytes)
+	}
+
+	var body httpWaterOrdersResponse
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return nil, fmt.Errorf("decoding district API response: %w", err)
+	}
+
+	orders := make([]WaterOrder, 0, len(body.Orders))
+	for _, order := range body.Orders {
+		converted := WaterOrder{
+			ID:            order.ID,
+			Start:         order.Start.UTC(),
+			End:           order.End.UTC(),
+			Status:        order.Status,
+			OrderedVolume: order.OrderedM3 * 1000,
+		}
+		if order.DeliveredM3 != nil {
+			delivered := *order.DeliveredM3 * 1000
+			converted.DeliveredVolume = &delivered
+		}
+		orders = append(orders, converted)
+	}
+	return orders, nil
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// stubWaterOrderProvider returns fixed orders
+type stubWaterOrderProvider struct {
+	orders  []WaterOrder
+	err     error
+	account string
+}
+
+func (p *stubWaterOrderProvider) Name() string { return "stub" }
+
+func (p *stubWaterOrderProvider) WaterOrders(ctx context.Context, account string, startDate, endDate time.Time) ([]WaterOrder, error) {
+	p.account = account
+	return p.orders, p.err
+}
+
+func TestReconcileWaterOrders(t *testing.T) {
+	june := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	july := june.AddDate(0, 1, 0)
+	repo := &stubRepository{comparison: map[int][]repository.AggregatedDataWithCount{
+		0: {
+			aggregatedPoint(june, 1, 90000, 90000, 3),
+			aggregatedPoint(june, 2, 30000, 30000, 1),
+			aggregatedPoint(july, 1, 50000, 50000, 2),
+		},
+	}}
+	farms := &stubFarmRepository{existing: &model.Farm{ID: 1, WaterAccount: "WD-4471"}}
+	provider := &stubWaterOrderProvider{orders: []WaterOrder{
+		{ID: "o-3", Start: july.Add(6 * time.Hour), OrderedVolume: 60000},
+		{ID: "o-1", Start: june.Add(6 * time.Hour), OrderedVolume: 100000, DeliveredVolume: weatherFloat(95000)},
+		{ID: "o-2", Start: june.AddDate(0, 0, 10), OrderedVolume: 40000, DeliveredVolume: weatherFloat(40000)},
+		{ID: "o-4", Start: july.AddDate(0, 0, 2), OrderedVolume: 20000, Status: WaterOrderStatusCancelled},
+		{ID: "o-5", Start: july.AddDate(0, 1, 0), OrderedVolume: 10000},
+	}}
+	svc := NewWaterOrderService(repo, farms, provider)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	result, err := svc.Reconcile(ctx, 1, june, july.AddDate(0, 1, 0), WaterOrderPeriodMonthly)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.ctx != ctx {
+		t.Error("expected the event queries bound to the request context")
+	}
+	if provider.account != "WD-4471" || result.Provider != "stub" {
+		t.Errorf("expected the farm's account read from stub, got %q from %s", provider.account, result.Provider)
+	}
+	if len(result.Periods) != 2 || len(result.Orders) != 4 || result.Orders[0].ID != "o-1" {
+		t.Fatalf("expected 2 months and 4 orders in start order, got %+v", result)
+	}
+
+	jun := result.Periods[0]
+	if jun.Orders != 2 || jun.OrderedVolume != 140000 || jun.DeliveredVolume != 135000 || jun.AppliedVolume != 120000 ||
+		jun.UndeliveredVolume != 5000 || jun.UnaccountedVolume != 15000 || *jun.DeliveryPercent != 96.43 || *jun.ApplicationPercent != 88.89 {
+		t.Errorf("unexpected June balance %+v", jun.WaterBalance)
+	}
+	jul := result.Periods[1]
+	if jul.Orders != 1 || jul.PendingOrders != 1 || jul.OrderedVolume != 60000 || jul.DeliveredVolume != 0 ||
+		jul.AppliedVolume != 50000 || jul.ApplicationPercent != nil {
+		t.Errorf("expected the cancelled order left out and the pending one undelivered in July, got %+v", jul.WaterBalance)
+	}
+	if result.Totals.OrderedVolume != 200000 || result.Totals.AppliedVolume != 170000 || result.Totals.PendingOrders != 1 {
+		t.Errorf("unexpected totals %+v", result.Totals)
+	}
+
+	if _, err := svc.Reconcile(context.Background(), 2, june, july, WaterOrderPeriodMonthly); !errors.Is(err, ErrFarmNotFound) {
+		t.Errorf("expected ErrFarmNotFound, got %v", err)
+	}
+
+	farms.existing.WaterAccount = ""
+	if _, err := svc.Reconcile(context.Background(), 1, june, july, WaterOrderPeriodMonthly); !errors.Is(err, ErrFarmWithoutWaterAccount) {
+		t.Errorf("expected ErrFarmWithoutWaterAccount, got %v", err)
+	}
+
+	farms.existing.WaterAccount = "WD-4471"
+	provider.err = errors.New("timeout")
+	if _, err := svc.Reconcile(context.Background(), 1, june, july, WaterOrderPeriodMonthly); !errors.Is(err, ErrWaterOrderProvider) {
+		t.Errorf("expected ErrWaterOrderProvider, got %v", err)
+	}
+}
+
+func TestHTTPWaterOrderProvider(t *testing.T) {
+	var path, query, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, query, auth = r.URL.EscapedPath(), r.URL.RawQuery, r.Header.Get("Authorization")
+		w.Write([]byte(`{"orders":[{"id":"A-1","start":"2025-06-02T06:00:00+02:00","end":"2025-06-02T18:00:00+02:00","status":"delivered","ordered_m3":120,"delivered_m3":118.5},{"id":"A-2","start":"2025-06-09T06:00:00Z","end":"2025-06-09T18:00:00Z","status":"scheduled","ordered_m3":80,"delivered_m3":null}]}`))
+	}))
+	defer server.Close()
+
+	provider := NewHTTPWaterOrderProvider(server.URL, "secret")
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	orders, err := provider.WaterOrders(context.Background(), "WD 7", start, start.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != "/accounts/WD%207/water-orders" || query != "from=2025-06-01&to=2025-07-01" || auth != "Bearer secret" {
+		t.Errorf("unexpected request %s?%s with %q", path, query, auth)
+	}
+	if len(orders) != 2 || orders[0].OrderedVolume != 120000 || *orders[0].DeliveredVolume != 118500 ||
+		orders[0].Start.Hour() != 4 || orders[1].DeliveredVolume != nil {
+		t.Errorf("unexpected orders %+v", orders)
+	}
+}
+
+func TestHTTPWaterOrderProvider_Limits(t *testing.T) {
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("oversized response", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"orders":[`))
+			w.Write([]byte(strings.Repeat(" ", maxWaterOrdersResponseBytes)))
+			w.Write([]byte(`]}`))
+		}))
+		defer server.Close()
+
+		_, err := NewHTTPWaterOrderProvider(server.URL, "").WaterOrders(context.Background(), "WD7", start, start.AddDate(0, 1, 0))
+		if err == nil || !strings.Contains(err.Error(), "exceeds") {
+			t.Errorf("expected the response to be rejected as too large, got %v", err)
+		}
+	})
+
+	t.Run("cancelled request", func(t *testing.T) {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer server.Close()
+		defer close(release)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := NewHTTPWaterOrderProvider(server.URL, "").WaterOrders(ctx, "WD7", start, start.AddDate(0, 1, 0))
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the request to end with its context, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+var (
+	// ErrFarmWithoutCoordinates is returned when weather is requested for a farm without latitude and longitude
+	ErrFarmWithoutCoordinates = errors.New("farm has no latitude and longitude")
+	// ErrWeatherProvider wraps failures of the external weather provider
+	ErrWeatherProvider = errors.New("weather provider request failed")
+)
+
+// DailyWeather is one day of weather reported by a provider. Values are nil when the provider
+// has no data for the day.
+type DailyWeather struct {
+	Date            time.Time
+	PrecipitationMM *float64
+	ET0MM           *float64
+	TempMinC        *float64
+	TempMaxC        *float64
+}
+
+// WeatherProvider fetches daily weather for a location. Implementations must return days in
+// [startDate, endDate) only.
+type WeatherProvider interface {
+	// Name identifies the provider in stored observations
+	Name() string
+	DailyWeather(latitude, longitude float64, startDate, endDate time.Time) ([]DailyWeather, error)
+}
+
+// WeatherService defines the interface for syncing provider weather into storage
+type WeatherService interface {
+	FarmExists(farmID uint) (bool, error)
+	SyncWeather(farmID uint, startDate, endDate time.Time) (*WeatherSyncResult, error)
+}
+
+// WeatherSyncResult reports what a sync stored
+type WeatherSyncResult struct {
+	FarmID   uint       `json:"farm_id"`
+	Provider string     `json:"provider"`
+	Period   PeriodInfo `json:"period"`
+	Days     int        `json:"days"`
+}
+
+// weatherService implements WeatherService
+type weatherService struct {
+	repo     repository.IrrigationRepository
+	farms    repository.FarmRepository
+	weather  repository.WeatherRepository
+	provider WeatherProvider
+}
+
+// NewWeatherService creates a new weather service
+func NewWeatherService(repo repository.IrrigationRepository, farms repository.FarmRepository, weather repository.WeatherRepository, provider WeatherProvider) WeatherService {
+	return &weatherService{repo: repo, farms: farms, weather: weather, provider: provider}
+}
+
+// FarmExists checks if a farm exists
+func (s *weatherService) FarmExists(farmID uint) (bool, error) {
+	return s.repo.FarmExists(farmID)
+}
+
+// SyncWeather fetches the farm's daily weather for the days in [startDate, endDate) and stores
+// it, replacing observations already stored for those days
+func (s *weatherService) SyncWeather(farmID uint, startDate, endDate time.Time) (*WeatherSyncResult, error) {
+	startDate, endDate = truncateToDay(startDate), truncateToDay(endDate)
+
+	farm, err := s.farms.GetFarmWithSectors(farmID)
+	if err != nil {
+		return nil, err
+	}
+	if farm == nil {
+		return nil, ErrFarmNotFound
+	}
+	if farm.Latitude == nil || farm.Longitude == nil {
+		return nil, ErrFarmWithoutCoordinates
+	}
+
+	days, err := s.provider.DailyWeather(*farm.Latitude, *farm.Longitude, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrWeatherProvider, err)
+	}
+
+	observations := make([]model.WeatherObservation, 0, len(days))
+	for _, day := range days {
+		observations = append(observations, model.WeatherObservation{
+			FarmID:          farmID,
+			Date:            truncateToDay(day.Date),
+			PrecipitationMM: day.PrecipitationMM,
+			ET0MM:           day.ET0MM,
+			TempMinC:        day.TempMinC,
+			TempMaxC:        day.TempMaxC,
+			Source:          s.provider.Name(),
+		})
+	}
+	if err := s.weather.UpsertObservations(observations); err != nil {
+		return nil, err
+	}
+
+	return &WeatherSyncResult{
+		FarmID:   farmID,
+		Provider: s.provider.Name(),
+		Period:   PeriodInfo{StartDate: startDate, EndDate: endDate},
+		Days:     len(observations),
+	}, nil
+}
+
+// attachWeather adds the rainfall and reference evapotranspiration of each data point's bucket,
+// summed over the bucket's days within the requested range. Weather is recorded per day and per farm, so hourly points
+// carry none and every sector's point for a bucket carries the same values. Days without a
+// stored value are left out of the sums; a point with no value at all carries none. Buckets with
+// a frost or heat day are marked with those weather events.
+func (s *analyticsService) attachWeather(points []AggregatedDataPoint, data []repository.AggregatedDataWithCount, farmID uint, startDate, endDate time.Time, aggregation string) error {
+	if s.weather == nil || len(points) == 0 || aggregation == "hourly" {
+		return nil
+	}
+
+	observations, err := s.weather.GetObservations(farmID, truncateToDay(startDate), endDate)
+	if err != nil {
+		return err
+	}
+
+	type weatherTotals struct {
+		precipitation, et0 *float64
+	}
+	byBucket := make(map[int64]*weatherTotals)
+	add := func(total **float64, value *float64) {
+		if value == nil {
+			return
+		}
+		if *total == nil {
+			*total = new(float64)
+		}
+		**total += *value
+	}
+	for _, observation := range observations {
+		key := bucketStart(observation.Date, aggregation).Unix()
+		if byBucket[key] == nil {
+			byBucket[key] = &weatherTotals{}
+		}
+		add(&byBucket[key].precipitation, observation.PrecipitationMM)
+		add(&byBucket[key].et0, observation.ET0MM)
+	}
+
+	round := func(v *float64) *float64 {
+		if v == nil {
+			return nil
+		}
+		rounded := math.Round(*v*100) / 100
+		return &rounded
+	}
+	events := bucketWeatherEvents(observations, aggregation)
+	for i, item := range data {
+		totals, exists := byBucket[item.Data.StartTime.Unix()]
+		if !exists {
+			continue
+		}
+		points[i].RainfallMM = round(totals.precipitation)
+		points[i].ET0MM = round(totals.et0)
+		points[i].WeatherEvents = events[item.Data.StartTime.Unix()]
+	}
+	return nil
+}
+
+// openMeteoArchiveURL is the Open-Meteo historical weather endpoint
+const openMeteoArchiveURL = "https://archive-api.open-meteo.com/v1/archive"
+
+// OpenMeteoProvider fetches daily precipitation, FAO-56 ET0 and temperature extremes from the
+// Open-Meteo archive API, which needs no API key
+type OpenMeteoProvider struct {
+	// BaseURL overrides the archive endpoint, e.g. for a self-hosted instance
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewOpenMeteoProvider creates an Open-Meteo provider with a 10 second request timeout
+func NewOpenMeteoProvider() *OpenMeteoProvider {
+	return &OpenMeteoProvider{BaseURL: openMeteoArchiveURL, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Name identifies the provider in stored observations
+func (p *OpenMeteoProvider) Name() string {
+	return "open-meteo"
+}
+
+// openMeteoResponse is the part of an Open-Meteo archive response that is read
+type openMeteoResponse struct {
+	Daily struct {
+		Time          []string   `json:"time"`
+		Precipitation []*float64 `json:"precipitation_sum"`
+		ET0           []*float64 `json:"et0_fao_evapotranspiration"`
+		TempMin       []*float64 `json:"temperature_2m_min"`
+		TempMax       []*float64 `json:"temperature_2m_max"`
+	} `json:"daily"`
+}
+
+// DailyWeather fetches the days in [startDate, endDate) in UTC
+func (p *OpenMeteoProvider) DailyWeather(latitude, longitude float64, startDate, endDate time.Time) ([]DailyWeather, error) {
+	lastDay := truncateToDay(endDate).AddDate(0, 0, -1)
+	if lastDay.Before(startDate) {
+		return nil, nil
+	}
+
+	query := url.Values{}
+	query.Set("latitude", strconv.FormatFloat(latitude, 'f', 6, 64))
+	query.Set("longitude", strconv.FormatFloat(longitude, 'f', 6, 64))
+	query.Set("start_date", startDate.Format("2006-01-02"))
+	query.Set("end_date", lastDay.Format("2006-01-02"))
+	query.Set("daily", "precipitation_sum,et0_fao_evapotranspiration,temperature_2m_min,temperature_2m_max")
+	query.Set("timezone", "UTC")
+
+	resp, err := p.Client.Get(p.BaseURL + "?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("open-meteo responded %d", resp.StatusCode)
+	}
+
+	var body openMeteoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decoding open-meteo response: %w", err)
+	}
+	daily := body.Daily
+	if len(daily.Precipitation) != len(daily.Time) || len(daily.ET0) != len(daily.Time) ||
+		len(daily.TempMin) != len(daily.Time) || len(daily.TempMax) != len(daily.Time) {
+		return nil, errors.New("open-meteo response has mismatched daily series")
+	}
+
+	days := make([]DailyWeather, 0, len(daily.Time))
+	for i, value := range daily.Time {
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return nil, fmt.Errorf("open-meteo returned invalid date %q", value)
+		}
+		days = append(days, DailyWeather{
+			Date:            date,
+			PrecipitationMM: daily.Precipitation[i],
+			ET0MM:           daily.ET0[i],
+			TempMinC:        daily.TempMin[i],
+			TempMaxC:        daily.TempMax[i],
+		})
+	}
+	return days, nil
+}
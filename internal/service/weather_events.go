@@ -0,0 +1,62 @@
+package service
+
+import (
+	"time"
+
+	"irrigation-analytics/internal/model"
+)
+
+// Weather events marked on buckets, since water use legitimately deviates from normal on them:
+// frost protection runs sprinklers through the night and heat waves raise demand
+const (
+	// WeatherEventFrost marks a day whose minimum temperature fell to frostMaxTempC or below
+	WeatherEventFrost = "frost"
+	// WeatherEventHeat marks a day whose maximum temperature reached heatMinTempC or above
+	WeatherEventHeat = "heat"
+)
+
+// Weather event thresholds in °C
+const (
+	frostMaxTempC = 0.0
+	heatMinTempC  = 35.0
+)
+
+// weatherEventKey is the key a time's weather events are grouped under: its bucket start, or its
+// day for hourly buckets since weather is recorded per day
+func weatherEventKey(t time.Time, aggregation string) int64 {
+	if aggregation == "hourly" {
+		return truncateToDay(t).Unix()
+	}
+	return bucketStart(t, aggregation).Unix()
+}
+
+// bucketWeatherEvents returns the frost and heat events of each bucket with any, keyed by
+// weatherEventKey, in the order frost, heat
+func bucketWeatherEvents(observations []model.WeatherObservation, aggregation string) map[int64][]string {
+	type flags struct{ frost, heat bool }
+	byBucket := make(map[int64]*flags)
+	for _, observation := range observations {
+		frost := observation.TempMinC != nil && *observation.TempMinC <= frostMaxTempC
+		heat := observation.TempMaxC != nil && *observation.TempMaxC >= heatMinTempC
+		if !frost && !heat {
+			continue
+		}
+		key := weatherEventKey(observation.Date, aggregation)
+		if byBucket[key] == nil {
+			byBucket[key] = &flags{}
+		}
+		byBucket[key].frost = byBucket[key].frost || frost
+		byBucket[key].heat = byBucket[key].heat || heat
+	}
+
+	events := make(map[int64][]string, len(byBucket))
+	for key, f := range byBucket {
+		if f.frost {
+			events[key] = append(events[key], WeatherEventFrost)
+		}
+		if f.heat {
+			events[key] = append(events[key], WeatherEventHeat)
+		}
+	}
+	return events
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// stubWeatherRepository serves and records observations
+type stubWeatherRepository struct {
+	observations []model.WeatherObservation
+	stored       []model.WeatherObservation
+}
+
+func (r *stubWeatherRepository) UpsertObservations(observations []model.WeatherObservation) error {
+	r.stored = observations
+	return nil
+}
+
+func (r *stubWeatherRepository) GetObservations(farmID uint, startDate, endDate time.Time) ([]model.WeatherObservation, error) {
+	return r.observations, nil
+}
+
+func (r *stubWeatherRepository) WithContext(ctx context.Context) repository.WeatherRepository {
+	return r
+}
+
+func (r *stubWeatherRepository) WithCapture(capture *repository.QueryCapture) repository.WeatherRepository {
+	return r
+}
+
+// stubWeatherProvider returns fixed days
+type stubWeatherProvider struct {
+	days []DailyWeather
+	err  error
+}
+
+func (p *stubWeatherProvider) Name() string { return "stub" }
+
+func (p *stubWeatherProvider) DailyWeather(latitude, longitude float64, startDate, endDate time.Time) ([]DailyWeather, error) {
+	return p.days, p.err
+}
+
+func weatherFloat(v float64) *float64 { return &v }
+
+// TestGetIrrigationAnalytics_Weather verifies daily weather is summed per weekly bucket and
+// repeated for every sector
+func TestGetIrrigationAnalytics_Weather(t *testing.T) {
+	week := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC) // a Monday
+	repo := &stubRepository{
+		comparison: map[int][]repository.AggregatedDataWithCount{
+			0: {
+				aggregatedPoint(week, 1, 100, 100, 1),
+				aggregatedPoint(week, 2, 100, 100, 1),
+				aggregatedPoint(week.AddDate(0, 0, 7), 1, 100, 100, 1),
+			},
+		},
+	}
+	weather := &stubWeatherRepository{observations: []model.WeatherObservation{
+		{Date: week, PrecipitationMM: weatherFloat(2.5), ET0MM: weatherFloat(4.1), TempMaxC: weatherFloat(37.2)},
+		{Date: week.AddDate(0, 0, 3), PrecipitationMM: weatherFloat(1.25), ET0MM: nil},
+	}}
+	svc := NewAnalyticsService(repo, nil, nil, weather)
+
+	response, err := svc.GetIrrigationAnalytics(1, nil, week, week.AddDate(0, 0, 14), "weekly", AnalyticsOptions{IncludeWeather: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		point := response.Data[i]
+		if point.RainfallMM == nil || *point.RainfallMM != 3.75 || point.ET0MM == nil || *point.ET0MM != 4.1 {
+			t.Errorf("point %d: expected 3.75 mm rain and 4.1 mm ET0, got %v/%v", i, point.RainfallMM, point.ET0MM)
+		}
+	}
+	if events := response.Data[0].WeatherEvents; len(events) != 1 || events[0] != WeatherEventHeat {
+		t.Errorf("expected the week to be marked with heat, got %v", events)
+	}
+	if response.Data[2].RainfallMM != nil || response.Data[2].ET0MM != nil {
+		t.Error("expected no weather for a week without observations")
+	}
+
+	// Without the option the stored weather is not read
+	response, _ = svc.GetIrrigationAnalytics(1, nil, week, week.AddDate(0, 0, 14), "weekly", AnalyticsOptions{})
+	if response.Data[0].RainfallMM != nil {
+		t.Error("expected no weather without IncludeWeather")
+	}
+}
+
+func TestSyncWeather(t *testing.T) {
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	farms := &stubFarmRepository{existing: &model.Farm{ID: 1, Latitude: weatherFloat(38.5), Longitude: weatherFloat(-121.7)}}
+	weather := &stubWeatherRepository{}
+	provider := &stubWeatherProvider{days: []DailyWeather{
+		{Date: day, PrecipitationMM: weatherFloat(0), ET0MM: weatherFloat(6.2)},
+		{Date: day.AddDate(0, 0, 1), PrecipitationMM: weatherFloat(3.1), ET0MM: weatherFloat(5.4)},
+	}}
+	svc := NewWeatherService(&stubRepository{}, farms, weather, provider)
+
+	result, err := svc.SyncWeather(1, day, day.AddDate(0, 0, 2))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Days != 2 || result.Provider != "stub" || len(weather.stored) != 2 {
+		t.Fatalf("expected 2 days stored from stub, got %+v (%d stored)", result, len(weather.stored))
+	}
+	if weather.stored[1].FarmID != 1 || weather.stored[1].Source != "stub" || *weather.stored[1].PrecipitationMM != 3.1 {
+		t.Errorf("unexpected stored observation %+v", weather.stored[1])
+	}
+
+	if _, err := svc.SyncWeather(2, day, day.AddDate(0, 0, 2)); !errors.Is(err, ErrFarmNotFound) {
+		t.Errorf("expected ErrFarmNotFound, got %v", err)
+	}
+
+	farms.existing.Latitude = nil
+	if _, err := svc.SyncWeather(1, day, day.AddDate(0, 0, 2)); !errors.Is(err, ErrFarmWithoutCoordinates) {
+		t.Errorf("expected ErrFarmWithoutCoordinates, got %v", err)
+	}
+
+	farms.existing.Latitude = weatherFloat(38.5)
+	provider.err = errors.New("timeout")
+	if _, err := svc.SyncWeather(1, day, day.AddDate(0, 0, 2)); !errors.Is(err, ErrWeatherProvider) {
+		t.Errorf("expected ErrWeatherProvider, got %v", err)
+	}
+}
+
+func TestOpenMeteoProvider(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		w.Write([]byte(`{"daily":{"time":["2025-06-01","2025-06-02"],"precipitation_sum":[0.4,null],"et0_fao_evapotranspiration":[5.9,6.3],"temperature_2m_min":[-1.2,14],"temperature_2m_max":[12.5,36.1]}}`))
+	}))
+	defer server.Close()
+
+	provider := NewOpenMeteoProvider()
+	provider.BaseURL = server.URL
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	days, err := provider.DailyWeather(38.5, -121.7, start, start.AddDate(0, 0, 2))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(days) != 2 || *days[0].PrecipitationMM != 0.4 || days[1].PrecipitationMM != nil || *days[1].ET0MM != 6.3 ||
+		*days[0].TempMinC != -1.2 || *days[1].TempMaxC != 36.1 {
+		t.Errorf("unexpected days %+v", days)
+	}
+	if want := "end_date=2025-06-02"; !strings.Contains(query, want) {
+		t.Errorf("expected the last day to be inclusive in the query, got %s", query)
+	}
+}
+
+func TestBucketWeatherEvents(t *testing.T) {
+	day := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC) // a Monday
+	observations := []model.WeatherObservation{
+		{Date: day, TempMinC: weatherFloat(-0.4), TempMaxC: weatherFloat(15)},
+		{Date: day.AddDate(0, 0, 1), TempMinC: weatherFloat(0.1), TempMaxC: weatherFloat(20)},
+		{Date: day.AddDate(0, 0, 4), TempMinC: nil, TempMaxC: weatherFloat(35)},
+	}
+
+	weekly := bucketWeatherEvents(observations, "weekly")
+	if events := weekly[day.Unix()]; len(events) != 2 || events[0] != WeatherEventFrost || events[1] != WeatherEventHeat {
+		t.Errorf("expected frost then heat for the week, got %v", events)
+	}
+
+	hourly := bucketWeatherEvents(observations, "hourly")
+	if events := hourly[weatherEventKey(day.Add(5*time.Hour), "hourly")]; len(events) != 1 || events[0] != WeatherEventFrost {
+		t.Errorf("expected an hour of the frost day to be marked, got %v", events)
+	}
+	if events := hourly[weatherEventKey(day.AddDate(0, 0, 1), "hourly")]; events != nil {
+		t.Errorf("expected no events above 0 °C, got %v", events)
+	}
+}
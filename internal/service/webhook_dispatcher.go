@@ -0,0 +1,378 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// DefaultWebhookInterval is how often the dispatcher evaluates alerts and sends due deliveries
+const DefaultWebhookInterval = time.Minute
+
+// Webhook delivery retries: the nth failed attempt is retried after webhookRetryBase * 2^(n-1),
+// at most webhookRetryMax, and a delivery fails for good after MaxWebhookAttempts attempts
+const (
+	MaxWebhookAttempts = 8
+	webhookRetryBase   = time.Minute
+	webhookRetryMax    = time.Hour
+)
+
+// webhookDeliveryBatch bounds the deliveries sent in one dispatch, and webhookDeliveryWorkers
+// the webhooks sent to at once
+const (
+	webhookDeliveryBatch   = 100
+	webhookDeliveryWorkers = 4
+)
+
+// WebhookAlert is the JSON body POSTed for an alert
+type WebhookAlert struct {
+	Event      string    `json:"event"`
+	FarmID     uint      `json:"farm_id"`
+	WebhookID  uint      `json:"webhook_id"`
+	DetectedAt time.Time `json:"detected_at"`
+	// Day is the UTC day a volume or efficiency alert is about
+	Day         *time.Time `json:"day,omitempty"`
+	WaterVolume *float64   `json:"water_volume,omitempty"`
+	Efficiency  *float64   `json:"efficiency,omitempty"`
+	Threshold   *float64   `json:"threshold,omitempty"`
+	// LastEventAt is when the farm's latest event arrived, for no_data alerts
+	LastEventAt *time.Time `json:"last_event_at,omitempty"`
+	NoDataHours *int       `json:"no_data_hours,omitempty"`
+}
+
+// WebhookDispatcher evaluates webhook thresholds and delivers the alerts they raise
+type WebhookDispatcher interface {
+	Evaluate(ctx context.Context) error
+	Deliver(ctx context.Context) error
+	Run(ctx context.Context, interval time.Duration)
+}
+
+// webhookDispatcher implements WebhookDispatcher
+type webhookDispatcher struct {
+	webhooks repository.WebhookRepository
+	client   *http.Client
+	logger   *slog.Logger
+	now      func() time.Time
+}
+
+// NewWebhookDispatcher creates a dispatcher whose deliveries each get 10 seconds
+func NewWebhookDispatcher(webhooks repository.WebhookRepository, logger *slog.Logger) WebhookDispatcher {
+	return &webhookDispatcher{
+		webhooks: webhooks,
+		client:   newWebhookClient(isPublicAddress),
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// newWebhookClient returns the delivery client. It connects only to addresses allowed accepts,
+// checked after DNS resolution so a host can't be re-pointed at the internal network once it is
+// registered. It ignores proxy settings, which would hide the address it reaches, and doesn't
+// follow redirects: a 3xx response fails the attempt.
+func newWebhookClient(allowed func(netip.Addr) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidWebhookTarget, err)
+			}
+			if !allowed(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s is not a public address", ErrInvalidWebhookTarget, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Evaluate checks every webhook's thresholds and queues an alert for each one crossed. Daily
+// volume is checked for today so far and yesterday, efficiency only for yesterday, the last
+// finished day. An alert is queued once per webhook and day, or per silence for no_data. A
+// webhook that fails is logged without stopping the others.
+func (d *webhookDispatcher) Evaluate(ctx context.Context) error {
+	repo := d.webhooks.WithContext(ctx)
+	webhooks, err := repo.ListAlerting()
+	if err != nil {
+		return err
+	}
+
+	now := d.now().UTC()
+	for i := range webhooks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := d.evaluate(repo, &webhooks[i], now); err != nil {
+			d.logger.Error("webhook evaluation failed", "webhook_id", webhooks[i].ID, "farm_id", webhooks[i].FarmID, "error", err)
+		}
+	}
+	return nil
+}
+
+// evaluate queues the alerts one webhook's thresholds raise at now
+func (d *webhookDispatcher) evaluate(repo repository.WebhookRepository, webhook *model.Webhook, now time.Time) error {
+	var alerts []WebhookAlert
+	alert := func(event string) WebhookAlert {
+		return WebhookAlert{Event: event, FarmID: webhook.FarmID, WebhookID: webhook.ID, DetectedAt: now}
+	}
+
+	if webhook.MaxDailyWaterVolume != nil || webhook.MinDailyEfficiency != nil {
+		today := truncateToDay(now)
+		yesterday := today.AddDate(0, 0, -1)
+		days, err := repo.EventDayTotals(webhook.FarmID, yesterday, today.AddDate(0, 0, 1))
+		if err != nil {
+			return err
+		}
+		for _, total := range days {
+			day, volume := total.Day.UTC(), total.WaterVolume
+			if limit := webhook.MaxDailyWaterVolume; limit != nil && volume > *limit {
+				a := alert(model.WebhookEventDailyVolumeExceeded)
+				a.Day, a.WaterVolume, a.Threshold = &day, &volume, limit
+				alerts = append(alerts, a)
+			}
+			if floor := webhook.MinDailyEfficiency; floor != nil && day.Equal(yesterday) && total.NominalAmount > 0 {
+				efficiency := math.Round(total.RealAmount/total.NominalAmount*10000) / 10000
+				if efficiency < *floor {
+					a := alert(model.WebhookEventEfficiencyBelowFloor)
+					a.Day, a.Efficiency, a.Threshold = &day, &efficiency, floor
+					alerts = append(alerts, a)
+				}
+			}
+		}
+	}
+
+	if hours := webhook.NoDataHours; hours != nil {
+		last, err := repo.LastEventCreatedAt(webhook.FarmID)
+		if err != nil {
+			return err
+		}
+		// A farm that never received data has nothing to fall silent
+		if last != nil && now.Sub(*last) >= time.Duration(*hours)*time.Hour {
+			lastUTC := last.UTC()
+			a := alert(model.WebhookEventNoData)
+			a.LastEventAt, a.NoDataHours = &lastUTC, hours
+			alerts = append(alerts, a)
+		}
+	}
+
+	for _, a := range alerts {
+		if err := d.enqueue(repo, a, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enqueue queues an alert under its key unless it already was
+func (d *webhookDispatcher) enqueue(repo repository.WebhookRepository, alert WebhookAlert, now time.Time) error {
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	queued, err := repo.Enqueue(&model.WebhookDelivery{
+		WebhookID:     alert.WebhookID,
+		Key:           alertKey(alert),
+		Event:         alert.Event,
+		Payload:       model.RawPayload(payload),
+		Status:        model.WebhookDeliveryPending,
+		NextAttemptAt: now,
+	})
+	if err == nil && queued {
+		d.logger.Info("webhook alert queued", "webhook_id", alert.WebhookID, "farm_id", alert.FarmID, "event", alert.Event)
+	}
+	return err
+}
+
+// alertKey identifies an alert: its event and the day it is about, or for no_data the last event
+// before the silence, so a silence is reported once however long it lasts
+func alertKey(alert WebhookAlert) string {
+	if alert.LastEventAt != nil {
+		return alert.Event + ":" + alert.LastEventAt.Format(time.RFC3339Nano)
+	}
+	return alert.Event + ":" + alert.Day.Format("2006-01-02")
+}
+
+// Deliver sends the deliveries that are due. Webhooks are served concurrently by
+// webhookDeliveryWorkers workers, each sending one webhook's deliveries in order. A webhook's
+// first failed attempt defers the rest of its deliveries to the next dispatch, so a slow or
+// unreachable receiver holds up nothing but itself, for one timeout per dispatch. A failed
+// attempt is retried with exponential backoff until MaxWebhookAttempts; deliveries of deleted
+// webhooks fail without being sent.
+func (d *webhookDispatcher) Deliver(ctx context.Context) error {
+	repo := d.webhooks.WithContext(ctx)
+	due, err := repo.DueDeliveries(d.now(), webhookDeliveryBatch)
+	if err != nil {
+		return err
+	}
+
+	// Group the deliveries by webhook, keeping the due order within each
+	var webhookIDs []uint
+	byWebhook := make(map[uint][]*model.WebhookDelivery)
+	for i := range due {
+		id := due[i].WebhookID
+		if _, ok := byWebhook[id]; !ok {
+			webhookIDs = append(webhookIDs, id)
+		}
+		byWebhook[id] = append(byWebhook[id], &due[i])
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	queue := make(chan uint)
+	for w := 0; w < webhookDeliveryWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range queue {
+				if err := d.deliverWebhook(ctx, repo, id, byWebhook[id]); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, id := range webhookIDs {
+		queue <- id
+	}
+	close(queue)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// deliverWebhook sends one webhook's due deliveries in order, stopping at the first failure
+func (d *webhookDispatcher) deliverWebhook(ctx context.Context, repo repository.WebhookRepository, webhookID uint, deliveries []*model.WebhookDelivery) error {
+	webhook, err := repo.GetForDelivery(webhookID)
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		delivery.Attempts++
+		now := d.now()
+		failed := false
+		if webhook == nil {
+			delivery.Status, delivery.LastError = model.WebhookDeliveryFailed, "webhook deleted"
+		} else if err := d.post(ctx, webhook, delivery); err == nil {
+			delivery.Status, delivery.LastError, delivery.DeliveredAt = model.WebhookDeliveryDelivered, "", &now
+		} else {
+			failed = true
+			delivery.LastError = err.Error()
+			if delivery.Attempts >= MaxWebhookAttempts {
+				delivery.Status = model.WebhookDeliveryFailed
+			} else {
+				delivery.NextAttemptAt = now.Add(webhookRetryDelay(delivery.Attempts))
+			}
+			d.logger.Warn("webhook delivery failed",
+				"webhook_id", delivery.WebhookID,
+				"delivery_id", delivery.ID,
+				"attempts", delivery.Attempts,
+				"error", err,
+			)
+		}
+		if err := repo.UpdateDelivery(delivery); err != nil {
+			return err
+		}
+		if failed {
+			return nil
+		}
+	}
+	return nil
+}
+
+// webhookRetryDelay is the wait after the given number of failed attempts
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := webhookRetryBase << (attempts - 1)
+	if delay <= 0 || delay > webhookRetryMax {
+		return webhookRetryMax
+	}
+	return delay
+}
+
+// post sends one signed delivery, failing on any non-2xx response
+func (d *webhookDispatcher) post(ctx context.Context, webhook *model.Webhook, delivery *model.WebhookDelivery) error {
+	body := []byte(delivery.Payload)
+	timestamp := d.now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", SignWebhook(webhook.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Run evaluates and delivers once immediately and then every interval until ctx ends; an
+// interval of 0 or less uses DefaultWebhookInterval
+func (d *webhookDispatcher) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultWebhookInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.Evaluate(ctx); err != nil && ctx.Err() == nil {
+			d.logger.Error("webhook evaluation failed", "error", err)
+		}
+		if err := d.Deliver(ctx); err != nil && ctx.Err() == nil {
+			d.logger.Error("webhook delivery failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// Webhook delivery listing limits
+const (
+	DefaultWebhookDeliveryLimit = 50
+	MaxWebhookDeliveryLimit     = 500
+)
+
+// webhookLookupTimeout bounds the DNS lookup of a new webhook's host
+const webhookLookupTimeout = 5 * time.Second
+
+// ErrWebhookNotFound is returned when the farm has no webhook with the ID
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// ErrInvalidWebhookTarget is returned when a webhook's host is not a public address, or doesn't
+// resolve to one. Webhooks are POSTed from inside the network, so they must never reach it.
+var ErrInvalidWebhookTarget = errors.New("invalid webhook target")
+
+// nonPublicPrefixes are the special-purpose ranges, beyond the loopback, private, link-local,
+// multicast and unspecified ones netip recognizes, that webhooks may not reach
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// isPublicAddress reports whether a webhook may be delivered to addr
+func isPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// WebhookService defines the interface for registering farm alert webhooks
+type WebhookService interface {
+	FarmExists(farmID uint) (bool, error)
+	CreateWebhook(ctx context.Context, farmID uint, input WebhookInput) (*CreatedWebhook, error)
+	ListWebhooks(farmID uint) ([]model.Webhook, error)
+	DeleteWebhook(farmID, webhookID uint) (bool, error)
+	ListDeliveries(farmID, webhookID uint, limit int) ([]model.WebhookDelivery, error)
+}
+
+// WebhookInput describes a webhook; at least one threshold must be set
+type WebhookInput struct {
+	URL                 string
+	MaxDailyWaterVolume *float64
+	MinDailyEfficiency  *float64
+	NoDataHours         *int
+}
+
+// CreatedWebhook is a new webhook together with its signing secret, which is not shown again
+type CreatedWebhook struct {
+	model.Webhook
+	Secret string `json:"secret"`
+}
+
+// webhookService implements WebhookService
+type webhookService struct {
+	repo     repository.IrrigationRepository
+	webhooks repository.WebhookRepository
+	// lookup resolves a webhook's host and allowed decides which addresses it may resolve to;
+	// tests replace them
+	lookup  func(ctx context.Context, host string) ([]netip.Addr, error)
+	allowed func(netip.Addr) bool
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(repo repository.IrrigationRepository, webhooks repository.WebhookRepository) WebhookService {
+	return &webhookService{
+		repo:     repo,
+		webhooks: webhooks,
+		lookup: func(ctx context.Context, host string) ([]netip.Addr, error) {
+			return net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+		},
+		allowed: isPublicAddress,
+	}
+}
+
+// FarmExists checks if a farm exists
+func (s *webhookService) FarmExists(farmID uint) (bool, error) {
+	return s.repo.FarmExists(farmID)
+}
+
+// CreateWebhook registers a webhook with a new random signing secret. Its host must resolve to
+// public addresses only; the dispatcher checks again when it connects, since DNS can change.
+func (s *webhookService) CreateWebhook(ctx context.Context, farmID uint, input WebhookInput) (*CreatedWebhook, error) {
+	if err := s.checkTarget(ctx, input.URL); err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	webhook := model.Webhook{
+		FarmID:              farmID,
+		URL:                 input.URL,
+		Secret:              hex.EncodeToString(secret),
+		MaxDailyWaterVolume: input.MaxDailyWaterVolume,
+		MinDailyEfficiency:  input.MinDailyEfficiency,
+		NoDataHours:         input.NoDataHours,
+	}
+	if err := s.webhooks.Create(&webhook); err != nil {
+		return nil, err
+	}
+	return &CreatedWebhook{Webhook: webhook, Secret: webhook.Secret}, nil
+}
+
+// checkTarget rejects a webhook URL whose host is, or resolves to, a non-public address
+func (s *webhookService) checkTarget(ctx context.Context, rawURL string) error {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWebhookTarget, err)
+	}
+	host := target.Hostname()
+
+	addrs := []netip.Addr{}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addrs = append(addrs, addr)
+	} else {
+		ctx, cancel := context.WithTimeout(ctx, webhookLookupTimeout)
+		defer cancel()
+		if addrs, err = s.lookup(ctx, host); err != nil || len(addrs) == 0 {
+			return fmt.Errorf("%w: %s does not resolve", ErrInvalidWebhookTarget, host)
+		}
+	}
+	for _, addr := range addrs {
+		if !s.allowed(addr) {
+			return fmt.Errorf("%w: %s is not a public address", ErrInvalidWebhookTarget, host)
+		}
+	}
+	return nil
+}
+
+// ListWebhooks returns the farm's webhooks, without their secrets
+func (s *webhookService) ListWebhooks(farmID uint) ([]model.Webhook, error) {
+	return s.webhooks.List(farmID)
+}
+
+// DeleteWebhook removes a webhook of the farm, reporting whether it existed
+func (s *webhookService) DeleteWebhook(farmID, webhookID uint) (bool, error) {
+	return s.webhooks.Delete(farmID, webhookID)
+}
+
+// ListDeliveries returns up to limit of a webhook's deliveries, newest first
+func (s *webhookService) ListDeliveries(farmID, webhookID uint, limit int) ([]model.WebhookDelivery, error) {
+	webhook, err := s.webhooks.Get(farmID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if webhook == nil {
+		return nil, ErrWebhookNotFound
+	}
+	return s.webhooks.ListDeliveries(webhookID, limit)
+}
+
+// SignWebhook returns the X-Webhook-Signature of a delivery: "sha256=" and the hex HMAC-SHA256,
+// keyed by the webhook's secret, of the X-Webhook-Timestamp value, a dot and the body
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
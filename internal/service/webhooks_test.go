@@ -0,0 +1,372 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"irrigation-analytics/internal/model"
+	"irrigation-analytics/internal/repository"
+)
+
+// stubWebhookRepository keeps webhooks and deliveries in memory over fixed event totals
+type stubWebhookRepository struct {
+	webhooks   []model.Webhook
+	deliveries []model.WebhookDelivery
+	days       []repository.EventDayTotal
+	lastEvent  *time.Time
+}
+
+func (r *stubWebhookRepository) Create(webhook *model.Webhook) error {
+	webhook.ID = uint(len(r.webhooks) + 1)
+	r.webhooks = append(r.webhooks, *webhook)
+	return nil
+}
+
+func (r *stubWebhookRepository) List(farmID uint) ([]model.Webhook, error) { return r.webhooks, nil }
+
+func (r *stubWebhookRepository) Get(farmID, webhookID uint) (*model.Webhook, error) {
+	for _, webhook := range r.webhooks {
+		if webhook.FarmID == farmID && webhook.ID == webhookID {
+			return &webhook, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *stubWebhookRepository) Delete(farmID, webhookID uint) (bool, error) {
+	for i, webhook := range r.webhooks {
+		if webhook.FarmID == farmID && webhook.ID == webhookID {
+			r.webhooks = append(r.webhooks[:i], r.webhooks[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *stubWebhookRepository) ListAlerting() ([]model.Webhook, error) { return r.webhooks, nil }
+
+func (r *stubWebhookRepository) GetForDelivery(webhookID uint) (*model.Webhook, error) {
+	for _, webhook := range r.webhooks {
+		if webhook.ID == webhookID {
+			return &webhook, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *stubWebhookRepository) Enqueue(delivery *model.WebhookDelivery) (bool, error) {
+	for _, queued := range r.deliveries {
+		if queued.WebhookID == delivery.WebhookID && queued.Key == delivery.Key {
+			return false, nil
+		}
+	}
+	delivery.ID = uint(len(r.deliveries) + 1)
+	r.deliveries = append(r.deliveries, *delivery)
+	return true, nil
+}
+
+func (r *stubWebhookRepository) DueDeliveries(now time.Time, limit int) ([]model.WebhookDelivery, error) {
+	var due []model.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if delivery.Status == model.WebhookDeliveryPending && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	return due, nil
+}
+
+func (r *stubWebhookRepository) UpdateDelivery(delivery *model.WebhookDelivery) error {
+	r.deliveries[delivery.ID-1] = *delivery
+	return nil
+}
+
+func (r *stubWebhookRepository) ListDeliveries(webhookID uint, limit int) ([]model.WebhookDelivery, error) {
+	return r.deliveries, nil
+}
+
+func (r *stubWebhookRepository) EventDayTotals(farmID uint, from, to time.Time) ([]repository.EventDayTotal, error) {
+	return r.days, nil
+}
+
+func (r *stubWebhookRepository) LastEventCreatedAt(farmID uint) (*time.Time, error) {
+	return r.lastEvent, nil
+}
+
+func (r *stubWebhookRepository) WithContext(ctx context.Context) repository.WebhookRepository {
+	return r
+}
+
+// TestWebhookDispatcher verifies crossed thresholds are queued once, delivered signed, and
+// retried with backoff while the receiver fails
+func TestWebhookDispatcher(t *testing.T) {
+	failing := true
+	var received []*http.Request
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received, bodies = append(received, r), append(bodies, body)
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	now := time.Date(2025, 7, 10, 9, 0, 0, 0, time.UTC)
+	yesterday := time.Date(2025, 7, 9, 0, 0, 0, 0, time.UTC)
+	lastEvent := now.Add(-7 * time.Hour)
+	maxVolume, minEfficiency, noDataHours := 5000.0, 0.8, 6
+	repo := &stubWebhookRepository{
+		days: []repository.EventDayTotal{
+			{Day: yesterday, WaterVolume: 6200, NominalAmount: 1000, RealAmount: 700},
+			{Day: yesterday.AddDate(0, 0, 1), WaterVolume: 900, NominalAmount: 1000, RealAmount: 500},
+		},
+		lastEvent: &lastEvent,
+	}
+	// The receiver listens on loopback, which only tests may reach
+	allowAll := func(netip.Addr) bool { return true }
+	webhooks := NewWebhookService(nil, repo).(*webhookService)
+	webhooks.allowed = allowAll
+	created, err := webhooks.CreateWebhook(context.Background(), 1, WebhookInput{
+		URL:                 server.URL,
+		MaxDailyWaterVolume: &maxVolume,
+		MinDailyEfficiency:  &minEfficiency,
+		NoDataHours:         &noDataHours,
+	})
+	if err != nil || len(created.Secret) != 64 {
+		t.Fatalf("expected a webhook with a 64 character secret, got %+v, %v", created, err)
+	}
+
+	dispatcher := NewWebhookDispatcher(repo, slog.New(slog.NewTextHandler(io.Discard, nil))).(*webhookDispatcher)
+	dispatcher.now = func() time.Time { return now }
+	dispatcher.client = newWebhookClient(allowAll)
+	for i := 0; i < 2; i++ {
+		if err := dispatcher.Evaluate(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// Today's low efficiency is ignored until the day is over
+	keys := map[string]bool{}
+	for _, delivery := range repo.deliveries {
+		keys[delivery.Key] = true
+	}
+	expected := []string{
+		"daily_volume_exceeded:2025-07-09",
+		"efficiency_below_floor:2025-07-09",
+		"no_data:" + lastEvent.Format(time.RFC3339Nano),
+	}
+	if len(repo.deliveries) != len(expected) {
+		t.Fatalf("expected %d deliveries queued once, got %+v", len(expected), keys)
+	}
+	for _, key := range expected {
+		if !keys[key] {
+			t.Errorf("expected a delivery keyed %s, got %v", key, keys)
+		}
+	}
+
+	if err := dispatcher.Deliver(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first := repo.deliveries[0]
+	if first.Status != model.WebhookDeliveryPending || first.Attempts != 1 || !first.NextAttemptAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("expected a retry in a minute, got %+v", first)
+	}
+	// The failure defers the webhook's other deliveries to the next dispatch
+	if len(received) != 1 || repo.deliveries[1].Attempts != 0 || repo.deliveries[2].Attempts != 0 {
+		t.Errorf("expected one attempt before the webhook was deferred, got %d", len(received))
+	}
+
+	req, body := received[0], bodies[0]
+	timestamp, _ := strconv.ParseInt(req.Header.Get("X-Webhook-Timestamp"), 10, 64)
+	if req.Header.Get("X-Webhook-Signature") != SignWebhook(created.Secret, timestamp, body) {
+		t.Error("expected the delivery to be signed with the webhook secret")
+	}
+	var alert WebhookAlert
+	if err := json.Unmarshal(body, &alert); err != nil || alert.Event != req.Header.Get("X-Webhook-Event") || alert.FarmID != 1 {
+		t.Errorf("unexpected alert %+v (%v)", alert, err)
+	}
+
+	failing = false
+	now = now.Add(time.Minute)
+	if err := dispatcher.Deliver(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, delivery := range repo.deliveries {
+		attempts := 1
+		if i == 0 {
+			attempts = 2
+		}
+		if delivery.Status != model.WebhookDeliveryDelivered || delivery.Attempts != attempts || delivery.LastError != "" {
+			t.Errorf("expected the retry and the deferred deliveries to deliver, got %+v", delivery)
+		}
+	}
+}
+
+// TestWebhookDispatcher_SlowReceiver verifies a receiver that hangs holds up only its own
+// deliveries, for one attempt per dispatch
+func TestWebhookDispatcher_SlowReceiver(t *testing.T) {
+	fastReceived := make(chan struct{})
+	overlapped := false
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-fastReceived:
+			overlapped = true
+		case <-time.After(2 * time.Second):
+		}
+		w.WriteHeader(http.StatusGatewayTimeout)
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(fastReceived)
+	}))
+	defer fast.Close()
+
+	now := time.Date(2025, 7, 10, 9, 0, 0, 0, time.UTC)
+	repo := &stubWebhookRepository{webhooks: []model.Webhook{{ID: 1, FarmID: 1, URL: slow.URL}, {ID: 2, FarmID: 2, URL: fast.URL}}}
+	for i, webhookID := range []uint{1, 1, 1, 2} {
+		repo.deliveries = append(repo.deliveries, model.WebhookDelivery{
+			ID: uint(i + 1), WebhookID: webhookID, Status: model.WebhookDeliveryPending, NextAttemptAt: now, Payload: model.RawPayload(`{}`),
+		})
+	}
+	dispatcher := NewWebhookDispatcher(repo, slog.New(slog.NewTextHandler(io.Discard, nil))).(*webhookDispatcher)
+	dispatcher.now = func() time.Time { return now }
+	dispatcher.client = newWebhookClient(func(netip.Addr) bool { return true })
+
+	if err := dispatcher.Deliver(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !overlapped {
+		t.Error("expected the fast receiver served while the slow one hung")
+	}
+	if got := repo.deliveries[3]; got.Status != model.WebhookDeliveryDelivered {
+		t.Errorf("expected the fast receiver's delivery delivered, got %+v", got)
+	}
+	if got := repo.deliveries[0]; got.Attempts != 1 || got.Status != model.WebhookDeliveryPending {
+		t.Errorf("expected the slow receiver's first delivery retried later, got %+v", got)
+	}
+	for _, got := range repo.deliveries[1:3] {
+		if got.Attempts != 0 {
+			t.Errorf("expected the slow receiver's other deliveries deferred, got %+v", got)
+		}
+	}
+}
+
+func TestCreateWebhook_RejectsNonPublicTargets(t *testing.T) {
+	webhooks := NewWebhookService(nil, &stubWebhookRepository{}).(*webhookService)
+	webhooks.lookup = func(ctx context.Context, host string) ([]netip.Addr, error) {
+		switch host {
+		case "hooks.example.com":
+			return []netip.Addr{netip.MustParseAddr("93.184.215.14")}, nil
+		case "rebind.example.com":
+			return []netip.Addr{netip.MustParseAddr("93.184.215.14"), netip.MustParseAddr("10.1.2.3")}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://hooks.example.com/alerts", true},
+		{"http://93.184.215.14:8080/alerts", true},
+		{"http://127.0.0.1/alerts", false},
+		{"http://10.0.0.5/alerts", false},
+		{"http://192.168.1.1/alerts", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://100.100.100.200/", false},
+		{"http://0.0.0.0:8080/", false},
+		{"http://[::1]/alerts", false},
+		{"http://[fd00::1]/alerts", false},
+		{"http://[::ffff:127.0.0.1]/alerts", false},
+		{"https://rebind.example.com/alerts", false},
+		{"https://unknown.example.com/alerts", false},
+	}
+	for _, tt := range tests {
+		_, err := webhooks.CreateWebhook(context.Background(), 1, WebhookInput{URL: tt.url})
+		if tt.allowed && err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.url, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrInvalidWebhookTarget) {
+			t.Errorf("%s: expected ErrInvalidWebhookTarget, got %v", tt.url, err)
+		}
+	}
+}
+
+// TestWebhookDispatcher_RefusesInternalTargets verifies deliveries never connect to internal
+// addresses, directly or through a redirect
+func TestWebhookDispatcher_RefusesInternalTargets(t *testing.T) {
+	var internalHits, redirectHits int
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		internalHits++
+	}))
+	defer internal.Close()
+	redirecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirectHits++
+		http.Redirect(w, r, internal.URL, http.StatusTemporaryRedirect)
+	}))
+	defer redirecting.Close()
+
+	now := time.Date(2025, 7, 10, 9, 0, 0, 0, time.UTC)
+	repo := &stubWebhookRepository{
+		webhooks: []model.Webhook{{ID: 1, FarmID: 1, URL: internal.URL}, {ID: 2, FarmID: 1, URL: redirecting.URL}},
+		deliveries: []model.WebhookDelivery{
+			{ID: 1, WebhookID: 1, Status: model.WebhookDeliveryPending, NextAttemptAt: now, Payload: model.RawPayload(`{}`)},
+			{ID: 2, WebhookID: 2, Status: model.WebhookDeliveryPending, NextAttemptAt: now, Payload: model.RawPayload(`{}`)},
+		},
+	}
+	dispatcher := NewWebhookDispatcher(repo, slog.New(slog.NewTextHandler(io.Discard, nil))).(*webhookDispatcher)
+	dispatcher.now = func() time.Time { return now }
+
+	// The default client refuses the loopback receiver when it connects
+	if err := dispatcher.Deliver(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if internalHits != 0 || redirectHits != 0 {
+		t.Fatalf("expected no connection to loopback, got %d and %d requests", internalHits, redirectHits)
+	}
+	if got := repo.deliveries[0]; got.Attempts != 1 || !strings.Contains(got.LastError, ErrInvalidWebhookTarget.Error()) {
+		t.Errorf("expected the delivery refused, got %+v", got)
+	}
+
+	// With loopback allowed, the redirect still isn't followed
+	dispatcher.client = newWebhookClient(func(netip.Addr) bool { return true })
+	repo.webhooks = repo.webhooks[1:]
+	repo.deliveries[0].Status = model.WebhookDeliveryFailed
+	now = now.Add(time.Hour)
+	if err := dispatcher.Deliver(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if redirectHits != 1 || internalHits != 0 {
+		t.Errorf("expected the redirect not followed, got %d and %d requests", redirectHits, internalHits)
+	}
+	if got := repo.deliveries[1]; got.Status != model.WebhookDeliveryPending || got.LastError != "webhook responded 307" {
+		t.Errorf("expected the redirect to fail the attempt, got %+v", got)
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{1, time.Minute},
+		{3, 4 * time.Minute},
+		{6, 32 * time.Minute},
+		{7, time.Hour},
+		{70, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := webhookRetryDelay(tt.attempts); got != tt.expected {
+			t.Errorf("webhookRetryDelay(%d) = %v, expected %v", tt.attempts, got, tt.expected)
+		}
+	}
+}